@@ -3,18 +3,22 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/crystal-mush/gotinymush/pkg/boltstore"
+	"github.com/crystal-mush/gotinymush/pkg/dbdiff"
 	"github.com/crystal-mush/gotinymush/pkg/flatfile"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 	"github.com/crystal-mush/gotinymush/pkg/validate"
 )
 
 func main() {
-	dbPath := flag.String("db", "", "Path to TinyMUSH flatfile (e.g., game.FLAT)")
+	dbPath := flag.String("db", "", "Path to TinyMUSH, TinyMUX or PennMUSH flatfile (e.g., game.FLAT), or a .bolt store")
 	showPlayers := flag.Bool("players", false, "List all player objects")
 	showRooms := flag.Bool("rooms", false, "List room summary")
 	showObj := flag.Int("obj", -1, "Show details for a specific object by dbref")
@@ -22,10 +26,19 @@ func main() {
 	runValidate := flag.Bool("validate", false, "Run referential integrity checks")
 	runFullValidate := flag.Bool("validate-all", false, "Run all validators (double-escape, percent, integrity, etc.)")
 	autoFix := flag.Bool("fix", false, "Auto-apply all fixable findings (use with -validate-all)")
+	importJSON := flag.String("import-json", "", "Load a -export-json dump instead of a flatfile")
+	exportJSON := flag.String("export-json", "", "Write the database as JSON to this path (- for stdout)")
+	exportCSV := flag.String("export-csv", "", "Write one CSV row per attribute to this path (- for stdout)")
+	filterType := flag.String("type", "", "Export only these object types (e.g., ROOM,EXIT)")
+	filterOwner := flag.String("owner", "", "Export only objects owned by this dbref")
+	filterRange := flag.String("range", "", "Export only this dbref range (e.g., 100-200, 100- or 42)")
+	savePath := flag.String("save", "", "Write the loaded database as a TinyMUSH 3.1 flatfile")
+	diffPath := flag.String("diff", "", "Compare against this newer flatfile or .bolt store and report what changed")
 	flag.Parse()
 
-	if *dbPath == "" {
+	if (*dbPath == "") == (*importJSON == "") {
 		fmt.Fprintln(os.Stderr, "Usage: dbloader -db <path-to-flatfile> [options]")
+		fmt.Fprintln(os.Stderr, "       dbloader -import-json <path> [options]")
 		fmt.Fprintln(os.Stderr, "  -players      List all players")
 		fmt.Fprintln(os.Stderr, "  -rooms        List rooms summary")
 		fmt.Fprintln(os.Stderr, "  -obj <dbref>  Show object details")
@@ -33,20 +46,82 @@ func main() {
 		fmt.Fprintln(os.Stderr, "  -validate     Run integrity checks")
 		fmt.Fprintln(os.Stderr, "  -validate-all Run all validators (double-escape, percent, integrity, etc.)")
 		fmt.Fprintln(os.Stderr, "  -fix          Auto-apply all fixable findings (use with -validate-all)")
+		fmt.Fprintln(os.Stderr, "  -export-json <path>  Write JSON (- for stdout)")
+		fmt.Fprintln(os.Stderr, "  -export-csv <path>   Write CSV, one row per attribute (- for stdout)")
+		fmt.Fprintln(os.Stderr, "  -type, -owner, -range  Limit exports by type, owner or dbref range")
+		fmt.Fprintln(os.Stderr, "  -save <path>  Write the database as a flatfile")
+		fmt.Fprintln(os.Stderr, "  -diff <path>  Report what changed between -db and this database")
 		os.Exit(1)
 	}
 
-	fmt.Printf("Loading flatfile: %s\n", *dbPath)
-	start := time.Now()
+	filter, err := flatfile.ParseFilter(*filterType, *filterOwner, *filterRange)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
 
-	db, err := flatfile.Load(*dbPath)
+	// An export to stdout keeps stdout clean: status goes to stderr and
+	// the summary is skipped.
+	toStdout := *exportJSON == "-" || *exportCSV == "-"
+	var status io.Writer = os.Stdout
+	if toStdout {
+		status = os.Stderr
+	}
+
+	start := time.Now()
+	var (
+		db  *gamedb.Database
+		rep *flatfile.Report
+	)
+	if *importJSON != "" {
+		fmt.Fprintf(status, "Loading JSON: %s\n", *importJSON)
+		db, err = loadJSON(*importJSON)
+	} else {
+		db, rep, err = loadDatabase(*dbPath, status)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 		os.Exit(1)
 	}
 
 	elapsed := time.Since(start)
-	fmt.Printf("Loaded in %v\n\n", elapsed)
+	fmt.Fprintf(status, "Loaded in %v\n\n", elapsed)
+
+	if rep != nil && len(rep.Dropped) > 0 {
+		fmt.Fprintf(status, "Converted from %s; not carried over:\n", rep.Format)
+		for _, line := range rep.Lines() {
+			fmt.Fprintln(status, line)
+		}
+		fmt.Fprintln(status)
+	}
+
+	if *exportJSON != "" {
+		exportTo(*exportJSON, "JSON", status, func(w io.Writer) error { return flatfile.WriteJSON(w, db, filter) })
+	}
+	if *exportCSV != "" {
+		exportTo(*exportCSV, "CSV", status, func(w io.Writer) error { return flatfile.WriteCSV(w, db, filter) })
+	}
+	if *savePath != "" {
+		if err := flatfile.Save(*savePath, db); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(status, "Saved flatfile: %s\n", *savePath)
+	}
+	if toStdout {
+		return
+	}
+
+	if *diffPath != "" {
+		newer, _, err := loadDatabase(*diffPath, status)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println()
+		dbdiff.Diff(db, newer).Write(os.Stdout)
+		return
+	}
 
 	// Always print summary
 	printSummary(db)
@@ -82,6 +157,57 @@ func main() {
 	}
 }
 
+// loadDatabase loads a flatfile in any format ImportFile reads, or a
+// bolt store if path ends in .bolt.
+func loadDatabase(path string, status io.Writer) (*gamedb.Database, *flatfile.Report, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".bolt") {
+		fmt.Fprintf(status, "Loading flatfile: %s\n", path)
+		return flatfile.ImportFile(path)
+	}
+	fmt.Fprintf(status, "Loading bolt store: %s\n", path)
+	store, err := boltstore.OpenReadOnly(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer store.Close()
+	if err := store.LoadAll(); err != nil {
+		return nil, nil, err
+	}
+	return store.DB(), nil, nil
+}
+
+func loadJSON(path string) (*gamedb.Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return flatfile.ReadJSON(f)
+}
+
+// exportTo runs write against path, or stdout for "-", and exits on error.
+func exportTo(path, kind string, status io.Writer, write func(io.Writer) error) {
+	if path == "-" {
+		if err := write(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s export: %v\n", kind, err)
+			os.Exit(1)
+		}
+		return
+	}
+	f, err := os.Create(path)
+	if err == nil {
+		err = write(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s export: %v\n", kind, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(status, "Wrote %s: %s\n", kind, path)
+}
+
 func printSummary(db *gamedb.Database) {
 	fmt.Println("=== DATABASE SUMMARY ===")
 	fmt.Printf("Format:         %d (TinyMUSH 3.0 = 6)\n", db.Format)
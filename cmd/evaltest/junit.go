@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	File     string      `xml:"file,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the results as JUnit XML, the format CI systems read
+// test reports in.
+func writeJUnit(path string, suites []*suite, results [][]testResult) error {
+	var out junitSuites
+	var total time.Duration
+	for i, s := range suites {
+		js := junitSuite{Name: s.name, File: s.path}
+		var elapsed time.Duration
+		for _, res := range results[i] {
+			jc := junitCase{Name: res.name, ClassName: s.name, Time: seconds(res.elapsed)}
+			if len(res.failures) > 0 {
+				jc.Failure = &junitFailure{Message: firstLine(res.failures[0]), Text: strings.Join(res.failures, "\n")}
+				js.Failures++
+			}
+			js.Cases = append(js.Cases, jc)
+			elapsed += res.elapsed
+		}
+		js.Tests = len(js.Cases)
+		js.Time = seconds(elapsed)
+		out.Suites = append(out.Suites, js)
+		out.Tests += js.Tests
+		out.Failures += js.Failures
+		total += elapsed
+	}
+	out.Time = seconds(total)
+	data, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"strings"
 
@@ -18,56 +20,46 @@ func main() {
 	player := flag.Int("player", 1, "DBRef number to use as player context")
 	expr := flag.String("e", "", "Expression to evaluate (non-interactive mode)")
 	batch := flag.String("batch", "", "File with expressions to evaluate (one per line)")
+	suitePath := flag.String("suite", "", "Run the .mush test suites in this file or directory")
+	junit := flag.String("junit", "", "With -suite, also write results as JUnit XML to this file")
+	golden := flag.Bool("update-golden", false, "With -suite, rewrite expected output with what the tests produce")
+	confPath := flag.String("conf", "", "With -suite, game config to apply to each test's game")
+	verbose := flag.Bool("v", false, "With -suite, list passing tests and show server logging")
 	flag.Parse()
 
-	var db *gamedb.Database
-
-	if *dbPath != "" {
-		fmt.Fprintf(os.Stderr, "Loading database from %s...\n", *dbPath)
-		f, err := os.Open(*dbPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-			os.Exit(1)
+	if *suitePath != "" {
+		if !*verbose {
+			log.SetOutput(io.Discard)
 		}
-		db, err = flatfile.Parse(f)
-		f.Close()
+		failed, err := runSuites(*suitePath, *junit, suiteOptions{
+			db:      *dbPath,
+			conf:    *confPath,
+			player:  gamedb.DBRef(*player),
+			golden:  *golden,
+			verbose: *verbose,
+		})
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing database: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		if failed > 0 {
 			os.Exit(1)
 		}
+		return
+	}
+
+	if *dbPath != "" {
+		fmt.Fprintf(os.Stderr, "Loading database from %s...\n", *dbPath)
+	}
+	db, err := loadDB(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *dbPath != "" {
 		fmt.Fprintf(os.Stderr, "Loaded %d objects, %d attr definitions\n",
 			len(db.Objects), len(db.AttrNames))
 	} else {
-		// Create minimal empty database for testing
-		db = gamedb.NewDatabase()
-		// Create a minimal God object (#1)
-		db.Objects[1] = &gamedb.Object{
-			DBRef:    1,
-			Name:     "Wizard",
-			Location: 0,
-			Contents: gamedb.Nothing,
-			Exits:    gamedb.Nothing,
-			Link:     0,
-			Next:     gamedb.Nothing,
-			Owner:    1,
-			Parent:   gamedb.Nothing,
-			Zone:     gamedb.Nothing,
-			Flags:    [3]int{int(gamedb.TypePlayer) | gamedb.FlagWizard, 0, 0},
-		}
-		// Create Room Zero (#0)
-		db.Objects[0] = &gamedb.Object{
-			DBRef:    0,
-			Name:     "Room Zero",
-			Location: gamedb.Nothing,
-			Contents: 1,
-			Exits:    gamedb.Nothing,
-			Link:     gamedb.Nothing,
-			Next:     gamedb.Nothing,
-			Owner:    1,
-			Parent:   gamedb.Nothing,
-			Zone:     gamedb.Nothing,
-			Flags:    [3]int{int(gamedb.TypeRoom), 0, 0},
-		}
 		fmt.Fprintf(os.Stderr, "Using minimal test database (no flatfile loaded)\n")
 	}
 
@@ -165,3 +157,50 @@ func main() {
 		}
 	}
 }
+
+// loadDB parses the flatfile at path, or with no path builds a minimal
+// database: Room Zero (#0) holding a wizard (#1).
+func loadDB(path string) (*gamedb.Database, error) {
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening database: %v", err)
+		}
+		defer f.Close()
+		db, err := flatfile.Parse(f)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %v", path, err)
+		}
+		return db, nil
+	}
+	db := gamedb.NewDatabase()
+	// Create a minimal God object (#1)
+	db.Objects[1] = &gamedb.Object{
+		DBRef:    1,
+		Name:     "Wizard",
+		Location: 0,
+		Contents: gamedb.Nothing,
+		Exits:    gamedb.Nothing,
+		Link:     0,
+		Next:     gamedb.Nothing,
+		Owner:    1,
+		Parent:   gamedb.Nothing,
+		Zone:     gamedb.Nothing,
+		Flags:    [3]int{int(gamedb.TypePlayer) | gamedb.FlagWizard, 0, 0},
+	}
+	// Create Room Zero (#0)
+	db.Objects[0] = &gamedb.Object{
+		DBRef:    0,
+		Name:     "Room Zero",
+		Location: gamedb.Nothing,
+		Contents: 1,
+		Exits:    gamedb.Nothing,
+		Link:     gamedb.Nothing,
+		Next:     gamedb.Nothing,
+		Owner:    1,
+		Parent:   gamedb.Nothing,
+		Zone:     gamedb.Nothing,
+		Flags:    [3]int{int(gamedb.TypeRoom), 0, 0},
+	}
+	return db, nil
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	"github.com/crystal-mush/gotinymush/pkg/server"
+)
+
+// A .mush suite file is read line by line. Blank lines and lines starting
+// with # are ignored. At the top level:
+//
+//	fixture <path>     flatfile each test starts from (relative to the suite)
+//	player <dbref>     who runs the commands (default: -player)
+//	setup ... end      commands run before each test
+//	teardown ... end   commands run after each test
+//	test <name> ... end
+//
+// Inside a test:
+//
+//	> <command>        run a command and check its output
+//	< <line>           a line the command before is expected to output
+//	wait <seconds>     move the game clock on and run what comes due; its
+//	                   output is checked like a command's
+//	fixture <path>     this test's own flatfile
+//	player <dbref>     run the steps after this as another player
+//	<expr> | <result>  evaluate an expression, as in -batch
+//	<expr>             evaluate an expression without checking it
+//
+// A command's output must match the < lines after it exactly; with none,
+// it must output nothing.
+
+// suiteEpoch is where the game clock starts in every test, so time()
+// and secs() give the same answers from run to run.
+var suiteEpoch = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type stepKind int
+
+const (
+	stepCommand stepKind = iota
+	stepWait
+	stepExpr
+	stepPlayer
+)
+
+// step is one line of a test and the < lines that follow it.
+type step struct {
+	kind     stepKind
+	line     int    // index into suite.lines
+	end      int    // index after the last < line
+	text     string // command, expression, seconds or dbref
+	want     []string
+	expected string // for stepExpr
+	checked  bool   // stepExpr has an expected result
+}
+
+type testCase struct {
+	name    string
+	line    int
+	fixture string
+	steps   []*step
+}
+
+type suite struct {
+	path     string
+	name     string
+	fixture  string
+	player   gamedb.DBRef
+	setup    []string
+	teardown []string
+	tests    []*testCase
+	lines    []string
+}
+
+// testResult is how one test went.
+type testResult struct {
+	suite    string
+	name     string
+	failures []string
+	elapsed  time.Duration
+	actual   map[*step][]string // what each step produced, for -update-golden
+}
+
+// suiteOptions are the flags that apply to every suite.
+type suiteOptions struct {
+	db      string // default fixture
+	conf    string // game config to apply to each test's game
+	player  gamedb.DBRef
+	golden  bool
+	verbose bool
+}
+
+// findSuites returns the .mush files at path, which may be a file or a
+// directory searched recursively, in name order.
+func findSuites(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(p, ".mush") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// parseSuite reads a suite file.
+func parseSuite(path string, player gamedb.DBRef) (*suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &suite{path: path, name: strings.TrimSuffix(filepath.Base(path), ".mush"), player: player}
+	sc := bufio.NewScanner(strings.NewReader(string(data)))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		s.lines = append(s.lines, sc.Text())
+	}
+	bad := func(i int, format string, args ...any) error {
+		return fmt.Errorf("%s:%d: %s", path, i+1, fmt.Sprintf(format, args...))
+	}
+
+	var block *[]string // setup or teardown being read
+	var tc *testCase
+	for i, raw := range s.lines {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch {
+		case block != nil:
+			if line == "end" {
+				block = nil
+			} else {
+				*block = append(*block, strings.TrimSpace(strings.TrimPrefix(line, ">")))
+			}
+		case tc == nil:
+			switch word {
+			case "fixture":
+				s.fixture = rest
+			case "player":
+				ref, ok := parseRef(rest)
+				if !ok {
+					return nil, bad(i, "bad player %q", rest)
+				}
+				s.player = ref
+			case "setup":
+				block = &s.setup
+			case "teardown":
+				block = &s.teardown
+			case "test":
+				if rest == "" {
+					return nil, bad(i, "test needs a name")
+				}
+				tc = &testCase{name: rest, line: i}
+			default:
+				return nil, bad(i, "expected fixture, player, setup, teardown or test, not %q", word)
+			}
+		case line == "end":
+			s.tests = append(s.tests, tc)
+			tc = nil
+		case line == "<" || strings.HasPrefix(line, "< "):
+			var last *step
+			if n := len(tc.steps); n > 0 {
+				last = tc.steps[n-1]
+			}
+			if last == nil || (last.kind != stepCommand && last.kind != stepWait) {
+				return nil, bad(i, "expected output with no command before it")
+			}
+			last.want = append(last.want, strings.TrimPrefix(strings.TrimPrefix(line, "<"), " "))
+			last.end = i + 1
+		case strings.HasPrefix(line, ">"):
+			tc.steps = append(tc.steps, &step{kind: stepCommand, line: i, end: i + 1, text: strings.TrimSpace(line[1:])})
+		case word == "wait":
+			if _, err := strconv.Atoi(rest); err != nil {
+				return nil, bad(i, "bad wait %q", rest)
+			}
+			tc.steps = append(tc.steps, &step{kind: stepWait, line: i, end: i + 1, text: rest})
+		case word == "player":
+			if _, ok := parseRef(rest); !ok {
+				return nil, bad(i, "bad player %q", rest)
+			}
+			tc.steps = append(tc.steps, &step{kind: stepPlayer, line: i, end: i + 1, text: rest})
+		case word == "fixture":
+			tc.fixture = rest
+		default:
+			st := &step{kind: stepExpr, line: i, end: i + 1, text: line}
+			if expr, expected, ok := strings.Cut(line, " |"); ok {
+				st.text, st.expected, st.checked = expr, strings.TrimPrefix(expected, " "), true
+			}
+			tc.steps = append(tc.steps, st)
+		}
+	}
+	switch {
+	case block != nil:
+		return nil, fmt.Errorf("%s: setup or teardown has no end", path)
+	case tc != nil:
+		return nil, bad(tc.line, "test %q has no end", tc.name)
+	}
+	return s, nil
+}
+
+// parseRef reads "#12" or "12".
+func parseRef(s string) (gamedb.DBRef, bool) {
+	n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	return gamedb.DBRef(n), err == nil && n >= 0
+}
+
+// runTest runs one test in a game of its own.
+func runTest(s *suite, tc *testCase, opts suiteOptions) (res testResult) {
+	res = testResult{suite: s.name, name: tc.name, actual: make(map[*step][]string)}
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			res.failures = append(res.failures, fmt.Sprintf("panic: %v", r))
+		}
+		res.elapsed = time.Since(start)
+	}()
+	fail := func(st *step, format string, args ...any) {
+		res.failures = append(res.failures, fmt.Sprintf("line %d: %s", st.line+1, fmt.Sprintf(format, args...)))
+	}
+
+	fixture := tc.fixture
+	if fixture == "" {
+		fixture = s.fixture
+	}
+	if fixture != "" && !filepath.IsAbs(fixture) {
+		fixture = filepath.Join(filepath.Dir(s.path), fixture)
+	} else if fixture == "" {
+		fixture = opts.db
+	}
+	db, err := loadDB(fixture)
+	if err != nil {
+		res.failures = append(res.failures, err.Error())
+		return res
+	}
+	g := server.NewGame(db)
+	if opts.conf != "" {
+		gc, err := server.LoadGameConf(opts.conf)
+		if err != nil {
+			res.failures = append(res.failures, err.Error())
+			return res
+		}
+		g.ApplyGameConf(gc)
+	}
+	now := suiteEpoch
+	g.Clock = func() time.Time { return now }
+
+	sessions := make(map[gamedb.DBRef]*server.Session)
+	defer func() {
+		for _, sess := range sessions {
+			sess.Close()
+		}
+	}()
+	as := func(ref gamedb.DBRef) (*server.Session, error) {
+		if sess := sessions[ref]; sess != nil {
+			return sess, nil
+		}
+		if obj, ok := db.Objects[ref]; !ok || obj.ObjType() != gamedb.TypePlayer {
+			return nil, fmt.Errorf("#%d is not a player", ref)
+		}
+		sessions[ref] = g.OpenSession(ref)
+		return sessions[ref], nil
+	}
+	quiet := func() {
+		for _, sess := range sessions {
+			sess.Output()
+		}
+	}
+
+	actor, err := as(s.player)
+	if err != nil {
+		res.failures = append(res.failures, err.Error())
+		return res
+	}
+	for _, cmd := range s.setup {
+		actor.Run(cmd)
+	}
+	for _, st := range tc.steps {
+		quiet()
+		var out []string
+		switch st.kind {
+		case stepPlayer:
+			ref, _ := parseRef(st.text)
+			if actor, err = as(ref); err != nil {
+				fail(st, "%v", err)
+				return res
+			}
+			continue
+		case stepExpr:
+			got := actor.Eval(st.text)
+			res.actual[st] = []string{got}
+			if opts.verbose && !st.checked {
+				fmt.Printf("  %s => %s\n", st.text, got)
+			}
+			if st.checked && got != st.expected {
+				fail(st, "%s\n    Expected: %s\n    Got:      %s", st.text, st.expected, got)
+			}
+			continue
+		case stepCommand:
+			var settled bool
+			if out, settled = actor.Run(st.text); !settled {
+				fail(st, "the queue was still busy after %q", st.text)
+			}
+		case stepWait:
+			secs, _ := strconv.Atoi(st.text)
+			now = now.Add(time.Duration(secs) * time.Second)
+			if !g.DrainQueue() {
+				fail(st, "the queue was still busy after waiting")
+			}
+			out = actor.Output()
+		}
+		for i := range out {
+			out[i] = strings.TrimRight(out[i], " \r\n")
+		}
+		res.actual[st] = out
+		if !equalLines(out, st.want) {
+			fail(st, "%s\n    Expected:%s\n    Got:%s", st.text, indentLines(st.want), indentLines(out))
+		}
+	}
+	for _, cmd := range s.teardown {
+		actor.Run(cmd)
+	}
+	return res
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func indentLines(lines []string) string {
+	if len(lines) == 0 {
+		return " (nothing)"
+	}
+	return "\n      " + strings.Join(lines, "\n      ")
+}
+
+// updateGolden rewrites s's file with what its tests actually produced:
+// each command's < lines and each expression's | result. Everything
+// else, comments included, is kept. It reports whether anything changed.
+func updateGolden(s *suite, results []testResult) (bool, error) {
+	byLine := make(map[int]*step)
+	actual := make(map[*step][]string)
+	for _, tc := range s.tests {
+		for _, st := range tc.steps {
+			byLine[st.line] = st
+		}
+	}
+	for _, res := range results {
+		for st, out := range res.actual {
+			actual[st] = out
+		}
+	}
+	var lines []string
+	for i := 0; i < len(s.lines); i++ {
+		st := byLine[i]
+		out, ran := actual[st]
+		if st == nil || !ran {
+			lines = append(lines, s.lines[i])
+			continue
+		}
+		raw := s.lines[i]
+		indent := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+		if st.kind == stepExpr {
+			lines = append(lines, indent+st.text+" | "+out[0])
+			continue
+		}
+		lines = append(lines, raw)
+		for _, o := range out {
+			lines = append(lines, strings.TrimRight(indent+"< "+o, " "))
+		}
+		// Blank lines and comments among the old < lines are kept.
+		for j := i + 1; j < st.end; j++ {
+			if t := strings.TrimSpace(s.lines[j]); t == "" || strings.HasPrefix(t, "#") {
+				lines = append(lines, s.lines[j])
+			}
+		}
+		i = st.end - 1
+	}
+	text := strings.Join(lines, "\n") + "\n"
+	if text == strings.Join(s.lines, "\n")+"\n" {
+		return false, nil
+	}
+	return true, os.WriteFile(s.path, []byte(text), 0644)
+}
+
+// runSuites runs every suite under path and reports the results, as JUnit
+// XML to junitPath if it is set. It returns how many tests failed.
+func runSuites(path, junitPath string, opts suiteOptions) (int, error) {
+	files, err := findSuites(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, fmt.Errorf("no .mush suites in %s", path)
+	}
+	var all [][]testResult
+	var suites []*suite
+	passed, failed := 0, 0
+	for _, file := range files {
+		s, err := parseSuite(file, opts.player)
+		if err != nil {
+			return failed, err
+		}
+		var results []testResult
+		for _, tc := range s.tests {
+			res := runTest(s, tc, opts)
+			results = append(results, res)
+			if len(res.failures) == 0 {
+				passed++
+				if opts.verbose {
+					fmt.Printf("[PASS] %s: %s\n", s.name, tc.name)
+				}
+				continue
+			}
+			if opts.golden {
+				continue
+			}
+			failed++
+			fmt.Printf("[FAIL] %s: %s\n", s.name, tc.name)
+			for _, f := range res.failures {
+				fmt.Printf("  %s\n", f)
+			}
+		}
+		if opts.golden {
+			changed, err := updateGolden(s, results)
+			if err != nil {
+				return failed, err
+			}
+			if changed {
+				fmt.Printf("Updated %s\n", file)
+			}
+		}
+		suites = append(suites, s)
+		all = append(all, results)
+	}
+	if !opts.golden {
+		fmt.Printf("%d passed, %d failed\n", passed, failed)
+	}
+	if junitPath != "" {
+		if err := writeJUnit(junitPath, suites, all); err != nil {
+			return failed, err
+		}
+	}
+	return failed, nil
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -8,9 +11,11 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/crystal-mush/gotinymush/pkg/admin"
@@ -19,6 +24,7 @@ import (
 	mushcrypt "github.com/crystal-mush/gotinymush/pkg/crypt"
 	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
 	"github.com/crystal-mush/gotinymush/pkg/flatfile"
+	"github.com/crystal-mush/gotinymush/pkg/replication"
 	"github.com/crystal-mush/gotinymush/pkg/server"
 	"gopkg.in/yaml.v3"
 )
@@ -39,7 +45,8 @@ func main() {
 	textDir := flag.String("textdir", envDefault("MUSH_TEXTDIR", ""), "Path to text files directory (env: MUSH_TEXTDIR)")
 	aliasConf := flag.String("aliasconf", envDefault("MUSH_ALIASCONF", ""), "Path to alias config file(s), comma-separated (env: MUSH_ALIASCONF)")
 	confFile := flag.String("conf", envDefault("MUSH_CONF", ""), "Path to game config file (env: MUSH_CONF)")
-	comsysDB := flag.String("comsysdb", envDefault("MUSH_COMSYSDB", ""), "Path to mod_comsys.db file for channel import (env: MUSH_COMSYSDB)")
+	comsysDB := flag.String("comsysdb", envDefault("MUSH_COMSYSDB", ""), "Path to mod_comsys.db or PennMUSH chatdb for channel import (env: MUSH_COMSYSDB)")
+	mailDB := flag.String("maildb", envDefault("MUSH_MAILDB", ""), "Path to TinyMUX mail.db or PennMUSH maildb for mail import (env: MUSH_MAILDB)")
 	dictDir := flag.String("dictdir", envDefault("MUSH_DICTDIR", ""), "Path to dictionary directory (env: MUSH_DICTDIR)")
 	sqlDBPath := flag.String("sqldb", envDefault("MUSH_SQLDB", ""), "Path to SQLite3 database file (env: MUSH_SQLDB)")
 	fresh := flag.Bool("fresh", os.Getenv("MUSH_FRESH") == "true", "Delete bolt DB on startup for a clean reimport every restart (env: MUSH_FRESH)")
@@ -47,6 +54,8 @@ func main() {
 	tlsKey := flag.String("tls-key", envDefault("MUSH_TLS_KEY", ""), "Path to TLS private key file (env: MUSH_TLS_KEY)")
 	tlsPort := flag.String("tls-port", envDefault("MUSH_TLS_PORT", ""), "TLS listen port (env: MUSH_TLS_PORT)")
 	restoreArchive := flag.String("restore", envDefault("MUSH_RESTORE", ""), "Restore from archive before boot (env: MUSH_RESTORE)")
+	restoreKey := flag.String("restore-key", envDefault("MUSH_RESTORE_KEY", ""), "age identity file for restoring an encrypted archive (env: MUSH_RESTORE_KEY)")
+	standbyOf := flag.String("standby", envDefault("MUSH_STANDBY", ""), "Run as a warm standby, replicating the primary at host:port into -bolt (env: MUSH_STANDBY)")
 	godPass := flag.String("godpass", envDefault("MUSH_GODPASS", ""), "Set God (#1) password and exit (env: MUSH_GODPASS)")
 	debugFlag := flag.Bool("debug", os.Getenv("MUSH_DEBUG") == "true", "Enable debug logging (env: MUSH_DEBUG)")
 	flag.Parse()
@@ -99,6 +108,19 @@ func main() {
 		}
 	}
 
+	// Warm standby: mirror a primary's bolt store instead of running a game
+	if *standbyOf != "" {
+		if *boltPath == "" {
+			dataDir := "/game/data"
+			if *confFile != "" {
+				dataDir = filepath.Dir(*confFile)
+			}
+			*boltPath = filepath.Join(dataDir, "game.bolt")
+		}
+		runStandby(*standbyOf, *boltPath, gc)
+		return
+	}
+
 	setupMode := *dbPath == "" && *boltPath == ""
 	if setupMode {
 		log.Printf("No database specified — starting in setup mode (admin panel only)")
@@ -113,8 +135,13 @@ func main() {
 	// Pre-boot restore from archive
 	if *restoreArchive != "" {
 		log.Printf("Restoring from archive: %s", *restoreArchive)
+		// An encrypted archive is opened with -restore-key, or else the
+		// passphrase from MUSH_RESTORE_PASSPHRASE or archive_passphrase.
+		passphrase := envDefault("MUSH_RESTORE_PASSPHRASE", gc.ArchivePassphrase)
 		result, err := archive.RestoreArchive(archive.RestoreParams{
-			ArchivePath: *restoreArchive,
+			ArchivePath:  *restoreArchive,
+			Passphrase:   passphrase,
+			IdentityFile: *restoreKey,
 			BoltDest:    *boltPath,
 			SQLDest:     *sqlDBPath,
 			DictDest:    *dictDir,
@@ -232,6 +259,9 @@ func main() {
 		if !needImport && store.HasData() {
 			// Normal run: load from bbolt
 			log.Printf("Loading database from bbolt: %s", *boltPath)
+			if gc.LazyLoad {
+				store.SetLazy(server.ColdObject(time.Duration(gc.LazyIdleDays)*24*time.Hour), gc.LazyCache)
+			}
 			if err := store.LoadAll(); err != nil {
 				log.Fatalf("Error loading from bolt: %v", err)
 			}
@@ -247,11 +277,12 @@ func main() {
 			if err != nil {
 				log.Fatalf("Error opening flatfile: %v", err)
 			}
-			db, err := flatfile.Parse(f)
+			db, rep, err := flatfile.Import(f)
 			f.Close()
 			if err != nil {
 				log.Fatalf("Error parsing flatfile: %v", err)
 			}
+			logImportReport(rep)
 			if err := store.ImportFromDatabase(db); err != nil {
 				log.Fatalf("Error importing into bolt: %v", err)
 			}
@@ -268,11 +299,12 @@ func main() {
 		if err != nil {
 			log.Fatalf("Error opening database: %v", err)
 		}
-		db, err := flatfile.Parse(f)
+		db, rep, err := flatfile.Import(f)
 		f.Close()
 		if err != nil {
 			log.Fatalf("Error parsing database: %v", err)
 		}
+		logImportReport(rep)
 		log.Printf("Database loaded: %d objects, %d attribute definitions",
 			len(db.Objects), len(db.AttrNames))
 
@@ -364,7 +396,7 @@ func main() {
 
 	// Load mail system if enabled
 	if gc.MailEnabled {
-		loadMail(srv.Game, store, gc.MailExpiration)
+		loadMail(srv.Game, store, gc.MailExpiration, *mailDB)
 	} else {
 		log.Printf("Mail system disabled by config")
 	}
@@ -372,6 +404,50 @@ func main() {
 	// Load structures from bbolt
 	loadStructures(store)
 
+	// Load @site rules from bbolt
+	loadSites(srv.Game, store)
+
+	// Load @aliases entries from bbolt, over those from the alias config
+	loadConfAliases(srv.Game, store)
+	loadDoings(srv.Game, store)
+
+	// Load recorded scenes from bbolt
+	loadScenes(srv.Game, store)
+
+	// Load pending character registrations from bbolt
+	loadRegistrations(srv.Game, store)
+	loadConnLog(srv.Game, store)
+	loadCron(srv.Game, store)
+	loadQueue(srv.Game, store)
+	loadRuntimeSettings(srv.Game, store)
+
+	// Batch object writes now that loading is done
+	if store != nil {
+		store.StartWriteBehind(time.Duration(gc.WriteBehind) * time.Millisecond)
+	}
+
+	// Stream bolt writes to warm standbys
+	if store != nil && gc.ReplicationListen != "" {
+		secret := envDefault("MUSH_REPLICATION_SECRET", gc.ReplicationSecret)
+		if secret == "" {
+			log.Fatalf("replication_listen is set but replication_secret is empty")
+		}
+		var tlsConf *tls.Config
+		if gc.ReplicationTLS {
+			cert, err := tls.LoadX509KeyPair(gc.TLSCert, gc.TLSKey)
+			if err != nil {
+				log.Fatalf("Replication TLS needs tls_cert and tls_key: %v", err)
+			}
+			tlsConf = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		}
+		primary := replication.NewPrimary(store, secret)
+		if err := primary.Listen(gc.ReplicationListen, tlsConf); err != nil {
+			log.Fatalf("%v", err)
+		}
+		srv.Game.Replication = primary
+		log.Printf("Replication: accepting standbys on %s", gc.ReplicationListen)
+	}
+
 	// Store paths on Game for archive system
 	srv.Game.ConfPath = *confFile
 	srv.Game.AliasConfs = aliasPaths
@@ -393,6 +469,18 @@ func main() {
 
 	// Run @startup actions
 	srv.Game.RunStartup()
+	srv.Game.StartCron()
+	srv.Game.StartMaintenance()
+	if bridge := server.NewIRCBridge(srv.Game); bridge != nil {
+		srv.Game.IRC = bridge
+		bridge.Start()
+		log.Printf("IRC bridge enabled: %s", gc.IRCServer)
+	}
+	if relay := server.NewDiscordRelay(srv.Game); relay != nil {
+		srv.Game.Discord = relay
+		relay.Start()
+		log.Printf("Discord relay enabled")
+	}
 
 	// Start auto-archive if configured
 	if gc.ArchiveInterval > 0 {
@@ -408,11 +496,56 @@ func main() {
 	} else {
 		log.Printf("Starting %s on port %d...", gc.MudName, cfg.Port)
 	}
+	// SIGTERM/SIGINT shut down cleanly; SIGHUP reloads config and text files
+	srv.HandleSignals()
+
 	if err := srv.Start(); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
 
+// runStandby follows a primary's replication stream into boltPath until
+// SIGTERM or SIGINT. To fail over, stop it and start the game on boltPath.
+func runStandby(primary, boltPath string, gc *server.GameConf) {
+	secret := envDefault("MUSH_REPLICATION_SECRET", gc.ReplicationSecret)
+	if secret == "" {
+		log.Fatalf("Standby mode needs replication_secret (or MUSH_REPLICATION_SECRET)")
+	}
+	sb := &replication.Standby{Primary: primary, Secret: secret, BoltPath: boltPath}
+	if gc.ReplicationTLS {
+		sb.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		if gc.ReplicationCA != "" {
+			pem, err := os.ReadFile(gc.ReplicationCA)
+			if err != nil {
+				log.Fatalf("Replication CA: %v", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				log.Fatalf("Replication CA: no certificates in %s", gc.ReplicationCA)
+			}
+			sb.TLS.RootCAs = pool
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	log.Printf("Standby mode: replicating %s into %s", primary, boltPath)
+	sb.Run(ctx)
+	log.Printf("Standby stopped at batch %d; %s is ready to boot", sb.Status().Seq, boltPath)
+}
+
+// logImportReport logs what a flatfile from another server couldn't
+// carry over. TinyMUSH flatfiles import whole and log nothing.
+func logImportReport(rep *flatfile.Report) {
+	if len(rep.Dropped) == 0 {
+		return
+	}
+	log.Printf("Converted %s flatfile (%d objects); not carried over:", rep.Format, rep.Objects)
+	for _, line := range rep.Lines() {
+		log.Printf("  %s", line)
+	}
+}
+
 // loadComsys initializes the channel system from bbolt or mod_comsys.db.
 func loadComsys(game *server.Game, store *boltstore.Store, comsysPath string) {
 	cs := server.NewComsys()
@@ -429,6 +562,11 @@ func loadComsys(game *server.Game, store *boltstore.Store, comsysPath string) {
 		}
 		if len(channels) > 0 {
 			cs.LoadChannels(channels, aliases)
+			if history, err := store.LoadChanMessages(); err != nil {
+				log.Printf("WARNING: failed to load channel history from bolt: %v", err)
+			} else {
+				cs.LoadLog(history)
+			}
 			game.Comsys = cs
 			return
 		}
@@ -446,12 +584,13 @@ func loadComsys(game *server.Game, store *boltstore.Store, comsysPath string) {
 	}
 	defer f.Close()
 
-	channels, aliases, err := flatfile.ParseComsys(f)
+	channels, aliases, rep, err := flatfile.ImportComsys(f)
 	if err != nil {
 		log.Printf("WARNING: failed to parse comsys db %s: %v", comsysPath, err)
 		return
 	}
 	log.Printf("Parsed comsys: %d channels, %d aliases from %s", len(channels), len(aliases), comsysPath)
+	logImportReport(rep)
 
 	// Store in bbolt for future loads
 	if store != nil {
@@ -491,11 +630,171 @@ func loadStructures(store *boltstore.Store) {
 	log.Printf("Loaded %d structure defs, %d instances from bolt", defCount, instCount)
 }
 
+// loadSites populates the site access rules from bbolt.
+func loadSites(game *server.Game, store *boltstore.Store) {
+	if store == nil {
+		return
+	}
+	rules, err := store.LoadSiteRules()
+	if err != nil {
+		log.Printf("WARNING: failed to load site rules from bolt: %v", err)
+		return
+	}
+	if len(rules) > 0 {
+		game.Sites.LoadRules(rules)
+		log.Printf("Loaded %d site rules from bolt", len(rules))
+	}
+}
+
+// loadConfAliases applies the aliases added in-game with @aliases.
+func loadConfAliases(game *server.Game, store *boltstore.Store) {
+	if store == nil {
+		return
+	}
+	aliases, err := store.LoadConfAliases()
+	if err != nil {
+		log.Printf("WARNING: failed to load aliases from bolt: %v", err)
+		return
+	}
+	if len(aliases) > 0 {
+		game.LoadConfAliases(aliases)
+		log.Printf("Loaded %d in-game aliases from bolt", len(aliases))
+	}
+}
+
+// loadDoings restores the players' @doing messages and the WHO poll.
+func loadDoings(game *server.Game, store *boltstore.Store) {
+	if store == nil {
+		return
+	}
+	doings, err := store.LoadDoings()
+	if err != nil {
+		log.Printf("WARNING: failed to load @doing messages from bolt: %v", err)
+		return
+	}
+	poll, err := store.GetPoll()
+	if err != nil {
+		log.Printf("WARNING: failed to load WHO poll from bolt: %v", err)
+	}
+	game.LoadDoings(doings, poll)
+}
+
+// loadRegistrations populates the character registration queue from bbolt.
+func loadRegistrations(game *server.Game, store *boltstore.Store) {
+	if store == nil {
+		return
+	}
+	regs, err := store.LoadRegistrations()
+	if err != nil {
+		log.Printf("WARNING: failed to load registrations from bolt: %v", err)
+		return
+	}
+	if len(regs) > 0 {
+		game.Registrations.LoadRegistrations(regs)
+		log.Printf("Loaded %d pending registrations from bolt", len(regs))
+	}
+}
+
+// loadConnLog populates the @last connection history from bbolt.
+func loadConnLog(game *server.Game, store *boltstore.Store) {
+	if store == nil {
+		return
+	}
+	recs, err := store.LoadConnRecords()
+	if err != nil {
+		log.Printf("WARNING: failed to load connection history from bolt: %v", err)
+		return
+	}
+	if len(recs) > 0 {
+		game.ConnLog.Load(recs)
+		log.Printf("Loaded %d connection records from bolt", len(recs))
+	}
+}
+
+// loadCron restores the @cron schedule from bbolt.
+func loadCron(game *server.Game, store *boltstore.Store) {
+	if store == nil {
+		return
+	}
+	jobs, err := store.LoadCronJobs()
+	if err != nil {
+		log.Printf("WARNING: failed to load cron jobs from bolt: %v", err)
+		return
+	}
+	if len(jobs) > 0 {
+		game.Cron.Load(jobs)
+		log.Printf("Loaded %d cron jobs from bolt", len(jobs))
+	}
+}
+
+// loadRuntimeSettings applies the MOTDs, @functions, @helptext entries and
+// @admin/save values set in-game.
+func loadRuntimeSettings(game *server.Game, store *boltstore.Store) {
+	if store == nil {
+		return
+	}
+	settings, err := store.LoadRuntimeSettings()
+	if err != nil {
+		log.Printf("WARNING: failed to load runtime settings from bolt: %v", err)
+		return
+	}
+	if len(settings) > 0 {
+		game.LoadRuntimeSettings(settings)
+		log.Printf("Loaded %d runtime settings from bolt", len(settings))
+	}
+}
+
+// loadQueue restores the @wait and semaphore queues saved at the last
+// shutdown or dump, then clears them from bbolt so a crash can't run them
+// twice.
+func loadQueue(game *server.Game, store *boltstore.Store) {
+	if store == nil {
+		return
+	}
+	saved, err := store.LoadQueue()
+	if err != nil {
+		log.Printf("WARNING: failed to load the command queue from bolt: %v", err)
+		return
+	}
+	if len(saved) == 0 {
+		return
+	}
+	n := game.RestoreQueue(saved)
+	log.Printf("Restored %d of %d queued commands from bolt", n, len(saved))
+	if err := store.SaveQueue(nil); err != nil {
+		log.Printf("WARNING: failed to clear the saved command queue: %v", err)
+	}
+}
+
+// loadScenes starts the scene recorder and loads recorded scenes from bbolt.
+func loadScenes(game *server.Game, store *boltstore.Store) {
+	key, err := server.SceneMasterKey(game)
+	if err != nil {
+		log.Printf("WARNING: scene logging disabled: %v", err)
+		return
+	}
+	game.Scenes = server.NewSceneRecorder(game, key)
+	if store != nil {
+		scenes, err := store.LoadScenes()
+		if err != nil {
+			log.Printf("WARNING: failed to load scenes from bolt: %v", err)
+		} else if len(scenes) > 0 {
+			game.Scenes.LoadScenes(scenes)
+			log.Printf("Loaded %d scenes from bolt", len(scenes))
+		}
+	}
+	if n := game.Scenes.PurgeExpired(time.Duration(game.Conf.SceneRetention) * 24 * time.Hour); n > 0 {
+		log.Printf("Purged %d expired scenes", n)
+	}
+	server.StartSceneRetention(game)
+}
+
 // loadMail initializes the mail system from bbolt.
-func loadMail(game *server.Game, store *boltstore.Store, expireDays int) {
+func loadMail(game *server.Game, store *boltstore.Store, expireDays int, mailPath string) {
 	m := server.NewMail(expireDays)
 
-	if store != nil && store.HasMailData() {
+	switch {
+	case store != nil && store.HasMailData():
 		msgs, err := store.LoadMail()
 		if err != nil {
 			log.Printf("WARNING: failed to load mail from bolt: %v", err)
@@ -507,12 +806,53 @@ func loadMail(game *server.Game, store *boltstore.Store, expireDays int) {
 			}
 			log.Printf("Loaded %d mail messages for %d players from bolt", total, len(msgs))
 		}
+	case mailPath != "":
+		importMail(m, store, mailPath)
+	}
+	if store != nil {
+		if aliases, err := store.LoadMailAliases(); err != nil {
+			log.Printf("WARNING: failed to load mail aliases from bolt: %v", err)
+		} else {
+			m.LoadAliases(aliases)
+		}
 	}
 
 	game.Mail = m
 	log.Printf("Mail system enabled (expiration: %d days)", expireDays)
 }
 
+// importMail loads a TinyMUX or PennMUSH mail database, saving it to
+// bbolt so later boots load it from there.
+func importMail(m *server.Mail, store *boltstore.Store, mailPath string) {
+	f, err := os.Open(mailPath)
+	if err != nil {
+		log.Printf("WARNING: cannot open mail db %s: %v", mailPath, err)
+		return
+	}
+	defer f.Close()
+
+	msgs, aliases, rep, err := flatfile.ImportMail(f)
+	if err != nil {
+		log.Printf("WARNING: failed to parse mail db %s: %v", mailPath, err)
+		return
+	}
+	log.Printf("Parsed mail: %d messages for %d players, %d aliases from %s", rep.Objects, len(msgs), len(aliases), mailPath)
+	logImportReport(rep)
+
+	if store != nil {
+		if err := store.ImportMail(msgs); err != nil {
+			log.Printf("WARNING: failed to import mail into bolt: %v", err)
+		}
+		for i := range aliases {
+			if err := store.PutMailAlias(&aliases[i]); err != nil {
+				log.Printf("WARNING: failed to import mail alias %s into bolt: %v", aliases[i].Name, err)
+			}
+		}
+	}
+	m.LoadMessages(msgs)
+	m.LoadAliases(aliases)
+}
+
 // startSetupMode runs the server in setup-only mode: just the admin panel web server,
 // no game engine, no telnet listeners. Used when no database is configured yet.
 func startSetupMode(confFile string, port int, gc *server.GameConf, dataDir string) {
@@ -545,6 +885,15 @@ func startSetupMode(confFile string, port int, gc *server.GameConf, dataDir stri
 			"game_running":   false,
 		})
 	})
+	// Never ready: orchestrators shouldn't route players to setup mode.
+	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]any{
+			"ready":   false,
+			"reasons": []string{"setup mode: no game loaded"},
+		})
+	})
 
 	mux.Handle("/admin/", adminPanel.Handler("/admin"))
 
@@ -57,6 +57,23 @@ type ServerController interface {
 	WallAll(msg string)
 	CreateArchive() (string, error)
 	Shutdown()
+
+	// ReadConf re-reads the config file and applies what it can at runtime,
+	// returning a report of applied, skipped and invalid directives.
+	ReadConf() (any, error)
+
+	// Archives, and recovering single objects from one.
+	Archives() ([]map[string]any, error)
+	RestoreObjects(archive string, from, to int, overwrite bool) (map[string]any, error)
+
+	// Character registration queue.
+	PendingRegistrations() []map[string]any
+	ApproveRegistration(id int) (map[string]any, error)
+	RejectRegistration(id int) error
+
+	// Live console.
+	OpenConsole(wizard string) (Console, error)
+	RecentLog(n int) []string
 }
 
 // FileRole describes what role a discovered file plays in an import.
@@ -150,6 +167,10 @@ type Admin struct {
 
 	// Authentication
 	auth *adminAuth
+
+	// Open live consoles, by id
+	consoleMu sync.Mutex
+	consoles  map[string]*openConsole
 }
 
 // ShutdownStatus tracks the state of a pending graceful shutdown.
@@ -202,6 +223,7 @@ func (a *Admin) Handler(prefix string) http.Handler {
 
 	mux.HandleFunc("GET /api/config", a.handleGetConfig)
 	mux.HandleFunc("PUT /api/config", a.handlePutConfig)
+	mux.HandleFunc("POST /api/config/reload", a.handleReloadConfig)
 
 	// Import routes (existing)
 	mux.HandleFunc("POST /api/import/upload", a.handleImportUpload)
@@ -225,6 +247,19 @@ func (a *Admin) Handler(prefix string) http.Handler {
 	mux.HandleFunc("GET /api/server/shutdown", a.handleShutdownStatus)
 	mux.HandleFunc("DELETE /api/server/shutdown", a.handleShutdownCancel)
 
+	mux.HandleFunc("GET /api/archives", a.handleArchives)
+	mux.HandleFunc("POST /api/archives/restore-object", a.handleRestoreObjects)
+
+	mux.HandleFunc("GET /api/registrations", a.handleRegistrations)
+	mux.HandleFunc("POST /api/registrations/{id}/approve", a.handleRegistrationApprove)
+	mux.HandleFunc("DELETE /api/registrations/{id}", a.handleRegistrationReject)
+
+	mux.HandleFunc("POST /api/console", a.handleConsoleOpen)
+	mux.HandleFunc("GET /api/console/{id}/events", a.handleConsoleEvents)
+	mux.HandleFunc("POST /api/console/{id}/command", a.handleConsoleCommand)
+	mux.HandleFunc("DELETE /api/console/{id}", a.handleConsoleClose)
+	mux.HandleFunc("GET /api/logs", a.handleLogs)
+
 	mux.HandleFunc("GET /api/setup/status", a.handleSetupStatus)
 	mux.HandleFunc("POST /api/import/create-new", a.handleCreateNewDB)
 	mux.HandleFunc("POST /api/server/launch", a.handleServerLaunch)
@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"net/http"
+)
+
+// handleArchives lists the backup archives available to restore from.
+func (a *Admin) handleArchives(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.controller == nil {
+		writeError(w, http.StatusServiceUnavailable, "no server controller available")
+		return
+	}
+	archives, err := a.controller.Archives()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"archives": archives})
+}
+
+// handleRestoreObjects copies one object, or a range of them, out of an
+// archive into the live database, as @restore/object does.
+func (a *Admin) handleRestoreObjects(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.controller == nil {
+		writeError(w, http.StatusServiceUnavailable, "no server controller available")
+		return
+	}
+	var req struct {
+		Archive   string `json:"archive"` // filename, or "latest"
+		From      int    `json:"from"`
+		To        *int   `json:"to"` // default: same as from
+		Overwrite bool   `json:"overwrite"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	to := req.From
+	if req.To != nil {
+		to = *req.To
+	}
+	if req.From < 0 || to < req.From {
+		writeError(w, http.StatusBadRequest, "invalid dbref range")
+		return
+	}
+	result, err := a.controller.RestoreObjects(req.Archive, req.From, to, req.Overwrite)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -41,6 +41,22 @@ func (a *Admin) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (a *Admin) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.controller == nil {
+		writeError(w, http.StatusServiceUnavailable, "game is not running")
+		return
+	}
+	report, err := a.controller.ReadConf()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "config not reloaded: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
 func (a *Admin) handlePutConfig(w http.ResponseWriter, r *http.Request) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
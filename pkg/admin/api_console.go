@@ -0,0 +1,225 @@
+package admin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// consoleIdle is how long a console may go without a command or an
+// attached event stream before it is closed.
+const consoleIdle = 15 * time.Minute
+
+// openConsole is a console the panel has handed out, with what the reaper
+// needs to tell whether anyone is still using it.
+type openConsole struct {
+	Console
+	lastUsed time.Time
+	streams  int
+}
+
+// Console is a live session in the running game: commands typed into it
+// run as the wizard it was opened for, and everything that wizard would
+// see arrives on Output until the console is closed.
+type Console interface {
+	// Player returns the name and dbref of the wizard the console runs as.
+	Player() string
+	// Run executes a command as if the wizard had typed it.
+	Run(command string)
+	// Output delivers the console's output, one line at a time. It is
+	// closed when the console is.
+	Output() <-chan string
+	// Close disconnects the console from the game.
+	Close()
+}
+
+// handleConsoleOpen opens a console as the wizard named in the request.
+func (a *Admin) handleConsoleOpen(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Wizard string `json:"wizard"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Wizard) == "" {
+		writeError(w, http.StatusBadRequest, "wizard is required")
+		return
+	}
+
+	a.mu.RLock()
+	ctrl, setupMode := a.controller, a.setupMode
+	a.mu.RUnlock()
+	if ctrl == nil || setupMode {
+		writeError(w, http.StatusServiceUnavailable, "no game is running")
+		return
+	}
+	console, err := ctrl.OpenConsole(req.Wizard)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		console.Close()
+		writeError(w, http.StatusInternalServerError, "failed to open console")
+		return
+	}
+	id := hex.EncodeToString(b)
+	a.consoleMu.Lock()
+	if a.consoles == nil {
+		a.consoles = make(map[string]*openConsole)
+		go a.reapConsoles()
+	}
+	a.consoles[id] = &openConsole{Console: console, lastUsed: time.Now()}
+	a.consoleMu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{"id": id, "player": console.Player()})
+}
+
+// handleConsoleEvents streams a console's output as server-sent events,
+// one "data:" line per line of output, until the console is closed or
+// the client goes away. A client going away closes the console.
+func (a *Admin) handleConsoleEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	console := a.console(id)
+	if console == nil {
+		writeError(w, http.StatusNotFound, "no such console")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	a.consoleMu.Lock()
+	console.streams++
+	a.consoleMu.Unlock()
+	defer a.closeConsole(id)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	output := console.Output()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line, ok := <-output:
+			if !ok {
+				fmt.Fprint(w, "event: closed\ndata: \n\n")
+				flusher.Flush()
+				return
+			}
+			for _, l := range strings.Split(line, "\n") {
+				fmt.Fprintf(w, "data: %s\n", strings.TrimSuffix(l, "\r"))
+			}
+			fmt.Fprint(w, "\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// handleConsoleCommand runs a command in a console.
+func (a *Admin) handleConsoleCommand(w http.ResponseWriter, r *http.Request) {
+	console := a.console(r.PathValue("id"))
+	if console == nil {
+		writeError(w, http.StatusNotFound, "no such console")
+		return
+	}
+	var req struct {
+		Command string `json:"command"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Command) == "" {
+		writeError(w, http.StatusBadRequest, "command is required")
+		return
+	}
+	console.Run(req.Command)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleConsoleClose closes a console.
+func (a *Admin) handleConsoleClose(w http.ResponseWriter, r *http.Request) {
+	if !a.closeConsole(r.PathValue("id")) {
+		writeError(w, http.StatusNotFound, "no such console")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "closed"})
+}
+
+// handleLogs returns the most recent server log lines (?lines=N, default
+// 200).
+func (a *Admin) handleLogs(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.controller == nil {
+		writeError(w, http.StatusServiceUnavailable, "no server controller available")
+		return
+	}
+	n := 200
+	if s := r.URL.Query().Get("lines"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid line count")
+			return
+		}
+		n = v
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"lines": a.controller.RecentLog(n)})
+}
+
+// console returns the open console with the given id, or nil, and marks
+// it as in use.
+func (a *Admin) console(id string) *openConsole {
+	a.consoleMu.Lock()
+	defer a.consoleMu.Unlock()
+	c := a.consoles[id]
+	if c != nil {
+		c.lastUsed = time.Now()
+	}
+	return c
+}
+
+// closeConsole forgets and closes the console with the given id. It
+// reports false if there was no such console.
+func (a *Admin) closeConsole(id string) bool {
+	a.consoleMu.Lock()
+	c := a.consoles[id]
+	delete(a.consoles, id)
+	a.consoleMu.Unlock()
+	if c == nil {
+		return false
+	}
+	c.Close()
+	return true
+}
+
+// reapConsoles closes consoles that nobody is watching or typing into, so
+// a panel tab that was never closed doesn't leave a wizard connected.
+func (a *Admin) reapConsoles() {
+	for range time.Tick(time.Minute) {
+		var idle []string
+		a.consoleMu.Lock()
+		for id, c := range a.consoles {
+			if c.streams == 0 && time.Since(c.lastUsed) > consoleIdle {
+				idle = append(idle, id)
+			}
+		}
+		a.consoleMu.Unlock()
+		for _, id := range idle {
+			a.closeConsole(id)
+		}
+	}
+}
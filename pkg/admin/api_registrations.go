@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// handleRegistrations lists character requests awaiting approval.
+func (a *Admin) handleRegistrations(w http.ResponseWriter, r *http.Request) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.controller == nil {
+		writeError(w, http.StatusServiceUnavailable, "no server controller available")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"registrations": a.controller.PendingRegistrations(),
+	})
+}
+
+// handleRegistrationApprove creates the requested character.
+func (a *Admin) handleRegistrationApprove(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.controller == nil {
+		writeError(w, http.StatusServiceUnavailable, "no server controller available")
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid registration id")
+		return
+	}
+	result, err := a.controller.ApproveRegistration(id)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleRegistrationReject drops a character request.
+func (a *Admin) handleRegistrationReject(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.controller == nil {
+		writeError(w, http.StatusServiceUnavailable, "no server controller available")
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid registration id")
+		return
+	}
+	if err := a.controller.RejectRegistration(id); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "rejected"})
+}
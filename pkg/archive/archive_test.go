@@ -0,0 +1,159 @@
+package archive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"filippo.io/age"
+)
+
+// testArchive creates an archive holding one text file.
+func testArchive(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	textDir := filepath.Join(dir, "text")
+	if err := os.MkdirAll(textDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(textDir, "motd.txt"), []byte("Welcome!\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := CreateArchive(ArchiveParams{TextDir: textDir, ArchiveDir: filepath.Join(dir, "backups"), MudName: "Test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func restoredMOTD(t *testing.T, params RestoreParams) (string, error) {
+	t.Helper()
+	params.TextDest = t.TempDir()
+	if _, err := RestoreArchive(params); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(params.TextDest, "motd.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), nil
+}
+
+func TestEncryptPassphrase(t *testing.T) {
+	plain := testArchive(t)
+	path, err := EncryptArchive(plain, EncryptOptions{Passphrase: "swordfish"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != plain+EncryptedExt {
+		t.Errorf("encrypted to %s", path)
+	}
+	if _, err := os.Stat(plain); !os.IsNotExist(err) {
+		t.Errorf("unencrypted archive left behind: %v", err)
+	}
+
+	archives, err := ListArchives(filepath.Dir(path))
+	if err != nil || len(archives) != 1 || archives[0].Path != path {
+		t.Errorf("ListArchives: %+v, %v", archives, err)
+	}
+
+	if got, err := restoredMOTD(t, RestoreParams{ArchivePath: path, Passphrase: "swordfish"}); err != nil || got != "Welcome!\n" {
+		t.Errorf("restore: %q, %v", got, err)
+	}
+	if _, err := restoredMOTD(t, RestoreParams{ArchivePath: path, Passphrase: "wrong"}); err == nil {
+		t.Errorf("restore with the wrong passphrase succeeded")
+	}
+	if _, err := restoredMOTD(t, RestoreParams{ArchivePath: path}); err == nil {
+		t.Errorf("restore with no passphrase succeeded")
+	}
+}
+
+func TestEncryptRecipients(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(keyFile, []byte(id.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := EncryptArchive(testArchive(t), EncryptOptions{Passphrase: "ignored", Recipients: []string{id.Recipient().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := restoredMOTD(t, RestoreParams{ArchivePath: path, IdentityFile: keyFile}); err != nil || got != "Welcome!\n" {
+		t.Errorf("restore: %q, %v", got, err)
+	}
+	if _, err := restoredMOTD(t, RestoreParams{ArchivePath: path, Passphrase: "ignored"}); err == nil {
+		t.Errorf("recipient-encrypted archive opened with the passphrase")
+	}
+
+	if _, err := EncryptArchive(testArchive(t), EncryptOptions{Recipients: []string{"not-a-key"}}); err == nil {
+		t.Errorf("bad recipient accepted")
+	}
+}
+
+// memRemote is a Remote held in memory.
+type memRemote map[string]bool
+
+func (m memRemote) Upload(ctx context.Context, localPath string) error {
+	m[filepath.Base(localPath)] = true
+	return nil
+}
+
+func (m memRemote) List(ctx context.Context) ([]string, error) {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (m memRemote) Delete(ctx context.Context, name string) error {
+	delete(m, name)
+	return nil
+}
+
+func (m memRemote) String() string { return "mem" }
+
+func TestPruneRemote(t *testing.T) {
+	r := memRemote{
+		"archive-20261014-120000.tar.gz":     true,
+		"archive-20261015-120000.tar.gz.age": true,
+		"archive-20261016-120000.tar.gz.age": true,
+		"archive-20261013-120000.tar.gz":     true,
+		"notes.txt":                          true,
+	}
+	pruned, err := PruneRemote(context.Background(), r, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(pruned)
+	if want := []string{"archive-20261013-120000.tar.gz", "archive-20261014-120000.tar.gz"}; !reflect.DeepEqual(pruned, want) {
+		t.Errorf("pruned %v, want %v", pruned, want)
+	}
+	if len(r) != 3 || !r["notes.txt"] || !r["archive-20261016-120000.tar.gz.age"] {
+		t.Errorf("left %v", r)
+	}
+}
+
+func TestNewRemote(t *testing.T) {
+	r, err := NewRemote(RemoteConfig{URL: "s3://backups/mush/", S3AccessKey: "a", S3SecretKey: "b"})
+	if err != nil || r.String() != "s3://backups/mush/" {
+		t.Errorf("s3 remote %v, %v", r, err)
+	}
+	for _, bad := range []RemoteConfig{
+		{URL: "ftp://host/dir"},
+		{URL: "s3:///prefix"},
+		{URL: "sftp://host/dir", SFTPPassword: "x"},
+		{URL: "sftp://user@host/dir"},
+	} {
+		if _, err := NewRemote(bad); err == nil {
+			t.Errorf("NewRemote(%q) succeeded", bad.URL)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// EncryptedExt is appended to an archive's name when it is encrypted.
+const EncryptedExt = ".age"
+
+// EncryptOptions chooses how archives are encrypted. Recipients (age
+// public keys, "age1...") take precedence over Passphrase; with neither,
+// archives are left unencrypted.
+type EncryptOptions struct {
+	Passphrase string
+	Recipients []string
+}
+
+// Enabled reports whether the options encrypt anything.
+func (o EncryptOptions) Enabled() bool {
+	return o.Passphrase != "" || len(o.Recipients) > 0
+}
+
+func (o EncryptOptions) recipients() ([]age.Recipient, error) {
+	if len(o.Recipients) == 0 {
+		r, err := age.NewScryptRecipient(o.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Recipient{r}, nil
+	}
+	return age.ParseRecipients(strings.NewReader(strings.Join(o.Recipients, "\n")))
+}
+
+// EncryptArchive encrypts the archive at path with age, writing
+// path+".age" and removing the unencrypted file. It returns the new path.
+func EncryptArchive(path string, opts EncryptOptions) (string, error) {
+	recipients, err := opts.recipients()
+	if err != nil {
+		return "", fmt.Errorf("archive: encryption key: %w", err)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("archive: open %s: %w", path, err)
+	}
+	defer in.Close()
+
+	outPath := path + EncryptedExt
+	out, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("archive: create %s: %w", outPath, err)
+	}
+	w, err := age.Encrypt(out, recipients...)
+	if err == nil {
+		if _, err = io.Copy(w, in); err == nil {
+			err = w.Close()
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return "", fmt.Errorf("archive: encrypt %s: %w", path, err)
+	}
+
+	in.Close()
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("archive: remove unencrypted %s: %w", path, err)
+	}
+	return outPath, nil
+}
+
+// DecryptArchive decrypts an age-encrypted archive to destPath, using
+// either a passphrase or the age identities in identityFile.
+func DecryptArchive(path, destPath, passphrase, identityFile string) error {
+	var identities []age.Identity
+	switch {
+	case identityFile != "":
+		f, err := os.Open(identityFile)
+		if err != nil {
+			return fmt.Errorf("archive: open identity file: %w", err)
+		}
+		defer f.Close()
+		if identities, err = age.ParseIdentities(f); err != nil {
+			return fmt.Errorf("archive: identity file %s: %w", identityFile, err)
+		}
+	case passphrase != "":
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return fmt.Errorf("archive: passphrase: %w", err)
+		}
+		identities = []age.Identity{id}
+	default:
+		return fmt.Errorf("archive: %s is encrypted; a passphrase or identity file is needed", path)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("archive: open %s: %w", path, err)
+	}
+	defer in.Close()
+	r, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return fmt.Errorf("archive: decrypt %s: %w", path, err)
+	}
+
+	out, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("archive: create %s: %w", destPath, err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("archive: decrypt %s: %w", path, err)
+	}
+	return out.Close()
+}
+
+// IsEncrypted reports whether path names an encrypted archive.
+func IsEncrypted(path string) bool {
+	return strings.HasSuffix(path, EncryptedExt)
+}
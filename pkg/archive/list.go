@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"time"
 )
 
 // ArchiveInfo holds metadata about an existing archive file.
@@ -21,14 +22,16 @@ type ArchiveInfo struct {
 	Objects   int    // From manifest
 }
 
-// ListArchives scans an archive directory for .tar.gz files and returns info
-// about each, sorted newest-first.
+// ListArchives scans an archive directory for .tar.gz and encrypted
+// .tar.gz.age files and returns info about each, sorted newest-first.
 func ListArchives(archiveDir string) ([]ArchiveInfo, error) {
 	pattern := filepath.Join(archiveDir, "*.tar.gz")
 	matches, err := filepath.Glob(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("archive: glob %s: %w", pattern, err)
 	}
+	encrypted, _ := filepath.Glob(pattern + EncryptedExt)
+	matches = append(matches, encrypted...)
 
 	var archives []ArchiveInfo
 	for _, path := range matches {
@@ -44,8 +47,12 @@ func ListArchives(archiveDir string) ([]ArchiveInfo, error) {
 			Timestamp: info.ModTime().Format("2006-01-02 15:04:05"),
 		}
 
-		// Try to read manifest for richer metadata
-		if m, err := readManifest(path); err == nil {
+		// Read the manifest for richer metadata. An encrypted archive's
+		// can't be read without the key, so give its file time in the
+		// manifest's format for the two to sort together.
+		if IsEncrypted(path) {
+			ai.Timestamp = info.ModTime().UTC().Format(time.RFC3339)
+		} else if m, err := readManifest(path); err == nil {
 			ai.Timestamp = m.Timestamp
 			ai.MudName = m.MudName
 			ai.Objects = m.Objects
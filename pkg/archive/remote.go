@@ -0,0 +1,315 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// RemoteConfig describes where completed archives are uploaded. URL is
+// "s3://bucket/prefix" for S3 or an S3-compatible store, or
+// "sftp://user@host[:port]/dir".
+type RemoteConfig struct {
+	URL string
+
+	S3Endpoint  string // host[:port], or http://host:port for plain HTTP (default s3.amazonaws.com)
+	S3Region    string
+	S3AccessKey string // empty = AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY
+	S3SecretKey string
+
+	SFTPPassword   string
+	SFTPKeyFile    string // private key file
+	SFTPKnownHosts string // known_hosts file (default ~/.ssh/known_hosts)
+}
+
+// Remote is an upload target for archives.
+type Remote interface {
+	// Upload copies a local archive to the remote under its base name.
+	Upload(ctx context.Context, localPath string) error
+	// List returns the names of the archives on the remote.
+	List(ctx context.Context) ([]string, error)
+	// Delete removes one archive by name.
+	Delete(ctx context.Context, name string) error
+	// String describes the remote for logs, without credentials.
+	String() string
+}
+
+// NewRemote returns the Remote cfg.URL names.
+func NewRemote(cfg RemoteConfig) (Remote, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("archive: remote %q: %w", cfg.URL, err)
+	}
+	switch u.Scheme {
+	case "s3":
+		return newS3Remote(cfg, u)
+	case "sftp":
+		return newSFTPRemote(cfg, u)
+	}
+	return nil, fmt.Errorf("archive: remote %q: scheme must be s3 or sftp", cfg.URL)
+}
+
+// isArchiveName reports whether name looks like an archive CreateArchive
+// wrote, so pruning never touches anything else in the remote directory.
+func isArchiveName(name string) bool {
+	return strings.HasPrefix(name, "archive-") &&
+		(strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tar.gz"+EncryptedExt))
+}
+
+// PruneRemote deletes all but the newest keep archives on r. Archive
+// names carry their creation time, so name order is age order.
+func PruneRemote(ctx context.Context, r Remote, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	names, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var archives []string
+	for _, name := range names {
+		if isArchiveName(name) {
+			archives = append(archives, name)
+		}
+	}
+	if len(archives) <= keep {
+		return nil, nil
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(archives)))
+	var pruned []string
+	for _, name := range archives[keep:] {
+		if err := r.Delete(ctx, name); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, name)
+	}
+	return pruned, nil
+}
+
+// --- S3 ---
+
+type s3Remote struct {
+	client *minio.Client
+	bucket string
+	prefix string // "" or ending in "/"
+}
+
+func newS3Remote(cfg RemoteConfig, u *url.URL) (*s3Remote, error) {
+	endpoint, secure := cfg.S3Endpoint, true
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+	if rest, ok := strings.CutPrefix(endpoint, "http://"); ok {
+		endpoint, secure = rest, false
+	}
+	endpoint = strings.TrimPrefix(endpoint, "https://")
+
+	creds := credentials.NewEnvAWS()
+	if cfg.S3AccessKey != "" {
+		creds = credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, "")
+	}
+	client, err := minio.New(endpoint, &minio.Options{Creds: creds, Secure: secure, Region: cfg.S3Region})
+	if err != nil {
+		return nil, fmt.Errorf("archive: s3 client: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("archive: remote %q names no bucket", cfg.URL)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &s3Remote{client: client, bucket: u.Host, prefix: prefix}, nil
+}
+
+func (r *s3Remote) Upload(ctx context.Context, localPath string) error {
+	key := r.prefix + filepath.Base(localPath)
+	if _, err := r.client.FPutObject(ctx, r.bucket, key, localPath, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("archive: upload to %s: %w", r, err)
+	}
+	return nil
+}
+
+func (r *s3Remote) List(ctx context.Context) ([]string, error) {
+	var names []string
+	for obj := range r.client.ListObjects(ctx, r.bucket, minio.ListObjectsOptions{Prefix: r.prefix}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("archive: list %s: %w", r, obj.Err)
+		}
+		names = append(names, strings.TrimPrefix(obj.Key, r.prefix))
+	}
+	return names, nil
+}
+
+func (r *s3Remote) Delete(ctx context.Context, name string) error {
+	if err := r.client.RemoveObject(ctx, r.bucket, r.prefix+name, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("archive: delete %s from %s: %w", name, r, err)
+	}
+	return nil
+}
+
+func (r *s3Remote) String() string {
+	return "s3://" + r.bucket + "/" + r.prefix
+}
+
+// --- SFTP ---
+
+// sftpRemote connects for each operation; archives are infrequent and a
+// held connection would go stale between them.
+type sftpRemote struct {
+	addr   string
+	dir    string
+	config *ssh.ClientConfig
+}
+
+func newSFTPRemote(cfg RemoteConfig, u *url.URL) (*sftpRemote, error) {
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("archive: remote %q names no user", cfg.URL)
+	}
+	var auth []ssh.AuthMethod
+	if cfg.SFTPKeyFile != "" {
+		key, err := os.ReadFile(cfg.SFTPKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("archive: sftp key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("archive: sftp key %s: %w", cfg.SFTPKeyFile, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.SFTPPassword != "" {
+		auth = append(auth, ssh.Password(cfg.SFTPPassword))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("archive: remote %q needs an sftp key or password", cfg.URL)
+	}
+
+	known := cfg.SFTPKnownHosts
+	if known == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("archive: sftp known_hosts: %w", err)
+		}
+		known = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeys, err := knownhosts.New(known)
+	if err != nil {
+		return nil, fmt.Errorf("archive: sftp known_hosts: %w", err)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "22")
+	}
+	return &sftpRemote{
+		addr: addr,
+		dir:  u.Path,
+		config: &ssh.ClientConfig{
+			User:            u.User.Username(),
+			Auth:            auth,
+			HostKeyCallback: hostKeys,
+			Timeout:         30 * time.Second,
+		},
+	}, nil
+}
+
+// with runs fn on a fresh SFTP session.
+func (r *sftpRemote) with(fn func(*sftp.Client) error) error {
+	conn, err := ssh.Dial("tcp", r.addr, r.config)
+	if err != nil {
+		return fmt.Errorf("archive: connect %s: %w", r, err)
+	}
+	defer conn.Close()
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return fmt.Errorf("archive: sftp %s: %w", r, err)
+	}
+	defer client.Close()
+	return fn(client)
+}
+
+func (r *sftpRemote) Upload(ctx context.Context, localPath string) error {
+	return r.with(func(c *sftp.Client) error {
+		if r.dir != "" {
+			if err := c.MkdirAll(r.dir); err != nil {
+				return fmt.Errorf("archive: mkdir %s: %w", r, err)
+			}
+		}
+		in, err := os.Open(localPath)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		// Write under a temporary name and rename, so a dropped
+		// connection never leaves a partial archive that looks whole.
+		final := path.Join(r.dir, filepath.Base(localPath))
+		tmp := final + ".part"
+		out, err := c.Create(tmp)
+		if err != nil {
+			return fmt.Errorf("archive: upload to %s: %w", r, err)
+		}
+		_, err = io.Copy(out, in)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			c.Remove(final)
+			err = c.Rename(tmp, final)
+		}
+		if err != nil {
+			c.Remove(tmp)
+			return fmt.Errorf("archive: upload to %s: %w", r, err)
+		}
+		return nil
+	})
+}
+
+func (r *sftpRemote) List(ctx context.Context) ([]string, error) {
+	var names []string
+	err := r.with(func(c *sftp.Client) error {
+		dir := r.dir
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := c.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("archive: list %s: %w", r, err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+		return nil
+	})
+	return names, err
+}
+
+func (r *sftpRemote) Delete(ctx context.Context, name string) error {
+	return r.with(func(c *sftp.Client) error {
+		if err := c.Remove(path.Join(r.dir, name)); err != nil {
+			return fmt.Errorf("archive: delete %s from %s: %w", name, r, err)
+		}
+		return nil
+	})
+}
+
+func (r *sftpRemote) String() string {
+	return "sftp://" + r.config.User + "@" + r.addr + r.dir
+}
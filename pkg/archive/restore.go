@@ -16,15 +16,17 @@ import (
 
 // RestoreParams holds all inputs needed to restore an archive.
 type RestoreParams struct {
-	ArchivePath string    // Path to the .tar.gz archive
-	BoltDest    string    // Destination path for bolt database
-	SQLDest     string    // Destination path for SQLite database (empty = skip)
-	DictDest    string    // Destination directory for dictionary files (empty = skip)
-	TextDest    string    // Destination directory for text files (empty = skip)
-	ConfDest    string    // Destination path for main config file (empty = skip)
-	AliasDest   string    // Destination directory for alias config files (empty = skip)
-	Stdin       io.Reader // For interactive prompts
-	Stdout      io.Writer // For interactive output
+	ArchivePath  string    // Path to the .tar.gz or encrypted .tar.gz.age archive
+	Passphrase   string    // Passphrase for an encrypted archive
+	IdentityFile string    // age identity file for an encrypted archive (instead of Passphrase)
+	BoltDest     string    // Destination path for bolt database
+	SQLDest      string    // Destination path for SQLite database (empty = skip)
+	DictDest     string    // Destination directory for dictionary files (empty = skip)
+	TextDest     string    // Destination directory for text files (empty = skip)
+	ConfDest     string    // Destination path for main config file (empty = skip)
+	AliasDest    string    // Destination directory for alias config files (empty = skip)
+	Stdin        io.Reader // For interactive prompts
+	Stdout       io.Writer // For interactive output
 }
 
 // RestoreResult summarizes a completed restore operation.
@@ -44,8 +46,17 @@ func RestoreArchive(params RestoreParams) (*RestoreResult, error) {
 	}
 	defer os.RemoveAll(tmpDir)
 
+	// Decrypt first if needed
+	archivePath := params.ArchivePath
+	if IsEncrypted(archivePath) {
+		archivePath = filepath.Join(tmpDir, "archive.tar.gz")
+		if err := DecryptArchive(params.ArchivePath, archivePath, params.Passphrase, params.IdentityFile); err != nil {
+			return nil, fmt.Errorf("restore: %w", err)
+		}
+	}
+
 	// Extract all entries
-	if err := extractArchive(params.ArchivePath, tmpDir); err != nil {
+	if err := extractArchive(archivePath, tmpDir); err != nil {
 		return nil, fmt.Errorf("restore: extract: %w", err)
 	}
 
@@ -174,6 +185,83 @@ func ExtractTarGz(archivePath, destDir string) error {
 	return extractTarGzToDir(archivePath, destDir)
 }
 
+// ExtractBolt copies just the bolt snapshot (data/game.bolt) out of an
+// archive to destPath, decrypting the archive first if needed, and checks
+// it against the manifest's checksum. It is used to recover single objects
+// without restoring the whole archive.
+func ExtractBolt(archivePath, destPath, passphrase, identityFile string) error {
+	name := filepath.Base(archivePath)
+	if IsEncrypted(archivePath) {
+		tmpDir, err := os.MkdirTemp("", "mush-extract-*")
+		if err != nil {
+			return fmt.Errorf("archive: create temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		plain := filepath.Join(tmpDir, "archive.tar.gz")
+		if err := DecryptArchive(archivePath, plain, passphrase, identityFile); err != nil {
+			return err
+		}
+		archivePath = plain
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("archive: open %s: %w", archivePath, err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("archive: %s: %w", archivePath, err)
+	}
+	defer gr.Close()
+
+	// The bolt snapshot comes first and the manifest last, so hash the
+	// snapshot on the way out and compare once the manifest turns up.
+	var sum string
+	var manifest *Manifest
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("archive: read %s: %w", archivePath, err)
+		}
+		switch hdr.Name {
+		case "data/game.bolt":
+			out, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("archive: create %s: %w", destPath, err)
+			}
+			h := sha256.New()
+			_, err = io.Copy(io.MultiWriter(out, h), tr)
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("archive: extract bolt: %w", err)
+			}
+			sum = hex.EncodeToString(h.Sum(nil))
+		case "manifest.json":
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return fmt.Errorf("archive: parse manifest: %w", err)
+			}
+		}
+	}
+	if sum == "" {
+		return fmt.Errorf("archive: %s has no database snapshot", name)
+	}
+	if manifest != nil {
+		if entry, ok := manifest.Files["data/game.bolt"]; ok && entry.SHA256 != sum {
+			os.Remove(destPath)
+			return fmt.Errorf("archive: checksum mismatch for data/game.bolt — archive may be corrupt")
+		}
+	}
+	return nil
+}
+
 // extractArchive extracts a .tar.gz to a destination directory (internal).
 func extractArchive(archivePath, destDir string) error {
 	return extractTarGzToDir(archivePath, destDir)
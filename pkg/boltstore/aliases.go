@@ -0,0 +1,49 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// confAliasKey is "<kind>:<name>".
+func confAliasKey(kind, name string) []byte {
+	return []byte(kind + ":" + name)
+}
+
+// PutConfAlias persists an in-game alias or bad name.
+func (s *Store) PutConfAlias(a *gamedb.ConfAlias) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a); err != nil {
+		return fmt.Errorf("boltstore: encode alias %s %q: %w", a.Kind, a.Name, err)
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketConfAliases).Put(confAliasKey(a.Kind, a.Name), buf.Bytes())
+	})
+}
+
+// DeleteConfAlias removes an in-game alias or bad name.
+func (s *Store) DeleteConfAlias(kind, name string) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketConfAliases).Delete(confAliasKey(kind, name))
+	})
+}
+
+// LoadConfAliases reads all in-game aliases and bad names from bbolt.
+func (s *Store) LoadConfAliases() ([]gamedb.ConfAlias, error) {
+	var aliases []gamedb.ConfAlias
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketConfAliases).ForEach(func(k, v []byte) error {
+			var a gamedb.ConfAlias
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&a); err != nil {
+				return fmt.Errorf("decode alias %q: %w", string(k), err)
+			}
+			aliases = append(aliases, a)
+			return nil
+		})
+	})
+	return aliases, err
+}
@@ -0,0 +1,173 @@
+package boltstore
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// Change is one key written to a bucket, or deleted from it. Changes are
+// the raw encoded bytes, so applying them to another store reproduces this
+// one exactly.
+type Change struct {
+	Bucket string
+	Key    []byte
+	Value  []byte
+	Delete bool
+}
+
+// changeTx is a read-write transaction whose buckets record what is
+// written to them while a change feed is set.
+type changeTx struct {
+	*bbolt.Tx
+	changes *[]Change // nil = not recording
+}
+
+// Bucket returns the named bucket, or nil if it doesn't exist.
+func (tx *changeTx) Bucket(name []byte) *changeBucket {
+	b := tx.Tx.Bucket(name)
+	if b == nil {
+		return nil
+	}
+	return &changeBucket{Bucket: b, name: string(name), tx: tx}
+}
+
+func (tx *changeTx) record(bucket string, key, value []byte, del bool) {
+	if tx.changes == nil {
+		return
+	}
+	// Keys from a cursor point into the mmap and are only valid for the
+	// life of the transaction.
+	*tx.changes = append(*tx.changes, Change{Bucket: bucket, Key: bytes.Clone(key), Value: bytes.Clone(value), Delete: del})
+}
+
+// changeBucket is a bucket whose writes are recorded.
+type changeBucket struct {
+	*bbolt.Bucket
+	name string
+	tx   *changeTx
+}
+
+func (b *changeBucket) Put(key, value []byte) error {
+	if err := b.Bucket.Put(key, value); err != nil {
+		return err
+	}
+	b.tx.record(b.name, key, value, false)
+	return nil
+}
+
+func (b *changeBucket) Delete(key []byte) error {
+	if err := b.Bucket.Delete(key); err != nil {
+		return err
+	}
+	b.tx.record(b.name, key, nil, true)
+	return nil
+}
+
+// Cursor returns a cursor whose deletes are recorded.
+func (b *changeBucket) Cursor() *changeCursor {
+	return &changeCursor{Cursor: b.Bucket.Cursor(), b: b}
+}
+
+// changeCursor is a cursor whose deletes are recorded. bbolt doesn't say
+// which key a cursor is on, so it remembers the last one it returned.
+type changeCursor struct {
+	*bbolt.Cursor
+	b   *changeBucket
+	key []byte
+}
+
+func (c *changeCursor) at(k, v []byte) ([]byte, []byte) {
+	c.key = k
+	return k, v
+}
+
+func (c *changeCursor) First() ([]byte, []byte)           { return c.at(c.Cursor.First()) }
+func (c *changeCursor) Last() ([]byte, []byte)            { return c.at(c.Cursor.Last()) }
+func (c *changeCursor) Next() ([]byte, []byte)            { return c.at(c.Cursor.Next()) }
+func (c *changeCursor) Prev() ([]byte, []byte)            { return c.at(c.Cursor.Prev()) }
+func (c *changeCursor) Seek(seek []byte) ([]byte, []byte) { return c.at(c.Cursor.Seek(seek)) }
+
+func (c *changeCursor) Delete() error {
+	if err := c.Cursor.Delete(); err != nil {
+		return err
+	}
+	c.b.tx.record(c.b.name, c.key, nil, true)
+	return nil
+}
+
+// update runs fn in a read-write transaction and, once it commits, hands
+// what it wrote to the change feed. feedMu is held throughout, so the feed
+// sees commits in the order they happened.
+func (s *Store) update(fn func(tx *changeTx) error) error {
+	s.feedMu.Lock()
+	defer s.feedMu.Unlock()
+	var changes []Change
+	ctx := &changeTx{}
+	if s.feed != nil {
+		ctx.changes = &changes
+	}
+	err := s.bolt.Update(func(tx *bbolt.Tx) error {
+		ctx.Tx = tx
+		return fn(ctx)
+	})
+	if err == nil && len(changes) > 0 {
+		s.feed(changes)
+	}
+	return err
+}
+
+// SetChangeFeed makes every committed write call fn with what it changed,
+// in commit order. fn is called with the store's write lock held, so it
+// must not block or write to the store. nil turns the feed off.
+func (s *Store) SetChangeFeed(fn func([]Change)) {
+	s.feedMu.Lock()
+	s.feed = fn
+	s.feedMu.Unlock()
+}
+
+// Snapshot writes a consistent copy of the whole database to w, as Backup
+// does. begin, if not nil, is called at the instant the copy is taken:
+// every change committed before it is in the copy, and every change the
+// feed reports after it is not.
+func (s *Store) Snapshot(w io.Writer, begin func()) (int64, error) {
+	if err := s.Flush(); err != nil {
+		return 0, fmt.Errorf("boltstore: commit before snapshot: %w", err)
+	}
+	s.feedMu.Lock()
+	tx, err := s.bolt.Begin(false)
+	if err == nil && begin != nil {
+		begin()
+	}
+	s.feedMu.Unlock()
+	if err != nil {
+		return 0, fmt.Errorf("boltstore: snapshot: %w", err)
+	}
+	defer tx.Rollback()
+	return tx.WriteTo(w)
+}
+
+// ApplyChanges writes changes recorded by another store's feed, in one
+// transaction, creating any bucket that doesn't exist yet.
+func (s *Store) ApplyChanges(changes []Change) error {
+	return s.update(func(tx *changeTx) error {
+		for _, c := range changes {
+			bb, err := tx.CreateBucketIfNotExists([]byte(c.Bucket))
+			if err != nil {
+				return err
+			}
+			b := &changeBucket{Bucket: bb, name: c.Bucket, tx: tx}
+			if c.Delete {
+				err = b.Delete(c.Key)
+			} else {
+				err = b.Put(c.Key, c.Value)
+			}
+			if err != nil {
+				return fmt.Errorf("boltstore: apply %s: %w", c.Bucket, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,71 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// chanLogPrefix is the key prefix shared by one channel's history.
+func chanLogPrefix(channel string) []byte {
+	return append([]byte(strings.ToLower(channel)), 0)
+}
+
+// chanLogKey is the lowercased channel name, a NUL, then the sequence
+// number, so a channel's history sorts together, oldest first.
+func chanLogKey(msg *gamedb.ChanMessage) []byte {
+	return append(chanLogPrefix(msg.Channel), intToKey(msg.Seq)...)
+}
+
+// PutChanMessage persists one line of channel history.
+func (s *Store) PutChanMessage(msg *gamedb.ChanMessage) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(msg); err != nil {
+		return fmt.Errorf("boltstore: encode channel message for %s: %w", msg.Channel, err)
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketChanLog).Put(chanLogKey(msg), buf.Bytes())
+	})
+}
+
+// DeleteChanMessage removes one line of channel history.
+func (s *Store) DeleteChanMessage(msg *gamedb.ChanMessage) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketChanLog).Delete(chanLogKey(msg))
+	})
+}
+
+// DeleteChanLog removes a channel's whole history.
+func (s *Store) DeleteChanLog(channel string) error {
+	prefix := chanLogPrefix(channel)
+	return s.update(func(tx *changeTx) error {
+		c := tx.Bucket(bucketChanLog).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LoadChanMessages reads all saved channel history, oldest first within
+// each channel.
+func (s *Store) LoadChanMessages() ([]gamedb.ChanMessage, error) {
+	var msgs []gamedb.ChanMessage
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketChanLog).ForEach(func(k, v []byte) error {
+			var msg gamedb.ChanMessage
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&msg); err != nil {
+				return fmt.Errorf("decode channel message %q: %w", k, err)
+			}
+			msgs = append(msgs, msg)
+			return nil
+		})
+	})
+	return msgs, err
+}
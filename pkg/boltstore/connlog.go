@@ -0,0 +1,52 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// connLogKey is the player's dbref key followed by the connect time, so a
+// player's sessions sort together, oldest first.
+func connLogKey(rec *gamedb.ConnRecord) []byte {
+	return append(refToKey(rec.Player), intToKey(int(rec.Connect.UnixNano()))...)
+}
+
+// PutConnRecord persists a connection history record. Writing a record
+// again with the same player and connect time replaces it.
+func (s *Store) PutConnRecord(rec *gamedb.ConnRecord) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rec); err != nil {
+		return fmt.Errorf("boltstore: encode connection record for #%d: %w", rec.Player, err)
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketConnLog).Put(connLogKey(rec), buf.Bytes())
+	})
+}
+
+// DeleteConnRecord removes a connection history record.
+func (s *Store) DeleteConnRecord(rec *gamedb.ConnRecord) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketConnLog).Delete(connLogKey(rec))
+	})
+}
+
+// LoadConnRecords reads all connection history from bbolt, oldest first
+// within each player.
+func (s *Store) LoadConnRecords() ([]gamedb.ConnRecord, error) {
+	var recs []gamedb.ConnRecord
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketConnLog).ForEach(func(k, v []byte) error {
+			var rec gamedb.ConnRecord
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&rec); err != nil {
+				return fmt.Errorf("decode connection record for #%d: %w", keyToRef(k[:8]), err)
+			}
+			recs = append(recs, rec)
+			return nil
+		})
+	})
+	return recs, err
+}
@@ -0,0 +1,44 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// PutCronJob persists a @cron entry, keyed by ID.
+func (s *Store) PutCronJob(job *gamedb.CronJob) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(job); err != nil {
+		return fmt.Errorf("boltstore: encode cron job %d: %w", job.ID, err)
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketCron).Put(intToKey(job.ID), buf.Bytes())
+	})
+}
+
+// DeleteCronJob removes a @cron entry.
+func (s *Store) DeleteCronJob(id int) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketCron).Delete(intToKey(id))
+	})
+}
+
+// LoadCronJobs reads all @cron entries from bbolt, in ID order.
+func (s *Store) LoadCronJobs() ([]gamedb.CronJob, error) {
+	var jobs []gamedb.CronJob
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketCron).ForEach(func(k, v []byte) error {
+			var job gamedb.CronJob
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&job); err != nil {
+				return fmt.Errorf("decode cron job %d: %w", keyToInt(k), err)
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+	return jobs, err
+}
@@ -0,0 +1,46 @@
+package boltstore
+
+import (
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// PutDoing persists a player's @doing message. An empty one is removed.
+func (s *Store) PutDoing(player gamedb.DBRef, doing string) error {
+	return s.update(func(tx *changeTx) error {
+		b := tx.Bucket(bucketDoing)
+		if doing == "" {
+			return b.Delete(refToKey(player))
+		}
+		return b.Put(refToKey(player), []byte(doing))
+	})
+}
+
+// LoadDoings reads every player's @doing message from bbolt.
+func (s *Store) LoadDoings() (map[gamedb.DBRef]string, error) {
+	doings := make(map[gamedb.DBRef]string)
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketDoing).ForEach(func(k, v []byte) error {
+			doings[keyToRef(k)] = string(v)
+			return nil
+		})
+	})
+	return doings, err
+}
+
+// PutPoll persists the WHO poll set with @doing/header.
+func (s *Store) PutPoll(poll string) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketMeta).Put(keyPoll, []byte(poll))
+	})
+}
+
+// GetPoll returns the persisted WHO poll, or "" if none was set.
+func (s *Store) GetPoll() (string, error) {
+	var poll string
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		poll = string(tx.Bucket(bucketMeta).Get(keyPoll))
+		return nil
+	})
+	return poll, err
+}
@@ -17,6 +17,9 @@ func init() {
 	gob.Register(gamedb.StructDef{})
 	gob.Register(gamedb.StructInstance{})
 	gob.Register(gamedb.MailMessage{})
+	gob.Register(gamedb.SiteRule{})
+	gob.Register(gamedb.Scene{})
+	gob.Register(gamedb.Registration{})
 }
 
 // encodeObject serializes an Object to bytes using gob.
@@ -17,6 +17,18 @@ var (
 	bucketStructDefs  = []byte("structdefs")
 	bucketStructInsts = []byte("structinsts")
 	bucketMail        = []byte("mail")
+	bucketSites       = []byte("sites")
+	bucketScenes      = []byte("scenes")
+	bucketSceneLog      = []byte("scenelog")
+	bucketRegistrations = []byte("registrations")
+	bucketConnLog       = []byte("connlog")
+	bucketCron          = []byte("cron")
+	bucketMailAliases   = []byte("maliases")
+	bucketChanLog       = []byte("chanlog")
+	bucketConfAliases   = []byte("confaliases")
+	bucketDoing         = []byte("doing")
+	bucketQueue         = []byte("queue")
+	bucketRuntime       = []byte("runtime")
 )
 
 // Meta key constants.
@@ -27,6 +39,8 @@ var (
 	keySize          = []byte("size")
 	keyNextAttr      = []byte("nextattr")
 	keyRecordPlayers = []byte("recordplayers")
+	keySceneKey      = []byte("scenekey")
+	keyPoll          = []byte("poll")
 )
 
 // refToKey converts a DBRef to an 8-byte big-endian key.
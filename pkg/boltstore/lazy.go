@@ -0,0 +1,195 @@
+package boltstore
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// pager keeps the attributes of cold objects on disk. Every object stays
+// in the cache, so contents chains, searches and locks work as usual;
+// only a cold object's attribute list is left out until something asks
+// for it with Attributes. The most recently used cold objects are kept in
+// memory, up to size; beyond that the least recently used is written
+// through and dropped again.
+type pager struct {
+	s    *Store
+	cold func(*gamedb.Object) bool
+	size int
+
+	mu     sync.Mutex
+	out    map[*gamedb.Object]bool // attributes on disk only
+	lru    *list.List              // cold objects in memory, most recent first
+	elems  map[*gamedb.Object]*list.Element
+	faults int64
+}
+
+// LazyStats describes lazy loading for status displays.
+type LazyStats struct {
+	PagedOut int   // cold objects whose attributes are on disk only
+	PagedIn  int   // cold objects whose attributes are in memory
+	Faults   int64 // times attributes have been read back
+}
+
+// SetLazy makes LoadAll leave the attributes of objects for which cold
+// returns true on disk, reading them back when they're used and keeping
+// at most cacheSize such objects' attributes in memory. It must be called
+// before LoadAll.
+func (s *Store) SetLazy(cold func(*gamedb.Object) bool, cacheSize int) {
+	if cacheSize < 1 {
+		cacheSize = 1
+	}
+	s.lazy = &pager{
+		s:     s,
+		cold:  cold,
+		size:  cacheSize,
+		out:   make(map[*gamedb.Object]bool),
+		lru:   list.New(),
+		elems: make(map[*gamedb.Object]*list.Element),
+	}
+}
+
+// LazyStats reports how many objects are paged out. ok is false if lazy
+// loading is off.
+func (s *Store) LazyStats() (stats LazyStats, ok bool) {
+	p := s.lazy
+	if p == nil {
+		return LazyStats{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return LazyStats{PagedOut: len(p.out), PagedIn: p.lru.Len(), Faults: p.faults}, true
+}
+
+// pageOutOnLoad drops a freshly loaded object's attributes if it is cold.
+func (p *pager) pageOutOnLoad(obj *gamedb.Object) {
+	if !p.cold(obj) {
+		return
+	}
+	obj.Attrs = nil
+	obj.SetPager(p)
+	p.out[obj] = true
+}
+
+// Touch reads a paged-out object's attributes back and marks it recently
+// used, paging out the least recently used objects to make room.
+func (p *pager) Touch(obj *gamedb.Object) {
+	p.mu.Lock()
+	if p.out[obj] {
+		p.mu.Unlock()
+		attrs, err := p.s.readAttrs(obj.DBRef)
+		if err != nil {
+			log.Printf("boltstore: page in #%d: %v", obj.DBRef, err)
+			return
+		}
+		p.mu.Lock()
+		if p.out[obj] {
+			obj.Attrs = attrs
+			delete(p.out, obj)
+			p.faults++
+		}
+	}
+	if e, ok := p.elems[obj]; ok {
+		p.lru.MoveToFront(e)
+	} else {
+		p.elems[obj] = p.lru.PushFront(obj)
+	}
+	var victims []*gamedb.Object
+	for p.lru.Len() > p.size {
+		e := p.lru.Back()
+		victim := e.Value.(*gamedb.Object)
+		p.lru.Remove(e)
+		delete(p.elems, victim)
+		victims = append(victims, victim)
+	}
+	p.mu.Unlock()
+
+	for _, victim := range victims {
+		p.pageOut(victim)
+	}
+}
+
+// pageOut writes obj through, so nothing changed in memory is lost, then
+// drops its attributes. Any queued write of obj is superseded; flushMu
+// keeps a commit already under way from landing on top of this one.
+func (p *pager) pageOut(obj *gamedb.Object) {
+	if p.s.cache.Objects[obj.DBRef] != obj {
+		// Destroyed, or its dbref reused: it is no longer ours to write.
+		obj.SetPager(nil)
+		return
+	}
+	data, err := encodeObject(obj)
+	if err != nil {
+		log.Printf("boltstore: page out #%d: %v", obj.DBRef, err)
+		return
+	}
+	p.s.flushMu.Lock()
+	p.s.dequeue(obj.DBRef)
+	err = p.s.update(func(tx *changeTx) error {
+		b := tx.Bucket(bucketObjects)
+		key := refToKey(obj.DBRef)
+		if bytes.Equal(b.Get(key), data) {
+			return nil
+		}
+		return b.Put(key, data)
+	})
+	p.s.flushMu.Unlock()
+	if err != nil {
+		log.Printf("boltstore: page out #%d: %v", obj.DBRef, err)
+		return
+	}
+	p.mu.Lock()
+	obj.Attrs = nil
+	p.out[obj] = true
+	p.mu.Unlock()
+}
+
+// encode encodes obj for the objects bucket. A paged-out object keeps the
+// attributes already stored there; pageOut wrote them through, so nothing
+// queued can be newer.
+func (p *pager) encode(obj *gamedb.Object) ([]byte, error) {
+	p.mu.Lock()
+	out := p.out[obj]
+	p.mu.Unlock()
+	if !out {
+		return encodeObject(obj)
+	}
+	attrs, err := p.s.readAttrs(obj.DBRef)
+	if err != nil {
+		return nil, fmt.Errorf("read stored attributes: %w", err)
+	}
+	merged := *obj
+	merged.Attrs = attrs
+	return encodeObject(&merged)
+}
+
+// encodeLive encodes a cached object for the objects bucket.
+func (s *Store) encodeLive(obj *gamedb.Object) ([]byte, error) {
+	if s.lazy != nil {
+		return s.lazy.encode(obj)
+	}
+	return encodeObject(obj)
+}
+
+// readAttrs reads an object's stored attributes.
+func (s *Store) readAttrs(ref gamedb.DBRef) ([]gamedb.Attribute, error) {
+	var attrs []gamedb.Attribute
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bucketObjects).Get(refToKey(ref))
+		if v == nil {
+			return fmt.Errorf("not in store")
+		}
+		obj, err := decodeObject(v)
+		if err != nil {
+			return err
+		}
+		attrs = obj.Attrs
+		return nil
+	})
+	return attrs, err
+}
@@ -22,21 +22,21 @@ func (s *Store) PutMailMessage(player gamedb.DBRef, msg *gamedb.MailMessage) err
 	if err := gob.NewEncoder(&buf).Encode(msg); err != nil {
 		return fmt.Errorf("boltstore: encode mail msg: %w", err)
 	}
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketMail).Put(mailKey(player, msg.ID), buf.Bytes())
 	})
 }
 
 // DeleteMailMessage removes a single mail message from bbolt.
 func (s *Store) DeleteMailMessage(player gamedb.DBRef, msgID int) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketMail).Delete(mailKey(player, msgID))
 	})
 }
 
 // DeleteMailMessages removes multiple mail messages in a single transaction.
 func (s *Store) DeleteMailMessages(player gamedb.DBRef, msgIDs []int) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketMail)
 		for _, id := range msgIDs {
 			if err := b.Delete(mailKey(player, id)); err != nil {
@@ -90,7 +90,7 @@ func (s *Store) HasMailData() bool {
 
 // PutMailMessages persists multiple mail messages in a single transaction.
 func (s *Store) PutMailMessages(player gamedb.DBRef, msgs []*gamedb.MailMessage) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketMail)
 		for _, msg := range msgs {
 			var buf bytes.Buffer
@@ -121,3 +121,37 @@ func (s *Store) ImportMail(all map[gamedb.DBRef]map[int]*gamedb.MailMessage) err
 	log.Printf("boltstore: imported %d mail messages", total)
 	return nil
 }
+
+// PutMailAlias persists an @malias mailing list, keyed by ID.
+func (s *Store) PutMailAlias(ma *gamedb.MailAlias) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(ma); err != nil {
+		return fmt.Errorf("boltstore: encode mail alias %s: %w", ma.Name, err)
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketMailAliases).Put(intToKey(ma.ID), buf.Bytes())
+	})
+}
+
+// DeleteMailAlias removes an @malias mailing list.
+func (s *Store) DeleteMailAlias(id int) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketMailAliases).Delete(intToKey(id))
+	})
+}
+
+// LoadMailAliases reads all @malias mailing lists from bbolt, in ID order.
+func (s *Store) LoadMailAliases() ([]gamedb.MailAlias, error) {
+	var aliases []gamedb.MailAlias
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketMailAliases).ForEach(func(k, v []byte) error {
+			var ma gamedb.MailAlias
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&ma); err != nil {
+				return fmt.Errorf("decode mail alias %d: %w", keyToInt(k), err)
+			}
+			aliases = append(aliases, ma)
+			return nil
+		})
+	})
+	return aliases, err
+}
@@ -0,0 +1,50 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// SaveQueue replaces the saved command queue with entries, keyed by their
+// position so they load back in the same order.
+func (s *Store) SaveQueue(entries []gamedb.QueuedCommand) error {
+	return s.update(func(tx *changeTx) error {
+		b := tx.Bucket(bucketQueue)
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		for i := range entries {
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(&entries[i]); err != nil {
+				return fmt.Errorf("boltstore: encode queue entry %d: %w", i, err)
+			}
+			if err := b.Put(intToKey(i), buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LoadQueue reads the saved command queue from bbolt, in saved order.
+func (s *Store) LoadQueue() ([]gamedb.QueuedCommand, error) {
+	var entries []gamedb.QueuedCommand
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketQueue).ForEach(func(k, v []byte) error {
+			var e gamedb.QueuedCommand
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&e); err != nil {
+				return fmt.Errorf("decode queue entry %d: %w", keyToInt(k), err)
+			}
+			entries = append(entries, e)
+			return nil
+		})
+	})
+	return entries, err
+}
@@ -0,0 +1,44 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// PutRegistration persists a pending character registration, keyed by ID.
+func (s *Store) PutRegistration(reg *gamedb.Registration) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(reg); err != nil {
+		return fmt.Errorf("boltstore: encode registration %d: %w", reg.ID, err)
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketRegistrations).Put(intToKey(reg.ID), buf.Bytes())
+	})
+}
+
+// DeleteRegistration removes a pending registration.
+func (s *Store) DeleteRegistration(id int) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketRegistrations).Delete(intToKey(id))
+	})
+}
+
+// LoadRegistrations reads all pending registrations from bbolt.
+func (s *Store) LoadRegistrations() ([]gamedb.Registration, error) {
+	var regs []gamedb.Registration
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketRegistrations).ForEach(func(k, v []byte) error {
+			var reg gamedb.Registration
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&reg); err != nil {
+				return fmt.Errorf("decode registration %d: %w", keyToInt(k), err)
+			}
+			regs = append(regs, reg)
+			return nil
+		})
+	})
+	return regs, err
+}
@@ -0,0 +1,49 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// runtimeKey is "<kind>:<name>".
+func runtimeKey(kind, name string) []byte {
+	return []byte(kind + ":" + name)
+}
+
+// PutRuntimeSetting persists an @motd, @function or @admin/save setting.
+func (s *Store) PutRuntimeSetting(rs *gamedb.RuntimeSetting) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rs); err != nil {
+		return fmt.Errorf("boltstore: encode runtime setting %s %q: %w", rs.Kind, rs.Name, err)
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketRuntime).Put(runtimeKey(rs.Kind, rs.Name), buf.Bytes())
+	})
+}
+
+// DeleteRuntimeSetting removes a runtime setting.
+func (s *Store) DeleteRuntimeSetting(kind, name string) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketRuntime).Delete(runtimeKey(kind, name))
+	})
+}
+
+// LoadRuntimeSettings reads all runtime settings from bbolt.
+func (s *Store) LoadRuntimeSettings() ([]gamedb.RuntimeSetting, error) {
+	var settings []gamedb.RuntimeSetting
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketRuntime).ForEach(func(k, v []byte) error {
+			var rs gamedb.RuntimeSetting
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&rs); err != nil {
+				return fmt.Errorf("decode runtime setting %q: %w", string(k), err)
+			}
+			settings = append(settings, rs)
+			return nil
+		})
+	})
+	return settings, err
+}
@@ -0,0 +1,132 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// sceneLinePrefix is the key prefix shared by one scene's log.
+func sceneLinePrefix(id int) []byte {
+	return intToKey(id)
+}
+
+// sceneLineKey is the scene ID then the line's position in the log, so a
+// scene's lines sort together, oldest first.
+func sceneLineKey(id, seq int) []byte {
+	return append(sceneLinePrefix(id), intToKey(seq)...)
+}
+
+// encodeScene encodes a scene record without its log.
+func encodeScene(scene *gamedb.Scene) ([]byte, error) {
+	rec := *scene
+	rec.Log = nil
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&rec); err != nil {
+		return nil, fmt.Errorf("boltstore: encode scene %d: %w", scene.ID, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// PutScene persists a scene record, keyed by its ID. The log is not part
+// of the record; its lines are written one at a time by PutSceneLine.
+func (s *Store) PutScene(scene *gamedb.Scene) error {
+	data, err := encodeScene(scene)
+	if err != nil {
+		return err
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketScenes).Put(intToKey(scene.ID), data)
+	})
+}
+
+// PutSceneLine persists line seq of a scene's log.
+func (s *Store) PutSceneLine(id, seq int, blob []byte) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketSceneLog).Put(sceneLineKey(id, seq), blob)
+	})
+}
+
+// DeleteScene removes a scene record and its log.
+func (s *Store) DeleteScene(id int) error {
+	prefix := sceneLinePrefix(id)
+	return s.update(func(tx *changeTx) error {
+		c := tx.Bucket(bucketSceneLog).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketScenes).Delete(intToKey(id))
+	})
+}
+
+// LoadScenes reads all scene records from bbolt, each with its log.
+// Scenes saved before the log was kept line by line carry it inline; those
+// lines are moved to the log bucket so later PutScene calls don't drop them.
+func (s *Store) LoadScenes() ([]gamedb.Scene, error) {
+	var scenes []gamedb.Scene
+	var inline []int
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		lines := tx.Bucket(bucketSceneLog).Cursor()
+		return tx.Bucket(bucketScenes).ForEach(func(k, v []byte) error {
+			var scene gamedb.Scene
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&scene); err != nil {
+				return fmt.Errorf("decode scene %d: %w", keyToInt(k), err)
+			}
+			if len(scene.Log) > 0 {
+				inline = append(inline, len(scenes))
+			}
+			prefix := sceneLinePrefix(scene.ID)
+			for lk, lv := lines.Seek(prefix); lk != nil && bytes.HasPrefix(lk, prefix); lk, lv = lines.Next() {
+				scene.Log = append(scene.Log, bytes.Clone(lv))
+			}
+			scenes = append(scenes, scene)
+			return nil
+		})
+	})
+	if err != nil || len(inline) == 0 {
+		return scenes, err
+	}
+	err = s.update(func(tx *changeTx) error {
+		for _, i := range inline {
+			scene := &scenes[i]
+			for seq, blob := range scene.Log {
+				if err := tx.Bucket(bucketSceneLog).Put(sceneLineKey(scene.ID, seq), blob); err != nil {
+					return err
+				}
+			}
+			data, err := encodeScene(scene)
+			if err != nil {
+				return err
+			}
+			if err := tx.Bucket(bucketScenes).Put(intToKey(scene.ID), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return scenes, err
+}
+
+// SceneKey returns the stored scene master key, or nil if none is set.
+func (s *Store) SceneKey() ([]byte, error) {
+	var key []byte
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(bucketMeta).Get(keySceneKey); v != nil {
+			key = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return key, err
+}
+
+// PutSceneKey stores the scene master key.
+func (s *Store) PutSceneKey(key []byte) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketMeta).Put(keySceneKey, key)
+	})
+}
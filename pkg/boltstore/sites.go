@@ -0,0 +1,44 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	bbolt "go.etcd.io/bbolt"
+)
+
+// PutSiteRule persists a site access rule, keyed by its pattern.
+func (s *Store) PutSiteRule(rule *gamedb.SiteRule) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rule); err != nil {
+		return fmt.Errorf("boltstore: encode site rule %q: %w", rule.Pattern, err)
+	}
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketSites).Put([]byte(rule.Pattern), buf.Bytes())
+	})
+}
+
+// DeleteSiteRule removes a site access rule.
+func (s *Store) DeleteSiteRule(pattern string) error {
+	return s.update(func(tx *changeTx) error {
+		return tx.Bucket(bucketSites).Delete([]byte(pattern))
+	})
+}
+
+// LoadSiteRules reads all site access rules from bbolt.
+func (s *Store) LoadSiteRules() ([]gamedb.SiteRule, error) {
+	var rules []gamedb.SiteRule
+	err := s.bolt.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketSites).ForEach(func(k, v []byte) error {
+			var rule gamedb.SiteRule
+			if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&rule); err != nil {
+				return fmt.Errorf("decode site rule %q: %w", string(k), err)
+			}
+			rules = append(rules, rule)
+			return nil
+		})
+	})
+	return rules, err
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 	bbolt "go.etcd.io/bbolt"
@@ -12,8 +14,15 @@ import (
 
 // Store wraps a bbolt database and an in-memory cache for ACID persistence.
 type Store struct {
-	bolt  *bbolt.DB
-	cache *gamedb.Database
+	bolt    *bbolt.DB
+	cache   *gamedb.Database
+	wb      *writeBehind // nil = write-through
+	flushMu sync.Mutex   // serializes write-behind commits
+
+	feedMu sync.Mutex     // held across each write and its feed call
+	feed   func([]Change) // nil = no change feed (see SetChangeFeed)
+
+	lazy *pager // nil = every object's attributes in memory (see SetLazy)
 }
 
 // Open opens or creates a bbolt database file and ensures all buckets exist.
@@ -25,7 +34,7 @@ func Open(path string) (*Store, error) {
 
 	// Ensure all buckets exist.
 	err = db.Update(func(tx *bbolt.Tx) error {
-		for _, name := range [][]byte{bucketMeta, bucketObjects, bucketAttrDefs, bucketPlayers, bucketChannels, bucketChanAliases, bucketStructDefs, bucketStructInsts, bucketMail} {
+		for _, name := range [][]byte{bucketMeta, bucketObjects, bucketAttrDefs, bucketPlayers, bucketChannels, bucketChanAliases, bucketStructDefs, bucketStructInsts, bucketMail, bucketSites, bucketScenes, bucketSceneLog, bucketRegistrations, bucketConnLog, bucketCron, bucketMailAliases, bucketChanLog, bucketConfAliases, bucketDoing, bucketQueue, bucketRuntime} {
 			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
 				return err
 			}
@@ -43,8 +52,28 @@ func Open(path string) (*Store, error) {
 	}, nil
 }
 
-// Close closes the underlying bbolt database.
+// OpenReadOnly opens an existing bbolt database without writing to it,
+// for tools that inspect a backup or a stopped game's store. bbolt locks
+// the file, so this fails after a second if a running game has it open.
+func OpenReadOnly(path string) (*Store, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("boltstore: open %s: %w", path, err)
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: open %s: %w", path, err)
+	}
+	return &Store{
+		bolt:  db,
+		cache: gamedb.NewDatabase(),
+	}, nil
+}
+
+// Close commits any queued writes and closes the underlying bbolt database.
 func (s *Store) Close() error {
+	if err := s.StopWriteBehind(); err != nil {
+		log.Printf("boltstore: final write-behind commit: %v", err)
+	}
 	if s.bolt != nil {
 		return s.bolt.Close()
 	}
@@ -64,30 +93,39 @@ func (s *Store) Path() string {
 	return ""
 }
 
-// PutObject persists a single object to bbolt (write-through).
+// PutObject persists a single object to bbolt: written through, or queued
+// when write-behind is on.
 func (s *Store) PutObject(obj *gamedb.Object) error {
-	data, err := encodeObject(obj)
-	if err != nil {
-		return fmt.Errorf("boltstore: encode object #%d: %w", obj.DBRef, err)
-	}
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
-		return tx.Bucket(bucketObjects).Put(refToKey(obj.DBRef), data)
-	})
+	return s.PutObjects(obj)
 }
 
-// PutObjects persists multiple objects in a single bbolt transaction.
+// PutObjects persists multiple objects in a single bbolt transaction, or
+// queues them when write-behind is on. The objects are encoded before
+// PutObjects returns, so the caller is free to change them afterwards.
 func (s *Store) PutObjects(objs ...*gamedb.Object) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	data := make(map[gamedb.DBRef][]byte, len(objs))
+	for _, obj := range objs {
+		if obj == nil {
+			continue
+		}
+		enc, err := s.encodeLive(obj)
+		if err != nil {
+			return fmt.Errorf("boltstore: encode object #%d: %w", obj.DBRef, err)
+		}
+		data[obj.DBRef] = enc
+	}
+	if s.enqueue(data) {
+		return nil
+	}
+	return s.putEncoded(data)
+}
+
+// putEncoded writes encoded objects in one transaction.
+func (s *Store) putEncoded(objs map[gamedb.DBRef][]byte) error {
+	return s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketObjects)
-		for _, obj := range objs {
-			if obj == nil {
-				continue
-			}
-			data, err := encodeObject(obj)
-			if err != nil {
-				return fmt.Errorf("boltstore: encode object #%d: %w", obj.DBRef, err)
-			}
-			if err := b.Put(refToKey(obj.DBRef), data); err != nil {
+		for ref, data := range objs {
+			if err := b.Put(refToKey(ref), data); err != nil {
 				return err
 			}
 		}
@@ -97,7 +135,8 @@ func (s *Store) PutObjects(objs ...*gamedb.Object) error {
 
 // DeleteObject removes an object from bbolt.
 func (s *Store) DeleteObject(ref gamedb.DBRef) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	s.dequeue(ref)
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketObjects).Delete(refToKey(ref))
 	})
 }
@@ -108,14 +147,14 @@ func (s *Store) PutAttrDef(def *gamedb.AttrDef) error {
 	if err != nil {
 		return fmt.Errorf("boltstore: encode attrdef %d: %w", def.Number, err)
 	}
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketAttrDefs).Put(intToKey(def.Number), data)
 	})
 }
 
 // PutMeta persists database metadata (version, nextattr, size, etc.).
 func (s *Store) PutMeta() error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketMeta)
 		b.Put(keyVersion, intToKey(s.cache.Version))
 		b.Put(keyFormat, intToKey(s.cache.Format))
@@ -138,7 +177,7 @@ func (s *Store) ImportFromDatabase(db *gamedb.Database) error {
 	}
 
 	// Persist attribute definitions.
-	err := s.bolt.Update(func(tx *bbolt.Tx) error {
+	err := s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketAttrDefs)
 		for _, def := range db.AttrNames {
 			data, err := encodeAttrDef(def)
@@ -186,7 +225,7 @@ func (s *Store) ImportFromDatabase(db *gamedb.Database) error {
 
 // writeBatch writes a batch of objects in a single transaction.
 func (s *Store) writeBatch(objs []*gamedb.Object) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketObjects)
 		for _, obj := range objs {
 			data, err := encodeObject(obj)
@@ -203,7 +242,7 @@ func (s *Store) writeBatch(objs []*gamedb.Object) error {
 
 // rebuildPlayerIndex writes all player name→DBRef mappings.
 func (s *Store) rebuildPlayerIndex(db *gamedb.Database) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketPlayers)
 		for _, obj := range db.Objects {
 			if obj.ObjType() == gamedb.TypePlayer && !obj.IsGoing() {
@@ -264,7 +303,10 @@ func (s *Store) LoadAll() error {
 	}
 
 	// Load objects.
+	// Players are indexed as they load, while a cold player's alias is
+	// still in memory.
 	count := 0
+	s.cache.StartPlayerIndex()
 	err = s.bolt.View(func(tx *bbolt.Tx) error {
 		b := tx.Bucket(bucketObjects)
 		return b.ForEach(func(k, v []byte) error {
@@ -273,6 +315,10 @@ func (s *Store) LoadAll() error {
 				return fmt.Errorf("decode object: %w", err)
 			}
 			s.cache.Objects[obj.DBRef] = obj
+			s.cache.IndexPlayer(obj)
+			if s.lazy != nil {
+				s.lazy.pageOutOnLoad(obj)
+			}
 			count++
 			return nil
 		})
@@ -282,30 +328,31 @@ func (s *Store) LoadAll() error {
 	}
 
 	log.Printf("boltstore: loaded %d objects, %d attr defs from bolt", count, len(s.cache.AttrNames))
+	if stats, ok := s.LazyStats(); ok {
+		log.Printf("boltstore: lazy loading: attributes of %d cold object(s) left on disk", stats.PagedOut)
+	}
 	return nil
 }
 
 // Backup creates a hot snapshot of the bbolt database using tx.WriteTo().
+// Queued writes are committed first so the snapshot is current.
 func (s *Store) Backup(path string) error {
-	return s.bolt.View(func(tx *bbolt.Tx) error {
-		f, err := os.Create(path)
-		if err != nil {
-			return fmt.Errorf("boltstore: create backup %s: %w", path, err)
-		}
-		defer f.Close()
-		_, err = tx.WriteTo(f)
-		if err != nil {
-			return fmt.Errorf("boltstore: write backup: %w", err)
-		}
-		log.Printf("boltstore: backup written to %s", path)
-		return nil
-	})
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("boltstore: create backup %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := s.Snapshot(f, nil); err != nil {
+		return fmt.Errorf("boltstore: write backup: %w", err)
+	}
+	log.Printf("boltstore: backup written to %s", path)
+	return nil
 }
 
 // UpdatePlayerIndex updates the player name→DBRef secondary index.
 // If oldName is non-empty, the old entry is removed.
 func (s *Store) UpdatePlayerIndex(obj *gamedb.Object, oldName string) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketPlayers)
 		if oldName != "" {
 			b.Delete([]byte(strings.ToLower(oldName)))
@@ -338,14 +385,14 @@ func (s *Store) PutChannel(ch *gamedb.Channel) error {
 	if err != nil {
 		return fmt.Errorf("boltstore: encode channel %q: %w", ch.Name, err)
 	}
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketChannels).Put([]byte(strings.ToLower(ch.Name)), data)
 	})
 }
 
 // DeleteChannel removes a channel from bbolt.
 func (s *Store) DeleteChannel(name string) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketChannels).Delete([]byte(strings.ToLower(name)))
 	})
 }
@@ -378,14 +425,14 @@ func (s *Store) PutChanAlias(ca *gamedb.ChanAlias) error {
 	if err != nil {
 		return fmt.Errorf("boltstore: encode chan alias: %w", err)
 	}
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketChanAliases).Put(chanAliasKey(ca.Player, ca.Alias), data)
 	})
 }
 
 // DeleteChanAlias removes a channel alias from bbolt.
 func (s *Store) DeleteChanAlias(player gamedb.DBRef, alias string) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketChanAliases).Delete(chanAliasKey(player, alias))
 	})
 }
@@ -393,7 +440,7 @@ func (s *Store) DeleteChanAlias(player gamedb.DBRef, alias string) error {
 // DeleteChanAliasesForPlayer removes all channel aliases for a player from bbolt.
 func (s *Store) DeleteChanAliasesForPlayer(player gamedb.DBRef) error {
 	prefix := []byte(fmt.Sprintf("%d:", player))
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketChanAliases)
 		c := b.Cursor()
 		for k, _ := c.Seek(prefix); k != nil && len(k) >= len(prefix) && string(k[:len(prefix)]) == string(prefix); k, _ = c.Next() {
@@ -424,7 +471,7 @@ func (s *Store) LoadChanAliases() ([]gamedb.ChanAlias, error) {
 
 // ImportComsys bulk-loads channels and aliases into bbolt.
 func (s *Store) ImportComsys(channels []gamedb.Channel, aliases []gamedb.ChanAlias) error {
-	err := s.bolt.Update(func(tx *bbolt.Tx) error {
+	err := s.update(func(tx *changeTx) error {
 		b := tx.Bucket(bucketChannels)
 		for i := range channels {
 			data, err := encodeChannel(&channels[i])
@@ -448,7 +495,7 @@ func (s *Store) ImportComsys(channels []gamedb.Channel, aliases []gamedb.ChanAli
 			end = len(aliases)
 		}
 		batch := aliases[i:end]
-		err := s.bolt.Update(func(tx *bbolt.Tx) error {
+		err := s.update(func(tx *changeTx) error {
 			b := tx.Bucket(bucketChanAliases)
 			for j := range batch {
 				data, err := encodeChanAlias(&batch[j])
@@ -496,14 +543,14 @@ func (s *Store) PutStructDef(player gamedb.DBRef, def *gamedb.StructDef) error {
 	if err != nil {
 		return fmt.Errorf("boltstore: encode struct def %q: %w", def.Name, err)
 	}
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketStructDefs).Put(structKey(player, def.Name), data)
 	})
 }
 
 // DeleteStructDef removes a structure definition from bbolt.
 func (s *Store) DeleteStructDef(player gamedb.DBRef, name string) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketStructDefs).Delete(structKey(player, name))
 	})
 }
@@ -514,14 +561,14 @@ func (s *Store) PutStructInstance(player gamedb.DBRef, name string, inst *gamedb
 	if err != nil {
 		return fmt.Errorf("boltstore: encode struct instance %q: %w", name, err)
 	}
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketStructInsts).Put(structKey(player, name), data)
 	})
 }
 
 // DeleteStructInstance removes a structure instance from bbolt.
 func (s *Store) DeleteStructInstance(player gamedb.DBRef, name string) error {
-	return s.bolt.Update(func(tx *bbolt.Tx) error {
+	return s.update(func(tx *changeTx) error {
 		return tx.Bucket(bucketStructInsts).Delete(structKey(player, name))
 	})
 }
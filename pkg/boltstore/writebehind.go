@@ -0,0 +1,141 @@
+package boltstore
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// writeBehind holds objects waiting to be written, already encoded so the
+// background writer never reads an object the game may be changing.
+// Repeated writes of the same object between commits collapse into one.
+type writeBehind struct {
+	mu       sync.Mutex
+	dirty    map[gamedb.DBRef][]byte
+	interval time.Duration
+	closed   bool // set by StopWriteBehind; later writes go straight through
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// StartWriteBehind makes PutObject and PutObjects queue their objects and
+// return immediately. A background goroutine commits the queue in a single
+// transaction every interval. Backup, Flush and Close commit synchronously.
+func (s *Store) StartWriteBehind(interval time.Duration) {
+	if interval <= 0 || s.wb != nil {
+		return
+	}
+	wb := &writeBehind{
+		dirty:    make(map[gamedb.DBRef][]byte),
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	s.wb = wb
+	go func() {
+		defer close(wb.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Flush(); err != nil {
+					log.Printf("boltstore: write-behind commit: %v", err)
+				}
+			case <-wb.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("boltstore: write-behind enabled, committing every %s", interval)
+}
+
+// StopWriteBehind stops the background writer, commits anything still
+// queued, and returns to writing through.
+func (s *Store) StopWriteBehind() error {
+	wb := s.wb
+	if wb == nil {
+		return nil
+	}
+	close(wb.stop)
+	<-wb.done
+	wb.mu.Lock()
+	wb.closed = true
+	wb.mu.Unlock()
+	err := s.Flush()
+	s.wb = nil
+	return err
+}
+
+// PendingWrites returns the number of objects queued but not yet committed.
+func (s *Store) PendingWrites() int {
+	if s.wb == nil {
+		return 0
+	}
+	s.wb.mu.Lock()
+	defer s.wb.mu.Unlock()
+	return len(s.wb.dirty)
+}
+
+// Flush commits every queued object in one transaction. Objects that fail
+// to commit stay queued unless they have been queued again since.
+func (s *Store) Flush() error {
+	wb := s.wb
+	if wb == nil {
+		return nil
+	}
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	wb.mu.Lock()
+	if len(wb.dirty) == 0 {
+		wb.mu.Unlock()
+		return nil
+	}
+	batch := wb.dirty
+	wb.dirty = make(map[gamedb.DBRef][]byte, len(batch))
+	wb.mu.Unlock()
+
+	err := s.putEncoded(batch)
+	if err != nil {
+		wb.mu.Lock()
+		for ref, data := range batch {
+			if _, requeued := wb.dirty[ref]; !requeued {
+				wb.dirty[ref] = data
+			}
+		}
+		wb.mu.Unlock()
+	}
+	return err
+}
+
+// enqueue queues encoded objects for the background writer. It returns
+// false if write-behind is off and the caller should write through.
+func (s *Store) enqueue(objs map[gamedb.DBRef][]byte) bool {
+	wb := s.wb
+	if wb == nil {
+		return false
+	}
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	if wb.closed {
+		return false
+	}
+	for ref, data := range objs {
+		wb.dirty[ref] = data
+	}
+	return true
+}
+
+// dequeue drops a queued write, so a deleted object isn't written back.
+func (s *Store) dequeue(ref gamedb.DBRef) {
+	wb := s.wb
+	if wb == nil {
+		return
+	}
+	wb.mu.Lock()
+	delete(wb.dirty, ref)
+	wb.mu.Unlock()
+}
@@ -0,0 +1,241 @@
+// Package dbdiff compares two GoTinyMUSH databases, such as two backups of
+// the same game, and reports the objects created and destroyed between
+// them and what changed on the objects in both: name, owner, parent,
+// zone, flags, powers and attributes.
+package dbdiff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// Result is the difference between an old and a new database.
+type Result struct {
+	Created   []*gamedb.Object // live in new, missing or GARBAGE in old
+	Destroyed []*gamedb.Object // live in old, missing or GARBAGE in new
+	Changed   []*ObjectDiff    // live in both, in dbref order
+}
+
+// ObjectDiff is what changed on one object.
+type ObjectDiff struct {
+	Ref     gamedb.DBRef
+	Name    string // name in the new database
+	OldName string // set if the object was renamed
+
+	OldOwner, NewOwner   gamedb.DBRef
+	OldParent, NewParent gamedb.DBRef
+	OldZone, NewZone     gamedb.DBRef
+
+	FlagsSet, FlagsCleared   []string
+	PowersSet, PowersCleared []string
+
+	Attrs []AttrDiff // in attribute name order
+}
+
+// AttrDiff is one attribute added, removed or changed. Attributes are
+// matched by name, since user attribute numbers differ between games.
+type AttrDiff struct {
+	Name     string
+	Old, New string // raw values, including any "\x01owner:flags:" prefix
+	Added    bool
+	Removed  bool
+}
+
+// Empty reports whether the two databases were the same.
+func (r *Result) Empty() bool {
+	return len(r.Created) == 0 && len(r.Destroyed) == 0 && len(r.Changed) == 0
+}
+
+// Diff compares old against new.
+func Diff(old, new *gamedb.Database) *Result {
+	r := &Result{}
+	for _, ref := range unionRefs(old, new) {
+		o, n := live(old, ref), live(new, ref)
+		switch {
+		case o == nil && n != nil:
+			r.Created = append(r.Created, n)
+		case o != nil && n == nil:
+			r.Destroyed = append(r.Destroyed, o)
+		case o != nil && n != nil:
+			if d := diffObject(old, new, o, n); d != nil {
+				r.Changed = append(r.Changed, d)
+			}
+		}
+	}
+	return r
+}
+
+// live returns the object at ref unless it is missing or GARBAGE.
+func live(db *gamedb.Database, ref gamedb.DBRef) *gamedb.Object {
+	obj := db.Objects[ref]
+	if obj == nil || obj.ObjType() == gamedb.TypeGarbage {
+		return nil
+	}
+	return obj
+}
+
+func unionRefs(a, b *gamedb.Database) []gamedb.DBRef {
+	seen := make(map[gamedb.DBRef]bool, len(a.Objects))
+	refs := make([]gamedb.DBRef, 0, len(a.Objects))
+	for _, db := range []*gamedb.Database{a, b} {
+		for ref := range db.Objects {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+	return refs
+}
+
+func diffObject(olddb, newdb *gamedb.Database, o, n *gamedb.Object) *ObjectDiff {
+	d := &ObjectDiff{
+		Ref: n.DBRef, Name: n.Name,
+		OldOwner: o.Owner, NewOwner: n.Owner,
+		OldParent: o.Parent, NewParent: n.Parent,
+		OldZone: o.Zone, NewZone: n.Zone,
+	}
+	changed := o.Owner != n.Owner || o.Parent != n.Parent || o.Zone != n.Zone
+	if o.Name != n.Name {
+		d.OldName = o.Name
+		changed = true
+	}
+	oflags, nflags := o.Flags, n.Flags
+	oflags[0] &^= gamedb.TypeMask
+	nflags[0] &^= gamedb.TypeMask
+	for w := range oflags {
+		d.FlagsSet = append(d.FlagsSet, bitNames(flagNames, w, nflags[w]&^oflags[w])...)
+		d.FlagsCleared = append(d.FlagsCleared, bitNames(flagNames, w, oflags[w]&^nflags[w])...)
+	}
+	for w := range o.Powers {
+		d.PowersSet = append(d.PowersSet, bitNames(powerNames, w, n.Powers[w]&^o.Powers[w])...)
+		d.PowersCleared = append(d.PowersCleared, bitNames(powerNames, w, o.Powers[w]&^n.Powers[w])...)
+	}
+	d.Attrs = diffAttrs(attrsByName(olddb, o), attrsByName(newdb, n))
+
+	if !changed && len(d.FlagsSet)+len(d.FlagsCleared)+len(d.PowersSet)+len(d.PowersCleared)+len(d.Attrs) == 0 {
+		return nil
+	}
+	return d
+}
+
+func attrsByName(db *gamedb.Database, obj *gamedb.Object) map[string]string {
+	m := make(map[string]string, len(obj.Attrs))
+	for _, a := range obj.Attrs {
+		name := db.GetAttrName(a.Number)
+		if name == "" {
+			name = fmt.Sprintf("#%d", a.Number)
+		}
+		m[name] = a.Value
+	}
+	return m
+}
+
+func diffAttrs(old, new map[string]string) []AttrDiff {
+	var out []AttrDiff
+	for name, ov := range old {
+		nv, ok := new[name]
+		switch {
+		case !ok:
+			out = append(out, AttrDiff{Name: name, Old: ov, Removed: true})
+		case ov != nv:
+			out = append(out, AttrDiff{Name: name, Old: ov, New: nv})
+		}
+	}
+	for name, nv := range new {
+		if _, ok := old[name]; !ok {
+			out = append(out, AttrDiff{Name: name, New: nv, Added: true})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Write prints r as a report, one object per paragraph. Attribute values
+// are shown without their owner/flags prefix; a change to the prefix
+// alone shows as "owner/flags changed".
+func (r *Result) Write(w io.Writer) {
+	if r.Empty() {
+		fmt.Fprintln(w, "No differences.")
+		return
+	}
+	fmt.Fprintf(w, "%d created, %d destroyed, %d changed\n", len(r.Created), len(r.Destroyed), len(r.Changed))
+	if len(r.Created) > 0 {
+		fmt.Fprintln(w, "\n=== CREATED ===")
+		for _, obj := range r.Created {
+			fmt.Fprintf(w, "  #%d %s (%s, owner #%d)\n", obj.DBRef, obj.Name, obj.ObjType(), obj.Owner)
+		}
+	}
+	if len(r.Destroyed) > 0 {
+		fmt.Fprintln(w, "\n=== DESTROYED ===")
+		for _, obj := range r.Destroyed {
+			fmt.Fprintf(w, "  #%d %s (%s, owner #%d)\n", obj.DBRef, obj.Name, obj.ObjType(), obj.Owner)
+		}
+	}
+	if len(r.Changed) > 0 {
+		fmt.Fprintln(w, "\n=== CHANGED ===")
+		for _, d := range r.Changed {
+			d.write(w)
+		}
+	}
+}
+
+func (d *ObjectDiff) write(w io.Writer) {
+	fmt.Fprintf(w, "\n#%d %s\n", d.Ref, d.Name)
+	if d.OldName != "" {
+		fmt.Fprintf(w, "  name:   %s -> %s\n", d.OldName, d.Name)
+	}
+	if d.OldOwner != d.NewOwner {
+		fmt.Fprintf(w, "  owner:  #%d -> #%d\n", d.OldOwner, d.NewOwner)
+	}
+	if d.OldParent != d.NewParent {
+		fmt.Fprintf(w, "  parent: #%d -> #%d\n", d.OldParent, d.NewParent)
+	}
+	if d.OldZone != d.NewZone {
+		fmt.Fprintf(w, "  zone:   #%d -> #%d\n", d.OldZone, d.NewZone)
+	}
+	writeBits(w, "flags: ", d.FlagsSet, d.FlagsCleared)
+	writeBits(w, "powers:", d.PowersSet, d.PowersCleared)
+	for _, a := range d.Attrs {
+		switch {
+		case a.Added:
+			fmt.Fprintf(w, "  + %s: %s\n", a.Name, attrText(a.New))
+		case a.Removed:
+			fmt.Fprintf(w, "  - %s: %s\n", a.Name, attrText(a.Old))
+		case attrText(a.Old) == attrText(a.New):
+			fmt.Fprintf(w, "  ~ %s: owner/flags changed\n", a.Name)
+		default:
+			fmt.Fprintf(w, "  ~ %s: %s\n    -> %s\n", a.Name, attrText(a.Old), attrText(a.New))
+		}
+	}
+}
+
+func writeBits(w io.Writer, label string, set, cleared []string) {
+	if len(set)+len(cleared) == 0 {
+		return
+	}
+	var parts []string
+	for _, name := range set {
+		parts = append(parts, "+"+name)
+	}
+	for _, name := range cleared {
+		parts = append(parts, "-"+name)
+	}
+	fmt.Fprintf(w, "  %s %s\n", label, strings.Join(parts, " "))
+}
+
+// attrText strips an "\x01owner:flags:" prefix from a raw value.
+func attrText(raw string) string {
+	if !strings.HasPrefix(raw, "\x01") {
+		return raw
+	}
+	if parts := strings.SplitN(raw[1:], ":", 3); len(parts) == 3 {
+		return parts[2]
+	}
+	return raw
+}
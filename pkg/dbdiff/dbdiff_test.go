@@ -0,0 +1,102 @@
+package dbdiff
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+func testDB(objs ...*gamedb.Object) *gamedb.Database {
+	db := gamedb.NewDatabase()
+	db.AddAttrDef(256, "RACE", 0)
+	for _, obj := range objs {
+		db.Objects[obj.DBRef] = obj
+	}
+	return db
+}
+
+func thing(ref gamedb.DBRef, name string, attrs ...gamedb.Attribute) *gamedb.Object {
+	return &gamedb.Object{DBRef: ref, Name: name, Owner: 1, Parent: gamedb.Nothing, Zone: gamedb.Nothing,
+		Flags: [3]int{int(gamedb.TypeThing)}, Attrs: attrs}
+}
+
+func TestDiff(t *testing.T) {
+	old := testDB(
+		thing(1, "Wizard", gamedb.Attribute{Number: 6, Value: "Tall."}, gamedb.Attribute{Number: 256, Value: "Elf"}),
+		thing(2, "Sword"),
+		thing(3, "Shield"),
+		thing(4, "Unchanged", gamedb.Attribute{Number: 256, Value: "\x011:0:Orc"}),
+	)
+	changed := thing(1, "Wizard", gamedb.Attribute{Number: 6, Value: "Short."}, gamedb.Attribute{Number: 5, Value: "xyzzy"})
+	changed.Owner = 2
+	changed.Flags[0] |= gamedb.FlagWizard
+	changed.Powers[1] = gamedb.Pow2Builder
+	garbage := thing(3, "Garbage")
+	garbage.Flags[0] = int(gamedb.TypeGarbage)
+	// Same value under a different user attribute number.
+	moved := thing(4, "Unchanged", gamedb.Attribute{Number: 300, Value: "\x011:0:Orc"})
+	renamed := thing(2, "Blade")
+	renamed.Zone = 10
+	newdb := testDB(changed, renamed, garbage, moved, thing(5, "Helm"))
+	newdb.AddAttrDef(300, "RACE", 0)
+
+	r := Diff(old, newdb)
+	if len(r.Created) != 1 || r.Created[0].DBRef != 5 {
+		t.Errorf("created %v", r.Created)
+	}
+	if len(r.Destroyed) != 1 || r.Destroyed[0].DBRef != 3 {
+		t.Errorf("destroyed %v", r.Destroyed)
+	}
+	if len(r.Changed) != 2 {
+		t.Fatalf("changed %+v", r.Changed)
+	}
+
+	wiz := r.Changed[0]
+	if wiz.Ref != 1 || wiz.OldOwner != 1 || wiz.NewOwner != 2 || wiz.OldName != "" {
+		t.Errorf("wizard diff %+v", wiz)
+	}
+	if !reflect.DeepEqual(wiz.FlagsSet, []string{"WIZARD"}) || len(wiz.FlagsCleared) != 0 || !reflect.DeepEqual(wiz.PowersSet, []string{"builder"}) {
+		t.Errorf("flags +%v -%v, powers +%v", wiz.FlagsSet, wiz.FlagsCleared, wiz.PowersSet)
+	}
+	want := []AttrDiff{
+		{Name: "DESC", Old: "Tall.", New: "Short."},
+		{Name: "PASS", New: "xyzzy", Added: true},
+		{Name: "RACE", Old: "Elf", Removed: true},
+	}
+	if !reflect.DeepEqual(wiz.Attrs, want) {
+		t.Errorf("attrs %+v", wiz.Attrs)
+	}
+
+	sword := r.Changed[1]
+	if sword.Ref != 2 || sword.OldName != "Sword" || sword.Name != "Blade" || sword.NewZone != 10 {
+		t.Errorf("sword diff %+v", sword)
+	}
+
+	var buf bytes.Buffer
+	r.Write(&buf)
+	for _, line := range []string{"1 created, 1 destroyed, 2 changed", "#5 Helm (THING, owner #1)",
+		"owner:  #1 -> #2", "flags:  +WIZARD", "~ DESC: Tall.\n    -> Short.", "- RACE: Elf", "name:   Sword -> Blade"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("report lacks %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestDiffSame(t *testing.T) {
+	r := Diff(testDB(thing(1, "One")), testDB(thing(1, "One")))
+	var buf bytes.Buffer
+	r.Write(&buf)
+	if !r.Empty() || buf.String() != "No differences.\n" {
+		t.Errorf("got %+v, %q", r, buf.String())
+	}
+}
+
+func TestBitNames(t *testing.T) {
+	got := bitNames(flagNames, 1, gamedb.Flag2Ansi|0x00100000)
+	if !reflect.DeepEqual(got, []string{"ANSI", "word1:0x100000"}) {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,131 @@
+package dbdiff
+
+import (
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// bitName names one flag or power bit.
+type bitName struct {
+	Word int
+	Bit  int
+	Name string
+}
+
+var flagNames = []bitName{
+	{0, gamedb.FlagSeeThru, "SEE_THROUGH"},
+	{0, gamedb.FlagWizard, "WIZARD"},
+	{0, gamedb.FlagLinkOK, "LINK_OK"},
+	{0, gamedb.FlagDark, "DARK"},
+	{0, gamedb.FlagJumpOK, "JUMP_OK"},
+	{0, gamedb.FlagSticky, "STICKY"},
+	{0, gamedb.FlagDestroyOK, "DESTROY_OK"},
+	{0, gamedb.FlagHaven, "HAVEN"},
+	{0, gamedb.FlagQuiet, "QUIET"},
+	{0, gamedb.FlagHalt, "HALT"},
+	{0, gamedb.FlagTrace, "TRACE"},
+	{0, gamedb.FlagGoing, "GOING"},
+	{0, gamedb.FlagMonitor, "MONITOR"},
+	{0, gamedb.FlagMyopic, "MYOPIC"},
+	{0, gamedb.FlagPuppet, "PUPPET"},
+	{0, gamedb.FlagChownOK, "CHOWN_OK"},
+	{0, gamedb.FlagEnterOK, "ENTER_OK"},
+	{0, gamedb.FlagVisual, "VISUAL"},
+	{0, gamedb.FlagImmortal, "IMMORTAL"},
+	{0, gamedb.FlagHasStartup, "HAS_STARTUP"},
+	{0, gamedb.FlagOpaque, "OPAQUE"},
+	{0, gamedb.FlagVerbose, "VERBOSE"},
+	{0, gamedb.FlagInherit, "INHERIT"},
+	{0, gamedb.FlagNoSpoof, "NOSPOOF"},
+	{0, gamedb.FlagRobot, "ROBOT"},
+	{0, gamedb.FlagSafe, "SAFE"},
+	{0, gamedb.FlagRoyalty, "ROYALTY"},
+	{0, gamedb.FlagHearThru, "HEAR_THROUGH"},
+	{0, gamedb.FlagTerse, "TERSE"},
+	{1, gamedb.Flag2Key, "KEY"},
+	{1, gamedb.Flag2Abode, "ABODE"},
+	{1, gamedb.Flag2Floating, "FLOATING"},
+	{1, gamedb.Flag2Unfindable, "UNFINDABLE"},
+	{1, gamedb.Flag2ParentOK, "PARENT_OK"},
+	{1, gamedb.Flag2Light, "LIGHT"},
+	{1, gamedb.Flag2HasListen, "HAS_LISTEN"},
+	{1, gamedb.Flag2HasFwd, "HAS_FORWARDLIST"},
+	{1, gamedb.Flag2Connected, "CONNECTED"},
+	{1, gamedb.Flag2Slave, "SLAVE"},
+	{1, gamedb.Flag2Suspect, "SUSPECT"},
+	{1, gamedb.Flag2HTML, "HTML"},
+	{1, gamedb.Flag2Ansi, "ANSI"},
+	{1, gamedb.Flag2Blind, "BLIND"},
+	{1, gamedb.Flag2ControlOK, "CONTROL_OK"},
+	{1, gamedb.Flag2Watcher, "WATCHER"},
+	{1, gamedb.Flag2HasCommands, "HAS_COMMANDS"},
+	{1, gamedb.Flag2StopMatch, "STOP"},
+	{1, gamedb.Flag2Bounce, "BOUNCE"},
+	{1, gamedb.Flag2ZoneParent, "ZONE_PARENT"},
+	{1, gamedb.Flag2NoBLeed, "NO_BLEED"},
+	{1, gamedb.Flag2HasDaily, "HAS_DAILY"},
+	{1, gamedb.Flag2GoingTwice, "GOING_TWICE"},
+	{1, gamedb.Flag2Gagged, "GAGGED"},
+	{1, gamedb.Flag2Staff, "STAFF"},
+	{1, gamedb.Flag2Fixed, "FIXED"},
+}
+
+var powerNames = []bitName{
+	{0, gamedb.PowAnnounce, "announce"},
+	{0, gamedb.PowMdarkAttr, "attr_read"},
+	{0, gamedb.PowWizAttr, "attr_write"},
+	{0, gamedb.PowBoot, "boot"},
+	{1, gamedb.Pow2Builder, "builder"},
+	{0, gamedb.PowChownAny, "chown_anything"},
+	{1, gamedb.Pow2Cloak, "cloak"},
+	{0, gamedb.PowCommAll, "comm_all"},
+	{0, gamedb.PowControlAll, "control_all"},
+	{0, gamedb.PowWizardWho, "expanded_who"},
+	{0, gamedb.PowFindUnfind, "find_unfindable"},
+	{0, gamedb.PowFreeMoney, "free_money"},
+	{0, gamedb.PowFreeQuota, "free_quota"},
+	{0, gamedb.PowGuest, "guest"},
+	{0, gamedb.PowHalt, "halt"},
+	{0, gamedb.PowHide, "hide"},
+	{0, gamedb.PowIdle, "idle"},
+	{1, gamedb.Pow2LinkHome, "link_any_home"},
+	{1, gamedb.Pow2LinkToAny, "link_to_anything"},
+	{1, gamedb.Pow2LinkVar, "link_variable"},
+	{0, gamedb.PowLongfingers, "long_fingers"},
+	{0, gamedb.PowNoDestroy, "no_destroy"},
+	{1, gamedb.Pow2OpenAnyLoc, "open_anywhere"},
+	{0, gamedb.PowPassLocks, "pass_locks"},
+	{0, gamedb.PowPoll, "poll"},
+	{0, gamedb.PowProg, "prog"},
+	{0, gamedb.PowChgQuotas, "quota"},
+	{0, gamedb.PowSearch, "search"},
+	{0, gamedb.PowExamAll, "see_all"},
+	{0, gamedb.PowSeeQueue, "see_queue"},
+	{0, gamedb.PowSeeHidden, "see_hidden"},
+	{0, gamedb.PowStatAny, "stat_any"},
+	{0, gamedb.PowSteal, "steal_money"},
+	{0, gamedb.PowTelAnywhr, "tel_anywhere"},
+	{0, gamedb.PowTelUnrst, "tel_anything"},
+	{0, gamedb.PowUnkillable, "unkillable"},
+	{0, gamedb.PowWatch, "watch_logins"},
+}
+
+// bitNames names the bits set in mask, a value from flag or power word
+// word. Bits with no name show as "word<n>:0x<bit>".
+func bitNames(table []bitName, word, mask int) []string {
+	var names []string
+	for _, b := range table {
+		if b.Word == word && mask&b.Bit != 0 {
+			names = append(names, b.Name)
+			mask &^= b.Bit
+		}
+	}
+	for bit := 1; mask != 0 && bit != 0; bit <<= 1 {
+		if mask&bit != 0 {
+			names = append(names, fmt.Sprintf("word%d:0x%x", word, bit))
+			mask &^= bit
+		}
+	}
+	return names
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Xterm256ToEsc returns an ANSI escape sequence for a xterm-256 color index.
@@ -73,6 +74,253 @@ func ParseColorSpec(spec string, bg bool) string {
 	return ""
 }
 
+// ColorDepth describes how much color a client can render.
+type ColorDepth int
+
+const (
+	ColorNone ColorDepth = iota // No ANSI support; escapes are stripped
+	Color16                     // Basic 8/16-color ANSI (SGR 30-37, 40-47, 90-97)
+	Color256                    // xterm-256 palette (SGR 38;5;N)
+	ColorTrue                   // 24-bit truecolor (SGR 38;2;R;G;B)
+)
+
+// String returns a short name for the color depth.
+func (c ColorDepth) String() string {
+	switch c {
+	case ColorNone:
+		return "none"
+	case Color16:
+		return "ansi"
+	case Color256:
+		return "xterm256"
+	case ColorTrue:
+		return "truecolor"
+	}
+	return "unknown"
+}
+
+// ansiPalette holds the RGB values of the 16 basic xterm colors, used when
+// mapping extended colors down to plain ANSI.
+var ansiPalette = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels are the component intensities of the xterm 6x6x6 color cube.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// Xterm256ToRGB returns the RGB value of a xterm-256 color index.
+func Xterm256ToRGB(index int) (r, g, b int) {
+	switch {
+	case index < 0 || index > 255:
+		return 0, 0, 0
+	case index < 16:
+		c := ansiPalette[index]
+		return c[0], c[1], c[2]
+	case index < 232:
+		index -= 16
+		return cubeLevels[index/36], cubeLevels[(index/6)%6], cubeLevels[index%6]
+	}
+	v := 8 + (index-232)*10
+	return v, v, v
+}
+
+// RGBToXterm256 returns the xterm-256 index closest to an RGB color,
+// choosing between the color cube and the grayscale ramp.
+func RGBToXterm256(r, g, b int) int {
+	nearestLevel := func(v int) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if absInt(v-l) < absInt(v-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	ri, gi, bi := nearestLevel(r), nearestLevel(g), nearestLevel(b)
+	cube := 16 + 36*ri + 6*gi + bi
+	cr, cg, cb := Xterm256ToRGB(cube)
+
+	avg := (r + g + b) / 3
+	gray := 232
+	if avg > 8 {
+		gray = 232 + (avg-8+5)/10
+		if gray > 255 {
+			gray = 255
+		}
+	}
+	gr, gg, gb := Xterm256ToRGB(gray)
+
+	if colorDist(r, g, b, gr, gg, gb) < colorDist(r, g, b, cr, cg, cb) {
+		return gray
+	}
+	return cube
+}
+
+// RGBToAnsi16 returns the index (0-15) of the basic ANSI color closest to
+// an RGB color.
+func RGBToAnsi16(r, g, b int) int {
+	best, bestDist := 0, -1
+	for i, c := range ansiPalette {
+		d := colorDist(r, g, b, c[0], c[1], c[2])
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func colorDist(r1, g1, b1, r2, g2, b2 int) int {
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	return dr*dr + dg*dg + db*db
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// ansi16SGR returns the SGR parameter for basic color index 0-15.
+func ansi16SGR(index int, bg bool) string {
+	base := 30
+	if index >= 8 {
+		base = 90
+		index -= 8
+	}
+	if bg {
+		base += 10
+	}
+	return strconv.Itoa(base + index)
+}
+
+// StripAnsi removes all ANSI CSI escape sequences from s.
+func StripAnsi(s string) string {
+	if strings.IndexByte(s, '\033') < 0 {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\033' {
+			i = skipEscape(s, i)
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// VisibleWidth returns the number of display columns in s: ANSI escape
+// sequences take no space and multi-byte UTF-8 characters count once.
+func VisibleWidth(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\033' {
+			i = skipEscape(s, i) + 1
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// skipEscape returns the index of the last byte of the escape sequence
+// starting at s[i] (which must be ESC). A CSI sequence (ESC [) runs through
+// its final byte in the range 0x40-0x7E; any other ESC pair is two bytes.
+func skipEscape(s string, i int) int {
+	if i+1 >= len(s) {
+		return i
+	}
+	if s[i+1] != '[' {
+		return i + 1
+	}
+	j := i + 2
+	for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+		j++
+	}
+	if j >= len(s) {
+		return len(s) - 1
+	}
+	return j
+}
+
+// DowngradeAnsi rewrites the SGR color sequences in s so they fit within
+// the given color depth. Truecolor is mapped to the nearest xterm-256 or
+// basic ANSI color, xterm-256 to the nearest basic color, and at ColorNone
+// every escape sequence is removed. Other escapes pass through unchanged.
+func DowngradeAnsi(s string, depth ColorDepth) string {
+	if depth >= ColorTrue || strings.IndexByte(s, '\033') < 0 {
+		return s
+	}
+	if depth == ColorNone {
+		return StripAnsi(s)
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\033' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		end := skipEscape(s, i)
+		seq := s[i : end+1]
+		if len(seq) >= 3 && seq[1] == '[' && seq[len(seq)-1] == 'm' {
+			seq = downgradeSGR(seq[2:len(seq)-1], depth)
+		}
+		sb.WriteString(seq)
+		i = end
+	}
+	return sb.String()
+}
+
+// downgradeSGR rewrites the parameter list of one SGR sequence.
+func downgradeSGR(params string, depth ColorDepth) string {
+	parts := strings.Split(params, ";")
+	out := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		p := parts[i]
+		if (p != "38" && p != "48") || i+1 >= len(parts) {
+			out = append(out, p)
+			continue
+		}
+		bg := p == "48"
+		switch {
+		case parts[i+1] == "5" && i+2 < len(parts):
+			idx, _ := strconv.Atoi(parts[i+2])
+			i += 2
+			if depth >= Color256 {
+				out = append(out, p, "5", strconv.Itoa(idx))
+				continue
+			}
+			if idx < 16 {
+				out = append(out, ansi16SGR(idx, bg))
+				continue
+			}
+			r, g, b := Xterm256ToRGB(idx)
+			out = append(out, ansi16SGR(RGBToAnsi16(r, g, b), bg))
+		case parts[i+1] == "2" && i+4 < len(parts):
+			r, _ := strconv.Atoi(parts[i+2])
+			g, _ := strconv.Atoi(parts[i+3])
+			b, _ := strconv.Atoi(parts[i+4])
+			i += 4
+			if depth >= Color256 {
+				out = append(out, p, "5", strconv.Itoa(RGBToXterm256(r, g, b)))
+				continue
+			}
+			out = append(out, ansi16SGR(RGBToAnsi16(r, g, b), bg))
+		default:
+			out = append(out, p)
+		}
+	}
+	return "\033[" + strings.Join(out, ";") + "m"
+}
+
 // cssColors maps CSS color names to [R, G, B] values.
 var cssColors = map[string][3]int{
 	"aliceblue":            {240, 248, 255},
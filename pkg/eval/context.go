@@ -2,6 +2,8 @@ package eval
 
 import (
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 )
@@ -82,8 +84,14 @@ type GameState interface {
 	ConnTime(player gamedb.DBRef) float64
 	// IdleTime returns idle time in seconds for a player (-1 if not connected).
 	IdleTime(player gamedb.DBRef) float64
+	// Now returns the game's current time, which @timewarp may move.
+	Now() time.Time
 	// DoingString returns a player's @doing string.
 	DoingString(player gamedb.DBRef) string
+	// Poll returns the WHO poll set with @doing/header.
+	Poll() string
+	// Locatable returns true if player may learn where target is.
+	Locatable(player, target gamedb.DBRef) bool
 	// IsConnected returns true if the player is connected.
 	IsConnected(player gamedb.DBRef) bool
 	// LookupPlayer finds a player by name (partial match).
@@ -127,6 +135,9 @@ type GameState interface {
 	ExecuteSQL(player gamedb.DBRef, query, rowDelim, fieldDelim string) string
 	// EscapeSQL escapes a string for safe SQL interpolation (doubles single quotes).
 	EscapeSQL(input string) string
+	// HTTPRequest fetches a URL (method GET or POST) for a wizard, returning
+	// the response body or an error string. Honors http_enabled and the limits.
+	HTTPRequest(player gamedb.DBRef, method, url, body, contentType string) string
 	// EvalLockStr parses and evaluates a lock expression string.
 	// Returns true if actor passes the lock on thing.
 	EvalLockStr(player, thing, actor gamedb.DBRef, lockStr string) bool
@@ -152,10 +163,38 @@ type GameState interface {
 	// MailSubject returns the subject of message #num for player.
 	// Returns "" if not found or mail disabled.
 	MailSubject(player gamedb.DBRef, num int) string
+	// MailStats returns "<read> <unread> <cleared>" for target's mailbox.
+	// Players may check their own mailbox; Wizards may check anyone's.
+	MailStats(player, target gamedb.DBRef) string
+	// MailList returns target's message numbers, limited to one folder
+	// unless folder is negative.
+	MailList(player, target gamedb.DBRef, folder int) string
+	// MailMessageField returns a field of message #num in target's mailbox.
+	// Valid fields: body, subject, from, to, cc, time, flags, folder.
+	MailMessageField(player, target gamedb.DBRef, num int, field string) string
+	// MailMarkRead marks message #num in player's own mailbox as read.
+	MailMarkRead(player gamedb.DBRef, num int)
+	// MailSend delivers mail from player to a list of recipient names.
+	// Returns "" on success or an error string.
+	MailSend(player gamedb.DBRef, recipients, subject, body string) string
 	// ChannelInfo returns a field value for a channel by name.
 	// Valid fields: owner, description, header, flags, numsent, subscribers, joinlock, translock, recvlock, charge.
 	// Returns "" if channel not found, unknown field, or player lacks permission (must be channel owner or Wizard).
 	ChannelInfo(player gamedb.DBRef, name, field string) string
+	// ChannelList returns the sorted names of all channels visible to player.
+	ChannelList(player gamedb.DBRef) []string
+	// ChannelWho returns a space-separated dbref list of a channel's members.
+	// If all is false, only connected, listening members are returned.
+	ChannelWho(player gamedb.DBRef, name string, all bool) string
+	// ChannelFlags returns the flag list for a channel visible to player.
+	ChannelFlags(player gamedb.DBRef, name string) string
+	// ChannelAliases returns target's channel aliases (player must control target).
+	ChannelAliases(player, target gamedb.DBRef) string
+	// ChannelAliasField returns a field (channel, title, status) of target's alias.
+	ChannelAliasField(player, target gamedb.DBRef, alias, field string) string
+	// ChannelEmit sends a headerless message to a channel.
+	// Returns "" on success or an error string. Requires owner, Comm_All, or Wizard.
+	ChannelEmit(player gamedb.DBRef, name, message string) string
 	// ListAttrDefs returns a space-separated list of user-defined attribute names
 	// matching the given pattern (wildcard). Empty pattern matches all.
 	// objType filters by object type ("player", "thing", "room", "exit", or "" for all).
@@ -170,11 +209,37 @@ type GameState interface {
 	// SetAttrDefFlags modifies flags on a user-defined attribute definition.
 	// Returns "" on success, error string on failure. Wizard-only.
 	SetAttrDefFlags(player gamedb.DBRef, attrName, flags string) string
+	// QueuePIDs returns the PIDs of queue entries run by target, or of every
+	// entry player may see if target is Nothing.
+	// Requires control of target or the see_queue power.
+	QueuePIDs(player, target gamedb.DBRef) string
+	// QueueEntries returns the number of queue entries run by target.
+	// Requires control of target or the see_queue power.
+	QueueEntries(player, target gamedb.DBRef) string
+	// SemaphoreCount returns the number of commands waiting on target's
+	// semaphore attribute (SEMAPHORE if attrName is empty).
+	SemaphoreCount(player, target gamedb.DBRef, attrName string) string
+	// SearchObjects runs an @search specification for player and returns
+	// the matching dbrefs, space-separated, or "#-1 <ERROR>". exec
+	// evaluates eval= predicates in the caller's context.
+	SearchObjects(player gamedb.DBRef, spec string, exec func(string) string) string
 	// IsWizard returns true if the player is an effective wizard.
 	IsWizard(player gamedb.DBRef) bool
+	// CanObjeval reports whether player may evaluate as obj with objeval().
+	CanObjeval(player, obj gamedb.DBRef) bool
+	// UFunExecutor returns who u() of an attribute on thing runs as,
+	// given the attribute's raw value: thing, or the attribute's setter
+	// if running as thing would lend it privileges it shouldn't have.
+	UFunExecutor(thing gamedb.DBRef, rawAttr string) gamedb.DBRef
+	// TraceOutput sends a line of TRACE output about obj to obj's owner.
+	TraceOutput(obj gamedb.DBRef, line string)
 	// GetObjLockStr returns the serialized default lock (obj.Lock BoolExp) for an object.
 	// Returns "" if no header lock is set. Used as fallback when attr 42 is empty.
 	GetObjLockStr(obj gamedb.DBRef) string
+	// SideEffect runs the command behind a side-effect function (create,
+	// dig, open, tel, link, set, wipe) as player and returns the
+	// function's result.
+	SideEffect(player gamedb.DBRef, fn string, args []string) string
 }
 
 // EvalContext is the execution context for MUSH expression evaluation.
@@ -202,6 +267,32 @@ type EvalContext struct {
 	FuncNestLim int // default 50
 	FuncInvkLim int // default 2500
 
+	// CPULim caps the wall-clock time one context may spend calling
+	// functions (function_cpu_limit), timed from its first call; 0 is no
+	// limit. Once it is spent every further call fails.
+	CPULim     time.Duration
+	cpuStart   time.Time
+	cpuOverrun bool
+
+	// FloatPrecision is how many decimal places floating-point results
+	// show (float_precision).
+	FloatPrecision int
+
+	// StructLim and InstanceLim cap the structures and instances one
+	// object may have (struct_limit, instance_limit); 0 is no limit.
+	StructLim   int
+	InstanceLim int
+
+	// RegisterLim caps how many named local registers may be set
+	// (register_limit); 0 is no limit.
+	RegisterLim int
+
+	// TraceTopDown and TraceLim govern the output of TRACE objects
+	// (trace_topdown, trace_output_limit); TraceLim 0 is no limit.
+	TraceTopDown bool
+	TraceLim     int
+	trace        *traceState
+
 	// Current command text
 	CurrCmd string
 
@@ -217,11 +308,21 @@ type EvalContext struct {
 	// ANSI colors enabled
 	AnsiColors bool
 
-	// User-defined functions (name -> UFun)
+	// %c is the current command rather than an ANSI color (c_is_command)
+	CIsCommand bool
+
+	// User-defined functions (name -> UFun). Usually the game's own map,
+	// shared rather than copied, so it is only read here.
 	UFunctions map[string]*UFunction
 
-	// Built-in function registry
-	Functions map[string]*Function
+	// Built-in function registry. It may be shared with other contexts
+	// (see SetFunctions); change it with RegisterFunction or AliasFunction.
+	Functions   map[string]*Function
+	sharedFuncs bool
+
+	// Function aliases (alias -> target, uppercase), checked before
+	// Functions. Shared like UFunctions.
+	FuncAliases map[string]string
 
 	// Game identity (set from game config)
 	MudName    string
@@ -285,24 +386,41 @@ const (
 	FnPres    = 0x0010 // Preserve registers across call
 )
 
+// ctxPool holds released contexts for NewEvalContext to reuse.
+var ctxPool = sync.Pool{New: func() any { return new(EvalContext) }}
+
 // NewEvalContext creates an EvalContext with reasonable defaults.
+// Call Release when done with it, if nothing else can still be using it.
 func NewEvalContext(db *gamedb.Database) *EvalContext {
-	ctx := &EvalContext{
-		DB:            db,
-		Player:        gamedb.Nothing,
-		Caller:        gamedb.Nothing,
-		Cause:         gamedb.Nothing,
-		RData:         NewRegisterData(),
-		FuncNestLim:   50,
-		FuncInvkLim:   2500,
-		SpaceCompress: false,
-		AnsiColors:    true,
-		UFunctions:    make(map[string]*UFunction),
-		Functions:     make(map[string]*Function),
+	ctx := ctxPool.Get().(*EvalContext)
+	*ctx = EvalContext{
+		DB:             db,
+		Player:         gamedb.Nothing,
+		Caller:         gamedb.Nothing,
+		Cause:          gamedb.Nothing,
+		RData:          NewRegisterData(),
+		FuncNestLim:    50,
+		FuncInvkLim:    2500,
+		FloatPrecision: 6,
+		StructLim:      100,
+		InstanceLim:    100,
+		RegisterLim:    50,
+		TraceTopDown:   true,
+		TraceLim:       200,
+		SpaceCompress:  false,
+		AnsiColors:     true,
 	}
 	return ctx
 }
 
+// Release returns ctx to be reused by NewEvalContext. Nothing may use ctx
+// afterwards; its RData and Notifications are left alone, so anything
+// that kept those keeps them intact.
+func (ctx *EvalContext) Release() {
+	*ctx = EvalContext{}
+	ctxPool.Put(ctx)
+}
+
 // GetAttrValue fetches an attribute value for an object from the DB.
 // Returns the raw value string including owner:flags:data prefix.
 func (ctx *EvalContext) GetAttrValue(obj gamedb.DBRef, attrNum int) string {
@@ -310,12 +428,8 @@ func (ctx *EvalContext) GetAttrValue(obj gamedb.DBRef, attrNum int) string {
 	if !ok {
 		return ""
 	}
-	for _, attr := range dbObj.Attrs {
-		if attr.Number == attrNum {
-			return attr.Value
-		}
-	}
-	return ""
+	raw, _ := dbObj.Attr(attrNum)
+	return raw
 }
 
 // GetAttrText fetches the text portion of an attribute (after owner:flags: prefix).
@@ -354,6 +468,7 @@ func StripAttrPrefix(raw string) string {
 
 // RegisterFunction adds a built-in function to the registry.
 func (ctx *EvalContext) RegisterFunction(name string, handler FnHandler, nargs int, flags int) {
+	ctx.ownFunctions()
 	ctx.Functions[name] = &Function{
 		Name:    name,
 		Handler: handler,
@@ -366,6 +481,79 @@ func (ctx *EvalContext) RegisterFunction(name string, handler FnHandler, nargs i
 // Both alias and target should be uppercase.
 func (ctx *EvalContext) AliasFunction(alias, target string) {
 	if fn, ok := ctx.Functions[target]; ok {
+		ctx.ownFunctions()
 		ctx.Functions[alias] = fn
 	}
 }
+
+// SetFunctions makes table ctx's built-in functions without copying it,
+// so one table can serve every context. RegisterFunction and
+// AliasFunction copy it before their first change; table itself is never
+// written.
+func (ctx *EvalContext) SetFunctions(table map[string]*Function) {
+	ctx.Functions = table
+	ctx.sharedFuncs = true
+}
+
+// ownFunctions gives ctx a Functions map of its own to change.
+func (ctx *EvalContext) ownFunctions() {
+	if ctx.Functions != nil && !ctx.sharedFuncs {
+		return
+	}
+	own := make(map[string]*Function, len(ctx.Functions)+1)
+	for name, fn := range ctx.Functions {
+		own[name] = fn
+	}
+	ctx.Functions = own
+	ctx.sharedFuncs = false
+}
+
+// Now returns the current time by the game's clock, or the system's when
+// there is no game.
+func (ctx *EvalContext) Now() time.Time {
+	if ctx.GameState != nil {
+		return ctx.GameState.Now()
+	}
+	return time.Now()
+}
+
+// CPUExceeded reports whether ctx has run into its CPU limit.
+func (ctx *EvalContext) CPUExceeded() bool {
+	return ctx.cpuOverrun
+}
+
+// overLimit reports whether a function may not be called because a limit
+// has been reached, writing the error to buf if so. It counts the call.
+func (ctx *EvalContext) overLimit(buf *strings.Builder) bool {
+	ctx.FuncInvkCtr++
+	if ctx.CPULim > 0 && !ctx.cpuOverrun {
+		if ctx.cpuStart.IsZero() {
+			ctx.cpuStart = time.Now()
+		} else if time.Since(ctx.cpuStart) > ctx.CPULim {
+			ctx.cpuOverrun = true
+		}
+	}
+	switch {
+	case ctx.cpuOverrun:
+		buf.WriteString("#-1 CPU LIMIT EXCEEDED")
+	case ctx.FuncNestLev >= ctx.FuncNestLim:
+		buf.WriteString("#-1 FUNCTION RECURSION LIMIT EXCEEDED")
+	case ctx.FuncInvkCtr >= ctx.FuncInvkLim:
+		buf.WriteString("#-1 FUNCTION INVOCATION LIMIT EXCEEDED")
+	default:
+		return false
+	}
+	return true
+}
+
+// LookupFunction finds a built-in function by uppercase name, following
+// FuncAliases.
+func (ctx *EvalContext) LookupFunction(name string) (*Function, bool) {
+	if target, ok := ctx.FuncAliases[name]; ok {
+		if fn, ok := ctx.Functions[target]; ok {
+			return fn, true
+		}
+	}
+	fn, ok := ctx.Functions[name]
+	return fn, ok
+}
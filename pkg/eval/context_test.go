@@ -0,0 +1,72 @@
+package eval_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+func newContext(db *gamedb.Database) *eval.EvalContext {
+	ctx := eval.NewEvalContext(db)
+	functions.RegisterAll(ctx)
+	return ctx
+}
+
+func TestSharedFunctionTable(t *testing.T) {
+	db := gamedb.NewDatabase()
+	a, b := newContext(db), newContext(db)
+	a.RegisterFunction("ADD", nil, 0, 0)
+	a.AliasFunction("PLUS", "ADD")
+	if _, ok := b.LookupFunction("PLUS"); ok {
+		t.Error("an alias made on one context showed up on another")
+	}
+	if fn, ok := b.LookupFunction("ADD"); !ok || fn.Handler == nil {
+		t.Error("re-registering ADD on one context replaced it on another")
+	}
+
+	b.FuncAliases = map[string]string{"SUM": "ADD"}
+	if got := b.Exec("sum(1,2)", eval.EvFCheck|eval.EvEval, nil); got != "3" {
+		t.Errorf("sum(1,2) through a function alias = %q, want 3", got)
+	}
+}
+
+func TestEvalLimits(t *testing.T) {
+	ctx := newContext(gamedb.NewDatabase())
+	ctx.FuncNestLim = 2
+	if got := ctx.Exec("iter(a,iter(b,iter(c,x)))", eval.EvFCheck|eval.EvEval, nil); got != "#-1 FUNCTION RECURSION LIMIT EXCEEDED" {
+		t.Errorf("nested past the recursion limit = %q", got)
+	}
+
+	ctx = newContext(gamedb.NewDatabase())
+	ctx.FuncInvkLim = 1000000
+	ctx.CPULim = time.Millisecond
+	got := ctx.Exec("iter(lnum(100000),add(1,1))", eval.EvFCheck|eval.EvEval, nil)
+	if !ctx.CPUExceeded() || !strings.HasSuffix(got, "#-1 CPU LIMIT EXCEEDED") {
+		t.Errorf("past the CPU limit: exceeded = %v, result ends %q", ctx.CPUExceeded(), got[max(0, len(got)-40):])
+	}
+	if got := ctx.Exec("add(1,1)", eval.EvFCheck|eval.EvEval, nil); got != "#-1 CPU LIMIT EXCEEDED" {
+		t.Errorf("a call after the CPU limit = %q", got)
+	}
+}
+
+func BenchmarkNewContext(b *testing.B) {
+	db := gamedb.NewDatabase()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newContext(db).Release()
+	}
+}
+
+func BenchmarkContextExec(b *testing.B) {
+	db := gamedb.NewDatabase()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx := newContext(db)
+		ctx.Exec("add(1,mul(2,3))", eval.EvFCheck|eval.EvEval, nil)
+		ctx.Release()
+	}
+}
@@ -11,6 +11,14 @@ import (
 // Exec evaluates a MUSH expression string and returns the result.
 // This is the main entry point corresponding to TinyMUSH's exec() function.
 func (ctx *EvalContext) Exec(input string, evalFlags int, cargs []string) string {
+	if ctx.tracing(evalFlags) {
+		return ctx.traceExec(input, evalFlags, cargs)
+	}
+	return ctx.execString(input, evalFlags, cargs)
+}
+
+// execString evaluates input into a fresh buffer.
+func (ctx *EvalContext) execString(input string, evalFlags int, cargs []string) string {
 	var buf strings.Builder
 	buf.Grow(len(input) * 2)
 	ctx.exec(&buf, input, evalFlags, cargs)
@@ -143,7 +151,7 @@ func (ctx *EvalContext) exec(buf *strings.Builder, input string, evalFlags int,
 			funcNameUpper := strings.ToUpper(funcName)
 
 			// Look up built-in function
-			fn, ok := ctx.Functions[funcNameUpper]
+			fn, ok := ctx.LookupFunction(funcNameUpper)
 			if !ok {
 				// Check for @function-defined (UFunction) functions
 				if uf, ufOK := ctx.UFunctions[funcNameUpper]; ufOK {
@@ -171,12 +179,7 @@ func (ctx *EvalContext) exec(buf *strings.Builder, input string, evalFlags int,
 					buf.WriteString(truncated2)
 					// Call the UFunction: fetch attr, evaluate with args as %0-%9
 					ctx.FuncNestLev++
-					ctx.FuncInvkCtr++
-					if ctx.FuncNestLev >= ctx.FuncNestLim {
-						buf.WriteString("#-1 FUNCTION RECURSION LIMIT EXCEEDED")
-					} else if ctx.FuncInvkCtr >= ctx.FuncInvkLim {
-						buf.WriteString("#-1 FUNCTION INVOCATION LIMIT EXCEEDED")
-					} else {
+					if !ctx.overLimit(buf) {
 						attrText := ctx.GetAttrText(uf.Obj, uf.Attr)
 						if attrText != "" {
 							// Evaluate as the object (privileged) or as caller
@@ -268,19 +271,16 @@ func (ctx *EvalContext) exec(buf *strings.Builder, input string, evalFlags int,
 				nfargs = 0
 			}
 
-			// Check recursion and invocation limits
+			// Check recursion, invocation and CPU limits
 			ctx.FuncNestLev++
-			ctx.FuncInvkCtr++
-			if ctx.FuncNestLev >= ctx.FuncNestLim {
-				buf.WriteString("#-1 FUNCTION RECURSION LIMIT EXCEEDED")
-			} else if ctx.FuncInvkCtr >= ctx.FuncInvkLim {
-				buf.WriteString("#-1 FUNCTION INVOCATION LIMIT EXCEEDED")
-			} else if fn.Flags&FnVarArgs != 0 || nfargs == fn.NArgs || nfargs == -fn.NArgs {
-				// Call the function
-				fn.Handler(ctx, evaledArgs, buf, ctx.Caller, ctx.Cause)
-			} else {
-				buf.WriteString(fmt.Sprintf("#-1 FUNCTION (%s) EXPECTS %d ARGUMENTS BUT GOT %d",
-					fn.Name, fn.NArgs, nfargs))
+			if !ctx.overLimit(buf) {
+				if fn.Flags&FnVarArgs != 0 || nfargs == fn.NArgs || nfargs == -fn.NArgs {
+					// Call the function
+					fn.Handler(ctx, evaledArgs, buf, ctx.Caller, ctx.Cause)
+				} else {
+					buf.WriteString(fmt.Sprintf("#-1 FUNCTION (%s) EXPECTS %d ARGUMENTS BUT GOT %d",
+						fn.Name, fn.NArgs, nfargs))
+				}
 			}
 			ctx.FuncNestLev--
 			if evalFlags&EvFCheckPersist == 0 {
@@ -373,12 +373,40 @@ func (ctx *EvalContext) exec(buf *strings.Builder, input string, evalFlags int,
 }
 
 // handlePercent processes a %-substitution starting at input[pos] (the char after %).
-// Returns the new position.
+// Returns the new position. As in TinyMUSH, an uppercase substitution
+// letter capitalizes the first letter of what it produces (%S, %Q0, %VA...).
 func (ctx *EvalContext) handlePercent(buf *strings.Builder, input string, pos int, evalFlags int, cargs []string, ansi *bool) int {
 	if pos >= len(input) {
 		return pos
 	}
+	if ch := input[pos]; ch >= 'A' && ch <= 'Z' && !strings.ContainsRune("RTBX", rune(ch)) && (ch != 'C' || ctx.CIsCommand) {
+		var sub strings.Builder
+		pos = ctx.percentSub(&sub, input, pos, evalFlags, cargs, ansi)
+		buf.WriteString(capitalizeFirst(sub.String()))
+		return pos
+	}
+	return ctx.percentSub(buf, input, pos, evalFlags, cargs, ansi)
+}
 
+// capitalizeFirst uppercases the first letter of s, looking past any
+// leading ANSI escape sequences.
+func capitalizeFirst(s string) string {
+	i := 0
+	for i < len(s) && s[i] == '\033' {
+		end := strings.IndexByte(s[i:], 'm')
+		if end < 0 {
+			return s
+		}
+		i += end + 1
+	}
+	if i < len(s) && s[i] >= 'a' && s[i] <= 'z' {
+		return s[:i] + string(s[i]-'a'+'A') + s[i+1:]
+	}
+	return s
+}
+
+// percentSub does the work of handlePercent, without capitalization.
+func (ctx *EvalContext) percentSub(buf *strings.Builder, input string, pos int, evalFlags int, cargs []string, ansi *bool) int {
 	ch := input[pos]
 	switch ch {
 	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
@@ -423,11 +451,7 @@ func (ctx *EvalContext) handlePercent(buf *strings.Builder, input string, pos in
 	case 'n', 'N':
 		// Cause/enactor name
 		if obj, ok := ctx.DB.Objects[ctx.Cause]; ok {
-			name := obj.Name
-			if ch == 'N' && len(name) > 0 {
-				name = strings.ToUpper(name[:1]) + name[1:]
-			}
-			buf.WriteString(name)
+			buf.WriteString(obj.Name)
 		}
 		return pos + 1
 
@@ -442,38 +466,22 @@ func (ctx *EvalContext) handlePercent(buf *strings.Builder, input string, pos in
 
 	case 's', 'S':
 		// Subjective pronoun
-		pronoun := ctx.getPronoun(ctx.Cause, "subj")
-		if ch == 'S' && len(pronoun) > 0 {
-			pronoun = strings.ToUpper(pronoun[:1]) + pronoun[1:]
-		}
-		buf.WriteString(pronoun)
+		buf.WriteString(ctx.getPronoun(ctx.Cause, "subj"))
 		return pos + 1
 
 	case 'o', 'O':
 		// Objective pronoun
-		pronoun := ctx.getPronoun(ctx.Cause, "obj")
-		if ch == 'O' && len(pronoun) > 0 {
-			pronoun = strings.ToUpper(pronoun[:1]) + pronoun[1:]
-		}
-		buf.WriteString(pronoun)
+		buf.WriteString(ctx.getPronoun(ctx.Cause, "obj"))
 		return pos + 1
 
 	case 'p', 'P':
 		// Possessive pronoun
-		pronoun := ctx.getPronoun(ctx.Cause, "poss")
-		if ch == 'P' && len(pronoun) > 0 {
-			pronoun = strings.ToUpper(pronoun[:1]) + pronoun[1:]
-		}
-		buf.WriteString(pronoun)
+		buf.WriteString(ctx.getPronoun(ctx.Cause, "poss"))
 		return pos + 1
 
 	case 'a', 'A':
 		// Absolute possessive
-		pronoun := ctx.getPronoun(ctx.Cause, "aposs")
-		if ch == 'A' && len(pronoun) > 0 {
-			pronoun = strings.ToUpper(pronoun[:1]) + pronoun[1:]
-		}
-		buf.WriteString(pronoun)
+		buf.WriteString(ctx.getPronoun(ctx.Cause, "aposs"))
 		return pos + 1
 
 	case 'q', 'Q':
@@ -518,8 +526,14 @@ func (ctx *EvalContext) handlePercent(buf *strings.Builder, input string, pos in
 		}
 		return pos + 1
 
-	case 'x', 'X':
+	case 'x', 'X', 'c', 'C':
 		// ANSI color: %xn, %xr, %x<208>, %x<#FF5733>, %x/<208>, etc.
+		// %c is accepted as a synonym (%ch, %cr) for MUX-style softcode,
+		// unless c_is_command makes it the current command, as in TinyMUSH.
+		if (ch == 'c' || ch == 'C') && ctx.CIsCommand {
+			buf.WriteString(ctx.CurrCmd)
+			return pos + 1
+		}
 		pos++
 		if pos >= len(input) {
 			return pos
@@ -551,7 +565,7 @@ func (ctx *EvalContext) handlePercent(buf *strings.Builder, input string, pos in
 			}
 			// Fall back to single-char lookup (only if no / prefix)
 			if !bg {
-				ansiCode := ansiCharLookup(input[pos])
+				ansiCode := AnsiCode(input[pos])
 				if ansiCode != "" {
 					buf.WriteString(ansiCode)
 					if input[pos] == 'n' || input[pos] == 'N' {
@@ -606,6 +620,43 @@ func (ctx *EvalContext) handlePercent(buf *strings.Builder, input string, pos in
 		buf.WriteString(ctx.CurrCmd)
 		return pos + 1
 
+	case '=':
+		// Attribute on the executor: %=<attr>, same as v(attr)
+		pos++
+		if pos >= len(input) || input[pos] != '<' {
+			return pos
+		}
+		end := strings.IndexByte(input[pos:], '>')
+		if end < 0 {
+			return pos
+		}
+		name := strings.ToUpper(strings.TrimSpace(input[pos+1 : pos+end]))
+		if name != "" {
+			buf.WriteString(ctx.GetAttrByNameHelper(ctx.Player, name))
+		}
+		return pos + end + 1
+
+	case '_':
+		// Named variable set by setx()/xvars(): %_<name> or %_x
+		pos++
+		if pos >= len(input) {
+			return pos
+		}
+		name := input[pos : pos+1]
+		next := pos + 1
+		if input[pos] == '<' {
+			end := strings.IndexByte(input[pos:], '>')
+			if end < 0 {
+				return pos
+			}
+			name = input[pos+1 : pos+end]
+			next = pos + end + 1
+		}
+		if ctx.RData != nil {
+			buf.WriteString(ctx.RData.XRegs[strings.ToLower(strings.TrimSpace(name))])
+		}
+		return next
+
 	case '+':
 		// Number of function args
 		buf.WriteString(fmt.Sprintf("%d", len(cargs)))
@@ -850,36 +901,3 @@ func (ctx *EvalContext) getPronoun(obj gamedb.DBRef, ptype string) string {
 	}
 	return ""
 }
-
-// ansiCharLookup maps a character from %x? to an ANSI escape code.
-func ansiCharLookup(ch byte) string {
-	switch ch {
-	case 'n', 'N':
-		return "\033[0m"
-	case 'h', 'H':
-		return "\033[1m"
-	case 'i', 'I':
-		return "\033[7m"
-	case 'f', 'F':
-		return "\033[5m"
-	case 'u', 'U':
-		return "\033[4m"
-	case 'x', 'X':
-		return "\033[30m"
-	case 'r', 'R':
-		return "\033[31m"
-	case 'g', 'G':
-		return "\033[32m"
-	case 'y', 'Y':
-		return "\033[33m"
-	case 'b', 'B':
-		return "\033[34m"
-	case 'm', 'M':
-		return "\033[35m"
-	case 'c', 'C':
-		return "\033[36m"
-	case 'w', 'W':
-		return "\033[37m"
-	}
-	return ""
-}
@@ -0,0 +1,114 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// alignCol is one column of an align() layout.
+type alignCol struct {
+	width  int
+	just   int  // 0 left, 1 right, 2 center
+	repeat bool // '.': repeat while other columns have text
+	lines  []string
+}
+
+// fnAlign lays text out in side-by-side, word-wrapped columns, as
+// PennMUSH's align() does.
+//
+//	align(<widths>, <col 1>, ..., <col n>[, <filler>[, <col sep>[, <row sep>]]])
+//
+// <widths> is a space-separated list of column widths, each optionally
+// prefixed with < (left, the default), > (right) or - (center) and
+// suffixed with . to repeat the column's text for as long as any other
+// column has text. Widths count visible characters, so ANSI color
+// doesn't upset the layout.
+func fnAlign(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if len(args) < 2 {
+		buf.WriteString("#-1 FUNCTION (ALIGN) EXPECTS AT LEAST 2 ARGUMENTS")
+		return
+	}
+	specs := strings.Fields(args[0])
+	n := len(specs)
+	if n == 0 || len(args) < n+1 || len(args) > n+4 {
+		buf.WriteString("#-1 INVALID NUMBER OF ARGUMENTS")
+		return
+	}
+	fill, colSep, rowSep := " ", " ", "\r\n"
+	if len(args) > n+1 && args[n+1] != "" {
+		fill = args[n+1]
+	}
+	if len(args) > n+2 {
+		colSep = args[n+2]
+	}
+	if len(args) > n+3 {
+		rowSep = args[n+3]
+	}
+
+	cols := make([]alignCol, n)
+	rows := 0
+	for i, spec := range specs {
+		c := &cols[i]
+		switch spec[0] {
+		case '<':
+			spec = spec[1:]
+		case '>':
+			c.just, spec = 1, spec[1:]
+		case '-':
+			c.just, spec = 2, spec[1:]
+		}
+		if strings.HasSuffix(spec, ".") {
+			c.repeat, spec = true, strings.TrimSuffix(spec, ".")
+		}
+		c.width = toInt(spec)
+		if c.width < 1 || c.width > 4000 {
+			buf.WriteString("#-1 CANNOT HAVE COLUMNS THAT NARROW OR WIDE")
+			return
+		}
+		c.lines = wrapLines(args[i+1], c.width)
+		if !c.repeat && len(c.lines) > rows {
+			rows = len(c.lines)
+		}
+	}
+	if rows == 0 {
+		for _, c := range cols {
+			rows = max(rows, len(c.lines))
+		}
+	}
+
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			buf.WriteString(rowSep)
+		}
+		for i, c := range cols {
+			if i > 0 {
+				buf.WriteString(colSep)
+			}
+			line := ""
+			switch {
+			case r < len(c.lines):
+				line = c.lines[r]
+			case c.repeat && len(c.lines) > 0:
+				line = c.lines[r%len(c.lines)]
+			}
+			writeCell(buf, line, c.width, c.just, fill, true)
+		}
+	}
+}
+
+// wrapLines word-wraps text to width visible columns and returns the
+// lines, keeping the text's own line breaks.
+func wrapLines(text string, width int) []string {
+	if text == "" {
+		return nil
+	}
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		var b strings.Builder
+		wrapParagraph(&b, strings.TrimRight(para, "\r"), width, "", "")
+		lines = append(lines, strings.Split(b.String(), "\r\n")...)
+	}
+	return lines
+}
@@ -11,7 +11,7 @@ import (
 // Usage: cinfo(<channel>, <field>)
 // Fields: owner, description, header, flags, numsent, subscribers, joinlock, translock, recvlock, charge
 // Requires caller to be channel owner or Wizard.
-func fnCinfo(ctx *eval.EvalContext, args []string, buf *strings.Builder, executor, _ gamedb.DBRef) {
+func fnCinfo(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if ctx.GameState == nil || len(args) < 2 {
 		return
 	}
@@ -20,5 +20,93 @@ func fnCinfo(ctx *eval.EvalContext, args []string, buf *strings.Builder, executo
 	if name == "" || field == "" {
 		return
 	}
-	buf.WriteString(ctx.GameState.ChannelInfo(executor, name, field))
+	buf.WriteString(ctx.GameState.ChannelInfo(ctx.Player, name, field))
+}
+
+// fnComlist returns the channels visible to the executor.
+// Usage: comlist([<output delimiter>])  (alias: clist)
+func fnComlist(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil {
+		return
+	}
+	delim := " "
+	if len(args) > 0 && args[0] != "" {
+		delim = args[0]
+	}
+	buf.WriteString(strings.Join(ctx.GameState.ChannelList(ctx.Player), delim))
+}
+
+// fnCwho returns the dbrefs of connected players listening to a channel.
+// Usage: cwho(<channel>)
+func fnCwho(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil || len(args) < 1 {
+		return
+	}
+	buf.WriteString(ctx.GameState.ChannelWho(ctx.Player, strings.TrimSpace(args[0]), false))
+}
+
+// fnCwhoall returns the dbrefs of everything subscribed to a channel.
+// Usage: cwhoall(<channel>)
+func fnCwhoall(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil || len(args) < 1 {
+		return
+	}
+	buf.WriteString(ctx.GameState.ChannelWho(ctx.Player, strings.TrimSpace(args[0]), true))
+}
+
+// fnCflags returns a channel's flags (Public/Private, Loud, Objects, NoTitles).
+// Usage: cflags(<channel>)
+func fnCflags(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil || len(args) < 1 {
+		return
+	}
+	buf.WriteString(ctx.GameState.ChannelFlags(ctx.Player, strings.TrimSpace(args[0])))
+}
+
+// fnComalias returns a player's channel aliases.
+// Usage: comalias(<player>)
+func fnComalias(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil || len(args) < 1 {
+		return
+	}
+	target := resolveDBRef(ctx, args[0])
+	if _, ok := ctx.DB.Objects[target]; !ok {
+		buf.WriteString("#-1 NOT FOUND")
+		return
+	}
+	buf.WriteString(ctx.GameState.ChannelAliases(ctx.Player, target))
+}
+
+// fnComtitle returns the title a player has set on one of their aliases.
+// Usage: comtitle(<player>, <alias>)
+func fnComtitle(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	comAliasField(ctx, args, buf, "title")
+}
+
+// fnCominfo returns the channel name a player has aliased as <alias>.
+// Usage: cominfo(<player>, <alias>)
+func fnCominfo(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	comAliasField(ctx, args, buf, "channel")
+}
+
+func comAliasField(ctx *eval.EvalContext, args []string, buf *strings.Builder, field string) {
+	if ctx.GameState == nil || len(args) < 2 {
+		return
+	}
+	target := resolveDBRef(ctx, args[0])
+	if _, ok := ctx.DB.Objects[target]; !ok {
+		buf.WriteString("#-1 NOT FOUND")
+		return
+	}
+	buf.WriteString(ctx.GameState.ChannelAliasField(ctx.Player, target, strings.TrimSpace(args[1]), field))
+}
+
+// fnCemit sends a message to a channel without the channel header.
+// Usage: cemit(<channel>, <message>)
+// Requires channel ownership, Comm_All power, or Wizard.
+func fnCemit(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil || len(args) < 2 {
+		return
+	}
+	buf.WriteString(ctx.GameState.ChannelEmit(ctx.Player, strings.TrimSpace(args[0]), args[1]))
 }
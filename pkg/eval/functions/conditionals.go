@@ -1,13 +1,16 @@
 package functions
 
 import (
+	"regexp"
 	"strings"
 
 	"github.com/crystal-mush/gotinymush/pkg/eval"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 )
 
-func isTrue(s string) bool {
+// IsTrue is TinyMUSH's boolean test, as t() makes it: anything but an
+// empty string or 0 is true.
+func IsTrue(s string) bool {
 	s = strings.TrimSpace(s)
 	return s != "" && s != "0"
 }
@@ -17,7 +20,7 @@ func fnIf(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller, ca
 	if len(args) < 2 { return }
 	// Evaluate the condition
 	cond := ctx.Exec(args[0], eval.EvFCheck|eval.EvEval, nil)
-	if isTrue(cond) {
+	if IsTrue(cond) {
 		result := ctx.Exec(args[1], eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
 		buf.WriteString(result)
 	} else if len(args) > 2 {
@@ -98,6 +101,56 @@ func fnSwitchAll(ctx *eval.EvalContext, args []string, buf *strings.Builder, cal
 	ctx.Loop.SwitchToken = oldToken
 }
 
+// fnReswitch is switch() with regular expression patterns:
+// reswitch(str, re1, res1[, re2, res2]...[, default]).
+func fnReswitch(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	reswitchHelper(ctx, args, buf, false, false)
+}
+
+// fnReswitchi — case-insensitive reswitch.
+func fnReswitchi(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	reswitchHelper(ctx, args, buf, true, false)
+}
+
+// fnReswitchAll — reswitch() returning every matching result.
+func fnReswitchAll(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	reswitchHelper(ctx, args, buf, false, true)
+}
+
+// fnReswitchAlli — case-insensitive reswitchall.
+func fnReswitchAlli(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	reswitchHelper(ctx, args, buf, true, true)
+}
+
+func reswitchHelper(ctx *eval.EvalContext, args []string, buf *strings.Builder, caseInsensitive, all bool) {
+	if len(args) < 2 { return }
+	expr := ctx.Exec(args[0], eval.EvFCheck|eval.EvEval, nil)
+
+	oldSwitch := ctx.Loop.InSwitch
+	oldToken := ctx.Loop.SwitchToken
+	ctx.Loop.InSwitch++
+	ctx.Loop.SwitchToken = expr
+	defer func() {
+		ctx.Loop.InSwitch = oldSwitch
+		ctx.Loop.SwitchToken = oldToken
+	}()
+
+	matched := false
+	i := 1
+	for ; i+1 < len(args); i += 2 {
+		pattern := ctx.Exec(args[i], eval.EvFCheck|eval.EvEval, nil)
+		if caseInsensitive { pattern = "(?i)" + pattern }
+		re, err := regexp.Compile(pattern)
+		if err != nil || !re.MatchString(expr) { continue }
+		buf.WriteString(ctx.Exec(args[i+1], eval.EvFCheck|eval.EvEval|eval.EvStrip, nil))
+		if !all { return }
+		matched = true
+	}
+	if !matched && i < len(args) {
+		buf.WriteString(ctx.Exec(args[i], eval.EvFCheck|eval.EvEval|eval.EvStrip, nil))
+	}
+}
+
 // fnCase is like switch() but uses exact matching instead of wildcard.
 func fnCase(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller, cause gamedb.DBRef) {
 	if len(args) < 2 { return }
@@ -133,7 +186,7 @@ func fnCase(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller,
 func fnIffalse(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller, cause gamedb.DBRef) {
 	if len(args) < 2 { return }
 	cond := ctx.Exec(args[0], eval.EvFCheck|eval.EvEval, nil)
-	if !isTrue(cond) {
+	if !IsTrue(cond) {
 		buf.WriteString(ctx.Exec(args[1], eval.EvFCheck|eval.EvEval|eval.EvStrip, nil))
 	} else if len(args) > 2 {
 		buf.WriteString(ctx.Exec(args[2], eval.EvFCheck|eval.EvEval|eval.EvStrip, nil))
@@ -160,7 +213,7 @@ func fnIfzero(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller
 func fnUsetrue(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller, cause gamedb.DBRef) {
 	if len(args) < 2 { return }
 	cond := ctx.Exec(args[0], eval.EvFCheck|eval.EvEval, nil)
-	if isTrue(cond) {
+	if IsTrue(cond) {
 		attrSpec := ctx.Exec(args[1], eval.EvFCheck|eval.EvEval, nil)
 		var uargs []string
 		for _, a := range args[2:] {
@@ -175,7 +228,7 @@ func fnUsetrue(ctx *eval.EvalContext, args []string, buf *strings.Builder, calle
 func fnUsefalse(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller, cause gamedb.DBRef) {
 	if len(args) < 2 { return }
 	cond := ctx.Exec(args[0], eval.EvFCheck|eval.EvEval, nil)
-	if !isTrue(cond) {
+	if !IsTrue(cond) {
 		attrSpec := ctx.Exec(args[1], eval.EvFCheck|eval.EvEval, nil)
 		var uargs []string
 		for _, a := range args[2:] {
@@ -189,13 +242,13 @@ func fnUsefalse(ctx *eval.EvalContext, args []string, buf *strings.Builder, call
 // fnIsfalse — returns 1 if arg is false (empty or "0")
 func fnIsfalse(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("1"); return }
-	buf.WriteString(boolToStr(!isTrue(args[0])))
+	buf.WriteString(boolToStr(!IsTrue(args[0])))
 }
 
 // fnIstrue — returns 1 if arg is true (non-empty and not "0")
 func fnIstrue(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	buf.WriteString(boolToStr(isTrue(args[0])))
+	buf.WriteString(boolToStr(IsTrue(args[0])))
 }
 
 // fnUdefault — like default() but calls u(attr, args) instead of just getting
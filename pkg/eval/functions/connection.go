@@ -57,13 +57,26 @@ func fnIdleFn(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 	writeInt(buf, int(secs))
 }
 
-// fnDoingFn returns a player's @doing string.
+// fnDoingFn returns a player's @doing string, if the executor can see them on WHO.
 func fnDoingFn(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 || ctx.GameState == nil {
 		return
 	}
 	ref := resolveDBRef(ctx, args[0])
-	buf.WriteString(ctx.GameState.DoingString(ref))
+	for _, p := range ctx.GameState.ConnectedPlayersVisible(ctx.Player) {
+		if p == ref {
+			buf.WriteString(ctx.GameState.DoingString(ref))
+			return
+		}
+	}
+}
+
+// fnPoll returns the WHO poll.
+func fnPoll(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil {
+		return
+	}
+	buf.WriteString(ctx.GameState.Poll())
 }
 
 // fnPmatch matches a player name (partial) to a dbref.
@@ -343,6 +356,10 @@ func fnRloc(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 		return
 	}
 	ref := resolveDBRef(ctx, args[0])
+	if !locatable(ctx, ref) {
+		buf.WriteString("#-1")
+		return
+	}
 	maxDepth := 20
 	if len(args) > 1 {
 		maxDepth = toInt(args[1])
@@ -412,23 +429,7 @@ func fnNearby(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 // visLen returns the visual (display) length of a string, ignoring ANSI
 // escape sequences (\033[...m) which occupy zero columns.
 func visLen(s string) int {
-	n := 0
-	inEsc := false
-	for i := 0; i < len(s); i++ {
-		if inEsc {
-			if s[i] == 'm' {
-				inEsc = false
-			}
-			continue
-		}
-		if s[i] == '\033' && i+1 < len(s) && s[i+1] == '[' {
-			inEsc = true
-			i++ // skip '['
-			continue
-		}
-		n++
-	}
-	return n
+	return eval.VisibleWidth(s)
 }
 
 // fnWrap performs word-wrapping at a given width.
@@ -569,49 +570,130 @@ func hardBreak(buf *strings.Builder, word string, firstAvail, width int, indent
 	}
 }
 
-// fnColumns formats text into columns.
+// fnColumns formats a list into columns across a 78-column line.
+//
+//	columns(<list>, <width>[, <delimiter>[, <indent>]])
+//
+// As in TinyMUSH, each item is cut off or padded to <width> visible
+// columns, and every row starts with <indent> spaces.
 func fnColumns(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	if len(args) < 2 {
+	if len(args) < 2 || len(args) > 4 {
+		buf.WriteString("#-1 FUNCTION (COLUMNS) EXPECTS 2 TO 4 ARGUMENTS")
+		return
+	}
+	colWidth := toInt(args[1])
+	if colWidth < 1 || colWidth > 78 {
+		buf.WriteString("#-1 OUT OF RANGE")
 		return
 	}
 	delim := " "
 	if len(args) > 2 && args[2] != "" {
 		delim = args[2]
 	}
-	items := splitList(args[0], delim)
-	colWidth := toInt(args[1])
-	if colWidth < 1 {
-		colWidth = 20
-	}
-	lineWidth := 78
+	indent := 0
 	if len(args) > 3 {
-		lineWidth = toInt(args[3])
-		if lineWidth < 1 {
-			lineWidth = 78
+		indent = toInt(args[3])
+		if indent < 0 || indent > 77 {
+			indent = 1
 		}
 	}
-
-	colsPerRow := lineWidth / colWidth
+	colsPerRow := (78 - indent) / colWidth
 	if colsPerRow < 1 {
 		colsPerRow = 1
 	}
+	writeGrid(buf, splitList(args[0], delim), colWidth, colsPerRow, strings.Repeat(" ", indent), "", " ")
+}
 
-	for i, item := range items {
-		if i > 0 && i%colsPerRow == 0 {
+// fnTable formats a list into a table, TinyMUSH 3.3 style.
+//
+//	table(<list>[, <field width>[, <line length>[, <delimiter>[, <field sep>[, <fill>]]]]])
+//
+// Fields are <field width> (default 10) visible columns wide, padded
+// with <fill>, and there are <line length> / (<field width> + width of
+// <field sep>) to a row. A line break in <list> starts a new row.
+func fnTable(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller, cause gamedb.DBRef) {
+	if len(args) < 1 || len(args) > 6 {
+		buf.WriteString("#-1 FUNCTION (TABLE) EXPECTS 1 TO 6 ARGUMENTS")
+		return
+	}
+	fieldWidth, lineLen := 10, 78
+	if len(args) > 1 && strings.TrimSpace(args[1]) != "" {
+		fieldWidth = toInt(args[1])
+	}
+	if len(args) > 2 && strings.TrimSpace(args[2]) != "" {
+		lineLen = toInt(args[2])
+	}
+	if fieldWidth < 1 || lineLen < 1 || fieldWidth > lineLen {
+		buf.WriteString("#-1 OUT OF RANGE")
+		return
+	}
+	delim := " "
+	if len(args) > 3 && args[3] != "" {
+		delim = args[3]
+	}
+	sep := " "
+	if len(args) > 4 {
+		sep = args[4]
+	}
+	fill := " "
+	if len(args) > 5 && args[5] != "" {
+		fill = args[5]
+	}
+	colsPerRow := lineLen / (fieldWidth + visLen(sep))
+	if colsPerRow < 1 {
+		colsPerRow = 1
+	}
+	for i, part := range strings.Split(strings.ReplaceAll(args[0], "\r\n", "\n"), "\n") {
+		if i > 0 {
 			buf.WriteString("\r\n")
 		}
-		// Left-justify within column
-		buf.WriteString(item)
-		padding := colWidth - len(item)
-		if padding > 0 && (i+1)%colsPerRow != 0 && i+1 < len(items) {
-			buf.WriteString(strings.Repeat(" ", padding))
+		writeGrid(buf, splitList(part, delim), fieldWidth, colsPerRow, "", sep, fill)
+	}
+}
+
+// writeGrid lays items out colsPerRow to a row, each cut off or padded
+// with fill to width visible columns. The last field of a row isn't
+// padded.
+func writeGrid(buf *strings.Builder, items []string, width, colsPerRow int, indent, sep, fill string) {
+	for i, item := range items {
+		col := i % colsPerRow
+		if col == 0 {
+			if i > 0 {
+				buf.WriteString("\r\n")
+			}
+			buf.WriteString(indent)
+		} else {
+			buf.WriteString(sep)
 		}
+		last := col == colsPerRow-1 || i == len(items)-1
+		writeCell(buf, item, width, 0, fill, !last)
 	}
 }
 
-// fnTable is an alias for columns.
-func fnTable(ctx *eval.EvalContext, args []string, buf *strings.Builder, caller, cause gamedb.DBRef) {
-	fnColumns(ctx, args, buf, caller, cause)
+// writeCell writes text cut off or padded to width visible columns,
+// justified left (0), right (1) or center (2). Color is reset after
+// colored text so it doesn't bleed into the padding.
+func writeCell(buf *strings.Builder, text string, width, just int, fill string, pad bool) {
+	if visLen(text) > width {
+		text = ansiTruncate(text, width)
+	}
+	gap := width - visLen(text)
+	left, right := 0, gap
+	switch just {
+	case 1:
+		left, right = gap, 0
+	case 2:
+		left = gap / 2
+		right = gap - left
+	}
+	writePad(buf, fill, left)
+	buf.WriteString(text)
+	if strings.Contains(text, "\033") && !strings.HasSuffix(text, "\033[0m") {
+		buf.WriteString("\033[0m")
+	}
+	if pad {
+		writePad(buf, fill, right)
+	}
 }
 
 // fnTables implements tables(list, field_widths[, lead_str[, trail_str[, list_sep[, field_sep[, pad]]]]])
@@ -747,24 +829,7 @@ func processTables(args []string, buf *strings.Builder, just int) {
 
 // ansiStrLen returns the visible length of a string, not counting ANSI escape sequences.
 func ansiStrLen(s string) int {
-	n := 0
-	i := 0
-	for i < len(s) {
-		if s[i] == '\033' && i+1 < len(s) && s[i+1] == '[' {
-			// Skip ANSI escape sequence: ESC [ ... letter
-			i += 2
-			for i < len(s) && !((s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= 'a' && s[i] <= 'z')) {
-				i++
-			}
-			if i < len(s) {
-				i++ // skip the final letter
-			}
-			continue
-		}
-		n++
-		i++
-	}
-	return n
+	return eval.VisibleWidth(s)
 }
 
 // ansiTruncate truncates a string to maxVisible visible characters, preserving ANSI sequences.
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"net/http"
 	"strings"
 
 	"github.com/crystal-mush/gotinymush/pkg/eval"
@@ -45,3 +46,29 @@ func fnSQLEscape(ctx *eval.EvalContext, args []string, buff *strings.Builder, ca
 	}
 	buff.WriteString(ctx.GameState.EscapeSQL(args[0]))
 }
+
+// fnHTTPGet implements httpget(<url>)
+func fnHTTPGet(ctx *eval.EvalContext, args []string, buff *strings.Builder, caller, cause gamedb.DBRef) {
+	if ctx.GameState == nil {
+		buff.WriteString("#-1 HTTP DISABLED")
+		return
+	}
+	buff.WriteString(ctx.GameState.HTTPRequest(ctx.Player, http.MethodGet, args[0], "", ""))
+}
+
+// fnHTTPPost implements httppost(<url>, <body>[, <content type>])
+func fnHTTPPost(ctx *eval.EvalContext, args []string, buff *strings.Builder, caller, cause gamedb.DBRef) {
+	if len(args) < 2 || len(args) > 3 {
+		buff.WriteString("#-1 FUNCTION (HTTPPOST) EXPECTS 2 OR 3 ARGUMENTS")
+		return
+	}
+	if ctx.GameState == nil {
+		buff.WriteString("#-1 HTTP DISABLED")
+		return
+	}
+	contentType := "application/x-www-form-urlencoded"
+	if len(args) == 3 && strings.TrimSpace(args[2]) != "" {
+		contentType = strings.TrimSpace(args[2])
+	}
+	buff.WriteString(ctx.GameState.HTTPRequest(ctx.Player, http.MethodPost, args[0], args[1], contentType))
+}
@@ -61,7 +61,7 @@ var headingNamesExact = [32]string{
 
 // fnHvec — convert heading (0-31) to unit direction vector.
 // hvec(heading) → "dx dy"
-func fnHvec(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnHvec(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { return }
 	h := toInt(args[0])
 	h = ((h % headingPoints) + headingPoints) % headingPoints // normalize to 0-31
@@ -71,9 +71,9 @@ func fnHvec(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamed
 	// Clean up near-zero values
 	if math.Abs(dx) < 1e-10 { dx = 0 }
 	if math.Abs(dy) < 1e-10 { dy = 0 }
-	writeFloat(buf, dx)
+	writeFloat(ctx, buf, dx)
 	buf.WriteByte(' ')
-	writeFloat(buf, dy)
+	writeFloat(ctx, buf, dy)
 }
 
 // fnHdelta — shortest turn between two headings.
@@ -106,11 +106,11 @@ func fnHname(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 
 // fnH2deg — heading to degrees.
 // h2deg(heading) → degrees (0-360, 0=East counterclockwise)
-func fnH2deg(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnH2deg(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	h := toInt(args[0])
 	deg := float64(h) * (360.0 / float64(headingPoints))
-	writeFloat(buf, deg)
+	writeFloat(ctx, buf, deg)
 }
 
 // fnDeg2h — degrees to nearest heading.
@@ -291,7 +291,7 @@ func fnAbsgrid(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 
 // fnGriddist — distance between two grid locations.
 // griddist(loc1, loc2) → distance (2D, ignoring altitude)
-func fnGriddist(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnGriddist(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
 	x1, y1, ok1 := parseGridLoc(args[0])
 	x2, y2, ok2 := parseGridLoc(args[1])
@@ -301,12 +301,12 @@ func fnGriddist(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 	}
 	dx := float64(x2 - x1)
 	dy := float64(y2 - y1)
-	writeFloat(buf, math.Sqrt(dx*dx+dy*dy))
+	writeFloat(ctx, buf, math.Sqrt(dx*dx+dy*dy))
 }
 
 // fnGridcourse — calculate heading and distance from one grid loc to another.
 // gridcourse(from, to) → "heading distance"
-func fnGridcourse(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnGridcourse(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
 	x1, y1, ok1 := parseGridLoc(args[0])
 	x2, y2, ok2 := parseGridLoc(args[1])
@@ -327,13 +327,13 @@ func fnGridcourse(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _
 	h = h % headingPoints
 	writeInt(buf, h)
 	buf.WriteByte(' ')
-	writeFloat(buf, dist)
+	writeFloat(ctx, buf, dist)
 }
 
 // fnGridnav — project a new position given current pos, heading, speed, climb, and drift.
 // gridnav(x y z, heading, speed[, climb[, drift]]) → "x y z"
 // drift is maximum random perturbation per axis per tick.
-func fnGridnav(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnGridnav(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 3 { return }
 	pos := parseVector(args[0])
 	if len(pos) < 2 { return }
@@ -367,11 +367,11 @@ func fnGridnav(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	if newZ < float64(altMin) { newZ = float64(altMin) }
 	if newZ > float64(altMax) { newZ = float64(altMax) }
 
-	writeFloat(buf, newX)
+	writeFloat(ctx, buf, newX)
 	buf.WriteByte(' ')
-	writeFloat(buf, newY)
+	writeFloat(ctx, buf, newY)
 	buf.WriteByte(' ')
-	writeFloat(buf, newZ)
+	writeFloat(ctx, buf, newZ)
 }
 
 // --- Random vector / drift functions ---
@@ -380,7 +380,7 @@ func fnGridnav(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 // vrand(max_magnitude[, dimensions]) → "x y z"
 // The direction is uniformly random; magnitude is uniform [0, max].
 // Default dimensions = 3.
-func fnVrand(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnVrand(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { return }
 	maxMag := toFloat(args[0])
 	dims := 3
@@ -400,7 +400,7 @@ func fnVrand(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	norm = math.Sqrt(norm)
 	if norm < 1e-15 {
 		// Degenerate case: just return zeros
-		writeVector(buf, v)
+		writeVector(ctx, buf, v)
 		return
 	}
 
@@ -409,14 +409,14 @@ func fnVrand(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	for i := range v {
 		v[i] = v[i] / norm * mag
 	}
-	writeVector(buf, v)
+	writeVector(ctx, buf, v)
 }
 
 // fnVrandc — per-component random vector in [-max, +max] for each component.
 // vrandc(max_x max_y max_z) → "dx dy dz"
 // Each component is independently randomized in [-max_i, +max_i].
 // This is useful for rectangular drift zones (e.g., different drift on altitude vs XY).
-func fnVrandc(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnVrandc(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { return }
 	maxVec := parseVector(args[0])
 	if len(maxVec) == 0 { return }
@@ -424,13 +424,13 @@ func fnVrandc(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 	for i, m := range maxVec {
 		r[i] = (rand.Float64()*2 - 1) * m
 	}
-	writeVector(buf, r)
+	writeVector(ctx, buf, r)
 }
 
 // fnDrift — apply random drift to a position vector.
 // drift(position, max_drift) → "x y z"
 // max_drift can be a single number (uniform per axis) or a vector (per-component max).
-func fnDrift(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnDrift(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
 	pos := parseVector(args[0])
 	if len(pos) == 0 { return }
@@ -462,7 +462,7 @@ func fnDrift(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 		}
 	}
 
-	writeVector(buf, r)
+	writeVector(ctx, buf, r)
 }
 
 // --- Multi-object tactical flight functions ---
@@ -494,7 +494,7 @@ func fnBearing(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 
 // fnPitch — vertical angle (climb/dive) from position 1 to position 2 in degrees.
 // pitch(x1 y1 z1, x2 y2 z2) → degrees (-90 to +90, positive = climbing)
-func fnPitch(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnPitch(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
 	a := parseVector(args[0])
 	b := parseVector(args[1])
@@ -505,14 +505,14 @@ func fnPitch(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	horiz := math.Sqrt(dx*dx + dy*dy)
 	if horiz < 0.001 && math.Abs(dz) < 0.001 { buf.WriteString("0"); return }
 	pitch := math.Atan2(dz, horiz) * (180.0 / math.Pi)
-	writeFloat(buf, pitch)
+	writeFloat(ctx, buf, pitch)
 }
 
 // fnClosing — closing rate between two moving objects.
 // closing(pos1, heading1, speed1, pos2, heading2, speed2) → rate
 // Positive = getting closer, negative = separating.
 // Rate is in distance units per tick.
-func fnClosing(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnClosing(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 6 { buf.WriteString("0"); return }
 	p1 := parseVector(args[0])
 	h1 := toInt(args[1])
@@ -543,25 +543,25 @@ func fnClosing(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	distNext := math.Sqrt(ndx*ndx + ndy*ndy)
 
 	// Closing rate: positive means getting closer
-	writeFloat(buf, distNow-distNext)
+	writeFloat(ctx, buf, distNow-distNext)
 }
 
 // fnRelvel — relative velocity vector between two objects.
 // relvel(heading1, speed1, heading2, speed2) → "dx dy"
 // Returns velocity of obj2 relative to obj1 (from obj1's perspective).
-func fnRelvel(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnRelvel(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 4 { return }
 	vx1, vy1 := headingToVec(toInt(args[0]), toFloat(args[1]))
 	vx2, vy2 := headingToVec(toInt(args[2]), toFloat(args[3]))
-	writeFloat(buf, vx2-vx1)
+	writeFloat(ctx, buf, vx2-vx1)
 	buf.WriteByte(' ')
-	writeFloat(buf, vy2-vy1)
+	writeFloat(ctx, buf, vy2-vy1)
 }
 
 // fnEta — estimated ticks to reach target at current heading and speed.
 // eta(pos1, heading, speed, pos2) → ticks (or -1 if moving away / stopped)
 // This is straight-line ETA assuming target is stationary.
-func fnEta(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnEta(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 4 { buf.WriteString("-1"); return }
 	p1 := parseVector(args[0])
 	h := toInt(args[1])
@@ -587,7 +587,7 @@ func fnEta(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb
 	}
 
 	ticks := dist / closingSpeed
-	writeFloat(buf, ticks)
+	writeFloat(ctx, buf, ticks)
 }
 
 // fnIntercept — calculate heading for obj1 to intercept moving obj2.
@@ -714,7 +714,7 @@ func fnGps(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb
 
 // fnGriddist3d — 3D distance between two grid locations (with altitude).
 // griddist3d(loc1[:alt1], loc2[:alt2]) → distance
-func fnGriddist3d(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnGriddist3d(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
 	x1, y1, z1, ok1 := parseGridLocFull(args[0])
 	x2, y2, z2, ok2 := parseGridLocFull(args[1])
@@ -725,7 +725,7 @@ func fnGriddist3d(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _
 	dx := float64(x2 - x1)
 	dy := float64(y2 - y1)
 	dz := float64(z2 - z1)
-	writeFloat(buf, math.Sqrt(dx*dx+dy*dy+dz*dz))
+	writeFloat(ctx, buf, math.Sqrt(dx*dx+dy*dy+dz*dz))
 }
 
 // fnMapinstance — construct an instanced grid location key.
@@ -918,7 +918,7 @@ func fnPoiinrange(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _
 
 // fnPoidist — distance from a position to the nearest point of a POI (4D).
 // poidist(poi_value, x y z) → distance
-func fnPoidist(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnPoidist(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
 	poi := strings.TrimSpace(args[0])
 	parts := strings.SplitN(poi, "|", 4)
@@ -944,7 +944,7 @@ func fnPoidist(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 		vDist = pos[2] - (pz + ph)
 	}
 
-	writeFloat(buf, math.Sqrt(hDist*hDist+vDist*vDist))
+	writeFloat(ctx, buf, math.Sqrt(hDist*hDist+vDist*vDist))
 }
 
 // fnPoibearing — heading from a position to a POI.
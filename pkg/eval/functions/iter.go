@@ -101,7 +101,7 @@ func fnFilter(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 	var results []string
 	for _, word := range words {
 		result := ctx.CallIterFun(objAttr, []string{word})
-		if isTrue(result) {
+		if IsTrue(result) {
 			results = append(results, word)
 		}
 	}
@@ -155,7 +155,7 @@ func fnWhile(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	limit := 10000
 	for i := 0; i < limit; i++ {
 		cond := ctx.CallIterFun(condFn, []string{current})
-		if !isTrue(cond) { break }
+		if !IsTrue(cond) { break }
 		current = ctx.CallIterFun(bodyFn, []string{current})
 		results = append(results, current)
 	}
@@ -316,7 +316,7 @@ func whenHelper(ctx *eval.EvalContext, args []string, buf *strings.Builder, want
 		ctx.Loop.LoopTokens[idx] = word
 		ctx.Loop.LoopNumbers[idx] = i
 		result := ctx.Exec(pattern, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
-		if isTrue(result) == wantTrue {
+		if IsTrue(result) == wantTrue {
 			results = append(results, word)
 		}
 	}
@@ -340,7 +340,7 @@ func fnFilterbool(ctx *eval.EvalContext, args []string, buf *strings.Builder, _,
 	var results []string
 	for _, word := range words {
 		result := ctx.CallIterFun(objAttr, []string{word})
-		if isTrue(result) {
+		if IsTrue(result) {
 			results = append(results, word)
 		}
 	}
@@ -360,7 +360,7 @@ func fnUntil(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	limit := 10000
 	for i := 0; i < limit; i++ {
 		cond := ctx.CallIterFun(condFn, []string{current})
-		if isTrue(cond) { break }
+		if IsTrue(cond) { break }
 		current = ctx.CallIterFun(bodyFn, []string{current})
 		results = append(results, current)
 	}
@@ -500,7 +500,7 @@ func when2Helper(ctx *eval.EvalContext, args []string, buf *strings.Builder, wan
 		ctx.Loop.LoopTokens2[idx] = w2
 		ctx.Loop.LoopNumbers[idx] = i
 		result := ctx.Exec(pattern, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
-		if isTrue(result) == wantTrue {
+		if IsTrue(result) == wantTrue {
 			results = append(results, w1)
 		}
 	}
@@ -420,7 +420,7 @@ func fnWildgrep(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _
 	attrPattern := args[1]
 	searchPattern := args[2]
 	var results []string
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		attrName := ""
 		if def, ok := ctx.DB.AttrNames[attr.Number]; ok {
 			attrName = def.Name
@@ -439,7 +439,7 @@ func fnWildgrep(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _
 
 // Aggregate list functions
 
-func fnLadd(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnLadd(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	delim := " "
 	if len(args) > 1 && args[1] != "" { delim = args[1] }
@@ -448,7 +448,7 @@ func fnLadd(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamed
 	for _, w := range words {
 		sum += toFloat(w)
 	}
-	writeFloat(buf, sum)
+	writeFloat(ctx, buf, sum)
 }
 
 func fnLand(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -583,7 +583,7 @@ func fnMerge(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 
 // fnLavg — average of a list of numbers.
 // lavg(list[, delim]) → float average
-func fnLavg(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnLavg(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	delim := " "
 	if len(args) > 1 && args[1] != "" { delim = args[1] }
@@ -591,12 +591,12 @@ func fnLavg(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamed
 	if len(words) == 0 { buf.WriteString("0"); return }
 	sum := 0.0
 	for _, w := range words { sum += toFloat(w) }
-	writeFloat(buf, sum/float64(len(words)))
+	writeFloat(ctx, buf, sum/float64(len(words)))
 }
 
 // fnLsub — subtract all elements in a list from the first.
 // lsub(list[, delim]) → first - second - third - ...
-func fnLsub(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnLsub(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	delim := " "
 	if len(args) > 1 && args[1] != "" { delim = args[1] }
@@ -604,12 +604,12 @@ func fnLsub(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamed
 	if len(words) == 0 { buf.WriteString("0"); return }
 	result := toFloat(words[0])
 	for i := 1; i < len(words); i++ { result -= toFloat(words[i]) }
-	writeFloat(buf, result)
+	writeFloat(ctx, buf, result)
 }
 
 // fnLmul — multiply all elements in a list.
 // lmul(list[, delim]) → product
-func fnLmul(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnLmul(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	delim := " "
 	if len(args) > 1 && args[1] != "" { delim = args[1] }
@@ -617,12 +617,12 @@ func fnLmul(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamed
 	if len(words) == 0 { buf.WriteString("0"); return }
 	result := toFloat(words[0])
 	for i := 1; i < len(words); i++ { result *= toFloat(words[i]) }
-	writeFloat(buf, result)
+	writeFloat(ctx, buf, result)
 }
 
 // fnLdiv — divide first element by all subsequent elements.
 // ldiv(list[, delim]) → first / second / third / ...
-func fnLdiv(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnLdiv(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	delim := " "
 	if len(args) > 1 && args[1] != "" { delim = args[1] }
@@ -633,7 +633,7 @@ func fnLdiv(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamed
 		d := toFloat(words[i])
 		if d != 0 { result /= d }
 	}
-	writeFloat(buf, result)
+	writeFloat(ctx, buf, result)
 }
 
 // fnListmatch — filter list elements by wildcard pattern.
@@ -9,37 +9,146 @@ import (
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 )
 
-// fnMail implements mail() — returns message count or detailed stats.
-// mail()          -> total message count for enactor
-// mail(<player>)  -> "total unread cleared" counts
+// fnMail implements mail() — returns message count, stats, or message text.
+// mail()               -> total message count for executor
+// mail(<num>)          -> text of executor's message <num>
+// mail(<player>)       -> "read unread cleared" counts (wizard for others)
+// mail(<player>,<num>) -> text of <player>'s message <num> (wizard for others)
 func fnMail(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if ctx.GameState == nil {
 		buf.WriteString("#-1 MAIL SYSTEM DISABLED")
 		return
 	}
 
-	var player gamedb.DBRef
-	if len(args) == 0 || args[0] == "" {
-		player = ctx.Player
-	} else {
-		player = ctx.GameState.LookupPlayer(args[0])
+	if len(args) == 0 || (len(args) == 1 && strings.TrimSpace(args[0]) == "") {
+		total, _, _ := ctx.GameState.MailCount(ctx.Player)
+		if total < 0 {
+			buf.WriteString("#-1 MAIL SYSTEM DISABLED")
+			return
+		}
+		buf.WriteString(strconv.Itoa(total))
+		return
+	}
+
+	if len(args) == 1 {
+		arg := strings.TrimSpace(args[0])
+		if num, err := strconv.Atoi(arg); err == nil {
+			buf.WriteString(ctx.GameState.MailMessageField(ctx.Player, ctx.Player, num, "body"))
+			return
+		}
+		player := mailPlayer(ctx, arg)
 		if player == gamedb.Nothing {
 			buf.WriteString("#-1 NO SUCH PLAYER")
 			return
 		}
+		buf.WriteString(ctx.GameState.MailStats(ctx.Player, player))
+		return
+	}
+
+	player := mailPlayer(ctx, args[0])
+	if player == gamedb.Nothing {
+		buf.WriteString("#-1 NO SUCH PLAYER")
+		return
+	}
+	num, err := strconv.Atoi(strings.TrimSpace(args[1]))
+	if err != nil {
+		buf.WriteString("#-1 INVALID MESSAGE NUMBER")
+		return
+	}
+	buf.WriteString(ctx.GameState.MailMessageField(ctx.Player, player, num, "body"))
+}
+
+// mailPlayer resolves a mailbox owner: "me", a player #dbref, or a player name.
+func mailPlayer(ctx *eval.EvalContext, s string) gamedb.DBRef {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "me") {
+		return ctx.Player
+	}
+	if strings.HasPrefix(s, "#") {
+		ref := resolveDBRef(ctx, s)
+		if obj, ok := ctx.DB.Objects[ref]; ok && obj.ObjType() == gamedb.TypePlayer {
+			return ref
+		}
+		return gamedb.Nothing
 	}
+	return ctx.GameState.LookupPlayer(s)
+}
 
-	total, unread, cleared := ctx.GameState.MailCount(player)
-	if total < 0 {
+// fnMaillist implements maillist([<player>[,<folder>]]) — returns the
+// message numbers in a mailbox, optionally limited to one folder.
+func fnMaillist(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil {
 		buf.WriteString("#-1 MAIL SYSTEM DISABLED")
 		return
 	}
 
-	if len(args) == 0 || args[0] == "" {
-		buf.WriteString(strconv.Itoa(total))
-	} else {
-		buf.WriteString(fmt.Sprintf("%d %d %d", total, unread, cleared))
+	player := ctx.Player
+	if len(args) > 0 && strings.TrimSpace(args[0]) != "" {
+		player = mailPlayer(ctx, args[0])
+		if player == gamedb.Nothing {
+			buf.WriteString("#-1 NO SUCH PLAYER")
+			return
+		}
+	}
+
+	folder := -1
+	if len(args) > 1 && strings.TrimSpace(args[1]) != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(args[1]))
+		if err != nil || n < 0 || n >= gamedb.MailFolders {
+			buf.WriteString("#-1 INVALID FOLDER")
+			return
+		}
+		folder = n
+	}
+	buf.WriteString(ctx.GameState.MailList(ctx.Player, player, folder))
+}
+
+// fnMailread implements mailread(<num>[,<field>]) — returns a field of the
+// executor's message <num> (body by default). Reading the body marks the
+// message as read, as @mail/read does.
+func fnMailread(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil {
+		buf.WriteString("#-1 MAIL SYSTEM DISABLED")
+		return
+	}
+	if len(args) < 1 || len(args) > 2 {
+		buf.WriteString("#-1 FUNCTION (MAILREAD) EXPECTS 1 OR 2 ARGUMENTS")
+		return
+	}
+
+	num, err := strconv.Atoi(strings.TrimSpace(args[0]))
+	if err != nil {
+		buf.WriteString("#-1 INVALID MESSAGE NUMBER")
+		return
+	}
+	field := "body"
+	if len(args) > 1 && strings.TrimSpace(args[1]) != "" {
+		field = strings.ToLower(strings.TrimSpace(args[1]))
+	}
+
+	result := ctx.GameState.MailMessageField(ctx.Player, ctx.Player, num, field)
+	if (field == "body" || field == "text") && !strings.HasPrefix(result, "#-1") {
+		ctx.GameState.MailMarkRead(ctx.Player, num)
+	}
+	buf.WriteString(result)
+}
+
+// fnMailsend implements mailsend(<players>,<subject>[,<body>]) — sends
+// @mail from the executor. Returns nothing on success.
+func fnMailsend(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil {
+		buf.WriteString("#-1 MAIL SYSTEM DISABLED")
+		return
+	}
+	if len(args) < 2 || len(args) > 3 {
+		buf.WriteString("#-1 FUNCTION (MAILSEND) EXPECTS 2 OR 3 ARGUMENTS")
+		return
+	}
+	body := ""
+	if len(args) > 2 {
+		body = args[2]
 	}
+	buf.WriteString(ctx.GameState.MailSend(ctx.Player, args[0], args[1], body))
 }
 
 // fnMailfrom implements mailfrom(<num>) — returns sender dbref of message.
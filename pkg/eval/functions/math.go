@@ -69,14 +69,42 @@ func writeInt(buf *strings.Builder, i int) {
 	buf.WriteString(strconv.Itoa(i))
 }
 
-func writeFloat(buf *strings.Builder, f float64) {
+func writeFloat(ctx *eval.EvalContext, buf *strings.Builder, f float64) {
 	if f == float64(int64(f)) {
 		buf.WriteString(strconv.FormatInt(int64(f), 10))
 	} else {
-		buf.WriteString(strconv.FormatFloat(f, 'f', 6, 64))
+		buf.WriteString(strconv.FormatFloat(f, 'f', floatPrecision(ctx), 64))
 	}
 }
 
+// floatPrecision is how many decimal places floating-point results show,
+// from the float_precision setting.
+func floatPrecision(ctx *eval.EvalContext) int {
+	if ctx == nil || ctx.FloatPrecision < 0 {
+		return 6
+	}
+	return ctx.FloatPrecision
+}
+
+// writeRounded writes f rounded to the places given in args[i], or to a
+// whole number if there's no such argument, as floor(), ceil() and
+// round() do.
+func writeRounded(buf *strings.Builder, args []string, i int, f float64, round func(float64) float64) {
+	places := 0
+	if len(args) > i {
+		places = toInt(args[i])
+	}
+	if places > 15 {
+		places = 15
+	}
+	if places <= 0 {
+		writeInt(buf, int(round(f)))
+		return
+	}
+	mult := math.Pow(10, float64(places))
+	buf.WriteString(strconv.FormatFloat(round(f*mult)/mult, 'f', places, 64))
+}
+
 // --- Arithmetic ---
 
 // add() returns integer result (C TinyMUSH ival behavior: parse as float, compute, truncate).
@@ -105,28 +133,28 @@ func fnMul(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb
 }
 
 // fadd() returns float result.
-func fnFadd(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnFadd(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	sum := 0.0
 	for _, a := range args {
 		sum += toFloat(a)
 	}
-	writeFloat(buf, sum)
+	writeFloat(ctx, buf, sum)
 }
 
 // fsub() returns float result.
-func fnFsub(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnFsub(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
-	writeFloat(buf, toFloat(args[0])-toFloat(args[1]))
+	writeFloat(ctx, buf, toFloat(args[0])-toFloat(args[1]))
 }
 
 // fmul() returns float result.
-func fnFmul(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnFmul(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) == 0 { buf.WriteString("0"); return }
 	prod := 1.0
 	for _, a := range args {
 		prod *= toFloat(a)
 	}
-	writeFloat(buf, prod)
+	writeFloat(ctx, buf, prod)
 }
 
 func fnDiv(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -139,14 +167,14 @@ func fnDiv(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb
 	writeInt(buf, toInt(args[0])/b)
 }
 
-func fnFdiv(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnFdiv(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
 	b := toFloat(args[1])
 	if b == 0 {
 		buf.WriteString("#-1 DIVIDE BY ZERO")
 		return
 	}
-	writeFloat(buf, toFloat(args[0])/b)
+	writeFloat(ctx, buf, toFloat(args[0])/b)
 }
 
 func fnModulo(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -159,10 +187,10 @@ func fnModulo(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 	writeInt(buf, toInt(args[0])%b)
 }
 
-func fnAbs(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAbs(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	f := toFloat(args[0])
-	writeFloat(buf, math.Abs(f))
+	writeFloat(ctx, buf, math.Abs(f))
 }
 
 func fnSign(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -181,17 +209,10 @@ func fnDec(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb
 	writeInt(buf, toInt(args[0])-1)
 }
 
+// round(<number>[, <places>])
 func fnRound(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	if len(args) < 2 { buf.WriteString("0"); return }
-	f := toFloat(args[0])
-	places := toInt(args[1])
-	mult := math.Pow(10, float64(places))
-	result := math.Round(f*mult) / mult
-	if places <= 0 {
-		writeInt(buf, int(result))
-	} else {
-		buf.WriteString(strconv.FormatFloat(result, 'f', places, 64))
-	}
+	if len(args) < 1 { buf.WriteString("0"); return }
+	writeRounded(buf, args, 1, toFloat(args[0]), math.Round)
 }
 
 func fnTrunc(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -199,57 +220,59 @@ func fnTrunc(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	writeInt(buf, int(toFloat(args[0])))
 }
 
+// floor(<number>[, <places>])
 func fnFloor(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeInt(buf, int(math.Floor(toFloat(args[0]))))
+	writeRounded(buf, args, 1, toFloat(args[0]), math.Floor)
 }
 
+// ceil(<number>[, <places>])
 func fnCeil(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeInt(buf, int(math.Ceil(toFloat(args[0]))))
+	writeRounded(buf, args, 1, toFloat(args[0]), math.Ceil)
 }
 
-func fnSqrt(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnSqrt(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	f := toFloat(args[0])
 	if f < 0 {
 		buf.WriteString("#-1 SQUARE ROOT OF NEGATIVE")
 		return
 	}
-	writeFloat(buf, math.Sqrt(f))
+	writeFloat(ctx, buf, math.Sqrt(f))
 }
 
-func fnPower(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnPower(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Pow(toFloat(args[0]), toFloat(args[1])))
+	writeFloat(ctx, buf, math.Pow(toFloat(args[0]), toFloat(args[1])))
 }
 
-func fnMax(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnMax(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) == 0 { buf.WriteString("0"); return }
 	m := toFloat(args[0])
 	for _, a := range args[1:] {
 		v := toFloat(a)
 		if v > m { m = v }
 	}
-	writeFloat(buf, m)
+	writeFloat(ctx, buf, m)
 }
 
-func fnMin(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnMin(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) == 0 { buf.WriteString("0"); return }
 	m := toFloat(args[0])
 	for _, a := range args[1:] {
 		v := toFloat(a)
 		if v < m { m = v }
 	}
-	writeFloat(buf, m)
+	writeFloat(ctx, buf, m)
 }
 
-func fnPi(_ *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	buf.WriteString(fmt.Sprintf("%.6f", math.Pi))
+func fnPi(ctx *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	buf.WriteString(fmt.Sprintf("%.*f", floatPrecision(ctx), math.Pi))
 }
 
-func fnE(_ *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	buf.WriteString(fmt.Sprintf("%.6f", math.E))
+func fnE(ctx *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	buf.WriteString(fmt.Sprintf("%.*f", floatPrecision(ctx), math.E))
 }
 
 // --- Comparison ---
@@ -335,111 +358,121 @@ func fnT(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.D
 
 // --- Trigonometric functions ---
 
-func fnSin(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnSin(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Sin(toFloat(args[0])))
+	writeFloat(ctx, buf, math.Sin(toFloat(args[0])))
 }
 
-func fnSind(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnSind(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Sin(toFloat(args[0])*math.Pi/180))
+	writeFloat(ctx, buf, math.Sin(toFloat(args[0])*math.Pi/180))
 }
 
-func fnCos(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnCos(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Cos(toFloat(args[0])))
+	writeFloat(ctx, buf, math.Cos(toFloat(args[0])))
 }
 
-func fnCosd(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnCosd(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Cos(toFloat(args[0])*math.Pi/180))
+	writeFloat(ctx, buf, math.Cos(toFloat(args[0])*math.Pi/180))
 }
 
-func fnTan(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnTan(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Tan(toFloat(args[0])))
+	writeFloat(ctx, buf, math.Tan(toFloat(args[0])))
 }
 
-func fnTand(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnTand(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Tan(toFloat(args[0])*math.Pi/180))
+	writeFloat(ctx, buf, math.Tan(toFloat(args[0])*math.Pi/180))
 }
 
-func fnAsin(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAsin(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	v := toFloat(args[0])
 	if v < -1 || v > 1 {
 		buf.WriteString("#-1 ARCSINE ARGUMENT OUT OF RANGE")
 		return
 	}
-	writeFloat(buf, math.Asin(v))
+	writeFloat(ctx, buf, math.Asin(v))
 }
 
-func fnAsind(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAsind(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	v := toFloat(args[0])
 	if v < -1 || v > 1 {
 		buf.WriteString("#-1 ARCSINE ARGUMENT OUT OF RANGE")
 		return
 	}
-	writeFloat(buf, math.Asin(v)*180/math.Pi)
+	writeFloat(ctx, buf, math.Asin(v)*180/math.Pi)
 }
 
-func fnAcos(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAcos(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	v := toFloat(args[0])
 	if v < -1 || v > 1 {
 		buf.WriteString("#-1 ARCCOSINE ARGUMENT OUT OF RANGE")
 		return
 	}
-	writeFloat(buf, math.Acos(v))
+	writeFloat(ctx, buf, math.Acos(v))
 }
 
-func fnAcosd(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAcosd(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	v := toFloat(args[0])
 	if v < -1 || v > 1 {
 		buf.WriteString("#-1 ARCCOSINE ARGUMENT OUT OF RANGE")
 		return
 	}
-	writeFloat(buf, math.Acos(v)*180/math.Pi)
+	writeFloat(ctx, buf, math.Acos(v)*180/math.Pi)
 }
 
-func fnAtan(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAtan(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Atan(toFloat(args[0])))
+	writeFloat(ctx, buf, math.Atan(toFloat(args[0])))
 }
 
-func fnAtand(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAtand(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Atan(toFloat(args[0]))*180/math.Pi)
+	writeFloat(ctx, buf, math.Atan(toFloat(args[0]))*180/math.Pi)
 }
 
 // --- Exponential/Logarithmic ---
 
-func fnExp(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnExp(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Exp(toFloat(args[0])))
+	writeFloat(ctx, buf, math.Exp(toFloat(args[0])))
 }
 
-func fnLn(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnLn(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	v := toFloat(args[0])
 	if v <= 0 {
 		buf.WriteString("#-1 LOG OF NEGATIVE OR ZERO")
 		return
 	}
-	writeFloat(buf, math.Log(v))
+	writeFloat(ctx, buf, math.Log(v))
 }
 
-func fnLog(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+// log(<number>[, <base>]) -- base 10 unless another is given.
+func fnLog(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	v := toFloat(args[0])
 	if v <= 0 {
 		buf.WriteString("#-1 LOG OF NEGATIVE OR ZERO")
 		return
 	}
-	writeFloat(buf, math.Log10(v))
+	if len(args) > 1 && strings.TrimSpace(args[1]) != "" {
+		base := toFloat(args[1])
+		if base <= 0 || base == 1 {
+			buf.WriteString("#-1 BASE OUT OF RANGE")
+			return
+		}
+		writeFloat(ctx, buf, math.Log(v)/math.Log(base))
+		return
+	}
+	writeFloat(ctx, buf, math.Log10(v))
 }
 
 // --- Bitwise ---
@@ -469,6 +502,16 @@ func fnBnand(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	writeInt(buf, toInt(args[0]) & ^toInt(args[1]))
 }
 
+func fnBxor(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if len(args) < 2 { buf.WriteString("0"); return }
+	writeInt(buf, toInt(args[0])^toInt(args[1]))
+}
+
+func fnBnot(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if len(args) < 1 { buf.WriteString("0"); return }
+	writeInt(buf, ^toInt(args[0]))
+}
+
 // --- Additional math ---
 
 func fnFloordiv(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -481,19 +524,19 @@ func fnFloordiv(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 	writeInt(buf, int(math.Floor(toFloat(args[0])/b)))
 }
 
-func fnDist2d(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnDist2d(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 4 { buf.WriteString("0"); return }
 	dx := toFloat(args[2]) - toFloat(args[0])
 	dy := toFloat(args[3]) - toFloat(args[1])
-	writeFloat(buf, math.Sqrt(dx*dx+dy*dy))
+	writeFloat(ctx, buf, math.Sqrt(dx*dx+dy*dy))
 }
 
-func fnDist3d(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnDist3d(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 6 { buf.WriteString("0"); return }
 	dx := toFloat(args[3]) - toFloat(args[0])
 	dy := toFloat(args[4]) - toFloat(args[1])
 	dz := toFloat(args[5]) - toFloat(args[2])
-	writeFloat(buf, math.Sqrt(dx*dx+dy*dy+dz*dz))
+	writeFloat(ctx, buf, math.Sqrt(dx*dx+dy*dy+dz*dz))
 }
 
 // --- Alpha comparison ---
@@ -518,7 +561,7 @@ func fnAlphamin(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 
 // --- List math ---
 
-func fnLmax(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnLmax(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	delim := " "
 	if len(args) > 1 { delim = args[1] }
@@ -529,10 +572,10 @@ func fnLmax(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamed
 		v := toFloat(w)
 		if v > m { m = v }
 	}
-	writeFloat(buf, m)
+	writeFloat(ctx, buf, m)
 }
 
-func fnLmin(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnLmin(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
 	delim := " "
 	if len(args) > 1 { delim = args[1] }
@@ -543,7 +586,7 @@ func fnLmin(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamed
 		v := toFloat(w)
 		if v < m { m = v }
 	}
-	writeFloat(buf, m)
+	writeFloat(ctx, buf, m)
 }
 
 // --- Logic variants ---
@@ -667,7 +710,12 @@ func fnLorbool(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 // --- Vector math ---
 
 func parseVector(s string) []float64 {
-	words := strings.Fields(strings.TrimSpace(s))
+	return parseVectorDelim(s, " ")
+}
+
+// parseVectorDelim splits a <delim>-separated vector into its components.
+func parseVectorDelim(s, delim string) []float64 {
+	words := splitList(strings.TrimSpace(s), delim)
 	vec := make([]float64, len(words))
 	for i, w := range words {
 		vec[i] = toFloat(w)
@@ -675,72 +723,114 @@ func parseVector(s string) []float64 {
 	return vec
 }
 
-func writeVector(buf *strings.Builder, v []float64) {
+// vectorDelims returns the delimiter and output delimiter given as
+// args[i] and args[i+1], as vadd() and friends take them. The output
+// delimiter defaults to the delimiter.
+func vectorDelims(args []string, i int) (delim, osep string) {
+	delim = " "
+	if len(args) > i && args[i] != "" { delim = args[i] }
+	osep = delim
+	if len(args) > i+1 && args[i+1] != "" { osep = args[i+1] }
+	return delim, osep
+}
+
+func writeVector(ctx *eval.EvalContext, buf *strings.Builder, v []float64) {
+	writeVectorSep(ctx, buf, v, " ")
+}
+
+func writeVectorSep(ctx *eval.EvalContext, buf *strings.Builder, v []float64, sep string) {
 	for i, f := range v {
-		if i > 0 { buf.WriteByte(' ') }
-		writeFloat(buf, f)
+		if i > 0 { buf.WriteString(sep) }
+		writeFloat(ctx, buf, f)
 	}
 }
 
-func fnVadd(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+// vadd(<vector>,<vector>[,<delim>[,<output delim>]])
+func fnVadd(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
-	a := parseVector(args[0])
-	b := parseVector(args[1])
+	delim, osep := vectorDelims(args, 2)
+	a := parseVectorDelim(args[0], delim)
+	b := parseVectorDelim(args[1], delim)
 	if len(a) != len(b) {
 		buf.WriteString("#-1 VECTORS MUST BE SAME DIMENSIONS")
 		return
 	}
 	r := make([]float64, len(a))
 	for i := range a { r[i] = a[i] + b[i] }
-	writeVector(buf, r)
+	writeVectorSep(ctx, buf, r, osep)
 }
 
-func fnVsub(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+// vsub(<vector>,<vector>[,<delim>[,<output delim>]])
+func fnVsub(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
-	a := parseVector(args[0])
-	b := parseVector(args[1])
+	delim, osep := vectorDelims(args, 2)
+	a := parseVectorDelim(args[0], delim)
+	b := parseVectorDelim(args[1], delim)
 	if len(a) != len(b) {
 		buf.WriteString("#-1 VECTORS MUST BE SAME DIMENSIONS")
 		return
 	}
 	r := make([]float64, len(a))
 	for i := range a { r[i] = a[i] - b[i] }
-	writeVector(buf, r)
+	writeVectorSep(ctx, buf, r, osep)
 }
 
-func fnVmul(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+// vmul(<vector|number>,<vector|number>[,<delim>[,<output delim>]]) --
+// scalar multiplication if either side is a single number, otherwise
+// elementwise.
+func fnVmul(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
-	a := parseVector(args[0])
-	scalar := toFloat(args[1])
-	r := make([]float64, len(a))
-	for i := range a { r[i] = a[i] * scalar }
-	writeVector(buf, r)
+	delim, osep := vectorDelims(args, 2)
+	a := parseVectorDelim(args[0], delim)
+	b := parseVectorDelim(args[1], delim)
+	if len(a) == 1 {
+		a, b = b, a
+	}
+	var r []float64
+	switch {
+	case len(b) == 1:
+		r = make([]float64, len(a))
+		for i := range a { r[i] = a[i] * b[0] }
+	case len(a) == len(b):
+		r = make([]float64, len(a))
+		for i := range a { r[i] = a[i] * b[i] }
+	default:
+		buf.WriteString("#-1 VECTORS MUST BE SAME DIMENSIONS")
+		return
+	}
+	writeVectorSep(ctx, buf, r, osep)
 }
 
-func fnVdot(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+// vdot(<vector>,<vector>[,<delim>[,<output delim>]])
+func fnVdot(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
-	a := parseVector(args[0])
-	b := parseVector(args[1])
+	delim, _ := vectorDelims(args, 2)
+	a := parseVectorDelim(args[0], delim)
+	b := parseVectorDelim(args[1], delim)
 	if len(a) != len(b) {
 		buf.WriteString("#-1 VECTORS MUST BE SAME DIMENSIONS")
 		return
 	}
 	sum := 0.0
 	for i := range a { sum += a[i] * b[i] }
-	writeFloat(buf, sum)
+	writeFloat(ctx, buf, sum)
 }
 
-func fnVmag(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+// vmag(<vector>[,<delim>])
+func fnVmag(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	a := parseVector(args[0])
+	delim, _ := vectorDelims(args, 1)
+	a := parseVectorDelim(args[0], delim)
 	sum := 0.0
 	for _, v := range a { sum += v * v }
-	writeFloat(buf, math.Sqrt(sum))
+	writeFloat(ctx, buf, math.Sqrt(sum))
 }
 
-func fnVunit(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+// vunit(<vector>[,<delim>[,<output delim>]])
+func fnVunit(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { return }
-	a := parseVector(args[0])
+	delim, osep := vectorDelims(args, 1)
+	a := parseVectorDelim(args[0], delim)
 	sum := 0.0
 	for _, v := range a { sum += v * v }
 	mag := math.Sqrt(sum)
@@ -750,20 +840,24 @@ func fnVunit(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	}
 	r := make([]float64, len(a))
 	for i := range a { r[i] = a[i] / mag }
-	writeVector(buf, r)
+	writeVectorSep(ctx, buf, r, osep)
 }
 
+// vdim(<vector>[,<delim>])
 func fnVdim(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	a := parseVector(args[0])
+	delim, _ := vectorDelims(args, 1)
+	a := parseVectorDelim(args[0], delim)
 	writeInt(buf, len(a))
 }
 
 // fnVcross — 3D cross product.
-func fnVcross(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+// vcross(<vector>,<vector>[,<delim>[,<output delim>]])
+func fnVcross(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
-	a := parseVector(args[0])
-	b := parseVector(args[1])
+	delim, osep := vectorDelims(args, 2)
+	a := parseVectorDelim(args[0], delim)
+	b := parseVectorDelim(args[1], delim)
 	if len(a) != 3 || len(b) != 3 {
 		buf.WriteString("#-1 VECTORS MUST BE 3D")
 		return
@@ -773,11 +867,11 @@ func fnVcross(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 		a[2]*b[0] - a[0]*b[2],
 		a[0]*b[1] - a[1]*b[0],
 	}
-	writeVector(buf, r)
+	writeVectorSep(ctx, buf, r, osep)
 }
 
 // fnVdist — N-dimensional distance between two points.
-func fnVdist(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnVdist(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
 	a := parseVector(args[0])
 	b := parseVector(args[1])
@@ -790,12 +884,12 @@ func fnVdist(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 		d := a[i] - b[i]
 		sum += d * d
 	}
-	writeFloat(buf, math.Sqrt(sum))
+	writeFloat(ctx, buf, math.Sqrt(sum))
 }
 
 // fnVlerp — linear interpolation between two vectors.
 // vlerp(v1, v2, t) — t=0 returns v1, t=1 returns v2.
-func fnVlerp(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnVlerp(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 3 { return }
 	a := parseVector(args[0])
 	b := parseVector(args[1])
@@ -808,7 +902,7 @@ func fnVlerp(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	for i := range a {
 		r[i] = a[i] + t*(b[i]-a[i])
 	}
-	writeVector(buf, r)
+	writeVector(ctx, buf, r)
 }
 
 // fnVnear — proximity test: returns 1 if v2 is within radius of v1.
@@ -835,7 +929,7 @@ func fnVnear(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 
 // fnVclamp — clamp each component of a vector to min/max bounds.
 // vclamp(v, min, max) — each arg is a vector of the same dimension.
-func fnVclamp(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnVclamp(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 3 { return }
 	v := parseVector(args[0])
 	lo := parseVector(args[1])
@@ -848,41 +942,41 @@ func fnVclamp(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 	for i := range v {
 		r[i] = math.Max(lo[i], math.Min(hi[i], v[i]))
 	}
-	writeVector(buf, r)
+	writeVector(ctx, buf, r)
 }
 
 // fnAtan2 — two-argument arctangent (radians).
-func fnAtan2(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAtan2(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
 	y := toFloat(args[0])
 	x := toFloat(args[1])
-	writeFloat(buf, math.Atan2(y, x))
+	writeFloat(ctx, buf, math.Atan2(y, x))
 }
 
 // fnBound — clamp a scalar value to [min, max].
 // bound(value, min, max)
-func fnBound(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnBound(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 3 { buf.WriteString("0"); return }
 	val := toFloat(args[0])
 	lo := toFloat(args[1])
 	hi := toFloat(args[2])
-	writeFloat(buf, math.Max(lo, math.Min(hi, val)))
+	writeFloat(ctx, buf, math.Max(lo, math.Min(hi, val)))
 }
 
 // fnAvg — average of a list of numbers.
 // avg(n1, n2, ...)
-func fnAvg(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnAvg(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) == 0 { buf.WriteString("0"); return }
 	sum := 0.0
 	for _, a := range args {
 		sum += toFloat(a)
 	}
-	writeFloat(buf, sum/float64(len(args)))
+	writeFloat(ctx, buf, sum/float64(len(args)))
 }
 
 // fnMedian — median of a list of numbers.
 // median(n1, n2, ...)
-func fnMedian(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnMedian(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) == 0 { buf.WriteString("0"); return }
 	vals := make([]float64, len(args))
 	for i, a := range args {
@@ -898,9 +992,9 @@ func fnMedian(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 	}
 	n := len(vals)
 	if n%2 == 0 {
-		writeFloat(buf, (vals[n/2-1]+vals[n/2])/2)
+		writeFloat(ctx, buf, (vals[n/2-1]+vals[n/2])/2)
 	} else {
-		writeFloat(buf, vals[n/2])
+		writeFloat(ctx, buf, vals[n/2])
 	}
 }
 
@@ -921,31 +1015,31 @@ func fnBetween(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 }
 
 // fnCosh — hyperbolic cosine.
-func fnCosh(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnCosh(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Cosh(toFloat(args[0])))
+	writeFloat(ctx, buf, math.Cosh(toFloat(args[0])))
 }
 
 // fnSinh — hyperbolic sine.
-func fnSinh(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnSinh(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Sinh(toFloat(args[0])))
+	writeFloat(ctx, buf, math.Sinh(toFloat(args[0])))
 }
 
 // fnTanh — hyperbolic tangent.
-func fnTanh(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnTanh(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { buf.WriteString("0"); return }
-	writeFloat(buf, math.Tanh(toFloat(args[0])))
+	writeFloat(ctx, buf, math.Tanh(toFloat(args[0])))
 }
 
 // fnFmod — floating-point modulo.
 // fmod(x, y) → x mod y (floating point)
-func fnFmod(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnFmod(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { buf.WriteString("0"); return }
 	x := toFloat(args[0])
 	y := toFloat(args[1])
 	if y == 0 { buf.WriteString("#-1 DIVIDE BY ZERO"); return }
-	writeFloat(buf, math.Mod(x, y))
+	writeFloat(ctx, buf, math.Mod(x, y))
 }
 
 // fnTobin — convert integer to binary string.
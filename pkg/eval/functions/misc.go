@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand/v2"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,16 +21,9 @@ func fnSetq(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 	}
 	// setq(register, value[, register, value, ...])
 	for i := 0; i+1 < len(args); i += 2 {
-		regName := strings.TrimSpace(args[i])
-		value := args[i+1]
-		if len(regName) == 1 {
-			idx := qidxChar(regName[0])
-			if idx >= 0 && idx < eval.MaxGlobalRegs && ctx.RData != nil {
-				ctx.RData.QRegs[idx] = value
-			}
-		} else if ctx.RData != nil {
-			// Named register
-			ctx.RData.XRegs[strings.ToLower(regName)] = value
+		if err := setQReg(ctx, args[i], args[i+1]); err != "" {
+			buf.WriteString(err)
+			return
 		}
 	}
 }
@@ -38,17 +32,11 @@ func fnSetr(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 	if len(args) < 2 {
 		return
 	}
-	regName := strings.TrimSpace(args[0])
-	value := args[1]
-	if len(regName) == 1 {
-		idx := qidxChar(regName[0])
-		if idx >= 0 && idx < eval.MaxGlobalRegs && ctx.RData != nil {
-			ctx.RData.QRegs[idx] = value
-		}
-	} else if ctx.RData != nil {
-		ctx.RData.XRegs[strings.ToLower(regName)] = value
+	if err := setQReg(ctx, args[0], args[1]); err != "" {
+		buf.WriteString(err)
+		return
 	}
-	buf.WriteString(value)
+	buf.WriteString(args[1])
 }
 
 func fnR(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -68,6 +56,55 @@ func fnR(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb
 	}
 }
 
+// setQReg sets a numbered, lettered or named local register, returning
+// an error string if it can't. Setting a named register to the empty
+// string frees it.
+func setQReg(ctx *eval.EvalContext, name, value string) string {
+	if ctx.RData == nil {
+		return ""
+	}
+	name = strings.TrimSpace(name)
+	if len(name) == 1 {
+		idx := qidxChar(name[0])
+		if idx < 0 || idx >= eval.MaxGlobalRegs {
+			return "#-1 INVALID GLOBAL REGISTER"
+		}
+		ctx.RData.QRegs[idx] = value
+		return ""
+	}
+	if !validQRegName(name) {
+		return "#-1 INVALID GLOBAL REGISTER"
+	}
+	name = strings.ToLower(name)
+	if value == "" {
+		delete(ctx.RData.XRegs, name)
+		return ""
+	}
+	if _, ok := ctx.RData.XRegs[name]; !ok && ctx.RegisterLim > 0 && len(ctx.RData.XRegs) >= ctx.RegisterLim {
+		return "#-1 REGISTER LIMIT EXCEEDED"
+	}
+	ctx.RData.XRegs[name] = value
+	return ""
+}
+
+// validQRegName reports whether name can name a local register: a
+// letter followed by letters, digits, _, -, . or #, at most 31 long.
+func validQRegName(name string) bool {
+	if name == "" || len(name) > 31 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || strings.IndexByte("_-.#", c) >= 0):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // qidxChar converts a register character (0-9, a-z) to an index (0-35).
 func qidxChar(ch byte) int {
 	if ch >= '0' && ch <= '9' {
@@ -117,112 +154,67 @@ func fnThink(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	})
 }
 
-func fnSet(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	if len(args) < 2 || ctx.GameState == nil {
-		return
-	}
-	first := strings.TrimSpace(args[0])
-	second := args[1]
-
-	// Check for obj/attr form
-	if slashIdx := strings.IndexByte(first, '/'); slashIdx >= 0 {
-		objStr := first[:slashIdx]
-		attrName := first[slashIdx+1:]
-		ref := resolveDBRef(ctx, objStr)
-		if ref == gamedb.Nothing {
-			return
-		}
-		if !ctx.GameState.Controls(ctx.Player, ref) {
-			return
-		}
-		ctx.GameState.SetAttrByName(ref, attrName, second)
+// sideEffect hands a side-effect function to the game, which runs the
+// matching command as the executor.
+func sideEffect(ctx *eval.EvalContext, fn string, args []string, buf *strings.Builder) {
+	if ctx.GameState == nil {
 		return
 	}
+	buf.WriteString(ctx.GameState.SideEffect(ctx.Player, fn, args))
+}
 
-	// Flag set/clear: set(obj, FLAG) or set(obj, !FLAG)
-	ref := resolveDBRef(ctx, first)
-	if ref == gamedb.Nothing {
-		return
-	}
-	if !ctx.GameState.Controls(ctx.Player, ref) {
-		return
-	}
-	ctx.GameState.SetFlag(ref, strings.TrimSpace(second))
+// fnSet: set(<object>, <flag or attr:value>) — @set <object>=<arg 2>.
+func fnSet(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	sideEffect(ctx, "set", args, buf)
 }
 
+// fnCreate: create(<name>, <cost>[, <type>]) — @create, or @dig or @open
+// for type r or e. Returns the new object's dbref.
 func fnCreate(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 || ctx.GameState == nil {
 		buf.WriteString("#-1")
 		return
 	}
-	name := strings.TrimSpace(args[0])
-	if name == "" {
+	sideEffect(ctx, "create", args, buf)
+}
+
+// fnDig: dig(<name>[, <exit to>[, <exit back>]]) — @dig. Returns the new
+// room's dbref.
+func fnDig(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if len(args) < 1 || ctx.GameState == nil {
 		buf.WriteString("#-1")
 		return
 	}
+	sideEffect(ctx, "dig", args, buf)
+}
 
-	owner := ctx.Player
-
-	switch {
-	case len(args) >= 3 && strings.EqualFold(strings.TrimSpace(args[1]), "e"):
-		// create(name, e, dest) — create an exit
-		loc := ctx.GameState.PlayerLocation(owner)
-		destRef := gamedb.Nothing
-		destStr := strings.TrimSpace(args[2])
-		if destStr != "" {
-			destRef = resolveDBRef(ctx, destStr)
-		}
-		ref := ctx.GameState.CreateExit(name, loc, destRef, owner)
-		buf.WriteString(fmt.Sprintf("#%d", ref))
-
-	case len(args) >= 2 && strings.EqualFold(strings.TrimSpace(args[1]), "r"):
-		// create(name, r) — create a room
-		ref := ctx.GameState.CreateObject(name, gamedb.TypeRoom, owner)
-		buf.WriteString(fmt.Sprintf("#%d", ref))
-
-	default:
-		// create(name) — create a thing, place in player inventory
-		ref := ctx.GameState.CreateObject(name, gamedb.TypeThing, owner)
-		obj, ok := ctx.DB.Objects[ref]
-		if !ok {
-			buf.WriteString("#-1")
-			return
-		}
-		// Place in player's inventory
-		obj.Location = owner
-		if pObj, ok := ctx.DB.Objects[owner]; ok {
-			obj.Next = pObj.Contents
-			pObj.Contents = ref
-		}
-		buf.WriteString(fmt.Sprintf("#%d", ref))
+// fnOpen: open(<name>[, <destination>]) — @open. Returns the new exit's
+// dbref.
+func fnOpen(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if len(args) < 1 || ctx.GameState == nil {
+		buf.WriteString("#-1")
+		return
 	}
+	sideEffect(ctx, "open", args, buf)
 }
 
+// fnTel: tel(<object>, <destination>) — @tel <object>=<destination>.
 func fnTel(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	if len(args) < 2 || ctx.GameState == nil {
-		return
-	}
-	victim := resolveDBRef(ctx, args[0])
-	dest := resolveDBRef(ctx, args[1])
-	if victim == gamedb.Nothing || dest == gamedb.Nothing {
-		return
-	}
-	if !ctx.GameState.Controls(ctx.Player, victim) {
-		return
-	}
-	ctx.GameState.Teleport(victim, dest)
+	sideEffect(ctx, "tel", args, buf)
 }
 
-func fnLink(_ *eval.EvalContext, _ []string, _ *strings.Builder, _, _ gamedb.DBRef) {
-	// Stub - would link object
+// fnLink: link(<object>, <destination>) — @link <object>=<destination>.
+func fnLink(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	sideEffect(ctx, "link", args, buf)
 }
 
 func fnTrigger(_ *eval.EvalContext, _ []string, _ *strings.Builder, _, _ gamedb.DBRef) {
 	// Stub - would trigger an attribute
 }
 
-func fnWipe(_ *eval.EvalContext, _ []string, _ *strings.Builder, _, _ gamedb.DBRef) {
-	// Stub - would wipe attributes
+// fnWipe: wipe(<object>[/<wild-attr>]) — @wipe.
+func fnWipe(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	sideEffect(ctx, "wipe", args, buf)
 }
 
 func fnForce(_ *eval.EvalContext, _ []string, _ *strings.Builder, _, _ gamedb.DBRef) {
@@ -316,12 +308,12 @@ func fnLrand(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 
 // Time functions
 
-func fnTime(_ *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	buf.WriteString(time.Now().Format("Mon Jan 02 15:04:05 2006"))
+func fnTime(ctx *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	buf.WriteString(ctx.Now().Format("Mon Jan 02 15:04:05 2006"))
 }
 
-func fnSecs(_ *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	buf.WriteString(strconv.FormatInt(time.Now().Unix(), 10))
+func fnSecs(ctx *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	buf.WriteString(strconv.FormatInt(ctx.Now().Unix(), 10))
 }
 
 func fnConvsecs(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -359,12 +351,12 @@ func fnConvtime(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 	buf.WriteString("-1")
 }
 
-func fnTimefmt(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+func fnTimefmt(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 {
 		return
 	}
 	format := args[0]
-	t := time.Now()
+	t := ctx.Now()
 	if len(args) > 1 {
 		secs, err := strconv.ParseInt(strings.TrimSpace(args[1]), 10, 64)
 		if err == nil {
@@ -492,9 +484,20 @@ func fnSetx(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 	ctx.RData.XRegs[name] = args[1]
 }
 
-// fnLregs — list all set register names.
+// fnLregs — list all set register names: lregs(), or listq([pattern])
+// to list only those matching a wildcard pattern.
 func fnLregs(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	if ctx.RData == nil { return }
+	pattern := ""
+	if len(args) > 0 { pattern = strings.TrimSpace(args[0]) }
+	buf.WriteString(strings.Join(qRegNames(ctx, pattern), " "))
+}
+
+// qRegNames lists the non-empty local registers whose names match
+// pattern (all of them if it's empty): numbered and lettered registers
+// in order, then named ones alphabetically. Internal registers, whose
+// names start with "__", are left out.
+func qRegNames(ctx *eval.EvalContext, pattern string) []string {
+	if ctx.RData == nil { return nil }
 	var names []string
 	for i := 0; i < eval.MaxGlobalRegs; i++ {
 		if ctx.RData.QRegs[i] != "" {
@@ -505,10 +508,42 @@ func fnLregs(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 			}
 		}
 	}
+	var named []string
 	for k := range ctx.RData.XRegs {
-		names = append(names, k)
+		if !strings.HasPrefix(k, "__") {
+			named = append(named, k)
+		}
+	}
+	sort.Strings(named)
+	names = append(names, named...)
+	if pattern == "" { return names }
+	matched := names[:0]
+	for _, n := range names {
+		if wildMatch(pattern, n) {
+			matched = append(matched, n)
+		}
+	}
+	return matched
+}
+
+// fnClearregs — empty local registers: clearregs() clears them all, or
+// clearregs(<register list>) just those named.
+func fnClearregs(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.RData == nil { return }
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		for i := range ctx.RData.QRegs {
+			ctx.RData.QRegs[i] = ""
+		}
+		for k := range ctx.RData.XRegs {
+			if !strings.HasPrefix(k, "__") {
+				delete(ctx.RData.XRegs, k)
+			}
+		}
+		return
+	}
+	for _, name := range strings.Fields(args[0]) {
+		setQReg(ctx, name, "")
 	}
-	buf.WriteString(strings.Join(names, " "))
 }
 
 // fnQvars — set multiple q-registers from a list.
@@ -637,16 +672,17 @@ func regmatchHelper(ctx *eval.EvalContext, args []string, buf *strings.Builder,
 
 // fnRegedit — regex-based search and replace.
 // regedit(string, pattern, replacement[, pattern, replacement, ...])
+// Only the first match is replaced; regeditall() replaces them all.
 func fnRegedit(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	regeditHelper(args, buf, false)
+	regeditHelper(args, buf, false, false)
 }
 
 // fnRegediti — case-insensitive regedit.
 func fnRegediti(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	regeditHelper(args, buf, true)
+	regeditHelper(args, buf, true, false)
 }
 
-func regeditHelper(args []string, buf *strings.Builder, caseInsensitive bool) {
+func regeditHelper(args []string, buf *strings.Builder, caseInsensitive, all bool) {
 	if len(args) < 3 { return }
 	result := args[0]
 	for i := 1; i+1 < len(args); i += 2 {
@@ -654,19 +690,46 @@ func regeditHelper(args []string, buf *strings.Builder, caseInsensitive bool) {
 		if caseInsensitive { pattern = "(?i)" + pattern }
 		re, err := regexp.Compile(pattern)
 		if err != nil { continue }
-		result = re.ReplaceAllString(result, args[i+1])
+		template := regeditTemplate(args[i+1])
+		if all {
+			result = re.ReplaceAllString(result, template)
+			continue
+		}
+		m := re.FindStringSubmatchIndex(result)
+		if m == nil { continue }
+		repl := re.ExpandString(nil, template, result, m)
+		result = result[:m[0]] + string(repl) + result[m[1]:]
 	}
 	buf.WriteString(result)
 }
 
-// fnRegeditall — regex-based search and replace (all occurrences, same as regedit in Go).
+// regeditTemplate turns $<number> in a regedit() replacement into Go's
+// ${<number>}, so "$1rash" is the first sub-match followed by "rash"
+// rather than a group named "1rash".
+func regeditTemplate(repl string) string {
+	if !strings.Contains(repl, "$") { return repl }
+	var b strings.Builder
+	for i := 0; i < len(repl); i++ {
+		if repl[i] != '$' || i+1 >= len(repl) || repl[i+1] < '0' || repl[i+1] > '9' {
+			b.WriteByte(repl[i])
+			continue
+		}
+		j := i + 1
+		for j < len(repl) && repl[j] >= '0' && repl[j] <= '9' { j++ }
+		b.WriteString("${" + repl[i+1:j] + "}")
+		i = j - 1
+	}
+	return b.String()
+}
+
+// fnRegeditall — regex-based search and replace of every match.
 func fnRegeditall(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	regeditHelper(args, buf, false)
+	regeditHelper(args, buf, false, true)
 }
 
 // fnRegeditalli — case-insensitive regeditall.
 func fnRegeditalli(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
-	regeditHelper(args, buf, true)
+	regeditHelper(args, buf, true, true)
 }
 
 // fnRegrab — grab first list element matching regex.
@@ -681,6 +744,7 @@ func fnRegrabi(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 }
 
 // fnRegraball — grab all list elements matching regex.
+// regraball(list, pattern[, delim[, output delim]])
 func fnRegraball(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	regrabHelper(args, buf, false, true)
 }
@@ -698,6 +762,8 @@ func regrabHelper(args []string, buf *strings.Builder, caseInsensitive, all bool
 	if err != nil { return }
 	delim := " "
 	if len(args) > 2 && args[2] != "" { delim = args[2] }
+	osep := delim
+	if len(args) > 3 && args[3] != "" { osep = args[3] }
 	words := splitList(args[0], delim)
 	var results []string
 	for _, w := range words {
@@ -709,7 +775,7 @@ func regrabHelper(args []string, buf *strings.Builder, caseInsensitive, all bool
 			results = append(results, w)
 		}
 	}
-	buf.WriteString(strings.Join(results, delim))
+	buf.WriteString(strings.Join(results, osep))
 }
 
 // fnRegrep — search attributes on an object for a regex pattern.
@@ -735,7 +801,7 @@ func regrepHelper(ctx *eval.EvalContext, args []string, buf *strings.Builder, ca
 	if err != nil { return }
 	attrPattern := args[1]
 	var results []string
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		attrName := ""
 		if def, ok := ctx.DB.AttrNames[attr.Number]; ok {
 			attrName = def.Name
@@ -920,7 +986,7 @@ func fnBeep(_ *eval.EvalContext, _ []string, buf *strings.Builder, _, _ gamedb.D
 // fnSearch — search the database by criteria.
 // search([player] [class]=<restriction>[,<low>[,<high>]])
 // lsearch([player] [class]=<restriction>[,<low>[,<high>]])
-// Implements the C TinyMUSH search_setup/search_perform logic.
+// The search itself is the same engine @search uses (GameState.SearchObjects).
 func fnSearch(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	// Handle both search() and lsearch() arg formats:
 	// search("all type=player")        — single arg with space-separated player/class
@@ -947,260 +1013,13 @@ func fnSearch(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 		}
 	}
 
-	// Determine if caller is a wizard
-	callerObj, callerOK := ctx.DB.Objects[ctx.Player]
-	isWiz := callerOK && callerObj.Flags[0]&gamedb.FlagWizard != 0
-
-	// Parse the search specification
-	// Format: [player] [class]=<restriction>[,<low>[,<high>]]
-	var ownerRef gamedb.DBRef = ctx.Player // default: search own objects
-	searchAll := false
-	searchClass := ""
-	restriction := ""
-	lowBound := gamedb.DBRef(0)
-	highBound := gamedb.DBRef(len(ctx.DB.Objects) - 1)
-	filterType := gamedb.ObjectType(-1) // -1 means no type filter
-
-	// Split on first '=' to get left side and right side
-	eqIdx := strings.Index(raw, "=")
-	var leftSide, rightSide string
-	if eqIdx >= 0 {
-		leftSide = strings.TrimSpace(raw[:eqIdx])
-		rightSide = raw[eqIdx+1:]
-	} else {
-		// No '=' — treat entire thing as left side (e.g. "all" or player name)
-		leftSide = raw
-		rightSide = ""
-	}
-
-	// Parse low,high from right side (comma-separated after restriction)
-	if rightSide != "" {
-		parts := strings.SplitN(rightSide, ",", 3)
-		restriction = strings.TrimSpace(parts[0])
-		if len(parts) >= 2 {
-			if v, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(parts[1], "#"))); err == nil {
-				lowBound = gamedb.DBRef(v)
-			}
-		}
-		if len(parts) >= 3 {
-			if v, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(parts[2], "#"))); err == nil {
-				highBound = gamedb.DBRef(v)
-			}
-		}
-	}
-
-	// Parse left side: could be "[player] [class]" or just "[class]" or just "[player]"
-	if leftSide != "" {
-		// Split by spaces
-		words := strings.Fields(leftSide)
-		if eqIdx >= 0 {
-			// There was an '=', so the last word is the class
-			if len(words) == 1 {
-				searchClass = strings.ToLower(words[0])
-			} else {
-				// First word(s) = player, last word = class
-				searchClass = strings.ToLower(words[len(words)-1])
-				playerName := strings.Join(words[:len(words)-1], " ")
-				if strings.EqualFold(playerName, "all") {
-					if isWiz {
-						searchAll = true
-					}
-				} else {
-					ref := resolveDBRef(ctx, playerName)
-					if ref != gamedb.Nothing {
-						ownerRef = ref
-					}
-				}
-			}
-		} else {
-			// No '=' — treat as player name or "all"
-			playerName := strings.Join(words, " ")
-			if strings.EqualFold(playerName, "all") {
-				if isWiz {
-					searchAll = true
-				}
-			} else {
-				ref := resolveDBRef(ctx, playerName)
-				if ref != gamedb.Nothing {
-					ownerRef = ref
-				}
-			}
-		}
-	}
-
-	// Non-wizard can only search own objects.
-	// Wizard with no explicit player specification defaults to searching all
-	// objects (matching C TinyMUSH behavior where wizard default is ANY_OWNER).
-	if !isWiz {
-		ownerRef = ctx.Player
-		searchAll = false
-	} else if leftSide == "" || (eqIdx >= 0 && len(strings.Fields(leftSide)) == 1) {
-		// Wizard didn't specify a player name — default to search all
-		// leftSide=="" means no args at all; single word with '=' means just a class, no player
-		searchAll = true
-	}
-
-	// Determine type filter from class
-	restrictionUpper := strings.ToUpper(restriction)
-	switch searchClass {
-	case "type":
-		switch restrictionUpper {
-		case "ROOM":
-			filterType = gamedb.TypeRoom
-		case "EXIT":
-			filterType = gamedb.TypeExit
-		case "THING", "OBJECT":
-			filterType = gamedb.TypeThing
-		case "PLAYER":
-			filterType = gamedb.TypePlayer
-		case "GARBAGE":
-			filterType = gamedb.TypeGarbage
-		}
-	case "rooms":
-		filterType = gamedb.TypeRoom
-	case "exits":
-		filterType = gamedb.TypeExit
-	case "objects", "things":
-		filterType = gamedb.TypeThing
-	case "players":
-		filterType = gamedb.TypePlayer
-	case "eroom":
-		filterType = gamedb.TypeRoom
-	case "eexit":
-		filterType = gamedb.TypeExit
-	case "eobject", "ething":
-		filterType = gamedb.TypeThing
-	case "eplayer":
-		filterType = gamedb.TypePlayer
-	}
-
-	// Parse flags= restriction into a list of flag names
-	var flagNames []string
-	var flagNegate []bool
-	if searchClass == "flags" {
-		for i := 0; i < len(restriction); i++ {
-			negate := false
-			if restriction[i] == '!' {
-				negate = true
-				i++
-				if i >= len(restriction) {
-					break
-				}
-			}
-			fname := flagCharToName(restriction[i])
-			if fname != "" {
-				flagNames = append(flagNames, fname)
-				flagNegate = append(flagNegate, negate)
-			}
-		}
-	}
-
-	// Parse parent= and zone= restrictions
-	var parentRef gamedb.DBRef = gamedb.Nothing
-	var zoneRef gamedb.DBRef = gamedb.Nothing
-	if searchClass == "parent" && restriction != "" {
-		parentRef = resolveDBRef(ctx, restriction)
-	}
-	if searchClass == "zone" && restriction != "" {
-		zoneRef = resolveDBRef(ctx, restriction)
-	}
-
-	// Prepare eval expression for eval=/evaluate=/eplayer=/eroom= etc.
-	isEvalClass := false
-	switch searchClass {
-	case "eval", "evaluate", "eplayer", "eroom", "eobject", "ething", "eexit":
-		isEvalClass = true
-	}
-
-	// Determine if name-based classes need name matching
-	isNameClass := false
-	switch searchClass {
-	case "name", "rooms", "exits", "objects", "things", "players":
-		isNameClass = true
-	}
-
-	// Perform the search
-	var results []string
-	for ref := lowBound; ref <= highBound; ref++ {
-		obj, ok := ctx.DB.Objects[ref]
-		if !ok {
-			continue
-		}
-
-		// Skip GOING objects
-		if obj.Flags[0]&gamedb.FlagGoing != 0 {
-			continue
-		}
-
-		// Owner filter
-		if !searchAll && obj.Owner != ownerRef {
-			continue
-		}
-
-		// Type filter
-		if filterType >= 0 && obj.ObjType() != filterType {
-			continue
-		}
-
-		// Class-specific filters
-		switch searchClass {
-		case "type":
-			// Already handled by filterType above
-		case "name":
-			if restriction != "" && !wildMatch(restriction+"*", obj.Name) {
-				continue
-			}
-		case "rooms", "exits", "objects", "things", "players":
-			if isNameClass && restriction != "" && !wildMatch(restriction+"*", obj.Name) {
-				continue
-			}
-		case "flags":
-			match := true
-			for i, fname := range flagNames {
-				has := objHasFlag(obj, fname)
-				if flagNegate[i] {
-					if has {
-						match = false
-						break
-					}
-				} else {
-					if !has {
-						match = false
-						break
-					}
-				}
-			}
-			if !match {
-				continue
-			}
-		case "parent":
-			if parentRef != gamedb.Nothing && obj.Parent != parentRef {
-				continue
-			}
-		case "zone":
-			if zoneRef != gamedb.Nothing && obj.Zone != zoneRef {
-				continue
-			}
-		case "eval", "evaluate", "eplayer", "eroom", "eobject", "ething", "eexit":
-			if isEvalClass && restriction != "" {
-				// Replace ## with current dbref
-				expr := strings.ReplaceAll(restriction, "##", fmt.Sprintf("#%d", ref))
-				result := ctx.Exec(expr, eval.EvFCheck|eval.EvEval, nil)
-				result = strings.TrimSpace(result)
-				if result == "" || result == "0" || result == "#-1" {
-					continue
-				}
-			}
-		case "":
-			// No class — list all matching owner (already filtered)
-		default:
-			// Unknown class — skip silently
-			continue
-		}
-
-		results = append(results, fmt.Sprintf("#%d", ref))
+	if ctx.GameState == nil {
+		buf.WriteString("#-1")
+		return
 	}
-	buf.WriteString(strings.Join(results, " "))
+	buf.WriteString(ctx.GameState.SearchObjects(ctx.Player, raw, func(expr string) string {
+		return ctx.Exec(expr, eval.EvFCheck|eval.EvEval, nil)
+	}))
 }
 
 // fnStats — return database statistics.
@@ -1275,18 +1094,14 @@ func fnPfind(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	if len(args) < 1 { buf.WriteString("#-1"); return }
 	name := strings.TrimSpace(args[0])
 	if strings.HasPrefix(name, "*") { name = name[1:] }
-	for ref, obj := range ctx.DB.Objects {
-		if obj.ObjType() == gamedb.TypePlayer && strings.EqualFold(obj.Name, name) {
-			buf.WriteString(fmt.Sprintf("#%d", ref))
-			return
-		}
+	if ref := ctx.DB.LookupPlayer(name); ref != gamedb.Nothing {
+		buf.WriteString(fmt.Sprintf("#%d", ref))
+		return
 	}
 	// Partial match
-	for ref, obj := range ctx.DB.Objects {
-		if obj.ObjType() == gamedb.TypePlayer && strings.HasPrefix(strings.ToLower(obj.Name), strings.ToLower(name)) {
-			buf.WriteString(fmt.Sprintf("#%d", ref))
-			return
-		}
+	if refs := ctx.DB.PlayersByPrefix(name); len(refs) > 0 {
+		buf.WriteString(fmt.Sprintf("#%d", refs[0]))
+		return
 	}
 	buf.WriteString("#-1 NO MATCH")
 }
@@ -1403,7 +1218,7 @@ func fnPlaymem(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _
 	for _, obj := range ctx.DB.Objects {
 		if obj.Owner == ref {
 			total += 128 + len(obj.Name)
-			for _, attr := range obj.Attrs {
+			for _, attr := range obj.Attributes() {
 				total += 16 + len(attr.Value)
 			}
 		}
@@ -1515,7 +1330,7 @@ func fnProgrammer(ctx *eval.EvalContext, args []string, buf *strings.Builder, _,
 		buf.WriteString("#-1"); return
 	}
 	// Check if player has A_PROGCMD attribute (set during @program)
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		if attr.Number == gamedb.A_PROGCMD {
 			buf.WriteString("#-1 IN PROGRAM")
 			return
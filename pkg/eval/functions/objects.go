@@ -102,7 +102,7 @@ func fnObjmem(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ g
 	obj, ok := ctx.DB.Objects[ref]
 	if !ok { buf.WriteString("0"); return }
 	size := 128 + len(obj.Name) // base struct + name
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		size += 16 + len(attr.Value) // overhead + value
 	}
 	writeInt(buf, size)
@@ -274,6 +274,7 @@ func fnNum(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 func fnLoc(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { return }
 	ref := resolveDBRef(ctx, args[0])
+	if !locatable(ctx, ref) { buf.WriteString("#-1"); return }
 	if obj, ok := ctx.DB.Objects[ref]; ok {
 		buf.WriteString(fmt.Sprintf("#%d", obj.Location))
 	} else {
@@ -281,6 +282,12 @@ func fnLoc(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	}
 }
 
+// locatable reports whether the executor may learn where ref is: UNFINDABLE
+// objects and rooms hide from those without the find_unfindable power.
+func locatable(ctx *eval.EvalContext, ref gamedb.DBRef) bool {
+	return ctx.GameState == nil || ctx.GameState.Locatable(ctx.Player, ref)
+}
+
 func fnOwner(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { return }
 	ref := resolveDBRef(ctx, args[0])
@@ -443,7 +450,7 @@ func fnHasattr(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _
 	attrName := strings.ToUpper(strings.TrimSpace(args[1]))
 	// Look up attr number
 	if def, ok := ctx.DB.AttrByName[attrName]; ok {
-		for _, attr := range obj.Attrs {
+		for _, attr := range obj.Attributes() {
 			if attr.Number == def.Number {
 				text := eval.StripAttrPrefix(attr.Value)
 				buf.WriteString(boolToStr(text != ""))
@@ -454,7 +461,7 @@ func fnHasattr(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _
 	// Also check well-known
 	for num, name := range gamedb.WellKnownAttrs {
 		if strings.EqualFold(name, attrName) {
-			for _, attr := range obj.Attrs {
+			for _, attr := range obj.Attributes() {
 				if attr.Number == num {
 					text := eval.StripAttrPrefix(attr.Value)
 					buf.WriteString(boolToStr(text != ""))
@@ -528,9 +535,21 @@ func fnS(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb
 	buf.WriteString(result)
 }
 
+// fnUlambda is u() run as the caller: ulambda(obj/attr or #lambda/code, args...)
+func fnUlambda(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if len(args) < 1 { return }
+	buf.WriteString(ctx.CallIterFun(args[0], args[1:]))
+}
+
+// fnObjeval evaluates an expression from another object's viewpoint:
+// objeval(obj, expr). Without permission to use obj's viewpoint, the
+// expression is evaluated from the caller's own, as in TinyMUSH.
 func fnObjeval(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 2 { return }
 	ref := resolveDBRef(ctx, ctx.Exec(args[0], eval.EvFCheck|eval.EvEval, nil))
+	if ref == gamedb.Nothing || (ctx.GameState != nil && !ctx.GameState.CanObjeval(ctx.Player, ref)) {
+		ref = ctx.Player
+	}
 	oldPlayer := ctx.Player
 	ctx.Player = ref
 	result := ctx.Exec(args[1], eval.EvFCheck|eval.EvEval, nil)
@@ -626,7 +645,7 @@ func fnLattr(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	obj, ok := ctx.DB.Objects[ref]
 	if !ok { return }
 	var names []string
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		name := ctx.DB.GetAttrName(attr.Number)
 		if name == "" { name = fmt.Sprintf("ATTR_%d", attr.Number) }
 		if pattern == "*" || wildMatch(pattern, name) {
@@ -640,7 +659,7 @@ func fnNattr(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	if len(args) < 1 { buf.WriteString("0"); return }
 	ref := resolveDBRef(ctx, args[0])
 	if obj, ok := ctx.DB.Objects[ref]; ok {
-		writeInt(buf, len(obj.Attrs))
+		writeInt(buf, len(obj.Attributes()))
 	} else { buf.WriteString("0") }
 }
 
@@ -696,6 +715,7 @@ func fnControls(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _
 func fnRoom(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 1 { return }
 	ref := resolveDBRef(ctx, args[0])
+	if !locatable(ctx, ref) { buf.WriteString("#-1"); return }
 	// Walk up locations until we find a room
 	for i := 0; i < 100; i++ {
 		obj, ok := ctx.DB.Objects[ref]
@@ -942,7 +962,7 @@ func grepHelper(ctx *eval.EvalContext, args []string, buf *strings.Builder, case
 	if caseInsensitive { searchPattern = strings.ToLower(searchPattern) }
 
 	var results []string
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		attrName := ""
 		if def, ok := ctx.DB.AttrNames[attr.Number]; ok {
 			attrName = def.Name
@@ -1219,6 +1239,7 @@ func fnWhere(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	if ref == gamedb.Nothing { buf.WriteString("#-1 NOT FOUND"); return }
 	obj, ok := ctx.DB.Objects[ref]
 	if !ok { buf.WriteString("#-1 NOT FOUND"); return }
+	if !locatable(ctx, ref) { buf.WriteString("#-1"); return }
 	switch obj.ObjType() {
 	case gamedb.TypeRoom:
 		buf.WriteString(fmt.Sprintf("#%d", ref))
@@ -1334,7 +1355,7 @@ func fnLcmds(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ ga
 	if len(args) > 2 && args[2] != "" { cmdChar = args[2] }
 
 	var cmds []string
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		text := eval.StripAttrPrefix(attr.Value)
 		if strings.HasPrefix(text, cmdChar) {
 			// Extract command pattern (before the colon)
@@ -1366,7 +1387,7 @@ func fnAttrcnt(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _
 		buf.WriteString("#-1 NOT FOUND"); return
 	}
 	count := 0
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		name := ""
 		if n, ok := gamedb.WellKnownAttrs[attr.Number]; ok {
 			name = n
@@ -0,0 +1,55 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// fnLps returns the PIDs of queue entries.
+// Usage: lps([<object>])
+// With no object, lists every entry the caller may see: their own objects',
+// or everything with the see_queue power.
+func fnLps(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil {
+		return
+	}
+	target := gamedb.Nothing
+	if len(args) > 0 && strings.TrimSpace(args[0]) != "" {
+		if target = resolveDBRef(ctx, args[0]); target == gamedb.Nothing {
+			buf.WriteString("#-1 NOT FOUND")
+			return
+		}
+	}
+	buf.WriteString(ctx.GameState.QueuePIDs(ctx.Player, target))
+}
+
+// fnQentries returns the number of queue entries run by an object.
+// Usage: qentries(<object>)
+func fnQentries(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil || len(args) < 1 {
+		return
+	}
+	target := resolveDBRef(ctx, args[0])
+	if target == gamedb.Nothing {
+		buf.WriteString("#-1 NOT FOUND")
+		return
+	}
+	buf.WriteString(ctx.GameState.QueueEntries(ctx.Player, target))
+}
+
+// fnSemcount returns the number of commands waiting on a semaphore.
+// Usage: semcount(<object>[/<attr>])
+func fnSemcount(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
+	if ctx.GameState == nil || len(args) < 1 {
+		return
+	}
+	objStr, attrName, _ := strings.Cut(args[0], "/")
+	target := resolveDBRef(ctx, objStr)
+	if target == gamedb.Nothing {
+		buf.WriteString("#-1 NOT FOUND")
+		return
+	}
+	buf.WriteString(ctx.GameState.SemaphoreCount(ctx.Player, target, strings.TrimSpace(attrName)))
+}
@@ -1,11 +1,34 @@
 package functions
 
 import (
+	"sync"
+
 	"github.com/crystal-mush/gotinymush/pkg/eval"
 )
 
-// RegisterAll registers all built-in functions on the given EvalContext.
+var (
+	tableOnce sync.Once
+	table     map[string]*eval.Function
+)
+
+// Table returns the built-in function table, building it the first time.
+// Every context shares it, so it must not be changed.
+func Table() map[string]*eval.Function {
+	tableOnce.Do(func() {
+		scratch := &eval.EvalContext{}
+		registerAll(scratch)
+		table = scratch.Functions
+	})
+	return table
+}
+
+// RegisterAll gives the given EvalContext all built-in functions, sharing
+// the one table rather than building it again.
 func RegisterAll(ctx *eval.EvalContext) {
+	ctx.SetFunctions(Table())
+}
+
+func registerAll(ctx *eval.EvalContext) {
 	// Math functions
 	ctx.RegisterFunction("ADD", fnAdd, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("SUB", fnSub, 2, 0)
@@ -22,10 +45,10 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("SIGN", fnSign, 1, 0)
 	ctx.RegisterFunction("INC", fnInc, 1, 0)
 	ctx.RegisterFunction("DEC", fnDec, 1, 0)
-	ctx.RegisterFunction("ROUND", fnRound, 2, 0)
+	ctx.RegisterFunction("ROUND", fnRound, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("TRUNC", fnTrunc, 1, 0)
-	ctx.RegisterFunction("FLOOR", fnFloor, 1, 0)
-	ctx.RegisterFunction("CEIL", fnCeil, 1, 0)
+	ctx.RegisterFunction("FLOOR", fnFloor, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("CEIL", fnCeil, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("SQRT", fnSqrt, 1, 0)
 	ctx.RegisterFunction("POWER", fnPower, 2, 0)
 	ctx.RegisterFunction("MAX", fnMax, 0, eval.FnVarArgs)
@@ -50,7 +73,7 @@ func RegisterAll(ctx *eval.EvalContext) {
 	// Exponential/Logarithmic
 	ctx.RegisterFunction("EXP", fnExp, 1, 0)
 	ctx.RegisterFunction("LN", fnLn, 1, 0)
-	ctx.RegisterFunction("LOG", fnLog, 1, 0)
+	ctx.RegisterFunction("LOG", fnLog, 0, eval.FnVarArgs)
 
 	// Bitwise
 	ctx.RegisterFunction("SHL", fnShl, 2, 0)
@@ -58,6 +81,8 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("BAND", fnBand, 2, 0)
 	ctx.RegisterFunction("BOR", fnBor, 2, 0)
 	ctx.RegisterFunction("BNAND", fnBnand, 2, 0)
+	ctx.RegisterFunction("BXOR", fnBxor, 2, 0)
+	ctx.RegisterFunction("BNOT", fnBnot, 1, 0)
 
 	// Additional math
 	ctx.RegisterFunction("FLOORDIV", fnFloordiv, 2, 0)
@@ -102,6 +127,10 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("NONZERO", fnIf, 0, eval.FnVarArgs|eval.FnNoEval)
 	ctx.RegisterFunction("SWITCH", fnSwitch, 0, eval.FnVarArgs|eval.FnNoEval)
 	ctx.RegisterFunction("SWITCHALL", fnSwitchAll, 0, eval.FnVarArgs|eval.FnNoEval)
+	ctx.RegisterFunction("RESWITCH", fnReswitch, 0, eval.FnVarArgs|eval.FnNoEval)
+	ctx.RegisterFunction("RESWITCHI", fnReswitchi, 0, eval.FnVarArgs|eval.FnNoEval)
+	ctx.RegisterFunction("RESWITCHALL", fnReswitchAll, 0, eval.FnVarArgs|eval.FnNoEval)
+	ctx.RegisterFunction("RESWITCHALLI", fnReswitchAlli, 0, eval.FnVarArgs|eval.FnNoEval)
 	ctx.RegisterFunction("CASE", fnCase, 0, eval.FnVarArgs|eval.FnNoEval)
 	ctx.RegisterFunction("IFFALSE", fnIffalse, 0, eval.FnVarArgs|eval.FnNoEval)
 	ctx.RegisterFunction("IFTRUE", fnIftrue, 0, eval.FnVarArgs|eval.FnNoEval)
@@ -234,14 +263,14 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("WILDGREP", fnWildgrep, 3, 0)
 
 	// Vector math
-	ctx.RegisterFunction("VADD", fnVadd, 2, 0)
-	ctx.RegisterFunction("VSUB", fnVsub, 2, 0)
-	ctx.RegisterFunction("VMUL", fnVmul, 2, 0)
-	ctx.RegisterFunction("VDOT", fnVdot, 2, 0)
-	ctx.RegisterFunction("VMAG", fnVmag, 1, 0)
-	ctx.RegisterFunction("VUNIT", fnVunit, 1, 0)
-	ctx.RegisterFunction("VDIM", fnVdim, 1, 0)
-	ctx.RegisterFunction("VCROSS", fnVcross, 2, 0)
+	ctx.RegisterFunction("VADD", fnVadd, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("VSUB", fnVsub, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("VMUL", fnVmul, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("VDOT", fnVdot, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("VMAG", fnVmag, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("VUNIT", fnVunit, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("VDIM", fnVdim, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("VCROSS", fnVcross, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("VDIST", fnVdist, 2, 0)
 	ctx.RegisterFunction("VLERP", fnVlerp, 3, 0)
 	ctx.RegisterFunction("VNEAR", fnVnear, 3, 0)
@@ -279,6 +308,9 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("X", fnX, 1, 0)
 	ctx.RegisterFunction("SETX", fnSetx, 2, 0)
 	ctx.RegisterFunction("LREGS", fnLregs, 0, 0)
+	ctx.RegisterFunction("LISTQ", fnLregs, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("CLEARREGS", fnClearregs, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("CLEARQ", fnClearregs, 0, eval.FnVarArgs) // alias
 	ctx.RegisterFunction("QVARS", fnQvars, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("XVARS", fnXvars, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("CLEARVARS", fnClearvars, 0, 0)
@@ -301,6 +333,7 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("V", fnV, 1, 0)
 	ctx.RegisterFunction("U", fnU, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("ULOCAL", fnUlocal, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("ULAMBDA", fnUlambda, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("S", fnS, 1, 0)
 	ctx.RegisterFunction("OBJEVAL", fnObjeval, 2, eval.FnNoEval)
 	ctx.RegisterFunction("DEFAULT", fnDefault, 2, eval.FnNoEval)
@@ -324,6 +357,7 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("CONN", fnConn, 1, 0)
 	ctx.RegisterFunction("IDLE", fnIdleFn, 1, 0)
 	ctx.RegisterFunction("DOING", fnDoingFn, 1, 0)
+	ctx.RegisterFunction("POLL", fnPoll, 0, 0)
 	ctx.RegisterFunction("PMATCH", fnPmatch, 1, 0)
 
 	// Additional object query functions
@@ -374,6 +408,7 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("WRAP", fnWrap, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("COLUMNS", fnColumns, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("TABLE", fnTable, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("ALIGN", fnAlign, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("TABLES", fnTables, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("RTABLES", fnRtables, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("CTABLES", fnCtables, 0, eval.FnVarArgs)
@@ -385,6 +420,8 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("THINK", fnThink, 1, 0)
 	ctx.RegisterFunction("SET", fnSet, 2, 0)
 	ctx.RegisterFunction("CREATE", fnCreate, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("DIG", fnDig, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("OPEN", fnOpen, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("TEL", fnTel, 2, 0)
 	ctx.RegisterFunction("LINK", fnLink, 2, 0)
 	ctx.RegisterFunction("TRIGGER", fnTrigger, 0, eval.FnVarArgs)
@@ -405,6 +442,8 @@ func RegisterAll(ctx *eval.EvalContext) {
 	// Database / SQL
 	ctx.RegisterFunction("SQL", fnSQL, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("SQLESCAPE", fnSQLEscape, 1, 0)
+	ctx.RegisterFunction("HTTPGET", fnHTTPGet, 1, 0)
+	ctx.RegisterFunction("HTTPPOST", fnHTTPPost, 0, eval.FnVarArgs)
 
 	// Regex functions
 	ctx.RegisterFunction("REGMATCH", fnRegmatch, 0, eval.FnVarArgs)
@@ -567,9 +606,26 @@ func RegisterAll(ctx *eval.EvalContext) {
 	ctx.RegisterFunction("MAIL", fnMail, 0, eval.FnVarArgs)
 	ctx.RegisterFunction("MAILFROM", fnMailfrom, 1, 0)
 	ctx.RegisterFunction("MAILSUBJ", fnMailsubj, 1, 0)
+	ctx.RegisterFunction("MAILLIST", fnMaillist, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("MAILREAD", fnMailread, 1, eval.FnVarArgs)
+	ctx.RegisterFunction("MAILSEND", fnMailsend, 2, eval.FnVarArgs)
+
+	// Queue inspection
+	ctx.RegisterFunction("LPS", fnLps, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("QENTRIES", fnQentries, 1, 0)
+	ctx.RegisterFunction("SEMCOUNT", fnSemcount, 1, 0)
 
 	// Channel/Comsys functions
 	ctx.RegisterFunction("CINFO", fnCinfo, 2, 0)
+	ctx.RegisterFunction("COMLIST", fnComlist, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("CLIST", fnComlist, 0, eval.FnVarArgs)
+	ctx.RegisterFunction("CWHO", fnCwho, 1, 0)
+	ctx.RegisterFunction("CWHOALL", fnCwhoall, 1, 0)
+	ctx.RegisterFunction("CFLAGS", fnCflags, 1, 0)
+	ctx.RegisterFunction("COMALIAS", fnComalias, 1, 0)
+	ctx.RegisterFunction("COMTITLE", fnComtitle, 2, 0)
+	ctx.RegisterFunction("COMINFO", fnCominfo, 2, 0)
+	ctx.RegisterFunction("CEMIT", fnCemit, 2, 0)
 
 	// Attribute definition functions
 	ctx.RegisterFunction("LATTRDEF", fnLattrdef, 0, eval.FnVarArgs)
@@ -10,7 +10,6 @@ import (
 	"hash"
 	"hash/crc32"
 	"math/rand/v2"
-	"regexp"
 	"strconv"
 	"strings"
 
@@ -186,19 +185,7 @@ func fnSquish(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 // sequences. In TinyMUSH, ljust/rjust/center pad based on visible characters,
 // not raw byte length, so ANSI color codes don't count toward width.
 func visualLen(s string) int {
-	n := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
-			// Skip ESC [ ... <letter> sequence
-			i += 2
-			for i < len(s) && !((s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= 'a' && s[i] <= 'z')) {
-				i++
-			}
-			continue
-		}
-		n++
-	}
-	return n
+	return eval.VisibleWidth(s)
 }
 
 func fnLjust(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -208,11 +195,7 @@ func fnLjust(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	fill := " "
 	if len(args) > 2 && len(args[2]) > 0 { fill = args[2] }
 	buf.WriteString(s)
-	vLen := visualLen(s)
-	padLen := width - vLen
-	for i := 0; i < padLen; i += len(fill) {
-		buf.WriteString(fill)
-	}
+	writePad(buf, fill, width-visualLen(s))
 }
 
 func fnRjust(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
@@ -221,10 +204,7 @@ func fnRjust(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ game
 	width := toInt(args[1])
 	fill := " "
 	if len(args) > 2 && len(args[2]) > 0 { fill = args[2] }
-	padLen := width - visualLen(s)
-	for i := 0; i < padLen; i += len(fill) {
-		buf.WriteString(fill)
-	}
+	writePad(buf, fill, width-visualLen(s))
 	buf.WriteString(s)
 }
 
@@ -240,13 +220,24 @@ func fnCenter(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gam
 		return
 	}
 	leftPad := padTotal / 2
-	for i := 0; i < leftPad; i += len(fill) {
-		buf.WriteString(fill)
-	}
+	writePad(buf, fill, leftPad)
 	buf.WriteString(s)
-	rightPad := padTotal - leftPad
-	for i := 0; i < rightPad; i += len(fill) {
-		buf.WriteString(fill)
+	writePad(buf, fill, padTotal-leftPad)
+}
+
+// writePad writes exactly n visible columns of padding, cycling through the
+// characters of fill. Color codes in fill are dropped so that the padding
+// never bleeds into the text that follows it.
+func writePad(buf *strings.Builder, fill string, n int) {
+	if n <= 0 {
+		return
+	}
+	runes := []rune(eval.StripAnsi(fill))
+	if len(runes) == 0 {
+		runes = []rune{' '}
+	}
+	for i := 0; i < n; i++ {
+		buf.WriteRune(runes[i%len(runes)])
 	}
 }
 
@@ -335,10 +326,8 @@ func fnStripansi(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _
 	buf.WriteString(stripAnsiStr(args[0]))
 }
 
-var ansiRegexp = regexp.MustCompile(`\033\[[0-9;]*m`)
-
 func stripAnsiStr(s string) string {
-	return ansiRegexp.ReplaceAllString(s, "")
+	return eval.StripAnsi(s)
 }
 
 func fnBefore(_ *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
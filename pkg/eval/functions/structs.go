@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -104,6 +105,23 @@ func toPersistedInst(inst *structInstance) *gamedb.StructInstance {
 	}
 }
 
+// PurgeStructs drops every structure and instance belonging to obj, as
+// when it's destroyed, and returns their names so they can be deleted
+// from storage too.
+func PurgeStructs(obj gamedb.DBRef) (defs, insts []string) {
+	globalStructs.mu.Lock()
+	defer globalStructs.mu.Unlock()
+	for name := range globalStructs.Structs[obj] {
+		defs = append(defs, name)
+	}
+	for name := range globalStructs.Instances[obj] {
+		insts = append(insts, name)
+	}
+	delete(globalStructs.Structs, obj)
+	delete(globalStructs.Instances, obj)
+	return defs, insts
+}
+
 func getPlayerStructs(player gamedb.DBRef) map[string]*structDef {
 	if globalStructs.Structs[player] == nil {
 		globalStructs.Structs[player] = make(map[string]*structDef)
@@ -144,7 +162,9 @@ func typeCheck(val string, typeCode byte) bool {
 const genericStructDelim = "\f" // form feed, matches TinyMUSH
 
 // fnStructure — define a named structure.
-// structure(name, components, types[, defaults[, output-delim]])
+// structure(name, components, types[, defaults[, sep[, delim]]]) — sep
+// splits the defaults, and delim (sep unless given) is what load() and
+// unload() use.
 func fnStructure(ctx *eval.EvalContext, args []string, buf *strings.Builder, _, _ gamedb.DBRef) {
 	if len(args) < 3 { buf.WriteString("0"); return }
 
@@ -170,18 +190,18 @@ func fnStructure(ctx *eval.EvalContext, args []string, buf *strings.Builder, _,
 	}
 
 	// Parse defaults
+	sep := " "
+	if len(args) > 4 && args[4] != "" { sep = args[4] }
 	defaults := make([]string, len(components))
 	if len(args) > 3 && args[3] != "" {
-		delim := " "
-		if len(args) > 4 && args[4] != "" { delim = args[4] }
-		defVals := splitList(args[3], delim)
+		defVals := splitList(args[3], sep)
 		for i := range defaults {
 			if i < len(defVals) { defaults[i] = defVals[i] }
 		}
 	}
 
-	outDelim := " "
-	if len(args) > 4 && args[4] != "" { outDelim = args[4] }
+	outDelim := sep
+	if len(args) > 5 && args[5] != "" { outDelim = args[5] }
 
 	// Lowercase component names
 	for i := range components {
@@ -195,6 +215,9 @@ func fnStructure(ctx *eval.EvalContext, args []string, buf *strings.Builder, _,
 	if _, exists := structs[name]; exists {
 		buf.WriteString("0"); return // can't redefine
 	}
+	if ctx.StructLim > 0 && len(structs) >= ctx.StructLim {
+		buf.WriteString("0"); return
+	}
 
 	def := &structDef{
 		Name:       name,
@@ -231,6 +254,9 @@ func fnConstruct(ctx *eval.EvalContext, args []string, buf *strings.Builder, _,
 	if _, exists := instances[instName]; exists {
 		buf.WriteString("0"); return // can't recreate
 	}
+	if ctx.InstanceLim > 0 && len(instances) >= ctx.InstanceLim {
+		buf.WriteString("0"); return
+	}
 
 	// Start with defaults
 	values := make([]string, len(def.Components))
@@ -401,6 +427,9 @@ func fnLoadStruct(ctx *eval.EvalContext, args []string, buf *strings.Builder, _,
 	if _, exists := instances[instName]; exists {
 		buf.WriteString("0"); return
 	}
+	if ctx.InstanceLim > 0 && len(instances) >= ctx.InstanceLim {
+		buf.WriteString("0"); return
+	}
 
 	delim := def.Delim
 	if len(args) > 3 && args[3] != "" { delim = args[3] }
@@ -483,23 +512,21 @@ func fnWriteStruct(ctx *eval.EvalContext, args []string, buf *strings.Builder, _
 	instName := strings.ToLower(strings.TrimSpace(args[1]))
 
 	globalStructs.mu.RLock()
-	instances := globalStructs.Instances[ctx.Player]
+	inst, ok := globalStructs.Instances[ctx.Player][instName]
 	var serialized string
-	if instances != nil {
-		if inst, ok := instances[instName]; ok {
-			serialized = strings.Join(inst.Values, genericStructDelim)
-		}
+	if ok {
+		serialized = strings.Join(inst.Values, genericStructDelim)
 	}
 	globalStructs.mu.RUnlock()
 
-	if serialized == "" { return }
+	if !ok { buf.WriteString("#-1 NO SUCH INSTANCE"); return }
 
 	// Parse obj/attr
 	parts := strings.SplitN(args[0], "/", 2)
-	if len(parts) != 2 { return }
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" { buf.WriteString("#-1 BAD ATTRIBUTE"); return }
 	ref := resolveDBRef(ctx, parts[0])
-	if ref == gamedb.Nothing { return }
-	if !ctx.GameState.Controls(ctx.Player, ref) { return }
+	if ref == gamedb.Nothing { buf.WriteString("#-1 NO MATCH"); return }
+	if !ctx.GameState.Controls(ctx.Player, ref) { buf.WriteString("#-1 PERMISSION DENIED"); return }
 	ctx.GameState.SetAttrByName(ref, parts[1], serialized)
 }
 
@@ -533,6 +560,7 @@ func fnLstructures(ctx *eval.EvalContext, _ []string, buf *strings.Builder, _, _
 	for name := range structs {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	buf.WriteString(strings.Join(names, " "))
 }
 
@@ -548,6 +576,7 @@ func fnLinstances(ctx *eval.EvalContext, _ []string, buf *strings.Builder, _, _
 	for name := range instances {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	buf.WriteString(strings.Join(names, " "))
 }
 
@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
@@ -12,6 +13,9 @@ import (
 // fetched attr text with the CALLING object as executor (%!), not the target object.
 // This means v() inside the callback resolves on the caller, not on obj.
 func (ctx *EvalContext) CallIterFun(objAttr string, callArgs []string) string {
+	if code, ok := lambdaCode(objAttr); ok {
+		return ctx.Exec(code, EvFCheck|EvEval, callArgs)
+	}
 	parts := strings.SplitN(objAttr, "/", 2)
 
 	var ref gamedb.DBRef
@@ -44,9 +48,25 @@ func (ctx *EvalContext) CallIterFun(objAttr string, callArgs []string) string {
 	return ctx.Exec(text, EvFCheck|EvEval, callArgs)
 }
 
+// lambdaPrefix marks an anonymous function, "#lambda/<code>", which u()
+// and friends evaluate directly rather than fetching from an attribute.
+const lambdaPrefix = "#lambda/"
+
+// lambdaCode returns the code of an anonymous "#lambda/<code>" function.
+func lambdaCode(objAttr string) (string, bool) {
+	s := strings.TrimLeft(objAttr, " ")
+	if len(s) < len(lambdaPrefix) || !strings.EqualFold(s[:len(lambdaPrefix)], lambdaPrefix) {
+		return "", false
+	}
+	return s[len(lambdaPrefix):], true
+}
+
 // CallUFun calls a user-defined function specified as "obj/attr" with the given arguments.
 // It fetches the attribute text, sets up %0-%9 from callArgs, evaluates it, and returns the result.
 func (ctx *EvalContext) CallUFun(objAttr string, callArgs []string) string {
+	if code, ok := lambdaCode(objAttr); ok {
+		return ctx.Exec(code, EvFCheck|EvEval, callArgs)
+	}
 	parts := strings.SplitN(objAttr, "/", 2)
 
 	var ref gamedb.DBRef
@@ -71,7 +91,8 @@ func (ctx *EvalContext) CallUFun(objAttr string, callArgs []string) string {
 	}
 
 	// Look up the attribute
-	text := ctx.GetAttrByNameHelper(ref, attrName)
+	raw := ctx.getAttrRaw(ref, attrName)
+	text := StripAttrPrefix(raw)
 	if text == "" {
 		return ""
 	}
@@ -79,8 +100,12 @@ func (ctx *EvalContext) CallUFun(objAttr string, callArgs []string) string {
 	// Evaluate the attribute text with the target object as executor (%!).
 	// In TinyMUSH, u(obj/attr) runs the code "as" obj, so unqualified
 	// attribute references (v(), u(attr), get(me/attr)) resolve on obj.
+	executor := ref
+	if ctx.GameState != nil {
+		executor = ctx.GameState.UFunExecutor(ref, raw)
+	}
 	oldPlayer := ctx.Player
-	ctx.Player = ref
+	ctx.Player = executor
 	result := ctx.Exec(text, EvFCheck|EvEval, callArgs)
 	ctx.Player = oldPlayer
 	return result
@@ -130,30 +155,25 @@ func (ctx *EvalContext) resolveDBRefSimple(s string) gamedb.DBRef {
 		s = s[1:]
 	}
 
-	// Search by name - players first
-	for _, obj := range ctx.DB.Objects {
-		if obj.ObjType() == gamedb.TypePlayer && strings.EqualFold(obj.Name, s) {
-			return obj.DBRef
-		}
-	}
-
-	return gamedb.Nothing
+	// Search by player name or alias
+	return ctx.DB.LookupPlayer(s)
 }
 
 // GetAttrByNameHelper fetches an attribute's text value by name from an object.
 // Walks the parent chain like TinyMUSH's atr_pget.
 func (ctx *EvalContext) GetAttrByNameHelper(ref gamedb.DBRef, attrName string) string {
+	return StripAttrPrefix(ctx.getAttrRaw(ref, attrName))
+}
+
+// getAttrRaw is GetAttrByNameHelper without the owner/flags prefix
+// stripped, or "" if the attribute is missing or can't be read.
+func (ctx *EvalContext) getAttrRaw(ref gamedb.DBRef, attrName string) string {
 	// Resolve the attribute number first
 	attrNum := -1
 	if def, ok := ctx.DB.AttrByName[attrName]; ok {
 		attrNum = def.Number
-	} else {
-		for num, name := range gamedb.WellKnownAttrs {
-			if strings.EqualFold(name, attrName) {
-				attrNum = num
-				break
-			}
-		}
+	} else if num, ok := gamedb.WellKnownAttrNum(attrName); ok {
+		attrNum = num
 	}
 	if attrNum < 0 {
 		return ""
@@ -166,16 +186,14 @@ func (ctx *EvalContext) GetAttrByNameHelper(ref gamedb.DBRef, attrName string) s
 		if !ok {
 			return ""
 		}
-		for _, attr := range obj.Attrs {
-			if attr.Number == attrNum {
-				// Check read permission if GameState is available
-				if ctx.GameState != nil {
-					if !ctx.GameState.CanReadAttrGS(ctx.Player, ref, attrNum, attr.Value) {
-						return ""
-					}
+		if raw, ok := obj.Attr(attrNum); ok {
+			// Check read permission if GameState is available
+			if ctx.GameState != nil {
+				if !ctx.GameState.CanReadAttrGS(ctx.Player, ref, attrNum, raw) {
+					return ""
 				}
-				return StripAttrPrefix(attr.Value)
 			}
+			return raw
 		}
 		if obj.Parent == gamedb.Nothing || obj.Parent == current {
 			return ""
@@ -186,6 +204,8 @@ func (ctx *EvalContext) GetAttrByNameHelper(ref gamedb.DBRef, attrName string) s
 }
 
 // AnsiCode maps a single character code to an ANSI escape sequence.
+// Lowercase color letters select the foreground and uppercase letters the
+// background, as in C TinyMUSH (%xr is red text, %xR is a red background).
 // This is the exported version used by the functions package.
 func AnsiCode(ch byte) string {
 	switch ch {
@@ -199,22 +219,12 @@ func AnsiCode(ch byte) string {
 		return "\033[5m"
 	case 'u', 'U':
 		return "\033[4m"
-	case 'x', 'X':
-		return "\033[30m"
-	case 'r', 'R':
-		return "\033[31m"
-	case 'g', 'G':
-		return "\033[32m"
-	case 'y', 'Y':
-		return "\033[33m"
-	case 'b', 'B':
-		return "\033[34m"
-	case 'm', 'M':
-		return "\033[35m"
-	case 'c', 'C':
-		return "\033[36m"
-	case 'w', 'W':
-		return "\033[37m"
+	}
+	if idx := strings.IndexByte("xrgybmcw", ch); idx >= 0 {
+		return "\033[" + strconv.Itoa(30+idx) + "m"
+	}
+	if idx := strings.IndexByte("XRGYBMCW", ch); idx >= 0 {
+		return "\033[" + strconv.Itoa(40+idx) + "m"
 	}
 	return ""
 }
@@ -0,0 +1,80 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// traceState collects TRACE output for one top-level evaluation. Lines
+// are sent to the traced object's owner when the outermost traced Exec
+// returns.
+type traceState struct {
+	depth     int
+	lines     []traceLine
+	discarded int
+}
+
+// traceLine is one "<obj>} '<in>' -> '<out>'" line of TRACE output.
+type traceLine struct {
+	obj  gamedb.DBRef
+	text string
+}
+
+// tracing reports whether evaluation as the current executor should be
+// traced: the executor has the TRACE flag and EvNoTrace isn't set.
+func (ctx *EvalContext) tracing(evalFlags int) bool {
+	if evalFlags&EvNoTrace != 0 || ctx.DB == nil {
+		return false
+	}
+	obj, ok := ctx.DB.Objects[ctx.Player]
+	return ok && obj.Flags[0]&gamedb.FlagTrace != 0
+}
+
+// traceExec evaluates input like Exec, recording what it turned into if
+// anything changed. Top-down output shows each expression before the
+// ones nested inside it; bottom-up shows the innermost first, in the
+// order they were evaluated.
+func (ctx *EvalContext) traceExec(input string, evalFlags int, cargs []string) string {
+	if ctx.trace == nil {
+		ctx.trace = &traceState{}
+	}
+	t := ctx.trace
+	player := ctx.Player
+	slot := len(t.lines)
+	t.depth++
+	out := ctx.execString(input, evalFlags, cargs)
+	t.depth--
+
+	if out != input {
+		if ctx.TraceLim > 0 && len(t.lines) >= ctx.TraceLim {
+			t.discarded++
+		} else {
+			name := "*NOTHING*"
+			if obj, ok := ctx.DB.Objects[player]; ok {
+				name = obj.Name
+			}
+			line := traceLine{player, fmt.Sprintf("%s(#%d)} '%s' -> '%s'", name, player, input, out)}
+			if ctx.TraceTopDown {
+				t.lines = append(t.lines, traceLine{})
+				copy(t.lines[slot+1:], t.lines[slot:])
+				t.lines[slot] = line
+			} else {
+				t.lines = append(t.lines, line)
+			}
+		}
+	}
+
+	if t.depth == 0 {
+		ctx.trace = nil
+		if ctx.GameState != nil {
+			for _, l := range t.lines {
+				ctx.GameState.TraceOutput(l.obj, l.text)
+			}
+			if t.discarded > 0 {
+				ctx.GameState.TraceOutput(player, fmt.Sprintf("%d lines of trace output discarded.", t.discarded))
+			}
+		}
+	}
+	return out
+}
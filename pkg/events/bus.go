@@ -19,12 +19,16 @@ type Bus struct {
 	mu          sync.RWMutex
 	subscribers map[gamedb.DBRef][]Subscriber
 	global      []Subscriber
+	watchers    map[gamedb.DBRef][]Subscriber // Per-room observers (scene recorder)
+	typed       map[EventType][]Subscriber    // Per-type observers (admin monitors)
 }
 
 // NewBus creates a new event bus.
 func NewBus() *Bus {
 	return &Bus{
 		subscribers: make(map[gamedb.DBRef][]Subscriber),
+		watchers:    make(map[gamedb.DBRef][]Subscriber),
+		typed:       make(map[EventType][]Subscriber),
 	}
 }
 
@@ -58,6 +62,86 @@ func (b *Bus) SubscribeGlobal(sub Subscriber) {
 	b.global = append(b.global, sub)
 }
 
+// WatchRoom registers a subscriber that receives one copy of every event
+// emitted to a room, regardless of who is present to hear it.
+func (b *Bus) WatchRoom(room gamedb.DBRef, sub Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.watchers[room] = append(b.watchers[room], sub)
+}
+
+// UnwatchRoom removes a room watcher.
+func (b *Bus) UnwatchRoom(room gamedb.DBRef, sub Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.watchers[room]
+	for i, s := range subs {
+		if s == sub {
+			b.watchers[room] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(b.watchers[room]) == 0 {
+		delete(b.watchers, room)
+	}
+}
+
+// SubscribeType registers a subscriber that receives every event of type t
+// passed to EmitToTypeSubscribers, whoever it was addressed to.
+func (b *Bus) SubscribeType(t EventType, sub Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.typed[t] = append(b.typed[t], sub)
+}
+
+// UnsubscribeType removes a type subscriber.
+func (b *Bus) UnsubscribeType(t EventType, sub Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	subs := b.typed[t]
+	for i, s := range subs {
+		if s == sub {
+			b.typed[t] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(b.typed[t]) == 0 {
+		delete(b.typed, t)
+	}
+}
+
+// EmitToTypeSubscribers sends an event to the subscribers of its type only.
+// Callers that deliver an event to several players use this to report it
+// once.
+func (b *Bus) EmitToTypeSubscribers(ev Event) {
+	b.mu.RLock()
+	subs := b.typed[ev.Type]
+	b.mu.RUnlock()
+
+	ev.Player = gamedb.Nothing
+	for _, s := range subs {
+		if !s.Closed() {
+			s.Receive(ev)
+		}
+	}
+}
+
+// EmitToWatchers sends an event to the watchers of a room only. Callers that
+// fan an event out to each occupant use this to report it once per room.
+func (b *Bus) EmitToWatchers(room gamedb.DBRef, ev Event) {
+	b.mu.RLock()
+	subs := b.watchers[room]
+	b.mu.RUnlock()
+
+	ev.Player = gamedb.Nothing
+	ev.Room = room
+	for _, s := range subs {
+		if !s.Closed() {
+			s.Receive(ev)
+		}
+	}
+}
+
 // Emit sends an event to the player specified in ev.Player and all global subscribers.
 func (b *Bus) Emit(ev Event) {
 	b.mu.RLock()
@@ -126,6 +210,7 @@ func (b *Bus) EmitToRoom(db *gamedb.Database, room gamedb.DBRef, ev Event) {
 			s.Receive(ev)
 		}
 	}
+	b.EmitToWatchers(room, ev)
 }
 
 // EmitToRoomExcept sends an event to all connected players in a room except one.
@@ -171,6 +256,7 @@ func (b *Bus) EmitToRoomExcept(db *gamedb.Database, room gamedb.DBRef, except ga
 			s.Receive(ev)
 		}
 	}
+	b.EmitToWatchers(room, ev)
 }
 
 // PlayerSubscribers returns the number of subscribers for a player.
@@ -206,4 +292,32 @@ func (b *Bus) Cleanup() {
 		}
 	}
 	b.global = activeGlobal
+
+	for room, subs := range b.watchers {
+		var active []Subscriber
+		for _, s := range subs {
+			if !s.Closed() {
+				active = append(active, s)
+			}
+		}
+		if len(active) == 0 {
+			delete(b.watchers, room)
+		} else {
+			b.watchers[room] = active
+		}
+	}
+
+	for t, subs := range b.typed {
+		var active []Subscriber
+		for _, s := range subs {
+			if !s.Closed() {
+				active = append(active, s)
+			}
+		}
+		if len(active) == 0 {
+			delete(b.typed, t)
+		} else {
+			b.typed[t] = active
+		}
+	}
 }
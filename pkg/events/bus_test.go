@@ -181,6 +181,26 @@ func TestBusCleanup(t *testing.T) {
 	}
 }
 
+func TestBusTypeSubscriber(t *testing.T) {
+	bus := NewBus()
+	monitor := &mockSubscriber{}
+	bus.SubscribeType(EvSuspect, monitor)
+
+	bus.EmitToTypeSubscribers(Event{Type: EvSay, Text: "wrong type"})
+	bus.EmitToTypeSubscribers(Event{Type: EvSuspect, Player: 3, Text: "[Suspect] Bob has connected."})
+
+	events := monitor.Events()
+	if len(events) != 1 || events[0].Type != EvSuspect || events[0].Player != gamedb.Nothing {
+		t.Fatalf("type subscriber got %+v", events)
+	}
+
+	bus.UnsubscribeType(EvSuspect, monitor)
+	bus.EmitToTypeSubscribers(Event{Type: EvSuspect, Text: "after unsubscribe"})
+	if len(monitor.Events()) != 1 {
+		t.Errorf("event delivered after UnsubscribeType")
+	}
+}
+
 func TestEventTypeString(t *testing.T) {
 	tests := []struct {
 		t    EventType
@@ -190,6 +210,7 @@ func TestEventTypeString(t *testing.T) {
 		{EvSay, "say"},
 		{EvChannel, "channel"},
 		{EvMove, "move"},
+		{EvSuspect, "suspect"},
 		{EventType(999), "unknown"},
 	}
 	for _, tt := range tests {
@@ -20,6 +20,8 @@ const (
 	EvWho                         // WHO data
 	EvWhisper                     // Whisper
 	EvEmit                        // @emit / @remit / @oemit
+	EvSuspect                     // Activity by a SUSPECT player, for wizards
+	EvMail                        // New mail, or the mail summary at login
 )
 
 // String returns a human-readable name for the event type.
@@ -53,6 +55,10 @@ func (t EventType) String() string {
 		return "whisper"
 	case EvEmit:
 		return "emit"
+	case EvSuspect:
+		return "suspect"
+	case EvMail:
+		return "mail"
 	default:
 		return "unknown"
 	}
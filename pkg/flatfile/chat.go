@@ -0,0 +1,197 @@
+package flatfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// Penn channel flags.
+const (
+	pennChanObject   = 0x0002
+	pennChanDisabled = 0x0004
+	pennChanQuiet    = 0x0008
+	pennChanAdmin    = 0x0010
+	pennChanWizard   = 0x0020
+	pennChanNoTitles = 0x0100
+)
+
+// pennChanDropped names the Penn channel flags GoTinyMUSH has no match for.
+var pennChanDropped = map[int]string{
+	0x0004: "DISABLED",
+	0x0010: "ADMIN",
+	0x0020: "WIZARD",
+	0x0040: "CANHIDE",
+	0x0080: "OPEN",
+	0x0200: "NONAMES",
+	0x0400: "NOCEMIT",
+	0x0800: "INTERACT",
+}
+
+// Penn channel user flags.
+const (
+	pennChanUserQuiet = 0x1
+	pennChanUserHide  = 0x2
+	pennChanUserGag   = 0x4
+)
+
+// ImportComsys reads a TinyMUSH mod_comsys.db or a PennMUSH 1.8 chatdb,
+// telling them apart by header. mod_comsys.db loads as ParseComsys reads
+// it, with an empty report.
+func ImportComsys(r io.Reader) ([]gamedb.Channel, []gamedb.ChanAlias, *Report, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	head, _ := br.Peek(4096)
+	if isPennHeader(head) {
+		return ParsePennChat(br)
+	}
+	channels, aliases, err := ParseComsys(br)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rep := newReport("TinyMUSH comsys")
+	rep.Objects = len(channels)
+	return channels, aliases, rep, nil
+}
+
+// ParsePennChat reads a PennMUSH 1.8 chatdb: each channel is a record
+// starting at " name", with its members as records starting at
+// "   dbref". Penn players talk on a channel by its name, so every
+// member gets an alias made from the channel's name ("pub" for Public).
+func ParsePennChat(r io.Reader) ([]gamedb.Channel, []gamedb.ChanAlias, *Report, error) {
+	pr := &pennReader{r: bufio.NewReaderSize(r, 64*1024)}
+	rep := newReport("PennMUSH 1.8 chat")
+	var (
+		channels []*gamedb.Channel
+		members  []*gamedb.ChanAlias
+		ch       *gamedb.Channel
+		member   *gamedb.ChanAlias
+		lockType string
+	)
+	for {
+		t, err := pr.next()
+		if err == io.EOF {
+			return nil, nil, nil, fmt.Errorf("chatdb: unexpected EOF at line %d (no end-of-dump marker)", pr.line)
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("chatdb: read error at line %d: %w", pr.line, err)
+		}
+
+		switch {
+		case t.label == "***END OF DUMP***":
+			out := make([]gamedb.Channel, len(channels))
+			for i, c := range channels {
+				out[i] = *c
+			}
+			return out, pennChanAliases(members), rep, nil
+		case t.indent == 1 && t.label == "name":
+			ch = &gamedb.Channel{Name: t.value, Flags: gamedb.ChanPublic | gamedb.ChanLoud, Owner: gamedb.Nothing}
+			channels = append(channels, ch)
+			member = nil
+			rep.Objects++
+		case ch == nil:
+			// Header: +V, savedtime, channel count.
+		case t.indent <= 2:
+			switch t.label {
+			case "description":
+				ch.Description = t.value
+			case "flags":
+				ch.Flags = pennChanFlags(atoi(t.value), rep)
+			case "creator":
+				ch.Owner = pennDBRef(t.value)
+			case "cost":
+				ch.Charge = atoi(t.value)
+			case "lock":
+				lockType = t.value
+			case "key":
+				pennChanLock(ch, lockType, t.value, rep)
+			}
+		case t.label == "dbref":
+			member = &gamedb.ChanAlias{Player: pennDBRef(t.value), Channel: ch.Name, IsListening: true}
+			members = append(members, member)
+		case member != nil:
+			switch t.label {
+			case "flags":
+				flags := atoi(t.value)
+				member.Muted = flags&pennChanUserQuiet != 0
+				member.Gagged = flags&pennChanUserGag != 0
+				if flags&pennChanUserHide != 0 {
+					rep.drop("hidden channel member")
+				}
+			case "title":
+				member.Title = t.value
+			}
+		}
+	}
+}
+
+func pennChanFlags(flags int, rep *Report) int {
+	for bit, name := range pennChanDropped {
+		if flags&bit != 0 {
+			rep.drop("channel flag " + name)
+		}
+	}
+	out := 0
+	if flags&(pennChanAdmin|pennChanWizard|pennChanDisabled) == 0 {
+		out |= gamedb.ChanPublic
+	}
+	if flags&pennChanQuiet == 0 {
+		out |= gamedb.ChanLoud
+	}
+	if flags&pennChanObject != 0 {
+		out |= gamedb.ChanObject
+	}
+	if flags&pennChanNoTitles != 0 {
+		out |= gamedb.ChanNoTitles
+	}
+	return out
+}
+
+// pennChanLock sets the channel lock matching a Penn lock type: join,
+// speak (transmit) or see (receive).
+func pennChanLock(ch *gamedb.Channel, typ, key string, rep *Report) {
+	text := pennLockText(key, rep)
+	switch strings.ToLower(typ) {
+	case "join":
+		ch.JoinLock = text
+	case "speak":
+		ch.TransLock = text
+	case "see":
+		ch.RecvLock = text
+	default:
+		rep.drop("channel lock " + typ)
+	}
+}
+
+// pennChanAliases gives each membership an alias: the first three letters
+// of the channel name, lowercased, or more of it where a player already
+// has that alias.
+func pennChanAliases(members []*gamedb.ChanAlias) []gamedb.ChanAlias {
+	taken := make(map[gamedb.DBRef]map[string]bool)
+	out := make([]gamedb.ChanAlias, 0, len(members))
+	for _, m := range members {
+		if taken[m.Player] == nil {
+			taken[m.Player] = make(map[string]bool)
+		}
+		base := strings.ToLower(strings.Join(strings.Fields(m.Channel), ""))
+		alias := base[:min(3, len(base))]
+		if taken[m.Player][alias] {
+			alias = base
+		}
+		for n := 2; taken[m.Player][alias]; n++ {
+			alias = base + strconv.Itoa(n)
+		}
+		taken[m.Player][alias] = true
+		m.Alias = alias
+		out = append(out, *m)
+	}
+	return out
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
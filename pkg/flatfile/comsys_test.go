@@ -2,7 +2,10 @@ package flatfile
 
 import (
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 )
 
 func TestParseComsysFile(t *testing.T) {
@@ -73,3 +76,67 @@ func TestParseComsysFile(t *testing.T) {
 		}
 	}
 }
+
+const pennChatDump = `+V3
+savedtime "Thu Oct 15 12:00:00 2026"
+channels 2
+ name "Public"
+  description "Open chat"
+  flags 1
+  creator #1
+  cost 0
+  buffer 3
+  users 2
+   dbref #1
+    flags 1
+    title "The Boss"
+   dbref #3
+    flags 4
+    title ""
+ name "Publicity"
+  description "Staff only"
+  flags 16
+  creator #1
+  cost 5
+  buffer 0
+  lock "join"
+  key "=#1|FLAG^WIZARD"
+  lock "mod"
+  key "#1"
+  users 1
+   dbref #1
+    flags 0
+    title ""
+***END OF DUMP***
+`
+
+func TestImportPennChat(t *testing.T) {
+	channels, aliases, rep, err := ImportComsys(strings.NewReader(pennChatDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Format != "PennMUSH 1.8 chat" || len(channels) != 2 || len(aliases) != 3 {
+		t.Fatalf("got %s, %d channels, %d aliases", rep.Format, len(channels), len(aliases))
+	}
+
+	pub, staff := channels[0], channels[1]
+	if pub.Name != "Public" || pub.Description != "Open chat" || pub.Owner != 1 ||
+		pub.Flags != gamedb.ChanPublic|gamedb.ChanLoud {
+		t.Errorf("Public: %+v", pub)
+	}
+	if staff.Flags != gamedb.ChanLoud || staff.Charge != 5 || staff.JoinLock != "=#1|#-1" {
+		t.Errorf("ADMIN channel should not be public and should keep its join lock, failing closed: %+v", staff)
+	}
+
+	want := []gamedb.ChanAlias{
+		{Player: 1, Channel: "Public", Alias: "pub", Title: "The Boss", IsListening: true, Muted: true},
+		{Player: 3, Channel: "Public", Alias: "pub", IsListening: true, Gagged: true},
+		{Player: 1, Channel: "Publicity", Alias: "publicity", IsListening: true},
+	}
+	for i, w := range want {
+		if aliases[i] != w {
+			t.Errorf("alias %d: got %+v, want %+v", i, aliases[i], w)
+		}
+	}
+	checkDropped(t, rep, "channel flag ADMIN", "channel lock mod", "lock key FLAG^")
+}
@@ -0,0 +1,290 @@
+package flatfile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// JSON and CSV exports are for offline tools: diffing two dumps, loading
+// a game into a spreadsheet or a script. JSON is lossless, and ReadJSON
+// turns it back into a Database; CSV has one row per attribute.
+
+// Filter picks the objects an export includes. A nil Filter picks all.
+type Filter struct {
+	Types map[gamedb.ObjectType]bool // empty: every type
+	Owner gamedb.DBRef               // Nothing: every owner
+	From  gamedb.DBRef               // lowest dbref
+	To    gamedb.DBRef               // highest dbref, or Nothing for no limit
+}
+
+// ParseFilter builds a Filter from command-line text: a comma-separated
+// list of types ("ROOM,EXIT"), an owner dbref ("#12") and a dbref range
+// ("100-200", "100-" or "42"). It returns nil when all three are empty.
+func ParseFilter(types, owner, refs string) (*Filter, error) {
+	if types == "" && owner == "" && refs == "" {
+		return nil, nil
+	}
+	f := &Filter{Types: make(map[gamedb.ObjectType]bool), Owner: gamedb.Nothing, To: gamedb.Nothing}
+	for _, name := range strings.Split(types, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		typ, ok := objectTypeNamed(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown object type %q", name)
+		}
+		f.Types[typ] = true
+	}
+	if owner != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(owner, "#"))
+		if err != nil {
+			return nil, fmt.Errorf("bad owner %q", owner)
+		}
+		f.Owner = gamedb.DBRef(n)
+	}
+	if refs != "" {
+		lo, hi, ranged := strings.Cut(refs, "-")
+		from, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(lo), "#"))
+		if err != nil {
+			return nil, fmt.Errorf("bad dbref range %q", refs)
+		}
+		f.From, f.To = gamedb.DBRef(from), gamedb.DBRef(from)
+		if ranged {
+			f.To = gamedb.Nothing
+			if hi = strings.TrimPrefix(strings.TrimSpace(hi), "#"); hi != "" {
+				to, err := strconv.Atoi(hi)
+				if err != nil || to < from {
+					return nil, fmt.Errorf("bad dbref range %q", refs)
+				}
+				f.To = gamedb.DBRef(to)
+			}
+		}
+	}
+	return f, nil
+}
+
+// Match reports whether obj passes the filter.
+func (f *Filter) Match(obj *gamedb.Object) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Types) > 0 && !f.Types[obj.ObjType()] {
+		return false
+	}
+	if f.Owner != gamedb.Nothing && obj.Owner != f.Owner {
+		return false
+	}
+	return obj.DBRef >= f.From && (f.To == gamedb.Nothing || obj.DBRef <= f.To)
+}
+
+func objectTypeNamed(name string) (gamedb.ObjectType, bool) {
+	for t := gamedb.TypeRoom; t <= gamedb.TypeGarbage; t++ {
+		if strings.EqualFold(t.String(), name) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// jsonDB is the JSON export layout.
+type jsonDB struct {
+	NextAttr      int           `json:"next_attr"`
+	RecordPlayers int           `json:"record_players"`
+	AttrDefs      []jsonAttrDef `json:"attr_defs"`
+	Objects       []jsonObject  `json:"objects"`
+}
+
+type jsonAttrDef struct {
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+	Flags  int    `json:"flags,omitempty"`
+}
+
+type jsonObject struct {
+	DBRef      gamedb.DBRef `json:"dbref"`
+	Name       string       `json:"name"`
+	Type       string       `json:"type"`
+	Location   gamedb.DBRef `json:"location"`
+	Contents   gamedb.DBRef `json:"contents"`
+	Exits      gamedb.DBRef `json:"exits"`
+	Link       gamedb.DBRef `json:"link"`
+	Next       gamedb.DBRef `json:"next"`
+	Owner      gamedb.DBRef `json:"owner"`
+	Parent     gamedb.DBRef `json:"parent"`
+	Zone       gamedb.DBRef `json:"zone"`
+	Pennies    int          `json:"pennies"`
+	Flags      [3]int       `json:"flags"`
+	Powers     [2]int       `json:"powers"`
+	LastAccess int64        `json:"last_access,omitempty"`
+	LastMod    int64        `json:"last_mod,omitempty"`
+	Lock       string       `json:"lock,omitempty"`
+	Attrs      []jsonAttr   `json:"attrs,omitempty"`
+}
+
+// jsonAttr is one attribute. Owner and Flags are present only when the
+// value carried an "\x01owner:flags:" prefix.
+type jsonAttr struct {
+	Number int    `json:"number"`
+	Name   string `json:"name,omitempty"`
+	Owner  *int   `json:"owner,omitempty"`
+	Flags  int    `json:"flags,omitempty"`
+	Value  string `json:"value"`
+}
+
+// WriteJSON writes the attribute definitions and the objects f picks, in
+// dbref order.
+func WriteJSON(w io.Writer, db *gamedb.Database, f *Filter) error {
+	out := jsonDB{NextAttr: db.NextAttr, RecordPlayers: db.RecordPlayers, AttrDefs: []jsonAttrDef{}, Objects: []jsonObject{}}
+	nums := make([]int, 0, len(db.AttrNames))
+	for num := range db.AttrNames {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		def := db.AttrNames[num]
+		out.AttrDefs = append(out.AttrDefs, jsonAttrDef{Number: num, Name: def.Name, Flags: def.Flags})
+	}
+
+	for _, ref := range sortedRefs(db) {
+		obj := db.Objects[ref]
+		if !f.Match(obj) {
+			continue
+		}
+		jo := jsonObject{
+			DBRef: obj.DBRef, Name: obj.Name, Type: obj.ObjType().String(),
+			Location: obj.Location, Contents: obj.Contents, Exits: obj.Exits, Link: obj.Link, Next: obj.Next,
+			Owner: obj.Owner, Parent: obj.Parent, Zone: obj.Zone, Pennies: obj.Pennies,
+			Flags: obj.Flags, Powers: obj.Powers, Lock: UnparseLock(db, obj.Lock),
+		}
+		if !obj.LastAccess.IsZero() {
+			jo.LastAccess = obj.LastAccess.Unix()
+		}
+		if !obj.LastMod.IsZero() {
+			jo.LastMod = obj.LastMod.Unix()
+		}
+		for _, a := range obj.Attrs {
+			ja := jsonAttr{Number: a.Number, Name: db.GetAttrName(a.Number), Value: a.Value}
+			if owner, flags, value, ok := splitAttrInfo(a.Value); ok {
+				ja.Owner, ja.Flags, ja.Value = &owner, flags, value
+			}
+			jo.Attrs = append(jo.Attrs, ja)
+		}
+		out.Objects = append(out.Objects, jo)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// ReadJSON reads a WriteJSON export back into a Database. Object types
+// come from the flags; the "type" and attribute "name" fields are only
+// for people reading the file.
+func ReadJSON(r io.Reader) (*gamedb.Database, error) {
+	var in jsonDB
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return nil, fmt.Errorf("read json: %w", err)
+	}
+	db := gamedb.NewDatabase()
+	db.Format = FTinyMUSH
+	db.NextAttr = in.NextAttr
+	db.RecordPlayers = in.RecordPlayers
+	for _, def := range in.AttrDefs {
+		db.AddAttrDef(def.Number, def.Name, def.Flags)
+	}
+	for _, jo := range in.Objects {
+		obj := &gamedb.Object{
+			DBRef: jo.DBRef, Name: jo.Name,
+			Location: jo.Location, Contents: jo.Contents, Exits: jo.Exits, Link: jo.Link, Next: jo.Next,
+			Owner: jo.Owner, Parent: jo.Parent, Zone: jo.Zone, Pennies: jo.Pennies,
+			Flags: jo.Flags, Powers: jo.Powers,
+		}
+		if jo.LastAccess != 0 {
+			obj.LastAccess = time.Unix(jo.LastAccess, 0)
+		}
+		if jo.LastMod != 0 {
+			obj.LastMod = time.Unix(jo.LastMod, 0)
+		}
+		if jo.Lock != "" {
+			if obj.Lock = ParseLock(db, jo.Lock); obj.Lock == nil {
+				return nil, fmt.Errorf("read json: object #%d: bad lock %q", jo.DBRef, jo.Lock)
+			}
+		}
+		for _, ja := range jo.Attrs {
+			value := ja.Value
+			if ja.Owner != nil {
+				value = fmt.Sprintf("\x01%d:%d:%s", *ja.Owner, ja.Flags, value)
+			}
+			obj.Attrs = append(obj.Attrs, gamedb.Attribute{Number: ja.Number, Value: value})
+		}
+		db.Objects[obj.DBRef] = obj
+	}
+	db.Size = len(db.Objects)
+	return db, nil
+}
+
+// csvHeader names WriteCSV's columns.
+var csvHeader = []string{"dbref", "type", "name", "owner", "location", "parent", "zone", "flags", "powers", "attr", "attr_owner", "attr_flags", "value"}
+
+// WriteCSV writes one row per attribute of each object f picks, with the
+// object's fields repeated on every row. An object with no attributes
+// gets one row with the attribute columns empty.
+func WriteCSV(w io.Writer, db *gamedb.Database, f *Filter) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, ref := range sortedRefs(db) {
+		obj := db.Objects[ref]
+		if !f.Match(obj) {
+			continue
+		}
+		row := []string{
+			strconv.Itoa(int(obj.DBRef)), obj.ObjType().String(), obj.Name,
+			strconv.Itoa(int(obj.Owner)), strconv.Itoa(int(obj.Location)),
+			strconv.Itoa(int(obj.Parent)), strconv.Itoa(int(obj.Zone)),
+			fmt.Sprintf("%d %d %d", obj.Flags[0], obj.Flags[1], obj.Flags[2]),
+			fmt.Sprintf("%d %d", obj.Powers[0], obj.Powers[1]),
+		}
+		if len(obj.Attrs) == 0 {
+			cw.Write(append(row, "", "", "", ""))
+			continue
+		}
+		for _, a := range obj.Attrs {
+			name := db.GetAttrName(a.Number)
+			if name == "" {
+				name = strconv.Itoa(a.Number)
+			}
+			owner, flags, value := "", "", a.Value
+			if o, fl, v, ok := splitAttrInfo(a.Value); ok {
+				owner, flags, value = strconv.Itoa(o), strconv.Itoa(fl), v
+			}
+			cw.Write(append(row[:len(row):len(row)], name, owner, flags, value))
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// splitAttrInfo splits an "\x01owner:flags:value" attribute value.
+func splitAttrInfo(raw string) (owner, flags int, value string, ok bool) {
+	if !strings.HasPrefix(raw, "\x01") {
+		return 0, 0, raw, false
+	}
+	parts := strings.SplitN(raw[1:], ":", 3)
+	if len(parts) != 3 {
+		return 0, 0, raw, false
+	}
+	o, err1 := strconv.Atoi(parts[0])
+	f, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return 0, 0, raw, false
+	}
+	return o, f, parts[2], true
+}
@@ -0,0 +1,131 @@
+package flatfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	db, _, err := Import(strings.NewReader(pennDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first bytes.Buffer
+	if err := WriteJSON(&first, db, nil); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadJSON(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	player := back.Objects[1]
+	if player.ObjType() != gamedb.TypePlayer || player.Pennies != 1000 || !player.LastMod.Equal(db.Objects[1].LastMod) {
+		t.Errorf("player %+v", player)
+	}
+	if got := UnparseLock(back, player.Lock); got != "=#1|#-1" {
+		t.Errorf("lock %q", got)
+	}
+	if got := attrValue(player, 256); got != "\x012:2048:Elf" {
+		t.Errorf("RACE %q", got)
+	}
+	if def := back.AttrByName["RACE"]; def == nil || def.Flags != gamedb.AFVisual || back.NextAttr != db.NextAttr {
+		t.Errorf("RACE definition %+v, next attr %d", def, back.NextAttr)
+	}
+
+	var second bytes.Buffer
+	if err := WriteJSON(&second, back, nil); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("second export differs:\n%s\n---\n%s", first.String(), second.String())
+	}
+}
+
+func TestJSONFlatfileRoundTrip(t *testing.T) {
+	f, err := os.Open("../../data/minimal.FLAT")
+	if err != nil {
+		t.Skipf("minimal.FLAT not found: %v", err)
+	}
+	defer f.Close()
+	db, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var js bytes.Buffer
+	if err := WriteJSON(&js, db, nil); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadJSON(&js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want, got bytes.Buffer
+	if err := Write(&want, db); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(&got, back); err != nil {
+		t.Fatal(err)
+	}
+	if want.String() != got.String() {
+		t.Errorf("flatfile written from JSON differs from the original")
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter("room, exit", "#1", "1-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs := map[string]*gamedb.Object{
+		"room #0":   {DBRef: 0, Owner: 1, Flags: [3]int{int(gamedb.TypeRoom)}},
+		"exit #2":   {DBRef: 2, Owner: 1, Flags: [3]int{int(gamedb.TypeExit)}},
+		"exit #3/2": {DBRef: 3, Owner: 2, Flags: [3]int{int(gamedb.TypeExit)}},
+		"thing #4":  {DBRef: 4, Owner: 1, Flags: [3]int{int(gamedb.TypeThing)}},
+	}
+	for name, obj := range objs {
+		if got, want := f.Match(obj), name == "exit #2"; got != want {
+			t.Errorf("%s: match %v, want %v", name, got, want)
+		}
+	}
+
+	if f, _ := ParseFilter("", "", "42"); !f.Match(&gamedb.Object{DBRef: 42}) || f.Match(&gamedb.Object{DBRef: 43}) {
+		t.Errorf("single dbref range")
+	}
+	if f, _ := ParseFilter("", "", ""); f != nil || !f.Match(&gamedb.Object{}) {
+		t.Errorf("empty filter %+v", f)
+	}
+	for _, bad := range [][3]string{{"widget", "", ""}, {"", "me", ""}, {"", "", "9-3"}} {
+		if _, err := ParseFilter(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("ParseFilter%q: no error", bad)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	db, _, err := Import(strings.NewReader(pennDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _ := ParseFilter("player", "", "")
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, db, f); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1+len(db.Objects[1].Attrs) {
+		t.Fatalf("got %d rows: %q", len(rows), rows)
+	}
+	race := rows[len(rows)-1]
+	if race[0] != "1" || race[1] != "PLAYER" || race[9] != "RACE" || race[10] != "2" || race[11] != "2048" || race[12] != "Elf" {
+		t.Errorf("RACE row %q", race)
+	}
+}
@@ -0,0 +1,134 @@
+package flatfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// Report lists what an import from another server's flatfile couldn't
+// carry over, so a game migrating to GoTinyMUSH knows what to fix by hand.
+type Report struct {
+	Format  string         // source format, e.g. "PennMUSH 1.8"
+	Objects int            // objects imported
+	Dropped map[string]int // untranslated feature -> times seen
+}
+
+func newReport(format string) *Report {
+	return &Report{Format: format, Dropped: make(map[string]int)}
+}
+
+// drop records one object's use of a feature that has no GoTinyMUSH
+// equivalent, like "flag AUDITORIUM" or "lock type Zone".
+func (r *Report) drop(what string) {
+	r.Dropped[what]++
+}
+
+// Lines returns one line per untranslated feature, most common first.
+func (r *Report) Lines() []string {
+	keys := make([]string, 0, len(r.Dropped))
+	for k := range r.Dropped {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if r.Dropped[keys[i]] != r.Dropped[keys[j]] {
+			return r.Dropped[keys[i]] > r.Dropped[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = fmt.Sprintf("%6d  %s", r.Dropped[k], k)
+	}
+	return lines
+}
+
+// ImportFile reads a flatfile in any format Import understands.
+func ImportFile(path string) (*gamedb.Database, *Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open flatfile: %w", err)
+	}
+	defer f.Close()
+
+	return Import(f)
+}
+
+// Import reads a TinyMUSH 3, TinyMUX 2 or PennMUSH 1.8 flatfile, telling
+// them apart by their header. TinyMUSH flatfiles load as Parse reads
+// them, with an empty report.
+func Import(r io.Reader) (*gamedb.Database, *Report, error) {
+	br := bufio.NewReaderSize(r, 256*1024)
+	head, _ := br.Peek(4096)
+	switch {
+	case isPennHeader(head):
+		return ParsePenn(br)
+	case bytes.HasPrefix(head, []byte("+X")):
+		return ParseMUX(br)
+	}
+	db, err := Parse(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	rep := newReport("TinyMUSH")
+	rep.Objects = len(db.Objects)
+	return db, rep, nil
+}
+
+// isPennHeader reports whether a flatfile starts like a PennMUSH labeled
+// dump: a +V line followed by "savedtime", "dbversion" or the flag list,
+// where a TinyMUSH 2 +V header is followed by +/-/! records.
+func isPennHeader(head []byte) bool {
+	if !bytes.HasPrefix(head, []byte("+V")) {
+		return false
+	}
+	i := bytes.IndexByte(head, '\n')
+	if i < 0 {
+		return false
+	}
+	next := string(head[i+1:])
+	for _, p := range []string{"savedtime", "dbversion", "+FLAGS LIST"} {
+		if strings.HasPrefix(next, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// flagBit is a GoTinyMUSH flag or power: a word index and a bit in it.
+type flagBit struct {
+	word int
+	bit  int
+}
+
+// nextAttrNum returns the first attribute number above every built-in
+// and defined one, for attributes an import has to add.
+func nextAttrNum(db *gamedb.Database) int {
+	n := db.NextAttr
+	if n < gamedb.A_USER_START {
+		n = gamedb.A_USER_START
+	}
+	for num := range db.AttrNames {
+		if num >= n {
+			n = num + 1
+		}
+	}
+	return n
+}
+
+// sortedRefs returns the database's dbrefs in order, so conversions that
+// hand out attribute numbers do it the same way every run.
+func sortedRefs(db *gamedb.Database) []gamedb.DBRef {
+	refs := make([]gamedb.DBRef, 0, len(db.Objects))
+	for ref := range db.Objects {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+	return refs
+}
@@ -0,0 +1,297 @@
+package flatfile
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const pennDump = `+V74
+savedtime "Thu Oct 15 12:00:00 2026"
++FLAGS LIST
+flagcount 1
+ name "WIZARD"
+  letter "W"
+flagaliascount 0
++POWER LIST
+flagcount 0
+flagaliascount 0
++ATTRIBUTES LIST
+attrcount 1
+ name "RACE"
+  flags "visual"
+  creator #1
+  data ""
+attraliascount 0
+~3
+!0
+name "Room Zero"
+location #-1
+contents #1
+exits #2
+next #-1
+parent #-1
+lockcount 0
+owner #1
+zone #-1
+pennies 0
+type 1
+flags "LINK_OK FLOATING"
+powers ""
+warnings 0
+created 1700000000
+modified 1700000100
+attrcount 1
+ name "DESCRIBE"
+  owner #1
+  flags ""
+  derefs 0
+  value "A plain room.
+Second line, with \"quotes\" and a \\ backslash."
+!1
+name "One"
+location #0
+contents #-1
+exits #0
+next #-1
+parent #-1
+lockcount 2
+ type "Basic"
+  creator #1
+  flags ""
+  derefs 0
+  key "=#1|FLAG^WIZARD"
+ type "Zone"
+  creator #1
+  flags ""
+  derefs 0
+  key "#1"
+owner #1
+zone #-1
+pennies 1000
+type 8
+flags "WIZARD ANSI MISTRUST"
+powers "Builder Cemit"
+warnings 0
+created 1700000000
+modified 1700000200
+attrcount 2
+ name "XYXXY"
+  owner #1
+  flags "no_command wizard"
+  derefs 0
+  value "2:sha512:abc"
+ name "RACE"
+  owner #2
+  flags "visual veiled"
+  derefs 0
+  value "Elf"
+!2
+name "Out;o"
+location #0
+contents #-1
+exits #0
+next #-1
+parent #-1
+lockcount 1
+ type "Use"
+  creator #1
+  flags ""
+  derefs 0
+  key "RACE:Elf&OBJID^#1:1700000000"
+owner #1
+zone #-1
+pennies 0
+type 4
+flags ""
+powers ""
+warnings 0
+created 1700000000
+modified 1700000300
+attrcount 0
+***END OF DUMP***
+`
+
+const muxDump = `+X1031937
++S2
++N257
++A256
+"0:RACE"
+-R1
+!0
+0
+-1
+1
+-1
+-1
+-1
+1
+-1
+0
+770
+0
+0
+0
+>43
+"Limbo"
+>6
+"A grey void."
+<
+!1
+0
+-1
+-1
+-1
+0
+-1
+1
+-1
+19
+65536
+5
+8
+2
+>43
+"Wizard"
+>25
+"150"
+>42
+"#1"
+>200
+"MUX only"
+>256
+"Elf"
+<
+***END OF DUMP***
+`
+
+func attrValue(obj *gamedb.Object, num int) string {
+	for _, a := range obj.Attrs {
+		if a.Number == num {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func checkDropped(t *testing.T, rep *Report, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if rep.Dropped[w] == 0 {
+			t.Errorf("report lacks %q: %v", w, rep.Lines())
+		}
+	}
+}
+
+func TestImportPenn(t *testing.T) {
+	db, rep, err := Import(strings.NewReader(pennDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Format != "PennMUSH 1.8" || rep.Objects != 3 || db.Format != FPenn || db.RecordPlayers != 1 {
+		t.Errorf("got %s, %d objects, format %d, record %d", rep.Format, rep.Objects, db.Format, db.RecordPlayers)
+	}
+
+	room := db.Objects[0]
+	if room.ObjType() != gamedb.TypeRoom || room.Flags[0] != gamedb.FlagLinkOK || room.Flags[1] != gamedb.Flag2Floating {
+		t.Errorf("room flags %x", room.Flags)
+	}
+	if room.Exits != 2 || !room.LastMod.Equal(time.Unix(1700000100, 0)) {
+		t.Errorf("room exits #%d, modified %v", room.Exits, room.LastMod)
+	}
+	if got := attrValue(room, 6); got != "A plain room.\nSecond line, with \"quotes\" and a \\ backslash." {
+		t.Errorf("DESCRIBE became DESC %q", got)
+	}
+
+	player := db.Objects[1]
+	if player.Flags[0] != int(gamedb.TypePlayer)|gamedb.FlagWizard || player.Flags[1] != gamedb.Flag2Ansi {
+		t.Errorf("player flags %x", player.Flags)
+	}
+	if player.Powers != [2]int{0, gamedb.Pow2Builder} || player.Pennies != 1000 {
+		t.Errorf("player powers %x, pennies %d", player.Powers, player.Pennies)
+	}
+	if player.Link != 0 || player.Exits != gamedb.Nothing {
+		t.Errorf("player home #%d, exits #%d; want home #0 from Penn's exits field", player.Link, player.Exits)
+	}
+	if got := UnparseLock(db, player.Lock); got != "=#1|#-1" {
+		t.Errorf("basic lock %q", got)
+	}
+	if got := attrValue(player, 5); got != "\x011:260:2:sha512:abc" {
+		t.Errorf("XYXXY became PASS %q", got)
+	}
+	race := db.AttrByName["RACE"]
+	if race == nil || race.Number != 256 || race.Flags != gamedb.AFVisual || db.NextAttr != 257 {
+		t.Fatalf("RACE definition %+v, next attr %d", race, db.NextAttr)
+	}
+	if got := attrValue(player, 256); got != "\x012:2048:Elf" {
+		t.Errorf("RACE %q", got)
+	}
+
+	exit := db.Objects[2]
+	if exit.Location != 0 || exit.Exits != 0 {
+		t.Errorf("exit goes to #%d from #%d", exit.Location, exit.Exits)
+	}
+	if got := attrValue(exit, 62); got != "RACE:Elf&#1" {
+		t.Errorf("use lock %q", got)
+	}
+
+	checkDropped(t, rep, "flag MISTRUST", "power CEMIT", "lock key FLAG^", "lock type Zone",
+		"attribute flag veiled", "password (PennMUSH hash; reset with @newpassword)")
+}
+
+func TestImportMUX(t *testing.T) {
+	db, rep, err := Import(strings.NewReader(muxDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Format != "TinyMUX 2" || rep.Objects != 2 {
+		t.Errorf("got %s, %d objects", rep.Format, rep.Objects)
+	}
+
+	room := db.Objects[0]
+	if room.Name != "Limbo" || room.Flags[1] != gamedb.Flag2Abode|gamedb.Flag2Ansi {
+		t.Errorf("room %q flags %x", room.Name, room.Flags)
+	}
+	if attrValue(room, 43) != "" || attrValue(room, 6) != "A grey void." {
+		t.Errorf("room attrs %+v", room.Attrs)
+	}
+
+	wiz := db.Objects[1]
+	if wiz.Name != "Wizard" || wiz.Pennies != 150 || wiz.Link != 0 {
+		t.Errorf("player %q, %d pennies, home #%d", wiz.Name, wiz.Pennies, wiz.Link)
+	}
+	if wiz.Flags != [3]int{19, gamedb.Flag2Staff, 0} || wiz.Powers != [2]int{gamedb.PowBoot, 0} {
+		t.Errorf("player flags %x, powers %x", wiz.Flags, wiz.Powers)
+	}
+	if got := UnparseLock(db, wiz.Lock); got != "#1" {
+		t.Errorf("lock %q", got)
+	}
+	def := db.AttrByName["MUX_200"]
+	if def == nil || attrValue(wiz, def.Number) != "MUX only" || attrValue(wiz, 256) != "Elf" {
+		t.Errorf("MUX_200 %+v, attrs %+v", def, wiz.Attrs)
+	}
+	if def != nil && db.NextAttr <= def.Number {
+		t.Errorf("next attr %d not past MUX_200 (#%d)", db.NextAttr, def.Number)
+	}
+
+	checkDropped(t, rep, "flag AUDITORIUM", "flag word 3 (marker flags)", "power word 2 bits 0x2",
+		"built-in attribute 200 (kept as MUX_200)")
+}
+
+func TestImportTinyMUSH(t *testing.T) {
+	f, err := os.Open("../../data/minimal.FLAT")
+	if err != nil {
+		t.Skipf("minimal.FLAT not found: %v", err)
+	}
+	defer f.Close()
+	db, rep, err := Import(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Format != FTinyMUSH || len(rep.Dropped) != 0 || rep.Objects != len(db.Objects) {
+		t.Errorf("format %d, report %s %v", db.Format, rep.Format, rep.Lines())
+	}
+}
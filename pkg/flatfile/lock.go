@@ -0,0 +1,194 @@
+package flatfile
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// Locks in TinyMUSH 3 flatfiles (V_ATRKEY) are stored as the text of the
+// A_LOCK attribute, in the same syntax @lock takes with dbrefs resolved:
+// "#12", "!#12", "SEX:m*", "#1|(#2&!#3)". The C server parses that text
+// whenever it checks the lock, so the writer's output must be something
+// its parse_boolexp reads back to the same tree.
+
+// UnparseLock renders a lock as A_LOCK text. Attribute locks name their
+// attribute where it has a name. Sub-expressions are parenthesized only
+// where precedence or right-to-left grouping needs it, so parsing the
+// text gives back exactly this tree.
+func UnparseLock(db *gamedb.Database, b *gamedb.BoolExp) string {
+	if b == nil {
+		return ""
+	}
+	sub := func(s *gamedb.BoolExp, right bool) string {
+		text := UnparseLock(db, s)
+		if s == nil || (s.Type != gamedb.BoolAnd && s.Type != gamedb.BoolOr) {
+			return text
+		}
+		// '&' binds tighter than '|', and chains group to the right.
+		if (b.Type == gamedb.BoolOr && s.Type == gamedb.BoolAnd) || (b.Type == s.Type && right) {
+			return text
+		}
+		return "(" + text + ")"
+	}
+	attr := func(num int) string {
+		if name := db.GetAttrName(num); name != "" {
+			return name
+		}
+		return strconv.Itoa(num)
+	}
+	switch b.Type {
+	case gamedb.BoolAnd:
+		return sub(b.Sub1, false) + "&" + sub(b.Sub2, true)
+	case gamedb.BoolOr:
+		return sub(b.Sub1, false) + "|" + sub(b.Sub2, true)
+	case gamedb.BoolNot:
+		return "!" + sub(b.Sub1, false)
+	case gamedb.BoolIndir:
+		return "@" + sub(b.Sub1, false)
+	case gamedb.BoolCarry:
+		return "+" + sub(b.Sub1, false)
+	case gamedb.BoolIs:
+		return "=" + sub(b.Sub1, false)
+	case gamedb.BoolOwner:
+		return "$" + sub(b.Sub1, false)
+	case gamedb.BoolConst:
+		return "#" + strconv.Itoa(b.Thing)
+	case gamedb.BoolAttr:
+		return attr(b.Thing) + ":" + b.StrVal
+	case gamedb.BoolEval:
+		return attr(b.Thing) + "/" + b.StrVal
+	}
+	return "#-1"
+}
+
+// lockText is a recursive-descent parser for A_LOCK text, following C
+// TinyMUSH's parse_boolexp: '|' binds loosest, then '&', then the prefix
+// operators, with parentheses for grouping.
+type lockText struct {
+	p    *Parser
+	text string
+	pos  int
+}
+
+// ParseLock parses lock text as UnparseLock writes it, resolving
+// attribute names against db. It returns nil if the text doesn't parse.
+func ParseLock(db *gamedb.Database, text string) *gamedb.BoolExp {
+	return (&Parser{db: db}).parseLockText(text)
+}
+
+// parseLockText parses A_LOCK text into a lock, or returns nil if it
+// doesn't parse.
+func (p *Parser) parseLockText(text string) *gamedb.BoolExp {
+	lt := &lockText{p: p, text: text}
+	b := lt.or()
+	lt.skipSpace()
+	if lt.pos != len(lt.text) {
+		return nil
+	}
+	return b
+}
+
+func (lt *lockText) skipSpace() {
+	for lt.pos < len(lt.text) && lt.text[lt.pos] == ' ' {
+		lt.pos++
+	}
+}
+
+func (lt *lockText) peek() byte {
+	lt.skipSpace()
+	if lt.pos < len(lt.text) {
+		return lt.text[lt.pos]
+	}
+	return 0
+}
+
+func (lt *lockText) or() *gamedb.BoolExp {
+	left := lt.and()
+	if left == nil || lt.peek() != OrToken {
+		return left
+	}
+	lt.pos++
+	right := lt.or()
+	if right == nil {
+		return nil
+	}
+	return &gamedb.BoolExp{Type: gamedb.BoolOr, Sub1: left, Sub2: right}
+}
+
+func (lt *lockText) and() *gamedb.BoolExp {
+	left := lt.factor()
+	if left == nil || lt.peek() != AndToken {
+		return left
+	}
+	lt.pos++
+	right := lt.and()
+	if right == nil {
+		return nil
+	}
+	return &gamedb.BoolExp{Type: gamedb.BoolAnd, Sub1: left, Sub2: right}
+}
+
+func (lt *lockText) factor() *gamedb.BoolExp {
+	prefix := map[byte]gamedb.BoolExpType{
+		NotToken: gamedb.BoolNot, IndirToken: gamedb.BoolIndir, CarryToken: gamedb.BoolCarry,
+		IsToken: gamedb.BoolIs, OwnerToken: gamedb.BoolOwner,
+	}
+	ch := lt.peek()
+	if typ, ok := prefix[ch]; ok {
+		lt.pos++
+		sub := lt.factor()
+		if sub == nil {
+			return nil
+		}
+		return &gamedb.BoolExp{Type: typ, Sub1: sub}
+	}
+	if ch == '(' {
+		lt.pos++
+		b := lt.or()
+		if b == nil || lt.peek() != ')' {
+			return nil
+		}
+		lt.pos++
+		return b
+	}
+	return lt.leaf()
+}
+
+// leaf parses a dbref ("#12" or "12"), an attribute lock ("NAME:pattern")
+// or an evaluation lock ("NAME/value"). The pattern runs to the next
+// '&', '|' or ')'.
+func (lt *lockText) leaf() *gamedb.BoolExp {
+	start := lt.pos
+	for lt.pos < len(lt.text) && !strings.ContainsRune("&|)", rune(lt.text[lt.pos])) {
+		lt.pos++
+	}
+	atom := strings.TrimSpace(lt.text[start:lt.pos])
+	if atom == "" {
+		return nil
+	}
+	if i := strings.IndexAny(atom, ":/"); i > 0 {
+		num := lt.attrNum(atom[:i])
+		if num < 0 {
+			return nil
+		}
+		b := &gamedb.BoolExp{Type: gamedb.BoolAttr, Thing: num, StrVal: atom[i+1:]}
+		if atom[i] == '/' {
+			b.Type = gamedb.BoolEval
+		}
+		return b
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(atom, "#")); err == nil {
+		return &gamedb.BoolExp{Type: gamedb.BoolConst, Thing: n}
+	}
+	return nil
+}
+
+// attrNum resolves an attribute lock's attribute, given by name or number.
+func (lt *lockText) attrNum(name string) int {
+	if n, err := strconv.Atoi(name); err == nil {
+		return n
+	}
+	return lt.p.resolveAttrName(name)
+}
@@ -0,0 +1,301 @@
+package flatfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// TinyMUX and PennMUSH mail databases keep one record per mailbox copy
+// of a message, with the read, cleared, urgent and folder state packed
+// into one "read" word. The folder number is in bits 8-11 in both.
+
+// Mail read-word bits shared by TinyMUX and PennMUSH.
+const (
+	foreignMailRead    = 0x0001
+	foreignMailCleared = 0x0002
+	foreignMailUrgent  = 0x0004
+	foreignMailFolder  = 0x0f00
+)
+
+// TinyMUX-only read-word bits.
+const (
+	muxMailSafe    = 0x0010
+	muxMailForward = 0x0080
+	muxMailReply   = 0x4000
+)
+
+// pennMailForward is PennMUSH's forwarded-message bit.
+const pennMailForward = 0x0080
+
+// Penn mail database header flags.
+const (
+	pennMailSubject    = 0x1
+	pennMailAliases    = 0x2
+	pennMailSenderTime = 0x8
+)
+
+// ImportMail reads a TinyMUX (+V header) or PennMUSH (+<flags> header)
+// mail database into per-player mailboxes, each message numbered from 1
+// in the order the file lists them.
+func ImportMail(r io.Reader) (map[gamedb.DBRef]map[int]*gamedb.MailMessage, []gamedb.MailAlias, *Report, error) {
+	br := bufio.NewReaderSize(r, 256*1024)
+	head, _ := br.Peek(2)
+	if bytes.HasPrefix(head, []byte("+V")) {
+		return ParseMUXMail(br)
+	}
+	return ParsePennMail(br)
+}
+
+// mailboxes collects imported messages, numbering each player's from 1.
+type mailboxes map[gamedb.DBRef]map[int]*gamedb.MailMessage
+
+func (mb mailboxes) add(player gamedb.DBRef, msg *gamedb.MailMessage) {
+	if mb[player] == nil {
+		mb[player] = make(map[int]*gamedb.MailMessage)
+	}
+	msg.ID = len(mb[player]) + 1
+	mb[player][msg.ID] = msg
+}
+
+// ParseMUXMail reads a TinyMUX 2 mail.db. Message bodies are stored once
+// after the mailbox records and shared by number. @malias lists after the
+// bodies are reported, not imported.
+func ParseMUXMail(r io.Reader) (map[gamedb.DBRef]map[int]*gamedb.MailMessage, []gamedb.MailAlias, *Report, error) {
+	p := &Parser{reader: bufio.NewReaderSize(r, 256*1024)}
+	rep := newReport("TinyMUX 2 mail")
+	header, err := p.readLine()
+	if err != nil || !strings.HasPrefix(header, "+V") {
+		return nil, nil, nil, fmt.Errorf("mail: expected +V header, got %q", header)
+	}
+	if v, _ := strconv.Atoi(header[2:]); v >= 5 {
+		p.readLine() // mail_top
+	}
+
+	type muxMail struct {
+		to     gamedb.DBRef
+		number int
+		msg    *gamedb.MailMessage
+	}
+	var records []muxMail
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("mail: unexpected EOF at line %d", p.line)
+		}
+		if strings.HasPrefix(line, "***") {
+			break
+		}
+		to, _ := strconv.Atoi(strings.TrimSpace(line))
+		from, _ := p.readInt()
+		number, _ := p.readInt()
+		sent, _ := p.readString(true)
+		subject, _ := p.readString(true)
+		tolist, _ := p.readString(true)
+		read, err := p.readInt()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("mail: message for #%d at line %d: %w", to, p.line, err)
+		}
+		msg := &gamedb.MailMessage{
+			From:    gamedb.DBRef(from),
+			To:      mailRecipients(tolist, gamedb.DBRef(to)),
+			Subject: subject,
+			Time:    mailTime(sent),
+			Flags:   foreignMailFlags(read),
+			Folder:  (read & foreignMailFolder) >> 8,
+		}
+		if read&muxMailSafe != 0 {
+			msg.Flags |= gamedb.MailSafe
+		}
+		if read&muxMailForward != 0 {
+			msg.Flags |= gamedb.MailForward
+		}
+		if read&muxMailReply != 0 {
+			msg.Flags |= gamedb.MailReply
+		}
+		records = append(records, muxMail{gamedb.DBRef(to), number, msg})
+	}
+
+	bodies := make(map[int]string)
+	for {
+		line, err := p.readLine()
+		if strings.HasPrefix(line, "+++") || (err != nil && line == "") {
+			break
+		}
+		number, _ := strconv.Atoi(strings.TrimSpace(line))
+		if bodies[number], err = p.readString(true); err != nil {
+			return nil, nil, nil, fmt.Errorf("mail: body %d at line %d: %w", number, p.line, err)
+		}
+	}
+	if rest, _ := p.readLine(); strings.Contains(rest, "MALIAS") {
+		rep.drop("@malias lists")
+	}
+
+	mb := make(mailboxes)
+	for _, rec := range records {
+		body, ok := bodies[rec.number]
+		if !ok {
+			rep.drop("message with no body")
+			continue
+		}
+		rec.msg.Body = body
+		mb.add(rec.to, rec.msg)
+		rep.Objects++
+	}
+	return mb, nil, rep, nil
+}
+
+// ParsePennMail reads a PennMUSH 1.8 maildb, including its @malias lists.
+func ParsePennMail(r io.Reader) (map[gamedb.DBRef]map[int]*gamedb.MailMessage, []gamedb.MailAlias, *Report, error) {
+	p := &Parser{reader: bufio.NewReaderSize(r, 256*1024)}
+	rep := newReport("PennMUSH 1.8 mail")
+	header, err := p.readLine()
+	if err != nil || !strings.HasPrefix(header, "+") {
+		return nil, nil, nil, fmt.Errorf("mail: expected +<flags> header, got %q", header)
+	}
+	flags, err := strconv.Atoi(header[1:])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("mail: bad header %q", header)
+	}
+
+	var aliases []gamedb.MailAlias
+	if flags&pennMailAliases != 0 {
+		if aliases, err = readPennMailAliases(p); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	count, err := p.readInt()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("mail: message count: %w", err)
+	}
+	mb := make(mailboxes)
+	for i := 0; i < count; i++ {
+		to, _ := p.readInt()
+		from, _ := p.readInt()
+		if flags&pennMailSenderTime != 0 {
+			p.readLine() // sender's creation time, for spotting a recycled dbref
+		}
+		subject := ""
+		if flags&pennMailSubject != 0 {
+			subject, _ = p.readString(true)
+		}
+		body, _ := p.readString(true)
+		sent, _ := p.readString(true)
+		read, err := p.readInt()
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("mail: message %d at line %d: %w", i+1, p.line, err)
+		}
+		msg := &gamedb.MailMessage{
+			From:    gamedb.DBRef(from),
+			To:      []gamedb.DBRef{gamedb.DBRef(to)},
+			Subject: subject,
+			Body:    body,
+			Time:    mailTime(sent),
+			Flags:   foreignMailFlags(read),
+			Folder:  (read & foreignMailFolder) >> 8,
+		}
+		if read&pennMailForward != 0 {
+			msg.Flags |= gamedb.MailForward
+		}
+		mb.add(gamedb.DBRef(to), msg)
+		rep.Objects++
+	}
+	return mb, aliases, rep, nil
+}
+
+// readPennMailAliases reads the "*** Begin MALIAS ***" section. Penn
+// alias names lack the '*' GoTinyMUSH keeps on them.
+func readPennMailAliases(p *Parser) ([]gamedb.MailAlias, error) {
+	if line, _ := p.readLine(); !strings.Contains(line, "MALIAS") {
+		return nil, fmt.Errorf("mail: expected MALIAS section, got %q", line)
+	}
+	n, err := p.readInt()
+	if err != nil {
+		return nil, fmt.Errorf("mail: alias count: %w", err)
+	}
+	aliases := make([]gamedb.MailAlias, 0, n)
+	for i := 0; i < n; i++ {
+		line, _ := p.readLine()
+		var owner, nflags, mflags int
+		if _, err := fmt.Sscanf(line, "%d %d %d", &owner, &nflags, &mflags); err != nil {
+			return nil, fmt.Errorf("mail: alias %d at line %d: %q", i+1, p.line, line)
+		}
+		size, _ := p.readInt()
+		name, _ := p.readString(true)
+		desc, _ := p.readString(true)
+		ma := gamedb.MailAlias{ID: i + 1, Name: "*" + name, Owner: gamedb.DBRef(owner), Desc: desc}
+		for j := 0; j < size; j++ {
+			member, _ := p.readInt()
+			ma.Members = append(ma.Members, gamedb.DBRef(member))
+		}
+		aliases = append(aliases, ma)
+	}
+	return aliases, nil
+}
+
+func foreignMailFlags(read int) int {
+	flags := 0
+	if read&foreignMailRead != 0 {
+		flags |= gamedb.MailIsRead
+	}
+	if read&foreignMailCleared != 0 {
+		flags |= gamedb.MailCleared
+	}
+	if read&foreignMailUrgent != 0 {
+		flags |= gamedb.MailUrgent
+	}
+	return flags
+}
+
+// mailRecipients parses a TinyMUX to-list ("#12 #15" or "12 15"),
+// falling back to the mailbox owner when it names nobody.
+func mailRecipients(tolist string, owner gamedb.DBRef) []gamedb.DBRef {
+	var to []gamedb.DBRef
+	for _, tok := range strings.Fields(tolist) {
+		if n, err := strconv.Atoi(strings.TrimPrefix(tok, "#")); err == nil {
+			to = append(to, gamedb.DBRef(n))
+		}
+	}
+	if len(to) == 0 {
+		to = []gamedb.DBRef{owner}
+	}
+	return to
+}
+
+// mailTime parses a message time, written as ctime text or Unix seconds.
+func mailTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0)
+	}
+	if t, err := time.ParseInLocation(time.ANSIC, s, time.Local); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// mailFolderWord matches one "<n>:<NAME>:<n>" entry of the MAILFOLDERS
+// attribute, as TinyMUX and PennMUSH write it.
+var mailFolderWord = regexp.MustCompile(`^(\d+:[^:\s]+):\d+$`)
+
+// convertMailFolders rewrites a TinyMUX or PennMUSH MAILFOLDERS value in
+// GoTinyMUSH's "<n>:<NAME>" form, keeping any attribute-info prefix.
+func convertMailFolders(raw string) string {
+	text := raw
+	if strings.HasPrefix(raw, "\x01") {
+		text = stripAttrInfoPrefix(raw)
+	}
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = mailFolderWord.ReplaceAllString(w, "$1")
+	}
+	return raw[:len(raw)-len(text)] + strings.Join(words, " ")
+}
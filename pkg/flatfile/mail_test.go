@@ -0,0 +1,127 @@
+package flatfile
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const muxMailDump = `+V5
+2
+5
+1
+0
+"Mon Oct 12 09:30:00 2026"
+"Hello"
+"5 7"
+513
+7
+1
+0
+"Mon Oct 12 09:30:00 2026"
+"Hello"
+"5 7"
+4
+5
+1
+1
+"Tue Oct 13 10:00:00 2026"
+"Orphan"
+"5"
+0
+*** END OF DUMP ***
+0
+"First line.\nSecond line."
++++ END OF DUMP +++
+*** Begin MALIAS ***
+0
+`
+
+const pennMailDump = `+15
+*** Begin MALIAS ***
+1
+1 0 0
+2
+"staff"
+"The staff"
+1
+4
+2
+3
+1
+1700000000
+"Welcome"
+"Hi there, with \"quotes\"."
+"Thu Oct 15 12:00:00 2026"
+1
+3
+1
+1700000000
+"Later"
+"Second message."
+"1760529600"
+770
+***END OF DUMP***
+`
+
+func TestImportMUXMail(t *testing.T) {
+	msgs, aliases, rep, err := ImportMail(strings.NewReader(muxMailDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Format != "TinyMUX 2 mail" || rep.Objects != 2 || len(aliases) != 0 {
+		t.Errorf("got %s, %d messages, %d aliases", rep.Format, rep.Objects, len(aliases))
+	}
+	m := msgs[5][1]
+	if m == nil || m.Subject != "Hello" || m.Body != "First line.\nSecond line." || m.From != 1 {
+		t.Fatalf("#5's message: %+v", m)
+	}
+	if len(m.To) != 2 || m.To[1] != 7 || m.Folder != 2 || m.Flags != gamedb.MailIsRead {
+		t.Errorf("#5's message: to %v, folder %d, flags %x", m.To, m.Folder, m.Flags)
+	}
+	if !m.Time.Equal(time.Date(2026, 10, 12, 9, 30, 0, 0, time.Local)) {
+		t.Errorf("time %v", m.Time)
+	}
+	if m7 := msgs[7][1]; m7 == nil || m7.Flags != gamedb.MailUrgent || m7.Folder != 0 {
+		t.Errorf("#7's copy: %+v", m7)
+	}
+	checkDropped(t, rep, "message with no body", "@malias lists")
+}
+
+func TestImportPennMail(t *testing.T) {
+	msgs, aliases, rep, err := ImportMail(strings.NewReader(pennMailDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Format != "PennMUSH 1.8 mail" || rep.Objects != 2 {
+		t.Errorf("got %s, %d messages", rep.Format, rep.Objects)
+	}
+	if len(aliases) != 1 || aliases[0].Name != "*staff" || aliases[0].Desc != "The staff" ||
+		aliases[0].Owner != 1 || len(aliases[0].Members) != 2 || aliases[0].Members[1] != 4 {
+		t.Errorf("aliases %+v", aliases)
+	}
+	first, second := msgs[3][1], msgs[3][2]
+	if first == nil || second == nil {
+		t.Fatalf("#3's mailbox: %+v", msgs[3])
+	}
+	if first.Subject != "Welcome" || first.Body != `Hi there, with "quotes".` || first.Flags != gamedb.MailIsRead || first.Folder != 0 {
+		t.Errorf("first message %+v", first)
+	}
+	if second.Folder != 3 || second.Flags != gamedb.MailCleared || !second.Time.Equal(time.Unix(1760529600, 0)) {
+		t.Errorf("second message %+v", second)
+	}
+}
+
+func TestConvertMailFolders(t *testing.T) {
+	for raw, want := range map[string]string{
+		"0:INBOX:1 2:FRIENDS:0": "0:INBOX 2:FRIENDS",
+		"\x011:0:3:OLD:0":       "\x011:0:3:OLD",
+		"1:ALREADY":             "1:ALREADY",
+	} {
+		if got := convertMailFolders(raw); got != want {
+			t.Errorf("%q became %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package flatfile
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// TinyMUX 2 shares TinyMUSH's flatfile layout (+X header instead of +T),
+// its first flag word and most of its powers. The second flag word was
+// renumbered, so it goes through muxFlags2.
+
+// muxFlags2 maps TinyMUX's second flag word. Entries with no GoTinyMUSH
+// bit are reported and dropped.
+var muxFlags2 = []struct {
+	bit  int
+	name string
+	to   int
+}{
+	{0x00000001, "KEY", gamedb.Flag2Key},
+	{0x00000002, "ABODE", gamedb.Flag2Abode},
+	{0x00000004, "FLOATING", gamedb.Flag2Floating},
+	{0x00000008, "UNFINDABLE", gamedb.Flag2Unfindable},
+	{0x00000010, "PARENT_OK", gamedb.Flag2ParentOK},
+	{0x00000020, "LIGHT", gamedb.Flag2Light},
+	{0x00000040, "HAS_LISTEN", gamedb.Flag2HasListen},
+	{0x00000080, "HAS_FWDLIST", gamedb.Flag2HasFwd},
+	{0x00000100, "AUDITORIUM", 0},
+	{0x00000200, "ANSI", gamedb.Flag2Ansi},
+	{0x00000400, "HEAD", 0},
+	{0x00000800, "FIXED", gamedb.Flag2Fixed},
+	{0x00001000, "UNINSPECTED", 0},
+	{0x00002000, "NO_COMMAND", 0},
+	{0x00004000, "KEEPALIVE", 0},
+	{0x00008000, "NOBLEED", gamedb.Flag2NoBLeed},
+	{0x00010000, "STAFF", gamedb.Flag2Staff},
+	{0x00020000, "HAS_DAILY", gamedb.Flag2HasDaily},
+	{0x00040000, "GAGGED", gamedb.Flag2Gagged},
+	{0x01000000, "VACATION", 0},
+	{0x04000000, "HTML", gamedb.Flag2HTML},
+	{0x08000000, "BLIND", gamedb.Flag2Blind},
+	{0x10000000, "SUSPECT", gamedb.Flag2Suspect},
+	{0x40000000, "CONNECTED", gamedb.Flag2Connected},
+	{0x80000000, "SLAVE", gamedb.Flag2Slave},
+}
+
+// muxPowers1Dropped are first-word TinyMUX power bits that mean
+// something else in GoTinyMUSH.
+const muxPowers1Dropped = 0x00070000
+
+// muxLastSharedAttr is the last built-in attribute number TinyMUX and
+// TinyMUSH agree on. Higher built-ins are kept as user attributes named
+// MUX_<n>.
+const muxLastSharedAttr = 125
+
+// ParseMUX reads a TinyMUX 2 flatfile and converts its flags, powers and
+// built-in attributes to GoTinyMUSH's numbering.
+func ParseMUX(r io.Reader) (*gamedb.Database, *Report, error) {
+	db, err := Parse(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if db.Format != FMux {
+		return nil, nil, fmt.Errorf("not a TinyMUX flatfile (no +X header)")
+	}
+	rep := newReport("TinyMUX 2")
+	renamed := make(map[int]int) // MUX built-in number -> user attr number
+	for _, ref := range sortedRefs(db) {
+		convertMUXObject(db, db.Objects[ref], renamed, rep)
+	}
+	db.NextAttr = nextAttrNum(db)
+	rep.Objects = len(db.Objects)
+	return db, rep, nil
+}
+
+func convertMUXObject(db *gamedb.Database, obj *gamedb.Object, renamed map[int]int, rep *Report) {
+	f2 := 0
+	for _, m := range muxFlags2 {
+		if obj.Flags[1]&m.bit == 0 {
+			continue
+		}
+		if m.to == 0 {
+			rep.drop("flag " + m.name)
+		}
+		f2 |= m.to
+	}
+	if unknown := obj.Flags[1] &^ muxKnownFlags2(); unknown != 0 {
+		rep.drop(fmt.Sprintf("flag word 2 bits 0x%x", unknown))
+	}
+	obj.Flags[1] = f2
+	if obj.Flags[2] != 0 {
+		rep.drop("flag word 3 (marker flags)")
+		obj.Flags[2] = 0
+	}
+	if obj.Powers[0]&muxPowers1Dropped != 0 {
+		rep.drop(fmt.Sprintf("power bits 0x%x", obj.Powers[0]&muxPowers1Dropped))
+		obj.Powers[0] &^= muxPowers1Dropped
+	}
+	if obj.Powers[1]&^gamedb.Pow2Builder != 0 {
+		rep.drop(fmt.Sprintf("power word 2 bits 0x%x", obj.Powers[1]&^gamedb.Pow2Builder))
+		obj.Powers[1] &= gamedb.Pow2Builder
+	}
+
+	attrs := obj.Attrs[:0]
+	for _, a := range obj.Attrs {
+		switch {
+		case a.Number == 43 && obj.Name == "": // A_NAME, when names live in attributes
+			obj.Name = stripAttrInfoPrefix(a.Value)
+			continue
+		case a.Number == 25 && db.Flags&VAtrMoney != 0: // A_MONEY
+			obj.Pennies, _ = strconv.Atoi(stripAttrInfoPrefix(a.Value))
+			continue
+		case a.Number == 96: // A_MAILFOLDERS
+			a.Value = convertMailFolders(a.Value)
+		case a.Number < gamedb.A_USER_START && (a.Number > muxLastSharedAttr || gamedb.WellKnownAttrs[a.Number] == ""):
+			num, ok := renamed[a.Number]
+			if !ok {
+				num = nextAttrNum(db)
+				db.AddAttrDef(num, fmt.Sprintf("MUX_%d", a.Number), 0)
+				renamed[a.Number] = num
+			}
+			rep.drop(fmt.Sprintf("built-in attribute %d (kept as MUX_%d)", a.Number, a.Number))
+			a.Number = num
+		}
+		attrs = append(attrs, a)
+	}
+	obj.Attrs = attrs
+}
+
+// muxKnownFlags2 returns the second-word bits muxFlags2 names.
+func muxKnownFlags2() int {
+	known := 0
+	for _, m := range muxFlags2 {
+		known |= m.bit
+	}
+	return known
+}
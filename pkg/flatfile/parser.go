@@ -41,6 +41,7 @@ const (
 	FMuck     = 4
 	FMux      = 5
 	FTinyMUSH = 6
+	FPenn     = 7
 )
 
 // Lock expression token characters
@@ -180,6 +181,7 @@ func (p *Parser) parseHeader() error {
 		p.format = FMux
 		p.db.Format = FMux
 		p.applyVersionFlags(val)
+		p.readName = (val & VAtrName) == 0 // names may live in A_NAME
 		p.version = val & VMask
 		p.db.Version = p.version
 		p.read3Flags = (val & V3Flags) != 0
@@ -474,12 +476,14 @@ func (p *Parser) parseObject() error {
 				if attr.Number == 42 { // A_LOCK
 					lockText := stripAttrInfoPrefix(attr.Value)
 					if lockText != "" {
-						// Parse inline using a simple boolexp text parser.
 						// The value is stored as text like "#0" or "=SECURED_TYPE:*crystal*"
-						obj.Lock = parseBoolExpText(lockText)
+						obj.Lock = p.parseLockText(lockText)
+					}
+					// Remove A_LOCK from attrs since it's now in obj.Lock,
+					// unless it didn't parse: then keep the text as it was.
+					if lockText == "" || obj.Lock != nil {
+						obj.Attrs = append(obj.Attrs[:i], obj.Attrs[i+1:]...)
 					}
-					// Remove A_LOCK from attrs since it's now in obj.Lock
-					obj.Attrs = append(obj.Attrs[:i], obj.Attrs[i+1:]...)
 					break
 				}
 			}
@@ -792,124 +796,6 @@ func stripAttrInfoPrefix(raw string) string {
 	return raw
 }
 
-// parseBoolExpText parses a simple boolean expression string (from an A_LOCK
-// attribute value) into a BoolExp tree. This handles common cases like "#0",
-// "=ATTR:pattern", "+ATTR:pattern", "#1|#2", "@#123", etc.
-func parseBoolExpText(text string) *gamedb.BoolExp {
-	text = strings.TrimSpace(text)
-	if text == "" {
-		return nil
-	}
-
-	// Handle OR
-	if idx := strings.IndexByte(text, '|'); idx >= 0 {
-		left := parseBoolExpText(text[:idx])
-		right := parseBoolExpText(text[idx+1:])
-		if left != nil && right != nil {
-			return &gamedb.BoolExp{Type: gamedb.BoolOr, Sub1: left, Sub2: right}
-		}
-		if left != nil {
-			return left
-		}
-		return right
-	}
-
-	// Handle AND
-	if idx := strings.IndexByte(text, '&'); idx >= 0 {
-		left := parseBoolExpText(text[:idx])
-		right := parseBoolExpText(text[idx+1:])
-		if left != nil && right != nil {
-			return &gamedb.BoolExp{Type: gamedb.BoolAnd, Sub1: left, Sub2: right}
-		}
-		if left != nil {
-			return left
-		}
-		return right
-	}
-
-	// Handle prefix operators
-	switch text[0] {
-	case '!':
-		sub := parseBoolExpText(text[1:])
-		if sub != nil {
-			return &gamedb.BoolExp{Type: gamedb.BoolNot, Sub1: sub}
-		}
-		return nil
-	case '+':
-		sub := parseBoolExpText(text[1:])
-		if sub != nil {
-			return &gamedb.BoolExp{Type: gamedb.BoolCarry, Sub1: sub}
-		}
-		return nil
-	case '=':
-		sub := parseBoolExpText(text[1:])
-		if sub != nil {
-			return &gamedb.BoolExp{Type: gamedb.BoolIs, Sub1: sub}
-		}
-		return nil
-	case '$':
-		sub := parseBoolExpText(text[1:])
-		if sub != nil {
-			return &gamedb.BoolExp{Type: gamedb.BoolOwner, Sub1: sub}
-		}
-		return nil
-	case '@':
-		sub := parseBoolExpText(text[1:])
-		if sub != nil {
-			return &gamedb.BoolExp{Type: gamedb.BoolIndir, Sub1: sub}
-		}
-		return nil
-	}
-
-	// Handle attribute lock: "NAME:pattern" or "NUM:pattern"
-	if colonIdx := strings.IndexByte(text, ':'); colonIdx >= 0 {
-		name := text[:colonIdx]
-		pattern := text[colonIdx+1:]
-		attrNum := -1
-		if n, err := strconv.Atoi(name); err == nil && n >= 0 {
-			attrNum = n
-		} else {
-			upper := strings.ToUpper(name)
-			for num, n := range gamedb.WellKnownAttrs {
-				if n == upper {
-					attrNum = num
-					break
-				}
-			}
-		}
-		if attrNum >= 0 {
-			return &gamedb.BoolExp{Type: gamedb.BoolAttr, Thing: attrNum, StrVal: pattern}
-		}
-	}
-
-	// Handle eval lock: "NAME/expression"
-	if slashIdx := strings.IndexByte(text, '/'); slashIdx >= 0 {
-		name := text[:slashIdx]
-		expr := text[slashIdx+1:]
-		attrNum := -1
-		if n, err := strconv.Atoi(name); err == nil && n >= 0 {
-			attrNum = n
-		}
-		if attrNum >= 0 {
-			return &gamedb.BoolExp{Type: gamedb.BoolEval, Thing: attrNum, StrVal: expr}
-		}
-	}
-
-	// Handle #dbref
-	if text[0] == '#' {
-		if n, err := strconv.Atoi(text[1:]); err == nil {
-			return &gamedb.BoolExp{Type: gamedb.BoolConst, Thing: n}
-		}
-	}
-
-	// Plain number = dbref
-	if n, err := strconv.Atoi(text); err == nil {
-		return &gamedb.BoolExp{Type: gamedb.BoolConst, Thing: n}
-	}
-
-	return nil
-}
-
 // --- Low-level I/O helpers ---
 
 func (p *Parser) peekByte() (byte, error) {
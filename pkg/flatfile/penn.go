@@ -0,0 +1,556 @@
+package flatfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// PennMUSH 1.8 writes a labeled flatfile. Every field is a "label value"
+// line; strings are quoted with backslash escapes and may run over several
+// lines; dbrefs are written "#12". Locks and attributes are counted lists
+// of records, each starting on a line indented one space, with its fields
+// indented two:
+//
+//	!12
+//	name "Vault"
+//	location #0
+//	...
+//	lockcount 1
+//	 type "Basic"
+//	  creator #1
+//	  key "#1|FLAG^WIZARD"
+//	...
+//	attrcount 1
+//	 name "DESCRIBE"
+//	  owner #1
+//	  flags "visual"
+//	  value "A big steel door."
+
+// Penn object types.
+var pennTypes = map[int]gamedb.ObjectType{
+	0x1:  gamedb.TypeRoom,
+	0x2:  gamedb.TypeThing,
+	0x4:  gamedb.TypeExit,
+	0x8:  gamedb.TypePlayer,
+	0x10: gamedb.TypeGarbage,
+}
+
+// pennFlags maps Penn flag names to GoTinyMUSH flags. Penn names that
+// aren't here are reported and dropped.
+var pennFlags = map[string]flagBit{
+	"TRANSPARENT": {0, gamedb.FlagSeeThru},
+	"WIZARD":      {0, gamedb.FlagWizard},
+	"LINK_OK":     {0, gamedb.FlagLinkOK},
+	"DARK":        {0, gamedb.FlagDark},
+	"JUMP_OK":     {0, gamedb.FlagJumpOK},
+	"STICKY":      {0, gamedb.FlagSticky},
+	"DESTROY_OK":  {0, gamedb.FlagDestroyOK},
+	"HAVEN":       {0, gamedb.FlagHaven},
+	"QUIET":       {0, gamedb.FlagQuiet},
+	"HALT":        {0, gamedb.FlagHalt},
+	"DEBUG":       {0, gamedb.FlagTrace},
+	"TRACE":       {0, gamedb.FlagTrace},
+	"GOING":       {0, gamedb.FlagGoing},
+	"MONITOR":     {0, gamedb.FlagMonitor},
+	"MYOPIC":      {0, gamedb.FlagMyopic},
+	"PUPPET":      {0, gamedb.FlagPuppet},
+	"CHOWN_OK":    {0, gamedb.FlagChownOK},
+	"ENTER_OK":    {0, gamedb.FlagEnterOK},
+	"VISUAL":      {0, gamedb.FlagVisual},
+	"OPAQUE":      {0, gamedb.FlagOpaque},
+	"VERBOSE":     {0, gamedb.FlagVerbose},
+	"TRUST":       {0, gamedb.FlagInherit},
+	"INHERIT":     {0, gamedb.FlagInherit},
+	"NOSPOOF":     {0, gamedb.FlagNoSpoof},
+	"SAFE":        {0, gamedb.FlagSafe},
+	"ROYALTY":     {0, gamedb.FlagRoyalty},
+	"AUDIBLE":     {0, gamedb.FlagHearThru},
+	"TERSE":       {0, gamedb.FlagTerse},
+	"ABODE":       {1, gamedb.Flag2Abode},
+	"FLOATING":    {1, gamedb.Flag2Floating},
+	"UNFINDABLE":  {1, gamedb.Flag2Unfindable},
+	"LIGHT":       {1, gamedb.Flag2Light},
+	"GOING_TWICE": {1, gamedb.Flag2GoingTwice},
+	"CONNECTED":   {1, gamedb.Flag2Connected},
+	"SUSPECT":     {1, gamedb.Flag2Suspect},
+	"ANSI":        {1, gamedb.Flag2Ansi},
+	"COLOR":       {1, gamedb.Flag2Ansi},
+	"GAGGED":      {1, gamedb.Flag2Gagged},
+	"FIXED":       {1, gamedb.Flag2Fixed},
+}
+
+// pennPowers maps Penn power names to GoTinyMUSH powers.
+var pennPowers = map[string]flagBit{
+	"ANNOUNCE":       {0, gamedb.PowAnnounce},
+	"BOOT":           {0, gamedb.PowBoot},
+	"CHAT_PRIVS":     {0, gamedb.PowCommAll},
+	"GUEST":          {0, gamedb.PowGuest},
+	"HALT":           {0, gamedb.PowHalt},
+	"HIDE":           {0, gamedb.PowHide},
+	"IDLE":           {0, gamedb.PowIdle},
+	"LONG_FINGERS":   {0, gamedb.PowLongfingers},
+	"NO_PAY":         {0, gamedb.PowFreeMoney},
+	"NO_QUOTA":       {0, gamedb.PowFreeQuota},
+	"POLL":           {0, gamedb.PowPoll},
+	"QUOTAS":         {0, gamedb.PowChgQuotas},
+	"SEARCH":         {0, gamedb.PowSearch},
+	"SEE_ALL":        {0, gamedb.PowExamAll},
+	"SEE_QUEUE":      {0, gamedb.PowSeeQueue},
+	"TPORT_ANYTHING": {0, gamedb.PowTelUnrst},
+	"TPORT_ANYWHERE": {0, gamedb.PowTelAnywhr},
+	"UNKILLABLE":     {0, gamedb.PowUnkillable},
+	"BUILDER":        {1, gamedb.Pow2Builder},
+	"LINK_ANYWHERE":  {1, gamedb.Pow2LinkToAny},
+	"OPEN_ANYWHERE":  {1, gamedb.Pow2OpenAnyLoc},
+	"SQL_OK":         {1, gamedb.Pow2UseSQL},
+}
+
+// pennAttrFlags maps Penn attribute flag names to AF* flags.
+var pennAttrFlags = map[string]int{
+	"no_command":  gamedb.AFNoProg,
+	"no_inherit":  gamedb.AFPrivate,
+	"no_clone":    gamedb.AFNoClone,
+	"wizard":      gamedb.AFWizard,
+	"mortal_dark": gamedb.AFMDark,
+	"hidden":      gamedb.AFMDark,
+	"locked":      gamedb.AFLock,
+	"internal":    gamedb.AFInternal,
+	"visual":      gamedb.AFVisual,
+	"regexp":      gamedb.AFRegexp,
+	"case":        gamedb.AFCase,
+	"noname":      gamedb.AFNoName,
+	"debug":       gamedb.AFTrace,
+}
+
+// pennAttrNames renames Penn built-in attributes whose TinyMUSH
+// counterparts have other names. The rest match by name or become user
+// attributes.
+var pennAttrNames = map[string]string{
+	"DESCRIBE":  "DESC",
+	"ADESCRIBE": "ADESC",
+	"ODESCRIBE": "ODESC",
+	"IDESCRIBE": "IDESC",
+	"XYXXY":     "PASS",
+}
+
+// pennLocks maps Penn lock types to the attribute holding the matching
+// TinyMUSH lock. The Basic lock is the object's own lock.
+var pennLocks = map[string]int{
+	"enter":    59,  // A_LENTER
+	"leave":    60,  // A_LLEAVE
+	"page":     61,  // A_LPAGE
+	"use":      62,  // A_LUSE
+	"give":     63,  // A_LGIVE
+	"teleport": 85,  // A_LTPORT
+	"drop":     86,  // A_LDROP
+	"receive":  87,  // A_LRECEIVE
+	"link":     93,  // A_LLINK
+	"parent":   98,  // A_LPARENT
+	"control":  99,  // A_LCONTROL
+	"open":     144, // A_LOPEN
+	"speech":   209, // A_LSPEECH
+	"chown":    217, // A_LCHOWN
+}
+
+var (
+	// pennObjID matches an object id ("#12:1234567", or as OBJID^#12:1234567),
+	// which names the same object as its dbref.
+	pennObjID = regexp.MustCompile(`(?:OBJID\^)?#(\d+):\d+`)
+	// pennKeyword matches the keyed lock atoms TinyMUSH has no match for:
+	// FLAG^WIZARD, TYPE^PLAYER, CHANNEL^Public and the like.
+	pennKeyword = regexp.MustCompile(`([A-Za-z_]+)\^[^&|()]*`)
+)
+
+// pennToken is one line of a Penn flatfile. Lines that aren't label/value
+// pairs ("!12", "~400", "+FLAGS LIST", the end marker) are all label.
+type pennToken struct {
+	indent int
+	label  string
+	value  string
+}
+
+// pennRecord holds the fields of one lock or attribute.
+type pennRecord map[string]string
+
+type pennObject struct {
+	ref    int
+	fields map[string]string
+	locks  []pennRecord
+	attrs  []pennRecord
+}
+
+type pennReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+// ParsePenn reads a PennMUSH 1.8 flatfile. Flags, powers, attributes and
+// locks go through the mapping tables above; whatever has no GoTinyMUSH
+// equivalent is listed in the report.
+func ParsePenn(r io.Reader) (*gamedb.Database, *Report, error) {
+	pr := &pennReader{r: bufio.NewReaderSize(r, 256*1024)}
+	var (
+		objs    []*pennObject
+		defs    []pennRecord
+		obj     *pennObject
+		list    *[]pennRecord
+		section string
+		size    int
+	)
+	for {
+		t, err := pr.next()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("unexpected EOF at line %d (no end-of-dump marker)", pr.line)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read error at line %d: %w", pr.line, err)
+		}
+
+		switch {
+		case t.label == "***END OF DUMP***":
+			return convertPenn(objs, defs, size)
+		case strings.HasPrefix(t.label, "!"):
+			ref, err := strconv.Atoi(t.label[1:])
+			if err != nil {
+				return nil, nil, fmt.Errorf("bad object header %q at line %d", t.label, pr.line)
+			}
+			obj = &pennObject{ref: ref, fields: make(map[string]string)}
+			objs = append(objs, obj)
+			list = nil
+		case strings.HasPrefix(t.label, "~"):
+			size, _ = strconv.Atoi(t.label[1:])
+		case strings.HasPrefix(t.label, "+"):
+			section, list = t.label, nil
+		case t.indent == 0:
+			list = nil
+			switch {
+			case obj != nil:
+				obj.fields[t.label] = t.value
+				if t.label == "lockcount" {
+					list = &obj.locks
+				} else if t.label == "attrcount" {
+					list = &obj.attrs
+				}
+			case section == "+ATTRIBUTES LIST" && t.label == "attrcount":
+				list = &defs
+			}
+		case list == nil:
+			// Flag and power definitions, aliases: nothing to import.
+		case t.indent == 1 || len(*list) == 0:
+			*list = append(*list, pennRecord{t.label: t.value})
+		default:
+			(*list)[len(*list)-1][t.label] = t.value
+		}
+	}
+}
+
+// pennImport converts parsed Penn objects into a Database.
+type pennImport struct {
+	db       *gamedb.Database
+	rep      *Report
+	p        *Parser        // resolves attribute names in lock keys
+	defFlags map[string]int // flags from +ATTRIBUTES LIST, by name
+	nextAttr int
+}
+
+func convertPenn(objs []*pennObject, defs []pennRecord, size int) (*gamedb.Database, *Report, error) {
+	pi := &pennImport{
+		db:       gamedb.NewDatabase(),
+		rep:      newReport("PennMUSH 1.8"),
+		defFlags: make(map[string]int),
+		nextAttr: gamedb.A_USER_START,
+	}
+	pi.p = &Parser{db: pi.db}
+	pi.db.Format = FPenn
+	pi.db.Size = size
+	for _, d := range defs {
+		pi.defFlags[strings.ToUpper(d["name"])] = pi.attrFlags(d["flags"])
+	}
+
+	players := 0
+	for _, po := range objs {
+		obj, err := pi.object(po)
+		if err != nil {
+			return nil, nil, err
+		}
+		if obj.ObjType() == gamedb.TypePlayer {
+			players++
+		}
+		pi.db.Objects[obj.DBRef] = obj
+	}
+	// Lock keys name attributes, so they're converted once every
+	// attribute has a number.
+	for _, po := range objs {
+		pi.locks(pi.db.Objects[gamedb.DBRef(po.ref)], po.locks)
+	}
+
+	pi.db.NextAttr = pi.nextAttr
+	pi.db.RecordPlayers = players
+	pi.rep.Objects = len(objs)
+	return pi.db, pi.rep, nil
+}
+
+func (pi *pennImport) object(po *pennObject) (*gamedb.Object, error) {
+	f := po.fields
+	typeNum, _ := strconv.Atoi(f["type"])
+	typ, ok := pennTypes[typeNum]
+	if !ok {
+		return nil, fmt.Errorf("object #%d: unknown PennMUSH type %q", po.ref, f["type"])
+	}
+	obj := &gamedb.Object{
+		DBRef:    gamedb.DBRef(po.ref),
+		Name:     f["name"],
+		Location: pennDBRef(f["location"]),
+		Zone:     pennDBRef(f["zone"]),
+		Contents: pennDBRef(f["contents"]),
+		Exits:    pennDBRef(f["exits"]),
+		Link:     gamedb.Nothing,
+		Next:     pennDBRef(f["next"]),
+		Owner:    pennDBRef(f["owner"]),
+		Parent:   pennDBRef(f["parent"]),
+	}
+	obj.Pennies, _ = strconv.Atoi(f["pennies"])
+	// Penn keeps a thing's or player's home in its exits field.
+	if typ == gamedb.TypeThing || typ == gamedb.TypePlayer {
+		obj.Link, obj.Exits = obj.Exits, gamedb.Nothing
+	}
+	if mod, _ := strconv.ParseInt(f["modified"], 10, 64); mod > 0 {
+		obj.LastMod = time.Unix(mod, 0)
+		obj.LastAccess = obj.LastMod
+	}
+
+	obj.Flags[0] = int(typ)
+	for _, name := range strings.Fields(strings.ToUpper(f["flags"])) {
+		if fb, ok := pennFlags[name]; ok {
+			obj.Flags[fb.word] |= fb.bit
+		} else {
+			pi.rep.drop("flag " + name)
+		}
+	}
+	for _, name := range strings.Fields(strings.ToUpper(f["powers"])) {
+		if fb, ok := pennPowers[name]; ok {
+			obj.Powers[fb.word] |= fb.bit
+		} else {
+			pi.rep.drop("power " + name)
+		}
+	}
+	if w := f["warnings"]; w != "" && w != "0" {
+		pi.rep.drop("@warnings")
+	}
+
+	for _, rec := range po.attrs {
+		pi.attr(obj, rec)
+	}
+	return obj, nil
+}
+
+// attr adds a Penn attribute to obj, carrying its owner and flags in the
+// "\x01owner:flags:" prefix when they differ from the defaults.
+func (pi *pennImport) attr(obj *gamedb.Object, rec pennRecord) {
+	name := strings.ToUpper(rec["name"])
+	if to, ok := pennAttrNames[name]; ok {
+		name = to
+	}
+	num := pi.attrNum(name)
+	if num == 5 { // A_PASS
+		pi.rep.drop("password (PennMUSH hash; reset with @newpassword)")
+	}
+	value := rec["value"]
+	if num == 96 { // A_MAILFOLDERS
+		value = convertMailFolders(value)
+	}
+	owner := pennDBRef(rec["owner"])
+	flags := pi.attrFlags(rec["flags"])
+	if (owner != gamedb.Nothing && owner != obj.Owner) || flags != 0 {
+		if owner == gamedb.Nothing {
+			owner = obj.Owner
+		}
+		value = fmt.Sprintf("\x01%d:%d:%s", owner, flags, value)
+	}
+	obj.Attrs = append(obj.Attrs, gamedb.Attribute{Number: num, Value: value})
+}
+
+// attrNum returns the number of a built-in attribute, or defines a user
+// attribute the first time a name is seen.
+func (pi *pennImport) attrNum(name string) int {
+	if num := pi.p.resolveAttrName(name); num >= 0 {
+		return num
+	}
+	num := pi.nextAttr
+	pi.nextAttr++
+	pi.db.AddAttrDef(num, name, pi.defFlags[name])
+	return num
+}
+
+func (pi *pennImport) attrFlags(names string) int {
+	flags := 0
+	for _, name := range strings.Fields(strings.ToLower(names)) {
+		if af, ok := pennAttrFlags[name]; ok {
+			flags |= af
+		} else {
+			pi.rep.drop("attribute flag " + name)
+		}
+	}
+	return flags
+}
+
+// locks sets the Basic lock as the object's lock and stores the others
+// in their lock attributes.
+func (pi *pennImport) locks(obj *gamedb.Object, recs []pennRecord) {
+	for _, rec := range recs {
+		typ := rec["type"]
+		if strings.EqualFold(typ, "Basic") {
+			obj.Lock = pi.lockKey(rec["key"])
+			continue
+		}
+		num, ok := pennLocks[strings.ToLower(typ)]
+		if !ok {
+			pi.rep.drop("lock type " + typ)
+			continue
+		}
+		if b := pi.lockKey(rec["key"]); b != nil {
+			obj.Attrs = append(obj.Attrs, gamedb.Attribute{Number: num, Value: UnparseLock(pi.db, b)})
+		}
+	}
+}
+
+// lockKey converts a Penn lock key, failing closed on text that doesn't
+// parse.
+func (pi *pennImport) lockKey(key string) *gamedb.BoolExp {
+	text := pennLockText(key, pi.rep)
+	if text == "" {
+		return nil
+	}
+	b := pi.p.parseLockText(text)
+	if b == nil {
+		pi.rep.drop("lock that doesn't parse")
+		return &gamedb.BoolExp{Type: gamedb.BoolConst, Thing: -1}
+	}
+	return b
+}
+
+// pennLockText rewrites a Penn lock key in TinyMUSH lock syntax, or ""
+// for a key that passes everyone. Atoms TinyMUSH can't express fail
+// closed: they become #-1, which nothing passes.
+func pennLockText(key string, rep *Report) string {
+	key = strings.TrimSpace(key)
+	if key == "" || key == "#TRUE" {
+		return ""
+	}
+	key = pennObjID.ReplaceAllString(key, "#$1")
+	key = strings.ReplaceAll(key, "#FALSE", "#-1")
+	key = strings.ReplaceAll(key, "#TRUE", "!#-1")
+	return pennKeyword.ReplaceAllStringFunc(key, func(atom string) string {
+		rep.drop("lock key " + strings.ToUpper(atom[:strings.IndexByte(atom, '^')+1]))
+		return "#-1"
+	})
+}
+
+// pennDBRef parses a "#12" field.
+func pennDBRef(s string) gamedb.DBRef {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "#"))
+	if err != nil {
+		return gamedb.Nothing
+	}
+	return gamedb.DBRef(n)
+}
+
+// next reads the next line's token.
+func (pr *pennReader) next() (pennToken, error) {
+	var t pennToken
+	for {
+		b, err := pr.r.ReadByte()
+		if err != nil {
+			return t, err
+		}
+		if b == ' ' {
+			t.indent++
+			continue
+		}
+		if b == '\n' {
+			pr.line++
+			t.indent = 0
+			continue
+		}
+		if b != '\r' {
+			pr.r.UnreadByte()
+			break
+		}
+	}
+
+	if b, _ := pr.r.Peek(1); len(b) > 0 && strings.IndexByte("!~+*", b[0]) >= 0 {
+		line, err := pr.readLine()
+		t.label = line
+		return t, err
+	}
+	var label strings.Builder
+	for {
+		b, err := pr.r.ReadByte()
+		if err != nil {
+			return t, err
+		}
+		if b == '\n' {
+			pr.line++
+			t.label = strings.TrimRight(label.String(), "\r")
+			return t, nil
+		}
+		if b == ' ' {
+			break
+		}
+		label.WriteByte(b)
+	}
+	t.label = label.String()
+
+	var err error
+	if b, _ := pr.r.Peek(1); len(b) > 0 && b[0] == '"' {
+		t.value, err = pr.readQuoted()
+		if err == nil {
+			_, err = pr.readLine()
+		}
+	} else {
+		t.value, err = pr.readLine()
+	}
+	return t, err
+}
+
+// readLine reads the rest of the line. A last line without a newline is
+// still a line.
+func (pr *pennReader) readLine() (string, error) {
+	line, err := pr.r.ReadString('\n')
+	pr.line++
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// readQuoted reads a quoted string, where a backslash makes the next
+// character literal.
+func (pr *pennReader) readQuoted() (string, error) {
+	pr.r.ReadByte() // opening quote
+	var buf strings.Builder
+	for {
+		b, err := pr.r.ReadByte()
+		if err != nil {
+			return buf.String(), err
+		}
+		switch b {
+		case '"':
+			return buf.String(), nil
+		case '\\':
+			if b, err = pr.r.ReadByte(); err != nil {
+				return buf.String(), err
+			}
+		case '\n':
+			pr.line++
+		}
+		buf.WriteByte(b)
+	}
+}
@@ -0,0 +1,155 @@
+package flatfile
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// roundTrip writes db in layout v, parses the result, and checks that
+// writing the parsed database again gives the same bytes.
+func roundTrip(t *testing.T, db *gamedb.Database, v Version) *gamedb.Database {
+	t.Helper()
+	var first bytes.Buffer
+	if err := WriteVersion(&first, db, v); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	back, err := Parse(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatalf("parse written flatfile: %v\n%s", err, first.String())
+	}
+	var second bytes.Buffer
+	if err := WriteVersion(&second, back, v); err != nil {
+		t.Fatalf("write again: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("save -> parse -> save changed the flatfile:\n%s\n---\n%s", first.String(), second.String())
+	}
+	return back
+}
+
+// compareDBs reports every object, attribute definition or header field
+// that differs between two databases.
+func compareDBs(t *testing.T, want, got *gamedb.Database, timestamps bool) {
+	t.Helper()
+	if got.NextAttr != want.NextAttr || got.RecordPlayers < want.RecordPlayers {
+		t.Errorf("header: next attr %d, record %d; want %d, %d", got.NextAttr, got.RecordPlayers, want.NextAttr, want.RecordPlayers)
+	}
+	if !reflect.DeepEqual(got.AttrNames, want.AttrNames) {
+		t.Errorf("attr defs: got %v, want %v", got.AttrNames, want.AttrNames)
+	}
+	if len(got.Objects) != len(want.Objects) {
+		t.Errorf("got %d objects, want %d", len(got.Objects), len(want.Objects))
+	}
+	for ref, w := range want.Objects {
+		g, ok := got.Objects[ref]
+		if !ok {
+			t.Errorf("#%d missing", ref)
+			continue
+		}
+		wo, gotObj := *w, *g
+		if len(wo.Attrs) == 0 && len(gotObj.Attrs) == 0 {
+			wo.Attrs, gotObj.Attrs = nil, nil
+		}
+		if !timestamps {
+			wo.LastAccess, wo.LastMod, gotObj.LastAccess, gotObj.LastMod = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+		}
+		if !reflect.DeepEqual(gotObj, wo) {
+			t.Errorf("#%d differs:\n got %+v\nwant %+v", ref, gotObj, wo)
+		}
+	}
+}
+
+func TestRoundTripMinimal(t *testing.T) {
+	f, err := os.Open("../../data/minimal.FLAT")
+	if err != nil {
+		t.Skipf("minimal.FLAT not found: %v", err)
+	}
+	defer f.Close()
+	db, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareDBs(t, db, roundTrip(t, db, TinyMUSH31), true)
+	compareDBs(t, db, roundTrip(t, db, TinyMUSH30), false)
+}
+
+func TestRoundTripFidelity(t *testing.T) {
+	db := gamedb.NewDatabase()
+	db.NextAttr = 258
+	db.RecordPlayers = 7
+	db.AddAttrDef(256, "FINGER_INFO", int(gamedb.AFVisual))
+	db.AddAttrDef(257, "CLAN", 0)
+	stamp := time.Unix(1739577600, 0)
+	obj := func(ref gamedb.DBRef, name string, typ gamedb.ObjectType, lock *gamedb.BoolExp, attrs ...gamedb.Attribute) {
+		db.Objects[ref] = &gamedb.Object{
+			DBRef: ref, Name: name, Location: 0, Zone: gamedb.Nothing, Contents: gamedb.Nothing,
+			Exits: gamedb.Nothing, Link: gamedb.Nothing, Next: gamedb.Nothing, Owner: 1, Parent: gamedb.Nothing,
+			Flags: [3]int{int(typ), 0x10, 0x4}, Powers: [2]int{0x100, 0x2},
+			LastAccess: stamp, LastMod: stamp.Add(time.Hour), Attrs: attrs, Lock: lock,
+		}
+	}
+	dbref := func(n int) *gamedb.BoolExp { return &gamedb.BoolExp{Type: gamedb.BoolConst, Thing: n} }
+	op := func(typ gamedb.BoolExpType, a, b *gamedb.BoolExp) *gamedb.BoolExp {
+		return &gamedb.BoolExp{Type: typ, Sub1: a, Sub2: b}
+	}
+
+	obj(0, "Room Zero", gamedb.TypeRoom, nil,
+		gamedb.Attribute{Number: 6, Value: "Line one.\nLine two, with \"quotes\", a \\ and \x1b[1mbold\x1b[0m."})
+	obj(1, "Wizard", gamedb.TypePlayer, dbref(1),
+		gamedb.Attribute{Number: 256, Value: "\x012:4096:Owned by #2 and set hidden"},
+		gamedb.Attribute{Number: 257, Value: "Tremere"})
+	obj(2, "Vault;v", gamedb.TypeThing,
+		op(gamedb.BoolOr, op(gamedb.BoolAnd, dbref(1), &gamedb.BoolExp{Type: gamedb.BoolNot, Sub1: dbref(3)}),
+			op(gamedb.BoolOr, &gamedb.BoolExp{Type: gamedb.BoolAttr, Thing: 257, StrVal: "Trem*"}, dbref(4))))
+	obj(3, "Gate", gamedb.TypeExit,
+		op(gamedb.BoolAnd, &gamedb.BoolExp{Type: gamedb.BoolNot, Sub1: op(gamedb.BoolOr, dbref(1), dbref(2))},
+			&gamedb.BoolExp{Type: gamedb.BoolIs, Sub1: &gamedb.BoolExp{Type: gamedb.BoolEval, Thing: 7, StrVal: "1"}}))
+	obj(4, "Old Thing", gamedb.TypeThing, &gamedb.BoolExp{Type: gamedb.BoolCarry, Sub1: dbref(2)})
+	db.Objects[4].LastAccess, db.Objects[4].LastMod = time.Unix(0, 0), time.Unix(0, 0)
+
+	compareDBs(t, db, roundTrip(t, db, TinyMUSH31), true)
+	compareDBs(t, db, roundTrip(t, db, TinyMUSH30), false)
+
+	var out bytes.Buffer
+	Write(&out, db)
+	for _, want := range []string{
+		"+T8338177\n",                     // 3.1 header flags
+		"+A256\n\"2048:FINGER_INFO\"\n",   // quoted attribute definition
+		"-R7\n",                           // recorded peak, not the player count
+		">42\n\"#1&!#3|CLAN:Trem*|#4\"\n", // attribute locks by name
+		">42\n\"!(#1|#2)&=SEX/1\"\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("flatfile lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLockTextFromC(t *testing.T) {
+	p := &Parser{db: gamedb.NewDatabase()}
+	p.db.AddAttrDef(300, "RACE", 0)
+	for text, want := range map[string]string{
+		"#12":            "#12",
+		"!#12":           "!#12",
+		"#1 | #2 & #3":   "#1|#2&#3",
+		"(#1|#2)&#3":     "(#1|#2)&#3",
+		"SEX:m*":         "SEX:m*",
+		"race:elf|$#5":   "RACE:elf|$#5",
+		"@#7":            "@#7",
+		"=#8&+#9":        "=#8&+#9",
+		"!(RACE:orc|#1)": "!(RACE:orc|#1)",
+		"NOSUCHATTR:x":   "",
+		"#1&":            "",
+		"(#1":            "",
+	} {
+		if got := UnparseLock(p.db, p.parseLockText(text)); got != want {
+			t.Errorf("%q parsed and unparsed to %q, want %q", text, got, want)
+		}
+	}
+}
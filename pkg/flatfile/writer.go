@@ -11,17 +11,43 @@ import (
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 )
 
-// Write writes the database to the given writer in TinyMUSH 3.0 flatfile format.
+// Version is a TinyMUSH flatfile layout the writer can produce.
+type Version int
+
+const (
+	// TinyMUSH30 is the TinyMUSH 3.0 layout: no object timestamps.
+	TinyMUSH30 Version = iota
+	// TinyMUSH31 is the layout TinyMUSH 3.1 through 3.3 read and write:
+	// 3.0 plus access/modify timestamps, and the typed-quota and
+	// visual-attribute flags those servers set in the header.
+	TinyMUSH31
+)
+
+// headerFlags returns the version flags written in the +T header.
+func (v Version) headerFlags() int {
+	flags := VZone | VLink | VAtrName | VAtrKey | VParent | VXFlags | V3Flags | VPowers | VQuoted
+	if v >= TinyMUSH31 {
+		flags |= VTQuotas | VTimestamps | VVisualAttrs
+	}
+	return flags
+}
+
+// Write writes the database to the given writer in TinyMUSH 3.1 flatfile format.
 func Write(w io.Writer, db *gamedb.Database) error {
-	wr := &writer{w: w}
+	return WriteVersion(w, db, TinyMUSH31)
+}
+
+// WriteVersion writes the database in the given flatfile layout. Reading
+// the output back with Parse gives the same objects, attribute
+// definitions and locks.
+func WriteVersion(w io.Writer, db *gamedb.Database, v Version) error {
+	wr := &writer{w: w, db: db, version: v}
 
-	// Version header: TinyMUSH 3.0 format with all standard flags
-	versionFlags := VZone | VLink | VAtrName | VAtrKey | VParent | VXFlags | V3Flags | VPowers | VQuoted | VTimestamps
-	version := 1 | versionFlags // version 1 with flags
-	wr.writef("+T%d\n", version)
+	// Version header: format version 1 with the layout's flags
+	wr.writef("+T%d\n", 1|v.headerFlags())
 
 	// Size
-	size := 0
+	size := db.Size
 	for ref := range db.Objects {
 		if int(ref) >= size {
 			size = int(ref) + 1
@@ -40,17 +66,20 @@ func Write(w io.Writer, db *gamedb.Database) error {
 	sort.Ints(attrNums)
 	for _, num := range attrNums {
 		def := db.AttrNames[num]
-		wr.writef("+A%d\n%d:%s\n", num, def.Flags, def.Name)
+		wr.writef("+A%d\n%s\n", num, quoteString(fmt.Sprintf("%d:%s", def.Flags, def.Name)))
 	}
 
-	// Record players count
-	playerCount := 0
+	// Record players: the recorded peak, or at least the players there are
+	record := 0
 	for _, obj := range db.Objects {
 		if obj.ObjType() == gamedb.TypePlayer && !obj.IsGoing() {
-			playerCount++
+			record++
 		}
 	}
-	wr.writef("-R%d\n", playerCount)
+	if db.RecordPlayers > record {
+		record = db.RecordPlayers
+	}
+	wr.writef("-R%d\n", record)
 
 	// Objects (sorted by dbref for consistency)
 	var refs []int
@@ -72,8 +101,13 @@ func Write(w io.Writer, db *gamedb.Database) error {
 	return wr.err
 }
 
-// Save writes the database to a file path.
+// Save writes the database to a file path in TinyMUSH 3.1 format.
 func Save(path string, db *gamedb.Database) error {
+	return SaveVersion(path, db, TinyMUSH31)
+}
+
+// SaveVersion writes the database to a file path in the given layout.
+func SaveVersion(path string, db *gamedb.Database, v Version) error {
 	// Write to temp file first, then rename for atomicity
 	tmpPath := path + ".tmp"
 	f, err := os.Create(tmpPath)
@@ -81,7 +115,7 @@ func Save(path string, db *gamedb.Database) error {
 		return fmt.Errorf("create temp file: %w", err)
 	}
 
-	if err := Write(f, db); err != nil {
+	if err := WriteVersion(f, db, v); err != nil {
 		f.Close()
 		os.Remove(tmpPath)
 		return err
@@ -105,8 +139,10 @@ func Save(path string, db *gamedb.Database) error {
 }
 
 type writer struct {
-	w   io.Writer
-	err error
+	w       io.Writer
+	db      *gamedb.Database
+	version Version
+	err     error
 }
 
 func (wr *writer) writef(format string, args ...interface{}) {
@@ -159,29 +195,31 @@ func (wr *writer) writeObject(obj *gamedb.Object) error {
 	wr.writef("%d\n", obj.Powers[0])
 	wr.writef("%d\n", obj.Powers[1])
 
-	// Timestamps
-	access := obj.LastAccess.Unix()
-	mod := obj.LastMod.Unix()
-	if access <= 0 {
-		access = time.Now().Unix()
-	}
-	if mod <= 0 {
-		mod = time.Now().Unix()
+	// Timestamps (3.1 on). Objects that never had them get the time of
+	// the dump, as the C server does.
+	if wr.version >= TinyMUSH31 {
+		access, mod := time.Now().Unix(), time.Now().Unix()
+		if !obj.LastAccess.IsZero() {
+			access = obj.LastAccess.Unix()
+		}
+		if !obj.LastMod.IsZero() {
+			mod = obj.LastMod.Unix()
+		}
+		wr.writef("%d\n", access)
+		wr.writef("%d\n", mod)
 	}
-	wr.writef("%d\n", access)
-	wr.writef("%d\n", mod)
 
-	// Write lock as attribute 42 (A_LOCK) when VAtrKey is set.
-	// This preserves boolean expression locks through export/import cycles.
+	// The lock goes in attribute 42 (A_LOCK), as text the C server
+	// parses (VAtrKey).
 	if obj.Lock != nil {
-		lockStr := gamedb.SerializeBoolExp(obj.Lock)
+		lockStr := UnparseLock(wr.db, obj.Lock)
 		if lockStr != "" {
 			wr.writef(">42\n%s\n", quoteString(lockStr))
 		}
 	}
 
 	// Attributes
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		if attr.Number <= 0 {
 			continue
 		}
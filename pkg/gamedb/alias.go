@@ -0,0 +1,20 @@
+package gamedb
+
+import "time"
+
+// Alias kinds for ConfAlias.
+const (
+	AliasCommand  = "command"  // Command alias: Name runs Target (may carry /switches)
+	AliasFunction = "function" // Function alias: Name() calls Target()
+	AliasBadName  = "badname"  // Forbidden player name pattern (Target unused)
+)
+
+// ConfAlias is a command alias, function alias or bad name added in-game
+// with @aliases. These are kept alongside the ones from alias.conf.
+type ConfAlias struct {
+	Kind    string // AliasCommand, AliasFunction or AliasBadName
+	Name    string // Alias or name pattern, lowercase
+	Target  string
+	Setter  DBRef
+	Created time.Time
+}
@@ -0,0 +1,65 @@
+package gamedb
+
+// attrIndexMin is the fewest attributes worth indexing; on smaller
+// objects a scan is as quick as a map.
+const attrIndexMin = 16
+
+// attrIndex maps attribute numbers to their positions in an object's
+// Attrs. It remembers the slice it was built from, so it is rebuilt when
+// Attrs is replaced, grows or shrinks, however that happened; only a
+// value changed in place leaves it current.
+type attrIndex struct {
+	pos  map[int]int
+	base *Attribute // &Attrs[0] when built
+	n    int        // len(Attrs) when built
+}
+
+// AttrPos returns the position of attribute num in Attributes(), or -1 if
+// the object doesn't have it. If the number appears more than once, the
+// first is found, as a scan would.
+func (o *Object) AttrPos(num int) int {
+	attrs := o.Attributes()
+	if len(attrs) < attrIndexMin {
+		for i, attr := range attrs {
+			if attr.Number == num {
+				return i
+			}
+		}
+		return -1
+	}
+	idx := o.attrIdx
+	if idx == nil || idx.n != len(attrs) || idx.base != &attrs[0] {
+		idx = newAttrIndex(attrs)
+		o.attrIdx = idx
+	}
+	i, ok := idx.pos[num]
+	if ok && attrs[i].Number != num {
+		// A number was changed in place; start again.
+		idx = newAttrIndex(attrs)
+		o.attrIdx = idx
+		i, ok = idx.pos[num]
+	}
+	if !ok {
+		return -1
+	}
+	return i
+}
+
+// Attr returns the raw value of attribute num, "\x01owner:flags:" prefix
+// and all, and whether the object has it. Parents are not searched.
+func (o *Object) Attr(num int) (string, bool) {
+	if i := o.AttrPos(num); i >= 0 {
+		return o.Attributes()[i].Value, true
+	}
+	return "", false
+}
+
+func newAttrIndex(attrs []Attribute) *attrIndex {
+	idx := &attrIndex{pos: make(map[int]int, len(attrs)), base: &attrs[0], n: len(attrs)}
+	for i, attr := range attrs {
+		if _, dup := idx.pos[attr.Number]; !dup {
+			idx.pos[attr.Number] = i
+		}
+	}
+	return idx
+}
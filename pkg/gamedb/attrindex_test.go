@@ -0,0 +1,76 @@
+package gamedb
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func manyAttrs(n int) *Object {
+	obj := &Object{DBRef: 1}
+	for i := 0; i < n; i++ {
+		obj.Attrs = append(obj.Attrs, Attribute{Number: A_USER_START + i, Value: fmt.Sprintf("v%d", i)})
+	}
+	return obj
+}
+
+func scanPos(obj *Object, num int) int {
+	for i, attr := range obj.Attrs {
+		if attr.Number == num {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestAttrPos(t *testing.T) {
+	obj := manyAttrs(40)
+	check := func(when string) {
+		t.Helper()
+		for num := A_USER_START - 1; num <= A_USER_START+45; num++ {
+			if got, want := obj.AttrPos(num), scanPos(obj, num); got != want {
+				t.Errorf("%s: AttrPos(%d) = %d, want %d", when, num, got, want)
+			}
+		}
+	}
+	check("built")
+
+	obj.Attrs = append(obj.Attrs, Attribute{Number: A_USER_START + 42, Value: "x"})
+	check("after appending to Attrs directly")
+
+	obj.SetAttributes(slices.Delete(obj.Attributes(), 3, 4))
+	check("after SetAttributes")
+
+	obj.Attrs[5].Number = A_USER_START + 44
+	check("after renumbering in place")
+
+	obj.Attrs = append(obj.Attrs, Attribute{Number: A_USER_START + 10, Value: "dup"})
+	if v, _ := obj.Attr(A_USER_START + 10); v != "v10" {
+		t.Errorf("Attr with a duplicate = %q, want the first, v10", v)
+	}
+	if _, ok := obj.Attr(1); ok {
+		t.Error("Attr(1) found an attribute the object doesn't have")
+	}
+}
+
+func TestWellKnownAttrNum(t *testing.T) {
+	if num, ok := WellKnownAttrNum("alias"); !ok || num != A_ALIAS {
+		t.Errorf("WellKnownAttrNum(alias) = %d, %v; want %d", num, ok, A_ALIAS)
+	}
+	if _, ok := WellKnownAttrNum("NOSUCHATTR"); ok {
+		t.Error("WellKnownAttrNum(NOSUCHATTR) found a match")
+	}
+}
+
+func benchmarkAttrLookup(b *testing.B, n int, lookup func(*Object, int) int) {
+	obj := manyAttrs(n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		lookup(obj, A_USER_START+i%n)
+	}
+}
+
+func BenchmarkAttrScan10(b *testing.B)  { benchmarkAttrLookup(b, 10, scanPos) }
+func BenchmarkAttrScan500(b *testing.B) { benchmarkAttrLookup(b, 500, scanPos) }
+func BenchmarkAttrPos10(b *testing.B)   { benchmarkAttrLookup(b, 10, (*Object).AttrPos) }
+func BenchmarkAttrPos500(b *testing.B)  { benchmarkAttrLookup(b, 500, (*Object).AttrPos) }
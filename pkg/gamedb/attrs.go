@@ -1,5 +1,7 @@
 package gamedb
 
+import "strings"
+
 // Well-known (built-in) attribute numbers from constants.h
 // These are system-defined and always present.
 // Numbers MUST match the C TinyMUSH source exactly since the flatfile uses these numbers.
@@ -152,6 +154,7 @@ var WellKnownAttrs = map[int]string{
 	209: "SpeechLock",
 	214: "CONFORMAT",  // A_LCON_FMT
 	215: "EXITFORMAT", // A_LEXITS_FMT
+	216: "ExitTo",     // A_EXITVARDEST
 	217: "ChownLock",
 	218: "LASTIP",
 	219: "DarkLock",
@@ -166,9 +169,27 @@ var WellKnownAttrs = map[int]string{
 	231: "PROPDIR",
 }
 
+// wellKnownByName maps upper-case built-in attribute names to numbers.
+var wellKnownByName = func() map[string]int {
+	m := make(map[string]int, len(WellKnownAttrs))
+	for num, name := range WellKnownAttrs {
+		m[strings.ToUpper(name)] = num
+	}
+	return m
+}()
+
+// WellKnownAttrNum returns the number of the built-in attribute named
+// name, ignoring case.
+func WellKnownAttrNum(name string) (int, bool) {
+	num, ok := wellKnownByName[strings.ToUpper(name)]
+	return num, ok
+}
+
 // Well-known attribute number constants.
 const A_SEMAPHORE = 47
+const A_ALIAS = 58
 const A_PROGCMD = 210
+const A_EXITVARDEST = 216
 
 // A_USER_START is the first attribute number available for user-defined attrs.
 const A_USER_START = 256
@@ -1,5 +1,7 @@
 package gamedb
 
+import "time"
+
 // Channel represents a comsys channel definition.
 type Channel struct {
 	Name           string
@@ -13,6 +15,17 @@ type Channel struct {
 	JoinLock       string // Lock expression (unparsed)
 	TransLock      string
 	RecvLock       string
+	LogLength      int  // Messages kept for recall; 0 = no history
+	LogPersist     bool // History is saved to the database
+}
+
+// ChanMessage is one line of a channel's history.
+type ChanMessage struct {
+	Channel string // Channel name
+	Seq     int    // Per-channel sequence number
+	Time    time.Time
+	Sender  DBRef
+	Text    string
 }
 
 // ChanAlias represents a player's subscription/alias for a channel.
@@ -22,6 +35,8 @@ type ChanAlias struct {
 	Alias       string // Player's alias for this channel
 	Title       string // Player's title on this channel
 	IsListening bool   // Currently tuned in
+	Gagged      bool   // Tuned in, but hears nothing
+	Muted       bool   // Doesn't hear connect/disconnect notices
 }
 
 // Channel flag constants (from TinyMUSH comsys).
@@ -0,0 +1,20 @@
+package gamedb
+
+import "time"
+
+// ConnRecord is one session in a player's connection history.
+type ConnRecord struct {
+	Player     DBRef
+	Connect    time.Time
+	Disconnect time.Time // Zero while the session is still open
+	Addr       string    // Remote address, without the port
+	Reason     string    // Why the session ended: quit, booted, shutdown...
+}
+
+// Duration returns how long the session lasted, or has lasted so far.
+func (r *ConnRecord) Duration() time.Duration {
+	if r.Disconnect.IsZero() {
+		return time.Since(r.Connect)
+	}
+	return r.Disconnect.Sub(r.Connect)
+}
@@ -0,0 +1,10 @@
+package gamedb
+
+// CronJob is a recurring @cron entry: Obj runs Attr, as if triggered,
+// whenever the time matches Spec.
+type CronJob struct {
+	ID   int
+	Obj  DBRef
+	Attr int
+	Spec string // Five-field cron timestring, or an alias such as @hourly
+}
@@ -12,6 +12,10 @@ const (
 	MailReply   = 0x0020
 )
 
+// MailFolders is how many folders each player has, numbered from 0; folder
+// 0 is the inbox.
+const MailFolders = 16
+
 // MailMessage represents a single mail message in a player's mailbox.
 // Each recipient gets their own copy with independent read/cleared/folder state.
 type MailMessage struct {
@@ -23,5 +27,15 @@ type MailMessage struct {
 	Body    string
 	Time    time.Time
 	Flags   int // MailIsRead | MailCleared | etc.
-	Folder  int // 0-14
+	Folder  int // 0 to MailFolders-1
+}
+
+// MailAlias is an @malias mailing list. Aliases owned by God are global;
+// the rest are personal to their owner.
+type MailAlias struct {
+	ID      int
+	Name    string // Includes the leading '*'; case-sensitive
+	Owner   DBRef
+	Desc    string
+	Members []DBRef
 }
@@ -0,0 +1,142 @@
+package gamedb
+
+import (
+	"sort"
+	"strings"
+)
+
+// playerIndex maps lowercase player names and aliases to players, so
+// *name matches don't scan every object. Like the rest of the Database it
+// is only touched with the world lock held.
+type playerIndex struct {
+	byKey map[string][]DBRef // name or alias -> players
+	keys  map[DBRef][]string // player -> its keys; the name comes first
+}
+
+// LookupPlayer finds the live player whose name or alias is name, ignoring
+// case. It returns Nothing if there is none.
+func (db *Database) LookupPlayer(name string) DBRef {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if key == "" {
+		return Nothing
+	}
+	idx := db.playerIndex()
+	match := Nothing
+	for _, ref := range idx.byKey[key] {
+		if match == Nothing || ref < match {
+			match = ref
+		}
+	}
+	return match
+}
+
+// PlayersByPrefix lists the live players whose names start with prefix,
+// ignoring case, lowest dbref first. Aliases are not matched.
+func (db *Database) PlayersByPrefix(prefix string) []DBRef {
+	prefix = strings.ToLower(prefix)
+	var refs []DBRef
+	for ref, keys := range db.playerIndex().keys {
+		if strings.HasPrefix(keys[0], prefix) {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+	return refs
+}
+
+// IndexPlayer brings obj's entry in the player index up to date after it
+// is created, renamed, re-aliased, destroyed or changes type. It does
+// nothing until the index has been built.
+func (db *Database) IndexPlayer(obj *Object) {
+	if db.players == nil || obj == nil {
+		return
+	}
+	db.players.remove(obj.DBRef)
+	if db.Objects[obj.DBRef] == obj {
+		db.players.add(obj)
+	}
+}
+
+// UnindexPlayer drops ref from the player index.
+func (db *Database) UnindexPlayer(ref DBRef) {
+	if db.players != nil {
+		db.players.remove(ref)
+	}
+}
+
+// StartPlayerIndex gives a database with no objects yet an empty player
+// index, for a loader that passes each object to IndexPlayer as it goes.
+// The boltstore does this so cold players are indexed before their
+// attributes are paged out.
+func (db *Database) StartPlayerIndex() {
+	db.players = newPlayerIndex()
+}
+
+// playerIndex returns the index, building it on first use.
+func (db *Database) playerIndex() *playerIndex {
+	if db.players == nil {
+		idx := newPlayerIndex()
+		for _, obj := range db.Objects {
+			idx.add(obj)
+		}
+		db.players = idx
+	}
+	return db.players
+}
+
+func newPlayerIndex() *playerIndex {
+	return &playerIndex{byKey: make(map[string][]DBRef), keys: make(map[DBRef][]string)}
+}
+
+// add indexes obj if it is a live player.
+func (idx *playerIndex) add(obj *Object) {
+	if obj.ObjType() != TypePlayer || obj.IsGoing() {
+		return
+	}
+	keys := []string{strings.ToLower(obj.Name)}
+	if alias := strings.ToLower(playerAlias(obj)); alias != "" && alias != keys[0] {
+		keys = append(keys, alias)
+	}
+	idx.keys[obj.DBRef] = keys
+	for _, k := range keys {
+		idx.byKey[k] = append(idx.byKey[k], obj.DBRef)
+	}
+}
+
+func (idx *playerIndex) remove(ref DBRef) {
+	for _, k := range idx.keys[ref] {
+		refs := idx.byKey[k]
+		for i, r := range refs {
+			if r == ref {
+				refs = append(refs[:i], refs[i+1:]...)
+				break
+			}
+		}
+		if len(refs) == 0 {
+			delete(idx.byKey, k)
+		} else {
+			idx.byKey[k] = refs
+		}
+	}
+	delete(idx.keys, ref)
+}
+
+// playerAlias returns the text of obj's ALIAS attribute.
+func playerAlias(obj *Object) string {
+	for _, attr := range obj.Attributes() {
+		if attr.Number != A_ALIAS {
+			continue
+		}
+		text := attr.Value
+		// Stored values may carry a "\x01owner:flags:" prefix.
+		if strings.HasPrefix(text, "\x01") {
+			if parts := strings.SplitN(text[1:], ":", 3); len(parts) == 3 {
+				text = parts[2]
+			} else {
+				text = text[1:]
+			}
+		}
+		return strings.TrimSpace(text)
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package gamedb
+
+import "time"
+
+// QueuedCommand is a @wait or semaphore queue entry saved across a
+// restart. QRegs and XRegs are its %q registers.
+type QueuedCommand struct {
+	Player    DBRef
+	Cause     DBRef
+	Caller    DBRef
+	Command   string
+	Args      []string
+	QRegs     []string
+	XRegs     map[string]string
+	WaitUntil time.Time // Zero for a semaphore wait with no timeout
+	SemObj    DBRef     // Nothing for a plain @wait
+	SemAttr   int
+	Deposit   int // wait_cost already paid
+}
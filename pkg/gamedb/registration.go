@@ -0,0 +1,13 @@
+package gamedb
+
+import "time"
+
+// Registration is a queued request for a new character, made with
+// "register" at the login screen when open creation is disabled.
+type Registration struct {
+	ID        int
+	Name      string
+	Email     string
+	Addr      string // Address the request came from
+	Requested time.Time
+}
@@ -0,0 +1,27 @@
+package gamedb
+
+import "time"
+
+// Kinds of RuntimeSetting.
+const (
+	RuntimeMOTD     = "motd"     // An @motd; Name is "", "wizard", "down", "full", or the text file it was written to
+	RuntimeRotation = "rotation" // A rotating @motd/add message; Name is its number
+	RuntimeHelpText = "helptext" // An @helptext entry; Name is "<file>:<topic>"
+	RuntimeFunction = "function" // An @function; Name is the function name
+	RuntimeAdmin    = "admin"    // An @admin/save parameter; Name is the parameter
+)
+
+// RuntimeSetting is a setting changed in-game with @motd, @helptext,
+// @function or @admin/save, kept so it outlives a restart.
+type RuntimeSetting struct {
+	Kind    string // One of the Runtime kinds above
+	Name    string
+	Value   string    // MOTD text or parameter value
+	Obj     DBRef     // @function object
+	Attr    int       // @function attribute number
+	Flags   int       // @function flags (eval.UfPriv, eval.UfPres), or @helptext's
+	From    time.Time // Rotating MOTD: shown from (zero = at once)
+	Until   time.Time // Rotating MOTD: shown until (zero = for good)
+	Setter  DBRef
+	Changed time.Time
+}
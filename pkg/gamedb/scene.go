@@ -0,0 +1,26 @@
+package gamedb
+
+import "time"
+
+// Scene is a recorded roleplay scene. The log is stored encrypted with a
+// key derived for the scene's room; only the server can read it back.
+type Scene struct {
+	ID           int
+	Room         DBRef
+	Title        string
+	Owner        DBRef // Player who started the recording
+	Started      time.Time
+	Ended        time.Time // Zero while recording
+	Participants []DBRef   // Players present while recording
+	Viewers      []DBRef   // Extra players granted read access
+	Public       bool      // Readable by anyone, not just participants
+	Log          [][]byte  // Encrypted SceneEntry records, oldest first
+}
+
+// SceneEntry is one captured line of a scene log.
+type SceneEntry struct {
+	Time   time.Time
+	Source DBRef
+	Type   string // Event type name ("say", "pose", "emit", "move", ...)
+	Text   string
+}
@@ -0,0 +1,19 @@
+package gamedb
+
+import "time"
+
+// Site rule kinds (C TinyMUSH access list permissions).
+const (
+	SiteForbid   = "forbid"   // Refuse all connections
+	SiteRegister = "register" // Connect only; no character creation
+	SiteNoGuest  = "noguest"  // No guest logins
+)
+
+// SiteRule restricts connections from an address or address range.
+type SiteRule struct {
+	Pattern string // CIDR ("10.0.0.0/8") or single address
+	Kind    string // SiteForbid, SiteRegister, or SiteNoGuest
+	Reason  string // Free-form note shown in @site/list
+	Setter  DBRef  // Wizard who added the rule
+	Created time.Time
+}
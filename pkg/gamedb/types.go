@@ -91,7 +91,9 @@ const (
 	Flag2Light      = 0x00000020
 	Flag2HasListen  = 0x00000040
 	Flag2HasFwd     = 0x00000080
+	Flag2GoingTwice = 0x00000100 // GOING room whose teardown has run
 	Flag2Connected  = 0x00000200
+	Flag2Suspect    = 0x00000400 // Activity is reported to wizards
 	Flag2Slave      = 0x00000800
 	Flag2HTML       = 0x00001000
 	Flag2Ansi       = 0x00002000
@@ -301,6 +303,40 @@ type Object struct {
 	LastMod    time.Time
 	Attrs    []Attribute
 	Lock     *BoolExp // parsed default lock (if in header)
+
+	pager   AttrPager  // nil unless the object's attributes may be paged out
+	attrIdx *attrIndex // positions in Attrs, built on demand by AttrPos
+}
+
+// AttrPager keeps the attributes of rarely used objects on disk instead of
+// in memory. Objects it manages call Touch before their attributes are
+// used, so it can read them back and note the object as recently used.
+type AttrPager interface {
+	Touch(o *Object)
+}
+
+// SetPager puts the object's attributes under p's management (nil = always
+// in memory).
+func (o *Object) SetPager(p AttrPager) {
+	o.pager = p
+}
+
+// Attributes returns the object's attributes, reading them back from disk
+// first if they were paged out. Use it rather than Attrs on a live game.
+func (o *Object) Attributes() []Attribute {
+	if o.pager != nil {
+		o.pager.Touch(o)
+	}
+	return o.Attrs
+}
+
+// SetAttributes replaces the object's attributes.
+func (o *Object) SetAttributes(attrs []Attribute) {
+	if o.pager != nil {
+		o.pager.Touch(o)
+	}
+	o.Attrs = attrs
+	o.attrIdx = nil
 }
 
 // ObjType returns the object type from the flags.
@@ -334,6 +370,8 @@ type Database struct {
 	Objects       map[DBRef]*Object
 	AttrNames     map[int]*AttrDef  // attr number -> definition
 	AttrByName    map[string]*AttrDef // attr name -> definition
+
+	players *playerIndex // built on first player lookup
 }
 
 // SafeContents returns a slice of DBRefs from obj's contents chain,
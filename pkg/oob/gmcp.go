@@ -27,6 +27,8 @@ func GMCPPackage(evType events.EventType) string {
 		return "Char.Logout"
 	case events.EvWho:
 		return "Char.Group"
+	case events.EvMail:
+		return "Char.Mail"
 	default:
 		return ""
 	}
@@ -1,6 +1,7 @@
 package oob
 
 import (
+	"bytes"
 	"io"
 	"log"
 	"net"
@@ -8,25 +9,35 @@ import (
 )
 
 // Negotiate performs OOB protocol negotiation with a telnet client.
-// It sends WILL for GMCP and MSDP, waits for responses, and returns
-// the negotiated capabilities. The timeout controls how long to wait
-// for client responses.
+// It sends WILL for GMCP, MSDP, MSSP, and EOR and DO for TTYPE, waits for
+// responses, and returns the negotiated capabilities. Terminal types are
+// collected MTTS-style so the caller can pick a color depth. The timeout
+// controls how long to wait for client responses.
 func Negotiate(conn net.Conn, timeout time.Duration) *Capabilities {
 	caps := NewCapabilities()
 
-	// Send WILL GMCP, WILL MSDP, and WILL MSSP
+	// Send WILL GMCP, WILL MSDP, WILL MSSP, WILL EOR, and DO TTYPE
 	willGMCP := []byte{IAC, WILL, TeloptGMCP}
 	willMSDP := []byte{IAC, WILL, TeloptMSDP}
 	willMSSP := []byte{IAC, WILL, TeloptMSSP}
+	willEOR := []byte{IAC, WILL, TeloptEOR}
+	doTTYPE := []byte{IAC, DO, TeloptTTYPE}
+	sendTTYPE := []byte{IAC, SB, TeloptTTYPE, TTYPESend, IAC, SE}
 
 	conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
 	conn.Write(willGMCP)
 	conn.Write(willMSDP)
 	conn.Write(willMSSP)
+	conn.Write(willEOR)
+	conn.Write(doTTYPE)
 
-	// Read responses within timeout
+	// Read responses within timeout. Bytes accumulate in data so that a
+	// subnegotiation split across reads is parsed once it is complete.
 	conn.SetReadDeadline(time.Now().Add(timeout))
 	buf := make([]byte, 256)
+	var data []byte
+	pos := 0
+	ttypePending := false
 	for {
 		n, err := conn.Read(buf)
 		if err != nil {
@@ -39,14 +50,33 @@ func Negotiate(conn net.Conn, timeout time.Duration) *Capabilities {
 			log.Printf("oob negotiate read error: %v", err)
 			break
 		}
+		data = append(data, buf[:n]...)
 
 		// Parse IAC sequences in the response
-		for i := 0; i < n-2; i++ {
-			if buf[i] != IAC {
+		for pos < len(data)-2 {
+			if data[pos] != IAC {
+				pos++
+				continue
+			}
+			cmd := data[pos+1]
+			opt := data[pos+2]
+			if cmd == SB {
+				end := bytes.Index(data[pos:], []byte{IAC, SE})
+				if end < 0 {
+					break // Wait for the rest of the subnegotiation
+				}
+				sub := data[pos+3 : pos+end]
+				if opt == TeloptTTYPE && len(sub) > 0 && sub[0] == TTYPEIs {
+					if caps.addTermType(string(sub[1:])) {
+						conn.Write(sendTTYPE)
+					} else {
+						ttypePending = false
+						log.Printf("oob: client terminal types %v (MTTS %d)", caps.TermTypes, caps.MTTS)
+					}
+				}
+				pos += end + 2
 				continue
 			}
-			cmd := buf[i+1]
-			opt := buf[i+2]
 			switch {
 			case cmd == DO && opt == TeloptGMCP:
 				caps.GMCP = true
@@ -63,12 +93,19 @@ func Negotiate(conn net.Conn, timeout time.Duration) *Capabilities {
 				log.Printf("oob: client supports MSSP")
 			case cmd == DONT && opt == TeloptMSSP:
 				log.Printf("oob: client declined MSSP")
+			case cmd == DO && opt == TeloptEOR:
+				caps.EOR = true
+			case cmd == WILL && opt == TeloptTTYPE:
+				ttypePending = true
+				conn.Write(sendTTYPE)
+			case cmd == WONT && opt == TeloptTTYPE:
+				ttypePending = false
 			}
-			i += 2 // Skip the 3-byte sequence
+			pos += 3 // Skip the 3-byte sequence
 		}
 
 		// If we got responses for all offered protocols, no need to wait longer
-		if (caps.GMCP || caps.MSDP) && caps.MSSP {
+		if (caps.GMCP || caps.MSDP) && caps.MSSP && !ttypePending {
 			break
 		}
 	}
@@ -163,3 +163,28 @@ func TestCapabilities(t *testing.T) {
 		t.Error("should have GMCP")
 	}
 }
+
+func TestTermTypeColor(t *testing.T) {
+	caps := NewCapabilities()
+	if caps.Xterm256() || caps.TrueColor() {
+		t.Error("no TTYPE replies should report no extended color")
+	}
+	if !caps.addTermType("Mudlet") || !caps.addTermType("ANSI-TRUECOLOR") {
+		t.Error("expected another TTYPE SEND after the first two replies")
+	}
+	if caps.addTermType("MTTS 2349") {
+		t.Error("MTTS reply should end the TTYPE cycle")
+	}
+	if caps.MTTS != 2349 || !caps.TrueColor() || !caps.Xterm256() || !caps.Ansi() {
+		t.Errorf("unexpected capabilities: %+v", caps)
+	}
+
+	caps = NewCapabilities()
+	caps.addTermType("XTERM-256COLOR")
+	if caps.addTermType("xterm-256color") {
+		t.Error("repeated reply should end the TTYPE cycle")
+	}
+	if !caps.Xterm256() || caps.TrueColor() {
+		t.Errorf("XTERM-256COLOR: Xterm256=%v TrueColor=%v", caps.Xterm256(), caps.TrueColor())
+	}
+}
@@ -4,6 +4,11 @@
 // data alongside normal text output.
 package oob
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Protocol identifies which OOB protocols a client supports.
 type Protocol int
 
@@ -20,6 +25,11 @@ type Capabilities struct {
 	MSDP bool // MSDP (telopt 69) negotiated
 	MCP  bool // MCP handshake completed
 	MSSP bool // MSSP (telopt 70) negotiated
+	EOR  bool // EOR (telopt 25) negotiated: prompts end with IAC EOR, not IAC GA
+
+	// Terminal identification from TTYPE/MTTS negotiation
+	TermTypes []string // Terminal type replies, in the order received
+	MTTS      int      // MTTS capability bitvector (0 = not reported)
 
 	// GMCP package subscriptions from the client
 	GMCPPackages map[string]bool
@@ -36,3 +46,61 @@ func NewCapabilities() *Capabilities {
 func (c *Capabilities) HasAny() bool {
 	return c.GMCP || c.MSDP || c.MCP || c.MSSP
 }
+
+// MTTS capability bits reported in the "MTTS <n>" terminal type reply.
+const (
+	MTTSAnsi      = 1
+	MTTSVT100     = 2
+	MTTSUTF8      = 4
+	MTTS256Colors = 8
+	MTTSTrueColor = 256
+)
+
+// addTermType records one TTYPE IS reply and reports whether another
+// TTYPE SEND should be issued. Per MTTS, clients cycle through their client
+// name, terminal type, and "MTTS <n>"; a repeated reply ends the cycle.
+func (c *Capabilities) addTermType(name string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if strings.HasPrefix(upper, "MTTS ") {
+		if n, err := strconv.Atoi(strings.TrimSpace(upper[5:])); err == nil {
+			c.MTTS = n
+		}
+		return false
+	}
+	for _, t := range c.TermTypes {
+		if t == upper {
+			return false
+		}
+	}
+	c.TermTypes = append(c.TermTypes, upper)
+	return len(c.TermTypes) < 3
+}
+
+// termHas reports whether any terminal type reply contains substr.
+func (c *Capabilities) termHas(substr string) bool {
+	for _, t := range c.TermTypes {
+		if strings.Contains(t, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrueColor reports whether the client advertised 24-bit color support.
+func (c *Capabilities) TrueColor() bool {
+	return c.MTTS&MTTSTrueColor != 0 || c.termHas("TRUECOLOR") || c.termHas("24BIT")
+}
+
+// Xterm256 reports whether the client advertised the xterm-256 palette.
+func (c *Capabilities) Xterm256() bool {
+	return c.TrueColor() || c.MTTS&MTTS256Colors != 0 || c.termHas("256COLOR")
+}
+
+// Ansi reports whether the client identified itself as ANSI capable.
+// A client that never answered TTYPE reports false.
+func (c *Capabilities) Ansi() bool {
+	if c.MTTS != 0 {
+		return c.MTTS&MTTSAnsi != 0
+	}
+	return c.Xterm256() || c.termHas("ANSI") || c.termHas("XTERM") || c.termHas("VT100")
+}
@@ -10,11 +10,25 @@ const (
 	SB   byte = 250 // Subnegotiation Begin
 	SE   byte = 240 // Subnegotiation End
 	NOP  byte = 241
+	GA   byte = 249 // Go Ahead: marks the end of a prompt
+	EOR  byte = 239 // End of Record: a prompt marker, once TeloptEOR is agreed
 
 	// Telnet options used by OOB protocols
 	TeloptGMCP byte = 201 // GMCP option number
 	TeloptMSDP byte = 69  // MSDP option number
 	TeloptMSSP byte = 70  // MSSP option number
+
+	// Terminal type (RFC 1091), used for MTTS color detection
+	TeloptTTYPE byte = 24
+
+	// End of record (RFC 885), used to mark prompts
+	TeloptEOR byte = 25
+)
+
+// TTYPE subnegotiation commands
+const (
+	TTYPEIs   byte = 0 // Client reports a terminal type
+	TTYPESend byte = 1 // Server requests the next terminal type
 )
 
 // MSDP subnegotiation type bytes
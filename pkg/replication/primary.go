@@ -0,0 +1,334 @@
+package replication
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/boltstore"
+)
+
+// backlog is how many batches a standby may fall behind before it is
+// disconnected; on reconnecting it is sent a fresh snapshot.
+const backlog = 4096
+
+// Primary accepts standbys and streams the store's writes to them.
+type Primary struct {
+	store  *boltstore.Store
+	secret string
+
+	mu       sync.Mutex
+	seq      uint64 // last batch published
+	standbys map[*standby]bool
+	ln       net.Listener
+	closed   bool
+}
+
+// standby is one connected standby.
+type standby struct {
+	addr    string
+	since   time.Time
+	batches chan batch
+	dropped chan struct{} // closed when disconnected by the primary
+	acked   atomic.Uint64
+}
+
+type batch struct {
+	seq     uint64
+	changes []boltstore.Change
+}
+
+// StandbyInfo describes a connected standby.
+type StandbyInfo struct {
+	Addr    string
+	Since   time.Time
+	Applied uint64 // last batch the standby reported applying
+	Behind  uint64 // batches committed here but not yet applied there
+}
+
+// NewPrimary starts feeding store's writes to standbys. Standbys must
+// prove they know secret before they are sent anything.
+func NewPrimary(store *boltstore.Store, secret string) *Primary {
+	p := &Primary{store: store, secret: secret, standbys: make(map[*standby]bool)}
+	store.SetChangeFeed(p.publish)
+	return p
+}
+
+// Listen accepts standbys on addr, over TLS if tlsConf is not nil.
+func (p *Primary) Listen(addr string, tlsConf *tls.Config) error {
+	var ln net.Listener
+	var err error
+	if tlsConf != nil {
+		ln, err = tls.Listen("tcp", addr, tlsConf)
+	} else {
+		warnCleartext(addr)
+		ln, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("replication: listen %s: %w", addr, err)
+	}
+	go p.Serve(ln)
+	return nil
+}
+
+// Serve accepts standbys on ln until Close.
+func (p *Primary) Serve(ln net.Listener) {
+	p.mu.Lock()
+	p.ln = ln
+	p.mu.Unlock()
+	for {
+		c, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			log.Printf("replication: accept: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		go p.handle(c)
+	}
+}
+
+// Close stops accepting standbys, disconnects those connected and turns
+// off the store's change feed.
+func (p *Primary) Close() error {
+	p.store.SetChangeFeed(nil)
+	p.mu.Lock()
+	p.closed = true
+	for sb := range p.standbys {
+		p.drop(sb)
+	}
+	ln := p.ln
+	p.mu.Unlock()
+	if ln != nil {
+		return ln.Close()
+	}
+	return nil
+}
+
+// Standbys lists the connected standbys, longest connected first.
+func (p *Primary) Standbys() []StandbyInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []StandbyInfo
+	for sb := range p.standbys {
+		applied := sb.acked.Load()
+		out = append(out, StandbyInfo{Addr: sb.addr, Since: sb.since, Applied: applied, Behind: p.seq - applied})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Since.Before(out[j].Since) })
+	return out
+}
+
+// Drain waits up to timeout for every connected standby to apply all
+// that has been committed, so a clean shutdown leaves them current. It
+// reports whether they got there.
+func (p *Primary) Drain(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		behind := false
+		for _, sb := range p.Standbys() {
+			if sb.Behind > 0 {
+				behind = true
+			}
+		}
+		if !behind {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// publish is the store's change feed. It must not block, so a standby
+// whose backlog is full is dropped rather than waited for.
+func (p *Primary) publish(changes []boltstore.Change) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seq++
+	b := batch{seq: p.seq, changes: changes}
+	for sb := range p.standbys {
+		select {
+		case sb.batches <- b:
+		default:
+			p.drop(sb)
+		}
+	}
+}
+
+// drop disconnects a standby. p.mu must be held.
+func (p *Primary) drop(sb *standby) {
+	if p.standbys[sb] {
+		delete(p.standbys, sb)
+		close(sb.dropped)
+	}
+}
+
+func (p *Primary) handle(nc net.Conn) {
+	c := newConn(nc)
+	defer c.Close()
+	addr := nc.RemoteAddr().String()
+
+	sb, err := p.sync(c, addr)
+	if err != nil {
+		log.Printf("replication: standby %s: %v", addr, err)
+		c.send(&frame{Type: frameError, Err: err.Error()})
+		return
+	}
+	defer func() {
+		p.mu.Lock()
+		p.drop(sb)
+		p.mu.Unlock()
+	}()
+	log.Printf("replication: standby %s synced", addr)
+
+	// Acks arrive on their own goroutine; a read error means the
+	// standby has gone.
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			f, err := c.recv()
+			if err != nil {
+				return
+			}
+			if f.Type == frameAck {
+				sb.acked.Store(f.Seq)
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	last := sb.acked.Load()
+	for {
+		select {
+		case b := <-sb.batches:
+			if err := c.send(&frame{Type: frameChanges, Seq: b.seq, Changes: b.changes}); err != nil {
+				log.Printf("replication: standby %s: %v", addr, err)
+				return
+			}
+			last = b.seq
+		case <-heartbeat.C:
+			if err := c.send(&frame{Type: frameHeartbeat, Seq: last}); err != nil {
+				log.Printf("replication: standby %s: %v", addr, err)
+				return
+			}
+		case <-sb.dropped:
+			log.Printf("replication: standby %s disconnected (more than %d batches behind, or shutting down)", addr, backlog)
+			c.send(&frame{Type: frameError, Err: "disconnected by primary"})
+			return
+		case <-gone:
+			log.Printf("replication: standby %s disconnected", addr)
+			return
+		}
+	}
+}
+
+// sync authenticates a new standby, answers its challenge in turn and
+// sends it a snapshot, registering it for the writes committed after the
+// snapshot was taken.
+func (p *Primary) sync(c *conn, addr string) (*standby, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	if err := c.send(&frame{Type: frameChallenge, Nonce: nonce}); err != nil {
+		return nil, err
+	}
+	f, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	if f.Type == frameAuth && f.Version != protocolVersion {
+		return nil, fmt.Errorf("protocol version %d, this primary speaks %d", f.Version, protocolVersion)
+	}
+	if f.Type != frameAuth || !hmac.Equal(f.MAC, mac(p.secret, roleStandby, nonce)) {
+		return nil, errors.New("authentication failed")
+	}
+	if len(f.Nonce) != nonceSize {
+		return nil, errors.New("no challenge for the primary")
+	}
+	if err := c.send(&frame{Type: frameAuth, MAC: mac(p.secret, rolePrimary, f.Nonce)}); err != nil {
+		return nil, err
+	}
+
+	sb := &standby{
+		addr:    addr,
+		since:   time.Now(),
+		batches: make(chan batch, backlog),
+		dropped: make(chan struct{}),
+	}
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return nil, errors.New("primary is shutting down")
+	}
+	var start uint64
+	w := &snapshotWriter{c: c}
+	_, err = p.store.Snapshot(w, func() {
+		p.mu.Lock()
+		p.standbys[sb] = true
+		start = p.seq
+		p.mu.Unlock()
+	})
+	if err == nil {
+		err = w.flush()
+	}
+	if err == nil {
+		err = c.send(&frame{Type: frameSnapshotEnd, Seq: start})
+	}
+	if err != nil {
+		p.mu.Lock()
+		p.drop(sb)
+		p.mu.Unlock()
+		return nil, err
+	}
+	sb.acked.Store(start)
+	return sb, nil
+}
+
+// snapshotWriter sends what is written to it as snapshot frames of up to
+// chunkSize bytes.
+type snapshotWriter struct {
+	c   *conn
+	buf []byte
+}
+
+func (w *snapshotWriter) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		room := chunkSize - len(w.buf)
+		if room > len(b) {
+			room = len(b)
+		}
+		w.buf = append(w.buf, b[:room]...)
+		b = b[room:]
+		if len(w.buf) == chunkSize {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *snapshotWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.c.send(&frame{Type: frameSnapshot, Data: w.buf})
+	w.buf = w.buf[:0]
+	return err
+}
@@ -0,0 +1,129 @@
+// Package replication keeps warm standby copies of a game's bolt store.
+// The primary streams every committed write (the same bytes PersistObject
+// and the rest of the store write) over TCP or TLS to standby processes,
+// which apply them to their own store. A standby that connects, or falls
+// too far behind, first receives a full snapshot. To fail over, stop the
+// standby and start the game on its bolt file.
+//
+// Each side proves to the other that it knows the shared secret before
+// anything else is sent, but without TLS the stream itself is readable by
+// anyone on the path, so a non-loopback address without TLS is logged
+// loudly.
+package replication
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/gob"
+	"log"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/boltstore"
+)
+
+// protocolVersion changes whenever the frames below do.
+const protocolVersion = 2
+
+const (
+	chunkSize         = 256 << 10        // snapshot bytes per frame
+	heartbeatInterval = 10 * time.Second // primary → standby when idle
+	writeTimeout      = 30 * time.Second
+	readTimeout       = 3 * heartbeatInterval
+)
+
+type frameType int
+
+const (
+	frameChallenge   frameType = iota + 1 // primary: Nonce
+	frameAuth                             // standby: Version, MAC, Nonce; primary: MAC
+	frameSnapshot                         // primary: Data, a piece of the snapshot
+	frameSnapshotEnd                      // primary: Seq the snapshot is current to
+	frameChanges                          // primary: Seq, Changes
+	frameHeartbeat                        // primary: Seq of the last batch sent
+	frameAck                              // standby: Seq applied
+	frameError                            // either: Err, then the connection closes
+)
+
+// frame is one gob-encoded message.
+type frame struct {
+	Type    frameType
+	Version int
+	Nonce   []byte
+	MAC     []byte
+	Data    []byte
+	Seq     uint64
+	Changes []boltstore.Change
+	Err     string
+}
+
+// conn sends and receives frames with deadlines, so a dead peer is
+// noticed instead of blocking forever.
+type conn struct {
+	net.Conn
+	enc *gob.Encoder
+	dec *gob.Decoder
+}
+
+func newConn(c net.Conn) *conn {
+	return &conn{Conn: c, enc: gob.NewEncoder(c), dec: gob.NewDecoder(c)}
+}
+
+func (c *conn) send(f *frame) error {
+	c.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.enc.Encode(f)
+}
+
+func (c *conn) recv() (*frame, error) {
+	c.SetReadDeadline(time.Now().Add(readTimeout))
+	var f frame
+	if err := c.dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Roles mixed into a MAC, so that neither side's answer can be replayed
+// as the other's.
+const (
+	rolePrimary = "primary"
+	roleStandby = "standby"
+)
+
+// mac answers a challenge for role; the secret itself never crosses the
+// wire.
+func mac(secret, role string, nonce []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(role))
+	h.Write(nonce)
+	return h.Sum(nil)
+}
+
+// nonceSize is the length of each side's challenge.
+const nonceSize = 32
+
+// warnCleartext logs loudly when replication to or from addr isn't
+// encrypted and addr isn't loopback: the snapshot and every change after
+// it, password hashes included, cross the network in the clear.
+func warnCleartext(addr string) {
+	if isLoopback(addr) {
+		return
+	}
+	log.Printf("replication: WARNING: %s is not loopback and replication_tls is off; the whole database crosses the network unencrypted", addr)
+}
+
+// isLoopback reports whether addr's host is a loopback address or
+// "localhost". An empty or unspecified host listens everywhere, so it is
+// not.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
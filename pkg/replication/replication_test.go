@@ -0,0 +1,170 @@
+package replication
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/boltstore"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+func startPrimary(t *testing.T) (*boltstore.Store, *Primary, string) {
+	t.Helper()
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "primary.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPrimary(store, "s3cret")
+	go p.Serve(ln)
+	t.Cleanup(func() { p.Close() })
+	return store, p, ln.Addr().String()
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStandbyFollowsPrimary(t *testing.T) {
+	store, p, addr := startPrimary(t)
+	put := func(ref gamedb.DBRef, name string) {
+		if err := store.PutObject(&gamedb.Object{DBRef: ref, Name: name, Owner: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put(0, "Limbo")
+	put(1, "Wizard")
+	if err := store.PutChanMessage(&gamedb.ChanMessage{Channel: "Public", Time: time.Now(), Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sb := &Standby{Primary: addr, Secret: "s3cret", BoltPath: filepath.Join(t.TempDir(), "standby.bolt")}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- sb.Run(ctx) }()
+	waitFor(t, "snapshot", func() bool { return sb.Status().Connected })
+	start := sb.Status().Seq
+	if start != 3 {
+		t.Errorf("snapshot Seq = %d, want 3", start)
+	}
+
+	// Written after the snapshot, so these arrive as changes.
+	put(2, "Thing")
+	put(0, "Limbo Renamed")
+	if err := store.DeleteObject(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteChanLog("Public"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "changes to be applied", func() bool {
+		s := p.Standbys()
+		return len(s) == 1 && s[0].Applied == start+4 && s[0].Behind == 0
+	})
+	if got := sb.Status().Seq; got != start+4 {
+		t.Errorf("standby Seq = %d, want %d", got, start+4)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	copy, err := boltstore.OpenReadOnly(sb.BoltPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copy.Close()
+	if err := copy.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	db := copy.DB()
+	if obj := db.Objects[0]; obj == nil || obj.Name != "Limbo Renamed" {
+		t.Errorf("#0 = %+v, want Limbo Renamed", obj)
+	}
+	if _, ok := db.Objects[1]; ok {
+		t.Error("#1 still present after delete")
+	}
+	if obj := db.Objects[2]; obj == nil || obj.Name != "Thing" {
+		t.Errorf("#2 = %+v, want Thing", obj)
+	}
+	msgs, err := copy.LoadChanMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("channel history = %v, want it deleted", msgs)
+	}
+}
+
+func TestStandbyWrongSecret(t *testing.T) {
+	_, p, addr := startPrimary(t)
+	sb := &Standby{Primary: addr, Secret: "guess", BoltPath: filepath.Join(t.TempDir(), "standby.bolt")}
+	_, err := sb.follow(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Fatalf("follow = %v, want authentication failure", err)
+	}
+	if n := len(p.Standbys()); n != 0 {
+		t.Errorf("%d standbys registered, want 0", n)
+	}
+}
+
+func TestStandbyRejectsImpostor(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// An impostor can't answer the standby's challenge, so it replays the
+	// standby's own answer to the primary's.
+	go func() {
+		nc, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c := newConn(nc)
+		defer c.Close()
+		c.send(&frame{Type: frameChallenge, Nonce: make([]byte, nonceSize)})
+		f, err := c.recv()
+		if err != nil {
+			return
+		}
+		c.send(&frame{Type: frameAuth, MAC: f.MAC})
+		c.send(&frame{Type: frameSnapshotEnd})
+	}()
+	sb := &Standby{Primary: ln.Addr().String(), Secret: "s3cret", BoltPath: filepath.Join(t.TempDir(), "standby.bolt")}
+	_, err = sb.follow(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "primary failed authentication") {
+		t.Fatalf("follow = %v, want the primary to fail authentication", err)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1:4300":  true,
+		"[::1]:4300":      true,
+		"localhost:4300":  true,
+		":4300":           false,
+		"0.0.0.0:4300":    false,
+		"10.0.0.5:4300":   false,
+		"db.example:4300": false,
+	} {
+		if got := isLoopback(addr); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package replication
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/boltstore"
+)
+
+// Standby follows a primary, keeping a copy of its bolt store at BoltPath.
+type Standby struct {
+	Primary  string      // host:port of the primary's replication listener
+	Secret   string      // shared with the primary
+	TLS      *tls.Config // nil for plain TCP
+	BoltPath string
+
+	mu     sync.Mutex
+	store  *boltstore.Store
+	status StandbyStatus
+}
+
+// StandbyStatus is how far a standby has got.
+type StandbyStatus struct {
+	Connected bool
+	Seq       uint64    // last batch applied
+	Synced    time.Time // when the last snapshot was installed
+	Applied   time.Time // when the last batch was applied
+	LastError string
+}
+
+// Status reports how far the standby has got.
+func (s *Standby) Status() StandbyStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Run follows the primary until ctx is cancelled, reconnecting after any
+// error. Each connection starts with a fresh snapshot, so nothing is lost
+// however long the primary was unreachable. The bolt file is closed when
+// Run returns, ready for a game to be started on it.
+func (s *Standby) Run(ctx context.Context) error {
+	defer s.closeStore()
+	if s.TLS == nil {
+		warnCleartext(s.Primary)
+	}
+	backoff := time.Second
+	for {
+		synced, err := s.follow(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		s.mu.Lock()
+		s.status.Connected = false
+		s.status.LastError = err.Error()
+		s.mu.Unlock()
+		if synced {
+			backoff = time.Second
+		}
+		log.Printf("replication: %s: %v; reconnecting in %s", s.Primary, err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
+	}
+}
+
+// follow makes one connection to the primary, installs its snapshot and
+// applies its changes until the connection fails. synced reports whether
+// it got as far as installing the snapshot.
+func (s *Standby) follow(ctx context.Context) (synced bool, err error) {
+	nd := &net.Dialer{Timeout: 10 * time.Second}
+	var nc net.Conn
+	if s.TLS != nil {
+		nc, err = (&tls.Dialer{NetDialer: nd, Config: s.TLS}).DialContext(ctx, "tcp", s.Primary)
+	} else {
+		nc, err = nd.DialContext(ctx, "tcp", s.Primary)
+	}
+	if err != nil {
+		return false, err
+	}
+	c := newConn(nc)
+	defer c.Close()
+	// Closing the connection is what interrupts a blocked read.
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+
+	f, err := c.recv()
+	if err != nil {
+		return false, err
+	}
+	if f.Type != frameChallenge {
+		return false, unexpected(f)
+	}
+	// Challenge the primary in turn: without this, anyone who can take
+	// its address could feed us a database of their own.
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return false, err
+	}
+	auth := &frame{Type: frameAuth, Version: protocolVersion, MAC: mac(s.Secret, roleStandby, f.Nonce), Nonce: nonce}
+	if err := c.send(auth); err != nil {
+		return false, err
+	}
+	if f, err = c.recv(); err != nil {
+		return false, err
+	}
+	if f.Type != frameAuth {
+		return false, unexpected(f)
+	}
+	if !hmac.Equal(f.MAC, mac(s.Secret, rolePrimary, nonce)) {
+		return false, errors.New("primary failed authentication")
+	}
+
+	seq, size, err := s.receiveSnapshot(c)
+	if err != nil {
+		return false, err
+	}
+	store := s.currentStore()
+	s.mu.Lock()
+	s.status = StandbyStatus{Connected: true, Seq: seq, Synced: time.Now()}
+	s.mu.Unlock()
+	log.Printf("replication: synced %d bytes from %s", size, s.Primary)
+
+	for {
+		f, err := c.recv()
+		if err != nil {
+			return true, err
+		}
+		switch f.Type {
+		case frameChanges:
+			if f.Seq != seq+1 {
+				return true, fmt.Errorf("expected batch %d, got %d", seq+1, f.Seq)
+			}
+			if err := store.ApplyChanges(f.Changes); err != nil {
+				return true, err
+			}
+			seq = f.Seq
+			s.mu.Lock()
+			s.status.Seq = seq
+			s.status.Applied = time.Now()
+			s.mu.Unlock()
+		case frameHeartbeat:
+		default:
+			return true, unexpected(f)
+		}
+		if err := c.send(&frame{Type: frameAck, Seq: seq}); err != nil {
+			return true, err
+		}
+	}
+}
+
+// receiveSnapshot writes the primary's snapshot beside BoltPath and, once
+// it is complete, swaps it in. A half-received snapshot never replaces
+// the copy already there.
+func (s *Standby) receiveSnapshot(c *conn) (seq uint64, size int64, err error) {
+	tmp := s.BoltPath + ".sync"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(tmp)
+		}
+	}()
+	for {
+		f, err := c.recv()
+		if err != nil {
+			return 0, 0, err
+		}
+		if f.Type == frameSnapshotEnd {
+			seq = f.Seq
+			break
+		}
+		if f.Type != frameSnapshot {
+			return 0, 0, unexpected(f)
+		}
+		n, err := out.Write(f.Data)
+		size += int64(n)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := out.Sync(); err != nil {
+		return 0, 0, err
+	}
+	if err := out.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	s.closeStore()
+	if err := os.Rename(tmp, s.BoltPath); err != nil {
+		return 0, 0, err
+	}
+	store, err := boltstore.Open(s.BoltPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	s.mu.Lock()
+	s.store = store
+	s.mu.Unlock()
+	return seq, size, nil
+}
+
+func (s *Standby) currentStore() *boltstore.Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store
+}
+
+func (s *Standby) closeStore() {
+	s.mu.Lock()
+	store := s.store
+	s.store = nil
+	s.mu.Unlock()
+	if store != nil {
+		store.Close()
+	}
+}
+
+func unexpected(f *frame) error {
+	if f.Type == frameError {
+		return errors.New("primary: " + f.Err)
+	}
+	return fmt.Errorf("unexpected frame type %d", f.Type)
+}
@@ -80,7 +80,12 @@ func (c *gameServerController) ConvertLegacyConfig(confPath string) ([]byte, err
 }
 
 func (c *gameServerController) GameName() string {
-	if c.game != nil && c.game.Conf != nil && c.game.Conf.MudName != "" {
+	if c.game == nil {
+		return "GoTinyMUSH"
+	}
+	c.game.Lock()
+	defer c.game.Unlock()
+	if c.game.Conf != nil && c.game.Conf.MudName != "" {
 		return c.game.Conf.MudName
 	}
 	return "GoTinyMUSH"
@@ -91,7 +96,12 @@ func (c *gameServerController) GameVersion() string {
 }
 
 func (c *gameServerController) GamePort() int {
-	if c.game != nil && c.game.Conf != nil && c.game.Conf.Port != 0 {
+	if c.game == nil {
+		return 0
+	}
+	c.game.Lock()
+	defer c.game.Unlock()
+	if c.game.Conf != nil && c.game.Conf.Port != 0 {
 		return c.game.Conf.Port
 	}
 	return 0
@@ -105,6 +115,8 @@ func (c *gameServerController) ConnectionStats() map[string]any {
 			"bytes_sent": 0, "bytes_recv": 0, "commands": 0,
 		}
 	}
+	c.game.Lock()
+	defer c.game.Unlock()
 	return c.game.ConnectionStats()
 }
 
@@ -131,6 +143,8 @@ func (c *gameServerController) GameStats() map[string]any {
 			"channels": 0, "mail_enabled": false, "user_functions": 0,
 		}
 	}
+	c.game.Lock()
+	defer c.game.Unlock()
 	return c.game.GameStats()
 }
 
@@ -139,6 +153,8 @@ func (c *gameServerController) WallAll(msg string) {
 	if c.game == nil || c.game.Conns == nil {
 		return
 	}
+	c.game.Lock()
+	defer c.game.Unlock()
 	for _, d := range c.game.Conns.AllDescriptors() {
 		if d.State == ConnConnected {
 			d.Send(msg)
@@ -152,6 +168,15 @@ func (c *gameServerController) CreateArchive() (string, error) {
 	if g == nil {
 		return "", fmt.Errorf("no game instance")
 	}
+	return archive.CreateArchive(c.archiveParams())
+}
+
+// archiveParams describes an archive of the game, read under the world
+// lock; the archive itself is written without it.
+func (c *gameServerController) archiveParams() archive.ArchiveParams {
+	g := c.game
+	g.Lock()
+	defer g.Unlock()
 
 	// Determine archive directory
 	archiveDir := ""
@@ -189,25 +214,17 @@ func (c *gameServerController) CreateArchive() (string, error) {
 		}
 	}
 
-	return archive.CreateArchive(params)
+	return params
 }
 
 // Shutdown disconnects all players and stops the server.
 func (c *gameServerController) Shutdown() {
 	c.running = false
 
-	// Disconnect all players
-	if c.game != nil && c.game.Conns != nil {
-		for _, d := range c.game.Conns.AllDescriptors() {
-			if d.State == ConnConnected {
-				c.game.DisconnectPlayer(d)
-			}
-			d.Close()
-		}
-	}
-
-	// Close listeners
-	if c.server != nil {
+	// Disconnect all players, commit queued writes and close listeners
+	if c.game != nil {
+		c.game.Shutdown(c.server, "")
+	} else if c.server != nil {
 		c.server.Stop()
 	}
 
@@ -219,3 +236,118 @@ func (c *gameServerController) Shutdown() {
 		os.Exit(0)
 	}()
 }
+
+// ReadConf re-reads the game config file, as @readconf does.
+func (c *gameServerController) ReadConf() (any, error) {
+	if c.game == nil {
+		return nil, fmt.Errorf("no game instance")
+	}
+	c.game.Lock()
+	defer c.game.Unlock()
+	return c.game.ReadConf()
+}
+
+// Archives lists the archives in the archive directory, newest first.
+func (c *gameServerController) Archives() ([]map[string]any, error) {
+	if c.game == nil {
+		return nil, fmt.Errorf("no game instance")
+	}
+	archiveDir := c.game.ArchiveDir
+	if archiveDir == "" {
+		archiveDir = "backups"
+	}
+	list, err := archive.ListArchives(archiveDir)
+	if err != nil {
+		return nil, err
+	}
+	out := []map[string]any{}
+	for _, ai := range list {
+		out = append(out, map[string]any{
+			"filename":  ai.Filename,
+			"size":      ai.Size,
+			"timestamp": ai.Timestamp,
+			"objects":   ai.Objects,
+			"encrypted": archive.IsEncrypted(ai.Path),
+		})
+	}
+	return out, nil
+}
+
+// RestoreObjects recovers objects from an archive, as @restore/object does.
+// Objects whose owner is gone are given to God.
+func (c *gameServerController) RestoreObjects(name string, from, to int, overwrite bool) (map[string]any, error) {
+	g := c.game
+	if g == nil {
+		return nil, fmt.Errorf("no game instance")
+	}
+	g.Lock()
+	defer g.Unlock()
+	res, err := g.RestoreObjects(name, gamedb.DBRef(from), gamedb.DBRef(to), overwrite, g.GodPlayer())
+	if err != nil {
+		return nil, err
+	}
+	restored := []map[string]any{}
+	for _, ref := range res.Restored {
+		restored = append(restored, map[string]any{"dbref": int(ref), "name": g.DB.Objects[ref].Name})
+	}
+	Logf(LogWizard, LevelInfo, "admin: restored %d object(s) #%d-#%d from %s", len(res.Restored), from, to, res.Archive)
+	return map[string]any{
+		"archive":  res.Archive,
+		"restored": restored,
+		"skipped":  append([]string{}, res.Skipped...),
+	}, nil
+}
+
+// PendingRegistrations lists character requests awaiting approval.
+func (c *gameServerController) PendingRegistrations() []map[string]any {
+	out := []map[string]any{}
+	if c.game == nil || c.game.Registrations == nil {
+		return out
+	}
+	for _, r := range c.game.Registrations.List() {
+		out = append(out, map[string]any{
+			"id":        r.ID,
+			"name":      r.Name,
+			"email":     r.Email,
+			"addr":      r.Addr,
+			"requested": r.Requested,
+		})
+	}
+	return out
+}
+
+// ApproveRegistration creates a requested character. The generated password
+// is returned only when it could not be emailed to the requester.
+func (c *gameServerController) ApproveRegistration(id int) (map[string]any, error) {
+	if c.game == nil {
+		return nil, fmt.Errorf("no game instance")
+	}
+	c.game.Lock()
+	defer c.game.Unlock()
+	ref, password, mailed, err := c.game.ApproveRegistration(id)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]any{
+		"dbref":  int(ref),
+		"name":   c.game.PlayerName(ref),
+		"mailed": mailed,
+	}
+	if !mailed {
+		result["password"] = password
+	}
+	return result, nil
+}
+
+// RejectRegistration drops a character request.
+func (c *gameServerController) RejectRegistration(id int) error {
+	if c.game == nil {
+		return fmt.Errorf("no game instance")
+	}
+	c.game.Lock()
+	defer c.game.Unlock()
+	if !c.game.RejectRegistration(id) {
+		return fmt.Errorf("no such registration")
+	}
+	return nil
+}
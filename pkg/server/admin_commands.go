@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -21,6 +22,9 @@ import (
 
 // --- Building Commands ---
 
+// cmdCreate implements @create <name>[=<cost>]. The cost, held between
+// create_min_cost and create_max_cost, is charged to the creator, and
+// the thing is worth a fifth of it.
 func cmdCreate(g *Game, d *Descriptor, args string, _ []string) {
 	if args == "" {
 		d.Send("Create what?")
@@ -29,8 +33,18 @@ func cmdCreate(g *Game, d *Descriptor, args string, _ []string) {
 	// @create name [= cost]
 	parts := strings.SplitN(args, "=", 2)
 	name := strings.TrimSpace(parts[0])
+	cost := 0
+	if len(parts) > 1 {
+		cost = toIntSimple(strings.TrimSpace(parts[1]))
+	}
+	cost = g.createCost(cost)
+	if !g.payFor(d.Player, cost) {
+		g.notEnoughMoney(d)
+		return
+	}
 	ref := g.CreateObject(name, gamedb.TypeThing, d.Player)
 	obj := g.DB.Objects[ref]
+	obj.Pennies = objectEndowment(cost)
 	// Place in player's inventory
 	playerObj := g.DB.Objects[d.Player]
 	obj.Location = d.Player
@@ -55,24 +69,45 @@ func cmdDestroy(g *Game, d *Descriptor, args string, switches []string) {
 		d.Send("No such object.")
 		return
 	}
-	// Check control
-	if !g.Controls(d.Player, target) {
+	if obj.HasFlag2(gamedb.Flag2GoingTwice) {
+		d.Send("That object has already been destroyed.")
+		return
+	}
+	// Check control; anyone carrying a DESTROY_OK object may destroy it
+	if !g.canDestroy(d.Player, target) {
 		d.Send("Permission denied.")
 		return
 	}
-	if obj.HasFlag(gamedb.FlagSafe) && !HasSwitch(switches, "override") {
+	// Players are always SAFE
+	if obj.ObjType() == gamedb.TypePlayer {
+		if !HasSwitch(switches, "override") {
+			d.Send("Players are always SAFE. Use @destroy/override to destroy a player.")
+			return
+		}
+		g.destroyPlayer(d, target)
+		return
+	}
+	if obj.HasFlag(gamedb.FlagSafe) && !obj.HasFlag(gamedb.FlagDestroyOK) && !HasSwitch(switches, "override") {
 		d.Send("That object is SAFE. Use @set to remove the SAFE flag first, or use @destroy/override.")
 		return
 	}
-	// Mark as GOING
-	obj.Flags[0] |= gamedb.FlagGoing
-	// Remove from location
-	if obj.Location != gamedb.Nothing {
-		g.RemoveFromContents(obj.Location, target)
+	switch obj.ObjType() {
+	case gamedb.TypeRoom:
+		// Rooms take two steps: the first @destroy marks the room GOING,
+		// a second one (or /instant) tears it down.
+		if !obj.IsGoing() && !HasSwitch(switches, "instant") {
+			obj.Flags[0] |= gamedb.FlagGoing
+			g.PersistObject(obj)
+			g.Conns.SendToRoom(g.DB, target, "The room shakes and begins to crumble.")
+			d.Send(fmt.Sprintf("%s(#%d) is marked GOING. @destroy it again to confirm, or @set it !GOING to cancel.", obj.Name, target))
+			return
+		}
+		g.destroyRoom(target)
+	case gamedb.TypeExit:
+		g.destroyExit(target)
+	default:
+		g.destroyThing(target)
 	}
-	// Clear location on the destroyed object
-	obj.Location = gamedb.Nothing
-	g.PersistObject(obj)
 	d.Send(fmt.Sprintf("Destroyed: %s(#%d)", obj.Name, target))
 }
 
@@ -89,13 +124,29 @@ func cmdLink(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send("I don't see that here.")
 		return
 	}
+	if strings.EqualFold(destStr, "variable") {
+		linkVariable(g, d, target)
+		return
+	}
 	dest := g.ResolveRef(d.Player, destStr)
 	if dest == gamedb.Nothing {
 		d.Send("I don't see that destination.")
 		return
 	}
+	if !Controls(g, d.Player, target) {
+		d.Send("Permission denied.")
+		return
+	}
+	if !g.canLinkTo(d.Player, dest) {
+		d.Send("You can't link to that.")
+		return
+	}
 	if obj, ok := g.DB.Objects[target]; ok {
 		if obj.ObjType() == gamedb.TypeExit {
+			if !g.payFor(d.Player, g.linkCost()) {
+				g.notEnoughMoney(d)
+				return
+			}
 			// For exits, destination is stored in Location
 			obj.Location = dest
 		} else {
@@ -107,6 +158,44 @@ func cmdLink(g *Game, d *Descriptor, args string, _ []string) {
 	}
 }
 
+// linkVariable links an exit to "variable": where it leads is worked out
+// from its ExitTo attribute each time it's used. Only Wizards and those
+// with the link_variable power may do this.
+func linkVariable(g *Game, d *Descriptor, target gamedb.DBRef) {
+	obj := g.DB.Objects[target]
+	if obj.ObjType() != gamedb.TypeExit {
+		d.Send("Only exits can be linked to variable.")
+		return
+	}
+	if !Controls(g, d.Player, target) || !g.canLinkVariable(d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	obj.Location = gamedb.Ambiguous
+	g.PersistObject(obj)
+	d.Send(fmt.Sprintf("Linked %s(#%d) to *VARIABLE*.", obj.Name, target))
+}
+
+// canLinkVariable reports whether player may link exits to "variable",
+// or have them lead anywhere: a Wizard or holder of link_variable.
+func (g *Game) canLinkVariable(player gamedb.DBRef) bool {
+	if Wizard(g, player) {
+		return true
+	}
+	obj, ok := g.DB.Objects[player]
+	return ok && obj.HasPower(1, gamedb.Pow2LinkVar)
+}
+
+// canLinkTo reports whether player may link an exit or home to dest: it
+// must control dest, or dest must be LINK_OK.
+func (g *Game) canLinkTo(player, dest gamedb.DBRef) bool {
+	obj, ok := g.DB.Objects[dest]
+	if !ok {
+		return false
+	}
+	return Controls(g, player, dest) || obj.HasFlag(gamedb.FlagLinkOK)
+}
+
 func cmdUnlink(g *Game, d *Descriptor, args string, _ []string) {
 	target := g.MatchObject(d.Player, args)
 	if target == gamedb.Nothing {
@@ -171,12 +260,12 @@ func (g *Game) PropagateParentAttrs(parent, child gamedb.DBRef) int {
 
 	// Build set of attr numbers the child already has
 	childAttrs := make(map[int]bool)
-	for _, attr := range childObj.Attrs {
+	for _, attr := range childObj.Attributes() {
 		childAttrs[attr.Number] = true
 	}
 
 	count := 0
-	for _, attr := range parentObj.Attrs {
+	for _, attr := range parentObj.Attributes() {
 		// Check if this attribute's definition has AF_PROPAGATE
 		def := g.LookupAttrDef(attr.Number)
 		if def == nil || def.Flags&gamedb.AFPropagate == 0 {
@@ -187,7 +276,7 @@ func (g *Game) PropagateParentAttrs(parent, child gamedb.DBRef) int {
 			continue
 		}
 		// Copy the attribute value from parent to child
-		childObj.Attrs = append(childObj.Attrs, gamedb.Attribute{
+		childObj.Attrs = append(childObj.Attributes(), gamedb.Attribute{
 			Number: attr.Number,
 			Value:  attr.Value,
 		})
@@ -247,8 +336,17 @@ func cmdClone(g *Game, d *Descriptor, args string, switches []string) {
 		newName = strings.TrimSpace(parts[1])
 	}
 
+	// A clone costs what the original would to make.
+	cost := g.objectCost(srcObj)
+	if !g.payFor(d.Player, cost) {
+		g.notEnoughMoney(d)
+		return
+	}
 	ref := g.CreateObject(newName, srcObj.ObjType(), d.Player)
 	newObj := g.DB.Objects[ref]
+	if srcObj.ObjType() == gamedb.TypeThing {
+		newObj.Pennies = objectEndowment(cost)
+	}
 
 	// /parent switch: set parent to the original instead of copying its parent
 	if HasSwitch(switches, "parent") {
@@ -263,8 +361,8 @@ func cmdClone(g *Game, d *Descriptor, args string, switches []string) {
 
 	// Copy attributes (unless /parent, where we inherit from parent chain)
 	if !HasSwitch(switches, "parent") {
-		for _, attr := range srcObj.Attrs {
-			newObj.Attrs = append(newObj.Attrs, gamedb.Attribute{
+		for _, attr := range srcObj.Attributes() {
+			newObj.Attrs = append(newObj.Attributes(), gamedb.Attribute{
 				Number: attr.Number,
 				Value:  attr.Value,
 			})
@@ -301,16 +399,20 @@ func cmdWipe(g *Game, d *Descriptor, args string, _ []string) {
 	if !ok {
 		return
 	}
+	if !Controls(g, d.Player, target) {
+		d.Send("Permission denied.")
+		return
+	}
 
 	if pattern == "*" {
-		count := len(obj.Attrs)
-		obj.Attrs = nil
+		count := len(obj.Attributes())
+		obj.SetAttributes(nil)
 		g.PersistObject(obj)
 		d.Send(fmt.Sprintf("Wiped %d attributes from %s(#%d).", count, obj.Name, target))
 	} else {
 		var remaining []gamedb.Attribute
 		count := 0
-		for _, attr := range obj.Attrs {
+		for _, attr := range obj.Attributes() {
 			name := g.DB.GetAttrName(attr.Number)
 			if name != "" && wildMatchSimple(pattern, strings.ToUpper(name)) {
 				count++
@@ -318,7 +420,7 @@ func cmdWipe(g *Game, d *Descriptor, args string, _ []string) {
 				remaining = append(remaining, attr)
 			}
 		}
-		obj.Attrs = remaining
+		obj.SetAttributes(remaining)
 		g.PersistObject(obj)
 		d.Send(fmt.Sprintf("Wiped %d attributes matching %s from %s(#%d).", count, pattern, obj.Name, target))
 	}
@@ -349,12 +451,18 @@ func cmdLock(g *Game, d *Descriptor, args string, switches []string) {
 		lockAttrNum = aLEnter // A_LENTER = 59
 	} else if HasSwitch(switches, "leave") || HasSwitch(switches, "leavelock") {
 		lockAttrNum = aLLeave // A_LLEAVE = 60
+	} else if HasSwitch(switches, "page") || HasSwitch(switches, "pagelock") {
+		lockAttrNum = aLPage // A_LPAGE = 61
 	} else if HasSwitch(switches, "use") || HasSwitch(switches, "uselock") {
 		lockAttrNum = aLUse // A_LUSE = 62
 	} else if HasSwitch(switches, "give") || HasSwitch(switches, "givelock") {
 		lockAttrNum = aLGive // A_LGIVE = 63
 	} else if HasSwitch(switches, "receive") || HasSwitch(switches, "receivelock") {
 		lockAttrNum = aLRecv // A_LRECEIVE = 87
+	} else if HasSwitch(switches, "tportlock") {
+		lockAttrNum = aLTport // A_LTPORT = 85
+	} else if HasSwitch(switches, "teloutlock") {
+		lockAttrNum = aLTelOut // A_LTELOUT = 94
 	}
 	// Parse lock expression at set time to resolve names (me, here, etc.) to dbrefs.
 	// This matches C TinyMUSH behavior where lock keys are stored as parsed boolexps.
@@ -383,12 +491,18 @@ func cmdUnlock(g *Game, d *Descriptor, args string, switches []string) {
 		lockAttrNum = aLEnter // A_LENTER = 59
 	} else if HasSwitch(switches, "leave") || HasSwitch(switches, "leavelock") {
 		lockAttrNum = aLLeave // A_LLEAVE = 60
+	} else if HasSwitch(switches, "page") || HasSwitch(switches, "pagelock") {
+		lockAttrNum = aLPage // A_LPAGE = 61
 	} else if HasSwitch(switches, "use") || HasSwitch(switches, "uselock") {
 		lockAttrNum = aLUse // A_LUSE = 62
 	} else if HasSwitch(switches, "give") || HasSwitch(switches, "givelock") {
 		lockAttrNum = aLGive // A_LGIVE = 63
 	} else if HasSwitch(switches, "receive") || HasSwitch(switches, "receivelock") {
 		lockAttrNum = aLRecv // A_LRECEIVE = 87
+	} else if HasSwitch(switches, "tportlock") {
+		lockAttrNum = aLTport // A_LTPORT = 85
+	} else if HasSwitch(switches, "teloutlock") {
+		lockAttrNum = aLTelOut // A_LTELOUT = 94
 	}
 	g.SetAttr(target, lockAttrNum, "")
 	d.Send("Unlocked.")
@@ -438,7 +552,7 @@ func (g *Game) lockAttrInstance(d *Descriptor, args string, lock bool) {
 		return
 	}
 
-	for i, attr := range obj.Attrs {
+	for i, attr := range obj.Attributes() {
 		if attr.Number == attrNum {
 			info := ParseAttrInfo(attr.Value)
 			text := eval.StripAttrPrefix(attr.Value)
@@ -451,7 +565,7 @@ func (g *Game) lockAttrInstance(d *Descriptor, args string, lock bool) {
 			} else {
 				info.Flags &^= gamedb.AFLock
 			}
-			obj.Attrs[i].Value = fmt.Sprintf("\x01%d:%d:%s", owner, info.Flags, text)
+			obj.Attributes()[i].Value = fmt.Sprintf("\x01%d:%d:%s", owner, info.Flags, text)
 			g.PersistObject(obj)
 			if lock {
 				d.Send("Attribute locked.")
@@ -594,23 +708,47 @@ func cmdTeleport(g *Game, d *Descriptor, args string, _ []string) {
 		functions.RegisterAll(c)
 	})
 
-	var victim gamedb.DBRef
-	var destStr string
-
 	if eqIdx := strings.IndexByte(args, '='); eqIdx >= 0 {
 		victimStr := ctx.Exec(strings.TrimSpace(args[:eqIdx]), eval.EvFCheck|eval.EvEval, nil)
-		destStr = ctx.Exec(strings.TrimSpace(args[eqIdx+1:]), eval.EvFCheck|eval.EvEval, nil)
+		destStr := ctx.Exec(strings.TrimSpace(args[eqIdx+1:]), eval.EvFCheck|eval.EvEval, nil)
+		g.teleport(d, victimStr, destStr)
+	} else {
+		g.teleport(d, "", ctx.Exec(strings.TrimSpace(args), eval.EvFCheck|eval.EvEval, nil))
+	}
+}
+
+// teleport does the work of @tel once its arguments are evaluated. An
+// empty victimStr teleports the enactor.
+func (g *Game) teleport(d *Descriptor, victimStr, destStr string) {
+	victim := d.Player
+	if victimStr != "" {
 		victim = g.MatchObject(d.Player, victimStr)
 		if victim == gamedb.Nothing {
 			d.Send("I don't see that here.")
 			return
 		}
-	} else {
-		victim = d.Player
-		destStr = ctx.Exec(strings.TrimSpace(args), eval.EvFCheck|eval.EvEval, nil)
 	}
 
-	if strings.EqualFold(destStr, "home") {
+	// FIXED objects stay put unless the enactor can teleport anything.
+	goingHome := strings.EqualFold(destStr, "home")
+	if Fixed(g, d.Player) && victim == d.Player {
+		if goingHome {
+			d.Send(g.fixedHomeMessage())
+		} else {
+			d.Send(g.fixedTelMessage())
+		}
+		return
+	}
+	if victim != d.Player && Fixed(g, victim) && !TelAnything(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	if !Controls(g, d.Player, victim) && !TelAnything(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+
+	if goingHome {
 		if obj, ok := g.DB.Objects[victim]; ok {
 			destStr = fmt.Sprintf("#%d", obj.Link)
 		}
@@ -621,46 +759,29 @@ func cmdTeleport(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send("I don't see that destination.")
 		return
 	}
+	if destObj, ok := g.DB.Objects[dest]; ok && !goingHome && !Controls(g, d.Player, dest) &&
+		!destObj.HasFlag(gamedb.FlagJumpOK) && !TelAnything(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
 
-	// Find descriptor for victim (if connected)
-	descs := g.Conns.GetByPlayer(victim)
-
-	// Remove from old location
-	if obj, ok := g.DB.Objects[victim]; ok {
-		oldLoc := obj.Location
-		isDark := obj.HasFlag(gamedb.FlagDark)
-		if oldLoc != gamedb.Nothing {
-			g.RemoveFromContents(oldLoc, victim)
-			if !isDark {
-				g.Conns.SendToRoomExcept(g.DB, oldLoc, victim,
-					fmt.Sprintf("%s has left.", DisplayName(obj.Name)))
-			}
-		}
-		obj.Location = dest
-		g.AddToContents(dest, victim)
-		persistList := []*gamedb.Object{obj}
-		if destObj, ok := g.DB.Objects[dest]; ok {
-			persistList = append(persistList, destObj)
-		}
-		if oldLoc != gamedb.Nothing {
-			if oldLocObj, ok := g.DB.Objects[oldLoc]; ok {
-				persistList = append(persistList, oldLocObj)
-			}
-		}
-		g.PersistObjects(persistList...)
-		if !isDark {
-			g.Conns.SendToRoomExcept(g.DB, dest, victim,
-				fmt.Sprintf("%s has arrived.", DisplayName(obj.Name)))
+	if g.locatedIn(dest, victim) {
+		d.Send("Bad destination.")
+		return
+	}
+	// A JUMP_OK destination's TportLock says who may teleport in.
+	if !goingHome && !Controls(g, d.Player, dest) && !TelAnything(g, d.Player) && !CouldDoIt(g, victim, dest, aLTport) {
+		g.moveDidIt(victim, dest, aTFail, aOTFail, aATFail, false)
+		if g.GetAttrText(dest, aTFail) == "" {
+			d.Send("Permission denied.")
 		}
+		return
 	}
-
-	if victim == d.Player {
-		g.ShowRoom(d, dest)
-	} else {
+	if !g.MoveObject(victim, dest, move{cause: d.Player, exit: gamedb.Nothing, tport: true}) {
+		return
+	}
+	if victim != d.Player {
 		d.Send(fmt.Sprintf("Teleported %s to %s(#%d).", g.ObjName(victim), g.ObjName(dest), dest))
-		if len(descs) > 0 {
-			g.ShowRoom(descs[0], dest)
-		}
 	}
 }
 
@@ -693,9 +814,10 @@ func cmdTriggerCmd(g *Game, d *Descriptor, args string, switches []string) {
 	d.Send("Triggered.")
 }
 
-func cmdWaitCmd(g *Game, d *Descriptor, args string, _ []string) {
-	g.DoWait(d.Player, d.Player, args)
-	d.Send("Queued.")
+func cmdWaitCmd(g *Game, d *Descriptor, args string, switches []string) {
+	if g.DoWait(d.Player, d.Player, args, switches) {
+		d.Send("Queued.")
+	}
 }
 
 func cmdNotify(g *Game, d *Descriptor, args string, _ []string) {
@@ -755,19 +877,76 @@ func cmdHalt(g *Game, d *Descriptor, args string, switches []string) {
 	d.Send(fmt.Sprintf("Halted. %d command(s) removed from queue.", removed))
 }
 
-func cmdBoot(g *Game, d *Descriptor, args string, _ []string) {
-	target := LookupPlayer(g.DB, strings.TrimSpace(args))
+// canBootPlayer reports whether player may boot victim, as in TinyMUSH:
+// God can never be booted, and a wizard only by another wizard, however
+// the booter came by POW_BOOT.
+func canBootPlayer(g *Game, player, victim gamedb.DBRef) bool {
+	if IsGod(g, victim) {
+		return false
+	}
+	return !Wizard(g, victim) || Wizard(g, player)
+}
+
+// cmdBoot disconnects a player, or with /port a single connection by its
+// descriptor number (as shown by SESSION). /quiet skips telling the victim.
+func cmdBoot(g *Game, d *Descriptor, args string, switches []string) {
+	if !CanBoot(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	args = strings.TrimSpace(args)
+	quiet := HasSwitch(switches, "quiet")
+
+	if HasSwitch(switches, "port") {
+		port, err := strconv.Atoi(args)
+		if err != nil {
+			d.Send("That's not a number!")
+			return
+		}
+		var victim *Descriptor
+		for _, dd := range g.Conns.AllDescriptors() {
+			if dd.ID == port {
+				victim = dd
+				break
+			}
+		}
+		if victim == nil {
+			d.Send("No such port.")
+			return
+		}
+		if victim.State == ConnConnected && !canBootPlayer(g, d.Player, victim.Player) {
+			d.Send("You cannot boot that player!")
+			return
+		}
+		if !quiet {
+			victim.Send("You have been booted.")
+		}
+		Logf(LogWizard, LevelInfo, "[%d] Port booted by %s(#%d)", victim.ID, g.PlayerName(d.Player), d.Player)
+		victim.DisconnectReason = "booted"
+		g.DisconnectPlayer(victim)
+		d.Send(fmt.Sprintf("Booted port %d.", port))
+		return
+	}
+
+	target := LookupPlayer(g.DB, args)
 	if target == gamedb.Nothing {
 		d.Send("No such player.")
 		return
 	}
+	if !canBootPlayer(g, d.Player, target) {
+		d.Send("You cannot boot that player!")
+		return
+	}
 	descs := g.Conns.GetByPlayer(target)
 	if len(descs) == 0 {
 		d.Send("That player is not connected.")
 		return
 	}
 	for _, dd := range descs {
-		dd.Send("You have been booted.")
+		if !quiet {
+			dd.Send("You have been booted.")
+		}
+		dd.DisconnectReason = "booted"
 		g.DisconnectPlayer(dd)
 	}
 	d.Send(fmt.Sprintf("Booted %s.", g.ObjName(target)))
@@ -784,6 +963,7 @@ func cmdWall(g *Game, d *Descriptor, args string, _ []string) {
 			dd.Send(msg)
 		}
 	}
+	g.wizardAlert("@wall from %s: %s", name, args)
 }
 
 // cmdFixDB repairs the contents chain for a location by rebuilding it from
@@ -857,100 +1037,298 @@ func cmdNewPassword(g *Game, d *Descriptor, args string, _ []string) {
 	d.Send(fmt.Sprintf("Password for %s changed.", g.ObjName(target)))
 }
 
-func cmdFind(g *Game, d *Descriptor, args string, _ []string) {
-	if args == "" {
-		d.Send("Find what?")
-		return
+// objStats counts objects by type. Going objects are counted apart from
+// garbage; @dbck turns them into garbage.
+type objStats struct {
+	total, rooms, exits, things, players, going, garbage int
+}
+
+// add counts obj.
+func (st *objStats) add(obj *gamedb.Object) {
+	st.total++
+	switch {
+	case obj.ObjType() == gamedb.TypeGarbage:
+		st.garbage++
+	case obj.IsGoing():
+		st.going++
+	case obj.ObjType() == gamedb.TypeRoom:
+		st.rooms++
+	case obj.ObjType() == gamedb.TypeExit:
+		st.exits++
+	case obj.ObjType() == gamedb.TypePlayer:
+		st.players++
+	default:
+		st.things++
 	}
-	pattern := strings.ToLower(strings.TrimSpace(args))
-	count := 0
+}
+
+// String formats st as C TinyMUSH's @stats breakdown line.
+func (st *objStats) String() string {
+	return fmt.Sprintf("%d objects = %d rooms, %d exits, %d things, %d players. (%d going, %d garbage)",
+		st.total, st.rooms, st.exits, st.things, st.players, st.going, st.garbage)
+}
+
+// statsByOwner counts every object, and each owner's objects.
+func (g *Game) statsByOwner() (objStats, map[gamedb.DBRef]*objStats) {
+	var all objStats
+	owners := make(map[gamedb.DBRef]*objStats)
 	for _, obj := range g.DB.Objects {
-		if obj.IsGoing() {
+		all.add(obj)
+		if obj.ObjType() == gamedb.TypeGarbage {
 			continue
 		}
-		if wildMatchSimple(pattern, strings.ToLower(obj.Name)) {
-			d.Send(fmt.Sprintf("  %s(#%d%s) Owner: %s(#%d)",
-				obj.Name, obj.DBRef, typeChar(obj.ObjType()),
-				g.ObjName(obj.Owner), obj.Owner))
-			count++
-			if count >= 200 {
-				d.Send("*** Too many results, truncated ***")
-				break
-			}
+		st := owners[obj.Owner]
+		if st == nil {
+			st = &objStats{}
+			owners[obj.Owner] = st
 		}
+		st.add(obj)
 	}
-	d.Send(fmt.Sprintf("%d object(s) found.", count))
+	return all, owners
 }
 
-func cmdStats(g *Game, d *Descriptor, _ string, _ []string) {
-	rooms, things, exits, players, garbage := 0, 0, 0, 0, 0
-	for _, obj := range g.DB.Objects {
-		switch obj.ObjType() {
-		case gamedb.TypeRoom:
-			rooms++
-		case gamedb.TypeThing:
-			things++
-		case gamedb.TypeExit:
-			exits++
-		case gamedb.TypePlayer:
-			if obj.IsGoing() {
-				garbage++
-			} else {
-				players++
-			}
-		case gamedb.TypeGarbage:
-			garbage++
-		default:
-			if obj.IsGoing() {
-				garbage++
-			} else {
-				things++
-			}
-		}
+// quotaLine describes player's quota: objects owned against QUOTA, if set.
+func (g *Game) quotaLine(player gamedb.DBRef, st *objStats) string {
+	used := st.rooms + st.exits + st.things
+	total := g.GetAttrTextDirect(player, 49) // A_QUOTA
+	if !isNumeric(total) {
+		return fmt.Sprintf("Quota: %d used, no quota set.", used)
 	}
-	d.Send(fmt.Sprintf("Database statistics:"))
-	d.Send(fmt.Sprintf("  %d rooms, %d things, %d exits, %d players, %d garbage",
-		rooms, things, exits, players, garbage))
-	d.Send(fmt.Sprintf("  %d total objects", len(g.DB.Objects)))
-	d.Send(fmt.Sprintf("  %d attribute definitions", len(g.DB.AttrNames)))
-	imm, wait, sem := g.Queue.Stats()
-	d.Send(fmt.Sprintf("  Queue: %d immediate, %d waiting, %d semaphore", imm, wait, sem))
-	d.Send(fmt.Sprintf("  %d active connections", g.Conns.Count()))
+	return fmt.Sprintf("Quota: %d of %d used, %d remaining.", used, toIntSimple(total), toIntSimple(total)-used)
 }
 
-func cmdPs(g *Game, d *Descriptor, _ string, switches []string) {
-	imm, wait, sem := g.Queue.Stats()
-	d.Send(fmt.Sprintf("Queue: %d immediate, %d waiting, %d semaphore", imm, wait, sem))
-	d.Send(fmt.Sprintf("Total: %d entries", imm+wait+sem))
+// statsTopOwners is how many owners @stats/all ranks.
+const statsTopOwners = 10
 
-	if HasSwitch(switches, "all") {
-		entries := g.Queue.Peek(50)
-		if len(entries) == 0 {
-			d.Send("(no entries)")
+// cmdStats implements @stats[/all] [<player>]. Plain @stats is free; a
+// per-player breakdown or /all costs search_cost. Another player's counts
+// need control of them or the stat_any power, as does the /all ranking.
+func cmdStats(g *Game, d *Descriptor, args string, switches []string) {
+	args = strings.TrimSpace(args)
+	statAny := Wizard(g, d.Player)
+	if o, ok := g.DB.Objects[d.Player]; ok && o.HasPower(0, gamedb.PowStatAny) {
+		statAny = true
+	}
+
+	if args == "" && !HasSwitch(switches, "all") {
+		all, _ := g.statsByOwner()
+		d.Send(fmt.Sprintf("The universe contains %d objects (next free is #%d).", all.total, g.NextRef))
+		d.Send("  " + all.String())
+		d.Send(fmt.Sprintf("  %d attribute definitions", len(g.DB.AttrNames)))
+		imm, wait, sem := g.Queue.Stats()
+		d.Send(fmt.Sprintf("  Queue: %d immediate, %d waiting, %d semaphore", imm, wait, sem))
+		d.Send(fmt.Sprintf("  %d active connections", g.Conns.Count()))
+		return
+	}
+
+	target := gamedb.Nothing
+	if strings.EqualFold(args, "me") {
+		target = d.Player
+	} else if args != "" && !strings.EqualFold(args, "all") {
+		if target = LookupPlayer(g.DB, args); target == gamedb.Nothing {
+			d.Send("No such player.")
 			return
 		}
-		for i, e := range entries {
-			name := g.PlayerName(e.Player)
-			cmd := e.Command
-			if len(cmd) > 60 {
-				cmd = cmd[:60] + "..."
-			}
-			qtype := "imm"
-			if !e.WaitUntil.IsZero() {
-				qtype = "wait"
-			}
-			if e.SemObj >= 0 {
-				qtype = "sem"
+		if !statAny && !g.Controls(d.Player, target) {
+			d.Send("Permission denied.")
+			return
+		}
+	}
+	if !g.payFor(d.Player, g.searchCost()) {
+		g.notEnoughMoney(d)
+		return
+	}
+
+	all, owners := g.statsByOwner()
+	if target != gamedb.Nothing {
+		st := owners[target]
+		if st == nil {
+			st = &objStats{}
+		}
+		d.Send(fmt.Sprintf("%s(#%d) owns %s", g.PlayerName(target), target, st))
+		d.Send(g.quotaLine(target, st))
+		return
+	}
+
+	d.Send(all.String())
+	if !statAny {
+		return
+	}
+	ranked := make([]gamedb.DBRef, 0, len(owners))
+	for owner := range owners {
+		ranked = append(ranked, owner)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := owners[ranked[i]], owners[ranked[j]]
+		if a.total != b.total {
+			return a.total > b.total
+		}
+		return ranked[i] < ranked[j]
+	})
+	if len(ranked) > statsTopOwners {
+		ranked = ranked[:statsTopOwners]
+	}
+	d.Send("")
+	d.Send(fmt.Sprintf("%-24s %7s %7s %7s %7s %7s", "Top Owners", "Total", "Rooms", "Exits", "Things", "Players"))
+	for _, owner := range ranked {
+		st := owners[owner]
+		d.Send(fmt.Sprintf("%-24.24s %7d %7d %7d %7d %7d", fmt.Sprintf("%s(#%d)", g.ObjName(owner), owner),
+			st.total, st.rooms, st.exits, st.things, st.players))
+	}
+}
+
+// psPageSize is how many queue entries @ps lists per page.
+const psPageSize = 50
+
+// cmdPs implements @ps[/brief|/long|/summary|/all] [<object|player>][=<page>].
+// Without /all it lists only entries run by things the target (default: you)
+// owns; naming an object you don't control, or /all, takes See_Queue.
+func cmdPs(g *Game, d *Descriptor, args string, switches []string) {
+	targetStr, pageStr, _ := strings.Cut(args, "=")
+	targetStr = strings.TrimSpace(targetStr)
+	page := 1
+	if pageStr = strings.TrimSpace(pageStr); pageStr != "" {
+		n, err := strconv.Atoi(pageStr)
+		if err != nil || n < 1 {
+			d.Send("Page must be a positive number.")
+			return
+		}
+		page = n
+	}
+
+	var match func(*QueueEntry) bool
+	switch {
+	case HasSwitch(switches, "all"):
+		if !SeeQueue(g, d.Player) {
+			d.Send("Permission denied.")
+			return
+		}
+		match = func(*QueueEntry) bool { return true }
+	case targetStr != "":
+		target := g.MatchObject(d.Player, targetStr)
+		if target == gamedb.Nothing {
+			target = LookupPlayer(g.DB, targetStr)
+		}
+		if target == gamedb.Nothing {
+			d.Send("I don't see that here.")
+			return
+		}
+		if !Controls(g, d.Player, target) && !SeeQueue(g, d.Player) {
+			d.Send("Permission denied.")
+			return
+		}
+		if g.DB.Objects[target].ObjType() == gamedb.TypePlayer {
+			match = func(e *QueueEntry) bool { return ResolveOwner(g, e.Player) == target }
+		} else {
+			match = func(e *QueueEntry) bool { return e.Player == target }
+		}
+	default:
+		owner := ResolveOwner(g, d.Player)
+		match = func(e *QueueEntry) bool { return ResolveOwner(g, e.Player) == owner }
+	}
+
+	entries := g.Queue.Matching(match)
+	var imm, wait, sem []*QueueEntry
+	for _, e := range entries {
+		switch {
+		case e.SemAttr > 0:
+			sem = append(sem, e)
+		case !e.WaitUntil.IsZero():
+			wait = append(wait, e)
+		default:
+			imm = append(imm, e)
+		}
+	}
+	totImm, totWait, totSem := g.Queue.Stats()
+	totals := fmt.Sprintf("Totals: Queue...%d/%d  Wait...%d/%d  Semaphore...%d/%d",
+		len(imm), totImm, len(wait), totWait, len(sem), totSem)
+	if HasSwitch(switches, "summary") {
+		d.Send(totals)
+		return
+	}
+
+	pages := (len(entries) + psPageSize - 1) / psPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if page > pages {
+		d.Send(fmt.Sprintf("There are only %d page(s).", pages))
+		return
+	}
+	first, last := (page-1)*psPageSize, page*psPageSize
+	long := HasSwitch(switches, "long")
+	now := g.Now()
+	shown := 0
+	for _, section := range []struct {
+		title string
+		list  []*QueueEntry
+	}{{"Player Queue", imm}, {"Wait Queue", wait}, {"Semaphore Queue", sem}} {
+		d.Send(fmt.Sprintf("----- %s -----", section.title))
+		for _, e := range section.list {
+			if shown >= first && shown < last {
+				g.showQueueEntry(d, e, long, now)
 			}
-			d.Send(fmt.Sprintf("  [%d] %s player=%s(#%d) cmd=%s", i+1, qtype, name, e.Player, cmd))
+			shown++
+		}
+	}
+	d.Send(totals)
+	if pages > 1 {
+		d.Send(fmt.Sprintf("Page %d of %d. Use @ps %s=<page> for more.", page, pages, targetStr))
+	}
+}
+
+// showQueueEntry prints one @ps line: [pid], the wait time or semaphore,
+// the object and the command. long adds the enactor, when a wait is due,
+// the semaphore attribute and the %0-%9 stack.
+func (g *Game) showQueueEntry(d *Descriptor, e *QueueEntry, long bool, now time.Time) {
+	when := ""
+	if !e.WaitUntil.IsZero() {
+		secs := int(e.WaitUntil.Sub(now).Round(time.Second).Seconds())
+		if secs < 0 {
+			secs = 0
+		}
+		when = fmt.Sprintf("[%d]", secs)
+	}
+	if e.SemAttr > 0 {
+		when = fmt.Sprintf("[#%d/%s]%s", e.SemObj, g.queueAttrName(e.SemAttr), when)
+	}
+	cmd := e.Command
+	if !long && len(cmd) > 60 {
+		cmd = cmd[:60] + "..."
+	}
+	d.Send(fmt.Sprintf("[%d]%s%s(#%d):%s", e.PID, when, g.ObjName(e.Player), e.Player, cmd))
+	if !long {
+		return
+	}
+	d.Send(fmt.Sprintf("      Enactor: %s(#%d)", g.ObjName(e.Cause), e.Cause))
+	if !e.WaitUntil.IsZero() {
+		d.Send(fmt.Sprintf("      Runs at: %s", e.WaitUntil.Format("Mon Jan _2 15:04:05 2006")))
+	}
+	if e.SemAttr > 0 {
+		d.Send(fmt.Sprintf("      Semaphore: %s(#%d)/%s", g.ObjName(e.SemObj), e.SemObj, g.queueAttrName(e.SemAttr)))
+	}
+	if len(e.Args) > 0 {
+		args := make([]string, len(e.Args))
+		for i, a := range e.Args {
+			args[i] = fmt.Sprintf("%%%d='%s'", i, a)
 		}
+		d.Send("      Args: " + strings.Join(args, " "))
 	}
 }
 
+// queueAttrName names a semaphore attribute, or gives its number if unknown.
+func (g *Game) queueAttrName(num int) string {
+	if def := g.LookupAttrDef(num); def != nil {
+		return def.Name
+	}
+	return fmt.Sprintf("%d", num)
+}
+
 // --- Softcode Commands ---
 
-func cmdSwitch(g *Game, d *Descriptor, args string, _ []string) {
+func cmdSwitch(g *Game, d *Descriptor, args string, switches []string) {
 	// @switch expr = pattern1, action1 [, pattern2, action2, ...] [, default]
+	// /all runs every matching action instead of only the first.
 	eqIdx := strings.IndexByte(args, '=')
 	if eqIdx < 0 {
 		d.Send("Usage: @switch expression = pattern1, action1, ...")
@@ -968,6 +1346,8 @@ func cmdSwitch(g *Game, d *Descriptor, args string, _ []string) {
 	parts := splitCommaRespectingBraces(rest)
 
 	// Walk pattern/action pairs
+	matchAll := HasSwitch(switches, "all")
+	matched := false
 	for i := 0; i+1 < len(parts); i += 2 {
 		pattern := ctx.Exec(strings.TrimSpace(parts[i]), eval.EvFCheck|eval.EvEval, nil)
 		if wildMatchSimple(strings.ToLower(pattern), strings.ToLower(expr)) {
@@ -977,11 +1357,14 @@ func cmdSwitch(g *Game, d *Descriptor, args string, _ []string) {
 			raw := stripOuterBraces(strings.TrimSpace(parts[i+1]))
 			raw = strings.ReplaceAll(raw, "#$", expr)
 			dispatchSwitchActionDesc(g, d, raw)
-			return
+			if !matchAll {
+				return
+			}
+			matched = true
 		}
 	}
 	// Default (odd trailing entry)
-	if len(parts)%2 == 1 {
+	if len(parts)%2 == 1 && !matched {
 		raw := stripOuterBraces(strings.TrimSpace(parts[len(parts)-1]))
 		raw = strings.ReplaceAll(raw, "#$", expr)
 		dispatchSwitchActionDesc(g, d, raw)
@@ -1233,6 +1616,10 @@ func cmdSet(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send("Permission denied.")
 		return
 	}
+	if wizardSetFlags[strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(value), "!"))] && !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
 	if g.SetFlag(target, value) {
 		d.Send("Set.")
 	} else {
@@ -1291,7 +1678,7 @@ func (g *Game) setAttrFlag(d *Descriptor, target gamedb.DBRef, attrName string,
 	}
 
 	// Find the attribute and modify its flags
-	for i, attr := range obj.Attrs {
+	for i, attr := range obj.Attributes() {
 		if attr.Number == attrNum {
 			info := ParseAttrInfo(attr.Value)
 			text := eval.StripAttrPrefix(attr.Value)
@@ -1304,7 +1691,7 @@ func (g *Game) setAttrFlag(d *Descriptor, target gamedb.DBRef, attrName string,
 			} else {
 				info.Flags |= bit
 			}
-			obj.Attrs[i].Value = fmt.Sprintf("\x01%d:%d:%s", owner, info.Flags, text)
+			obj.Attributes()[i].Value = fmt.Sprintf("\x01%d:%d:%s", owner, info.Flags, text)
 			g.PersistObject(obj)
 			d.Send("Set.")
 			return
@@ -1471,7 +1858,7 @@ func cmdBackup(g *Game, d *Descriptor, args string, _ []string) {
 	d.Send(fmt.Sprintf("Backing up database to %s...", path))
 	go func() {
 		if err := g.Store.Backup(path); err != nil {
-			log.Printf("ERROR: Backup failed: %v", err)
+			Logf(LogBugs, LevelError, "Backup failed: %v", err)
 			g.Conns.SendToPlayer(d.Player, fmt.Sprintf("Backup failed: %v", err))
 		} else {
 			log.Printf("Backup complete: %s", path)
@@ -1551,7 +1938,7 @@ func cmdDolist(g *Game, d *Descriptor, args string, switches []string) {
 
 // --- Communication Commands ---
 
-func cmdOemit(g *Game, d *Descriptor, args string, _ []string) {
+func cmdOemit(g *Game, d *Descriptor, args string, switches []string) {
 	// @oemit target = message — emits to target's room, excluding target
 	eqIdx := strings.IndexByte(args, '=')
 	if eqIdx < 0 {
@@ -1571,11 +1958,15 @@ func cmdOemit(g *Game, d *Descriptor, args string, _ []string) {
 	if loc == gamedb.Nothing {
 		loc = g.PlayerLocation(d.Player)
 	}
-	message = evalExpr(g, d.Player, message)
-	g.SendMarkedToRoomExcept(loc, target, "EMIT", message)
+	if !g.canEmitIn(d.Player, loc) {
+		d.Send("You are too far away to do that.")
+		return
+	}
+	message = evalMessage(g, d.Player, message, switches)
+	g.SendEmitToRoomExcept(loc, target, d.Player, d.Player, message)
 }
 
-func cmdRemit(g *Game, d *Descriptor, args string, _ []string) {
+func cmdRemit(g *Game, d *Descriptor, args string, switches []string) {
 	// @remit room = message
 	eqIdx := strings.IndexByte(args, '=')
 	if eqIdx < 0 {
@@ -1590,8 +1981,12 @@ func cmdRemit(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send("I don't see that here.")
 		return
 	}
-	message = evalExpr(g, d.Player, message)
-	g.SendMarkedToRoom(room, "EMIT", message)
+	if !g.canEmitIn(d.Player, room) {
+		d.Send("You are too far away to do that.")
+		return
+	}
+	message = evalMessage(g, d.Player, message, switches)
+	g.SendEmitToRoom(room, d.Player, d.Player, message)
 }
 
 // --- Builder/Admin Utilities ---
@@ -1682,63 +2077,6 @@ func formatUptime(start time.Time) string {
 	return fmt.Sprintf("Uptime: %dh %dm %ds (since %s)", hours, mins, secs, start.Format("2006-01-02 15:04:05"))
 }
 
-func cmdMotd(g *Game, d *Descriptor, args string, switches []string) {
-	if HasSwitch(switches, "wizard") {
-		if !Wizard(g, d.Player) { d.Send("Permission denied."); return }
-		if args == "" {
-			if g.WizMOTD != "" { d.Send(g.WizMOTD) } else { d.Send("No wizard MOTD set.") }
-		} else {
-			g.WizMOTD = args
-			d.Send("Wizard MOTD set.")
-		}
-		return
-	}
-	if HasSwitch(switches, "down") {
-		if !Wizard(g, d.Player) { d.Send("Permission denied."); return }
-		if args == "" {
-			if g.DownMOTD != "" { d.Send(g.DownMOTD) } else { d.Send("No down MOTD set.") }
-		} else {
-			g.DownMOTD = args
-			d.Send("Down MOTD set.")
-		}
-		return
-	}
-	if HasSwitch(switches, "full") {
-		if !Wizard(g, d.Player) { d.Send("Permission denied."); return }
-		if args == "" {
-			if g.FullMOTD != "" { d.Send(g.FullMOTD) } else { d.Send("No full MOTD set.") }
-		} else {
-			g.FullMOTD = args
-			d.Send("Full MOTD set.")
-		}
-		return
-	}
-
-	if args == "" {
-		// Show current MOTD
-		if g.MOTD != "" {
-			d.Send(g.MOTD)
-		} else if g.Texts != nil {
-			motd := g.Texts.GetMotd()
-			if motd != "" {
-				d.Send(motd)
-			} else {
-				d.Send("No message of the day.")
-			}
-		} else {
-			d.Send("No message of the day.")
-		}
-		return
-	}
-	// Wizard-only: set MOTD
-	if !Wizard(g, d.Player) {
-		d.Send("Permission denied.")
-		return
-	}
-	g.MOTD = args
-	d.Send("MOTD set.")
-}
-
 func cmdChzone(g *Game, d *Descriptor, args string, switches []string) {
 	// @chzone obj = zone
 	eqIdx := strings.IndexByte(args, '=')
@@ -1830,60 +2168,6 @@ func cmdChzone(g *Game, d *Descriptor, args string, switches []string) {
 	}
 }
 
-func cmdSearch(g *Game, d *Descriptor, args string, _ []string) {
-	// @search [type=TYPE] [name=PATTERN]
-	var typeFilter gamedb.ObjectType = -1
-	var namePattern string
-
-	for _, part := range strings.Fields(args) {
-		if eqIdx := strings.IndexByte(part, '='); eqIdx >= 0 {
-			key := strings.ToLower(part[:eqIdx])
-			val := part[eqIdx+1:]
-			switch key {
-			case "type":
-				switch strings.ToLower(val) {
-				case "room", "rooms":
-					typeFilter = gamedb.TypeRoom
-				case "thing", "things":
-					typeFilter = gamedb.TypeThing
-				case "exit", "exits":
-					typeFilter = gamedb.TypeExit
-				case "player", "players":
-					typeFilter = gamedb.TypePlayer
-				}
-			case "name":
-				namePattern = strings.ToLower(val)
-			}
-		} else if namePattern == "" {
-			namePattern = strings.ToLower(part)
-		}
-	}
-
-	count := 0
-	for _, obj := range g.DB.Objects {
-		if obj.IsGoing() {
-			continue
-		}
-		if typeFilter >= 0 && obj.ObjType() != typeFilter {
-			continue
-		}
-		if namePattern != "" && !wildMatchSimple(namePattern, strings.ToLower(obj.Name)) {
-			continue
-		}
-		// Only show objects the player owns (or all if wizard)
-		if !g.Controls(d.Player, obj.DBRef) {
-			continue
-		}
-		d.Send(fmt.Sprintf("  %s(#%d%s)", obj.Name, obj.DBRef, typeChar(obj.ObjType())))
-		count++
-		if count >= 200 {
-			d.Send("*** Too many results, truncated ***")
-			break
-		}
-	}
-	d.Send(fmt.Sprintf("%d object(s) found.", count))
-}
-
 // decompileAttrCmd maps well-known attribute numbers to their @-command names.
 // Attrs listed here are output as "@Command obj=value" in @decompile.
 // Attrs not listed here and >= A_USER_START use "&ATTR obj=value" format.
@@ -2026,7 +2310,7 @@ func cmdDecompile(g *Game, d *Descriptor, args string, _ []string) {
 	}
 
 	// Show attributes
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		name := g.DB.GetAttrName(attr.Number)
 		if name == "" {
 			name = fmt.Sprintf("ATTR_%d", attr.Number)
@@ -2120,10 +2404,16 @@ func (g *Game) StartAutoSave(intervalMinutes int) {
 				continue
 			}
 			log.Printf("Auto-saving database...")
-			if err := flatfile.Save(g.DBPath, g.DB); err != nil {
-				log.Printf("ERROR: Auto-save failed: %v", err)
+			var err error
+			var n int
+			g.Do(func() {
+				err = flatfile.Save(g.DBPath, g.DB)
+				n = len(g.DB.Objects)
+			})
+			if err != nil {
+				Logf(LogBugs, LevelError, "Auto-save failed: %v", err)
 			} else {
-				log.Printf("Auto-save complete: %d objects", len(g.DB.Objects))
+				log.Printf("Auto-save complete: %d objects", n)
 			}
 		}
 	}()
@@ -2197,9 +2487,8 @@ func cmdSQL(g *Game, d *Descriptor, args string, _ []string) {
 	}
 
 	trimmed := strings.TrimSpace(args)
-	upper := strings.ToUpper(trimmed)
 
-	if strings.HasPrefix(upper, "SELECT") {
+	if sqlReturnsRows(trimmed) {
 		// SELECT: show row-by-row field display
 		result, err := g.SQLDB.Query(trimmed, "\n", "\x01")
 		if err != nil {
@@ -2353,6 +2642,7 @@ func cmdFunction(g *Game, d *Descriptor, args string, switches []string) {
 			}
 			if _, ok := g.GameFuncs[funcName]; ok {
 				delete(g.GameFuncs, funcName)
+				g.deleteRuntime(gamedb.RuntimeFunction, funcName)
 				d.Send(fmt.Sprintf("Function %s deleted.", funcName))
 			} else {
 				d.Send(fmt.Sprintf("No @function named %s.", funcName))
@@ -2389,6 +2679,7 @@ func cmdFunction(g *Game, d *Descriptor, args string, switches []string) {
 	if objAttr == "" {
 		if _, ok := g.GameFuncs[funcName]; ok {
 			delete(g.GameFuncs, funcName)
+			g.deleteRuntime(gamedb.RuntimeFunction, funcName)
 			d.Send(fmt.Sprintf("Function %s deleted.", funcName))
 		} else {
 			d.Send(fmt.Sprintf("No @function named %s.", funcName))
@@ -2433,7 +2724,9 @@ func cmdFunction(g *Game, d *Descriptor, args string, switches []string) {
 		Flags: flags,
 	}
 	g.GameFuncs[funcName] = uf
-	log.Printf("@function %s = #%d/%s (flags=%d)", funcName, target, attrName, flags)
+	g.putRuntime(&gamedb.RuntimeSetting{Kind: gamedb.RuntimeFunction, Name: funcName, Obj: target, Attr: attrNum,
+		Flags: flags, Setter: d.Player, Changed: g.Now()})
+	Logf(LogWizard, LevelInfo, "@function %s = #%d/%s (flags=%d)", funcName, target, attrName, flags)
 	d.Send(fmt.Sprintf("Function %s defined.", funcName))
 }
 
@@ -2518,6 +2811,7 @@ func cmdArchive(g *Game, d *Descriptor, args string, switches []string) {
 
 	// Bolt snapshot closure
 	if g.Store != nil {
+		g.SaveQueue()
 		params.BoltSnapshotFunc = func(dest string) error {
 			return g.Store.Backup(dest)
 		}
@@ -2532,29 +2826,22 @@ func cmdArchive(g *Game, d *Descriptor, args string, switches []string) {
 	}
 
 	d.Send("Creating archive...")
+	conf := g.confCopy()
 	go func() {
 		archivePath, err := archive.CreateArchive(params)
 		if err != nil {
-			log.Printf("ERROR: Archive failed: %v", err)
+			Logf(LogBugs, LevelError, "Archive failed: %v", err)
+			g.Conns.SendToPlayer(d.Player, fmt.Sprintf("Archive failed: %v", err))
+			return
+		}
+		archivePath, err = finishArchive(conf, archivePath, archiveDir)
+		if err != nil {
+			Logf(LogBugs, LevelError, "Archive failed: %v", err)
 			g.Conns.SendToPlayer(d.Player, fmt.Sprintf("Archive failed: %v", err))
 			return
 		}
 		log.Printf("Archive created: %s", archivePath)
 		g.Conns.SendToPlayer(d.Player, fmt.Sprintf("Archive created: %s", archivePath))
-
-		// Prune old archives
-		retain := 0
-		if g.Conf != nil {
-			retain = g.Conf.ArchiveRetain
-		}
-		if retain > 0 {
-			pruneArchives(archiveDir, retain)
-		}
-
-		// Run post-archive hook
-		if hook := g.archiveHook(); hook != "" {
-			runArchiveHook(hook, archivePath)
-		}
 	}()
 }
 
@@ -2592,29 +2879,41 @@ func (g *Game) StartAutoArchive(intervalMinutes int) {
 	if intervalMinutes < 1 {
 		return
 	}
+	interval := time.Duration(intervalMinutes) * time.Minute
+	g.nextArchive = g.Now().Add(interval)
 	go func() {
-		ticker := time.NewTicker(time.Duration(intervalMinutes) * time.Minute)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for range ticker.C {
-			archiveDir := g.ArchiveDir
-			if archiveDir == "" {
-				archiveDir = "backups"
-			}
+			// Read what the archive needs under the world lock; the
+			// archive itself is written without it.
+			var params archive.ArchiveParams
+			var archiveDir string
+			var conf *GameConf
+			g.Do(func() {
+				g.nextArchive = g.Now().Add(interval)
+				archiveDir = g.ArchiveDir
+				if archiveDir == "" {
+					archiveDir = "backups"
+				}
 
-			mudName := "GoTinyMUSH"
-			if g.Conf != nil && g.Conf.MudName != "" {
-				mudName = g.Conf.MudName
-			}
+				mudName := "GoTinyMUSH"
+				if g.Conf != nil && g.Conf.MudName != "" {
+					mudName = g.Conf.MudName
+				}
 
-			params := archive.ArchiveParams{
-				ArchiveDir:  archiveDir,
-				MudName:     mudName,
-				ObjectCount: len(g.DB.Objects),
-				DictDir:     g.DictDir,
-				TextDir:     g.TextDir,
-				ConfPath:    g.ConfPath,
-				AliasConfs:  g.AliasConfs,
-			}
+				params = archive.ArchiveParams{
+					ArchiveDir:  archiveDir,
+					MudName:     mudName,
+					ObjectCount: len(g.DB.Objects),
+					DictDir:     g.DictDir,
+					TextDir:     g.TextDir,
+					ConfPath:    g.ConfPath,
+					AliasConfs:  g.AliasConfs,
+				}
+				conf = g.confCopy()
+				g.SaveQueue()
+			})
 			if g.Store != nil {
 				params.BoltSnapshotFunc = func(dest string) error {
 					return g.Store.Backup(dest)
@@ -2630,26 +2929,93 @@ func (g *Game) StartAutoArchive(intervalMinutes int) {
 			log.Printf("Auto-archive starting...")
 			archivePath, err := archive.CreateArchive(params)
 			if err != nil {
-				log.Printf("ERROR: Auto-archive failed: %v", err)
+				Logf(LogBugs, LevelError, "Auto-archive failed: %v", err)
 				continue
 			}
-			log.Printf("Auto-archive complete: %s", archivePath)
-
-			retain := 0
-			if g.Conf != nil {
-				retain = g.Conf.ArchiveRetain
-			}
-			if retain > 0 {
-				pruneArchives(archiveDir, retain)
-			}
-
-			if hook := g.archiveHook(); hook != "" {
-				runArchiveHook(hook, archivePath)
+			archivePath, err = finishArchive(conf, archivePath, archiveDir)
+			if err != nil {
+				Logf(LogBugs, LevelError, "Auto-archive failed: %v", err)
+				continue
 			}
+			log.Printf("Auto-archive complete: %s", archivePath)
 		}
 	}()
 }
 
+// finishArchive encrypts a new archive if archive_passphrase or
+// archive_recipients is set, prunes old ones, uploads it to
+// archive_remote and runs the post-archive hook. It returns the final
+// archive path. Only a failed encryption is an error; the unencrypted
+// archive is removed then, so nothing unencrypted is kept by mistake.
+// Remote failures are logged and leave the local archive in place. conf is
+// a copy of the game config (see confCopy), since archives are finished
+// without the world lock.
+func finishArchive(conf *GameConf, archivePath, archiveDir string) (string, error) {
+	if conf == nil {
+		return archivePath, nil
+	}
+	enc := archive.EncryptOptions{Passphrase: conf.ArchivePassphrase, Recipients: conf.ArchiveRecipients}
+	if enc.Enabled() {
+		encrypted, err := archive.EncryptArchive(archivePath, enc)
+		if err != nil {
+			os.Remove(archivePath)
+			return "", err
+		}
+		archivePath = encrypted
+	}
+
+	if conf.ArchiveRetain > 0 {
+		pruneArchives(archiveDir, conf.ArchiveRetain)
+	}
+
+	if conf.ArchiveRemote != "" {
+		uploadArchive(conf, archivePath)
+	}
+
+	if hook := archiveHook(conf); hook != "" {
+		runArchiveHook(hook, archivePath)
+	}
+	return archivePath, nil
+}
+
+// uploadArchive copies an archive to archive_remote and applies
+// archive_remote_retain (or archive_retain) there.
+func uploadArchive(gc *GameConf, archivePath string) {
+	remote, err := archive.NewRemote(archive.RemoteConfig{
+		URL:            gc.ArchiveRemote,
+		S3Endpoint:     gc.ArchiveS3Endpoint,
+		S3Region:       gc.ArchiveS3Region,
+		S3AccessKey:    gc.ArchiveS3AccessKey,
+		S3SecretKey:    gc.ArchiveS3SecretKey,
+		SFTPPassword:   gc.ArchiveSFTPPassword,
+		SFTPKeyFile:    gc.ArchiveSFTPKey,
+		SFTPKnownHosts: gc.ArchiveSFTPKnownHosts,
+	})
+	if err != nil {
+		Logf(LogBugs, LevelError, "Archive upload: %v", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	if err := remote.Upload(ctx, archivePath); err != nil {
+		Logf(LogBugs, LevelError, "Archive upload: %v", err)
+		return
+	}
+	log.Printf("Archive uploaded to %s", remote)
+
+	keep := gc.ArchiveRemoteRetain
+	if keep == 0 {
+		keep = gc.ArchiveRetain
+	}
+	pruned, err := archive.PruneRemote(ctx, remote, keep)
+	for _, name := range pruned {
+		log.Printf("Pruned old remote archive: %s", name)
+	}
+	if err != nil {
+		log.Printf("WARNING: prune remote archives: %v", err)
+	}
+}
+
 // pruneArchives deletes old archives beyond the keep count.
 func pruneArchives(dir string, keep int) {
 	if keep <= 0 {
@@ -2694,7 +3060,7 @@ func runArchiveHook(command, archivePath string) {
 
 // cmdAdmin implements @admin param=value for runtime configuration.
 // Wizard-only. Maps TinyMUSH config param names to GameConf fields.
-func cmdAdmin(g *Game, d *Descriptor, args string, _ []string) {
+func cmdAdmin(g *Game, d *Descriptor, args string, switches []string) {
 	if !Wizard(g, d.Player) {
 		d.Send("Permission denied.")
 		return
@@ -2703,11 +3069,28 @@ func cmdAdmin(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send("No game configuration loaded.")
 		return
 	}
+	save := HasSwitch(switches, "save")
+	if HasSwitch(switches, "list") {
+		adminList(g, d)
+		return
+	}
 
 	eqIdx := strings.IndexByte(args, '=')
 	if eqIdx < 0 {
+		param := strings.ToLower(strings.TrimSpace(args))
+		if HasSwitch(switches, "clear") {
+			if param == "" {
+				d.Send("Usage: @admin/clear param")
+				return
+			}
+			adminClear(g, d, param)
+			return
+		}
+		if save {
+			adminSave(g, d, param)
+			return
+		}
 		// Show a param value
-		param := strings.TrimSpace(args)
 		if param == "" {
 			d.Send("Usage: @admin param=value")
 			return
@@ -2721,7 +3104,7 @@ func cmdAdmin(g *Game, d *Descriptor, args string, _ []string) {
 		return
 	}
 
-	param := strings.TrimSpace(args[:eqIdx])
+	param := strings.ToLower(strings.TrimSpace(args[:eqIdx]))
 	value := strings.TrimSpace(args[eqIdx+1:])
 
 	ok := setAdminParam(g.Conf, param, value)
@@ -2730,7 +3113,14 @@ func cmdAdmin(g *Game, d *Descriptor, args string, _ []string) {
 		return
 	}
 	d.Send(fmt.Sprintf("Set: %s = %s", param, value))
-	log.Printf("@admin: %s set %s = %s", g.DB.Objects[d.Player].Name, param, value)
+	Logf(LogWizard, LevelInfo, "@admin: %s set %s = %s", g.DB.Objects[d.Player].Name, param, value)
+	if g.adminUnsaved == nil {
+		g.adminUnsaved = make(map[string]*gamedb.RuntimeSetting)
+	}
+	g.adminUnsaved[param] = &gamedb.RuntimeSetting{Kind: gamedb.RuntimeAdmin, Name: param, Value: value, Setter: d.Player, Changed: g.Now()}
+	if save {
+		adminSave(g, d, param)
+	}
 }
 
 // adminParamMap maps TinyMUSH @admin parameter names to get/set closures.
@@ -2749,10 +3139,33 @@ func getAdminParam(c *GameConf, param string) (string, bool) {
 		return strconv.Itoa(c.EarnLimit), true
 	case "page_cost":
 		return strconv.Itoa(c.PageCost), true
+	case "page_rate_limit":
+		return strconv.Itoa(c.PageRateLimit), true
+	case "page_requires_equals":
+		if c.PageRequiresEquals { return "1", true }
+		return "0", true
 	case "wait_cost":
 		return strconv.Itoa(c.WaitCost), true
 	case "link_cost":
 		return strconv.Itoa(c.LinkCost), true
+	case "search_cost":
+		return strconv.Itoa(c.SearchCost), true
+	case "dig_cost":
+		return strconv.Itoa(c.DigCost), true
+	case "open_cost":
+		return strconv.Itoa(c.OpenCost), true
+	case "create_min_cost":
+		return strconv.Itoa(c.CreateMin), true
+	case "create_max_cost":
+		return strconv.Itoa(c.CreateMax), true
+	case "robot_cost":
+		return strconv.Itoa(c.RobotCost), true
+	case "kill_min":
+		return strconv.Itoa(c.KillMin), true
+	case "kill_max":
+		return strconv.Itoa(c.KillMax), true
+	case "kill_guarantee":
+		return strconv.Itoa(c.KillGuarantee), true
 	case "machine_command_cost":
 		return strconv.Itoa(c.MachineCommandCost), true
 	case "trace_topdown":
@@ -2760,16 +3173,78 @@ func getAdminParam(c *GameConf, param string) (string, bool) {
 		return "0", true
 	case "trace_output_limit":
 		return strconv.Itoa(c.TraceOutputLimit), true
+	case "puppet_same_room":
+		if c.PuppetSameRoom { return "1", true }
+		return "0", true
+	case "local_master_rooms":
+		if c.LocalMasterRooms { return "1", true }
+		return "0", true
+	case "robot_speech":
+		if c.RobotSpeech { return "1", true }
+		return "0", true
+	case "who_data":
+		return strings.Join(c.WhoData, " "), true
+	case "use_global_aconn":
+		if c.GlobalAconn { return "1", true }
+		return "0", true
+	case "room_aconnect":
+		if c.RoomAconnect { return "1", true }
+		return "0", true
+	case "global_aconn_uselocks":
+		if c.GlobalAconnUselocks { return "1", true }
+		return "0", true
+	case "login_who":
+		if c.LoginWho { return "1", true }
+		return "0", true
 	case "idle_timeout":
 		return strconv.Itoa(c.IdleTimeout), true
+	case "idle_message_time":
+		return strconv.Itoa(c.IdleMessageTime), true
 	case "output_limit":
 		return strconv.Itoa(c.OutputLimit), true
+	case "conn_rate_limit":
+		return strconv.Itoa(c.ConnRateLimit), true
+	case "login_fail_limit":
+		return strconv.Itoa(c.LoginFailLimit), true
+	case "login_fail_window":
+		return strconv.Itoa(c.LoginFailWindow), true
+	case "scene_retention":
+		return strconv.Itoa(c.SceneRetention), true
+	case "registration_required":
+		if c.RegistrationRequired { return "1", true }
+		return "0", true
 	case "function_invocation_limit":
 		return strconv.Itoa(c.FunctionInvocationLimit), true
+	case "function_recursion_limit":
+		return strconv.Itoa(c.FunctionRecursionLimit), true
+	case "function_cpu_limit":
+		return strconv.Itoa(c.FunctionCPULimit), true
+	case "float_precision":
+		return strconv.Itoa(c.FloatPrecision), true
+	case "struct_limit":
+		return strconv.Itoa(c.StructLimit), true
+	case "instance_limit":
+		return strconv.Itoa(c.InstanceLimit), true
+	case "register_limit":
+		return strconv.Itoa(c.RegisterLimit), true
 	case "queue_idle_chunk":
 		return strconv.Itoa(c.QueueIdleChunk), true
+	case "player_queue_limit":
+		return strconv.Itoa(c.PlayerQueueLimit), true
+	case "wizard_queue_limit":
+		return strconv.Itoa(c.WizardQueueLimit), true
+	case "queue_owner_rate":
+		return strconv.Itoa(c.QueueOwnerRate), true
+	case "events_daily_hour":
+		return strconv.Itoa(c.EventsDailyHour), true
 	case "mud_name":
 		return c.MudName, true
+	case "fixed_home_message":
+		return c.FixedHomeMessage, true
+	case "fixed_tel_message":
+		return c.FixedTelMessage, true
+	case "program_prompt":
+		return c.ProgramPrompt, true
 	case "master_room":
 		return strconv.Itoa(c.MasterRoom), true
 	case "player_starting_room":
@@ -2799,6 +3274,12 @@ func getAdminParam(c *GameConf, param string) (string, bool) {
 	case "debug":
 		if IsDebug() { return "1", true }
 		return "0", true
+	case "log":
+		return strings.Join(enabledLogCategories(), " "), true
+	case "log_level":
+		return c.LogLevel, true
+	case "log_format":
+		return c.LogFormat, true
 	default:
 		return "", false
 	}
@@ -2826,26 +3307,108 @@ func setAdminParam(c *GameConf, param, value string) bool {
 		c.EarnLimit, _ = strconv.Atoi(value); return true
 	case "page_cost":
 		c.PageCost, _ = strconv.Atoi(value); return true
+	case "page_rate_limit":
+		c.PageRateLimit, _ = strconv.Atoi(value); return true
+	case "page_requires_equals":
+		c.PageRequiresEquals = parseBoolAdmin(value, negate); return true
 	case "wait_cost":
 		c.WaitCost, _ = strconv.Atoi(value); return true
 	case "link_cost":
 		c.LinkCost, _ = strconv.Atoi(value); return true
+	case "search_cost":
+		c.SearchCost, _ = strconv.Atoi(value); return true
+	case "dig_cost":
+		c.DigCost, _ = strconv.Atoi(value); return true
+	case "open_cost":
+		c.OpenCost, _ = strconv.Atoi(value); return true
+	case "create_min_cost":
+		c.CreateMin, _ = strconv.Atoi(value); return true
+	case "create_max_cost":
+		c.CreateMax, _ = strconv.Atoi(value); return true
+	case "robot_cost":
+		c.RobotCost, _ = strconv.Atoi(value); return true
+	case "kill_min":
+		c.KillMin, _ = strconv.Atoi(value); return true
+	case "kill_max":
+		c.KillMax, _ = strconv.Atoi(value); return true
+	case "kill_guarantee":
+		c.KillGuarantee, _ = strconv.Atoi(value); return true
 	case "machine_command_cost":
 		c.MachineCommandCost, _ = strconv.Atoi(value); return true
 	case "trace_topdown":
 		c.TraceTopdown = parseBoolAdmin(value, negate); return true
 	case "trace_output_limit":
 		c.TraceOutputLimit, _ = strconv.Atoi(value); return true
+	case "puppet_same_room":
+		c.PuppetSameRoom = parseBoolAdmin(value, negate); return true
+	case "local_master_rooms":
+		c.LocalMasterRooms = parseBoolAdmin(value, negate); return true
+	case "robot_speech":
+		c.RobotSpeech = parseBoolAdmin(value, negate); return true
+	case "login_who":
+		c.LoginWho = parseBoolAdmin(value, negate); return true
+	case "use_global_aconn":
+		c.GlobalAconn = parseBoolAdmin(value, negate); return true
+	case "room_aconnect":
+		c.RoomAconnect = parseBoolAdmin(value, negate); return true
+	case "global_aconn_uselocks":
+		c.GlobalAconnUselocks = parseBoolAdmin(value, negate); return true
+	case "who_data":
+		cols := strings.Fields(strings.ToLower(value))
+		for _, col := range cols {
+			if _, ok := whoColumns[col]; !ok {
+				return false
+			}
+		}
+		c.WhoData = cols; return true
 	case "idle_timeout":
 		c.IdleTimeout, _ = strconv.Atoi(value); return true
+	case "idle_message_time":
+		c.IdleMessageTime, _ = strconv.Atoi(value); return true
 	case "output_limit":
 		c.OutputLimit, _ = strconv.Atoi(value); return true
+	case "conn_rate_limit":
+		c.ConnRateLimit, _ = strconv.Atoi(value); return true
+	case "login_fail_limit":
+		c.LoginFailLimit, _ = strconv.Atoi(value); return true
+	case "login_fail_window":
+		c.LoginFailWindow, _ = strconv.Atoi(value); return true
+	case "scene_retention":
+		c.SceneRetention, _ = strconv.Atoi(value); return true
+	case "registration_required":
+		c.RegistrationRequired = parseBoolAdmin(value, negate); return true
 	case "function_invocation_limit":
 		c.FunctionInvocationLimit, _ = strconv.Atoi(value); return true
+	case "float_precision":
+		c.FloatPrecision, _ = strconv.Atoi(value); return true
+	case "struct_limit":
+		c.StructLimit, _ = strconv.Atoi(value); return true
+	case "instance_limit":
+		c.InstanceLimit, _ = strconv.Atoi(value); return true
+	case "register_limit":
+		c.RegisterLimit, _ = strconv.Atoi(value); return true
 	case "queue_idle_chunk":
 		c.QueueIdleChunk, _ = strconv.Atoi(value); return true
+	case "player_queue_limit":
+		c.PlayerQueueLimit, _ = strconv.Atoi(value); return true
+	case "wizard_queue_limit":
+		c.WizardQueueLimit, _ = strconv.Atoi(value); return true
+	case "queue_owner_rate":
+		c.QueueOwnerRate, _ = strconv.Atoi(value); return true
+	case "events_daily_hour":
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 23 {
+			c.EventsDailyHour = n
+			return true
+		}
+		return false
 	case "mud_name":
 		c.MudName = value; return true
+	case "fixed_home_message":
+		c.FixedHomeMessage = value; return true
+	case "fixed_tel_message":
+		c.FixedTelMessage = value; return true
+	case "program_prompt":
+		c.ProgramPrompt = value; return true
 	case "master_room":
 		c.MasterRoom, _ = strconv.Atoi(value); return true
 	case "player_starting_room":
@@ -2868,7 +3431,30 @@ func setAdminParam(c *GameConf, param, value string) bool {
 		c.ReadRemoteName = parseBoolAdmin(value, negate); return true
 	case "log":
 		// @admin log=all_commands / @admin log=!all_commands
-		// Currently a no-op placeholder; TinyMUSH uses this for log configuration
+		name := strings.ToLower(value)
+		if _, ok := legacyLogOptions[name]; !ok && name != "all" {
+			return false
+		}
+		if negate {
+			name = "!" + name
+		}
+		c.Log = append(enabledLogCategories(), name)
+		ConfigureLogging(c)
+		c.Log = enabledLogCategories()
+		return true
+	case "log_level":
+		if _, ok := parseLogLevel(value); !ok {
+			return false
+		}
+		c.LogLevel = strings.ToLower(value)
+		ConfigureLogging(c)
+		return true
+	case "log_format":
+		if !strings.EqualFold(value, "text") && !strings.EqualFold(value, "json") {
+			return false
+		}
+		c.LogFormat = strings.ToLower(value)
+		ConfigureLogging(c)
 		return true
 	case "debug":
 		SetDebug(parseBoolAdmin(value, negate))
@@ -2890,12 +3476,12 @@ func parseBoolAdmin(value string, negate bool) bool {
 }
 
 // archiveHook returns the configured archive hook command, with env override.
-func (g *Game) archiveHook() string {
+func archiveHook(conf *GameConf) string {
 	if v := os.Getenv("MUSH_ARCHIVE_HOOK"); v != "" {
 		return v
 	}
-	if g.Conf != nil {
-		return g.Conf.ArchiveHook
+	if conf != nil {
+		return conf.ArchiveHook
 	}
 	return ""
 }
@@ -3180,7 +3766,7 @@ func countAttrsOnObjects(g *Game, player gamedb.DBRef, objType int, isWiz bool)
 		if !isWiz && !Controls(g, player, obj.DBRef) {
 			continue
 		}
-		for _, attr := range obj.Attrs {
+		for _, attr := range obj.Attributes() {
 			counts[attr.Number]++
 		}
 	}
@@ -3202,7 +3788,7 @@ func findObjectsWithAttr(g *Game, player gamedb.DBRef, attrNum int, objType int,
 		if !isWiz && !Controls(g, player, obj.DBRef) {
 			continue
 		}
-		for _, attr := range obj.Attrs {
+		for _, attr := range obj.Attributes() {
 			if attr.Number == attrNum {
 				results = append(results, obj.DBRef)
 				if maxResults > 0 && len(results) >= maxResults {
@@ -3438,7 +4024,7 @@ func cmdAttributePropagate(g *Game, d *Descriptor, args string) {
 		}
 		// Check if object already has this attribute
 		hasIt := false
-		for _, attr := range obj.Attrs {
+		for _, attr := range obj.Attributes() {
 			if attr.Number == attrNum {
 				hasIt = true
 				break
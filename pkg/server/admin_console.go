@@ -0,0 +1,117 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/admin"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// consoleBuffer is how many lines of output a console holds for a slow
+// reader before it starts dropping them.
+const consoleBuffer = 256
+
+// adminConsole is a live admin-panel session in the game: a virtual
+// descriptor logged in as a wizard, whose output goes to a channel.
+type adminConsole struct {
+	game *Game
+	d    *Descriptor
+
+	mu     sync.Mutex
+	out    chan string
+	closed bool
+}
+
+// OpenConsole logs a virtual descriptor in as the named wizard for the
+// admin panel's live console.
+func (c *gameServerController) OpenConsole(wizard string) (admin.Console, error) {
+	g := c.game
+	if g == nil {
+		return nil, fmt.Errorf("no game instance")
+	}
+	g.Lock()
+	defer g.Unlock()
+	player := LookupPlayer(g.DB, wizard)
+	if player == gamedb.Nothing {
+		return nil, fmt.Errorf("no player named %q", wizard)
+	}
+	if !Wizard(g, player) {
+		return nil, fmt.Errorf("%s is not a wizard", g.PlayerName(player))
+	}
+	con := &adminConsole{game: g, out: make(chan string, consoleBuffer)}
+	con.d = &Descriptor{
+		ID:        g.Conns.NextID(),
+		Conn:      nullConn{},
+		State:     ConnConnected,
+		Player:    player,
+		Addr:      "admin console",
+		ConnTime:  time.Now(),
+		LastCmd:   time.Now(),
+		Transport: TransportWebSocket,
+		SendFunc:  con.send,
+	}
+	g.Conns.Add(con.d)
+	g.Conns.Login(con.d, player)
+	Logf(LogWizard, LevelInfo, "admin console opened as %s(#%d)", g.PlayerName(player), player)
+	return con, nil
+}
+
+// RecentLog returns up to the last n lines written to the server log.
+func (c *gameServerController) RecentLog(n int) []string {
+	return recentLog.Lines(n)
+}
+
+// send queues a line of output, dropping it if nobody is keeping up.
+func (con *adminConsole) send(msg string) {
+	con.mu.Lock()
+	defer con.mu.Unlock()
+	if con.closed {
+		return
+	}
+	select {
+	case con.out <- msg:
+	default:
+	}
+}
+
+func (con *adminConsole) Player() string {
+	con.game.Lock()
+	defer con.game.Unlock()
+	return fmt.Sprintf("%s(#%d)", con.game.PlayerName(con.d.Player), con.d.Player)
+}
+
+func (con *adminConsole) Run(command string) {
+	con.game.Lock()
+	defer con.game.Unlock()
+	con.d.LastCmd = con.game.Now()
+	con.game.logInput(con.d, command)
+	DispatchCommand(con.game, con.d, command)
+}
+
+func (con *adminConsole) Output() <-chan string {
+	return con.out
+}
+
+// Close logs the console's wizard out as a dropped connection would:
+// ADISCONNECT fires and the room hears about it if the wizard was seen.
+func (con *adminConsole) Close() {
+	con.mu.Lock()
+	if con.closed {
+		con.mu.Unlock()
+		return
+	}
+	// Output sent during the teardown is dropped, so the channel can close
+	// before we wait on the world lock.
+	con.closed = true
+	close(con.out)
+	con.mu.Unlock()
+
+	g := con.game
+	g.Do(func() {
+		g.DisconnectPlayer(con.d)
+		g.Conns.Remove(con.d)
+		Logf(LogWizard, LevelInfo, "admin console closed for %s(#%d)", g.PlayerName(con.d.Player), con.d.Player)
+	})
+}
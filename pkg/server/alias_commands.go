@@ -0,0 +1,245 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// LoadConfAliases applies aliases added with @aliases, read from
+// storage, on top of those from the alias config. An in-game alias
+// replaces a config alias of the same name.
+func (g *Game) LoadConfAliases(aliases []gamedb.ConfAlias) {
+	g.ConfAliases = make(map[string]*gamedb.ConfAlias)
+	for i := range aliases {
+		a := aliases[i]
+		if err := g.applyConfAlias(&a); err != nil {
+			log.Printf("@aliases: %s alias %q -> %q: %v", a.Kind, a.Name, a.Target, err)
+			continue
+		}
+		g.ConfAliases[a.Kind+":"+a.Name] = &a
+	}
+}
+
+// reapplyConfAliases puts the @aliases entries back after the alias
+// config has been reloaded.
+func (g *Game) reapplyConfAliases() {
+	for _, a := range g.ConfAliases {
+		if err := g.applyConfAlias(a); err != nil {
+			log.Printf("@aliases: %s alias %q -> %q: %v", a.Kind, a.Name, a.Target, err)
+		}
+	}
+}
+
+// applyConfAlias makes an alias or bad name take effect.
+func (g *Game) applyConfAlias(a *gamedb.ConfAlias) error {
+	switch a.Kind {
+	case gamedb.AliasCommand:
+		return g.addCommandAlias(a.Name, a.Target)
+	case gamedb.AliasFunction:
+		if !builtinFunction(a.Target) {
+			return fmt.Errorf("no such function %q", a.Target)
+		}
+		if g.FuncAliases == nil {
+			g.FuncAliases = make(map[string]string)
+		}
+		g.FuncAliases[strings.ToUpper(a.Name)] = strings.ToUpper(a.Target)
+	case gamedb.AliasBadName:
+		for _, bad := range g.BadNames {
+			if bad == a.Name {
+				return nil
+			}
+		}
+		g.BadNames = append(g.BadNames, a.Name)
+	default:
+		return fmt.Errorf("unknown kind")
+	}
+	return nil
+}
+
+// builtinFunction reports whether name is a built-in softcode function.
+func builtinFunction(name string) bool {
+	_, ok := functions.Table()[strings.ToUpper(name)]
+	return ok
+}
+
+// cmdAliases implements @aliases, which manages command aliases,
+// function aliases and bad player names without editing alias.conf:
+//
+//	@aliases[/list]
+//	@aliases/command <alias>=<command>[/<switch>...]
+//	@aliases/function <alias>=<function>
+//	@aliases/badname <name pattern>
+//	@aliases/remove/<command|function|badname> <alias>
+func cmdAliases(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	kind := ""
+	switch {
+	case HasSwitch(switches, "command"):
+		kind = gamedb.AliasCommand
+	case HasSwitch(switches, "function"):
+		kind = gamedb.AliasFunction
+	case HasSwitch(switches, "badname"):
+		kind = gamedb.AliasBadName
+	}
+	if kind == "" {
+		if HasSwitch(switches, "remove") {
+			d.Send("@aliases: Say /command, /function or /badname with /remove.")
+			return
+		}
+		aliasList(g, d)
+		return
+	}
+
+	name, target, _ := strings.Cut(args, "=")
+	name, target = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(target)
+	if name == "" || strings.ContainsAny(name, " /") {
+		d.Send("@aliases: That's not a good alias name.")
+		return
+	}
+	if HasSwitch(switches, "remove") {
+		aliasRemove(g, d, kind, name)
+		return
+	}
+
+	a := &gamedb.ConfAlias{Kind: kind, Name: name, Target: target, Setter: d.Player, Created: time.Now()}
+	switch kind {
+	case gamedb.AliasCommand:
+		if target == "" {
+			d.Send("@aliases: Alias it to what command?")
+			return
+		}
+		if cmd, ok := g.Commands[name]; ok && g.CmdAliases[name] == "" {
+			d.Send(fmt.Sprintf("@aliases: %s is already a command.", cmd.Name))
+			return
+		}
+		a.Target = strings.ToLower(target)
+	case gamedb.AliasFunction:
+		if target == "" {
+			d.Send("@aliases: Alias it to what function?")
+			return
+		}
+		if builtinFunction(name) && g.FuncAliases[strings.ToUpper(name)] == "" {
+			d.Send(fmt.Sprintf("@aliases: %s() is already a function.", strings.ToUpper(name)))
+			return
+		}
+		a.Target = strings.ToLower(target)
+	case gamedb.AliasBadName:
+		a.Target = ""
+	}
+	if err := g.applyConfAlias(a); err != nil {
+		d.Send(fmt.Sprintf("@aliases: %v.", err))
+		return
+	}
+	if g.ConfAliases == nil {
+		g.ConfAliases = make(map[string]*gamedb.ConfAlias)
+	}
+	g.ConfAliases[kind+":"+name] = a
+	if g.Store != nil {
+		if err := g.Store.PutConfAlias(a); err != nil {
+			Logf(LogBugs, LevelError, "persist alias %s %q: %v", kind, name, err)
+		}
+	}
+	Logf(LogWizard, LevelInfo, "aliases: %s(#%d) added %s %s %s", g.PlayerName(d.Player), d.Player, kind, name, a.Target)
+	if kind == gamedb.AliasBadName {
+		d.Send(fmt.Sprintf("Bad name %s added.", name))
+	} else {
+		d.Send(fmt.Sprintf("%s alias %s -> %s added.", strings.ToUpper(kind[:1])+kind[1:], name, a.Target))
+	}
+}
+
+// aliasRemove drops an alias added with @aliases.
+func aliasRemove(g *Game, d *Descriptor, kind, name string) {
+	key := kind + ":" + name
+	if _, ok := g.ConfAliases[key]; !ok {
+		if g.aliasFromConfig(kind, name) {
+			d.Send("@aliases: That one is set in the alias config; remove it there.")
+		} else {
+			d.Send("@aliases: No such alias.")
+		}
+		return
+	}
+	delete(g.ConfAliases, key)
+	switch kind {
+	case gamedb.AliasCommand:
+		delete(g.Commands, name)
+		delete(g.CmdAliases, name)
+	case gamedb.AliasFunction:
+		delete(g.FuncAliases, strings.ToUpper(name))
+	case gamedb.AliasBadName:
+		for i, bad := range g.BadNames {
+			if bad == name {
+				g.BadNames = append(g.BadNames[:i], g.BadNames[i+1:]...)
+				break
+			}
+		}
+	}
+	if g.Store != nil {
+		g.Store.DeleteConfAlias(kind, name)
+	}
+	Logf(LogWizard, LevelInfo, "aliases: %s(#%d) removed %s %s", g.PlayerName(d.Player), d.Player, kind, name)
+	d.Send("Alias removed.")
+}
+
+// aliasFromConfig reports whether an alias of kind exists but wasn't
+// added with @aliases.
+func (g *Game) aliasFromConfig(kind, name string) bool {
+	switch kind {
+	case gamedb.AliasCommand:
+		return g.CmdAliases[name] != ""
+	case gamedb.AliasFunction:
+		return g.FuncAliases[strings.ToUpper(name)] != ""
+	case gamedb.AliasBadName:
+		for _, bad := range g.BadNames {
+			if bad == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// aliasList shows every alias and bad name, and where each came from.
+func aliasList(g *Game, d *Descriptor) {
+	type row struct{ kind, name, target, source string }
+	var rows []row
+	source := func(kind, name string) string {
+		if a, ok := g.ConfAliases[kind+":"+name]; ok {
+			return "@aliases by " + g.PlayerName(a.Setter)
+		}
+		return "config"
+	}
+	for name, target := range g.CmdAliases {
+		rows = append(rows, row{gamedb.AliasCommand, name, target, source(gamedb.AliasCommand, name)})
+	}
+	for name, target := range g.FuncAliases {
+		lname := strings.ToLower(name)
+		rows = append(rows, row{gamedb.AliasFunction, lname, strings.ToLower(target), source(gamedb.AliasFunction, lname)})
+	}
+	for _, name := range g.BadNames {
+		rows = append(rows, row{gamedb.AliasBadName, name, "", source(gamedb.AliasBadName, name)})
+	}
+	if len(rows) == 0 {
+		d.Send("No aliases.")
+		return
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].kind != rows[j].kind {
+			return rows[i].kind < rows[j].kind
+		}
+		return rows[i].name < rows[j].name
+	})
+	d.Send(fmt.Sprintf("%-9s %-20s %-20s %s", "Kind", "Alias", "Target", "Source"))
+	for _, r := range rows {
+		d.Send(fmt.Sprintf("%-9s %-20s %-20s %s", r.kind, r.name, r.target, r.source))
+	}
+	d.Send(fmt.Sprintf("%d alias(es).", len(rows)))
+}
@@ -113,34 +113,10 @@ func (g *Game) ApplyAliasConfig(ac *AliasConfig) {
 
 	// Command aliases
 	for alias, target := range ac.CommandAliases {
-		// Target may contain /switch, e.g. "@dolist/now"
-		targetCmd := target
-		var prependSwitches []string
-		if slashIdx := strings.IndexByte(target, '/'); slashIdx >= 0 {
-			targetCmd = target[:slashIdx]
-			prependSwitches = strings.Split(target[slashIdx+1:], "/")
-		}
-
-		// Resolve target command
-		cmd, ok := g.Commands[strings.ToLower(targetCmd)]
-		if !ok {
-			log.Printf("aliasconf: command alias %q -> %q: target command %q not found", alias, target, targetCmd)
+		if err := g.addCommandAlias(alias, target); err != nil {
+			log.Printf("aliasconf: command alias %q -> %q: %v", alias, target, err)
 			continue
 		}
-
-		if len(prependSwitches) > 0 {
-			// Create a wrapper handler that prepends the switches
-			origHandler := cmd.Handler
-			sw := prependSwitches // capture for closure
-			g.Commands[alias] = &Command{
-				Name: cmd.Name,
-				Handler: func(g *Game, d *Descriptor, args string, switches []string) {
-					origHandler(g, d, args, append(sw, switches...))
-				},
-			}
-		} else {
-			g.Commands[alias] = cmd
-		}
 		cmdCount++
 	}
 
@@ -212,6 +188,44 @@ func (g *Game) ApplyAliasConfig(ac *AliasConfig) {
 		cmdCount, flagCount, funcCount, attrCount, len(ac.BadNames))
 }
 
+// addCommandAlias makes alias run target, which may carry /switches
+// (e.g. "@dolist/now").
+func (g *Game) addCommandAlias(alias, target string) error {
+	targetCmd := target
+	var prependSwitches []string
+	if slashIdx := strings.IndexByte(target, '/'); slashIdx >= 0 {
+		targetCmd = target[:slashIdx]
+		prependSwitches = strings.Split(target[slashIdx+1:], "/")
+	}
+
+	// Resolve target command
+	cmd, ok := g.Commands[strings.ToLower(targetCmd)]
+	if !ok {
+		return fmt.Errorf("target command %q not found", targetCmd)
+	}
+
+	if len(prependSwitches) > 0 {
+		// Create a wrapper handler that prepends the switches
+		origHandler := cmd.Handler
+		sw := prependSwitches // capture for closure
+		g.Commands[alias] = &Command{
+			Name: cmd.Name,
+			Handler: func(g *Game, d *Descriptor, args string, switches []string) {
+				origHandler(g, d, args, append(sw, switches...))
+			},
+			Switches: cmd.Switches,
+			NoGuest:  cmd.NoGuest,
+		}
+	} else {
+		g.Commands[alias] = cmd
+	}
+	if g.CmdAliases == nil {
+		g.CmdAliases = make(map[string]string)
+	}
+	g.CmdAliases[alias] = target
+	return nil
+}
+
 // IsBadName checks if a player name is forbidden.
 func (g *Game) IsBadName(name string) bool {
 	lower := strings.ToLower(name)
@@ -226,9 +240,7 @@ func (g *Game) IsBadName(name string) bool {
 // ApplyFuncAliases applies function aliases to an eval context.
 // Call this after RegisterAll.
 func (g *Game) ApplyFuncAliases(ctx *eval.EvalContext) {
-	for alias, target := range g.FuncAliases {
-		ctx.AliasFunction(alias, target)
-	}
+	ctx.FuncAliases = g.FuncAliases
 }
 
 // MakeEvalContextWithAliases creates an eval context with function aliases applied.
@@ -24,6 +24,7 @@ const (
 	aADrop   = 14  // A_ADROP
 	aLEnter  = 59  // A_LENTER — enter lock
 	aLLeave  = 60  // A_LLEAVE — leave lock
+	aLPage   = 61  // A_LPAGE — page lock
 	aLUse    = 62  // A_LUSE — use lock
 	aLGive   = 63  // A_LGIVE — give lock
 	aLRecv   = 87  // A_LRECEIVE — receive lock
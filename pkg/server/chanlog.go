@@ -0,0 +1,235 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	// chanLogDefault is the history length new channels start with.
+	chanLogDefault = 20
+
+	// chanRecallDefault is how many lines "<alias> recall" shows.
+	chanRecallDefault = 10
+)
+
+// chanLog is a ring buffer of a channel's most recent messages.
+type chanLog struct {
+	ring  []*gamedb.ChanMessage
+	head  int // index of the oldest message
+	count int
+	seq   int // last sequence number used
+}
+
+// messages returns the buffered messages, oldest first.
+func (l *chanLog) messages() []*gamedb.ChanMessage {
+	out := make([]*gamedb.ChanMessage, 0, l.count)
+	for i := 0; i < l.count; i++ {
+		out = append(out, l.ring[(l.head+i)%len(l.ring)])
+	}
+	return out
+}
+
+// resize changes the buffer's capacity, returning the messages dropped.
+func (l *chanLog) resize(size int) []*gamedb.ChanMessage {
+	msgs := l.messages()
+	var dropped []*gamedb.ChanMessage
+	if len(msgs) > size {
+		dropped = msgs[:len(msgs)-size]
+		msgs = msgs[len(msgs)-size:]
+	}
+	l.ring = make([]*gamedb.ChanMessage, size)
+	copy(l.ring, msgs)
+	l.head, l.count = 0, len(msgs)
+	return dropped
+}
+
+// add appends msg to a buffer of size messages, returning any dropped.
+func (l *chanLog) add(msg *gamedb.ChanMessage, size int) []*gamedb.ChanMessage {
+	var dropped []*gamedb.ChanMessage
+	if len(l.ring) != size {
+		dropped = l.resize(size)
+	}
+	if l.count < size {
+		l.ring[(l.head+l.count)%size] = msg
+		l.count++
+		return dropped
+	}
+	dropped = append(dropped, l.ring[l.head])
+	l.ring[l.head] = msg
+	l.head = (l.head + 1) % size
+	return dropped
+}
+
+// LoadLog restores channel history read from storage.
+func (cs *Comsys) LoadLog(msgs []gamedb.ChanMessage) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for i := range msgs {
+		msg := msgs[i]
+		key := strings.ToLower(msg.Channel)
+		ch := cs.Channels[key]
+		if ch == nil || ch.LogLength <= 0 {
+			continue
+		}
+		l := cs.logs[key]
+		if l == nil {
+			l = &chanLog{}
+			cs.logs[key] = l
+		}
+		l.add(&msg, ch.LogLength)
+		if msg.Seq > l.seq {
+			l.seq = msg.Seq
+		}
+	}
+}
+
+// LogMessage records text in ch's history, returning the new entry (nil
+// if ch keeps no history) and any entries it pushed out.
+func (cs *Comsys) LogMessage(ch *gamedb.Channel, sender gamedb.DBRef, text string) (*gamedb.ChanMessage, []*gamedb.ChanMessage) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if ch.LogLength <= 0 {
+		return nil, nil
+	}
+	key := strings.ToLower(ch.Name)
+	l := cs.logs[key]
+	if l == nil {
+		l = &chanLog{}
+		cs.logs[key] = l
+	}
+	l.seq++
+	msg := &gamedb.ChanMessage{Channel: ch.Name, Seq: l.seq, Time: time.Now(), Sender: sender, Text: text}
+	return msg, l.add(msg, ch.LogLength)
+}
+
+// ResizeLog applies a change to ch.LogLength, returning the entries dropped.
+func (cs *Comsys) ResizeLog(ch *gamedb.Channel) []*gamedb.ChanMessage {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	key := strings.ToLower(ch.Name)
+	l := cs.logs[key]
+	if l == nil {
+		return nil
+	}
+	if ch.LogLength <= 0 {
+		delete(cs.logs, key)
+		return l.messages()
+	}
+	return l.resize(ch.LogLength)
+}
+
+// Recall returns up to n of a channel's most recent messages, oldest
+// first. n <= 0 returns all of them.
+func (cs *Comsys) Recall(name string, n int) []gamedb.ChanMessage {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	l := cs.logs[strings.ToLower(name)]
+	if l == nil {
+		return nil
+	}
+	msgs := l.messages()
+	if n > 0 && len(msgs) > n {
+		msgs = msgs[len(msgs)-n:]
+	}
+	out := make([]gamedb.ChanMessage, len(msgs))
+	for i, m := range msgs {
+		out[i] = *m
+	}
+	return out
+}
+
+// DropLog forgets a channel's history.
+func (cs *Comsys) DropLog(name string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	delete(cs.logs, strings.ToLower(name))
+}
+
+// logChannelMessage adds a message sent on a channel to its history,
+// saving it if the channel's history is persistent.
+func (g *Game) logChannelMessage(channelName string, sender gamedb.DBRef, text string) {
+	ch := g.Comsys.GetChannel(channelName)
+	if ch == nil {
+		return
+	}
+	msg, dropped := g.Comsys.LogMessage(ch, sender, text)
+	if msg == nil || !ch.LogPersist || g.Store == nil {
+		return
+	}
+	if err := g.Store.PutChanMessage(msg); err != nil {
+		Logf(LogBugs, LevelError, "persist channel message for %s: %v", ch.Name, err)
+	}
+	for _, old := range dropped {
+		g.Store.DeleteChanMessage(old)
+	}
+}
+
+// showChannelRecall shows a subscriber the last lines of a channel.
+// args is the text after "recall": an optional line count.
+func (g *Game) showChannelRecall(d *Descriptor, ch *gamedb.Channel, args string) {
+	n := chanRecallDefault
+	if args = strings.TrimSpace(args); args != "" {
+		v, err := strconv.Atoi(args)
+		if err != nil || v <= 0 {
+			d.Send("Usage: <alias> recall [<lines>]")
+			return
+		}
+		n = v
+	}
+	if ch.LogLength <= 0 {
+		d.Send(fmt.Sprintf("Channel %s does not keep a history.", ch.Name))
+		return
+	}
+	msgs := g.Comsys.Recall(ch.Name, n)
+	if len(msgs) == 0 {
+		d.Send(fmt.Sprintf("Channel %s: nothing to recall.", ch.Name))
+		return
+	}
+	d.Send(fmt.Sprintf("Channel %s: last %d message(s):", ch.Name, len(msgs)))
+	for _, m := range msgs {
+		d.Send(fmt.Sprintf("[%s] %s", m.Time.Format("15:04"), m.Text))
+	}
+	d.Send("-- End of recall --")
+}
+
+// channelLogDump implements @clist/recall <channel>[=<lines>]: the whole
+// kept history of a channel, with senders and full timestamps, for its
+// owner and wizards.
+func channelLogDump(g *Game, d *Descriptor, args string) {
+	name, count, _ := strings.Cut(args, "=")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		d.Send("Usage: @clist/recall <channel>[=<lines>]")
+		return
+	}
+	ch := g.Comsys.GetChannel(name)
+	if ch == nil {
+		d.Send(fmt.Sprintf("Channel %q not found.", name))
+		return
+	}
+	if !Wizard(g, d.Player) && d.Player != ch.Owner {
+		d.Send("Permission denied. You must be the channel owner or a Wizard.")
+		return
+	}
+	n := 0
+	if count = strings.TrimSpace(count); count != "" {
+		v, err := strconv.Atoi(count)
+		if err != nil || v <= 0 {
+			d.Send("Usage: @clist/recall <channel>[=<lines>]")
+			return
+		}
+		n = v
+	}
+	msgs := g.Comsys.Recall(ch.Name, n)
+	d.Send(fmt.Sprintf("--- History of channel %s (%d of %d kept) ---", ch.Name, len(msgs), ch.LogLength))
+	for _, m := range msgs {
+		d.Send(fmt.Sprintf("%5d [%s] %s(#%d): %s", m.Seq, m.Time.Format("Jan 02 15:04:05"),
+			g.PlayerName(m.Sender), m.Sender, m.Text))
+	}
+	d.Send("--- End of history ---")
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Now returns the game's current time: Clock's if one is set (tests set
+// one to control time), otherwise the system's, moved by @timewarp. Queue
+// waits, idle timers, auto-archives, @cron, @daily and the softcode time
+// functions all read it, so a warp moves them together.
+func (g *Game) Now() time.Time {
+	now := time.Now
+	if g.Clock != nil {
+		now = g.Clock
+	}
+	return now().Add(time.Duration(g.warp.Load()))
+}
+
+// cmdTimewarp shows or moves the game clock. @timewarp <seconds> moves it
+// forward (back, if negative) and runs anything that has come due;
+// @timewarp/reset puts it back to real time.
+func cmdTimewarp(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	args = strings.TrimSpace(args)
+	switch {
+	case HasSwitch(switches, "reset"):
+		g.warp.Store(0)
+	case args == "":
+		if w := time.Duration(g.warp.Load()); w != 0 {
+			d.Send(fmt.Sprintf("The game clock is %d second(s) off real time; it is %s.", int64(w/time.Second), g.Now().Format(time.ANSIC)))
+		} else {
+			d.Send("The game clock is on real time.")
+		}
+		return
+	default:
+		secs, err := strconv.ParseInt(args, 10, 64)
+		if err != nil {
+			d.Send("That's not a number!")
+			return
+		}
+		g.warp.Add(int64(time.Duration(secs) * time.Second))
+	}
+	Logf(LogWizard, LevelInfo, "%s(#%d) warped the game clock to %d second(s) off real time",
+		g.PlayerName(d.Player), d.Player, int64(time.Duration(g.warp.Load())/time.Second))
+	d.Send(fmt.Sprintf("Time warped. It is now %s.", g.Now().Format(time.ANSIC)))
+	g.WakeQueue()
+}
@@ -2,10 +2,12 @@ package server
 
 import (
 	"fmt"
-	"log"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/crystal-mush/gotinymush/pkg/boltstore"
@@ -13,6 +15,7 @@ import (
 	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
 	"github.com/crystal-mush/gotinymush/pkg/events"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	"github.com/crystal-mush/gotinymush/pkg/replication"
 )
 
 // CommandHandler is the signature for game command implementations.
@@ -20,9 +23,127 @@ type CommandHandler func(g *Game, d *Descriptor, args string, switches []string)
 
 // Command represents a registered game command.
 type Command struct {
-	Name    string
-	Handler CommandHandler
-	NoGuest bool // if true, guests cannot use this command
+	Name     string
+	Handler  CommandHandler
+	NoGuest  bool     // if true, guests cannot use this command
+	NoGagged bool     // if true, GAGGED players cannot use this command
+	Switches []string // valid /switches; empty means the command takes none
+}
+
+// gaggedCommands are the speech commands a GAGGED player loses.
+var gaggedCommands = []string{
+	"say", "\"", "pose", ":", ";", "page", "reply", "whisper",
+	"@emit", "@pemit", "@oemit", "@remit",
+}
+
+// gaggedMsg is what a GAGGED player is told when they try to speak.
+const gaggedMsg = "You have been gagged."
+
+// commandSwitches lists the /switches each command honors, keyed by
+// lowercase command name. As in C TinyMUSH, a switch may be abbreviated to
+// any unique prefix; anything else is rejected before the handler runs.
+var commandSwitches = map[string][]string{
+	"@emit":      {"room", "noeval", "html"},
+	"@pemit":     {"contents", "list", "html", "noeval"},
+	"@oemit":     {"noeval"},
+	"@remit":     {"noeval"},
+	"say":        {"noeval"},
+	"pose":       {"noeval"},
+	"@destroy":   {"override", "instant"},
+	"@clone":     {"parent"},
+	"@lock":      {"attr", "enter", "enterlock", "leave", "leavelock", "use", "uselock", "give", "givelock", "receive", "receivelock", "page", "pagelock", "tportlock", "teloutlock"},
+	"look":       {"outside"},
+	"examine":    {"brief", "parent", "debug"},
+	"@unlock":    {"attr", "enter", "enterlock", "leave", "leavelock", "use", "uselock", "give", "givelock", "receive", "receivelock", "page", "pagelock", "tportlock", "teloutlock"},
+	"@trigger":   {"now"},
+	"@halt":      {"all"},
+	"@timewarp":  {"reset", "check", "dump", "idle", "queue"},
+	"@wait":      {"until"},
+	"@watch":     {"off", "list"},
+	"@monitor":   {"off", "list"},
+	"@ps":        {"all", "brief", "long", "summary"},
+	"@find":      {"more"},
+	"@stats":     {"all"},
+	"@sweep":     {"here", "inventory", "exits", "commands", "connected", "listeners", "players"},
+	"@switch":    {"all", "first"},
+	"@swi":       {"all", "first"},
+	"@dolist":    {"delimit", "now"},
+	"@end":       {"break", "assert"},
+	"@dump":      {"list"},
+	"@archive":   {"list"},
+	"@restore":   {"object", "overwrite"},
+	"@function":  {"privileged", "preserve", "delete"},
+	"@attribute": {"access", "rename", "delete", "propagate"},
+	"@attlist":   {"detail"},
+	"@motd":      {"wizard", "down", "full", "keep", "list", "add", "remove"},
+	"@chzone":    {"nostrip"},
+	"@cemit":     {"noheader"},
+	"@clist":     {"alpha", "members", "recall"},
+	"@clock":     {"join", "transmit", "receive"},
+	"@cset": {"description", "header", "public", "private", "loud", "quiet", "log", "persist", "nopersist",
+		"charge", "titles", "notitles"},
+	"@cwho":      {"alpha"},
+	"comlist":    {"alpha", "members"},
+	"@boot":      {"port", "quiet"},
+	"@mail": {"send", "to", "cc", "subject", "proof", "abort", "read", "list", "clear",
+		"unclear", "purge", "reply", "replyall", "quote", "forward", "fwd", "stats", "safe", "export",
+		"bcc", "folder", "file", "review", "urgent", "silent", "quick"},
+	"@malias": {"list", "desc", "add", "remove", "rename", "delete", "chown"},
+	"@mailto": {"verify", "off"},
+	"@site":   {"list", "forbid", "register", "noguest", "remove", "unthrottle"},
+	"@aliases": {"list", "command", "function", "badname", "remove"},
+	"@admin":   {"save", "list", "clear"},
+	"@helptext": {"wizhelp", "news", "plushelp", "wiznews", "wizard", "delete", "list"},
+	"help":     {"search"},
+	"@help":    {"search"},
+	"qhelp":    {"search"},
+	"wizhelp":  {"search"},
+	"news":     {"search"},
+	"man":      {"search"},
+	"wiznews":  {"search"},
+	"+jhelp":   {"search"},
+	"@http":    {"get", "post"},
+	"@pcreate": {"list", "approve", "reject"},
+	"@toad":     {"no_chown"},
+	"@doing":    {"message", "header", "poll", "quiet"},
+	"page":      {"noeval"},
+	"reply":     {"noeval"},
+	"@chownall": {"nostrip"},
+	"+scene":  {"start", "stop", "list", "view", "allow", "deny", "public", "private", "delete"},
+}
+
+// resolveSwitches checks user-supplied switches against a command's valid
+// set and expands unique abbreviations to their full names. It returns an
+// error message for the first switch that is unknown or ambiguous.
+func resolveSwitches(cmd *Command, switches []string) ([]string, string) {
+	var resolved []string
+	for _, sw := range switches {
+		if sw == "" {
+			continue
+		}
+		lower := strings.ToLower(sw)
+		match := ""
+		matches := 0
+		for _, valid := range cmd.Switches {
+			if valid == lower {
+				match, matches = valid, 1
+				break
+			}
+			if strings.HasPrefix(valid, lower) {
+				match = valid
+				matches++
+			}
+		}
+		switch {
+		case matches == 1:
+			resolved = append(resolved, match)
+		case matches > 1:
+			return nil, fmt.Sprintf("Ambiguous switch '%s' for %s.", sw, cmd.Name)
+		default:
+			return nil, fmt.Sprintf("Unrecognized switch '%s' for %s.", sw, cmd.Name)
+		}
+	}
+	return resolved, ""
 }
 
 // InitCommands registers all available game commands.
@@ -44,6 +165,7 @@ func InitCommands() map[string]*Command {
 	register(":", cmdPose)
 	register(";", cmdPoseNoSpc)
 	register("page", cmdPage)
+	register("reply", cmdReply)
 	register("@emit", cmdEmit)
 	register("think", cmdThink)
 	register("@pemit", cmdPemit)
@@ -58,6 +180,7 @@ func InitCommands() map[string]*Command {
 	register("inventory", cmdInventory)
 	register("WHO", cmdWho)
 	register("DOING", cmdDoing)
+	register("INFO", cmdMudInfo)
 	register("score", cmdScore)
 
 	// Building (no guest)
@@ -84,9 +207,19 @@ func InitCommands() map[string]*Command {
 	registerNG("@wait", cmdWaitCmd)
 	registerNG("@notify", cmdNotify)
 	registerNG("@halt", cmdHalt)
+	register("@end", cmdEnd)
+	register("@break", cmdBreak)
+	register("@assert", cmdAssert)
+	registerNG("@timewarp", cmdTimewarp)
 	registerNG("@boot", cmdBoot)
+	registerNG("@site", cmdSite)
+	registerNG("@aliases", cmdAliases)
 	registerNG("@wall", cmdWall)
 	registerNG("@newpassword", cmdNewPassword)
+	registerNG("@pcreate", cmdPcreate)
+	registerNG("@robot", cmdRobot)
+	registerNG("@toad", cmdToad)
+	registerNG("@chownall", cmdChownall)
 	registerNG("@find", cmdFind)
 	registerNG("@stats", cmdStats)
 	registerNG("@ps", cmdPs)
@@ -102,9 +235,11 @@ func InitCommands() map[string]*Command {
 	// Database (no guest)
 	registerNG("@dump", cmdDump)
 	registerNG("@fixdb", cmdFixDB)
+	registerNG("@dbck", cmdDbck)
 	registerNG("@backup", cmdBackup)
 	registerNG("@readcache", cmdReadCache)
 	registerNG("@archive", cmdArchive)
+	registerNG("@restore", cmdRestore)
 
 	// Softcode / Queue management (no guest)
 	registerNG("@function", cmdFunction)
@@ -121,10 +256,14 @@ func InitCommands() map[string]*Command {
 	registerNG("@sql", cmdSQL)
 	registerNG("@sqlinit", cmdSQLInit)
 	registerNG("@sqldisconnect", cmdSQLDisconnect)
+	registerNG("@http", cmdHTTP)
 
 	// Session
 	register("QUIT", cmdQuit)
 	register("@doing", cmdSetDoing)
+	register("@hide", cmdHide)
+	register("@unhide", cmdUnhide)
+	register("SESSION", cmdSession)
 
 	// Help system
 	register("help", cmdHelp)
@@ -135,6 +274,8 @@ func InitCommands() map[string]*Command {
 	register("man", cmdMan)
 	register("wiznews", cmdWizNews)
 	register("+jhelp", cmdJhelp)
+	registerNG("@helptext", cmdHelptext)
+	registerNG("+scene", cmdScene)
 	// NOTE: +help is NOT registered here. CrystalMUSH uses softcode $+help
 	// on Global Commands(#123) in the master room. The original crystal.conf
 	// has "helpfile +help text/plushelp" commented out (line 47).
@@ -146,6 +287,10 @@ func InitCommands() map[string]*Command {
 	registerNG("give", cmdGive)
 	register("enter", cmdEnter)
 	register("leave", cmdLeave)
+	register("follow", cmdFollow)
+	register("@follow", cmdFollow)
+	register("unfollow", cmdUnfollow)
+	register("dismiss", cmdDismiss)
 	register("whisper", cmdWhisper)
 	register("use", cmdUse)
 	registerNG("kill", cmdKill)
@@ -160,9 +305,17 @@ func InitCommands() map[string]*Command {
 	register("version", cmdVersion)
 	register("@uptime", cmdUptime)
 	register("uptime", cmdUptime)
+	register("@info", cmdInfo)
+	register("@readconf", cmdReadconf)
+	register("@log", cmdLog)
+	register("@last", cmdLast)
+	register("@watch", cmdWatch)
+	register("@monitor", cmdWatch)
 	register("@motd", cmdMotd)
+	register("@listmotd", cmdListMotd)
 	registerNG("@chzone", cmdChzone)
 	registerNG("@search", cmdSearch)
+	register("@sweep", cmdSweep)
 	registerNG("@decompile", cmdDecompile)
 	registerNG("@power", cmdPower)
 
@@ -196,8 +349,8 @@ func InitCommands() map[string]*Command {
 	// Player info
 	registerNG("@sex", makeAttrSetter(7))          // A_SEX = 7
 	registerNG("@alias", makeAttrSetter(58))       // A_ALIAS = 58
-	registerNG("@away", makeAttrSetter(73))        // A_AWAY = 73
-	registerNG("@idle", makeAttrSetter(74))        // A_IDLE = 74
+	registerNG("@away", makeAttrSetter(aAway))
+	registerNG("@idle", makeAttrSetter(aIdle))
 	registerNG("@listen", makeAttrSetter(26))      // A_LISTEN = 26
 	registerNG("@ahear", makeAttrSetter(29))       // A_AHEAR = 29
 	// Move attributes
@@ -215,7 +368,10 @@ func InitCommands() map[string]*Command {
 	registerNG("@cost", makeAttrSetter(24))        // A_COST = 24
 	// Startup/daily
 	registerNG("@startup", makeAttrSetter(19))     // A_STARTUP = 19
-	registerNG("@daily", makeAttrSetter(204))      // A_DAILY = 204
+	registerNG("@daily", makeAttrSetter(aDaily))
+	registerNG("@cron", cmdCron)
+	registerNG("@crondel", cmdCrondel)
+	registerNG("@crontab", cmdCrontab)
 	// Format overrides
 	registerNG("@conformat", makeAttrSetter(214))  // A_LCON_FMT = 214
 	registerNG("@exitformat", makeAttrSetter(215)) // A_LEXITS_FMT = 215
@@ -226,7 +382,7 @@ func InitCommands() map[string]*Command {
 	// Filtering
 	registerNG("@filter", makeAttrSetter(92))      // A_FILTER = 92
 	registerNG("@infilter", makeAttrSetter(91))    // A_INFILTER = 91
-	registerNG("@forwardlist", makeAttrSetter(95)) // A_FORWARDLIST = 95
+	registerNG("@forwardlist", cmdForwardlist)
 	registerNG("@prefix", makeAttrSetter(90))      // A_PREFIX = 90
 	registerNG("@inprefix", makeAttrSetter(89))    // A_INPREFIX = 89
 	// Enter/Leave/Use failure variants
@@ -267,24 +423,58 @@ func InitCommands() map[string]*Command {
 	registerNG("@cboot", cmdCboot)
 	registerNG("@cemit", cmdCemit)
 	registerNG("@cset", cmdCset)
+	registerNG("@clock", cmdClock)
 	registerNG("@cinfo", cmdCinfo)
 
 	// Mail system (no guest)
 	registerNG("@mail", cmdMail)
+	registerNG("@mailto", cmdMailto)
 	registerNG("-", cmdMailDash)
+	registerNG("--", cmdMailSendDash)
+	registerNG("@malias", cmdMalias)
+
+	for name, switches := range commandSwitches {
+		if cmd, ok := cmds[name]; ok {
+			cmd.Switches = switches
+		}
+	}
+	for _, name := range gaggedCommands {
+		if cmd, ok := cmds[name]; ok {
+			cmd.NoGagged = true
+		}
+	}
 
 	return cmds
 }
 
-// DispatchCommand parses and dispatches a player command.
+// DispatchCommand parses and dispatches a player command. A panic in the
+// command is recovered, so it stops only this command.
 func DispatchCommand(g *Game, d *Descriptor, input string) {
 	input = strings.TrimSpace(input)
 	if input == "" {
 		return
 	}
+	g.verboseEcho(d.Player, input)
+	defer func() {
+		if r := recover(); r != nil {
+			g.commandPanic(d.Player, d.Player, input, r)
+			d.Send(panicMsg)
+		}
+	}()
+	dispatchCommand(g, d, input)
+}
+
+func dispatchCommand(g *Game, d *Descriptor, input string) {
 
 	// Handle single-character prefixes: " for say, : for pose, ; for pose-nospc, & for setvattr
 	switch input[0] {
+	case '"', ':', ';':
+		if Gagged(g, d.Player) {
+			d.Send(gaggedMsg)
+			return
+		}
+	}
+	switch input[0] {
 	case '"':
 		cmdSay(g, d, input[1:], nil)
 		return
@@ -328,6 +518,15 @@ func DispatchCommand(g *Game, d *Descriptor, input string) {
 			d.Send("Permission denied.")
 			return
 		}
+		if cmd.NoGagged && Gagged(g, d.Player) {
+			d.Send(gaggedMsg)
+			return
+		}
+		switches, errMsg := resolveSwitches(cmd, switches)
+		if errMsg != "" {
+			d.Send(errMsg)
+			return
+		}
 		cmd.Handler(g, d, args, switches)
 		return
 	}
@@ -348,6 +547,15 @@ func DispatchCommand(g *Game, d *Descriptor, input string) {
 				d.Send("Permission denied.")
 				return
 			}
+			if matchedCmd.NoGagged && Gagged(g, d.Player) {
+				d.Send(gaggedMsg)
+				return
+			}
+			switches, errMsg := resolveSwitches(matchedCmd, switches)
+			if errMsg != "" {
+				d.Send(errMsg)
+				return
+			}
 			matchedCmd.Handler(g, d, args, switches)
 			return
 		}
@@ -371,6 +579,10 @@ func DispatchCommand(g *Game, d *Descriptor, input string) {
 	// Try channel alias matching
 	if g.Comsys != nil {
 		if ca := g.Comsys.LookupAlias(d.Player, strings.ToLower(cmdName)); ca != nil {
+			if Gagged(g, d.Player) {
+				d.Send(gaggedMsg)
+				return
+			}
 			g.ComsysProcessAlias(d, ca, args)
 			return
 		}
@@ -413,16 +625,20 @@ func evalExpr(g *Game, player gamedb.DBRef, text string) string {
 	ctx := MakeEvalContextWithGame(g, player, func(c *eval.EvalContext) {
 		functions.RegisterAll(c)
 	})
+	defer ctx.Release()
 	return ctx.Exec(text, eval.EvFCheck|eval.EvEval, nil)
 }
 
-func cmdSay(g *Game, d *Descriptor, args string, _ []string) {
+func cmdSay(g *Game, d *Descriptor, args string, switches []string) {
 	args = strings.TrimSpace(args)
 	if args == "" {
 		d.Send("Say what?")
 		return
 	}
-	args = evalExpr(g, d.Player, args)
+	if g.robotMuted(d) {
+		return
+	}
+	args = evalMessage(g, d.Player, args, switches)
 	playerName := g.PlayerName(d.Player)
 	loc := g.PlayerLocation(d.Player)
 
@@ -447,8 +663,11 @@ func cmdSay(g *Game, d *Descriptor, args string, _ []string) {
 	g.AudibleRelay(loc, d.Player, msg)
 }
 
-func cmdPose(g *Game, d *Descriptor, args string, _ []string) {
-	args = evalExpr(g, d.Player, strings.TrimSpace(args))
+func cmdPose(g *Game, d *Descriptor, args string, switches []string) {
+	if g.robotMuted(d) {
+		return
+	}
+	args = evalMessage(g, d.Player, strings.TrimSpace(args), switches)
 	playerName := g.PlayerName(d.Player)
 	loc := g.PlayerLocation(d.Player)
 	msg := fmt.Sprintf("%s %s", playerName, args)
@@ -464,6 +683,9 @@ func cmdPose(g *Game, d *Descriptor, args string, _ []string) {
 }
 
 func cmdPoseNoSpc(g *Game, d *Descriptor, args string, _ []string) {
+	if g.robotMuted(d) {
+		return
+	}
 	args = evalExpr(g, d.Player, args)
 	playerName := g.PlayerName(d.Player)
 	loc := g.PlayerLocation(d.Player)
@@ -478,100 +700,8 @@ func cmdPoseNoSpc(g *Game, d *Descriptor, args string, _ []string) {
 	g.MatchListenPatterns(loc, d.Player, msg)
 }
 
-func cmdPage(g *Game, d *Descriptor, args string, _ []string) {
-	if args == "" {
-		d.Send("Page whom?")
-		return
-	}
-	// Format: page name=message or page name message
-	var targetName, message string
-	if eqIdx := strings.IndexByte(args, '='); eqIdx >= 0 {
-		targetName = strings.TrimSpace(args[:eqIdx])
-		message = strings.TrimSpace(args[eqIdx+1:])
-	} else {
-		parts := strings.SplitN(args, " ", 2)
-		targetName = parts[0]
-		if len(parts) > 1 {
-			message = parts[1]
-		}
-	}
-
-	target := LookupPlayer(g.DB, targetName)
-	if target == gamedb.Nothing {
-		d.Send("I don't recognize that player.")
-		return
-	}
-
-	if !g.Conns.IsConnected(target) {
-		targetObj := g.DB.Objects[target]
-		d.Send(fmt.Sprintf("%s is not connected.", DisplayName(targetObj.Name)))
-		return
-	}
-
-	senderName := g.PlayerName(d.Player)
-	targetObj := g.DB.Objects[target]
-
-	pageData := map[string]any{
-		"sender":  senderName,
-		"target":  DisplayName(targetObj.Name),
-		"message": message,
-	}
-
-	if message == "" {
-		g.EmitEvent(d.Player, "PAGE", events.Event{
-			Type: events.EvPage, Source: d.Player,
-			Text: fmt.Sprintf("You page %s.", DisplayName(targetObj.Name)),
-			Data: pageData,
-		})
-		g.EmitEvent(target, "PAGE", events.Event{
-			Type: events.EvPage, Source: d.Player,
-			Text: fmt.Sprintf("%s pages you.", senderName),
-			Data: pageData,
-		})
-	} else {
-		message = evalExpr(g, d.Player, message)
-		pageData["message"] = message
-		if strings.HasPrefix(message, ":") {
-			pose := strings.TrimPrefix(message, ":")
-			g.EmitEvent(d.Player, "PAGE", events.Event{
-				Type: events.EvPage, Source: d.Player,
-				Text: fmt.Sprintf("Long distance to %s: %s %s", DisplayName(targetObj.Name), senderName, pose),
-				Data: pageData,
-			})
-			g.EmitEvent(target, "PAGE", events.Event{
-				Type: events.EvPage, Source: d.Player,
-				Text: fmt.Sprintf("From afar, %s %s", senderName, pose),
-				Data: pageData,
-			})
-		} else if strings.HasPrefix(message, ";") {
-			pose := strings.TrimPrefix(message, ";")
-			g.EmitEvent(d.Player, "PAGE", events.Event{
-				Type: events.EvPage, Source: d.Player,
-				Text: fmt.Sprintf("Long distance to %s: %s%s", DisplayName(targetObj.Name), senderName, pose),
-				Data: pageData,
-			})
-			g.EmitEvent(target, "PAGE", events.Event{
-				Type: events.EvPage, Source: d.Player,
-				Text: fmt.Sprintf("From afar, %s%s", senderName, pose),
-				Data: pageData,
-			})
-		} else {
-			g.EmitEvent(d.Player, "PAGE", events.Event{
-				Type: events.EvPage, Source: d.Player,
-				Text: fmt.Sprintf("You page %s with \"%s\"", DisplayName(targetObj.Name), message),
-				Data: pageData,
-			})
-			g.EmitEvent(target, "PAGE", events.Event{
-				Type: events.EvPage, Source: d.Player,
-				Text: fmt.Sprintf("%s pages: %s", senderName, message),
-				Data: pageData,
-			})
-		}
-	}
-}
-
 func cmdEmit(g *Game, d *Descriptor, args string, switches []string) {
-	if args == "" {
+	if args == "" || g.robotMuted(d) {
 		return
 	}
 
@@ -585,7 +715,7 @@ func cmdEmit(g *Game, d *Descriptor, args string, switches []string) {
 		targetStr := strings.TrimSpace(args[:eqIdx])
 		message := strings.TrimSpace(args[eqIdx+1:])
 		targetStr = evalExpr(g, d.Player, targetStr)
-		message = evalExpr(g, d.Player, message)
+		message = evalMessage(g, d.Player, message, switches)
 		target := g.ResolveRef(d.Player, targetStr)
 		if target == gamedb.Nothing {
 			target = g.MatchObject(d.Player, targetStr)
@@ -601,7 +731,13 @@ func cmdEmit(g *Game, d *Descriptor, args string, switches []string) {
 				loc = obj.Location
 			}
 		}
-		if loc != gamedb.Nothing {
+		if loc != gamedb.Nothing && !g.canEmitIn(d.Player, loc) {
+			d.Send("You are too far away to do that.")
+			return
+		}
+		if loc != gamedb.Nothing && HasSwitch(switches, "html") {
+			g.sendHTMLToRoom(loc, message)
+		} else if loc != gamedb.Nothing {
 			g.EmitEventToRoom(loc, "EMIT", events.Event{
 				Type:   events.EvEmit,
 				Source: d.Player,
@@ -613,8 +749,12 @@ func cmdEmit(g *Game, d *Descriptor, args string, switches []string) {
 		return
 	}
 
-	args = evalExpr(g, d.Player, args)
+	args = evalMessage(g, d.Player, args, switches)
 	loc := g.PlayerLocation(d.Player)
+	if HasSwitch(switches, "html") {
+		g.sendHTMLToRoom(loc, args)
+		return
+	}
 	g.EmitEventToRoom(loc, "EMIT", events.Event{
 		Type:   events.EvEmit,
 		Source: d.Player,
@@ -624,11 +764,39 @@ func cmdEmit(g *Game, d *Descriptor, args string, switches []string) {
 	g.MatchListenPatterns(loc, d.Player, args)
 }
 
+// evalMessage evaluates a message for say, pose and the emit commands,
+// or leaves it exactly as typed with /noeval.
+func evalMessage(g *Game, player gamedb.DBRef, msg string, switches []string) string {
+	if HasSwitch(switches, "noeval") {
+		return msg
+	}
+	return evalExpr(g, player, msg)
+}
+
+// sendHTML sends raw HTML to player's Pueblo connections (@pemit/html);
+// other clients get nothing.
+func (g *Game) sendHTML(player gamedb.DBRef, html string) {
+	for _, dd := range g.Conns.GetByPlayer(player) {
+		if dd.Pueblo {
+			dd.Send(html)
+		}
+	}
+}
+
+// sendHTMLToRoom sends raw HTML to the Pueblo connections of everyone in
+// room (@emit/html).
+func (g *Game) sendHTMLToRoom(room gamedb.DBRef, html string) {
+	for _, next := range g.DB.SafeContents(room) {
+		g.sendHTML(next, html)
+	}
+}
+
 func cmdThink(g *Game, d *Descriptor, args string, _ []string) {
 	// Evaluate the expression and show result only to the player
 	ctx := MakeEvalContextWithGame(g, d.Player, func(c *eval.EvalContext) {
 		functions.RegisterAll(c)
 	})
+	defer ctx.Release()
 	result := ctx.Exec(args, eval.EvFCheck|eval.EvEval, nil)
 	d.Send(result)
 }
@@ -637,6 +805,8 @@ func cmdPemit(g *Game, d *Descriptor, args string, switches []string) {
 	// @pemit target=message
 	// @pemit/contents target=message  (send to all contents of target)
 	// @pemit/list targets=message     (targets is space-separated dbrefs)
+	// @pemit/html target=message      (raw HTML, only to Pueblo clients)
+	// @pemit/noeval target=message    (message is sent as typed)
 	eqIdx := strings.IndexByte(args, '=')
 	if eqIdx < 0 {
 		d.Send("@pemit: I need a target and message separated by =.")
@@ -649,7 +819,9 @@ func cmdPemit(g *Game, d *Descriptor, args string, switches []string) {
 		functions.RegisterAll(c)
 	})
 	targetStr = ctx.Exec(targetStr, eval.EvFCheck|eval.EvEval, nil)
-	message = ctx.Exec(message, eval.EvFCheck|eval.EvEval, nil)
+	if !HasSwitch(switches, "noeval") {
+		message = ctx.Exec(message, eval.EvFCheck|eval.EvEval, nil)
+	}
 
 	if HasSwitch(switches, "contents") {
 		// @pemit/contents: send to all contents of the target location
@@ -665,8 +837,12 @@ func cmdPemit(g *Game, d *Descriptor, args string, switches []string) {
 			d.Send("I don't see that here.")
 			return
 		}
+		if !g.canPemit(d.Player, target) {
+			d.Send("You are too far away to do that.")
+			return
+		}
 		for _, cur := range g.DB.SafeContents(target) {
-			g.SendMarkedToPlayer(cur, "EMIT", message)
+			g.SendEmitToPlayer(cur, d.Player, d.Player, message)
 			g.CheckPemitListen(cur, d.Player, message)
 		}
 		// C TinyMUSH also delivers to the room itself (notify_all_from_inside
@@ -683,10 +859,15 @@ func cmdPemit(g *Game, d *Descriptor, args string, switches []string) {
 		targets := strings.Fields(targetStr)
 		for _, ts := range targets {
 			ref := g.ResolveRef(d.Player, strings.TrimSpace(ts))
-			if ref != gamedb.Nothing {
-				g.SendMarkedToPlayer(ref, "EMIT", message)
-				g.CheckPemitListen(ref, d.Player, message)
+			if ref == gamedb.Nothing {
+				continue
+			}
+			if !g.canPemit(d.Player, ref) {
+				d.Send(fmt.Sprintf("%s: You are too far away to do that.", ts))
+				continue
 			}
+			g.SendEmitToPlayer(ref, d.Player, d.Player, message)
+			g.CheckPemitListen(ref, d.Player, message)
 		}
 		return
 	}
@@ -703,7 +884,15 @@ func cmdPemit(g *Game, d *Descriptor, args string, switches []string) {
 		d.Send("I don't see that here.")
 		return
 	}
-	g.SendMarkedToPlayer(target, "EMIT", message)
+	if !g.canPemit(d.Player, target) {
+		d.Send("You are too far away to do that.")
+		return
+	}
+	if HasSwitch(switches, "html") {
+		g.sendHTML(target, message)
+		return
+	}
+	g.SendEmitToPlayer(target, d.Player, d.Player, message)
 	// C TinyMUSH: @pemit to an object triggers its LISTEN/^ patterns
 	g.CheckPemitListen(target, d.Player, message)
 }
@@ -722,14 +911,23 @@ func cmdGo(g *Game, d *Descriptor, args string, _ []string) {
 
 func tryMoveByExit(g *Game, d *Descriptor, name string) bool {
 	loc := g.PlayerLocation(d.Player)
-	locObj, ok := g.DB.Objects[loc]
-	if !ok {
+	if _, ok := g.DB.Objects[loc]; !ok {
 		return false
 	}
 
-	// Walk exits chain
+	// Walk the exits chain of the room, then of its parent rooms
 	seenExits := make(map[gamedb.DBRef]bool)
-	exitRef := locObj.Exits
+	for _, src := range append([]gamedb.DBRef{loc}, g.parentRooms(loc)...) {
+		if tryExitChain(g, d, loc, g.DB.Objects[src].Exits, name, seenExits) {
+			return true
+		}
+	}
+	return false
+}
+
+// tryExitChain looks for an exit called name in the exits chain starting
+// at exitRef and, if one matches, takes the player in loc through it.
+func tryExitChain(g *Game, d *Descriptor, loc, exitRef gamedb.DBRef, name string, seenExits map[gamedb.DBRef]bool) bool {
 	for exitRef != gamedb.Nothing && !seenExits[exitRef] {
 		seenExits[exitRef] = true
 		exitObj, ok := g.DB.Objects[exitRef]
@@ -745,6 +943,13 @@ func tryMoveByExit(g *Game, d *Descriptor, name string) bool {
 				// Found matching exit - move player
 				// TinyMUSH stores exit destination in Location field
 				dest := exitObj.Location
+				if dest == gamedb.Ambiguous {
+					var ok bool
+					if dest, ok = g.variableExitDest(exitRef, d.Player); !ok {
+						d.Send("That exit doesn't lead anywhere.")
+						return true
+					}
+				}
 				if dest == gamedb.Nothing || dest == gamedb.Home {
 					// Home exit
 					playerObj := g.DB.Objects[d.Player]
@@ -754,37 +959,19 @@ func tryMoveByExit(g *Game, d *Descriptor, name string) bool {
 					d.Send("That exit doesn't lead anywhere.")
 					return true
 				}
+				if g.robotBarred(d, exitRef, dest) {
+					return true
+				}
 				// Check exit lock
 				if !CouldDoIt(g, d.Player, exitRef, aLock) {
 					HandleLockFailure(g, d, exitRef, aFail, aOFail, aAFail, "You can't go that way.")
 					return true
 				}
-				// Exit SUCC (4) to player, OSUCC (1) to room, ASUCC (12) action
-				if succ := g.GetAttrText(exitRef, 4); succ != "" {
-					ctx := MakeEvalContextForObj(g, exitRef, d.Player, func(c *eval.EvalContext) {
-						functions.RegisterAll(c)
-					})
-					msg := ctx.Exec(succ, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
-					if msg != "" {
-						d.Send(msg)
-					}
-				}
-				// OSUCC: prepend player name, skip if player is DARK
-				if osucc := g.GetAttrText(exitRef, 1); osucc != "" {
-					pObj := g.DB.Objects[d.Player]
-					if pObj != nil && !pObj.HasFlag(gamedb.FlagDark) {
-						ctx := MakeEvalContextForObj(g, exitRef, d.Player, func(c *eval.EvalContext) {
-							functions.RegisterAll(c)
-						})
-						msg := ctx.Exec(osucc, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
-						if msg != "" {
-							g.Conns.SendToRoomExcept(g.DB, loc, d.Player,
-								DisplayName(pObj.Name)+" "+msg)
-						}
-					}
+				g.MoveObject(d.Player, dest, move{cause: gamedb.Nothing, exit: exitRef})
+				if exitObj.Location == gamedb.Nothing || exitObj.Location == gamedb.Home {
+					dest = gamedb.Home
 				}
-				g.QueueAttrAction(exitRef, d.Player, 12, nil) // exit ASUCC
-				g.MovePlayer(d, dest)
+				g.leadFollowers(d.Player, loc, exitRef, dest, nil)
 				return true
 			}
 		}
@@ -793,6 +980,40 @@ func tryMoveByExit(g *Game, d *Descriptor, name string) bool {
 	return false
 }
 
+// variableExitDest works out where an exit linked to "variable" leads
+// for player, by evaluating the exit's ExitTo attribute. The result must
+// be "home" or a room or thing the exit's owner could link to, unless
+// the owner is a Wizard or has the link_variable power.
+func (g *Game) variableExitDest(exit, player gamedb.DBRef) (gamedb.DBRef, bool) {
+	text := g.GetAttrText(exit, gamedb.A_EXITVARDEST)
+	if text == "" {
+		return gamedb.Nothing, false
+	}
+	ctx := MakeEvalContextForObj(g, exit, player, func(c *eval.EvalContext) {
+		functions.RegisterAll(c)
+	})
+	result := strings.TrimSpace(ctx.Exec(text, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil))
+	if strings.EqualFold(result, "home") {
+		return gamedb.Home, true
+	}
+	if !strings.HasPrefix(result, "#") {
+		return gamedb.Nothing, false
+	}
+	dest, err := parseDBRef(result)
+	destObj, ok := g.DB.Objects[dest]
+	if err != nil || !ok || destObj.IsGoing() {
+		return gamedb.Nothing, false
+	}
+	if t := destObj.ObjType(); t != gamedb.TypeRoom && t != gamedb.TypeThing {
+		return gamedb.Nothing, false
+	}
+	owner := ResolveOwner(g, exit)
+	if !g.canLinkVariable(owner) && !g.canLinkTo(owner, dest) {
+		return gamedb.Nothing, false
+	}
+	return dest, true
+}
+
 // matchesExitFromList checks if cmd matches any alias in a semicolon-separated
 // alias list (like EALIAS/LALIAS values). Uses case-insensitive prefix matching,
 // matching C TinyMUSH's matches_exit_from_list behavior.
@@ -871,13 +1092,21 @@ func cmdHome(g *Game, d *Descriptor, _ string, _ []string) {
 		d.Send("You have no home!")
 		return
 	}
+	if Fixed(g, d.Player) {
+		d.Send(g.fixedHomeMessage())
+		return
+	}
 	d.Send("There's no place like home...")
 	g.MovePlayer(d, home)
 }
 
 // --- Information Commands ---
 
-func cmdLook(g *Game, d *Descriptor, args string, _ []string) {
+func cmdLook(g *Game, d *Descriptor, args string, switches []string) {
+	if HasSwitch(switches, "outside") {
+		g.lookOutside(d, args)
+		return
+	}
 	if args == "" || strings.EqualFold(args, "here") {
 		// Look at current room
 		loc := g.PlayerLocation(d.Player)
@@ -890,6 +1119,11 @@ func cmdLook(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send("I don't know which one you mean!")
 		return
 	}
+	if target == gamedb.Nothing && strings.EqualFold(args, "out") {
+		// 'look out' from inside a thing looks at what's around it.
+		g.lookOutside(d, "")
+		return
+	}
 	if target == gamedb.Nothing {
 		d.Send("I don't see that here.")
 		return
@@ -897,7 +1131,32 @@ func cmdLook(g *Game, d *Descriptor, args string, _ []string) {
 	g.ShowObject(d, target)
 }
 
-func cmdExamine(g *Game, d *Descriptor, args string, _ []string) {
+// lookOutside implements look/outside: from inside a thing, look at the
+// room it's in, or at something in that room.
+func (g *Game) lookOutside(d *Descriptor, args string) {
+	container := g.PlayerLocation(d.Player)
+	cObj, ok := g.DB.Objects[container]
+	if !ok || cObj.ObjType() == gamedb.TypeRoom || cObj.Location == gamedb.Nothing {
+		d.Send("You can't look outside.")
+		return
+	}
+	if args == "" {
+		g.ShowRoom(d, cObj.Location)
+		return
+	}
+	target := g.MatchObject(container, args)
+	if target == gamedb.Ambiguous {
+		d.Send("I don't know which one you mean!")
+		return
+	}
+	if target == gamedb.Nothing || target == container {
+		d.Send("I don't see that here.")
+		return
+	}
+	g.ShowObject(d, target)
+}
+
+func cmdExamine(g *Game, d *Descriptor, args string, switches []string) {
 	if args == "" {
 		// C TinyMUSH: bare "examine" examines the player's location
 		args = "here"
@@ -921,6 +1180,15 @@ func cmdExamine(g *Game, d *Descriptor, args string, _ []string) {
 		return
 	}
 
+	if HasSwitch(switches, "debug") {
+		if !Wizard(g, d.Player) {
+			d.Send("Permission denied.")
+			return
+		}
+		g.debugExamine(d, target)
+		return
+	}
+
 	// Check if player can examine this object
 	if !Examinable(g, d.Player, target) {
 		// Non-examinable: just show the description like look
@@ -939,7 +1207,7 @@ func cmdExamine(g *Game, d *Descriptor, args string, _ []string) {
 			return
 		}
 		found := false
-		for _, attr := range obj.Attrs {
+		for _, attr := range obj.Attributes() {
 			name := g.DB.GetAttrName(attr.Number)
 			if name == "" {
 				name = fmt.Sprintf("ATTR_%d", attr.Number)
@@ -980,7 +1248,10 @@ func cmdExamine(g *Game, d *Descriptor, args string, _ []string) {
 		return
 	}
 
-	g.ShowExamine(d, target)
+	g.showExamine(d, target, examineOpts{
+		brief:  HasSwitch(switches, "brief"),
+		parent: HasSwitch(switches, "parent"),
+	})
 }
 
 func cmdInventory(g *Game, d *Descriptor, _ string, _ []string) {
@@ -1035,19 +1306,34 @@ func cmdDig(g *Game, d *Descriptor, args string, _ []string) {
 	// @dig name[=exit_to[;alias],exit_from[;alias]]
 	parts := strings.SplitN(args, "=", 2)
 	roomName := strings.TrimSpace(parts[0])
+	if !g.payFor(d.Player, g.digCost()) {
+		g.notEnoughMoney(d)
+		return
+	}
 
 	newRef := g.CreateObject(roomName, gamedb.TypeRoom, d.Player)
 	d.Send(fmt.Sprintf("Room %s created as #%d.", roomName, newRef))
 
-	// Handle exit creation if specified
+	// Handle exit creation if specified; each costs open_cost plus
+	// link_cost.
 	if len(parts) > 1 {
 		exitParts := strings.SplitN(parts[1], ",", 2)
-		if exitParts[0] != "" {
+		loc := g.PlayerLocation(d.Player)
+		exitCost := g.openCost() + g.linkCost()
+		if exitParts[0] != "" && !Controls(g, d.Player, loc) {
+			d.Send("Permission denied.")
+		} else if exitParts[0] != "" && !g.payFor(d.Player, exitCost) {
+			g.notEnoughMoney(d)
+		} else if exitParts[0] != "" {
 			exitTo := strings.TrimSpace(exitParts[0])
-			exitRef := g.CreateExit(exitTo, g.PlayerLocation(d.Player), newRef, d.Player)
+			exitRef := g.CreateExit(exitTo, loc, newRef, d.Player)
 			d.Send(fmt.Sprintf("Exit %s created as #%d.", exitTo, exitRef))
 		}
-		if len(exitParts) > 1 && exitParts[1] != "" {
+		if len(exitParts) > 1 && exitParts[1] != "" && !g.canLinkTo(d.Player, loc) {
+			d.Send("You can't link to that.")
+		} else if len(exitParts) > 1 && exitParts[1] != "" && !g.payFor(d.Player, exitCost) {
+			g.notEnoughMoney(d)
+		} else if len(exitParts) > 1 && exitParts[1] != "" {
 			exitFrom := strings.TrimSpace(exitParts[1])
 			exitRef := g.CreateExit(exitFrom, newRef, g.PlayerLocation(d.Player), d.Player)
 			d.Send(fmt.Sprintf("Exit %s created as #%d.", exitFrom, exitRef))
@@ -1063,11 +1349,28 @@ func cmdOpen(g *Game, d *Descriptor, args string, _ []string) {
 	// @open exit_name=destination
 	parts := strings.SplitN(args, "=", 2)
 	exitName := strings.TrimSpace(parts[0])
+	loc := g.PlayerLocation(d.Player)
+	if !Controls(g, d.Player, loc) {
+		d.Send("Permission denied.")
+		return
+	}
 	dest := gamedb.Nothing
 	if len(parts) > 1 {
 		dest = g.ResolveRef(d.Player, strings.TrimSpace(parts[1]))
+		if dest != gamedb.Nothing && !g.canLinkTo(d.Player, dest) {
+			d.Send("You can't link to that.")
+			return
+		}
+	}
+	// open_cost, plus link_cost if it leads somewhere.
+	cost := g.openCost()
+	if dest != gamedb.Nothing {
+		cost += g.linkCost()
+	}
+	if !g.payFor(d.Player, cost) {
+		g.notEnoughMoney(d)
+		return
 	}
-	loc := g.PlayerLocation(d.Player)
 	exitRef := g.CreateExit(exitName, loc, dest, d.Player)
 	d.Send(fmt.Sprintf("Exit %s created as #%d.", exitName, exitRef))
 }
@@ -1111,6 +1414,9 @@ func cmdRename(g *Game, d *Descriptor, args string, _ []string) {
 		if obj.ObjType() == gamedb.TypePlayer && g.Store != nil {
 			g.Store.UpdatePlayerIndex(obj, oldName)
 		}
+		if obj.ObjType() == gamedb.TypePlayer && g.isSuspect(target) {
+			g.reportSuspect(target, "name", fmt.Sprintf("was renamed from %s.", oldName))
+		}
 		d.Send("Name set.")
 	}
 }
@@ -1121,6 +1427,7 @@ func cmdEval(g *Game, d *Descriptor, args string, _ []string) {
 	ctx := MakeEvalContextWithGame(g, d.Player, func(c *eval.EvalContext) {
 		functions.RegisterAll(c)
 	})
+	defer ctx.Release()
 	result := ctx.Exec(args, eval.EvFCheck|eval.EvEval, nil)
 	d.Send(result)
 }
@@ -1137,6 +1444,7 @@ func cmdQuit(g *Game, d *Descriptor, _ string, _ []string) {
 	} else {
 		d.Send("Going home.")
 	}
+	d.DisconnectReason = "quit"
 	g.DisconnectPlayer(d)
 }
 
@@ -1154,9 +1462,40 @@ func cmdReadCache(g *Game, d *Descriptor, _ string, _ []string) {
 	d.Send(fmt.Sprintf("Text file cache reloaded. %d file(s) loaded from %s.", count, g.TextDir))
 }
 
-func cmdSetDoing(g *Game, d *Descriptor, args string, _ []string) {
-	d.DoingStr = args
-	d.Send("Set.")
+// cmdSession lists network session details (C TinyMUSH SESSION): port plus
+// the characters received and the pending, lost and total characters sent.
+// Input runs as it arrives, a line at a time, so unlike C there is none
+// pending or lost to show. WizRoy sees every connected player (optionally
+// only names starting with a prefix); others see only their own
+// connections.
+func cmdSession(g *Game, d *Descriptor, args string, _ []string) {
+	seeAll := WizRoy(g, d.Player)
+	prefix := strings.ToLower(strings.TrimSpace(args))
+	now := g.Now()
+	descs := g.Conns.AllDescriptors()
+	sort.Slice(descs, func(i, j int) bool { return descs[i].ID < descs[j].ID })
+
+	d.Send("                                     Input  Characters Output---")
+	d.Send("Player Name     On For Idle Port     Total  Pend  Lost     Total")
+	count := 0
+	for _, dd := range descs {
+		if dd.State != ConnConnected {
+			continue
+		}
+		count++
+		if !seeAll && dd.Player != d.Player {
+			continue
+		}
+		name := g.PlayerName(dd.Player)
+		if prefix != "" && !strings.HasPrefix(strings.ToLower(name), prefix) {
+			continue
+		}
+		traffic := dd.Traffic()
+		d.Send(fmt.Sprintf("%-15s%7s%5s%5d%10d%6d%6d%10d",
+			name, FormatConnTime(now.Sub(dd.ConnTime)), FormatIdleTime(now.Sub(dd.LastCmd)),
+			dd.ID, traffic.Recv, traffic.Pending, traffic.Flushed, traffic.Sent))
+	}
+	d.Send(fmt.Sprintf("%d Players logged in.", count))
 }
 
 // --- Game Helper Methods ---
@@ -1176,7 +1515,11 @@ type Game struct {
 	Mail        *Mail            // Built-in mail system (nil if disabled)
 	Conf        *GameConf        // Game configuration from conf file
 	FuncAliases map[string]string // Function aliases (alias -> target, uppercase)
-	BadNames    []string          // Forbidden player names from alias config
+	BadNames    []string          // Forbidden player names from alias config and @aliases
+	CmdAliases  map[string]string // Command aliases (alias -> target), for @aliases
+	ConfAliases map[string]*gamedb.ConfAlias // Aliases added with @aliases, by "<kind>:<name>"
+	Doings      map[gamedb.DBRef]string      // Persisted @doing messages, restored at login
+	WhoPoll     string                       // WHO column header set with @doing/header
 	HelpMain    *HelpFile         // help.txt
 	HelpQuick   *HelpFile         // qhelp.txt
 	HelpWiz     *HelpFile         // wizhelp.txt
@@ -1192,16 +1535,45 @@ type Game struct {
 	Spell       *SpellChecker     // Spellcheck engine (nil if disabled)
 	SQLDB       *SQLStore         // SQLite3 database (nil if disabled)
 	GameFuncs   map[string]*eval.UFunction // @function-defined functions (uppercase name -> def)
+	Runtime     map[string]*gamedb.RuntimeSetting // @motd, @function and @admin/save settings kept in bolt, by "<kind>:<name>"
 	ConfPath    string   // Path to game config file (for archive)
 	DictDir     string   // Path to dictionary directory (for archive)
 	AliasConfs  []string // Paths to alias config files (for archive)
 	ArchiveDir  string   // Path to archive output directory
 	EventBus    *events.Bus // Structured event bus for multi-transport output
+	IRC         *IRCBridge  // Comsys-to-IRC bridge (nil if disabled)
+	Discord     *DiscordRelay // Comsys-to-Discord relay (nil if disabled)
+	Replication *replication.Primary // Streams bolt writes to standbys (nil if disabled)
 	Guests      *GuestManager // Guest player tracking and cleanup
+	Sites       *SiteSecurity // @site rules and connection throttling (nil = no checks)
+	Scenes      *SceneRecorder // +scene logging (nil = disabled)
+	Registrations *RegistrationQueue // Character requests awaiting approval
+	forwarding  map[gamedb.DBRef]bool // Objects currently relaying via FORWARDLIST (loop guard)
+	puppeting   map[gamedb.DBRef]bool // PUPPETs currently relaying to their owners (loop guard)
+	nextArchive time.Time // When the next auto-archive is due (zero = disabled)
+	ConnLog     *ConnLog  // Per-player connection history for @last (nil = not kept)
+	Cron        *CronTab  // @cron schedule
+	lastDaily   string    // Date @daily last ran, as 2006-01-02
+	payMu       sync.Mutex
+	paidOn      map[gamedb.DBRef]string // Date each player last got a paycheck
+	httpMu      sync.Mutex
+	httpSent    map[gamedb.DBRef][]time.Time // Recent @http/httpget() requests per owner (http_rate_limit)
+	pageSent    map[gamedb.DBRef][]time.Time // Recent pages per player (page_rate_limit)
+	followers   map[gamedb.DBRef][]gamedb.DBRef // Who follows each leader through exits
+	watches     watchList // @watch command traces, by watched object
+	dollars     map[gamedb.DBRef]*dollarList // Parsed $-commands, by object (see dollarcmds.go)
 	objExecDepth int // Recursion depth counter for ExecuteAsObject
-	objExecCount map[gamedb.DBRef]int // Per-object execution counter for rate limiting
-	objExecCountReset time.Time // When the counter was last reset
+	runningRData *eval.RegisterData // q-registers of the queue entry being run, for @program
+	ended        bool               // An @end stopped the action list being run (see endcmd.go)
+	adminUnsaved map[string]*gamedb.RuntimeSetting // @admin changes not yet saved with @admin/save, by parameter
+	motdTurn     int                                // Rotating MOTDs shown so far, to pick the next (see motd.go)
+	ownerExecCount map[gamedb.DBRef]int // Per-owner queue executions this second (queue_owner_rate)
+	ownerExecCountReset time.Time // When the counter was last reset
 	queueWake chan struct{} // Signal to wake queue processor immediately (player input)
+	shuttingDown atomic.Bool // Set once Shutdown starts, so /ready stops reporting ready
+	Clock       func() time.Time // Game time source; nil means the system clock (see clock.go)
+	warp        atomic.Int64     // @timewarp offset from Clock, in nanoseconds
+	world       sync.Mutex // Held while the game's state is read or changed (see world.go)
 	PeakPlayers int        // Historical peak connected player count
 	StartTime   time.Time  // Server start time
 }
@@ -1222,22 +1594,35 @@ func (g *Game) EmitRoomExcept(room gamedb.DBRef, except gamedb.DBRef, ev events.
 }
 
 // PersistObject writes a single object to the bolt store (no-op if Store is nil).
+// Every change to an object ends here, so it also keeps the player name
+// index current.
 func (g *Game) PersistObject(obj *gamedb.Object) {
-	if g.Store == nil || obj == nil {
+	if obj == nil {
+		return
+	}
+	g.DB.IndexPlayer(obj)
+	g.forgetDollars(obj)
+	if g.Store == nil {
 		return
 	}
 	if err := g.Store.PutObject(obj); err != nil {
-		log.Printf("ERROR: persist object #%d: %v", obj.DBRef, err)
+		Logf(LogBugs, LevelError, "persist object #%d: %v", obj.DBRef, err)
 	}
 }
 
 // PersistObjects writes multiple objects to the bolt store in one transaction.
 func (g *Game) PersistObjects(objs ...*gamedb.Object) {
+	for _, obj := range objs {
+		if obj != nil {
+			g.DB.IndexPlayer(obj)
+			g.forgetDollars(obj)
+		}
+	}
 	if g.Store == nil {
 		return
 	}
 	if err := g.Store.PutObjects(objs...); err != nil {
-		log.Printf("ERROR: persist objects: %v", err)
+		Logf(LogBugs, LevelError, "persist objects: %v", err)
 	}
 }
 
@@ -1258,7 +1643,7 @@ func NewGame(db *gamedb.Database) *Game {
 	bus := events.NewBus()
 	cm := NewConnManager()
 	cm.EventBus = bus
-	return &Game{
+	g := &Game{
 		DB:        db,
 		Conns:     cm,
 		Commands:  InitCommands(),
@@ -1267,8 +1652,16 @@ func NewGame(db *gamedb.Database) *Game {
 		GameFuncs: make(map[string]*eval.UFunction),
 		EventBus:  bus,
 		Guests:    NewGuestManager(),
+		Sites:     NewSiteSecurity(),
+		Registrations: NewRegistrationQueue(),
+		ConnLog:   NewConnLog(),
+		Cron:      NewCronTab(),
 		queueWake: make(chan struct{}, 1),
 	}
+	cm.AnsiFlags = g.ansiFlags
+	g.initQueueLimits()
+	g.initQueueDeposits()
+	return g
 }
 
 // stringMatchWord implements C TinyMUSH's string_match: checks if sub is a prefix
@@ -1346,84 +1739,9 @@ func (g *Game) PlayerLocation(player gamedb.DBRef) gamedb.DBRef {
 	return gamedb.Nothing
 }
 
-// MovePlayer moves a player to a new location.
+// MovePlayer walks a player to a new location.
 func (g *Game) MovePlayer(d *Descriptor, dest gamedb.DBRef) {
-	player := d.Player
-	playerObj, ok := g.DB.Objects[player]
-	if !ok {
-		return
-	}
-
-	oldLoc := playerObj.Location
-	isDark := playerObj.HasFlag(gamedb.FlagDark)
-
-	// Source room: ALEAVE action (52), OLEAVE to room (51)
-	if oldLoc != gamedb.Nothing {
-		if !isDark {
-			g.QueueAttrAction(oldLoc, player, 52, nil) // ALEAVE
-			if oleave := g.GetAttrText(oldLoc, 51); oleave != "" {
-				ctx := MakeEvalContextForObj(g, oldLoc, player, func(c *eval.EvalContext) {
-					functions.RegisterAll(c)
-				})
-				msg := ctx.Exec(oleave, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
-				if msg != "" {
-					g.Conns.SendToRoomExcept(g.DB, oldLoc, player,
-						DisplayName(playerObj.Name)+" "+msg)
-				}
-			} else {
-				g.Conns.SendToRoomExcept(g.DB, oldLoc, player,
-					fmt.Sprintf("%s has left.", DisplayName(playerObj.Name)))
-			}
-		}
-		g.RemoveFromContents(oldLoc, player)
-	}
-
-	// Set new location
-	playerObj.Location = dest
-
-	// Add to new location's contents chain
-	g.AddToContents(dest, player)
-
-	// Announce arrival (default, before ShowRoom evaluates OSUCC)
-	if !isDark {
-		g.Conns.SendToRoomExcept(g.DB, dest, player,
-			fmt.Sprintf("%s has arrived.", DisplayName(playerObj.Name)))
-	}
-
-	// Persist moved player and affected rooms
-	persistList := []*gamedb.Object{playerObj}
-	if oldLoc != gamedb.Nothing {
-		if oldLocObj, ok := g.DB.Objects[oldLoc]; ok {
-			persistList = append(persistList, oldLocObj)
-		}
-	}
-	if destObj, ok := g.DB.Objects[dest]; ok {
-		persistList = append(persistList, destObj)
-	}
-	g.PersistObjects(persistList...)
-
-	// Show the room to the player (DESC + SUCC + CONFORMAT/EXITFORMAT)
-	// ShowRoom handles SUCC/OSUCC/ASUCC display via the lock-check path.
-	g.ShowRoom(d, dest)
-
-	// Dest room: AENTER action (35), OENTER to room (53) - skip if DARK
-	if !isDark {
-		g.QueueAttrAction(dest, player, 35, nil) // AENTER
-		if oenter := g.GetAttrText(dest, 53); oenter != "" {
-			ctx := MakeEvalContextForObj(g, dest, player, func(c *eval.EvalContext) {
-				functions.RegisterAll(c)
-			})
-			msg := ctx.Exec(oenter, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
-			if msg != "" {
-				g.Conns.SendToRoomExcept(g.DB, dest, player,
-					DisplayName(playerObj.Name)+" "+msg)
-			}
-		}
-
-		// Notify listeners on arrival
-		g.MatchListenPatterns(dest, player,
-			fmt.Sprintf("%s has arrived.", DisplayName(playerObj.Name)))
-	}
+	g.MoveObject(d.Player, dest, walk)
 }
 
 // RemoveFromContents removes an object from a location's contents chain.
@@ -1491,6 +1809,23 @@ func (g *Game) AddToContents(dest, obj gamedb.DBRef) {
 
 // ShowRoom displays a room to a player.
 func (g *Game) ShowRoom(d *Descriptor, room gamedb.DBRef) {
+	g.lookIn(d, room, false)
+}
+
+// ShowArrival shows a room to a player who has just moved into it (C
+// TinyMUSH look_in with LK_OBEYTERSE). TERSE players get only its name:
+// no description, SUCC/FAIL message, contents or exits, though others
+// still see OSUCC/OFAIL and ASUCC/AFAIL still run. An explicit look
+// always shows everything.
+func (g *Game) ShowArrival(d *Descriptor, room gamedb.DBRef) {
+	terse := false
+	if pObj, ok := g.DB.Objects[d.Player]; ok {
+		terse = pObj.HasFlag(gamedb.FlagTerse)
+	}
+	g.lookIn(d, room, terse)
+}
+
+func (g *Game) lookIn(d *Descriptor, room gamedb.DBRef, terse bool) {
 	roomObj, ok := g.DB.Objects[room]
 	if !ok {
 		d.Send("You see nothing special.")
@@ -1523,7 +1858,7 @@ func (g *Game) ShowRoom(d *Descriptor, room gamedb.DBRef) {
 		}
 	}
 	desc := g.GetAttrText(room, descAttr)
-	if desc != "" {
+	if desc != "" && !terse {
 		ctx := makeCtx()
 		evaluated := ctx.Exec(desc, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
 		d.Send(evaluated)
@@ -1538,7 +1873,7 @@ func (g *Game) ShowRoom(d *Descriptor, room gamedb.DBRef) {
 	succShown := false
 	if roomObj.ObjType() == gamedb.TypeRoom {
 		if CouldDoIt(g, d.Player, room, aLock) {
-			if succ := g.GetAttrText(room, 4); succ != "" { // A_SUCC
+			if succ := g.GetAttrText(room, 4); succ != "" && !terse { // A_SUCC
 				ctx := makeCtx()
 				msg := ctx.Exec(succ, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
 				if msg != "" {
@@ -1554,10 +1889,15 @@ func (g *Game) ShowRoom(d *Descriptor, room gamedb.DBRef) {
 				}
 			}
 			g.QueueAttrAction(room, d.Player, 12, nil) // A_ASUCC
+		} else if terse {
+			g.moveDidIt(d.Player, room, 0, aOFail, aAFail, false)
 		} else {
 			HandleLockFailure(g, d, room, aFail, aOFail, aAFail, "")
 		}
 	}
+	if terse {
+		return
+	}
 
 	// Build list of visible content dbrefs (excluding the looking player)
 	var contentRefs []gamedb.DBRef
@@ -1681,6 +2021,12 @@ func (g *Game) ShowRoom(d *Descriptor, room gamedb.DBRef) {
 
 	// ADESC (36) — action list executed on the room when looked at
 	g.QueueAttrAction(room, d.Player, 36, nil) // A_ADESC
+
+	// From inside a TRANSPARENT thing you can also see out.
+	if roomObj.ObjType() == gamedb.TypeThing && roomObj.HasFlag(gamedb.FlagSeeThru) && roomObj.Location != gamedb.Nothing {
+		d.Send("Outside:")
+		g.ShowRoom(d, roomObj.Location)
+	}
 }
 
 // ShowObject displays an object to a player.
@@ -1719,8 +2065,18 @@ func (g *Game) ShowObject(d *Descriptor, target gamedb.DBRef) {
 	g.QueueAttrAction(target, d.Player, 36, nil) // A_ADESC
 }
 
+// examineOpts are the examine switches that change what ShowExamine lists.
+type examineOpts struct {
+	brief  bool // no attributes
+	parent bool // attributes inherited from parents too
+}
+
 // ShowExamine shows detailed object info (wizard/owner command).
 func (g *Game) ShowExamine(d *Descriptor, target gamedb.DBRef) {
+	g.showExamine(d, target, examineOpts{})
+}
+
+func (g *Game) showExamine(d *Descriptor, target gamedb.DBRef, opts examineOpts) {
 	obj, ok := g.DB.Objects[target]
 	if !ok {
 		d.Send("I don't see that here.")
@@ -1805,39 +2161,16 @@ func (g *Game) ShowExamine(d *Descriptor, target gamedb.DBRef) {
 	}
 
 	// Show attributes with permission checks
-	// Resolve the object's resolved owner for annotation comparison
-	objResolvedOwner := ResolveOwner(g, target)
-	for _, attr := range obj.Attrs {
-		info := ParseAttrInfo(attr.Value)
-		def := g.LookupAttrDef(attr.Number)
-		if !CanReadAttr(g, d.Player, target, def, info.Flags, info.Owner) {
-			continue
-		}
-		name := g.DB.GetAttrName(attr.Number)
-		if name == "" {
-			name = fmt.Sprintf("ATTR_%d", attr.Number)
-		}
-		text := eval.StripAttrPrefix(attr.Value)
-		// C TinyMUSH: if attr has AF_IS_LOCK, parse through boolexp for human-readable names
-		if def != nil && def.Flags&gamedb.AFIsLock != 0 && text != "" {
-			parsed := ParseBoolExp(g, d.Player, text)
-			if parsed != nil {
-				text = UnparseBoolExp(g, parsed)
+	if !opts.brief {
+		seen := make(map[int]bool)
+		for _, attr := range obj.Attributes() {
+			seen[attr.Number] = true
+			if line, ok := g.examAttrLine(d.Player, target, gamedb.Nothing, attr, truncLen); ok {
+				d.Send(line)
 			}
 		}
-		if truncLen > 0 && len(text) > truncLen {
-			text = text[:truncLen] + "..."
-		}
-		// C TinyMUSH: only show annotation if player controls object or owns attr
-		showAnnotation := Controls(g, d.Player, target) || info.Owner == d.Player
-		annotation := ""
-		if showAnnotation {
-			annotation = attrAnnotation(g, d.Player, target, objResolvedOwner, info, def)
-		}
-		if annotation != "" {
-			d.Send(fmt.Sprintf("  %s %s: %s", name, annotation, text))
-		} else {
-			d.Send(fmt.Sprintf("  %s: %s", name, text))
+		if opts.parent {
+			g.showInheritedAttrs(d, target, seen, truncLen)
 		}
 	}
 
@@ -1917,6 +2250,128 @@ func (g *Game) ShowExamine(d *Descriptor, target gamedb.DBRef) {
 	}
 }
 
+// examAttrLine formats one of obj's attributes for examine as player sees
+// it: the name, an owner/flags annotation for those who control obj or
+// own the attribute, and the text, with locks unparsed and cut to
+// truncLen if that's set. An attribute inherited by another object is
+// marked "[from #obj]" after its name. ok is false if player can't read
+// the attribute.
+func (g *Game) examAttrLine(player, obj, heir gamedb.DBRef, attr gamedb.Attribute, truncLen int) (line string, ok bool) {
+	info := ParseAttrInfo(attr.Value)
+	def := g.LookupAttrDef(attr.Number)
+	if !CanReadAttr(g, player, obj, def, info.Flags, info.Owner) {
+		return "", false
+	}
+	name := g.DB.GetAttrName(attr.Number)
+	if name == "" {
+		name = fmt.Sprintf("ATTR_%d", attr.Number)
+	}
+	if heir != gamedb.Nothing {
+		name += fmt.Sprintf(" [from #%d]", obj)
+	}
+	text := eval.StripAttrPrefix(attr.Value)
+	// C TinyMUSH: if attr has AF_IS_LOCK, parse through boolexp for human-readable names
+	if def != nil && def.Flags&gamedb.AFIsLock != 0 && text != "" {
+		parsed := ParseBoolExp(g, player, text)
+		if parsed != nil {
+			text = UnparseBoolExp(g, parsed)
+		}
+	}
+	if truncLen > 0 && len(text) > truncLen {
+		text = text[:truncLen] + "..."
+	}
+	// C TinyMUSH: only show annotation if player controls object or owns attr
+	if Controls(g, player, obj) || info.Owner == player {
+		if annotation := attrAnnotation(g, player, obj, ResolveOwner(g, obj), info, def); annotation != "" {
+			return fmt.Sprintf("  %s %s: %s", name, annotation, text), true
+		}
+	}
+	return fmt.Sprintf("  %s: %s", name, text), true
+}
+
+// showInheritedAttrs lists the attributes target gets from its parent
+// chain (examine/parent): the nearest parent's copy of each one not in
+// seen, skipping PRIVATE attributes, which aren't inherited.
+func (g *Game) showInheritedAttrs(d *Descriptor, target gamedb.DBRef, seen map[int]bool, truncLen int) {
+	visited := map[gamedb.DBRef]bool{target: true}
+	cur := g.DB.Objects[target].Parent
+	for depth := 0; depth < 10 && cur != gamedb.Nothing && !visited[cur]; depth++ {
+		visited[cur] = true
+		pObj, ok := g.DB.Objects[cur]
+		if !ok {
+			return
+		}
+		for _, attr := range pObj.Attributes() {
+			if seen[attr.Number] {
+				continue
+			}
+			seen[attr.Number] = true
+			info := ParseAttrInfo(attr.Value)
+			if info.Flags&gamedb.AFPrivate != 0 {
+				continue
+			}
+			if def := g.LookupAttrDef(attr.Number); def != nil && def.Flags&gamedb.AFPrivate != 0 {
+				continue
+			}
+			if line, ok := g.examAttrLine(d.Player, cur, target, attr, truncLen); ok {
+				d.Send(line)
+			}
+		}
+		cur = pObj.Parent
+	}
+}
+
+// debugExamine shows an object's raw fields and attributes for wizards
+// (examine/debug, C TinyMUSH debug_examine): dbrefs as numbers, and the
+// text of each attribute they can read exactly as stored, with its
+// owner and flags.
+func (g *Game) debugExamine(d *Descriptor, target gamedb.DBRef) {
+	obj, ok := g.DB.Objects[target]
+	if !ok {
+		d.Send("I don't see that here.")
+		return
+	}
+	d.Send(fmt.Sprintf("Number   = %d", target))
+	d.Send(fmt.Sprintf("Name     = %s", obj.Name))
+	d.Send(fmt.Sprintf("Location = %d", obj.Location))
+	d.Send(fmt.Sprintf("Contents = %d", obj.Contents))
+	d.Send(fmt.Sprintf("Exits    = %d", obj.Exits))
+	d.Send(fmt.Sprintf("Link     = %d", obj.Link))
+	d.Send(fmt.Sprintf("Next     = %d", obj.Next))
+	d.Send(fmt.Sprintf("Parent   = %d", obj.Parent))
+	d.Send(fmt.Sprintf("Owner    = %d", obj.Owner))
+	d.Send(fmt.Sprintf("Zone     = %d", obj.Zone))
+	d.Send(fmt.Sprintf("Pennies  = %d", obj.Pennies))
+	d.Send(flagDescription(g, d.Player, obj))
+	if pwrStr := powerDescription(obj); pwrStr != "" {
+		d.Send(pwrStr)
+	}
+	var attrs []gamedb.Attribute
+	var names []string
+	for _, attr := range obj.Attributes() {
+		info := ParseAttrInfo(attr.Value)
+		if !CanReadAttr(g, d.Player, target, g.LookupAttrDef(attr.Number), info.Flags, info.Owner) {
+			continue
+		}
+		name := g.DB.GetAttrName(attr.Number)
+		if name == "" {
+			name = fmt.Sprintf("ATTR_%d", attr.Number)
+		}
+		attrs = append(attrs, attr)
+		names = append(names, name)
+	}
+	d.Send("Attr list: " + strings.Join(names, " "))
+	d.Send("Attributes:")
+	for i, attr := range attrs {
+		info := ParseAttrInfo(attr.Value)
+		header := fmt.Sprintf("#%d", info.Owner)
+		if flags := attrFlagString(info.Flags); flags != "" {
+			header += " " + flags
+		}
+		d.Send(fmt.Sprintf("  %s [%s]: %s", names[i], header, eval.StripAttrPrefix(attr.Value)))
+	}
+}
+
 // attrAnnotation builds a TinyMUSH-style annotation string for an attribute.
 // C TinyMUSH's view_atr shows: [#owner instance_flags(def_flags)]
 // Per-instance flags (aflags) and definition flags (ap->flags) are shown
@@ -2103,6 +2558,7 @@ var flagLetters = []flagEntry{
 	{0, gamedb.FlagVerbose, 'v', "VERBOSE", flagPermPublic},
 	{1, gamedb.Flag2Staff, 'w', "STAFF", flagPermPublic},
 	{1, gamedb.Flag2Slave, 'x', "SLAVE", flagPermWizard},
+	{1, gamedb.Flag2Suspect, 'u', "SUSPECT", flagPermWizard},
 	{1, gamedb.Flag2ControlOK, 'z', "CONTROL_OK", flagPermPublic},
 	{1, gamedb.Flag2StopMatch, '!', "STOP", flagPermPublic},
 	{1, gamedb.Flag2HasCommands, '$', "COMMANDS", flagPermPublic},
@@ -2329,10 +2785,8 @@ func (g *Game) LookupAttrNum(name string) int {
 		return def.Number
 	}
 	// Check well-known attrs
-	for num, n := range gamedb.WellKnownAttrs {
-		if strings.EqualFold(n, name) {
-			return num
-		}
+	if num, ok := gamedb.WellKnownAttrNum(name); ok {
+		return num
 	}
 	return -1
 }
@@ -2352,86 +2806,6 @@ func (g *Game) LookupAttrDef(attrNum int) *gamedb.AttrDef {
 	return nil
 }
 
-// ShowWho displays the WHO list.
-func (g *Game) ShowWho(d *Descriptor) {
-	isWiz := Wizard(g, d.Player)
-
-	now := time.Now()
-
-	// Header — matches C TinyMUSH dump_users() format
-	if isWiz {
-		d.Send("Player Name        On For Idle   Room    Cmds   Host")
-	} else {
-		d.Send(fmt.Sprintf("%-16s%9s %4s  %s", "Player Name", "On For", "Idle", "Doing"))
-	}
-
-	type whoEntry struct {
-		name  string
-		onFor string
-		idle  string
-		doing string
-		flags string
-		loc   gamedb.DBRef
-		cmds  int
-		host  string
-	}
-	var entries []whoEntry
-
-	descs := g.Conns.AllDescriptors()
-	for _, dd := range descs {
-		if dd.State != ConnConnected {
-			continue
-		}
-		// Hide DARK players from non-wizards
-		if !isWiz {
-			if pObj, ok := g.DB.Objects[dd.Player]; ok && pObj.HasFlag(gamedb.FlagDark) {
-				continue
-			}
-		}
-		name := g.PlayerName(dd.Player)
-		onFor := FormatConnTime(now.Sub(dd.ConnTime))
-		idle := FormatIdleTime(now.Sub(dd.LastCmd))
-		// Build player flags string (wizard WHO only)
-		var flags string
-		if isWiz {
-			if pObj, ok := g.DB.Objects[dd.Player]; ok {
-				if pObj.HasFlag(gamedb.FlagDark) {
-					flags += "D"
-				}
-			}
-		}
-		// Extract host/IP (strip port and IPv6 brackets)
-		host := dd.Addr
-		if idx := strings.LastIndex(host, ":"); idx >= 0 {
-			host = host[:idx]
-		}
-		host = strings.Trim(host, "[]")
-		loc := g.PlayerLocation(dd.Player)
-		entries = append(entries, whoEntry{name, onFor, idle, dd.DoingStr, flags, loc, dd.CmdCount, host})
-	}
-
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].name < entries[j].name
-	})
-
-	for _, e := range entries {
-		if isWiz {
-			// C format: "%-16s%9s %4s%-3s#%-6d%5d%3s%-25s"
-			d.Send(fmt.Sprintf("%-16s%9s %4s%-3s#%-6d%5d   %-25s",
-				e.name, e.onFor, e.idle, e.flags, e.loc, e.cmds, e.host))
-		} else {
-			d.Send(fmt.Sprintf("%-16s%9s %4s  %s", e.name, e.onFor, e.idle, e.doing))
-		}
-	}
-
-	count := len(entries)
-	peak := g.Conns.PeakPlayers
-	if count > peak {
-		peak = count
-	}
-	d.Send(fmt.Sprintf("%d Players logged in, %d record, no maximum.", count, peak))
-}
-
 // MatchObject resolves a name to a dbref, searching contents and location.
 func (g *Game) MatchObject(player gamedb.DBRef, name string) gamedb.DBRef {
 	name = strings.TrimSpace(name)
@@ -2458,22 +2832,7 @@ func (g *Game) MatchObject(player gamedb.DBRef, name string) gamedb.DBRef {
 	}
 	// Handle *player — global player name lookup
 	if name[0] == '*' {
-		pName := strings.ToLower(strings.TrimSpace(name[1:]))
-		if pName == "" {
-			return gamedb.Nothing
-		}
-		for ref, obj := range g.DB.Objects {
-			if obj.ObjType() != gamedb.TypePlayer {
-				continue
-			}
-			// Check name and aliases
-			for _, alias := range strings.Split(obj.Name, ";") {
-				if strings.EqualFold(strings.TrimSpace(alias), pName) {
-					return ref
-				}
-			}
-		}
-		return gamedb.Nothing
+		return g.DB.LookupPlayer(name[1:])
 	}
 
 	playerObj, ok := g.DB.Objects[player]
@@ -2717,10 +3076,8 @@ func (g *Game) getAttrTextWithParents(obj gamedb.DBRef, attrNum int, maxDepth in
 		if !ok {
 			return ""
 		}
-		for _, attr := range o.Attrs {
-			if attr.Number == attrNum {
-				return eval.StripAttrPrefix(attr.Value)
-			}
+		if raw, ok := o.Attr(attrNum); ok {
+			return eval.StripAttrPrefix(raw)
 		}
 		// Walk to parent
 		if o.Parent == gamedb.Nothing || o.Parent == current {
@@ -2737,12 +3094,8 @@ func (g *Game) GetAttrTextDirect(obj gamedb.DBRef, attrNum int) string {
 	if !ok {
 		return ""
 	}
-	for _, attr := range o.Attrs {
-		if attr.Number == attrNum {
-			return eval.StripAttrPrefix(attr.Value)
-		}
-	}
-	return ""
+	raw, _ := o.Attr(attrNum)
+	return eval.StripAttrPrefix(raw)
 }
 
 // SetAttr sets an attribute on an object, preserving existing per-instance flags.
@@ -2755,22 +3108,28 @@ func (g *Game) SetAttr(obj gamedb.DBRef, attrNum int, value string) {
 		return
 	}
 	owner := fmt.Sprintf("%d", o.Owner)
+	if attrNum == aDaily {
+		// HAS_DAILY marks objects the scheduler runs @daily on.
+		if value == "" {
+			o.Flags[1] &^= gamedb.Flag2HasDaily
+		} else {
+			o.Flags[1] |= gamedb.Flag2HasDaily
+		}
+	}
 
-	for i, attr := range o.Attrs {
-		if attr.Number == attrNum {
-			if value == "" {
-				// C TinyMUSH: atr_add with empty value calls atr_clr to delete the attr.
-				// Remove the attribute so parent chain inheritance works correctly.
-				o.Attrs = append(o.Attrs[:i], o.Attrs[i+1:]...)
-				g.PersistObject(o)
-				return
-			}
-			existing := ParseAttrInfo(attr.Value)
-			fullValue := fmt.Sprintf("\x01%s:%d:%s", owner, existing.Flags, value)
-			o.Attrs[i].Value = fullValue
+	if i := o.AttrPos(attrNum); i >= 0 {
+		if value == "" {
+			// C TinyMUSH: atr_add with empty value calls atr_clr to delete the attr.
+			// Remove the attribute so parent chain inheritance works correctly.
+			o.SetAttributes(slices.Delete(o.Attributes(), i, i+1))
 			g.PersistObject(o)
 			return
 		}
+		existing := ParseAttrInfo(o.Attributes()[i].Value)
+		fullValue := fmt.Sprintf("\x01%s:%d:%s", owner, existing.Flags, value)
+		o.Attributes()[i].Value = fullValue
+		g.PersistObject(o)
+		return
 	}
 
 	// If value is empty and attr doesn't exist, nothing to do.
@@ -2793,7 +3152,7 @@ func (g *Game) SetAttr(obj gamedb.DBRef, attrNum int, value string) {
 	}
 
 	fullValue := fmt.Sprintf("\x01%s:%d:%s", owner, instFlags, value)
-	o.Attrs = append(o.Attrs, gamedb.Attribute{Number: attrNum, Value: fullValue})
+	o.SetAttributes(append(o.Attributes(), gamedb.Attribute{Number: attrNum, Value: fullValue}))
 	g.PersistObject(o)
 }
 
@@ -2811,7 +3170,7 @@ func (g *Game) findParentAttr(obj gamedb.DBRef, attrNum int) *AttrInfo {
 		if !ok {
 			break
 		}
-		for _, attr := range pObj.Attrs {
+		for _, attr := range pObj.Attributes() {
 			if attr.Number == attrNum {
 				info := ParseAttrInfo(attr.Value)
 				return &info
@@ -2829,14 +3188,14 @@ func (g *Game) SetAttrRaw(obj gamedb.DBRef, attrNum int, value string, owner gam
 		return
 	}
 	fullValue := fmt.Sprintf("\x01%d:%d:%s", owner, flags, value)
-	for i, attr := range o.Attrs {
+	for i, attr := range o.Attributes() {
 		if attr.Number == attrNum {
-			o.Attrs[i].Value = fullValue
+			o.Attributes()[i].Value = fullValue
 			g.PersistObject(o)
 			return
 		}
 	}
-	o.Attrs = append(o.Attrs, gamedb.Attribute{Number: attrNum, Value: fullValue})
+	o.SetAttributes(append(o.Attributes(), gamedb.Attribute{Number: attrNum, Value: fullValue}))
 	g.PersistObject(o)
 }
 
@@ -2851,7 +3210,7 @@ func (g *Game) SetAttrChecked(player, obj gamedb.DBRef, attrNum int, value strin
 	def := g.LookupAttrDef(attrNum)
 	// Find existing instance flags
 	instFlags := 0
-	for _, attr := range o.Attrs {
+	for _, attr := range o.Attributes() {
 		if attr.Number == attrNum {
 			info := ParseAttrInfo(attr.Value)
 			instFlags = info.Flags
@@ -3030,16 +3389,19 @@ func cmdDrop(g *Game, d *Descriptor, args string, _ []string) {
 		return
 	}
 
-	// Remove from inventory, add to room contents
-	g.RemoveFromContents(d.Player, target)
+	// Remove from inventory, add to room contents, or send it on to
+	// the room's drop-to
 	loc := g.PlayerLocation(d.Player)
-	locObj, ok := g.DB.Objects[loc]
-	if !ok {
+	if _, ok := g.DB.Objects[loc]; !ok {
 		return
 	}
-	obj.Location = loc
-	g.AddToContents(loc, target)
-	g.PersistObjects(obj, locObj)
+	dest := loc
+	if home := g.safeHome(target, gamedb.Nothing); obj.HasFlag(gamedb.FlagSticky) && home != gamedb.Nothing {
+		dest = home
+	} else if dropTo := g.immediateDropTo(loc, target); dropTo != gamedb.Nothing {
+		dest = dropTo
+	}
+	g.Teleport(target, dest)
 
 	d.Send(fmt.Sprintf("You drop %s.", DisplayName(obj.Name)))
 	g.Conns.SendToRoomExcept(g.DB, loc, d.Player,
@@ -3072,21 +3434,8 @@ func cmdGive(g *Game, d *Descriptor, args string, _ []string) {
 
 	// Try as penny amount first (only if it's a pure number, not a dbref like #123)
 	if isNumeric(whatStr) {
-		amount := toIntSimple(whatStr)
-		if amount > 0 {
-			playerObj := g.DB.Objects[d.Player]
-			if playerObj.Pennies < amount {
-				d.Send(fmt.Sprintf("You don't have that many %s.", g.MoneyName(2)))
-				return
-			}
-			playerObj.Pennies -= amount
-			targetObj.Pennies += amount
-			g.PersistObjects(playerObj, targetObj)
-			d.Send(fmt.Sprintf("You give %d %s to %s.", amount, g.MoneyName(amount), DisplayName(targetObj.Name)))
-			g.Conns.SendToPlayer(target,
-				fmt.Sprintf("%s gives you %d %s.", g.PlayerName(d.Player), amount, g.MoneyName(amount)))
-			return
-		}
+		giveMoney(g, d, target, toIntSimple(whatStr))
+		return
 	}
 
 	// Try as object — match in giver's inventory
@@ -3210,33 +3559,21 @@ func cmdEnter(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send("Permission denied.")
 		return
 	}
+	if g.robotBarred(d, target) {
+		return
+	}
+	if g.locatedIn(target, d.Player) {
+		d.Send("You can't enter that.")
+		return
+	}
 	// Check enter lock
 	if !CouldDoIt(g, d.Player, target, aLEnter) {
 		HandleLockFailure(g, d, target, aEFail, aOEFail, aAEFail, "Permission denied.")
 		return
 	}
 
-	loc := g.PlayerLocation(d.Player)
-	playerObj := g.DB.Objects[d.Player]
-
-	// Remove from current location
-	g.RemoveFromContents(loc, d.Player)
-
-	// Announce departure
-	g.Conns.SendToRoomExcept(g.DB, loc, d.Player,
-		fmt.Sprintf("%s has left.", DisplayName(playerObj.Name)))
-
-	// Move inside target
-	playerObj.Location = target
-	g.AddToContents(target, d.Player)
-	g.PersistObjects(playerObj, obj)
-
 	d.Send(fmt.Sprintf("You enter %s.", DisplayName(obj.Name)))
-	g.Conns.SendToRoomExcept(g.DB, target, d.Player,
-		fmt.Sprintf("%s has arrived.", DisplayName(playerObj.Name)))
-
-	g.ShowRoom(d, target)
-	g.QueueAttrAction(target, d.Player, 35, nil) // A_AENTER = 35
+	g.MovePlayer(d, target)
 }
 
 func cmdLeave(g *Game, d *Descriptor, _ string, _ []string) {
@@ -3263,27 +3600,8 @@ func cmdLeave(g *Game, d *Descriptor, _ string, _ []string) {
 		return
 	}
 
-	// Remove from container
-	g.RemoveFromContents(loc, d.Player)
-	g.Conns.SendToRoomExcept(g.DB, loc, d.Player,
-		fmt.Sprintf("%s has left.", DisplayName(playerObj.Name)))
-
-	// Move to container's location
-	destObj, ok := g.DB.Objects[dest]
-	if !ok {
-		d.Send("You can't leave.")
-		return
-	}
-	playerObj.Location = dest
-	g.AddToContents(dest, d.Player)
-	g.PersistObjects(playerObj, destObj)
-
 	d.Send("You leave.")
-	g.Conns.SendToRoomExcept(g.DB, dest, d.Player,
-		fmt.Sprintf("%s has arrived.", DisplayName(playerObj.Name)))
-
-	g.ShowRoom(d, dest)
-	g.QueueAttrAction(loc, d.Player, 52, nil) // A_ALEAVE = 52
+	g.MovePlayer(d, dest)
 }
 
 func cmdWhisper(g *Game, d *Descriptor, args string, _ []string) {
@@ -3367,53 +3685,6 @@ func cmdUse(g *Game, d *Descriptor, args string, _ []string) {
 	g.QueueAttrAction(target, d.Player, 16, nil) // A_AUSE = 16
 }
 
-func cmdKill(g *Game, d *Descriptor, args string, _ []string) {
-	if args == "" {
-		d.Send("Kill whom?")
-		return
-	}
-	// kill player [= cost]
-	targetStr := args
-	if eqIdx := strings.IndexByte(args, '='); eqIdx >= 0 {
-		targetStr = strings.TrimSpace(args[:eqIdx])
-	}
-
-	target := g.MatchObject(d.Player, targetStr)
-	if target == gamedb.Nothing {
-		d.Send("I don't see that here.")
-		return
-	}
-	targetObj, ok := g.DB.Objects[target]
-	if !ok {
-		return
-	}
-
-	senderName := g.PlayerName(d.Player)
-	d.Send(fmt.Sprintf("You killed %s!", DisplayName(targetObj.Name)))
-	g.Conns.SendToPlayer(target,
-		fmt.Sprintf("%s killed you!", senderName))
-
-	// Send to home
-	home := targetObj.Link
-	if home != gamedb.Nothing {
-		loc := targetObj.Location
-		g.RemoveFromContents(loc, target)
-		g.Conns.SendToRoomExcept(g.DB, loc, target,
-			fmt.Sprintf("%s has left.", DisplayName(targetObj.Name)))
-		targetObj.Location = home
-		g.AddToContents(home, target)
-		if destObj, ok := g.DB.Objects[home]; ok {
-			g.PersistObjects(targetObj, destObj)
-		}
-		g.Conns.SendToRoomExcept(g.DB, home, target,
-			fmt.Sprintf("%s has arrived.", DisplayName(targetObj.Name)))
-		// Show room to victim
-		for _, dd := range g.Conns.GetByPlayer(target) {
-			g.ShowRoom(dd, home)
-		}
-	}
-}
-
 func cmdDictionary(g *Game, d *Descriptor, args string, _ []string) {
 	eqIdx := strings.IndexByte(args, '=')
 	if eqIdx < 0 {
@@ -3438,22 +3709,32 @@ func cmdDictionary(g *Game, d *Descriptor, args string, _ []string) {
 // DisconnectPlayer handles a player disconnecting.
 func (g *Game) DisconnectPlayer(d *Descriptor) {
 	if d.State == ConnConnected {
+		g.noteLogout(d)
 		playerName := g.PlayerName(d.Player)
 		loc := g.PlayerLocation(d.Player)
 
 		// Fire ADISCONNECT triggers (player + master room + master room contents)
 		connCount := len(g.Conns.GetByPlayer(d.Player))
-		g.FireConnectAttr(d.Player, connCount, 40) // A_ADISCONNECT = 40
+		g.fireAdisconnect(d, connCount-1)
 
 		// Clear CONNECTED flag on last disconnect (C TinyMUSH behavior)
 		if connCount <= 1 {
+			if d.ProgData != nil {
+				g.clearProgram(d.Player)
+			}
+			g.watches.remove(gamedb.Nothing, d.Player)
 			if obj, ok := g.DB.Objects[d.Player]; ok {
 				obj.Flags[1] &^= gamedb.Flag2Connected
 			}
 		}
 
-		g.Conns.SendToRoomExcept(g.DB, loc, d.Player,
-			fmt.Sprintf("%s has disconnected.", playerName))
+		if !Hidden(g, d.Player) {
+			g.Conns.SendToRoomExcept(g.DB, loc, d.Player,
+				fmt.Sprintf("%s has disconnected.", playerName))
+		}
+		if connCount <= 1 {
+			g.announceChannels(d.Player, "disconnected")
+		}
 
 		// Guest cleanup: if this was the last connection for a guest,
 		// schedule destruction after a grace period.
@@ -3461,10 +3742,12 @@ func (g *Game) DisconnectPlayer(d *Descriptor) {
 			player := d.Player
 			go func() {
 				time.Sleep(60 * time.Second)
-				// Check if guest reconnected during grace period
-				if len(g.Conns.GetByPlayer(player)) == 0 {
-					g.DestroyGuest(player)
-				}
+				g.Do(func() {
+					// Check if guest reconnected during grace period
+					if len(g.Conns.GetByPlayer(player)) == 0 {
+						g.DestroyGuest(player)
+					}
+				})
 			}()
 		}
 	}
@@ -2,14 +2,30 @@ package server
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/crystal-mush/gotinymush/pkg/archive"
+	"github.com/crystal-mush/gotinymush/pkg/boltstore"
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
 	"github.com/crystal-mush/gotinymush/pkg/events"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	"github.com/gorilla/websocket"
 )
 
 // testEnv holds the shared test infrastructure.
@@ -325,634 +341,4815 @@ func TestDispatchCommand_HuhMessage(t *testing.T) {
 	}
 }
 
-func TestDispatchCommand_Version(t *testing.T) {
+func TestDispatchCommand_Switches(t *testing.T) {
 	env := newTestEnv(t)
 	clearOutput(env.player)
 
-	DispatchCommand(env.game, env.player, "@version")
+	DispatchCommand(env.game, env.player, "@dolist/noww a b=think ##")
 	out := getOutput(env.player)
-	if !strings.Contains(out, "GoTinyMUSH") {
-		t.Errorf("@version: expected 'GoTinyMUSH', got: %s", out)
+	if out != "Unrecognized switch 'noww' for @dolist." {
+		t.Errorf("@dolist/noww: got %q", out)
 	}
-}
-
-func TestDispatchCommand_Inventory(t *testing.T) {
-	env := newTestEnv(t)
-	clearOutput(env.player)
 
-	DispatchCommand(env.game, env.player, "inventory")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "You are carrying") || !strings.Contains(out, "nothing") {
-		// Wizard starts with no inventory, so should see "nothing"
-		// or "You are carrying:" followed by nothing
-		_ = out // acceptable either way
+	DispatchCommand(env.game, env.player, "think/loud hi")
+	out = getOutput(env.player)
+	if out != "Unrecognized switch 'loud' for think." {
+		t.Errorf("think/loud: got %q", out)
 	}
-}
 
-func TestDispatchCommand_Score(t *testing.T) {
-	env := newTestEnv(t)
-	clearOutput(env.player)
+	// Unique prefixes expand to the full switch name.
+	DispatchCommand(env.game, env.player, "@dolist/n a b=think ##")
+	out = getOutput(env.player)
+	if !strings.Contains(out, "a") || !strings.Contains(out, "b") {
+		t.Errorf("@dolist/n: expected immediate output, got %q", out)
+	}
 
-	DispatchCommand(env.game, env.player, "score")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "1000") {
-		t.Errorf("score: expected pennies count '1000', got: %s", out)
+	DispatchCommand(env.game, env.player, "@mail/s")
+	out = getOutput(env.player)
+	if !strings.HasPrefix(out, "Ambiguous switch 's'") {
+		t.Errorf("@mail/s: got %q", out)
 	}
 }
 
-func TestDispatchCommand_Examine(t *testing.T) {
+func TestDispatchCommand_BootPort(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	env.game.Guests = NewGuestManager()
+	bob := makeTestDescriptor(t, env.game.Conns, 3)
 
-	DispatchCommand(env.game, env.player, "examine me")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Wizard") {
-		t.Errorf("examine: expected 'Wizard' in output, got: %s", out)
+	DispatchCommand(env.game, bob, fmt.Sprintf("@boot/port %d", env.player.ID))
+	if out := getOutput(bob); out != "Permission denied." {
+		t.Errorf("mortal @boot/port: got %q", out)
 	}
-}
 
-// --- Attribute Setter Tests ---
+	// POW_BOOT doesn't reach God, by port or by name.
+	env.game.DB.Objects[3].Powers[0] |= gamedb.PowBoot
+	for _, cmd := range []string{fmt.Sprintf("@boot/port %d", env.player.ID), "@boot Wizard"} {
+		clearOutput(bob)
+		DispatchCommand(env.game, bob, cmd)
+		if out := getOutput(bob); out != "You cannot boot that player!" {
+			t.Errorf("%s: got %q", cmd, out)
+		}
+	}
+	if env.player.IsClosed() {
+		t.Fatal("God was booted")
+	}
 
-func TestAttrSetter_Success(t *testing.T) {
-	env := newTestEnv(t)
 	clearOutput(env.player)
-
-	DispatchCommand(env.game, env.player, "@success me=You did it!")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Set.") {
-		t.Errorf("@success: expected 'Set.', got: %s", out)
+	DispatchCommand(env.game, env.player, fmt.Sprintf("@boot/port %d", bob.ID))
+	if out := getOutput(env.player); !strings.HasSuffix(out, fmt.Sprintf("Booted port %d.", bob.ID)) {
+		t.Errorf("@boot/port: got %q", out)
+	}
+	if !bob.IsClosed() {
+		t.Error("@boot/port: descriptor still open")
 	}
 
-	// Verify the attribute was actually set
-	text := env.game.GetAttrText(1, 4) // A_SUCC = 4
-	if text != "You did it!" {
-		t.Errorf("@success: expected attr text 'You did it!', got: %s", text)
+	DispatchCommand(env.game, env.player, "@boot/port 999")
+	if out := getOutput(env.player); out != "No such port." {
+		t.Errorf("@boot/port 999: got %q", out)
 	}
 }
 
-func TestAttrSetter_Fail(t *testing.T) {
+func TestFixedFlag(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	env.game.Conf = DefaultGameConf()
+	bob := makeTestDescriptor(t, env.game.Conns, 3)
+	env.game.DB.Objects[3].Flags[1] |= gamedb.Flag2Fixed
 
-	DispatchCommand(env.game, env.player, "@fail me=Sorry, you can't.")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Set.") {
-		t.Errorf("@fail: expected 'Set.', got: %s", out)
+	DispatchCommand(env.game, bob, "home")
+	if out := getOutput(bob); out != "Don't you like it here?" {
+		t.Errorf("FIXED home: got %q", out)
 	}
-	text := env.game.GetAttrText(1, 3) // A_FAIL = 3
-	if text != "Sorry, you can't." {
-		t.Errorf("@fail: expected 'Sorry, you can't.', got: %s", text)
+	clearOutput(bob)
+	DispatchCommand(env.game, bob, "@teleport #4")
+	if out := getOutput(bob); out != "Sorry, you are stuck here." {
+		t.Errorf("FIXED @teleport: got %q", out)
+	}
+	if loc := env.game.DB.Objects[3].Location; loc != 0 {
+		t.Errorf("FIXED player moved to #%d", loc)
 	}
-}
 
-func TestAttrSetter_Sex(t *testing.T) {
-	env := newTestEnv(t)
-	clearOutput(env.player)
+	// Objects owned by a FIXED player can't be sent home either.
+	env.game.DB.Objects[2].Owner = 3
+	clearOutput(bob)
+	DispatchCommand(env.game, bob, "@teleport #2=home")
+	if out := getOutput(bob); out != "Permission denied." {
+		t.Errorf("FIXED thing home: got %q", out)
+	}
 
-	DispatchCommand(env.game, env.player, "@sex me=Male")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Set.") {
-		t.Errorf("@sex: expected 'Set.', got: %s", out)
+	// tel_anything overrides.
+	env.game.DB.Objects[3].Powers[0] |= gamedb.PowTelUnrst
+	clearOutput(bob)
+	DispatchCommand(env.game, bob, "@teleport #4")
+	if loc := env.game.DB.Objects[3].Location; loc != 4 {
+		t.Errorf("tel_anything @teleport: still in #%d", loc)
 	}
-	text := env.game.GetAttrText(1, 7) // A_SEX = 7
-	if text != "Male" {
-		t.Errorf("@sex: expected 'Male', got: %s", text)
+
+	// Without a config the messages are the usual defaults.
+	env.game.Conf = nil
+	if home, tel := env.game.fixedHomeMessage(), env.game.fixedTelMessage(); home != "Don't you like it here?" || tel != "Sorry, you are stuck here." {
+		t.Errorf("FIXED messages without a config: %q, %q", home, tel)
 	}
 }
 
-func TestAttrSetter_Describe(t *testing.T) {
+func TestSceneRecording(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	env.game.Scenes = NewSceneRecorder(env.game, []byte("test key"))
+	bob := makeTestDescriptor(t, env.game.Conns, 3)
 
-	DispatchCommand(env.game, env.player, "@describe me=A powerful wizard.")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Set.") {
-		t.Errorf("@describe: expected 'Set.', got: %s", out)
+	DispatchCommand(env.game, env.player, "+scene/start Tavern talk")
+	if out := getOutput(env.player); !strings.Contains(out, "starts recording scene 1 (Tavern talk)") {
+		t.Fatalf("+scene/start: got %q", out)
 	}
-	text := env.game.GetAttrText(1, 6) // A_DESC = 6
-	if text != "A powerful wizard." {
-		t.Errorf("@describe: expected 'A powerful wizard.', got: %s", text)
+	DispatchCommand(env.game, bob, "say hello there")
+	DispatchCommand(env.game, env.player, "pose waves.")
+	clearOutput(bob)
+	DispatchCommand(env.game, bob, "+scene/stop")
+	if out := getOutput(bob); out != "Permission denied." {
+		t.Errorf("non-owner +scene/stop: got %q", out)
 	}
-}
+	DispatchCommand(env.game, env.player, "+scene/stop")
 
-func TestAttrSetter_NoEquals(t *testing.T) {
-	env := newTestEnv(t)
-	clearOutput(env.player)
-
-	DispatchCommand(env.game, env.player, "@success me")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "=") || !strings.Contains(out, "need") {
-		// Should show usage error about needing =
-		_ = out
+	sc := env.game.Scenes.Get(1)
+	if sc == nil || sc.Ended.IsZero() {
+		t.Fatal("scene 1 not stopped")
+	}
+	for _, blob := range sc.Log {
+		if strings.Contains(string(blob), "hello") {
+			t.Error("scene log stored in plaintext")
+		}
+	}
+	entries, err := env.game.Scenes.Entries(sc)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("entries: %v, %d lines", err, len(entries))
+	}
+	if entries[0].Text != `Bob says "hello there"` || entries[1].Text != "Wizard waves." {
+		t.Errorf("entries: %q, %q", entries[0].Text, entries[1].Text)
 	}
-}
-
-func TestAttrSetter_PermDenied(t *testing.T) {
-	env := newTestEnv(t)
-	// Create a non-wizard descriptor for Bob (#3)
-	bobDesc := makeTestDescriptor(t, env.game.Conns, 3)
-	clearOutput(bobDesc)
 
-	// Bob tries to set an attr on Wizard's object - should fail
-	DispatchCommand(env.game, bobDesc, "@success #1=hacked")
-	out := getOutput(bobDesc)
-	if !strings.Contains(out, "Permission denied") {
-		t.Errorf("@success perm: expected 'Permission denied', got: %s", out)
+	// Participants can read it; others only once allowed.
+	clearOutput(bob)
+	DispatchCommand(env.game, bob, "+scene/view 1")
+	if out := getOutput(bob); !strings.Contains(out, `Bob says "hello there"`) {
+		t.Errorf("participant +scene/view: got %q", out)
+	}
+	if env.game.Scenes.CanRead(sc, 2) {
+		t.Error("non-participant can read scene")
 	}
 }
 
-// --- Player Object Commands ---
-
-func TestGet(t *testing.T) {
+func TestScenePersistence(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	env.game.Store = store
+	env.game.Scenes = NewSceneRecorder(env.game, []byte("test key"))
+	bob := makeTestDescriptor(t, env.game.Conns, 3)
+
+	DispatchCommand(env.game, env.player, "+scene/start Tavern talk")
+	for i := 0; i < 20; i++ {
+		DispatchCommand(env.game, bob, fmt.Sprintf("say line %d", i))
+	}
+	// Each line is on disk as soon as it is said, before the scene stops.
+	scenes, err := store.LoadScenes()
+	if err != nil || len(scenes) != 1 || len(scenes[0].Log) != 20 {
+		t.Fatalf("LoadScenes while recording: %v, %d scenes", err, len(scenes))
+	}
+	DispatchCommand(env.game, env.player, "+scene/stop")
 
-	DispatchCommand(env.game, env.player, "get TestObject")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "You pick up TestObject") {
-		t.Errorf("get: expected 'You pick up TestObject', got: %s", out)
+	scenes, err = store.LoadScenes()
+	if err != nil || len(scenes) != 1 {
+		t.Fatalf("LoadScenes: %v, %d scenes", err, len(scenes))
+	}
+	sr := NewSceneRecorder(env.game, []byte("test key"))
+	sr.LoadScenes(scenes)
+	sc := sr.Get(1)
+	if sc == nil || sc.Ended.IsZero() || len(sc.Participants) != 2 {
+		t.Fatalf("reloaded scene: %+v", sc)
+	}
+	entries, err := sr.Entries(sc)
+	if err != nil || len(entries) != 20 || entries[19].Text != `Bob says "line 19"` {
+		t.Fatalf("reloaded entries: %v, %d lines", err, len(entries))
 	}
 
-	// Verify object moved to inventory
-	obj := env.game.DB.Objects[2]
-	if obj.Location != 1 {
-		t.Errorf("get: TestObject should be in Wizard's inventory, location=%d", obj.Location)
+	sr.Delete(1)
+	if scenes, _ := store.LoadScenes(); len(scenes) != 0 {
+		t.Errorf("%d scenes left after delete", len(scenes))
 	}
 }
 
-func TestGetEmpty(t *testing.T) {
+func TestPcreateRegistrationQueue(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	env.game.Registrations = NewRegistrationQueue()
 
-	DispatchCommand(env.game, env.player, "get")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Get what?") {
-		t.Errorf("get empty: expected 'Get what?', got: %s", out)
+	DispatchCommand(env.game, env.player, "@pcreate Alice=secret1")
+	if out := getOutput(env.player); !strings.HasPrefix(out, "New player 'Alice'") {
+		t.Fatalf("@pcreate: got %q", out)
+	}
+	if ref := LookupPlayer(env.game.DB, "Alice"); ref == gamedb.Nothing || !CheckPassword(env.game.DB, ref, "secret1") {
+		t.Error("@pcreate: player missing or password wrong")
 	}
-}
 
-func TestDrop(t *testing.T) {
-	env := newTestEnv(t)
-	clearOutput(env.player)
+	if _, err := env.game.RequestRegistration("Carol", "not an address", "10.0.0.1"); err == nil {
+		t.Error("bad email accepted")
+	}
+	reg, err := env.game.RequestRegistration("Carol", "carol@example.com", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("RequestRegistration: %v", err)
+	}
+	if _, err := env.game.RequestRegistration("carol", "c2@example.com", "10.0.0.2"); err == nil {
+		t.Error("duplicate pending name accepted")
+	}
+	env.game.RequestRegistration("Dave", "dave@example.com", "10.0.0.3")
+	// One site may only have maxPendingPerSite requests waiting.
+	for i, name := range []string{"Fay", "Gus", "Hal", "Ida"} {
+		_, err := env.game.RequestRegistration(name, "x@example.com", fmt.Sprintf("10.0.0.9:%d", 4000+i))
+		if (err != nil) != (name == "Ida") {
+			t.Errorf("request %d from one site: %v", i+1, err)
+		}
+	}
+	for _, name := range []string{"Fay", "Gus", "Hal"} {
+		for _, r := range env.game.Registrations.List() {
+			if r.Name == name {
+				env.game.RejectRegistration(r.ID)
+			}
+		}
+	}
 
-	// First pick up the object
-	DispatchCommand(env.game, env.player, "get TestObject")
 	clearOutput(env.player)
+	DispatchCommand(env.game, env.player, "@pcreate/list")
+	if out := getOutput(env.player); !strings.Contains(out, "carol@example.com") || !strings.Contains(out, "2 pending.") {
+		t.Errorf("@pcreate/list: got %q", out)
+	}
 
-	// Now drop it
-	DispatchCommand(env.game, env.player, "drop TestObject")
+	clearOutput(env.player)
+	DispatchCommand(env.game, env.player, fmt.Sprintf("@pcreate/approve %d", reg.ID))
 	out := getOutput(env.player)
-	if !strings.Contains(out, "You drop TestObject") {
-		t.Errorf("drop: expected 'You drop TestObject', got: %s", out)
+	_, pw, ok := strings.Cut(out, "with password '")
+	if !ok {
+		t.Fatalf("@pcreate/approve: got %q", out)
 	}
-
-	obj := env.game.DB.Objects[2]
-	if obj.Location != 0 {
-		t.Errorf("drop: TestObject should be back in Room Zero, location=%d", obj.Location)
+	pw = strings.TrimSuffix(pw, "'.")
+	if ref := LookupPlayer(env.game.DB, "Carol"); ref == gamedb.Nothing || !CheckPassword(env.game.DB, ref, pw) {
+		t.Error("approved player missing or password wrong")
 	}
-}
 
-func TestDropNotCarrying(t *testing.T) {
-	env := newTestEnv(t)
-	clearOutput(env.player)
+	DispatchCommand(env.game, env.player, "@pcreate/reject 2")
+	if n := len(env.game.Registrations.List()); n != 0 {
+		t.Errorf("%d registrations left in queue", n)
+	}
 
-	DispatchCommand(env.game, env.player, "drop TestObject")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "aren't carrying") {
-		t.Errorf("drop not carrying: expected 'aren't carrying', got: %s", out)
+	bob := makeTestDescriptor(t, env.game.Conns, 3)
+	DispatchCommand(env.game, bob, "@pcreate Eve=pw")
+	if out := getOutput(bob); out != "Permission denied." {
+		t.Errorf("mortal @pcreate: got %q", out)
 	}
 }
 
-func TestGivePennies(t *testing.T) {
+func TestToadAndDestroyPlayer(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
-
-	DispatchCommand(env.game, env.player, "give Bob=50")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "give") && !strings.Contains(out, "50") {
-		t.Errorf("give: expected give confirmation, got: %s", out)
+	env.game.Guests = NewGuestManager()
+	bob := makeTestDescriptor(t, env.game.Conns, 3)
+	env.game.DB.Objects[2].Owner = 3
+	env.game.DB.Objects[2].Flags[0] |= gamedb.FlagInherit
+	env.game.DB.Objects[3].Flags[0] |= gamedb.FlagRoyalty
+	env.game.DB.Objects[3].Flags[2] = 1
+	env.game.DB.Objects[3].Powers[0] = gamedb.PowTelUnrst
+
+	DispatchCommand(env.game, bob, "@toad Wizard")
+	if out := getOutput(bob); out != "Permission denied." {
+		t.Errorf("mortal @toad: got %q", out)
 	}
 
-	// Verify pennies transferred
-	wizard := env.game.DB.Objects[1]
-	bob := env.game.DB.Objects[3]
-	if wizard.Pennies != 950 {
-		t.Errorf("give: wizard should have 950 pennies, has %d", wizard.Pennies)
+	DispatchCommand(env.game, env.player, "@toad Bob")
+	if out := getOutput(env.player); !strings.HasSuffix(out, "You toaded Bob! (#3)") {
+		t.Errorf("@toad: got %q", out)
 	}
-	if bob.Pennies != 150 {
-		t.Errorf("give: bob should have 150 pennies, has %d", bob.Pennies)
+	toad := env.game.DB.Objects[3]
+	if toad.ObjType() != gamedb.TypeThing || toad.Owner != env.player.Player {
+		t.Errorf("toad: type %v owner #%d", toad.ObjType(), toad.Owner)
+	}
+	if toad.Flags != [3]int{int(gamedb.TypeThing) | gamedb.FlagHalt, 0, 0} || toad.Powers != [2]int{} {
+		t.Errorf("toad kept flags %#x or powers %#x", toad.Flags, toad.Powers)
+	}
+	if !bob.IsClosed() {
+		t.Error("toaded player still connected")
+	}
+	thing := env.game.DB.Objects[2]
+	if thing.Owner != env.player.Player || thing.HasFlag(gamedb.FlagInherit) || !thing.HasFlag(gamedb.FlagHalt) {
+		t.Errorf("toad's object: owner #%d flags %#x", thing.Owner, thing.Flags[0])
+	}
+	if LookupPlayer(env.game.DB, "Bob") != gamedb.Nothing {
+		t.Error("toad still matches as a player")
 	}
-}
 
-func TestEnter(t *testing.T) {
-	env := newTestEnv(t)
+	// Destroying a player hands their objects to the wizard and sends what
+	// they carry home; homes set to the player move elsewhere.
+	carol := &gamedb.Object{DBRef: 6, Name: "Carol", Location: 0, Owner: 6, Link: 0,
+		Contents: gamedb.Nothing, Exits: gamedb.Nothing, Next: gamedb.Nothing, Parent: gamedb.Nothing, Zone: gamedb.Nothing,
+		Flags: [3]int{int(gamedb.TypePlayer), 0, 0}}
+	env.game.DB.Objects[6] = carol
+	env.game.AddToContents(0, 6)
+	box := env.game.DB.Objects[5]
+	box.Owner = 6
+	box.Link = 6
+	env.game.RemoveFromContents(0, 5)
+	box.Location = 6
+	env.game.AddToContents(6, 5)
 	clearOutput(env.player)
-
-	DispatchCommand(env.game, env.player, "enter Container")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "You enter Container") {
-		t.Errorf("enter: expected 'You enter Container', got: %s", out)
+	DispatchCommand(env.game, env.player, "@destroy #6")
+	if out := getOutput(env.player); !strings.Contains(out, "always SAFE") {
+		t.Errorf("@destroy player without /override: got %q", out)
 	}
-
-	// Verify player moved inside container
-	playerObj := env.game.DB.Objects[1]
-	if playerObj.Location != 5 {
-		t.Errorf("enter: player should be in Container (#5), location=%d", playerObj.Location)
+	DispatchCommand(env.game, env.player, "@destroy/override #6")
+	if !carol.IsGoing() || env.game.DB.Objects[5].Owner != env.player.Player {
+		t.Errorf("@destroy player: going=%v, object owner #%d", carol.IsGoing(), env.game.DB.Objects[5].Owner)
+	}
+	if box.Location != 0 || box.Link != 0 || carol.Contents != gamedb.Nothing {
+		t.Errorf("carried object: at #%d, home #%d", box.Location, box.Link)
+	}
+	if !slices.Contains(env.game.DB.SafeContents(0), 5) || slices.Contains(env.game.DB.SafeContents(0), 6) {
+		t.Errorf("room contents after @destroy player: %v", env.game.DB.SafeContents(0))
 	}
 }
 
-func TestLeave(t *testing.T) {
+func TestDestroyRoomsAndExits(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	north := g.CreateExit("north", 0, 4, 1)
+	south := g.CreateExit("south", 4, 0, 1)
+	east := g.CreateExit("east", 0, 4, 1)
+
+	// Destroying an exit unlinks it from its source's exit chain.
+	DispatchCommand(g, env.player, fmt.Sprintf("@destroy #%d", north))
+	if g.DB.Objects[0].Exits != east || g.DB.Objects[east].Next != gamedb.Nothing {
+		t.Errorf("exit chain after @destroy: head #%d next #%d", g.DB.Objects[0].Exits, g.DB.Objects[east].Next)
+	}
 
-	// First enter the container
-	DispatchCommand(env.game, env.player, "enter Container")
+	bobObj := g.DB.Objects[3]
+	bobObj.Link = 4
+	g.RemoveFromContents(0, 3)
+	bobObj.Location = 4
+	g.AddToContents(4, 3)
+
+	// The first @destroy of a room only marks it GOING.
 	clearOutput(env.player)
+	DispatchCommand(g, env.player, "@destroy #4")
+	room := g.DB.Objects[4]
+	if !room.IsGoing() || bobObj.Location != 4 {
+		t.Fatalf("first @destroy: going=%v bob at #%d", room.IsGoing(), bobObj.Location)
+	}
+	if out := getOutput(env.player); !strings.Contains(out, "again to confirm") {
+		t.Errorf("first @destroy: got %q", out)
+	}
 
-	// Now leave
-	DispatchCommand(env.game, env.player, "leave")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "You leave") {
-		t.Errorf("leave: expected 'You leave', got: %s", out)
+	DispatchCommand(g, env.player, "@destroy #4")
+	if !room.HasFlag2(gamedb.Flag2GoingTwice) || room.Exits != gamedb.Nothing || room.Contents != gamedb.Nothing {
+		t.Errorf("room not torn down: flags %#x exits #%d contents #%d", room.Flags[1], room.Exits, room.Contents)
+	}
+	if bobObj.Location != 0 || bobObj.Link != 0 {
+		t.Errorf("bob: location #%d home #%d", bobObj.Location, bobObj.Link)
+	}
+	if !g.DB.Objects[south].IsGoing() || g.DB.Objects[east].Location != gamedb.Nothing {
+		t.Error("exits of and into the room were not cleaned up")
+	}
+	if !strings.Contains(getOutput(bob), "The floor disappears") {
+		t.Error("bob was not told the room went away")
 	}
 
-	playerObj := env.game.DB.Objects[1]
-	if playerObj.Location != 0 {
-		t.Errorf("leave: player should be back in Room Zero, location=%d", playerObj.Location)
+	// Anyone carrying a DESTROY_OK object can destroy it, even if SAFE.
+	thing := g.DB.Objects[2]
+	thing.Flags[0] |= gamedb.FlagDestroyOK | gamedb.FlagSafe
+	g.RemoveFromContents(0, 2)
+	thing.Location = 3
+	g.AddToContents(3, 2)
+	DispatchCommand(g, bob, "@destroy #2")
+	if !thing.IsGoing() || bobObj.Contents != gamedb.Nothing {
+		t.Errorf("DESTROY_OK: going=%v bob contents #%d", thing.IsGoing(), bobObj.Contents)
 	}
 }
 
-func TestWhisper(t *testing.T) {
+func TestRestoreObject(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
-
-	DispatchCommand(env.game, env.player, "whisper Bob=secret message")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "You whisper") {
-		t.Errorf("whisper: expected 'You whisper', got: %s", out)
+	g := env.game
+	g.DB.NextAttr = gamedb.A_USER_START
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.ImportFromDatabase(g.DB); err != nil {
+		t.Fatal(err)
+	}
+	g.Store = store
+	g.ArchiveDir = t.TempDir()
+
+	door := g.CreateExit("door", 0, 4, 1)
+	g.SetAttrByName(5, "HINT", "Look inside.")
+	path, err := archive.CreateArchive(archive.ArchiveParams{ArchiveDir: g.ArchiveDir, BoltSnapshotFunc: store.Backup})
+	if err != nil {
+		t.Fatal(err)
 	}
-}
 
-func TestUse(t *testing.T) {
-	env := newTestEnv(t)
+	DispatchCommand(g, env.player, "@destroy #5")
+	DispatchCommand(g, env.player, fmt.Sprintf("@destroy #%d", door))
+	g.DB.Objects[2].Name = "Renamed"
+	// The live game has since reused the archived attribute's number.
+	delete(g.DB.AttrByName, "HINT")
+	g.DB.AddAttrDef(gamedb.A_USER_START, "OTHER", 0)
 
-	// Set a USE attribute on TestObject
-	env.game.SetAttr(2, 45, "You use the object.") // A_USE = 45
 	clearOutput(env.player)
-
-	DispatchCommand(env.game, env.player, "use TestObject")
+	DispatchCommand(g, env.player, fmt.Sprintf("@restore/object #2-#%d=%s", door, filepath.Base(path)))
 	out := getOutput(env.player)
-	if !strings.Contains(out, "You use the object.") {
-		t.Errorf("use: expected 'You use the object.', got: %s", out)
+	container := g.DB.Objects[5]
+	if container.IsGoing() || container.Location != 0 {
+		t.Errorf("container: going=%v location #%d", container.IsGoing(), container.Location)
+	}
+	found := false
+	for _, c := range g.DB.SafeContents(0) {
+		found = found || c == 5
+	}
+	if !found {
+		t.Error("container is not back in Room Zero's contents")
+	}
+	if num := g.LookupAttrNum("HINT"); num == gamedb.A_USER_START || g.GetAttrText(5, num) != "Look inside." {
+		t.Errorf("HINT restored as attribute %d = %q", num, g.GetAttrText(5, num))
+	}
+	if g.DB.Objects[0].Exits != door || g.DB.Objects[door].IsGoing() || g.DB.Objects[door].Location != 4 {
+		t.Errorf("door not relinked: exits #%d", g.DB.Objects[0].Exits)
+	}
+	if g.DB.Objects[2].Name != "Renamed" || !strings.Contains(out, "#2 TestObject: still exists") {
+		t.Errorf("live object touched without /overwrite: %q", out)
+	}
+	if !strings.Contains(out, "2 object(s) restored") {
+		t.Errorf("got %q", out)
 	}
-}
 
-func TestKill(t *testing.T) {
-	env := newTestEnv(t)
-	clearOutput(env.player)
+	DispatchCommand(g, env.player, "@restore/object/overwrite #2=latest")
+	if g.DB.Objects[2].Name != "TestObject" || g.DB.Objects[2].Location != 0 {
+		t.Errorf("overwrite: %+v", g.DB.Objects[2])
+	}
 
-	DispatchCommand(env.game, env.player, "kill Bob")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "You killed Bob!") {
-		t.Errorf("kill: expected 'You killed Bob!', got: %s", out)
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, bob, "@restore/object #5=latest")
+	if !strings.Contains(getOutput(bob), "Permission denied") {
+		t.Error("non-wizard could restore")
 	}
 }
 
-// --- Building Commands ---
-
-func TestCreate(t *testing.T) {
+func TestLazyLoad(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	path := filepath.Join(t.TempDir(), "game.bolt")
+	store, err := boltstore.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Bob last connected two years ago, and #2 is being destroyed.
+	g.SetAttr(3, aLast, time.Now().AddDate(-2, 0, 0).Format(lastTimeFormat))
+	g.SetAttr(3, 6, "A quiet sort.")
+	g.SetAttr(2, 6, "Dusty.")
+	g.DB.Objects[2].Flags[0] |= gamedb.FlagGoing
+	g.SetAttr(1, 6, "The boss.")
+	if err := store.ImportFromDatabase(g.DB); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
 
-	DispatchCommand(env.game, env.player, "@create Widget")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Widget") && !strings.Contains(out, "created") {
-		// Some format of creation confirmation
-		_ = out
+	store, err = boltstore.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetLazy(ColdObject(365*24*time.Hour), 1)
+	if err := store.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	g.DB, g.Store = store.DB(), store
+	bob, thing := g.DB.Objects[3], g.DB.Objects[2]
+	if bob.Attrs != nil || thing.Attrs != nil || g.DB.Objects[1].Attrs == nil {
+		t.Fatalf("paged out: bob %v, thing %v, wizard %v", bob.Attrs, thing.Attrs, g.DB.Objects[1].Attrs)
+	}
+
+	// Writing a paged-out object keeps its stored attributes.
+	bob.Pennies = 77
+	g.PersistObject(bob)
+	if got := g.GetAttrText(3, 6); got != "A quiet sort." {
+		t.Errorf("Bob's description read back as %q", got)
+	}
+	// Changed in memory only; paging Bob out again must write it through.
+	bob.SetAttributes(append(bob.Attributes(), gamedb.Attribute{Number: 7, Value: "\x011:0:Welcome back."}))
+	if got := g.GetAttrText(2, 6); got != "Dusty." {
+		t.Errorf("#2's description read back as %q", got)
+	}
+	if bob.Attrs != nil {
+		t.Error("Bob still in memory with a one-object cache")
+	}
+	if stats, ok := store.LazyStats(); !ok || stats.PagedOut != 1 || stats.PagedIn != 1 || stats.Faults != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+	store.Close()
+
+	store, err = boltstore.OpenReadOnly(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	saved := store.DB().Objects[3]
+	if saved.Pennies != 77 || len(saved.Attrs) != 3 {
+		t.Errorf("saved Bob: pennies %d, attrs %v", saved.Pennies, saved.Attrs)
 	}
 }
 
-func TestDig(t *testing.T) {
+func TestWorldLock(t *testing.T) {
+	// Run with -race: each way work reaches the game — telnet input,
+	// WebSocket messages, REST requests, the queue processor and the
+	// maintenance timer — must take the world lock itself.
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Guests = NewGuestManager()
+	g.SetAttr(1, aPass, "wizpw")
+	g.SetAttr(3, aPass, "bobpw")
+	g.SetAttrByName(1, "SHUFFLE", "@tel #2=#4;@tel #2=#0")
+
+	// contentsOK reports whether every object is in its location's
+	// contents chain exactly once, and in no other.
+	contentsOK := func() bool {
+		seen := make(map[gamedb.DBRef]int)
+		for ref, obj := range g.DB.Objects {
+			n := 0
+			for next := obj.Contents; next != gamedb.Nothing; next = g.DB.Objects[next].Next {
+				if g.DB.Objects[next].Location != ref || n > len(g.DB.Objects) {
+					return false
+				}
+				seen[next]++
+				n++
+			}
+		}
+		for ref, obj := range g.DB.Objects {
+			if obj.Location != gamedb.Nothing && seen[ref] != 1 {
+				return false
+			}
+		}
+		return true
+	}
 
-	DispatchCommand(env.game, env.player, "@dig New Room")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "New Room") {
-		t.Errorf("@dig: expected room name in output, got: %s", out)
+	interval := maintenanceInterval
+	maintenanceInterval = 20 * time.Millisecond
+	g.StartMaintenance()
+	maintenanceInterval = interval
+	g.StartQueueProcessor()
+
+	// Telnet
+	s := &Server{Game: g, Config: Config{WelcomeText: "Welcome.\r\n"}}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handleConnection(conn)
+		}
+	}()
+
+	// WebSocket and REST
+	ws := &WebServer{game: g, mux: http.NewServeMux(), auth: NewAuthService(g, "secret", 60)}
+	ws.mux.HandleFunc("GET /ws", ws.handleWebSocket)
+	ws.RegisterRESTRoutes()
+	srv := httptest.NewServer(ws.mux)
+	defer srv.Close()
+	var token string
+	g.Do(func() { token, err = ws.auth.Login("Wizard", "wizpw") })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	fail := func(format string, args ...any) {
+		t.Errorf(format, args...)
+	}
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			fail("telnet: %v", err)
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(20 * time.Second))
+		sc := bufio.NewScanner(conn)
+		// Input sent before the welcome would go to option negotiation.
+		for sc.Scan() && !strings.Contains(sc.Text(), "Welcome.") {
+		}
+		fmt.Fprint(conn, "connect bob bobpw\n")
+		for i := 0; i < 50; i++ {
+			fmt.Fprint(conn, "get TestObject\ndrop TestObject\n")
+		}
+		fmt.Fprint(conn, "think telnet done\n")
+		for sc.Scan() {
+			if strings.Contains(sc.Text(), "telnet done") {
+				return
+			}
+		}
+		fail("telnet: no reply: %v", sc.Err())
+	}()
+	go func() {
+		defer wg.Done()
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws?token="+token, nil)
+		if err != nil {
+			fail("websocket: %v", err)
+			return
+		}
+		defer conn.Close()
+		for i := 0; i < 50; i++ {
+			conn.WriteJSON(WSMessage{Type: "command", Command: "@tel #5=#4"})
+			conn.WriteJSON(WSMessage{Type: "command", Command: "@tel #5=#0"})
+			conn.WriteJSON(WSMessage{Type: "command", Command: "@trigger me/SHUFFLE"})
+		}
+		conn.WriteJSON(WSMessage{Type: "command", Command: "think websocket done"})
+		conn.SetReadDeadline(time.Now().Add(20 * time.Second))
+		for {
+			var msg WSMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				fail("websocket: no reply: %v", err)
+				return
+			}
+			if strings.Contains(msg.Text, "websocket done") {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		call := func(method, path, body string) {
+			req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				fail("REST %s %s: %v", method, path, err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				fail("REST %s %s: %s", method, path, resp.Status)
+			}
+		}
+		for i := 0; i < 25; i++ {
+			call("PUT", "/api/v1/objects/2/attrs/notes", fmt.Sprintf(`{"value":"note %d"}`, i))
+			call("GET", "/api/v1/who", "")
+			call("POST", "/api/v1/command", `{"command":"@tel #2=#4","wait":1}`)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			ok := true
+			g.Do(func() { ok = contentsOK() })
+			if !ok {
+				fail("contents chains corrupted by concurrent commands")
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	wg.Wait()
+	g.DrainQueue()
+	g.Do(func() {
+		if !contentsOK() {
+			t.Error("contents chains corrupted by concurrent commands")
+		}
+	})
+}
+
+// lockProbe records whether the world lock was held when a response was
+// written.
+type lockProbe struct {
+	*httptest.ResponseRecorder
+	g        *Game
+	heldLock bool
+}
+
+func (p *lockProbe) Write(b []byte) (int, error) {
+	if p.g.world.TryLock() {
+		p.g.world.Unlock()
+	} else {
+		p.heldLock = true
 	}
+	return p.ResponseRecorder.Write(b)
 }
 
-func TestDescribe(t *testing.T) {
+func TestSlowClientsDontHoldLock(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.OutputLimit = 16384
+	ws := &WebServer{game: g}
+
+	// A locked handler's response is sent once the lock is released.
+	h := ws.locked(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "got %s", body)
+	})
+	rec := &lockProbe{ResponseRecorder: httptest.NewRecorder(), g: g}
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader("hello")))
+	if rec.heldLock || rec.Code != http.StatusAccepted || rec.Body.String() != "got hello" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("locked handler: held lock %v, %d %q %v", rec.heldLock, rec.Code, rec.Body, rec.Header())
+	}
 
-	DispatchCommand(env.game, env.player, "@describe here=A test room.")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Set.") && !strings.Contains(out, "set") {
-		t.Errorf("@describe: expected confirmation, got: %s", out)
+	// A WebSocket client that isn't reading doesn't hold up sends to it.
+	srv := httptest.NewServer(http.HandlerFunc(ws.handleWebSocket))
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	read := func() WSMessage {
+		t.Helper()
+		var msg WSMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	if msg := read(); msg.Type != "welcome" {
+		t.Fatalf("first message %+v, want welcome", msg)
+	}
+	var d *Descriptor
+	for _, dd := range g.Conns.AllDescriptors() {
+		if dd.Transport == TransportWebSocket {
+			d = dd
+		}
+	}
+	pad := strings.Repeat("x", 1024)
+	start := time.Now()
+	g.Do(func() {
+		for i := 0; i < 5000; i++ {
+			d.Send(fmt.Sprintf("line %d %s", i, pad))
+		}
+	})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("sending to a stalled client took %v", elapsed)
+	}
+	flushed := false
+	for {
+		msg := read()
+		if msg.Type != "text" {
+			t.Fatalf("got %+v, want text", msg)
+		}
+		if msg.Text == strings.TrimSpace(outputFlushedMsg) {
+			flushed = true
+		}
+		if strings.HasPrefix(msg.Text, "line 4999 ") {
+			break
+		}
 	}
+	if !flushed {
+		t.Error("no output flushed notice after exceeding output_limit")
+	}
+}
 
-	text := env.game.GetAttrText(0, 6) // A_DESC = 6
-	if text != "A test room." {
-		t.Errorf("@describe: expected 'A test room.', got: %s", text)
+func TestPlayerIndex(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	if got := LookupPlayer(g.DB, "bob"); got != 3 {
+		t.Fatalf("LookupPlayer(bob) = #%d, want #3", got)
+	}
+
+	DispatchCommand(g, env.player, "@name *bob=Robert")
+	DispatchCommand(g, env.player, "@alias #3=Bobby")
+	DispatchCommand(g, env.player, "@pcreate Carol=secret")
+	for _, tc := range []struct {
+		name string
+		want gamedb.DBRef
+	}{
+		{"Bob", gamedb.Nothing},
+		{"robert", 3},
+		{"*BOBBY", 3},
+	} {
+		if got := LookupPlayer(g.DB, tc.name); got != tc.want {
+			t.Errorf("LookupPlayer(%s) = #%d, want #%d", tc.name, got, tc.want)
+		}
+	}
+	carol := LookupPlayer(g.DB, "carol")
+	if carol == gamedb.Nothing {
+		t.Fatal("@pcreate'd player Carol not found")
+	}
+	if got := g.MatchObject(env.player.Player, "*bobby"); got != 3 {
+		t.Errorf("MatchObject(*bobby) = #%d, want #3", got)
+	}
+	if got := g.LookupPlayer("Rob"); got != 3 {
+		t.Errorf("Game.LookupPlayer(Rob) = #%d, want #3", got)
+	}
+
+	DispatchCommand(g, env.player, "@toad *carol")
+	if got := LookupPlayer(g.DB, "carol"); got != gamedb.Nothing {
+		t.Errorf("LookupPlayer(carol) after @toad = #%d, want no match (was #%d)", got, carol)
 	}
 }
 
-func TestName(t *testing.T) {
+func TestComsysListings(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	g.Comsys = NewComsys()
+	g.Comsys.AddChannel(&gamedb.Channel{Name: "Public", Owner: 1, Flags: gamedb.ChanPublic, Header: "\x1b[32m[Public]\x1b[0m", NumSent: 4})
+	g.Comsys.AddChannel(&gamedb.Channel{Name: "Alpha", Owner: 1, Flags: gamedb.ChanPublic | gamedb.ChanLoud})
+	g.Comsys.AddChannel(&gamedb.Channel{Name: "Staff", Owner: 1})
+	g.Comsys.AddAlias(&gamedb.ChanAlias{Player: 1, Channel: "Public", Alias: "pub", IsListening: true})
+	g.Comsys.AddAlias(&gamedb.ChanAlias{Player: 3, Channel: "Public", Alias: "p"})
+	g.Comsys.AddAlias(&gamedb.ChanAlias{Player: 1, Channel: "Alpha", Alias: "a", IsListening: true})
+
+	lines := func(d *Descriptor) []string {
+		return strings.Split(strings.TrimRight(getOutput(d), "\n"), "\n")
+	}
 
-	DispatchCommand(env.game, env.player, "@name #2=RenamedObject")
-	out := getOutput(env.player)
-	_ = out
+	DispatchCommand(g, env.player, "@clist/members")
+	out := lines(env.player)
+	if len(out) != 6 || !strings.HasPrefix(out[2], "Public ") || !strings.HasPrefix(out[4], "Staff ") {
+		t.Fatalf("@clist/members: %q", out)
+	}
+	if out[5] != "-- 3 channel(s), 3 member(s), 4 message(s) --" {
+		t.Errorf("@clist totals: %q", out[5])
+	}
+	// Columns line up even though the Public header is colored.
+	if idx := strings.Index(out[2], "P "); idx != strings.Index(out[3], "PL ") {
+		t.Errorf("@clist flags column misaligned: %q / %q", out[2], out[3])
+	}
 
-	obj := env.game.DB.Objects[2]
-	if obj.Name != "RenamedObject" {
-		t.Errorf("@name: expected 'RenamedObject', got: %s", obj.Name)
+	// Private channels are hidden from mortals.
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, bob, "@clist")
+	if out := getOutput(bob); strings.Contains(out, "Staff") {
+		t.Errorf("mortal @clist shows private channel: %q", out)
+	}
+
+	DispatchCommand(g, env.player, "@cwho/alpha Public")
+	out = lines(env.player)
+	if !strings.HasPrefix(out[3], "Bob ") || !strings.HasPrefix(out[4], "Wizard ") ||
+		out[5] != "-- 2 subscriber(s), 1 listening, 2 connected --" {
+		t.Errorf("@cwho/alpha: %q", out)
+	}
+
+	DispatchCommand(g, env.player, "comlist/alpha")
+	out = lines(env.player)
+	if !strings.HasPrefix(out[2], "a ") || out[len(out)-1] != "-- 2 alias(es), 2 on --" {
+		t.Errorf("comlist/alpha: %q", out)
 	}
 }
 
-func TestSet(t *testing.T) {
+func TestWriteBehindPersistence(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	path := filepath.Join(t.TempDir(), "game.bolt")
+	store, err := boltstore.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.game.Store = store
+	store.StartWriteBehind(time.Hour)
 
-	DispatchCommand(env.game, env.player, "@set #2=DARK")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "set") || !strings.Contains(out, "Set") {
-		// Some flag set confirmation
-		_ = out
+	// Repeated writes of one object coalesce into a single queued write.
+	obj := env.game.DB.Objects[2]
+	for _, name := range []string{"First", "Second", "Final"} {
+		obj.Name = name
+		env.game.PersistObject(obj)
+	}
+	env.game.PersistObjects(env.game.DB.Objects[5], obj)
+	if n := store.PendingWrites(); n != 2 {
+		t.Errorf("pending writes = %d, want 2", n)
+	}
+	// A deleted object is not written back.
+	store.DeleteObject(5)
+	if n := store.PendingWrites(); n != 1 {
+		t.Errorf("pending writes after delete = %d, want 1", n)
+	}
+
+	// Close commits what's queued.
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	store, err = boltstore.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := store.DB().Objects[2]; !ok || o.Name != "Final" {
+		t.Errorf("object #2 after reopen: %+v", o)
+	}
+	if _, ok := store.DB().Objects[5]; ok {
+		t.Error("deleted object #5 was written")
 	}
 }
 
-func TestLink(t *testing.T) {
+func TestForwardlist(t *testing.T) {
 	env := newTestEnv(t)
+	g := env.game
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	g.RemoveFromContents(0, 3)
+	g.DB.Objects[3].Location = 4
+	g.AddToContents(4, 3)
+
+	// Mortals can only forward to things they control or that are LINK_OK.
+	g.DB.Objects[5].Owner = 3
+	DispatchCommand(g, bob, "@forwardlist #5=#1")
+	if out := getOutput(bob); out != "You can't forward to #1." {
+		t.Errorf("@forwardlist to uncontrolled object: got %q", out)
+	}
+
+	DispatchCommand(g, env.player, "@forwardlist #2=#4 #5")
+	if out := getOutput(env.player); out != "Set." || !g.DB.Objects[2].HasFlag2(gamedb.Flag2HasFwd) {
+		t.Fatalf("@forwardlist: got %q", out)
+	}
+	// #5 forwards back to #2; the loop must end.
+	g.SetAttr(5, aForwardlist, "#2")
 	clearOutput(env.player)
 
-	// Link Wizard's home to OtherRoom
-	DispatchCommand(env.game, env.player, "@link me=#4")
-	out := getOutput(env.player)
-	_ = out
+	DispatchCommand(g, env.player, "say hello")
+	if out := getOutput(bob); strings.Count(out, "From TestObject, Wizard says") != 1 {
+		t.Errorf("forwarded speech: got %q", out)
+	}
 
-	playerObj := env.game.DB.Objects[1]
-	if playerObj.Link != 4 {
-		t.Errorf("@link: expected link to #4, got #%d", playerObj.Link)
+	g.SetAttr(2, aPrefix, "Radio:")
+	DispatchCommand(g, env.player, "say again")
+	if out := getOutput(bob); !strings.Contains(out, "Radio: Wizard says") {
+		t.Errorf("forwarded speech with @prefix: got %q", out)
 	}
 }
 
-func TestParent(t *testing.T) {
+func TestInfoCommand(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	g.StartTime = time.Now().Add(-90 * time.Second)
+	g.ArchiveDir = t.TempDir()
 
-	DispatchCommand(env.game, env.player, "@parent #2=#0")
+	DispatchCommand(g, env.player, "@info")
 	out := getOutput(env.player)
-	_ = out
+	for _, want := range []string{"Version:", "Uptime:", "Objects:", "Queue:",
+		"Connections:", "Last:          none in", "auto-archive disabled", "Heap:"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@info missing %q:\n%s", want, out)
+		}
+	}
 
-	obj := env.game.DB.Objects[2]
-	if obj.Parent != 0 {
-		t.Errorf("@parent: expected parent #0, got #%d", obj.Parent)
+	info := g.InfoStats()
+	if info["version"] != Version || info["uptime_seconds"].(int64) < 90 {
+		t.Errorf("InfoStats: got %v", info)
 	}
-}
 
-// --- Communication Commands ---
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, bob, "@info")
+	if out := getOutput(bob); out != "Permission denied." {
+		t.Errorf("@info as mortal: got %q", out)
+	}
+}
 
-func TestEmit(t *testing.T) {
+func TestSignalShutdownAndReload(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	g.Guests = NewGuestManager()
+	g.Conf = DefaultGameConf()
+	g.Conf.Port = 4201
+
+	// SIGHUP: the config file is re-read, but the listener port isn't.
+	dir := t.TempDir()
+	g.ConfPath = filepath.Join(dir, "game.yaml")
+	if err := os.WriteFile(g.ConfPath, []byte("mud_name: Reloaded\nport: 9999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g.ReloadConfig()
+	if g.Conf.MudName != "Reloaded" || g.Conf.Port != 4201 {
+		t.Errorf("after reload: mud_name=%q port=%d", g.Conf.MudName, g.Conf.Port)
+	}
 
-	// @emit sends to the room - in test env it goes to ConnManager but our
-	// test descriptor is in the room so it should receive via SendToRoom
-	DispatchCommand(env.game, env.player, "@emit Test broadcast message")
-	// Output goes through ConnManager.SendToRoom which iterates room contents
-	// In our test setup, the descriptor IS in the room
+	// SIGTERM: players are told, and queued writes are committed.
+	store, err := boltstore.Open(filepath.Join(dir, "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	g.Store = store
+	store.StartWriteBehind(time.Hour)
+	g.PersistObject(g.DB.Objects[2])
+
+	g.Shutdown(nil, "Goodbye!")
+	if out := getOutput(env.player); !strings.Contains(out, "Goodbye!") {
+		t.Errorf("shutdown message: got %q", out)
+	}
+	if n := store.PendingWrites(); n != 0 {
+		t.Errorf("pending writes after shutdown = %d, want 0", n)
+	}
 }
 
-func TestEmitWithSoftcode(t *testing.T) {
+func TestReadconf(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.ConfPath = filepath.Join(t.TempDir(), "game.yaml")
+	conf := "mud_name: Hot\nport: 7000\nmaster_room: 4\nplayer_starting_room: 2\n" +
+		"output_limit: lots\nno_such_thing: 1\n"
+	if err := os.WriteFile(g.ConfPath, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
 
-	DispatchCommand(env.game, env.player, "think [add(2,3)]")
+	DispatchCommand(g, env.player, "@readconf")
 	out := getOutput(env.player)
-	if !strings.Contains(out, "5") {
-		t.Errorf("softcode eval: expected '5', got: %s", out)
+	for _, want := range []string{
+		"Applied: master_room = 4 (was 2)",
+		"Applied: mud_name = Hot (was GoTinyMUSH)",
+		"Skipped: port = 7000 (takes effect at restart)",
+		"Invalid: no_such_thing: unknown directive",
+		"Invalid: output_limit:",
+		"Invalid: player_starting_room: #2 is not a room",
+		"2 applied, 1 skipped, 3 invalid.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@readconf missing %q:\n%s", want, out)
+		}
+	}
+	if g.Conf.MudName != "Hot" || g.Conf.Port != 6250 || g.Conf.OutputLimit != 16384 || g.Conf.PlayerStartingRoom != 0 {
+		t.Errorf("after @readconf: %+v", g.Conf)
 	}
 }
 
-// --- Admin Commands ---
-
-func TestMotd(t *testing.T) {
+func TestLogCategories(t *testing.T) {
 	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.LogDir = t.TempDir()
+	ConfigureLogging(g.Conf)
+	defer ConfigureLogging(DefaultGameConf())
+	readLog := func() string {
+		data, _ := os.ReadFile(filepath.Join(g.Conf.LogDir, "local.log"))
+		return string(data)
+	}
+
+	DispatchCommand(g, env.player, "@log testing=hello there")
+	if got := readLog(); !strings.Contains(got, "MSG: TESTI: Wizard(#1): hello there") {
+		t.Errorf("local.log = %q", got)
+	}
+
+	DispatchCommand(g, env.player, "@admin log=!local")
+	DispatchCommand(g, env.player, "@log dropped")
+	if got := readLog(); strings.Contains(got, "dropped") {
+		t.Errorf("@log written with local off: %q", got)
+	}
 	clearOutput(env.player)
+	DispatchCommand(g, env.player, "@admin log")
+	if out := getOutput(env.player); strings.Contains(out, "local") || !strings.Contains(out, "wizard") {
+		t.Errorf("@admin log = %q", out)
+	}
 
-	// Set MOTD (wizard only)
-	DispatchCommand(env.game, env.player, "@motd Welcome to GoTinyMUSH!")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "MOTD") || !strings.Contains(out, "set") {
-		_ = out
+	DispatchCommand(g, env.player, "@admin log=local")
+	DispatchCommand(g, env.player, "@admin log_format=json")
+	DispatchCommand(g, env.player, "@log as json")
+	if got := readLog(); !strings.Contains(got, `"category":"local"`) || !strings.Contains(got, `"msg":"LOCAL: Wizard(#1): as json"`) {
+		t.Errorf("json entry missing: %q", got)
 	}
 
-	// Check it was stored
-	if env.game.MOTD != "Welcome to GoTinyMUSH!" {
-		t.Errorf("@motd: expected 'Welcome to GoTinyMUSH!', got: %s", env.game.MOTD)
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, bob, "@log sneaky")
+	if out := getOutput(bob); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("non-wizard @log = %q", out)
 	}
 }
 
-func TestSearch(t *testing.T) {
+func TestLastConnections(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	g.Guests = NewGuestManager()
+	g.ConnLog = NewConnLog()
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	g.Store = store
 
-	DispatchCommand(env.game, env.player, "@search type=player")
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	if last := g.noteLogin(bob); last != "" {
+		t.Errorf("first login: %q", last)
+	}
+	bob.DisconnectReason = "quit"
+	g.DisconnectPlayer(bob)
+
+	bob = makeTestDescriptor(t, g.Conns, 3)
+	if last := g.noteLogin(bob); !strings.HasPrefix(last, "Last connect was from test on ") {
+		t.Errorf("second login: %q", last)
+	}
+	DispatchCommand(g, env.player, "@last Bob")
 	out := getOutput(env.player)
-	if !strings.Contains(out, "Wizard") || !strings.Contains(out, "Bob") {
-		t.Errorf("@search: expected Wizard and Bob in results, got: %s", out)
+	for _, want := range []string{"Connection history for Bob(#3):", "still connected", "quit"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@last missing %q:\n%s", want, out)
+		}
+	}
+
+	DispatchCommand(g, bob, "@last Wizard")
+	if out := getOutput(bob); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("@last on another player = %q", out)
+	}
+
+	recs, err := store.LoadConnRecords()
+	if err != nil || len(recs) != 2 || recs[0].Reason != "quit" || !recs[1].Disconnect.IsZero() {
+		t.Errorf("stored records = %+v, %v", recs, err)
 	}
 }
 
-func TestDecompile(t *testing.T) {
+func TestSuspectReports(t *testing.T) {
 	env := newTestEnv(t)
+	g := env.game
+	bob := makeTestDescriptor(t, g.Conns, 3)
 
-	// Set some attributes first
-	env.game.SetAttr(2, 6, "A test object.")  // DESC
-	env.game.SetAttr(2, 4, "You got it!")      // SUCC
+	DispatchCommand(g, bob, "@set me=SUSPECT")
+	if out := getOutput(bob); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("player set own SUSPECT: %q", out)
+	}
+	DispatchCommand(g, env.player, "@set Bob=SUSPECT")
+	if !g.isSuspect(3) {
+		t.Fatal("wizard could not set SUSPECT")
+	}
+
+	// Not WATCHER: no reports.
 	clearOutput(env.player)
+	g.logInput(bob, "look")
+	if out := getOutput(env.player); strings.Contains(out, "[Suspect]") {
+		t.Errorf("non-watcher got %q", out)
+	}
 
-	DispatchCommand(env.game, env.player, "@decompile #2")
+	g.DB.Objects[1].Flags[1] |= gamedb.Flag2Watcher
+	g.noteLogin(bob)
+	g.logInput(bob, "look")
+	DispatchCommand(g, env.player, "@name Bob=Robert")
 	out := getOutput(env.player)
-	if !strings.Contains(out, "TestObject") {
-		t.Errorf("@decompile: expected object name, got: %s", out)
+	for _, want := range []string{
+		"[Suspect] Bob has connected from test.",
+		"[Suspect] Bob typed: look",
+		"[Suspect] Robert was renamed from Bob.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("watcher missing %q:\n%s", want, out)
+		}
 	}
 }
 
-func TestChzone(t *testing.T) {
+func TestWatchTrace(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
 
-	// Zone a THING to another THING (rooms can only be zoned to rooms)
-	DispatchCommand(env.game, env.player, "@chzone #2=#5")
-	out := getOutput(env.player)
-	_ = out
+	DispatchCommand(g, env.player, "@watch #2")
+	if out := getOutput(env.player); !strings.Contains(out, "Watching TestObject(#2).") {
+		t.Fatalf("@watch = %q", out)
+	}
+	g.ExecuteAsObject(2, 1, "think hello")
+	if out := getOutput(env.player); !strings.Contains(out, "[watch] TestObject(#2): command: think hello") {
+		t.Errorf("trace missing: %q", out)
+	}
 
-	obj := env.game.DB.Objects[2]
-	if obj.Zone != 5 {
-		t.Errorf("@chzone: expected zone #5, got #%d", obj.Zone)
+	// Rate limited: only watchRateLimit lines a second get through.
+	g.watches.remove(2, 1)
+	g.watches.add(2, 1)
+	for i := 0; i < watchRateLimit+5; i++ {
+		g.traceWatch(2, "line %d", i)
+	}
+	if n := strings.Count(getOutput(env.player), "[watch]"); n != watchRateLimit {
+		t.Errorf("got %d trace lines, want %d", n, watchRateLimit)
 	}
-}
 
-// --- Dispatch and Switches ---
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, bob, "@watch #2")
+	if out := getOutput(bob); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("non-wizard @watch = %q", out)
+	}
 
-func TestSwitchParsing(t *testing.T) {
-	env := newTestEnv(t)
+	DispatchCommand(g, env.player, "@watch/off #2")
 	clearOutput(env.player)
-
-	// @dolist with /now switch should work
-	DispatchCommand(env.game, env.player, "@dolist/now a b c=think ##")
-	out := getOutput(env.player)
-	// Should see a, b, c as separate think outputs
-	if !strings.Contains(out, "a") {
-		t.Errorf("@dolist/now: expected output, got: %s", out)
+	g.traceWatch(2, "after off")
+	if out := getOutput(env.player); strings.Contains(out, "[watch]") {
+		t.Errorf("trace after @watch/off: %q", out)
 	}
 }
 
-// --- Help System ---
-
-func TestHelpNoFiles(t *testing.T) {
+func TestPsFiltering(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	g.Queue.AddWait(&QueueEntry{Player: 2, Cause: 3, Command: "say later",
+		WaitUntil: time.Now().Add(30 * time.Second), SemObj: gamedb.Nothing})
+	g.Queue.AddSemaphore(&QueueEntry{Player: 3, Cause: 3, Command: "say sem", SemObj: 2, SemAttr: 47})
 
-	// No help files loaded, should say no help available
-	DispatchCommand(env.game, env.player, "help")
+	DispatchCommand(g, env.player, "@ps/long")
 	out := getOutput(env.player)
-	if !strings.Contains(out, "No help available") && !strings.Contains(out, "no help") {
-		// Some message about no help
-		_ = out
+	for _, want := range []string{"TestObject(#2):say later", "Enactor: Bob(#3)", "Runs at:", "Totals: Queue...0/0  Wait...1/1  Semaphore...0/1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@ps/long missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "say sem") {
+		t.Errorf("@ps listed another player's entry:\n%s", out)
 	}
-}
 
-// --- Multiple Attribute Setters ---
+	DispatchCommand(g, env.player, "@ps Bob")
+	if out := getOutput(env.player); !strings.Contains(out, "[#2/SEMAPHORE]Bob(#3):say sem") {
+		t.Errorf("@ps Bob = %q", out)
+	}
 
-func TestAttrSetterBulk(t *testing.T) {
+	DispatchCommand(g, bob, "@ps #2")
+	DispatchCommand(g, bob, "@ps/all")
+	if out := getOutput(bob); strings.Count(out, "Permission denied.") != 2 {
+		t.Errorf("bob's @ps = %q", out)
+	}
+
+	for i := 0; i < psPageSize; i++ {
+		g.Queue.AddWait(&QueueEntry{Player: 2, Command: fmt.Sprintf("say n%d", i),
+			WaitUntil: time.Now().Add(time.Minute), SemObj: gamedb.Nothing})
+	}
+	DispatchCommand(g, env.player, "@ps/all =2")
+	out = getOutput(env.player)
+	if !strings.Contains(out, "Page 2 of 2.") || strings.Count(out, "TestObject(#2):") != 1 {
+		t.Errorf("@ps/all page 2 = %q", out)
+	}
+}
+
+func TestQueueFairness(t *testing.T) {
 	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.initQueueLimits()
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	g.DB.Objects[2].Owner = 3
+
+	// Owners take turns, queue_idle_chunk entries at a time.
+	for i := 0; i < 5; i++ {
+		g.Queue.Add(&QueueEntry{Player: 2, Command: fmt.Sprintf("bob%d", i), SemObj: gamedb.Nothing})
+	}
+	g.Queue.Add(&QueueEntry{Player: 1, Command: "wiz", SemObj: gamedb.Nothing})
+	ownerOf := func(obj gamedb.DBRef) gamedb.DBRef { return ResolveOwner(g, obj) }
+	var order []string
+	for _, e := range g.Queue.PopFair(5, 3, ownerOf) {
+		order = append(order, e.Command)
+	}
+	if got := strings.Join(order, " "); got != "bob0 bob1 bob2 wiz bob3" {
+		t.Errorf("PopFair order = %q", got)
+	}
+	g.Queue.HaltAll()
 
-	// Test several attribute-setting commands
-	tests := []struct {
-		cmd     string
-		attrNum int
-		value   string
-	}{
-		{"@success me=win", 4, "win"},
-		{"@osuccess me=wins", 1, "wins"},
-		{"@fail me=nope", 3, "nope"},
-		{"@ofail me=fails", 2, "fails"},
-		{"@sex me=Female", 7, "Female"},
-		{"@alias me=Wiz", 58, "Wiz"},
-		{"@listen me=* says *", 26, "* says *"},
-		{"@idesc me=Inside description", 32, "Inside description"},
-		{"@reject me=Go away!", 72, "Go away!"},
+	// Going over player_queue_limit halts the object and tells the owner.
+	g.Conf.PlayerQueueLimit = 3
+	for i := 0; i < 4; i++ {
+		g.Queue.Add(&QueueEntry{Player: 2, Command: "think x", SemObj: gamedb.Nothing})
+	}
+	if !g.DB.Objects[2].HasFlag(gamedb.FlagHalt) {
+		t.Error("object over its owner's queue limit was not halted")
+	}
+	if imm, _, _ := g.Queue.Stats(); imm != 0 {
+		t.Errorf("halted object still has %d queued", imm)
+	}
+	if out := getOutput(bob); !strings.Contains(out, "Run away object TestObject(#2): too many commands queued.") {
+		t.Errorf("owner notice = %q", out)
 	}
 
-	for _, tt := range tests {
-		clearOutput(env.player)
-		DispatchCommand(env.game, env.player, tt.cmd)
-		out := getOutput(env.player)
-		if !strings.Contains(out, "Set.") {
-			t.Errorf("%s: expected 'Set.', got: %s", tt.cmd, out)
+	// Going over queue_owner_rate does the same.
+	g.DB.Objects[2].Flags[0] &^= gamedb.FlagHalt
+	g.Conf.PlayerQueueLimit = 100
+	g.Conf.QueueOwnerRate = 2
+	for i := 0; i < 4; i++ {
+		g.Queue.Add(&QueueEntry{Player: 2, Command: "@va me=x", SemObj: gamedb.Nothing})
+	}
+	g.ProcessQueue()
+	if !g.DB.Objects[2].HasFlag(gamedb.FlagHalt) {
+		t.Error("object over queue_owner_rate was not halted")
+	}
+	if out := getOutput(bob); !strings.Contains(out, "owner ran over 2 commands per second") {
+		t.Errorf("owner notice = %q", out)
+	}
+}
+
+func TestWaitUntilAndSemaphoreTimeout(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+
+	DispatchCommand(g, env.player, "@wait/until 2099-01-02 03:04=think later")
+	DispatchCommand(g, env.player, "@wait/until 4102444800=think epoch")
+	DispatchCommand(g, env.player, "@wait/until someday=think never")
+	if out := getOutput(env.player); !strings.Contains(out, "That's not a valid time.") {
+		t.Errorf("bad time output = %q", out)
+	}
+	waits := g.Queue.Matching(func(e *QueueEntry) bool { return true })
+	if len(waits) != 2 {
+		t.Fatalf("queued %d entries, want 2", len(waits))
+	}
+	if want := time.Date(2099, 1, 2, 3, 4, 0, 0, time.Local); !waits[0].WaitUntil.Equal(want) {
+		t.Errorf("until date = %v, want %v", waits[0].WaitUntil, want)
+	}
+	if want := time.Unix(4102444800, 0); !waits[1].WaitUntil.Equal(want) {
+		t.Errorf("until epoch = %v, want %v", waits[1].WaitUntil, want)
+	}
+	g.Queue.HaltAll()
+
+	vb := g.ResolveAttrNum("VB")
+	DispatchCommand(g, env.player, "@wait me/VB/30=@va me=fired")
+	if _, _, sem := g.Queue.Stats(); sem != 1 || g.GetAttrText(1, vb) != "1" {
+		t.Fatalf("semaphore wait not queued: sem=%d VB=%q", sem, g.GetAttrText(1, vb))
+	}
+	e := g.Queue.Matching(func(e *QueueEntry) bool { return true })[0]
+	if e.WaitUntil.Sub(time.Now()) < 25*time.Second {
+		t.Errorf("timeout = %v", e.WaitUntil)
+	}
+	e.WaitUntil = time.Now().Add(-time.Second)
+	g.ProcessQueue()
+	if got := g.GetAttrText(1, vb); got != "0" && got != "" {
+		t.Errorf("VB after timeout = %q", got)
+	}
+	if got := g.GetAttrText(1, g.ResolveAttrNum("VA")); got != "fired" {
+		t.Errorf("timed-out command didn't run: VA = %q", got)
+	}
+}
+
+func TestCron(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Cron = NewCronTab()
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	g.Store = store
+
+	spec, err := parseCronSpec("3-59/15 9-17 1,15 * 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tue := time.Date(2026, 3, 3, 9, 18, 0, 0, time.Local) // a Tuesday, the 3rd
+	for _, tc := range []struct {
+		t    time.Time
+		want bool
+	}{
+		{tue, true},
+		{tue.Add(time.Minute), false},
+		{tue.Add(-time.Hour), false},
+		{tue.AddDate(0, 0, 1), false},
+		{time.Date(2026, 3, 15, 12, 48, 0, 0, time.Local), true},
+	} {
+		if got := spec.matches(tc.t); got != tc.want {
+			t.Errorf("matches(%v) = %v, want %v", tc.t, got, tc.want)
 		}
-		text := env.game.GetAttrText(1, tt.attrNum)
-		if text != tt.value {
-			t.Errorf("%s: expected attr %d = %q, got %q", tt.cmd, tt.attrNum, tt.value, text)
+	}
+	if _, err := parseCronSpec("61 * * * *"); err == nil {
+		t.Error("minute 61 accepted")
+	}
+
+	DispatchCommand(g, env.player, "&TICK #2=@va me=[add(v(va),1)]")
+	DispatchCommand(g, env.player, "@cron #2/TICK=*/5 * * * *")
+	DispatchCommand(g, env.player, "@cron #2/TICK=every day")
+	DispatchCommand(g, env.player, "@daily #2=@vb me=daily")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Cron entry added.") || !strings.Contains(out, "Invalid timestring:") {
+		t.Errorf("@cron output = %q", out)
+	}
+	if !g.DB.Objects[2].HasFlag2(gamedb.Flag2HasDaily) {
+		t.Error("@daily didn't set HAS_DAILY")
+	}
+	DispatchCommand(g, env.player, "@crontab #2")
+	out = getOutput(env.player)
+	for _, want := range []string{"TestObject(#2)/TICK: */5 * * * *", "TestObject(#2)/DAILY: 0 7 * * *", "Cron entries: 2."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@crontab missing %q:\n%s", want, out)
 		}
 	}
-}
+	if jobs, _ := store.LoadCronJobs(); len(jobs) != 1 || jobs[0].Spec != "*/5 * * * *" {
+		t.Errorf("stored jobs = %+v", jobs)
+	}
 
-// --- Eval Tests (softcode in commands) ---
+	g.runCron(time.Date(2026, 3, 3, 7, 5, 0, 0, time.Local))
+	g.runCron(time.Date(2026, 3, 3, 7, 6, 0, 0, time.Local))
+	for g.ProcessQueue() {
+	}
+	if got := g.GetAttrText(2, g.ResolveAttrNum("VA")); got != "1" {
+		t.Errorf("TICK ran %q times, want 1", got)
+	}
+	if got := g.GetAttrText(2, g.ResolveAttrNum("VB")); got != "daily" {
+		t.Errorf("@daily didn't run: VB = %q", got)
+	}
 
-func TestSayEvalsSoftcode(t *testing.T) {
+	DispatchCommand(g, env.player, "@crondel #2/TICK")
+	if out := getOutput(env.player); !strings.Contains(out, "Cron entries removed: 1.") {
+		t.Errorf("@crondel output = %q", out)
+	}
+	if jobs, _ := store.LoadCronJobs(); len(jobs) != 0 {
+		t.Errorf("jobs left after @crondel: %+v", jobs)
+	}
+}
+
+func TestPlayerMaintenance(t *testing.T) {
 	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.IdleTimeout = 0
+	g.Guests = NewGuestManager()
+	now := time.Now()
+
+	// Paycheck once a day, on login or while connected.
+	bob := g.DB.Objects[3]
+	bob.Pennies = 10
+	bobD := makeTestDescriptor(t, g.Conns, 3)
+	g.noteLogin(bobD)
+	g.playerMaintenance(now)
+	if bob.Pennies != 10+g.Conf.Paycheck {
+		t.Errorf("pennies after login = %d, want %d", bob.Pennies, 10+g.Conf.Paycheck)
+	}
+	g.playerMaintenance(now.Add(24 * time.Hour))
+	if bob.Pennies != 10+2*g.Conf.Paycheck {
+		t.Errorf("pennies next day = %d, want %d", bob.Pennies, 10+2*g.Conf.Paycheck)
+	}
+
+	// AWAY for disconnected players, IDLE for idle ones.
+	DispatchCommand(g, bobD, "@idle me=Back at [add(6,1)].")
+	DispatchCommand(g, bobD, "@away me=Gone.")
 	clearOutput(env.player)
+	DispatchCommand(g, env.player, "page Bob=hi")
+	if out := getOutput(env.player); strings.Contains(out, "Idle message") {
+		t.Errorf("idle message sent for an active player: %q", out)
+	}
+	bobD.LastCmd = now.Add(-time.Hour)
+	DispatchCommand(g, env.player, "page Bob=hi")
+	if out := getOutput(env.player); !strings.Contains(out, "Idle message from Bob: Back at 7.") {
+		t.Errorf("page to idle player = %q", out)
+	}
 
-	DispatchCommand(env.game, env.player, "say [add(1,2)]")
+	// Idle timeout: a warning, then the boot.
+	g.Conf.IdleTimeout = 3600
+	bobD.LastCmd = now.Add(-56 * time.Minute)
+	clearOutput(bobD)
+	g.checkIdle(now)
+	g.checkIdle(now)
+	if out := getOutput(bobD); strings.Count(out, "You will be disconnected in 4 minute(s)") != 1 {
+		t.Errorf("idle warning = %q", out)
+	}
+	g.checkIdle(now.Add(5 * time.Minute))
+	if out := getOutput(bobD); !strings.Contains(out, "*** Inactivity Timeout ***") {
+		t.Errorf("idle timeout = %q", out)
+	}
+	if bobD.DisconnectReason != "timeout" || !bobD.IsClosed() {
+		t.Errorf("idle player not disconnected (reason %q)", bobD.DisconnectReason)
+	}
+	g.Conns.Remove(bobD)
+	DispatchCommand(g, env.player, "page Bob=hi")
+	if out := getOutput(env.player); !strings.Contains(out, "Away message from Bob: Gone.") {
+		t.Errorf("page to disconnected player = %q", out)
+	}
+	if got := g.GetAttrText(3, aLast); got == "" {
+		t.Error("login didn't set LAST")
+	}
+}
+
+func TestSearchClasses(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.DB.Objects[2].Parent = 5
+	g.DB.Objects[2].Flags[0] |= gamedb.FlagDark
+	g.DB.Objects[3].Powers[0] |= gamedb.PowBoot
+	DispatchCommand(g, env.player, "&ISROOM me=hastype(%0,ROOM)")
+
+	cases := []struct{ expr, want string }{
+		{"search(type=thing)", "#2 #5"},
+		{"search(name=Test)", "#2"},
+		{"search(parent=#5)", "#2"},
+		{"search(flags=D)", "#2"},
+		{"search(flags=P!W)", "#3"},
+		{"search(power=boot)", "#3"},
+		{`search(eroom=\[eq(words(name(##)),2)\],4)`, "#4"},
+		{"lsearch(all, type, room, 1, 10)", "#4"},
+		{"search(ueval=me/ISROOM)", "#0 #4"},
+		{"search(type=fish)", "#-1 FISH: UNKNOWN TYPE"},
+	}
+	for _, c := range cases {
+		if got := evalExpr(g, env.player.Player, c.expr); got != c.want {
+			t.Errorf("%s = %q, want %q", c.expr, got, c.want)
+		}
+	}
+
+	// Bob may list players but not search the wizard's objects.
+	if got := evalExpr(g, 3, "search(players=)"); got != "#1 #3" {
+		t.Errorf("search(players=) as Bob = %q", got)
+	}
+	if got := evalExpr(g, 3, "search(Wizard type=thing)"); !strings.HasPrefix(got, "#-1") {
+		t.Errorf("search of another's objects as Bob = %q", got)
+	}
+
+	clearOutput(env.player)
+	DispatchCommand(g, env.player, "@search type=room,1")
 	out := getOutput(env.player)
-	if !strings.Contains(out, `You say "3"`) {
-		t.Errorf("say softcode: expected 'You say \"3\"', got: %s", out)
+	if !strings.Contains(out, "ROOMS:") || !strings.Contains(out, "Other Room(#4R)") ||
+		strings.Contains(out, "Room Zero") || !strings.Contains(out, "Found:  Rooms...1") {
+		t.Errorf("@search type=room,1: %s", out)
 	}
 }
 
-// --- Internal Attr Hiding ---
+func TestFindCostAndPaging(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	for i := 0; i < findPageSize+5; i++ {
+		g.CreateObject(fmt.Sprintf("Widget %d", i), gamedb.TypeThing, 3)
+	}
+	g.CreateObject("Widget Wiz", gamedb.TypeThing, 1)
+	bob := makeTestDescriptor(t, g.Conns, 3)
 
-func TestExamineHidesPassword(t *testing.T) {
+	DispatchCommand(g, bob, "@find widget")
+	out := getOutput(bob)
+	if strings.Count(out, "Widget") != findPageSize || !strings.Contains(out, "@find/more") {
+		t.Fatalf("@find first page: %d matches, output tail %q", strings.Count(out, "Widget"), out[len(out)-80:])
+	}
+	if g.DB.Objects[3].Pennies != 0 {
+		t.Errorf("@find should cost Bob search_cost; pennies = %d", g.DB.Objects[3].Pennies)
+	}
+
+	clearOutput(bob)
+	DispatchCommand(g, bob, "@find/more")
+	out = getOutput(bob)
+	if strings.Count(out, "Widget") != 5 || strings.Contains(out, "Widget Wiz") || !strings.Contains(out, "***End of List***") {
+		t.Errorf("@find/more: %s", out)
+	}
+
+	clearOutput(bob)
+	DispatchCommand(g, bob, "@find widget")
+	if out = getOutput(bob); !strings.Contains(out, "You don't have enough pennies.") {
+		t.Errorf("@find without money: %s", out)
+	}
+
+	// Wizards search everything for free, within the given range.
+	clearOutput(env.player)
+	DispatchCommand(g, env.player, "@find widget wiz,6")
+	if out = getOutput(env.player); !strings.Contains(out, "Widget Wiz") || g.DB.Objects[1].Pennies != 1000 {
+		t.Errorf("@find as wizard: %s", out)
+	}
+}
+
+func TestStatsBreakdown(t *testing.T) {
 	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.DB.Objects[3].Pennies = 1000
+	for i := 0; i < 3; i++ {
+		g.CreateObject(fmt.Sprintf("Gadget %d", i), gamedb.TypeThing, 3)
+	}
+	g.DB.Objects[g.NextRef-1].Flags[0] |= gamedb.FlagGoing
+	g.SetAttr(3, 49, "10") // QUOTA
+	bob := makeTestDescriptor(t, g.Conns, 3)
+
+	DispatchCommand(g, bob, "@stats me")
+	out := getOutput(bob)
+	if !strings.Contains(out, "Bob(#3) owns 4 objects = 0 rooms, 0 exits, 2 things, 1 players. (1 going, 0 garbage)") ||
+		!strings.Contains(out, "Quota: 2 of 10 used, 8 remaining.") {
+		t.Errorf("@stats me: %s", out)
+	}
+	if g.DB.Objects[3].Pennies != 900 {
+		t.Errorf("@stats <player> should cost search_cost; pennies = %d", g.DB.Objects[3].Pennies)
+	}
 
-	// Set a password attribute
-	env.game.SetAttr(1, 5, "secret_password") // A_PASS = 5
+	clearOutput(bob)
+	DispatchCommand(g, bob, "@stats Wizard")
+	if out = getOutput(bob); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("@stats of another player: %s", out)
+	}
+
+	DispatchCommand(g, env.player, "@stats/all")
+	out = getOutput(env.player)
+	wiz, bobRow := strings.Index(out, "Wizard(#1)"), strings.Index(out, "Bob(#3)")
+	if !strings.Contains(out, "Top Owners") || wiz < 0 || bobRow < 0 || wiz > bobRow {
+		t.Errorf("@stats/all ranking: %s", out)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	// TestObject listens; Bob is a connected player in the room.
+	DispatchCommand(g, env.player, "@listen TestObject=*")
+	g.DB.Objects[3].Location = 0
+	g.DB.Objects[3].Next = g.DB.Objects[0].Contents
+	g.DB.Objects[0].Contents = 3
+	makeTestDescriptor(t, g.Conns, 3)
 	clearOutput(env.player)
 
-	DispatchCommand(env.game, env.player, "examine me")
+	DispatchCommand(g, env.player, "@sweep")
 	out := getOutput(env.player)
-	if strings.Contains(out, "secret_password") {
-		t.Errorf("examine: password should be hidden, but found in output: %s", out)
+	for _, want := range []string{"Sweeping location...", "TestObject is listening. [messages]",
+		"Bob is listening. [player connected]", "Sweeping inventory...", "Sweep complete."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@sweep: missing %q in %s", want, out)
+		}
+	}
+
+	clearOutput(env.player)
+	DispatchCommand(g, env.player, "@sweep/here/players")
+	out = getOutput(env.player)
+	if strings.Contains(out, "TestObject") || !strings.Contains(out, "Bob is listening. [player]") ||
+		strings.Contains(out, "Sweeping inventory") {
+		t.Errorf("@sweep/here/players: %s", out)
 	}
 }
 
-// --- Look Command ---
+func TestMailFoldersAliasesReview(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Mail = NewMail(0)
+	bob := makeTestDescriptor(t, g.Conns, 3)
+
+	// A mail alias expands to its members; urgent mail says so.
+	DispatchCommand(g, env.player, "@malias *staff=Bob")
+	if out := getOutput(env.player); out != "MAIL: Alias set '*staff'." {
+		t.Fatalf("@malias create: got %q", out)
+	}
+	DispatchCommand(g, env.player, "@mail/quick/urgent *staff/Ping=first")
+	if out := getOutput(bob); !strings.Contains(out, "new URGENT mail from Wizard") {
+		t.Errorf("urgent notice: got %q", out)
+	}
+	clearOutput(env.player)
 
-func TestLook(t *testing.T) {
+	// Blind copies reach the recipient without appearing in the headers.
+	DispatchCommand(g, env.player, "@mail/to Wizard")
+	DispatchCommand(g, env.player, "@mail/bcc Bob")
+	DispatchCommand(g, env.player, "@mail/subject Second")
+	DispatchCommand(g, env.player, "-body")
+	DispatchCommand(g, env.player, "--")
+	clearOutput(env.player)
+	if msg := g.Mail.GetMessage(3, 2); msg == nil || msg.Subject != "Second" || len(msg.To) != 1 || msg.To[0] != 1 {
+		t.Fatalf("bcc copy: got %+v", msg)
+	}
+
+	// Message lists, folders and filing.
+	DispatchCommand(g, bob, "@mail/file 1-2=3")
+	DispatchCommand(g, bob, "@mail/folder 3=archive")
+	DispatchCommand(g, bob, "@mail/folder archive")
+	clearOutput(bob)
+	DispatchCommand(g, bob, "@mail urgent")
+	out := getOutput(bob)
+	if !strings.Contains(out, "folder 3: ARCHIVE (1 messages)") || !strings.Contains(out, "Ping") || strings.Contains(out, "Second") {
+		t.Errorf("folder list: got %q", out)
+	}
+
+	// Quoted replies.
+	DispatchCommand(g, bob, "@mail/reply/quote 1")
+	if body := g.Mail.GetDraft(3).Body.String(); !strings.Contains(body, "Wizard wrote:\n> first") {
+		t.Errorf("quoted reply: got %q", body)
+	}
+
+	// The sender can review what is still in recipients' mailboxes.
+	DispatchCommand(g, env.player, "@mail/review Bob")
+	out = getOutput(env.player)
+	if !strings.Contains(out, "Ping") || !strings.Contains(out, "Second") {
+		t.Errorf("@mail/review: got %q", out)
+	}
+	DispatchCommand(g, env.player, "@mail/review Bob=2")
+	if out := getOutput(env.player); !strings.Contains(out, "Subject: Second") {
+		t.Errorf("@mail/review read: got %q", out)
+	}
+}
+
+func TestMailNotifyAndAMail(t *testing.T) {
 	env := newTestEnv(t)
+	g := env.game
+	g.Mail = NewMail(0)
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	var got []events.Event
+	bob.ReceiveFunc = func(ev events.Event) { got = append(got, ev) }
+	g.SetAttr(3, aAMail, "@pemit me=Ding from %n")
+
+	g.sendMail(1, []gamedb.DBRef{3}, nil, "Hi", "there")
+	if len(got) != 1 || got[0].Type != events.EvMail || got[0].Text != "You have new mail from Wizard." ||
+		got[0].Data["subject"] != "Hi" {
+		t.Fatalf("mail event: got %+v", got)
+	}
+	if n := g.Queue.ImmediateCount(); n != 1 {
+		t.Errorf("AMAIL: %d queued actions, want 1", n)
+	}
 
-	// Set room description
-	env.game.SetAttr(0, 6, "A plain test room.") // A_DESC = 6
+	got = nil
+	g.mailLoginSummary(bob)
+	if len(got) != 1 || got[0].Text != "MAIL: You have 1 message(s) (1 unread, 0 cleared). Type @mail to read." ||
+		got[0].Data["unread"] != 1 {
+		t.Errorf("login summary: got %+v", got)
+	}
+}
+
+func TestChannelRecall(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Comsys = NewComsys()
+	DispatchCommand(g, env.player, "@ccreate Public")
+	DispatchCommand(g, env.player, "@cset Public=log 3")
+	DispatchCommand(g, env.player, "addcom pub=Public")
+	clearOutput(env.player)
+	for _, m := range []string{"one", "two", "three", "four"} {
+		DispatchCommand(g, env.player, "pub "+m)
+	}
 	clearOutput(env.player)
 
-	DispatchCommand(env.game, env.player, "look")
+	DispatchCommand(g, env.player, "pub recall 2")
 	out := getOutput(env.player)
-	if !strings.Contains(out, "Room Zero") {
-		t.Errorf("look: expected 'Room Zero' in output, got: %s", out)
+	if !strings.Contains(out, "last 2 message(s)") || !strings.Contains(out, `"three"`) ||
+		!strings.Contains(out, `"four"`) || strings.Contains(out, `"two"`) {
+		t.Errorf("recall: got %q", out)
 	}
-	if !strings.Contains(out, "A plain test room.") {
-		t.Errorf("look: expected description in output, got: %s", out)
+
+	// The ring keeps only the last three; the full log shows senders.
+	DispatchCommand(g, env.player, "@clist/recall Public")
+	out = getOutput(env.player)
+	if !strings.Contains(out, "(3 of 3 kept)") || strings.Contains(out, `"one"`) || !strings.Contains(out, "Wizard(#1)") {
+		t.Errorf("@clist/recall: got %q", out)
+	}
+
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, bob, "@clist/recall Public")
+	if out := getOutput(bob); !strings.Contains(out, "Permission denied") {
+		t.Errorf("@clist/recall by non-owner: got %q", out)
 	}
 }
 
-// --- Parent Chain Inheritance ---
+func TestChannelLocksAndCharge(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Comsys = NewComsys()
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, env.player, "@ccreate Guild")
+	DispatchCommand(g, env.player, "@clock/join Guild=#2")
+	DispatchCommand(g, env.player, "addcom g=Guild")
+	clearOutput(env.player)
 
-func TestParentChainDescLookup(t *testing.T) {
+	DispatchCommand(g, bob, "addcom g=Guild")
+	if out := getOutput(bob); out != "You can't join channel Guild." {
+		t.Fatalf("join lock: got %q", out)
+	}
+	DispatchCommand(g, env.player, "@clock Guild")
+	DispatchCommand(g, env.player, "@cset/charge Guild=30")
+	clearOutput(env.player)
+	DispatchCommand(g, bob, "addcom g=Guild")
+	if out := getOutput(bob); !strings.Contains(out, "You pay 30 pennies") || g.DB.Objects[3].Pennies != 70 {
+		t.Fatalf("charge: got %q, pennies %d", out, g.DB.Objects[3].Pennies)
+	}
+
+	// Transmit locks stop speech; gagged members hear nothing.
+	DispatchCommand(g, env.player, "@clock/transmit Guild=#1")
+	DispatchCommand(g, bob, "g hello")
+	if out := getOutput(bob); out != "You can't speak on channel Guild." {
+		t.Errorf("transmit lock: got %q", out)
+	}
+	DispatchCommand(g, bob, "g gag")
+	clearOutput(bob)
+	DispatchCommand(g, env.player, "g ping")
+	if out := getOutput(bob); out != "" {
+		t.Errorf("gagged member heard %q", out)
+	}
+
+	// DARK wizards are left off @cwho for mortals.
+	g.DB.Objects[1].Flags[0] |= gamedb.FlagDark
+	DispatchCommand(g, bob, "@cwho Guild")
+	if out := getOutput(bob); strings.Contains(out, "Wizard") || !strings.Contains(out, "Gag") {
+		t.Errorf("@cwho: got %q", out)
+	}
+}
+
+func TestIRCBridge(t *testing.T) {
 	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.IRCServer = "irc.example.com:6667"
+	g.Conf.IRCNick = "Bridge"
+	g.Conf.IRCChannels = []string{"Public=#mush"}
+	g.Comsys = NewComsys()
+	DispatchCommand(g, env.player, "@ccreate Public")
+	DispatchCommand(g, env.player, "addcom pub=Public")
+	clearOutput(env.player)
 
-	// Create parent room #6 with a DESC
-	env.game.DB.Objects[6] = &gamedb.Object{
-		DBRef:    6,
-		Name:     "Parent Room",
-		Location: gamedb.Nothing,
-		Contents: gamedb.Nothing,
-		Exits:    gamedb.Nothing,
-		Link:     gamedb.Nothing,
-		Next:     gamedb.Nothing,
-		Owner:    1,
-		Parent:   gamedb.Nothing,
-		Zone:     gamedb.Nothing,
-		Flags:    [3]int{int(gamedb.TypeRoom), 0, 0},
+	b := NewIRCBridge(g)
+	if b == nil {
+		t.Fatal("bridge not configured")
 	}
-	env.game.SetAttr(6, 6, "Inherited description.") // DESC on parent
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	b.dial = func() (net.Conn, error) { return net.Dial("tcp", ln.Addr().String()) }
+	g.IRC = b
+	b.Start()
+	t.Cleanup(b.Stop)
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	r := bufio.NewReader(server)
+	expect := func(want string) {
+		t.Helper()
+		server.SetReadDeadline(time.Now().Add(2 * time.Second))
+		line, err := r.ReadString('\n')
+		if err != nil || strings.TrimRight(line, "\r\n") != want {
+			t.Fatalf("IRC got %q (%v), want %q", line, err, want)
+		}
+	}
+	expect("NICK Bridge")
+	expect("USER Bridge 0 * :Bridge")
+	fmt.Fprintf(server, ":irc.example.com 001 Bridge :Welcome\r\n")
+	expect("JOIN #mush")
+	fmt.Fprintf(server, "PING :abc\r\n")
+	expect("PONG :abc")
+
+	// Outbound: channel speech goes to IRC without the header.
+	DispatchCommand(g, env.player, "pub Hello there")
+	expect(`PRIVMSG #mush :Wizard says, "Hello there"`)
+
+	// Inbound: IRC speech appears on the channel from a tagged speaker.
+	fmt.Fprintf(server, ":alice!a@host PRIVMSG #mush :\x02hi\x02 all\r\n")
+	fmt.Fprintf(server, "PING :sync\r\n")
+	expect("PONG :sync")
+	if out := getOutput(env.player); !strings.Contains(out, `[Public] alice@IRC says, "hi all"`) {
+		t.Errorf("inbound: got %q", out)
+	}
+}
 
-	// Set Room Zero's parent to #6
-	env.game.DB.Objects[0].Parent = 6
+func TestDiscordRelay(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Comsys = NewComsys()
+	DispatchCommand(g, env.player, "@ccreate Public")
+	DispatchCommand(g, env.player, "addcom pub=Public")
+
+	posts := make(chan string, 4)
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/hook":
+			var body struct{ Content string }
+			json.NewDecoder(r.Body).Decode(&body)
+			posts <- "hook " + body.Content
+		case r.Method == http.MethodPost:
+			var body struct{ Content string }
+			json.NewDecoder(r.Body).Decode(&body)
+			posts <- r.Header.Get("Authorization") + " " + r.URL.Path + " " + body.Content
+		case r.URL.Path == "/channels/42/messages":
+			polls++
+			if polls == 1 {
+				fmt.Fprint(w, `[{"id":"100","content":"old news","author":{"username":"carol"}}]`)
+				return
+			}
+			if r.URL.Query().Get("after") != "100" {
+				t.Errorf("poll after = %q, want 100", r.URL.Query().Get("after"))
+			}
+			fmt.Fprint(w, `[{"id":"102","content":"echo","author":{"username":"bot","bot":true}},`+
+				`{"id":"101","content":"hello there","author":{"username":"carol","global_name":"Carol"}}]`)
+		}
+	}))
+	defer srv.Close()
+
+	g.Conf.DiscordToken = "sekrit"
+	g.Conf.DiscordChannels = []string{"Public=42"}
+	g.Conf.DiscordInbound = []string{"Public"}
+	g.Conf.DiscordAlerts = srv.URL + "/hook"
+	r := NewDiscordRelay(g)
+	if r == nil {
+		t.Fatal("NewDiscordRelay returned nil")
+	}
+	r.api = srv.URL
+	g.Discord = r
+	go r.work()
+	defer r.Stop()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-posts:
+			if got != want {
+				t.Fatalf("Discord got %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	// Outbound: channel speech goes to the bot channel without the header.
+	g.SendToChannel("Public", env.player.Player, "[Public] Wizard says, \"hi\"")
+	expect(`Bot sekrit /channels/42/messages **[Public]** Wizard says, "hi"`)
+
+	// Wizard alerts go to the admin webhook.
+	DispatchCommand(g, env.player, "@wall rebooting soon")
+	expect(`hook :warning: @wall from Wizard: rebooting soon`)
+
+	// Inbound: the first poll only marks the spot; the next injects new
+	// messages from people, skipping bots.
 	clearOutput(env.player)
+	for i := 0; i < 2; i++ {
+		if err := r.pollOnce(); err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+	}
+	out := getOutput(env.player)
+	if !strings.Contains(out, `[Public] Carol@Discord says, "hello there"`) {
+		t.Errorf("inbound not delivered: %q", out)
+	}
+	if strings.Contains(out, "old news") || strings.Contains(out, "echo") {
+		t.Errorf("history or bot message relayed: %q", out)
+	}
+	select {
+	case got := <-posts:
+		t.Errorf("inbound message echoed back to Discord: %q", got)
+	default:
+	}
+}
 
-	// Room Zero has no DESC itself, should inherit from parent
-	text := env.game.GetAttrText(0, 6)
-	if text != "Inherited description." {
-		t.Errorf("parent chain: expected 'Inherited description.', got: %s", text)
+func TestPercentSubstitutions(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	me := env.player.Player
+	DispatchCommand(g, env.player, "@sex me=female")
+	DispatchCommand(g, env.player, "&MOOD me=cheerful")
+
+	for expr, want := range map[string]string{
+		"%s %o %p %a":                 "she her her hers",
+		"%S/%O/%P/%A":                 "She/Her/Her/Hers",
+		"%n %N %l":                    "Wizard Wizard #0",
+		"%=<mood> %=<MOOD>":           "cheerful cheerful",
+		"[setq(0,apple)]%q0 %Q0":      "apple Apple",
+		"[setx(fruit,kiwi)]%_<fruit>": "kiwi",
+		"[setx(f,fig)]%_f %_F":        "fig fig",
+		"[iter(a b,%I0)]":             "A B",
+	} {
+		if got := evalExpr(g, me, expr); got != want {
+			t.Errorf("%s = %q, want %q", expr, got, want)
+		}
+	}
+
+	DispatchCommand(g, env.player, "@sex me=plural")
+	if got := evalExpr(g, me, "%S like %p hats"); got != "They like their hats" {
+		t.Errorf("plural pronouns = %q", got)
+	}
+
+	// %c is a color code unless c_is_command is set.
+	if got := evalExpr(g, me, "%ch"); got == "h" || strings.Contains(got, "think") {
+		t.Errorf("%%ch = %q, want an ANSI code", got)
+	}
+	g.Conf.CIsCommand = true
+	ctx := MakeEvalContextWithGame(g, me, nil)
+	ctx.CurrCmd = "think hi"
+	if got := ctx.Exec("%c|%C", eval.EvFCheck|eval.EvEval, nil); got != "think hi|Think hi" {
+		t.Errorf("%%c with c_is_command = %q", got)
 	}
 }
 
-// --- WHO Command ---
+func TestRegexpCommands(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	run := func(cmd string) string {
+		t.Helper()
+		clearOutput(env.player)
+		DispatchCommand(g, env.player, cmd)
+		for g.ProcessQueue() {
+		}
+		return getOutput(env.player)
+	}
 
-func TestWho(t *testing.T) {
+	run(`&ROLL #2=$^roll (\d+)d(\d+)$:@pemit %#=Rolling %1 dice of %2 sides (%0).`)
+	run("@set #2/ROLL=regexp")
+	if out := run("ROLL 3d6"); !strings.Contains(out, "Rolling 3 dice of 6 sides (ROLL 3d6).") {
+		t.Errorf("regexp $-command output = %q", out)
+	}
+	if out := run("roll xd6"); strings.Contains(out, "Rolling") {
+		t.Errorf("non-matching input fired: %q", out)
+	}
+
+	// AF_CASE makes the match case-sensitive.
+	run("@set #2/ROLL=case")
+	if out := run("ROLL 3d6"); strings.Contains(out, "Rolling") {
+		t.Errorf("case-sensitive pattern matched wrong case: %q", out)
+	}
+	if out := run("roll 2d4"); !strings.Contains(out, "Rolling 2 dice of 4 sides") {
+		t.Errorf("case-sensitive pattern didn't match: %q", out)
+	}
+
+	// Without REGEXP the same text is a plain wildcard pattern.
+	run("&GLOB #2=$greet *:@pemit %#=Hello, %0!")
+	if out := run("greet world"); !strings.Contains(out, "Hello, world!") {
+		t.Errorf("wildcard $-command output = %q", out)
+	}
+
+	// ^-listens honor REGEXP too.
+	run("@set #2=MONITOR")
+	run(`&HEAR #2=^(\w+) says "(.*)":@pemit #1=Heard %1 say %2.`)
+	run("@set #2/HEAR=regexp")
+	if out := run("say spam and eggs"); !strings.Contains(out, "Heard Wizard say spam and eggs.") {
+		t.Errorf("regexp ^-listen output = %q", out)
+	}
+
+	if matched, _ := matchRegexp("([", "x", false); matched {
+		t.Error("invalid regexp matched")
+	}
+}
+
+func TestDollarCommandCache(t *testing.T) {
 	env := newTestEnv(t)
-	clearOutput(env.player)
+	g := env.game
+	run := func(cmd string) string {
+		t.Helper()
+		clearOutput(env.player)
+		DispatchCommand(g, env.player, cmd)
+		for g.ProcessQueue() {
+		}
+		return getOutput(env.player)
+	}
 
-	DispatchCommand(env.game, env.player, "WHO")
-	out := getOutput(env.player)
-	if !strings.Contains(out, "Wizard") {
-		t.Errorf("WHO: expected 'Wizard' in output, got: %s", out)
+	run("&CMD #2=$knock:@pemit %#=Knock knock.")
+	if out := run("knock"); !strings.Contains(out, "Knock knock.") {
+		t.Fatalf("$-command output = %q", out)
+	}
+	run("&CMD #2=$knock:@pemit %#=Who's there?")
+	if out := run("knock"); !strings.Contains(out, "Who's there?") || strings.Contains(out, "Knock knock.") {
+		t.Errorf("after the attribute changed, output = %q", out)
+	}
+	run("@set #2/CMD=noprog")
+	if out := run("knock"); strings.Contains(out, "Who's there?") {
+		t.Errorf("NOPROG attribute still matched: %q", out)
+	}
+
+	// Attributes replaced without going through SetAttr are seen too.
+	g.DB.Objects[2].SetAttributes([]gamedb.Attribute{{Number: gamedb.A_USER_START + 50, Value: "$ring:@pemit %#=Ding."}})
+	if out := run("ring"); !strings.Contains(out, "Ding.") {
+		t.Errorf("replaced attributes: output = %q", out)
+	}
+}
+
+func TestEndCommands(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	run := func(cmd string) string {
+		t.Helper()
+		clearOutput(env.player)
+		DispatchCommand(g, env.player, cmd)
+		for g.ProcessQueue() {
+		}
+		return getOutput(env.player)
+	}
+
+	run("&TB #2=$+test-b *:@end/break %0=@pemit %#=True; @pemit %#=False")
+	run("&TA #2=$+test-a *:@assert %0=@pemit %#=False; @pemit %#=True")
+	run("&TC #2=$+test-c *:@pemit %#=Before; @break %0; @pemit %#=After")
+	run("&TD #2=$+test-d *:@break %0={@pemit %#=One; @pemit %#=Two}; @pemit %#=Three")
+	for _, tc := range []struct{ cmd, want, not string }{
+		{"+test-b 0", "False", "True"},
+		{"+test-b 1", "True", "False"},
+		{"+test-a 0", "False", "True"},
+		{"+test-a 1", "True", "False"},
+		{"+test-c 0", "After", "\x00"},
+		{"+test-c 1", "Before", "After"},
+		{"+test-d 1", "One\r\nTwo", "Three"},
+		{"+test-d 0", "Three", "One"},
+	} {
+		if out := run(tc.cmd); !strings.Contains(out, tc.want) || strings.Contains(out, tc.not) {
+			t.Errorf("%s: output = %q, want %q and not %q", tc.cmd, out, tc.want, tc.not)
+		}
+	}
+
+	// An @break inside @dolist ends only that iteration's list.
+	if out := run("@dolist a b={@break strmatch(##,a); @pemit me=##}"); strings.Contains(out, "a") || !strings.Contains(out, "b") {
+		t.Errorf("@break in @dolist: output = %q", out)
+	}
+
+	// A queued condition is evaluated once, with or without an action
+	// list, so text substituted into it isn't run as code.
+	for _, cmd := range []string{"+test-c [pemit(%#,Injected)]0", "+test-d [pemit(%#,Injected)]0"} {
+		if out := run(cmd); strings.Contains(out, "Injected") || strings.Contains(out, "After") || strings.Contains(out, "Three") {
+			t.Errorf("%s: condition evaluated twice, output = %q", cmd, out)
+		}
+	}
+}
+
+func TestCommandPanic(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Commands["@boom"] = &Command{Name: "@boom", Handler: func(*Game, *Descriptor, string, []string) { panic("boom") }}
+
+	clearOutput(env.player)
+	DispatchCommand(g, env.player, "@boom")
+	out := getOutput(env.player)
+	if !strings.Contains(out, panicMsg) || !strings.Contains(out, "Server error running a command for Wizard(#1)") {
+		t.Errorf("panic in a typed command: output = %q", out)
+	}
+	if g.DB.Objects[1].HasFlag(gamedb.FlagHalt) {
+		t.Error("a player was set HALT")
+	}
+
+	g.Queue.Add(&QueueEntry{Player: 2, Cause: 1, Caller: 1, Command: "@boom"})
+	g.Queue.Add(&QueueEntry{Player: 2, Cause: 1, Caller: 1, Command: "think later"})
+	clearOutput(env.player)
+	for g.ProcessQueue() {
+	}
+	out = getOutput(env.player)
+	if !strings.Contains(out, panicMsg) || !strings.Contains(out, "TestObject(#2)") {
+		t.Errorf("panic in a queued command: output = %q", out)
+	}
+	if !g.DB.Objects[2].HasFlag(gamedb.FlagHalt) || strings.Contains(out, "later") {
+		t.Errorf("the object whose command panicked wasn't halted: output = %q", out)
+	}
+
+	clearOutput(env.player)
+	DispatchCommand(g, env.player, "think still here")
+	if out := getOutput(env.player); !strings.Contains(out, "still here") {
+		t.Errorf("after the panics: output = %q", out)
+	}
+}
+
+func TestTimewarp(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	g.Clock = func() time.Time { return now }
+	env.player.LastCmd = now
+	run := func(cmd string) string {
+		t.Helper()
+		clearOutput(env.player)
+		if cmd != "" {
+			DispatchCommand(g, env.player, cmd)
+		}
+		for g.ProcessQueue() {
+		}
+		return getOutput(env.player)
+	}
+
+	run("@wait 60=think fired")
+	now = now.Add(59 * time.Second)
+	if out := run(""); strings.Contains(out, "fired") {
+		t.Fatalf("@wait 60 ran after 59 seconds: %q", out)
+	}
+	now = now.Add(time.Second)
+	if out := run(""); !strings.Contains(out, "fired") {
+		t.Fatalf("@wait 60 didn't run after 60 seconds: %q", out)
+	}
+
+	run("@wait 3600=think warped")
+	if out := run("@timewarp 3600"); !strings.Contains(out, "warped") {
+		t.Errorf("@timewarp 3600 didn't run an hour's wait: %q", out)
+	}
+	if out := run("think secs()"); out != fmt.Sprint(now.Add(time.Hour).Unix()) {
+		t.Errorf("secs() after @timewarp = %q, want %d", out, now.Add(time.Hour).Unix())
+	}
+	if idle := g.IdleTime(1); idle < 3600 {
+		t.Errorf("idle time after @timewarp = %v", idle)
+	}
+	run("@timewarp/reset")
+	if !g.Now().Equal(now) {
+		t.Errorf("after /reset, Now() = %v, want %v", g.Now(), now)
+	}
+	if out := run("@timewarp 10"); strings.Contains(out, "Permission") {
+		t.Errorf("wizard was refused: %q", out)
+	}
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, bob, "@timewarp 10")
+	if out := getOutput(bob); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("mortal @timewarp: %q", out)
+	}
+}
+
+func TestSession(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	g.Clock = func() time.Time { return now }
+	s := g.OpenSession(1)
+	defer s.Close()
+
+	s.Run("&CMD #2=$hi:@pemit %#=Hello, %n.")
+	if out, settled := s.Run("hi"); !settled || len(out) != 1 || out[0] != "Hello, Wizard." {
+		t.Errorf("queued output = %q, settled = %v", out, settled)
+	}
+	if got := s.Eval("add(1,2)"); got != "3" {
+		t.Errorf("Eval(add(1,2)) = %q", got)
+	}
+
+	// Bob has no other connection for queued output to go to.
+	bob := g.OpenSession(3)
+	defer bob.Close()
+	bob.Run("@wait 10=think later")
+	now = now.Add(10 * time.Second)
+	if !g.DrainQueue() {
+		t.Fatal("DrainQueue didn't settle")
+	}
+	if out := bob.Output(); len(out) != 1 || out[0] != "later" {
+		t.Errorf("output after the wait = %q", out)
+	}
+	s.Run("&LOOP #2=@trigger me/LOOP")
+	if _, settled := s.Run("@trigger #2/LOOP"); settled {
+		t.Error("a self-triggering loop settled")
+	}
+}
+
+func TestAliasesCommand(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	g.Store = store
+	g.ApplyAliasConfig(&AliasConfig{
+		CommandAliases: map[string]string{"lk": "look"},
+		FuncAliases:    map[string]string{"plus": "add"},
+	})
+	run := func(cmd string) string {
+		t.Helper()
+		clearOutput(env.player)
+		DispatchCommand(g, env.player, cmd)
+		return getOutput(env.player)
+	}
+
+	// Config function aliases reach the evaluator.
+	if got := evalExpr(g, env.player.Player, "plus(2,3)"); got != "5" {
+		t.Errorf("config alias plus() = %q", got)
+	}
+
+	if out := run("@aliases/function summa=add"); !strings.Contains(out, "Function alias summa -> add added.") {
+		t.Errorf("@aliases/function output = %q", out)
+	}
+	if got := evalExpr(g, env.player.Player, "summa(1,2,3)"); got != "6" {
+		t.Errorf("summa() = %q", got)
+	}
+	if out := run("@aliases/function nope=nosuchfn"); !strings.Contains(out, "no such function") {
+		t.Errorf("bad function target output = %q", out)
+	}
+	if out := run("@aliases/command th=think"); !strings.Contains(out, "Command alias th -> think added.") {
+		t.Errorf("@aliases/command output = %q", out)
+	}
+	if out := run("th hello"); !strings.Contains(out, "hello") {
+		t.Errorf("th alias output = %q", out)
+	}
+	if out := run("@aliases/command look=think"); !strings.Contains(out, "already a command") {
+		t.Errorf("shadowing a command: %q", out)
+	}
+	run("@aliases/badname jerk*")
+	if !g.IsBadName("Jerkface") {
+		t.Error("bad name not applied")
+	}
+
+	out := run("@aliases")
+	for _, want := range []string{"lk", "config", "summa", "@aliases by Wizard", "jerk*"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@aliases list missing %q:\n%s", want, out)
+		}
+	}
+	if out := run("@aliases/remove/command lk"); !strings.Contains(out, "alias config") {
+		t.Errorf("removing a config alias: %q", out)
+	}
+
+	// Stored aliases come back on a fresh game that loads them.
+	stored, err := store.LoadConfAliases()
+	if err != nil || len(stored) != 3 {
+		t.Fatalf("stored aliases = %+v (%v)", stored, err)
+	}
+	env2 := newTestEnv(t)
+	env2.game.LoadConfAliases(stored)
+	if got := evalExpr(env2.game, env2.player.Player, "summa(4,4)"); got != "8" {
+		t.Errorf("reloaded summa() = %q", got)
+	}
+
+	run("@aliases/remove/function summa")
+	if got := evalExpr(g, env.player.Player, "summa(1,2)"); strings.Contains(got, "3") {
+		t.Errorf("summa() still works after removal: %q", got)
+	}
+	if stored, _ := store.LoadConfAliases(); len(stored) != 2 {
+		t.Errorf("stored aliases after remove = %+v", stored)
+	}
+}
+
+func TestSideEffectFunctions(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+
+	ref := evalExpr(g, 1, "[create(Widget,10)]")
+	var n int
+	if _, err := fmt.Sscanf(ref, "#%d", &n); err != nil {
+		t.Fatalf("create() = %q", ref)
+	}
+	if obj := g.DB.Objects[gamedb.DBRef(n)]; obj == nil || obj.Name != "Widget" || obj.Location != 1 {
+		t.Errorf("create(): object #%d not made in inventory", n)
+	}
+
+	room := evalExpr(g, 1, "[dig(Cave,Cave Door,Out)]")
+	if room != fmt.Sprintf("#%d", g.NextRef-3) {
+		t.Errorf("dig() = %q, NextRef %d", room, g.NextRef)
+	}
+	if exit := g.DB.Objects[g.DB.Objects[0].Exits]; exit == nil || exit.Name != "Cave Door" {
+		t.Errorf("dig(): exit to the room not opened")
+	}
+
+	evalExpr(g, 1, "[tel(#2,#4)][set(#2,Color:blue)]")
+	if loc := g.DB.Objects[2].Location; loc != 4 {
+		t.Errorf("tel(): #2 in #%d", loc)
+	}
+	if got := evalExpr(g, 1, "[get(#2/Color)]"); got != "blue" {
+		t.Errorf("set(): Color = %q", got)
+	}
+
+	// Bob controls none of it, so the commands' own checks stop him.
+	evalExpr(g, 3, "[tel(#2,#0)][wipe(#2)][link(#2,#0)]")
+	if loc := g.DB.Objects[2].Location; loc != 4 {
+		t.Errorf("Bob tel()'d #2 to #%d", loc)
+	}
+	if got := evalExpr(g, 1, "[get(#2/Color)]"); got != "blue" {
+		t.Errorf("Bob wiped #2: Color = %q", got)
+	}
+	if got := evalExpr(g, 3, "[open(Sneak)]"); got != "#-1 PERMISSION DENIED" {
+		t.Errorf("Bob open() in #0 = %q", got)
+	}
+
+	g.Conf.SideEffectFunctions = false
+	if got := evalExpr(g, 1, "[create(Gadget,10)]"); got != "#-1 FUNCTION DISABLED" {
+		t.Errorf("disabled create() = %q", got)
+	}
+}
+
+func TestSQLFunctions(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	store, err := OpenSQLStore(filepath.Join(t.TempDir(), "game.sqlite3"), 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	g.SQLDB = store
+	// Attribute text is evaluated with {} grouping stripped.
+	eval3 := func(expr string) string {
+		ctx := MakeEvalContextWithGame(g, 3, functions.RegisterAll)
+		return ctx.Exec(expr, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
+	}
+
+	if got := eval3("[sql(SELECT 1)]"); got != "#-1 PERMISSION DENIED" {
+		t.Errorf("sql() without use_sql = %q", got)
+	}
+	g.DB.Objects[3].Powers[1] |= gamedb.Pow2UseSQL
+	eval3("[sql({CREATE TABLE t (a, b)})]")
+	if got := eval3("[sql({INSERT INTO t VALUES (1, 'x'), (2, 'y'), (3, 'z')})]"); got != "3" {
+		t.Errorf("sql() insert = %q", got)
+	}
+	// Rows stop at sql_query_limit.
+	if got := eval3("[sql({SELECT a, b FROM t ORDER BY a},|,:)]"); got != "1:x|2:y" {
+		t.Errorf("sql() select = %q", got)
+	}
+	if got := eval3("[sql({WITH n AS (SELECT 7) SELECT * FROM n})]"); got != "7" {
+		t.Errorf("sql() WITH = %q", got)
+	}
+	if got := eval3("[sqlescape(it's)]"); got != "it''s" {
+		t.Errorf("sqlescape() = %q", got)
+	}
+
+	g.Conf.SQLQueryLimit = 3
+	g.ApplyGameConf(g.Conf)
+	if got := eval3("[sql(SELECT a FROM t)]"); got != "1 2 3" {
+		t.Errorf("sql() after raising the limit = %q", got)
+	}
+}
+
+func TestHTTPRequests(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.HTTPMaxBody = 8
+	g.Conf.HTTPRateLimit = 3
+	heldLock := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The game goes on while a function waits for the response.
+		if g.world.TryLock() {
+			g.world.Unlock()
+		} else {
+			heldLock = true
+		}
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		case "/redirect":
+			_, port, _ := net.SplitHostPort(r.Host)
+			http.Redirect(w, r, "http://127.0.0.2:"+port+"/", http.StatusFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s:%s", r.Method, body)
+	}))
+	defer srv.Close()
+	// Functions run with the world lock held, as from a command.
+	lockedEval := func(player gamedb.DBRef, expr string) (got string) {
+		g.Do(func() { got = evalExpr(g, player, expr) })
+		return got
+	}
+
+	if got := lockedEval(1, "[httpget("+srv.URL+")]"); got != "#-1 HTTP DISABLED" {
+		t.Errorf("httpget() while disabled = %q", got)
+	}
+	g.Conf.HTTPEnabled = true
+	if got := lockedEval(3, "[httpget("+srv.URL+")]"); got != "#-1 PERMISSION DENIED" {
+		t.Errorf("httpget() by a mortal = %q", got)
+	}
+	// Loopback and private addresses are off limits unless http_allow
+	// lists them, redirects included.
+	g.Conf.HTTPRateLimit = 0
+	if got := lockedEval(1, "[httpget("+srv.URL+")]"); got != "#-1 PRIVATE ADDRESS" {
+		t.Errorf("httpget() of loopback = %q", got)
+	}
+	g.Conf.HTTPAllow = []string{"127.0.0.1"}
+	if got := lockedEval(1, "[httpget("+srv.URL+"/redirect)]"); got != "#-1 PRIVATE ADDRESS" {
+		t.Errorf("httpget() redirected to another loopback address = %q", got)
+	}
+	for _, c := range []struct {
+		addr  string
+		allow []string
+		ok    bool
+	}{
+		{"169.254.169.254", nil, false},
+		{"10.1.2.3", nil, false},
+		{"192.168.0.9", []string{"192.168.0.0/24"}, true},
+		{"::1", nil, false},
+		{"::ffff:127.0.0.1", []string{"127.0.0.0/8"}, true},
+		{"93.184.216.34", nil, true},
+	} {
+		if ok := httpAllowed(netip.MustParseAddr(c.addr), c.allow); ok != c.ok {
+			t.Errorf("httpAllowed(%s, %v) = %v", c.addr, c.allow, ok)
+		}
+	}
+	g.Conf.HTTPRateLimit = 3
+	if got := lockedEval(1, "[httpget("+srv.URL+")]"); got != "GET:" {
+		t.Errorf("httpget() = %q", got)
+	}
+	// The body is cut at http_max_body.
+	if got := lockedEval(1, "[httppost("+srv.URL+",abcdefgh)]"); got != "POST:abc" {
+		t.Errorf("httppost() = %q", got)
+	}
+	if got := lockedEval(1, "[httpget("+srv.URL+"/missing)]"); got != "#-1 HTTP 404" {
+		t.Errorf("httpget() of a missing page = %q", got)
+	}
+	if got := lockedEval(1, "[httpget("+srv.URL+")]"); got != "#-1 RATE LIMITED" {
+		t.Errorf("fourth httpget() in a minute = %q", got)
+	}
+	if heldLock {
+		t.Error("httpget() held the world lock while waiting for the response")
+	}
+
+	g.Conf.HTTPRateLimit = 0
+	g.SetAttrByName(2, "GOT", "")
+	g.SetAttrByName(2, "DONE", "&GOT me=%1 %0")
+	DispatchCommand(g, env.player, "@http/post #2/DONE="+srv.URL+",hi")
+	// The response is queued from another goroutine, under the world lock.
+	got := ""
+	for deadline := time.Now().Add(5 * time.Second); got == "" && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		g.Do(func() {
+			for g.ProcessQueue() {
+			}
+			got = g.GetAttrTextByName(2, "GOT")
+		})
+	}
+	if got != "200 POST:hi" {
+		t.Errorf("@http triggered DONE with %q", got)
+	}
+}
+
+func TestStructures(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.InstanceLimit = 2
+
+	if got := evalExpr(g, 2, "[structure(pt,x y,i i,1|2,|,-)][construct(p1,pt,y,5)][unload(p1)]"); got != "111-5" {
+		t.Errorf("structure/construct/unload = %q", got)
+	}
+	if got := evalExpr(g, 2, "[load(p2,pt,3-4)][z(p2,x)][load(p3,pt,5-6)]"); got != "130" {
+		t.Errorf("load past instance_limit = %q", got)
+	}
+	if got := evalExpr(g, 2, "[lstructures()]/[linstances()]"); got != "pt/p1 p2" {
+		t.Errorf("listings = %q", got)
+	}
+	if got := evalExpr(g, 2, "[write(me/coords,nope)]"); got != "#-1 NO SUCH INSTANCE" {
+		t.Errorf("write() of a missing instance = %q", got)
+	}
+	// Bob has his own namespace.
+	if got := evalExpr(g, 3, "[z(p1,y)][lstructures()]"); got != "" {
+		t.Errorf("another object's structures = %q", got)
+	}
+
+	// Destroying the object drops its structures.
+	DispatchCommand(g, env.player, "@destroy #2")
+	if !g.DB.Objects[2].IsGoing() {
+		t.Fatal("#2 wasn't destroyed")
+	}
+	if got := evalExpr(g, 2, "[lstructures()][linstances()]"); got != "" {
+		t.Errorf("structures after @destroy = %q", got)
+	}
+}
+
+func TestUFunPrivileges(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+
+	// Bob can't borrow the wizard's identity with objeval().
+	if got := evalExpr(g, 3, "[objeval(#1,num(me))]"); got != "#3" {
+		t.Errorf("objeval() by a mortal = %q", got)
+	}
+	if got := evalExpr(g, 1, "[objeval(#2,num(me))]"); got != "#2" {
+		t.Errorf("objeval() by the owner = %q", got)
+	}
+
+	if got := evalExpr(g, 2, "[u(lit(#lambda/[add(%0,%1)]),2,3)]"); got != "5" {
+		t.Errorf("u(#lambda) = %q", got)
+	}
+	g.SetAttrByName(3, "FN", "[num(me)]")
+	if got := evalExpr(g, 1, "[ulambda(#3/fn)]"); got != "#1" {
+		t.Errorf("ulambda() = %q", got)
+	}
+
+	// An attribute Bob set on the wizard's object runs as Bob.
+	va := g.ResolveAttrNum("VA")
+	g.SetAttrRaw(2, va, "[num(me)]", 3, 0)
+	if got := evalExpr(g, 1, "[u(#2/va)]"); got != "#3" {
+		t.Errorf("u() of another's attribute on a wizard-owned object = %q", got)
+	}
+	g.SetAttrRaw(2, va, "[num(me)]", 1, 0)
+	if got := evalExpr(g, 1, "[u(#2/va)]"); got != "#2" {
+		t.Errorf("u() of the owner's attribute = %q", got)
+	}
+}
+
+func TestTraceFlag(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.DB.Objects[2].Flags[0] |= gamedb.FlagTrace
+
+	if got := evalExpr(g, 2, "[add(1,mul(2,3))]"); got != "7" {
+		t.Fatalf("traced result = %q", got)
+	}
+	want := "TestObject(#2)} '[add(1,mul(2,3))]' -> '7'\r\n" +
+		"TestObject(#2)} 'add(1,mul(2,3))' -> '7'\r\n" +
+		"TestObject(#2)} 'mul(2,3)' -> '6'"
+	if got := getOutput(env.player); got != want {
+		t.Errorf("top-down trace:\n%s", got)
+	}
+
+	g.Conf.TraceTopdown = false
+	g.Conf.TraceOutputLimit = 1
+	evalExpr(g, 2, "[add(1,mul(2,3))]")
+	want = "TestObject(#2)} 'mul(2,3)' -> '6'\r\n2 lines of trace output discarded."
+	if got := getOutput(env.player); got != want {
+		t.Errorf("limited bottom-up trace:\n%s", got)
+	}
+
+	// Untraced objects say nothing.
+	evalExpr(g, 3, "[add(1,2)]")
+	if got := getOutput(env.player); got != "" {
+		t.Errorf("untraced output = %q", got)
+	}
+}
+
+func TestProgram(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	g.SetAttrByName(2, "PROG", "@pemit %#=got %0/[r(x)]")
+
+	g.ExecuteQueueEntry(&QueueEntry{Player: 1, Cause: 1, Caller: 1,
+		Command: "think [setq(x,hi)];@program #3=#2/prog:Name?"})
+	if got := getOutput(bob); got != "Name?\r\n> \xff\xf9" {
+		t.Errorf("prompt = %q", got)
+	}
+	getOutput(env.player)
+	DispatchCommand(g, env.player, "@program #3=#2/prog")
+	if got := getOutput(env.player); got != "Input already pending." {
+		t.Errorf("second @program = %q", got)
+	}
+
+	// The program's command sees the q-registers @program was run with.
+	g.HandleProgInput(bob, "Fred")
+	if got := getOutput(bob); got != "got Fred/hi" {
+		t.Errorf("program input = %q", got)
+	}
+	if bob.ProgData != nil || g.GetAttrTextDirect(3, gamedb.A_PROGCMD) != "" {
+		t.Error("program not cleared after input")
+	}
+
+	DispatchCommand(g, env.player, "@program #3=#2/prog")
+	getOutput(bob)
+	DispatchCommand(g, env.player, "@quitprogram #3")
+	if got := getOutput(env.player); got != "Program for Bob terminated." {
+		t.Errorf("@quitprogram by the wizard = %q", got)
+	}
+	if got := getOutput(bob); got != "Program terminated." || bob.ProgData != nil {
+		t.Errorf("@quitprogram for Bob = %q", got)
+	}
+}
+
+func TestPuppet(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.DB.Objects[2].Flags[0] |= gamedb.FlagPuppet
+
+	// The owner is in the same room and hears it anyway.
+	DispatchCommand(g, env.player, "@pemit #2=Psst")
+	if got := getOutput(env.player); strings.Contains(got, "TestObject>") {
+		t.Errorf("relayed to an owner in the same room: %q", got)
+	}
+	g.Conf.PuppetSameRoom = true
+	DispatchCommand(g, env.player, "@pemit #2=Psst")
+	if got := getOutput(env.player); !strings.Contains(got, "TestObject> Psst") {
+		t.Errorf("puppet_same_room relay = %q", got)
+	}
+
+	// What the puppet itself does is always relayed.
+	g.Conf.PuppetSameRoom = false
+	DispatchCommand(g, g.MakeObjDescriptor(2), "look")
+	if got := getOutput(env.player); !strings.HasPrefix(got, "TestObject> Room Zero") {
+		t.Errorf("puppet's look = %q", got)
+	}
+}
+
+func TestAudibleExits(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	for _, cmd := range []string{"@tel #3=#4", "@open Out=#4", "@set here=AUDIBLE", "@set Out=AUDIBLE"} {
+		DispatchCommand(g, env.player, cmd)
+	}
+	getOutput(bob)
+
+	DispatchCommand(g, env.player, "say hi")
+	if got := getOutput(bob); got != "From a distance, Wizard says \"hi\"" {
+		t.Errorf("audible exit relay = %q", got)
+	}
+	DispatchCommand(g, env.player, "@prefix Out=Nearby,")
+	DispatchCommand(g, env.player, "@filter Out=*secret*,*private*")
+	DispatchCommand(g, env.player, "say a secret")
+	if got := getOutput(bob); got != "" {
+		t.Errorf("filtered message relayed: %q", got)
+	}
+	DispatchCommand(g, env.player, "say hello")
+	if got := getOutput(bob); got != "Nearby, Wizard says \"hello\"" {
+		t.Errorf("prefixed relay = %q", got)
+	}
+}
+
+func TestParentRooms(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+
+	// Other Room (#4) is the ZONE parent room of Room Zero.
+	g.DB.Objects[4].Flags[1] |= gamedb.Flag2ZoneParent
+	g.DB.Objects[0].Parent = 4
+	far := g.CreateObject("Far", gamedb.TypeRoom, 1)
+	g.CreateExit("Jump", 4, far, 1)
+	cmds := g.CreateObject("Commands", gamedb.TypeThing, 1)
+	g.Teleport(cmds, 4)
+	g.SetAttrByName(cmds, "CMD", "$xyzzy:@pemit %#=Nothing happens.")
+
+	DispatchCommand(g, env.player, "xyzzy")
+	for g.ProcessQueue() {
+	}
+	if got := getOutput(env.player); got != "Nothing happens." {
+		t.Errorf("$-command in the parent room = %q", got)
+	}
+	DispatchCommand(g, env.player, "jump")
+	if loc := g.PlayerLocation(1); loc != far {
+		t.Errorf("exit in the parent room went to #%d, want #%d", loc, far)
+	}
+
+	// Without local_master_rooms, neither is reachable.
+	g.Conf.LocalMasterRooms = false
+	g.Teleport(1, 0)
+	getOutput(env.player)
+	DispatchCommand(g, env.player, "jump")
+	if loc := g.PlayerLocation(1); loc != 0 {
+		t.Errorf("parent room exit used with local_master_rooms off")
+	}
+}
+
+func TestVariableExit(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	bob := makeTestDescriptor(t, g.Conns, 3)
+
+	DispatchCommand(g, env.player, "@open Lift")
+	lift := g.MatchObject(1, "Lift")
+	getOutput(env.player)
+	DispatchCommand(g, bob, "@link Lift=variable")
+	if got := getOutput(bob); got != "Permission denied." {
+		t.Errorf("mortal @link to variable = %q", got)
+	}
+	DispatchCommand(g, env.player, "@link Lift=variable")
+	if got := getOutput(env.player); !strings.HasSuffix(got, "to *VARIABLE*.") || g.DB.Objects[lift].Location != gamedb.Ambiguous {
+		t.Fatalf("@link to variable = %q", got)
+	}
+
+	DispatchCommand(g, env.player, "&ExitTo Lift=#9999")
+	getOutput(env.player)
+	DispatchCommand(g, env.player, "lift")
+	if got := getOutput(env.player); got != "That exit doesn't lead anywhere." {
+		t.Errorf("bad destination = %q", got)
+	}
+	DispatchCommand(g, env.player, "&ExitTo Lift=#[add(2,2)]")
+	DispatchCommand(g, env.player, "lift")
+	if loc := g.PlayerLocation(1); loc != 4 {
+		t.Errorf("variable exit led to #%d, want #4", loc)
+	}
+}
+
+func TestRoomDropTo(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.DB.Objects[0].Link = 4
+
+	// Things dropped in a room with a drop-to go there at once.
+	g.Teleport(2, 1)
+	DispatchCommand(g, env.player, "drop TestObject")
+	if loc := g.DB.Objects[2].Location; loc != 4 {
+		t.Fatalf("dropped object in #%d, want #4", loc)
+	}
+
+	// In a STICKY room they stay until the last player leaves.
+	g.DB.Objects[0].Flags[0] |= gamedb.FlagSticky
+	g.Teleport(2, 1)
+	DispatchCommand(g, env.player, "drop TestObject")
+	if loc := g.DB.Objects[2].Location; loc != 0 {
+		t.Fatalf("dropped object in STICKY room went to #%d", loc)
+	}
+	g.MovePlayer(env.player, 4)
+	if loc := g.DB.Objects[2].Location; loc != 4 {
+		t.Errorf("STICKY drop-to left object in #%d, want #4", loc)
+	}
+}
+
+func TestStickyThingsAndKill(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+
+	// A STICKY thing goes home when dropped.
+	thing := g.DB.Objects[2]
+	thing.Flags[0] |= gamedb.FlagSticky
+	thing.Link = 4
+	g.Teleport(2, 1)
+	DispatchCommand(g, env.player, "drop TestObject")
+	if thing.Location != 4 {
+		t.Fatalf("STICKY thing dropped into #%d, want its home #4", thing.Location)
+	}
+
+	// A guaranteed kill sends the victim home and pays insurance.
+	bob := g.DB.Objects[3]
+	bob.Link = 4
+	bob.Pennies = 0
+	DispatchCommand(g, env.player, "kill Bob=100")
+	if out := getOutput(env.player); !strings.Contains(out, "You killed Bob!") {
+		t.Errorf("kill output = %q", out)
+	}
+	if bob.Location != 4 || bob.Pennies != 50 {
+		t.Errorf("Bob in #%d with %d pennies, want #4 with 50", bob.Location, bob.Pennies)
+	}
+
+	// Wizards can't be killed.
+	bd := makeTestDescriptor(t, g.Conns, 3)
+	g.Teleport(3, 0)
+	DispatchCommand(g, bd, "kill Wizard=100")
+	if out := getOutput(bd); out != "That wouldn't be wise." {
+		t.Errorf("killing a wizard: %q", out)
+	}
+
+	// @dbck sends stranded objects home.
+	thing.Location = 99
+	DispatchCommand(g, env.player, "@dbck")
+	if thing.Location != 4 {
+		t.Errorf("@dbck left stranded thing in #%d", thing.Location)
+	}
+}
+
+func TestPennyEconomy(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.PageCost = 5
+	g.Conf.MachineCommandCost = 0
+	g.initQueueDeposits()
+	bob := g.DB.Objects[3]
+	bd := makeTestDescriptor(t, g.Conns, 3)
+
+	// @create charges its cost, and @destroy gives it back.
+	DispatchCommand(g, bd, "@create Widget=50")
+	widget := g.DB.Objects[g.NextRef-1]
+	if bob.Pennies != 50 || widget.Pennies != 10 {
+		t.Fatalf("after @create: Bob has %d, Widget worth %d; want 50 and 10", bob.Pennies, widget.Pennies)
+	}
+	DispatchCommand(g, bd, "@destroy Widget")
+	if bob.Pennies != 100 {
+		t.Errorf("@destroy should refund the Widget; Bob has %d", bob.Pennies)
+	}
+
+	DispatchCommand(g, bd, "@dig Hall")
+	DispatchCommand(g, bd, "page Wizard=hi")
+	if bob.Pennies != 85 {
+		t.Errorf("@dig and page should cost 15; Bob has %d", bob.Pennies)
+	}
+	bob.Pennies = 0
+	clearOutput(bd)
+	DispatchCommand(g, bd, "@dig Cellar")
+	if out := getOutput(bd); !strings.Contains(out, "You don't have enough pennies.") {
+		t.Errorf("@dig when broke: %q", out)
+	}
+
+	// Queueing takes wait_cost, refunded when the command is halted.
+	bob.Pennies = 100
+	g.Queue.Add(&QueueEntry{Player: 3, Cause: 3, Caller: 3, Command: "think hi"})
+	if bob.Pennies != 90 {
+		t.Errorf("queueing should cost wait_cost; Bob has %d", bob.Pennies)
+	}
+	g.Queue.HaltPlayer(3)
+	if bob.Pennies != 100 {
+		t.Errorf("@halt should refund wait_cost; Bob has %d", bob.Pennies)
+	}
+
+	// Wizards build for free; only stealers give negative money.
+	DispatchCommand(g, env.player, "@dig Attic")
+	if g.DB.Objects[1].Pennies != 1000 {
+		t.Errorf("wizard paid to @dig; has %d", g.DB.Objects[1].Pennies)
+	}
+	clearOutput(bd)
+	DispatchCommand(g, bd, "give Wizard=-10")
+	if out := getOutput(bd); !strings.Contains(out, "positive number") || bob.Pennies != 100 {
+		t.Errorf("negative give without steal_money: %q, Bob has %d", out, bob.Pennies)
+	}
+	bob.Powers[0] |= gamedb.PowSteal
+	DispatchCommand(g, bd, "give Wizard=-10")
+	if bob.Pennies != 110 || g.DB.Objects[1].Pennies != 990 {
+		t.Errorf("steal_money give: Bob %d, Wizard %d", bob.Pennies, g.DB.Objects[1].Pennies)
+	}
+}
+
+func TestRobots(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.RobotSpeech = false
+	bob := g.DB.Objects[3]
+	bob.Pennies = 1500
+	bd := makeTestDescriptor(t, g.Conns, 3)
+
+	DispatchCommand(g, bd, "@robot Robbie=beep")
+	robot := LookupPlayer(g.DB, "Robbie")
+	if robot == gamedb.Nothing {
+		t.Fatalf("@robot made nothing: %q", getOutput(bd))
+	}
+	ro := g.DB.Objects[robot]
+	if ro.Owner != 3 || !ro.HasFlag(gamedb.FlagRobot) || bob.Pennies != 500 {
+		t.Errorf("robot owner #%d, ROBOT %v, Bob has %d", ro.Owner, ro.HasFlag(gamedb.FlagRobot), bob.Pennies)
+	}
+	if !CheckPassword(g.DB, robot, "beep") {
+		t.Error("robot can't log in with its password")
+	}
+	DispatchCommand(g, bd, "@robot Robbie2=beep")
+	if bob.Pennies != 500 || LookupPlayer(g.DB, "Robbie2") != gamedb.Nothing {
+		t.Errorf("@robot without robot_cost: Bob has %d", bob.Pennies)
+	}
+
+	// With robot_speech off, robots keep quiet in rooms they don't control.
+	g.Teleport(robot, 0)
+	rd := makeTestDescriptor(t, g.Conns, robot)
+	DispatchCommand(g, rd, "say hello")
+	if out := getOutput(rd); !strings.Contains(out, "robots may not speak") {
+		t.Errorf("robot say: %q", out)
+	}
+
+	// Exits set ROBOT keep robots out.
+	exit := g.CreateExit("north", 0, 4, 1)
+	g.DB.Objects[exit].Flags[0] |= gamedb.FlagRobot
+	DispatchCommand(g, rd, "north")
+	if out := getOutput(rd); !strings.Contains(out, "robots aren't allowed") || ro.Location != 0 {
+		t.Errorf("robot through ROBOT exit: %q, in #%d", out, ro.Location)
+	}
+
+	if !outputFix(rd, "OUTPUTPREFIX <<") || rd.OutputPrefix != "<<" || outputFix(rd, "outputsuffix >>") {
+		t.Errorf("OUTPUTPREFIX: prefix %q", rd.OutputPrefix)
+	}
+}
+
+func TestLoginScreenCommands(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	s := &Server{Game: g}
+	d := makeTestDescriptor(t, g.Conns, gamedb.Nothing)
+	d.State = ConnLogin
+
+	s.handleLoginCommand(d, "OUTPUTPREFIX --start--")
+	s.handleLoginCommand(d, "OUTPUTSUFFIX --end--")
+	s.handleLoginCommand(d, "INFO")
+	out := getOutput(d)
+	for _, want := range []string{"--start--", "### Begin INFO 1", "Name: GoTinyMUSH", "### End INFO", "--end--"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("INFO from the login screen lacks %q: %q", want, out)
+		}
+	}
+	if strings.Index(out, "--start--") > strings.Index(out, "### Begin") {
+		t.Errorf("OUTPUTPREFIX came after the output: %q", out)
+	}
+
+	g.Conf.LoginWho = false
+	s.handleLoginCommand(d, "WHO")
+	if out := getOutput(d); strings.Contains(out, "Players logged in") {
+		t.Errorf("WHO worked from the login screen with login_who off: %q", out)
+	}
+}
+
+func TestSessionCommand(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	bob.StartWriter(func() int { return 0 })
+	bob.Send("hello")
+	bob.noteReceived(12)
+
+	DispatchCommand(g, env.player, "SESSION")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Player Name     On For Idle Port     Total  Pend  Lost     Total") {
+		t.Errorf("SESSION header: %q", out)
+	}
+	if !strings.Contains(out, "Bob") || !strings.Contains(out, "        12") {
+		t.Errorf("SESSION lacks Bob's input count: %q", out)
+	}
+}
+
+func TestDescriptorOutputLimit(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	d := NewDescriptor(1, serverConn)
+	d.StartWriter(func() int { return 200 })
+
+	// Nobody is reading yet: Send must not block, and the backlog is
+	// trimmed to the limit.
+	for i := 0; i < 100; i++ {
+		d.Send(fmt.Sprintf("line %03d", i))
+	}
+	if n := d.PendingOutput(); n > 200 {
+		t.Errorf("pending output %d exceeds limit", n)
+	}
+	if d.Traffic().Flushed == 0 {
+		t.Error("expected output to be flushed")
+	}
+
+	d.Close()
+	data, _ := io.ReadAll(clientConn)
+	out := string(data)
+	if !strings.Contains(out, "*** Output Flushed ***") {
+		t.Errorf("missing flush notice in %q", out)
+	}
+	if !strings.HasSuffix(out, "line 099\r\n") {
+		t.Errorf("newest output lost: %q", out)
+	}
+}
+
+func TestMailExportAndMailto(t *testing.T) {
+	env := newTestEnv(t)
+	env.game.Mail = NewMail(0)
+	env.game.Conf = DefaultGameConf()
+	env.game.Conf.SMTPHost = "smtp.example.com"
+	env.game.Conf.SMTPFrom = "mush@example.com"
+
+	sent := make(chan string, 4)
+	orig := smtpSendMail
+	smtpSendMail = func(_ string, _ smtp.Auth, _ string, to []string, msg []byte) error {
+		sent <- to[0] + "\n" + string(msg)
+		return nil
+	}
+	t.Cleanup(func() { smtpSendMail = orig })
+
+	env.game.sendMail(3, []gamedb.DBRef{1}, nil, "Hello", "From the start\nline two")
+	clearOutput(env.player)
+
+	data, _, err := exportMail(env.game, 1, -1, "mbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mbox := string(data)
+	if !strings.HasPrefix(mbox, "From 3@gotinymush ") || !strings.Contains(mbox, "Subject: Hello\n") {
+		t.Errorf("mbox headers: %q", mbox)
+	}
+	if !strings.Contains(mbox, "\n>From the start\n") {
+		t.Errorf("mbox body not From-escaped: %q", mbox)
+	}
+
+	DispatchCommand(env.game, env.player, "@mail/export 0=json")
+	if out := getOutput(env.player); !strings.Contains(out, "GET /api/v1/mail/export?folder=0&format=json") {
+		t.Errorf("@mail/export: got %q", out)
+	}
+
+	// Verification: the code arrives by email, and only then does mail forward.
+	DispatchCommand(env.game, env.player, "@mailto wiz@example.com")
+	msg := <-sent
+	idx := strings.Index(msg, "@mailto/verify ")
+	if !strings.HasPrefix(msg, "wiz@example.com\n") || idx < 0 {
+		t.Fatalf("verification email: %q", msg)
+	}
+	code := msg[idx+len("@mailto/verify ") : idx+len("@mailto/verify ")+8]
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@mailto/verify WRONGCODE")
+	if out := getOutput(env.player); out != "MAIL: That verification code is not correct." {
+		t.Errorf("bad code: got %q", out)
+	}
+	DispatchCommand(env.game, env.player, "@mailto/verify "+code)
+	if out := getOutput(env.player); out != "MAIL: New mail will be forwarded to wiz@example.com." {
+		t.Errorf("verify: got %q", out)
+	}
+
+	env.game.sendMail(3, []gamedb.DBRef{1}, nil, "Again", "Forward me")
+	msg = <-sent
+	if !strings.Contains(msg, "Subject: [GoTinyMUSH] Again") || !strings.Contains(msg, "Forward me") {
+		t.Errorf("forwarded email: %q", msg)
+	}
+
+	// Players can't set MAILTO themselves to dodge verification.
+	bob := makeTestDescriptor(t, env.game.Conns, 3)
+	DispatchCommand(env.game, bob, "&MAILTO me=evil@example.com")
+	if got := env.game.GetAttrTextDirect(3, env.game.mailtoAttrNum()); got != "" {
+		t.Errorf("mortal set MAILTO to %q", got)
+	}
+
+	// Verification emails can't be used to spam someone.
+	clearOutput(env.player)
+	DispatchCommand(env.game, env.player, "@mailto other@example.com")
+	if out := getOutput(env.player); !strings.Contains(out, "Please wait") {
+		t.Errorf("second @mailto within the cooldown: got %q", out)
+	}
+	thing := makeTestDescriptor(t, env.game.Conns, 2)
+	DispatchCommand(env.game, thing, "@mailto victim@example.com")
+	if out := getOutput(thing); out != "MAIL: Only players can forward their mail." {
+		t.Errorf("@mailto by an object: got %q", out)
+	}
+	for i := 0; i < mailtoPendingLimit; i++ {
+		env.game.Mail.RequestMailto(gamedb.DBRef(100+i), &MailtoPending{Expires: time.Now().Add(time.Hour)})
+	}
+	if msg := env.game.Mail.RequestMailto(3, &MailtoPending{Expires: time.Now().Add(time.Hour)}); !strings.Contains(msg, "Too many") {
+		t.Errorf("request over mailtoPendingLimit: got %q", msg)
+	}
+	select {
+	case msg := <-sent:
+		t.Errorf("refused @mailto sent %q", msg)
+	default:
+	}
+}
+
+func TestSiteRulesAndThrottling(t *testing.T) {
+	env := newTestEnv(t)
+	env.game.Sites = NewSiteSecurity()
+	env.game.Conf = DefaultGameConf()
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@site/forbid 10.1.0.0/16=spam bots")
+	if out := getOutput(env.player); out != "Site 10.1.0.0/16 is now forbid." {
+		t.Errorf("@site/forbid: got %q", out)
+	}
+	DispatchCommand(env.game, env.player, "@site/reg 192.168.5.7")
+	if out := getOutput(env.player); out != "Site 192.168.5.7/32 is now register." {
+		t.Errorf("@site/reg: got %q", out)
+	}
+	DispatchCommand(env.game, env.player, "@site/forbid nonsense")
+	if out := getOutput(env.player); out != "That's not an address or CIDR range." {
+		t.Errorf("@site bad pattern: got %q", out)
+	}
+
+	if env.game.SiteRuleFor("10.1.2.3:4000", gamedb.SiteForbid) == nil {
+		t.Error("10.1.2.3 should be forbidden")
+	}
+	if env.game.SiteRuleFor("10.2.0.1:4000", gamedb.SiteForbid) != nil {
+		t.Error("10.2.0.1 should not be forbidden")
+	}
+	if env.game.SiteRuleFor("192.168.5.7:1", gamedb.SiteRegister) == nil {
+		t.Error("192.168.5.7 should be register-only")
+	}
+
+	DispatchCommand(env.game, env.player, "@site")
+	if out := getOutput(env.player); !strings.Contains(out, "spam bots") || !strings.HasSuffix(out, "2 site rule(s).") {
+		t.Errorf("@site list: got %q", out)
+	}
+	DispatchCommand(env.game, env.player, "@site/remove 10.1.0.0/16")
+	if env.game.SiteRuleFor("10.1.2.3:4000", gamedb.SiteForbid) != nil {
+		t.Error("rule not removed")
+	}
+
+	// Failed logins lock the address out until unthrottled.
+	addr := "203.0.113.9:5555"
+	for i := 1; i < env.game.Conf.LoginFailLimit; i++ {
+		if env.game.NoteLoginFailure(addr) {
+			t.Fatalf("locked after %d failures", i)
+		}
+	}
+	if !env.game.NoteLoginFailure(addr) || !env.game.LoginLocked(addr) {
+		t.Error("expected lockout at login_fail_limit")
+	}
+	clearOutput(env.player)
+	DispatchCommand(env.game, env.player, "@site/unthrottle 203.0.113.9")
+	if env.game.LoginLocked(addr) {
+		t.Error("@site/unthrottle did not clear lockout")
+	}
+
+	bob := makeTestDescriptor(t, env.game.Conns, 3)
+	DispatchCommand(env.game, bob, "@site/forbid 1.2.3.4")
+	if out := getOutput(bob); out != "Permission denied." {
+		t.Errorf("mortal @site: got %q", out)
+	}
+}
+
+func TestWebClientAddr(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Guests = NewGuestManager()
+	ws := &WebServer{game: g}
+	req := func(remote string, headers ...string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		for i := 0; i+1 < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		return r
+	}
+
+	// Forwarding headers count only from a trusted proxy.
+	if got := ws.clientAddr(req("203.0.113.5:4000", "X-Forwarded-For", "10.1.2.3")); got != "203.0.113.5:4000" {
+		t.Errorf("spoofed X-Forwarded-For: got %q", got)
+	}
+	g.Conf.WebTrustedProxies = []string{"127.0.0.1", "10.0.0.0/8"}
+	if got := ws.clientAddr(req("127.0.0.1:4000", "X-Forwarded-For", "6.6.6.6, 198.51.100.7, 10.0.0.2")); got != "198.51.100.7" {
+		t.Errorf("X-Forwarded-For through proxies: got %q", got)
+	}
+	if got := ws.clientAddr(req("127.0.0.1:4000", "X-Real-IP", "198.51.100.8")); got != "198.51.100.8" {
+		t.Errorf("X-Real-IP from a proxy: got %q", got)
+	}
+	g.Conf.WebTrustedProxies = nil
+
+	// WebSocket connections are screened like telnet ones.
+	g.Sites = NewSiteSecurity()
+	srv := httptest.NewServer(http.HandlerFunc(ws.handleWebSocket))
+	defer srv.Close()
+	dial := func() WSMessage {
+		t.Helper()
+		hdr := http.Header{"X-Forwarded-For": {"198.51.100.9"}}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		var msg WSMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	DispatchCommand(g, env.player, "@site/forbid 127.0.0.1")
+	if msg := dial(); msg.Type != "error" || !strings.Contains(msg.Text, "not allowed") {
+		t.Errorf("forbidden site: %+v", msg)
+	}
+	DispatchCommand(g, env.player, "@site/remove 127.0.0.1")
+	g.Conf.ConnRateLimit = 1
+	if msg := dial(); msg.Type != "welcome" {
+		t.Errorf("first connection: %+v", msg)
+	}
+	if msg := dial(); msg.Type != "error" || !strings.Contains(msg.Text, "Too many connections") {
+		t.Errorf("over conn_rate_limit: %+v", msg)
+	}
+}
+
+func TestDispatchCommand_Version(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@version")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "GoTinyMUSH") {
+		t.Errorf("@version: expected 'GoTinyMUSH', got: %s", out)
+	}
+}
+
+func TestDispatchCommand_Inventory(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "inventory")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "You are carrying") || !strings.Contains(out, "nothing") {
+		// Wizard starts with no inventory, so should see "nothing"
+		// or "You are carrying:" followed by nothing
+		_ = out // acceptable either way
+	}
+}
+
+func TestDispatchCommand_Score(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "score")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "1000") {
+		t.Errorf("score: expected pennies count '1000', got: %s", out)
+	}
+}
+
+func TestDispatchCommand_Examine(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "examine me")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Wizard") {
+		t.Errorf("examine: expected 'Wizard' in output, got: %s", out)
+	}
+}
+
+// --- Attribute Setter Tests ---
+
+func TestAttrSetter_Success(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@success me=You did it!")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Set.") {
+		t.Errorf("@success: expected 'Set.', got: %s", out)
+	}
+
+	// Verify the attribute was actually set
+	text := env.game.GetAttrText(1, 4) // A_SUCC = 4
+	if text != "You did it!" {
+		t.Errorf("@success: expected attr text 'You did it!', got: %s", text)
+	}
+}
+
+func TestAttrSetter_Fail(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@fail me=Sorry, you can't.")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Set.") {
+		t.Errorf("@fail: expected 'Set.', got: %s", out)
+	}
+	text := env.game.GetAttrText(1, 3) // A_FAIL = 3
+	if text != "Sorry, you can't." {
+		t.Errorf("@fail: expected 'Sorry, you can't.', got: %s", text)
+	}
+}
+
+func TestAttrSetter_Sex(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@sex me=Male")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Set.") {
+		t.Errorf("@sex: expected 'Set.', got: %s", out)
+	}
+	text := env.game.GetAttrText(1, 7) // A_SEX = 7
+	if text != "Male" {
+		t.Errorf("@sex: expected 'Male', got: %s", text)
+	}
+}
+
+func TestAttrSetter_Describe(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@describe me=A powerful wizard.")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Set.") {
+		t.Errorf("@describe: expected 'Set.', got: %s", out)
+	}
+	text := env.game.GetAttrText(1, 6) // A_DESC = 6
+	if text != "A powerful wizard." {
+		t.Errorf("@describe: expected 'A powerful wizard.', got: %s", text)
+	}
+}
+
+func TestAttrSetter_NoEquals(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@success me")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "=") || !strings.Contains(out, "need") {
+		// Should show usage error about needing =
+		_ = out
+	}
+}
+
+func TestAttrSetter_PermDenied(t *testing.T) {
+	env := newTestEnv(t)
+	// Create a non-wizard descriptor for Bob (#3)
+	bobDesc := makeTestDescriptor(t, env.game.Conns, 3)
+	clearOutput(bobDesc)
+
+	// Bob tries to set an attr on Wizard's object - should fail
+	DispatchCommand(env.game, bobDesc, "@success #1=hacked")
+	out := getOutput(bobDesc)
+	if !strings.Contains(out, "Permission denied") {
+		t.Errorf("@success perm: expected 'Permission denied', got: %s", out)
+	}
+}
+
+// --- Player Object Commands ---
+
+func TestGet(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "get TestObject")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "You pick up TestObject") {
+		t.Errorf("get: expected 'You pick up TestObject', got: %s", out)
+	}
+
+	// Verify object moved to inventory
+	obj := env.game.DB.Objects[2]
+	if obj.Location != 1 {
+		t.Errorf("get: TestObject should be in Wizard's inventory, location=%d", obj.Location)
+	}
+}
+
+func TestGetEmpty(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "get")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Get what?") {
+		t.Errorf("get empty: expected 'Get what?', got: %s", out)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	// First pick up the object
+	DispatchCommand(env.game, env.player, "get TestObject")
+	clearOutput(env.player)
+
+	// Now drop it
+	DispatchCommand(env.game, env.player, "drop TestObject")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "You drop TestObject") {
+		t.Errorf("drop: expected 'You drop TestObject', got: %s", out)
+	}
+
+	obj := env.game.DB.Objects[2]
+	if obj.Location != 0 {
+		t.Errorf("drop: TestObject should be back in Room Zero, location=%d", obj.Location)
+	}
+}
+
+func TestDropNotCarrying(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "drop TestObject")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "aren't carrying") {
+		t.Errorf("drop not carrying: expected 'aren't carrying', got: %s", out)
+	}
+}
+
+func TestGivePennies(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "give Bob=50")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "give") && !strings.Contains(out, "50") {
+		t.Errorf("give: expected give confirmation, got: %s", out)
+	}
+
+	// Verify pennies transferred
+	wizard := env.game.DB.Objects[1]
+	bob := env.game.DB.Objects[3]
+	if wizard.Pennies != 950 {
+		t.Errorf("give: wizard should have 950 pennies, has %d", wizard.Pennies)
+	}
+	if bob.Pennies != 150 {
+		t.Errorf("give: bob should have 150 pennies, has %d", bob.Pennies)
+	}
+}
+
+func TestEnter(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "enter Container")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "You enter Container") {
+		t.Errorf("enter: expected 'You enter Container', got: %s", out)
+	}
+
+	// Verify player moved inside container
+	playerObj := env.game.DB.Objects[1]
+	if playerObj.Location != 5 {
+		t.Errorf("enter: player should be in Container (#5), location=%d", playerObj.Location)
+	}
+}
+
+func TestLeave(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	// First enter the container
+	DispatchCommand(env.game, env.player, "enter Container")
+	clearOutput(env.player)
+
+	// Now leave
+	DispatchCommand(env.game, env.player, "leave")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "You leave") {
+		t.Errorf("leave: expected 'You leave', got: %s", out)
+	}
+
+	playerObj := env.game.DB.Objects[1]
+	if playerObj.Location != 0 {
+		t.Errorf("leave: player should be back in Room Zero, location=%d", playerObj.Location)
+	}
+}
+
+func TestWhisper(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "whisper Bob=secret message")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "You whisper") {
+		t.Errorf("whisper: expected 'You whisper', got: %s", out)
+	}
+}
+
+func TestUse(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Set a USE attribute on TestObject
+	env.game.SetAttr(2, 45, "You use the object.") // A_USE = 45
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "use TestObject")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "You use the object.") {
+		t.Errorf("use: expected 'You use the object.', got: %s", out)
+	}
+}
+
+func TestKill(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "kill Bob")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "You killed Bob!") {
+		t.Errorf("kill: expected 'You killed Bob!', got: %s", out)
+	}
+}
+
+// --- Building Commands ---
+
+func TestCreate(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@create Widget")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Widget") && !strings.Contains(out, "created") {
+		// Some format of creation confirmation
+		_ = out
+	}
+}
+
+func TestDig(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@dig New Room")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "New Room") {
+		t.Errorf("@dig: expected room name in output, got: %s", out)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@describe here=A test room.")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Set.") && !strings.Contains(out, "set") {
+		t.Errorf("@describe: expected confirmation, got: %s", out)
+	}
+
+	text := env.game.GetAttrText(0, 6) // A_DESC = 6
+	if text != "A test room." {
+		t.Errorf("@describe: expected 'A test room.', got: %s", text)
+	}
+}
+
+func TestName(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@name #2=RenamedObject")
+	out := getOutput(env.player)
+	_ = out
+
+	obj := env.game.DB.Objects[2]
+	if obj.Name != "RenamedObject" {
+		t.Errorf("@name: expected 'RenamedObject', got: %s", obj.Name)
+	}
+}
+
+func TestSet(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@set #2=DARK")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "set") || !strings.Contains(out, "Set") {
+		// Some flag set confirmation
+		_ = out
+	}
+}
+
+func TestLink(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	// Link Wizard's home to OtherRoom
+	DispatchCommand(env.game, env.player, "@link me=#4")
+	out := getOutput(env.player)
+	_ = out
+
+	playerObj := env.game.DB.Objects[1]
+	if playerObj.Link != 4 {
+		t.Errorf("@link: expected link to #4, got #%d", playerObj.Link)
+	}
+}
+
+func TestParent(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@parent #2=#0")
+	out := getOutput(env.player)
+	_ = out
+
+	obj := env.game.DB.Objects[2]
+	if obj.Parent != 0 {
+		t.Errorf("@parent: expected parent #0, got #%d", obj.Parent)
+	}
+}
+
+// --- Communication Commands ---
+
+func TestEmit(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	// @emit sends to the room - in test env it goes to ConnManager but our
+	// test descriptor is in the room so it should receive via SendToRoom
+	DispatchCommand(env.game, env.player, "@emit Test broadcast message")
+	// Output goes through ConnManager.SendToRoom which iterates room contents
+	// In our test setup, the descriptor IS in the room
+}
+
+func TestEmitWithSoftcode(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "think [add(2,3)]")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "5") {
+		t.Errorf("softcode eval: expected '5', got: %s", out)
+	}
+}
+
+// --- Admin Commands ---
+
+func TestMotd(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	// Set MOTD (wizard only)
+	DispatchCommand(env.game, env.player, "@motd Welcome to GoTinyMUSH!")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "MOTD") || !strings.Contains(out, "set") {
+		_ = out
+	}
+
+	// Check it was stored
+	if env.game.MOTD != "Welcome to GoTinyMUSH!" {
+		t.Errorf("@motd: expected 'Welcome to GoTinyMUSH!', got: %s", env.game.MOTD)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@search type=player")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Wizard") || !strings.Contains(out, "Bob") {
+		t.Errorf("@search: expected Wizard and Bob in results, got: %s", out)
+	}
+}
+
+func TestDecompile(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Set some attributes first
+	env.game.SetAttr(2, 6, "A test object.")  // DESC
+	env.game.SetAttr(2, 4, "You got it!")      // SUCC
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "@decompile #2")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "TestObject") {
+		t.Errorf("@decompile: expected object name, got: %s", out)
+	}
+}
+
+func TestChzone(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	// Zone a THING to another THING (rooms can only be zoned to rooms)
+	DispatchCommand(env.game, env.player, "@chzone #2=#5")
+	out := getOutput(env.player)
+	_ = out
+
+	obj := env.game.DB.Objects[2]
+	if obj.Zone != 5 {
+		t.Errorf("@chzone: expected zone #5, got #%d", obj.Zone)
+	}
+}
+
+// --- Dispatch and Switches ---
+
+func TestSwitchParsing(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	// @dolist with /now switch should work
+	DispatchCommand(env.game, env.player, "@dolist/now a b c=think ##")
+	out := getOutput(env.player)
+	// Should see a, b, c as separate think outputs
+	if !strings.Contains(out, "a") {
+		t.Errorf("@dolist/now: expected output, got: %s", out)
+	}
+}
+
+// --- Help System ---
+
+func TestHelpNoFiles(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	// No help files loaded, should say no help available
+	DispatchCommand(env.game, env.player, "help")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "No help available") && !strings.Contains(out, "no help") {
+		// Some message about no help
+		_ = out
+	}
+}
+
+// --- Multiple Attribute Setters ---
+
+func TestAttrSetterBulk(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Test several attribute-setting commands
+	tests := []struct {
+		cmd     string
+		attrNum int
+		value   string
+	}{
+		{"@success me=win", 4, "win"},
+		{"@osuccess me=wins", 1, "wins"},
+		{"@fail me=nope", 3, "nope"},
+		{"@ofail me=fails", 2, "fails"},
+		{"@sex me=Female", 7, "Female"},
+		{"@alias me=Wiz", 58, "Wiz"},
+		{"@listen me=* says *", 26, "* says *"},
+		{"@idesc me=Inside description", 32, "Inside description"},
+		{"@reject me=Go away!", 72, "Go away!"},
+	}
+
+	for _, tt := range tests {
+		clearOutput(env.player)
+		DispatchCommand(env.game, env.player, tt.cmd)
+		out := getOutput(env.player)
+		if !strings.Contains(out, "Set.") {
+			t.Errorf("%s: expected 'Set.', got: %s", tt.cmd, out)
+		}
+		text := env.game.GetAttrText(1, tt.attrNum)
+		if text != tt.value {
+			t.Errorf("%s: expected attr %d = %q, got %q", tt.cmd, tt.attrNum, tt.value, text)
+		}
+	}
+}
+
+// --- Eval Tests (softcode in commands) ---
+
+func TestSayEvalsSoftcode(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "say [add(1,2)]")
+	out := getOutput(env.player)
+	if !strings.Contains(out, `You say "3"`) {
+		t.Errorf("say softcode: expected 'You say \"3\"', got: %s", out)
+	}
+}
+
+// --- Internal Attr Hiding ---
+
+func TestExamineHidesPassword(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Set a password attribute
+	env.game.SetAttr(1, 5, "secret_password") // A_PASS = 5
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "examine me")
+	out := getOutput(env.player)
+	if strings.Contains(out, "secret_password") {
+		t.Errorf("examine: password should be hidden, but found in output: %s", out)
+	}
+}
+
+// --- Look Command ---
+
+func TestLook(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Set room description
+	env.game.SetAttr(0, 6, "A plain test room.") // A_DESC = 6
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "look")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Room Zero") {
+		t.Errorf("look: expected 'Room Zero' in output, got: %s", out)
+	}
+	if !strings.Contains(out, "A plain test room.") {
+		t.Errorf("look: expected description in output, got: %s", out)
+	}
+}
+
+// --- Parent Chain Inheritance ---
+
+func TestParentChainDescLookup(t *testing.T) {
+	env := newTestEnv(t)
+
+	// Create parent room #6 with a DESC
+	env.game.DB.Objects[6] = &gamedb.Object{
+		DBRef:    6,
+		Name:     "Parent Room",
+		Location: gamedb.Nothing,
+		Contents: gamedb.Nothing,
+		Exits:    gamedb.Nothing,
+		Link:     gamedb.Nothing,
+		Next:     gamedb.Nothing,
+		Owner:    1,
+		Parent:   gamedb.Nothing,
+		Zone:     gamedb.Nothing,
+		Flags:    [3]int{int(gamedb.TypeRoom), 0, 0},
+	}
+	env.game.SetAttr(6, 6, "Inherited description.") // DESC on parent
+
+	// Set Room Zero's parent to #6
+	env.game.DB.Objects[0].Parent = 6
+	clearOutput(env.player)
+
+	// Room Zero has no DESC itself, should inherit from parent
+	text := env.game.GetAttrText(0, 6)
+	if text != "Inherited description." {
+		t.Errorf("parent chain: expected 'Inherited description.', got: %s", text)
+	}
+}
+
+// --- WHO Command ---
+
+func TestWho(t *testing.T) {
+	env := newTestEnv(t)
+	clearOutput(env.player)
+
+	DispatchCommand(env.game, env.player, "WHO")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "Wizard") {
+		t.Errorf("WHO: expected 'Wizard' in output, got: %s", out)
+	}
+}
+
+func TestHiddenPlayers(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	bob := g.DB.Objects[3]
+	bd := makeTestDescriptor(t, g.Conns, 3)
+
+	DispatchCommand(g, bd, "@hide")
+	if out := getOutput(bd); !strings.Contains(out, "Permission denied") || bob.HasFlag(gamedb.FlagDark) {
+		t.Errorf("mortal @hide: %q", out)
+	}
+
+	DispatchCommand(g, env.player, "@hide")
+	DispatchCommand(g, bd, "WHO")
+	if out := getOutput(bd); strings.Contains(out, "Wizard") {
+		t.Errorf("hidden wizard on mortal WHO: %q", out)
+	}
+	DispatchCommand(g, bd, "think [lwho()]")
+	if out := getOutput(bd); strings.Contains(out, "#1") {
+		t.Errorf("hidden wizard in lwho(): %q", out)
+	}
+	bob.Powers[0] |= gamedb.PowSeeHidden
+	DispatchCommand(g, bd, "think [lwho()]")
+	if out := getOutput(bd); !strings.Contains(out, "#1") {
+		t.Errorf("see_hidden lwho(): %q", out)
+	}
+	DispatchCommand(g, env.player, "@unhide")
+	if g.DB.Objects[1].HasFlag(gamedb.FlagDark) {
+		t.Error("@unhide left the wizard DARK")
+	}
+
+	// UNFINDABLE players in another room can't be located.
+	g.Teleport(1, 4)
+	g.DB.Objects[1].Flags[1] |= gamedb.Flag2Unfindable
+	DispatchCommand(g, bd, "think [loc(#1)]")
+	if out := getOutput(bd); !strings.Contains(out, "#-1") {
+		t.Errorf("loc() of unfindable player: %q", out)
+	}
+	bob.Powers[0] |= gamedb.PowFindUnfind
+	DispatchCommand(g, bd, "think [loc(#1)]")
+	if out := getOutput(bd); !strings.Contains(out, "#4") {
+		t.Errorf("find_unfindable loc(): %q", out)
+	}
+}
+
+func TestConnectTriggers(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.MasterRoom = 4
+	g.Teleport(2, 4)
+	g.DB.Objects[0].Zone = 5
+	g.SetAttr(2, 39, "@pemit %#=global %0 %1 %2")
+	g.SetAttr(5, 39, "@pemit %#=zone")
+	g.SetAttr(0, 39, "@pemit %#=room")
+	g.SetAttr(3, 40, "@pemit %#=bye %0 %1")
+	bd := makeTestDescriptor(t, g.Conns, 3)
+	g.countLogin(3)
+	g.countLogin(3)
+
+	g.fireAconnect(bd, "connect")
+	for g.ProcessQueue() {
+	}
+	out := getOutput(bd)
+	for _, want := range []string{"global connect 1 2", "zone", "room"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ACONNECT missing %q: %q", want, out)
+		}
+	}
+
+	g.Conf.RoomAconnect = false
+	g.Conf.GlobalAconnUselocks = true
+	DispatchCommand(g, env.player, "@lock/use #2=#1")
+	g.fireAconnect(bd, "connect")
+	for g.ProcessQueue() {
+	}
+	if out := getOutput(bd); strings.Contains(out, "global") || strings.Contains(out, "room") || !strings.Contains(out, "zone") {
+		t.Errorf("ACONNECT with room_aconnect off and uselocks: %q", out)
+	}
+
+	bd.DisconnectReason = "booted"
+	g.fireAdisconnect(bd, 0)
+	for g.ProcessQueue() {
+	}
+	if out := getOutput(bd); !strings.Contains(out, "bye boot 0") {
+		t.Errorf("ADISCONNECT: %q", out)
+	}
+}
+
+func TestPageGroups(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	carol, err := g.CreatePlayer("Carol", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bd := makeTestDescriptor(t, g.Conns, 3)
+	cd := makeTestDescriptor(t, g.Conns, carol)
+
+	// Several recipients, matched by name or by the start of a connected name.
+	DispatchCommand(g, env.player, "page Bob Car=hello")
+	if out := getOutput(bd); !strings.Contains(out, "To (Bob, Carol), Wizard pages: hello") {
+		t.Errorf("group page to Bob: %q", out)
+	}
+	// A reply goes to the pager and the rest of the group.
+	DispatchCommand(g, bd, "reply hi back")
+	if out := getOutput(cd); !strings.Contains(out, "To (Carol, Wizard), Bob pages: hi back") {
+		t.Errorf("reply to Carol: %q", out)
+	}
+	// page =<message> and page <message> repeat the last group.
+	DispatchCommand(g, bd, "page again")
+	if out := getOutput(env.player); !strings.Contains(out, "Bob pages: again") {
+		t.Errorf("repeat page: %q", out)
+	}
+
+	// PageLocks refuse with the Reject message; HAVEN refuses everyone.
+	DispatchCommand(g, cd, "@lock/page me=#1")
+	DispatchCommand(g, cd, "@reject me=Busy.")
+	DispatchCommand(g, bd, "page Carol=hi")
+	if out := getOutput(bd); !strings.Contains(out, "Carol is not accepting pages.") || !strings.Contains(out, "Reject message from Carol: Busy.") {
+		t.Errorf("page through a PageLock: %q", out)
+	}
+	DispatchCommand(g, cd, "page Bob=hi")
+	if out := getOutput(cd); !strings.Contains(out, "Bob can't return your page.") {
+		t.Errorf("page someone who can't page back: %q", out)
+	}
+	g.DB.Objects[3].Flags[0] |= gamedb.FlagHaven
+	DispatchCommand(g, env.player, "page Bob=hi")
+	if out := getOutput(env.player); !strings.Contains(out, "Bob is not accepting pages.") {
+		t.Errorf("page a HAVEN player: %q", out)
+	}
+
+	g.Conf.PageRateLimit = 1
+	DispatchCommand(g, cd, "page Wizard=one")
+	DispatchCommand(g, cd, "page Wizard=two")
+	if out := getOutput(cd); !strings.Contains(out, "paging too quickly") {
+		t.Errorf("page_rate_limit: %q", out)
+	}
+}
+
+func TestNoEvalSwitches(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	bd := makeTestDescriptor(t, g.Conns, 3)
+
+	for _, cmd := range []string{"say/noeval [add(1,2)]", "pose/noeval [add(1,2)]", "@emit/noeval [add(1,2)]",
+		"@pemit/noeval Bob=[add(1,2)]", "@remit/noeval #0=[add(1,2)]", "@oemit/noeval Wizard=[add(1,2)]"} {
+		DispatchCommand(g, env.player, cmd)
+		if out := getOutput(bd); !strings.Contains(out, "[add(1,2)]") || strings.Contains(out, "3") {
+			t.Errorf("%s: %q", cmd, out)
+		}
+	}
+	DispatchCommand(g, env.player, "@pemit Bob=[add(1,2)]")
+	if out := getOutput(bd); !strings.Contains(out, "3") {
+		t.Errorf("@pemit without /noeval: %q", out)
+	}
+
+	// @pemit/html only reaches Pueblo clients.
+	pd := makeTestDescriptor(t, g.Conns, 3)
+	pd.Pueblo = true
+	DispatchCommand(g, env.player, "@pemit/html Bob=<b>hi</b>")
+	if out := getOutput(pd); !strings.Contains(out, "<b>hi</b>") {
+		t.Errorf("@pemit/html to Pueblo client: %q", out)
+	}
+	if out := getOutput(bd); strings.Contains(out, "hi") {
+		t.Errorf("@pemit/html to plain client: %q", out)
+	}
+}
+
+func TestDoingPoll(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	bd := makeTestDescriptor(t, g.Conns, 3)
+
+	DispatchCommand(g, bd, "@doing Building things")
+	DispatchCommand(g, bd, "@doing/header Mood")
+	if out := getOutput(bd); !strings.Contains(out, "Permission denied") || g.Poll() != "Doing" {
+		t.Errorf("mortal @doing/header: %q, poll %q", out, g.Poll())
+	}
+	DispatchCommand(g, env.player, "@doing/header Mood")
+	DispatchCommand(g, bd, "WHO")
+	out := getOutput(bd)
+	if !strings.Contains(out, "  Mood") || !strings.Contains(out, "  Building things") {
+		t.Errorf("WHO with poll: %q", out)
+	}
+	DispatchCommand(g, bd, "think [poll()]|[doing(#3)]")
+	if out := getOutput(bd); !strings.Contains(out, "Mood|Building things") {
+		t.Errorf("poll()|doing(): %q", out)
+	}
+
+	// The message comes back on the next connection.
+	g.Conns.Remove(bd)
+	bd2 := makeTestDescriptor(t, g.Conns, 3)
+	g.restoreDoing(bd2)
+	if bd2.DoingStr != "Building things" {
+		t.Errorf("restored doing = %q", bd2.DoingStr)
+	}
+
+	g.Conf.WhoData = []string{"name", "cmds"}
+	DispatchCommand(g, bd2, "WHO")
+	if out := getOutput(bd2); !strings.Contains(out, "Player Name       Cmds") || strings.Contains(out, "Building") {
+		t.Errorf("who_data name cmds: %q", out)
+	}
+
+	// Wizards see player flags.
+	g.DB.Objects[3].Flags[1] |= gamedb.Flag2Suspect
+	clearOutput(env.player)
+	DispatchCommand(g, env.player, "WHO")
+	if out := getOutput(env.player); !strings.Contains(out, "+  #") {
+		t.Errorf("wizard WHO flags: %q", out)
+	}
+}
+
+func TestNoSpoof(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	bd := makeTestDescriptor(t, g.Conns, 3)
+
+	// NOSPOOF recipients see who an emit came from; the speaker doesn't.
+	g.DB.Objects[3].Flags[0] |= gamedb.FlagNoSpoof
+	DispatchCommand(g, env.player, "@pemit Bob=Boo.")
+	if out := getOutput(bd); out != "[Wizard(#1)] Boo." {
+		t.Errorf("@pemit to NOSPOOF player = %q", out)
+	}
+	g.DB.Objects[1].Flags[0] |= gamedb.FlagNoSpoof
+	DispatchCommand(g, env.player, "@emit Thunder rolls.")
+	if out := getOutput(bd); !strings.Contains(out, "[Wizard(#1)] Thunder rolls.") {
+		t.Errorf("@emit to NOSPOOF player = %q", out)
+	}
+	if out := getOutput(env.player); out != "Thunder rolls." {
+		t.Errorf("@emit seen by its speaker = %q", out)
+	}
+	g.DB.Objects[1].Flags[0] &^= gamedb.FlagNoSpoof
+
+	// Objects are attributed with their owner and whoever caused them.
+	g.SetAttrByName(2, "CMD", "$shout:@emit Hey!")
+	DispatchCommand(g, bd, "shout")
+	for g.ProcessQueue() {
+	}
+	if out := getOutput(bd); !strings.Contains(out, "[TestObject(#2){Wizard}<-(#3)] Hey!") {
+		t.Errorf("object @emit to NOSPOOF player = %q", out)
+	}
+
+	// Mortals can't @pemit far away without pemit_far_players.
+	g.Teleport(1, 4)
+	getOutput(env.player)
+	DispatchCommand(g, bd, "@pemit #1=Psst.")
+	if out := getOutput(bd); !strings.Contains(out, "too far away") {
+		t.Errorf("@pemit to far player = %q", out)
+	}
+	g.Conf.PemitFarPlayers = true
+	DispatchCommand(g, bd, "@pemit #1=Psst.")
+	if out := getOutput(env.player); out != "Psst." {
+		t.Errorf("@pemit with pemit_far_players = %q", out)
+	}
+	DispatchCommand(g, bd, "@remit #4=Hello.")
+	if out := getOutput(bd); !strings.Contains(out, "too far away") {
+		t.Errorf("@remit to far room = %q", out)
+	}
+}
+
+func TestGaggedHaven(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	bd := makeTestDescriptor(t, g.Conns, 3)
+	run := func(d *Descriptor, cmd string) {
+		DispatchCommand(g, d, cmd)
+		for g.ProcessQueue() {
+		}
+	}
+
+	run(env.player, "@set Bob=GAGGED")
+	getOutput(env.player)
+	for _, cmd := range []string{"say hi", "\"hi", ":waves", "page Wizard=hi", "@emit hi", "@pemit Wizard=hi"} {
+		run(bd, cmd)
+		if out := getOutput(bd); out != "You have been gagged." {
+			t.Errorf("gagged %s: %q", cmd, out)
+		}
+		if out := getOutput(env.player); out != "" {
+			t.Errorf("gagged %s was heard: %q", cmd, out)
+		}
+	}
+	run(bd, "@set me=!GAGGED")
+	if out := getOutput(bd); !strings.Contains(out, "Permission denied") || !Gagged(g, 3) {
+		t.Errorf("player ungagged themselves: %q", out)
+	}
+	run(env.player, "@set Bob=!GAGGED")
+	run(bd, "say free")
+	if out := getOutput(bd); out != "You say \"free\"" {
+		t.Errorf("ungagged say = %q", out)
+	}
+
+	// Objects in a HAVEN room don't hear.
+	run(env.player, "@set #2=MONITOR")
+	run(env.player, "&HEAR #2=^* says *:@pemit #1=Heard %0.")
+	run(bd, "say one")
+	if out := getOutput(env.player); !strings.Contains(out, "Heard Bob.") {
+		t.Errorf("listener outside HAVEN = %q", out)
+	}
+	run(env.player, "@set here=HAVEN")
+	run(bd, "say two")
+	if out := getOutput(env.player); strings.Contains(out, "Heard") {
+		t.Errorf("listener in HAVEN room heard: %q", out)
+	}
+}
+
+func TestMoveObjectAttrs(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	bd := makeTestDescriptor(t, g.Conns, 3)
+	run := func(d *Descriptor, cmd string) {
+		DispatchCommand(g, d, cmd)
+		for g.ProcessQueue() {
+		}
+	}
+
+	g.CreateExit("East", 0, 4, 1)
+	run(env.player, "@osucc East=walks east.")
+	run(env.player, "@oxenter #4=heads for the Other Room.")
+	run(env.player, "@enter #4=You step inside.")
+	run(env.player, "@move me=You moved.")
+	getOutput(env.player)
+	run(env.player, "east")
+	out := getOutput(bd)
+	for _, want := range []string{"Wizard walks east.", "Wizard heads for the Other Room.", "Wizard has left."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("old room missing %q: %q", want, out)
+		}
+	}
+	out = getOutput(env.player)
+	if !strings.Contains(out, "You step inside.") || !strings.Contains(out, "You moved.") {
+		t.Errorf("mover's messages = %q", out)
+	}
+
+	// Teleports fire OTPORT in the new room.
+	run(env.player, "@otport me=appears in a puff of smoke.")
+	run(env.player, "@tel #0")
+	if out := getOutput(bd); !strings.Contains(out, "Wizard has arrived.") || !strings.Contains(out, "Wizard appears in a puff of smoke.") {
+		t.Errorf("teleport arrival = %q", out)
+	}
+
+	// Things get the same announcements as players.
+	run(env.player, "@tel TestObject=#4")
+	if out := getOutput(bd); !strings.Contains(out, "TestObject has left.") {
+		t.Errorf("thing teleported away = %q", out)
+	}
+
+	// A TeloutLock keeps you from teleporting out.
+	g.DB.Objects[0].Flags[0] |= gamedb.FlagJumpOK
+	g.Teleport(3, 4)
+	run(env.player, "@lock/teloutlock #4=#1")
+	run(bd, "@tel #0")
+	if out := getOutput(bd); !strings.Contains(out, "You can't teleport out!") || g.PlayerLocation(3) != 4 {
+		t.Errorf("teleport past TeloutLock = %q, now in #%d", out, g.PlayerLocation(3))
+	}
+	run(env.player, "@unlock/teloutlock #4")
+	run(bd, "@tel #0")
+	if loc := g.PlayerLocation(3); loc != 0 {
+		t.Errorf("teleport after unlock left Bob in #%d", loc)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	bd := makeTestDescriptor(t, g.Conns, 3)
+
+	g.CreateExit("East", 0, 4, 1)
+	DispatchCommand(g, bd, "follow Wizard")
+	if out := getOutput(bd); out != "You begin following Wizard." {
+		t.Errorf("follow = %q", out)
+	}
+	if out := getOutput(env.player); out != "Bob is now following you." {
+		t.Errorf("leader told = %q", out)
+	}
+	DispatchCommand(g, env.player, "east")
+	if loc := g.PlayerLocation(3); loc != 4 {
+		t.Fatalf("follower left in #%d, want #4", loc)
+	}
+	if out := getOutput(bd); !strings.Contains(out, "You follow Wizard.") {
+		t.Errorf("follower told = %q", out)
+	}
+
+	// Followers are held to the exit's lock themselves.
+	g.Teleport(1, 0)
+	g.Teleport(3, 0)
+	exit := g.DB.Objects[0].Exits
+	DispatchCommand(g, env.player, fmt.Sprintf("@lock #%d=#1", exit))
+	getOutput(env.player)
+	getOutput(bd)
+	DispatchCommand(g, env.player, "east")
+	if loc := g.PlayerLocation(3); loc != 0 {
+		t.Errorf("follower got past the exit lock to #%d", loc)
+	}
+	if out := getOutput(bd); !strings.Contains(out, "You can't follow Wizard that way.") {
+		t.Errorf("locked-out follower told = %q", out)
+	}
+
+	DispatchCommand(g, env.player, "dismiss")
+	if out := getOutput(bd); out != "Wizard dismisses you." || g.following(3, 1) {
+		t.Errorf("dismiss = %q", out)
+	}
+	DispatchCommand(g, bd, "unfollow")
+	if out := getOutput(bd); out != "You aren't following anyone." {
+		t.Errorf("unfollow with no leader = %q", out)
+	}
+}
+
+func TestVehicles(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	bd := makeTestDescriptor(t, g.Conns, 3)
+
+	DispatchCommand(g, bd, "enter Container")
+	DispatchCommand(g, bd, "look out")
+	if out := getOutput(bd); !strings.Contains(out, "Room Zero") {
+		t.Errorf("look out = %q", out)
+	}
+	DispatchCommand(g, bd, "look/outside Wizard")
+	if out := getOutput(bd); !strings.Contains(out, "Wizard") {
+		t.Errorf("look/outside Wizard = %q", out)
+	}
+	g.DB.Objects[5].Flags[0] |= gamedb.FlagSeeThru
+	DispatchCommand(g, bd, "look")
+	if out := getOutput(bd); !strings.Contains(out, "Outside:\r\nRoom Zero") {
+		t.Errorf("look inside a TRANSPARENT thing = %q", out)
+	}
+
+	// Moving the vehicle tells both the passengers and the rooms.
+	getOutput(env.player)
+	DispatchCommand(g, env.player, "@tel Container=#4")
+	if out := getOutput(env.player); !strings.Contains(out, "Container has left.") {
+		t.Errorf("room the vehicle left saw %q", out)
+	}
+	out := getOutput(bd)
+	for _, want := range []string{"Container leaves Room Zero.", "Container arrives at Other Room.", "Other Room"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("passenger missing %q: %q", want, out)
+		}
+	}
+
+	// Nothing can be moved inside itself.
+	DispatchCommand(g, env.player, "@tel #5=#3")
+	if out := getOutput(env.player); !strings.Contains(out, "Bad destination.") || g.DB.Objects[5].Location != 4 {
+		t.Errorf("teleport into a passenger = %q", out)
+	}
+}
+
+func TestExamineSwitches(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.DB.Objects[2].Parent = 5
+	g.SetAttr(5, 6, "Inherited desc.")  // A_DESC
+	g.SetAttr(5, 4, "Overridden succ.") // A_SUCC
+	g.SetAttr(2, 4, "Own succ.")
+	getOutput(env.player)
+
+	DispatchCommand(g, env.player, "examine/brief TestObject")
+	if out := getOutput(env.player); strings.Contains(out, "Own succ.") || !strings.Contains(out, "Owner:") {
+		t.Errorf("examine/brief = %q", out)
+	}
+
+	DispatchCommand(g, env.player, "examine TestObject")
+	if out := getOutput(env.player); strings.Contains(out, "Inherited desc.") {
+		t.Errorf("plain examine shows parent attrs: %q", out)
+	}
+	DispatchCommand(g, env.player, "examine/parent TestObject")
+	out := getOutput(env.player)
+	if !strings.Contains(out, "DESC [from #5]: Inherited desc.") || !strings.Contains(out, "Own succ.") {
+		t.Errorf("examine/parent = %q", out)
+	}
+	if strings.Contains(out, "Overridden succ.") {
+		t.Errorf("examine/parent shows an overridden parent attr: %q", out)
+	}
+
+	DispatchCommand(g, env.player, "examine/debug TestObject")
+	out = getOutput(env.player)
+	for _, want := range []string{"Parent   = 5", "Owner    = 1", "Attr list: SUCC", "SUCC [#1]: Own succ."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("examine/debug missing %q: %q", want, out)
+		}
+	}
+	bd := makeTestDescriptor(t, g.Conns, 3)
+	DispatchCommand(g, bd, "examine/debug me")
+	if out := getOutput(bd); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("mortal examine/debug = %q", out)
+	}
+}
+
+func TestTerseVerbose(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	bd := makeTestDescriptor(t, g.Conns, 3)
+	g.SetAttr(4, 6, "A quiet room.") // A_DESC
+	g.CreateExit("east", 0, 4, 1)
+	g.DB.Objects[3].Flags[0] |= gamedb.FlagTerse
+
+	DispatchCommand(g, bd, "east")
+	out := getOutput(bd)
+	if !strings.Contains(out, "Other Room") || strings.Contains(out, "A quiet room.") {
+		t.Errorf("TERSE arrival = %q", out)
+	}
+	DispatchCommand(g, bd, "look")
+	if out := getOutput(bd); !strings.Contains(out, "A quiet room.") {
+		t.Errorf("TERSE look = %q", out)
+	}
+
+	g.DB.Objects[2].Flags[0] |= gamedb.FlagVerbose
+	getOutput(env.player)
+	DispatchCommand(g, env.player, "@force TestObject=think hello")
+	for g.ProcessQueue() {
+	}
+	if out := getOutput(env.player); !strings.Contains(out, "TestObject] think hello") {
+		t.Errorf("VERBOSE owner echo = %q", out)
+	}
+}
+
+func TestAdminConsole(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Guests = NewGuestManager()
+	ctrl := &gameServerController{game: g}
+
+	if _, err := ctrl.OpenConsole("Bob"); err == nil {
+		t.Error("console opened as a mortal")
+	}
+	con, err := ctrl.OpenConsole("Wizard")
+	if err != nil {
+		t.Fatalf("OpenConsole: %v", err)
+	}
+	if con.Player() != "Wizard(#1)" {
+		t.Errorf("Player() = %q", con.Player())
+	}
+	con.Run("think hello from the console")
+	select {
+	case line := <-con.Output():
+		if line != "hello from the console" {
+			t.Errorf("console output = %q", line)
+		}
+	default:
+		t.Error("no console output")
+	}
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	con.Close()
+	if _, ok := <-con.Output(); ok {
+		t.Error("output still open after Close")
+	}
+	if n := len(g.Conns.GetByPlayer(1)); n != 1 {
+		t.Errorf("wizard has %d descriptors after Close, want 1", n)
+	}
+	if out := getOutput(bob); !strings.Contains(out, "Wizard has disconnected.") {
+		t.Errorf("console Close not seen as a disconnect: %q", out)
+	}
+	con.Close()
+
+	ring := &logRing{max: 2}
+	fmt.Fprint(ring, "one\ntwo\nthr")
+	fmt.Fprint(ring, "ee\n")
+	if got := ring.Lines(5); len(got) != 2 || got[0] != "two" || got[1] != "three" {
+		t.Errorf("logRing.Lines = %q", got)
+	}
+}
+
+func TestRESTAttrWrites(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	ws := &WebServer{game: g}
+	call := func(player gamedb.DBRef, h http.HandlerFunc, method, target, body string, path map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for k, v := range path {
+			req.SetPathValue(k, v)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), claimsKey, &Claims{PlayerRef: player}))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+	path := map[string]string{"dbref": "2", "name": "notes"}
+
+	rec := call(1, ws.handlePutAttr, "PUT", "/api/v1/objects/2/attrs/notes", `{"value":"built via the api"}`, path)
+	if rec.Code != http.StatusOK || g.GetAttrTextByName(2, "NOTES") != "built via the api" {
+		t.Errorf("PUT as owner: %d %s", rec.Code, rec.Body)
+	}
+	rec = call(3, ws.handlePutAttr, "PUT", "/api/v1/objects/2/attrs/notes", `{"value":"vandalized"}`, path)
+	if rec.Code != http.StatusForbidden || g.GetAttrTextByName(2, "NOTES") != "built via the api" {
+		t.Errorf("PUT as a mortal who doesn't control it: %d %s", rec.Code, rec.Body)
+	}
+	rec = call(1, ws.handleDeleteAttr, "DELETE", "/api/v1/objects/2/attrs/notes", "", path)
+	if rec.Code != http.StatusOK || g.GetAttrTextByName(2, "NOTES") != "" {
+		t.Errorf("DELETE: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = call(1, ws.handleSearch, "GET", "/api/v1/search?q=type%3Dthing", "", nil)
+	var result struct {
+		Objects []struct {
+			Ref  int    `json:"ref"`
+			Name string `json:"name"`
+		} `json:"objects"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || len(result.Objects) == 0 || result.Objects[0].Name != "TestObject" {
+		t.Errorf("search: %d %s", rec.Code, rec.Body)
+	}
+	rec = call(3, ws.handleSearch, "GET", "/api/v1/search?q=Wizard+type%3Dthing", "", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("mortal searching someone else's objects: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestHealthReady(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	srv := &Server{Config: Config{Cleartext: true, Port: 6250}, Game: g}
+	ws := &WebServer{game: g, server: srv, startTime: time.Now()}
+	probe := func(h http.HandlerFunc) (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest("GET", "/", nil))
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("bad JSON: %v: %s", err, rec.Body)
+		}
+		return rec.Code, body
+	}
+
+	code, body := probe(ws.handleHealth)
+	db, _ := body["database"].(map[string]any)
+	if code != http.StatusOK || db["objects"] != float64(len(g.DB.Objects)) || body["queue"] == nil || body["listeners"] == nil {
+		t.Errorf("health: %d %v", code, body)
+	}
+	if code, body = probe(ws.handleReady); code != http.StatusServiceUnavailable {
+		t.Errorf("ready before the listener is up: %d %v", code, body)
+	}
+	srv.cleartextUp.Store(true)
+	if code, body = probe(ws.handleReady); code != http.StatusOK || body["ready"] != true {
+		t.Errorf("ready with the listener up: %d %v", code, body)
+	}
+
+	// A busy game, or one shutting down with the lock kept, still answers.
+	g.Lock()
+	defer g.Unlock()
+	if code, body = probe(ws.handleHealth); code != http.StatusOK || body["busy"] != true {
+		t.Errorf("health while the world is locked: %d %v", code, body)
+	}
+	if code, body = probe(ws.handleReady); code != http.StatusOK {
+		t.Errorf("ready while the world is locked: %d %v", code, body)
+	}
+	g.shuttingDown.Store(true)
+	if code, body = probe(ws.handleReady); code != http.StatusServiceUnavailable {
+		t.Errorf("ready while shutting down: %d %v", code, body)
+	}
+}
+
+func TestPublicPages(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.Conf = DefaultGameConf()
+	g.Conf.MudName = "Testville"
+	g.Comsys = NewComsys()
+	ws := &WebServer{game: g}
+	DispatchCommand(g, env.player, "@ccreate Public")
+	DispatchCommand(g, env.player, "addcom pub=Public")
+	DispatchCommand(g, env.player, "pub Hello from the square.")
+	DispatchCommand(g, env.player, "@ccreate Staff")
+	DispatchCommand(g, env.player, "@cset Staff=private")
+	g.DB.Objects[0].Flags[0] |= gamedb.FlagVisual
+	g.DB.Objects[4].Flags[0] |= gamedb.FlagVisual
+	g.SetAttr(0, 6, "A %xhbare%xn room.%r[add(1,1)] doors.[set(me,SEEN:1)]")
+	g.CreateExit("North;n", 0, 4, 1)
+	g.DB.Objects[4].Zone = 2
+
+	page := func(h http.HandlerFunc, path map[string]string) (int, string) {
+		req := httptest.NewRequest("GET", "/pages/", nil)
+		for k, v := range path {
+			req.SetPathValue(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := page(ws.handlePublicIndex, nil)
+	if code != http.StatusOK || !strings.Contains(body, "<h1>Testville</h1>") ||
+		!strings.Contains(body, `href="/pages/channels/Public"`) || strings.Contains(body, "Staff") ||
+		!strings.Contains(body, "<h2>TestObject</h2>") || !strings.Contains(body, "<h2>Elsewhere</h2>") {
+		t.Errorf("index: %d\n%s", code, body)
+	}
+	code, body = page(ws.handlePublicRoom, map[string]string{"dbref": "0"})
+	if code != http.StatusOK || !strings.Contains(body, "A bare room.\n[add(1,1)] doors.") || !strings.Contains(body, `<a href="/pages/rooms/4">North</a>`) {
+		t.Errorf("room: %d\n%s", code, body)
+	}
+	if g.GetAttrTextByName(0, "SEEN") != "" {
+		t.Error("viewing a room page ran its DESC")
+	}
+	g.SetAttr(0, 6, "A crowded room.")
+	if _, body = page(ws.handlePublicRoom, map[string]string{"dbref": "0"}); !strings.Contains(body, "A crowded room.") {
+		t.Errorf("room page kept a stale DESC:\n%s", body)
+	}
+	if code, _ = page(ws.handlePublicRoom, map[string]string{"dbref": "2"}); code != http.StatusNotFound {
+		t.Errorf("page for a thing: %d", code)
+	}
+	code, body = page(ws.handlePublicChannel, map[string]string{"name": "Public"})
+	if code != http.StatusOK || !strings.Contains(body, "Hello from the square.") {
+		t.Errorf("channel: %d\n%s", code, body)
+	}
+	if code, _ = page(ws.handlePublicChannel, map[string]string{"name": "Staff"}); code != http.StatusNotFound {
+		t.Errorf("private channel page: %d", code)
+	}
+
+	g.TextDir = t.TempDir()
+	os.MkdirAll(filepath.Join(g.TextDir, "pages"), 0755)
+	os.WriteFile(filepath.Join(g.TextDir, "pages", "who.html"), []byte(`{{len .Players}} online at {{.Game}}`), 0644)
+	if code, body = page(ws.handlePublicWho, nil); body != "1 online at Testville" {
+		t.Errorf("who with a custom template: %d %q", code, body)
+	}
+}
+
+func TestQueueSurvivesRestart(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	g.Store = store
+	now := time.Now()
+	g.Clock = func() time.Time { return now }
+
+	rdata := eval.NewRegisterData()
+	rdata.QRegs[0] = "kept"
+	rdata.XRegs["name"] = "also kept"
+	g.Queue.AddWait(&QueueEntry{Player: 1, Cause: 1, Caller: 1, Command: "think waited %q0 %q<name> %0",
+		Args: []string{"arg"}, RData: rdata, WaitUntil: now.Add(time.Hour)})
+	g.Queue.AddWait(&QueueEntry{Player: 3, Cause: 3, Command: "think gone", WaitUntil: now.Add(time.Hour)})
+	g.semaphoreWait(2, gamedb.A_SEMAPHORE, &QueueEntry{Player: 1, Cause: 1, Command: "think notified"}, 0)
+	g.SaveQueue()
+
+	// The restart: the queue is lost and #3 destroyed meanwhile.
+	g.Queue.HaltAll()
+	g.DB.Objects[3].Flags[0] |= gamedb.FlagGoing
+	saved, err := store.LoadQueue()
+	if err != nil || len(saved) != 3 {
+		t.Fatalf("LoadQueue = %d entries, %v", len(saved), err)
+	}
+	if n := g.RestoreQueue(saved); n != 2 {
+		t.Errorf("RestoreQueue = %d, want 2", n)
+	}
+	waits, sems := g.Queue.Waiting()
+	if len(waits) != 1 || len(sems) != 1 || sems[0].SemObj != 2 {
+		t.Fatalf("restored %d waits, %d semaphore waits", len(waits), len(sems))
+	}
+
+	clearOutput(env.player)
+	DispatchCommand(g, env.player, "@notify #2")
+	now = now.Add(2 * time.Hour)
+	g.DrainQueue()
+	out := getOutput(env.player)
+	if !strings.Contains(out, "waited kept also kept arg") || !strings.Contains(out, "notified") || strings.Contains(out, "gone") {
+		t.Errorf("restored queue ran:\n%s", out)
+	}
+}
+
+func TestRuntimeSettings(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	g.Store = store
+	g.Conf = DefaultGameConf()
+	g.GameFuncs = make(map[string]*eval.UFunction)
+	run := func(cmd string) string {
+		t.Helper()
+		clearOutput(env.player)
+		DispatchCommand(g, env.player, cmd)
+		return getOutput(env.player)
+	}
+
+	run("@motd/wizard Wizards, behave.")
+	run("@function twice=#2/VA")
+	run("@function gone=#2/VB")
+	run("@function/delete gone")
+	run("@admin wait_cost=7")
+	if out := run("@admin/save"); !strings.Contains(out, "Saved 1 @admin change(s).") {
+		t.Errorf("@admin/save: %q", out)
+	}
+	run("@admin paycheck=99")
+	out := run("@admin/list")
+	for _, want := range []string{"@motd/wizard = Wizards, behave.", "@function TWICE = #2/", "@admin wait_cost = 7  [Wizard(#1)",
+		"@admin paycheck = 99  [Wizard(#1)", "(not saved)", "4 setting(s)."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@admin/list lacks %q:\n%s", want, out)
+		}
+	}
+
+	// A restart: a fresh game loads what was kept.
+	env2 := newTestEnv(t)
+	env2.game.Conf = DefaultGameConf()
+	settings, err := store.LoadRuntimeSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env2.game.LoadRuntimeSettings(settings)
+	g2 := env2.game
+	if g2.WizMOTD != "Wizards, behave." || g2.GameFuncs["TWICE"] == nil || g2.GameFuncs["GONE"] != nil {
+		t.Errorf("after restart: wizmotd %q, functions %v", g2.WizMOTD, g2.GameFuncs)
+	}
+	if g2.Conf.WaitCost != 7 || g2.Conf.Paycheck == 99 {
+		t.Errorf("after restart: wait_cost %d, paycheck %d", g2.Conf.WaitCost, g2.Conf.Paycheck)
+	}
+
+	if out := run("@admin/clear wait_cost"); !strings.Contains(out, "Saved value for wait_cost cleared.") {
+		t.Errorf("@admin/clear: %q", out)
+	}
+	if settings, _ = store.LoadRuntimeSettings(); len(settings) != 2 {
+		t.Errorf("%d settings kept after @admin/clear, want 2", len(settings))
+	}
+}
+
+func TestMotdFilesAndRotation(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	g.TextDir = t.TempDir()
+	g.Texts = &TextFiles{}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	g.Clock = func() time.Time { return now }
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	run := func(d *Descriptor, cmd string) string {
+		t.Helper()
+		clearOutput(d)
+		DispatchCommand(g, d, cmd)
+		return getOutput(d)
+	}
+
+	if out := run(env.player, "@motd Welcome!"); !strings.Contains(out, "MOTD written to motd.txt.") {
+		t.Errorf("@motd: %q", out)
+	}
+	if data, _ := os.ReadFile(filepath.Join(g.TextDir, "motd.txt")); string(data) != "Welcome!\n" {
+		t.Errorf("motd.txt = %q", data)
+	}
+	run(env.player, "@motd/keep Also this.")
+	if out := run(env.player, "@motd"); !strings.Contains(out, "Welcome!") || !strings.Contains(out, "Also this.") ||
+		!strings.Contains(out, "[Set by Wizard(#1) on 2026-10-16 12:00]") {
+		t.Errorf("@motd for a wizard: %q", out)
+	}
+	if out := run(bob, "@motd"); !strings.Contains(out, "Welcome!") || strings.Contains(out, "Set by") {
+		t.Errorf("@motd for a mortal: %q", out)
+	}
+	if out := run(bob, "@motd Mine now"); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("mortal @motd: %q", out)
+	}
+
+	run(env.player, "@motd/add First rotation")
+	run(env.player, "@motd/add 2026-10-20 to 2026-10-25=Later")
+	run(env.player, "@motd/add to 2026-10-17=Second rotation")
+	if out := run(env.player, "@motd/add tomorrow=Bad"); !strings.Contains(out, "That's not a valid time.") {
+		t.Errorf("bad schedule: %q", out)
+	}
+	var logins []string
+	for i := 0; i < 3; i++ {
+		clearOutput(bob)
+		g.sendLoginMotd(bob)
+		logins = append(logins, getOutput(bob))
+	}
+	if !strings.Contains(logins[0], "First rotation") || !strings.Contains(logins[1], "Second rotation") ||
+		!strings.Contains(logins[2], "First rotation") || strings.Contains(strings.Join(logins, ""), "Later") ||
+		!strings.Contains(logins[0], "Welcome!") || !strings.Contains(logins[0], "Also this.") {
+		t.Errorf("logins:\n%s", strings.Join(logins, "\n--\n"))
+	}
+	now = now.AddDate(0, 0, 5)
+	clearOutput(bob)
+	g.sendLoginMotd(bob)
+	if out := getOutput(bob); !strings.Contains(out, "Later") {
+		t.Errorf("login on the 21st: %q", out)
+	}
+
+	out := run(env.player, "@listmotd")
+	if !strings.Contains(out, "2: Later") || !strings.Contains(out, "(over)") {
+		t.Errorf("@listmotd: %q", out)
+	}
+	if out := run(env.player, "@motd/remove 2"); !strings.Contains(out, "Rotating MOTD 2 removed.") {
+		t.Errorf("@motd/remove: %q", out)
+	}
+	if len(g.motdRotation()) != 2 {
+		t.Errorf("%d rotating MOTDs left, want 2", len(g.motdRotation()))
+	}
+}
+
+func TestHelptext(t *testing.T) {
+	env := newTestEnv(t)
+	g := env.game
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "game.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	g.Store = store
+	dir := t.TempDir()
+	content := "& help\n  The help index.\n& @force\n  Makes an object do something.\n&& @frobnicate\n  Wizards only.\n"
+	if err := os.WriteFile(filepath.Join(dir, "help.txt"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g.LoadHelpFiles(dir)
+	bob := makeTestDescriptor(t, g.Conns, 3)
+	run := func(d *Descriptor, cmd string) string {
+		t.Helper()
+		clearOutput(d)
+		DispatchCommand(g, d, cmd)
+		return getOutput(d)
+	}
+
+	if out := run(bob, "help @frob"); strings.Contains(out, "Wizards only.") || !strings.Contains(out, "No entry for '@frob'.") {
+		t.Errorf("mortal help @frob: %q", out)
+	}
+	if out := run(env.player, "help @frob"); !strings.Contains(out, "Wizards only.") {
+		t.Errorf("wizard help @frob: %q", out)
+	}
+	if out := run(bob, "help @f"); !strings.Contains(out, "Makes an object") {
+		t.Errorf("mortal help @f: %q", out)
+	}
+	if out := run(bob, "help object"); !strings.Contains(out, "Perhaps you meant: @force") {
+		t.Errorf("help object: %q", out)
+	}
+	if out := run(bob, "help/search object"); !strings.Contains(out, "@force") {
+		t.Errorf("help/search object: %q", out)
+	}
+
+	if out := run(bob, "@helptext rules=Be nice."); !strings.Contains(out, "Permission denied.") {
+		t.Errorf("mortal @helptext: %q", out)
+	}
+	if out := run(env.player, "@helptext rules=Be nice.%rReally."); !strings.Contains(out, "Set help entry 'rules'.") {
+		t.Errorf("@helptext: %q", out)
+	}
+	run(env.player, "@helptext/wizard staff=Ask a wizard.")
+	if out := run(env.player, "@helptext/delete @force"); !strings.Contains(out, "Removed help entry '@force'.") {
+		t.Errorf("@helptext/delete: %q", out)
+	}
+	if out := run(env.player, "@helptext/delete nosuch"); !strings.Contains(out, "No entry for 'nosuch'.") {
+		t.Errorf("@helptext/delete nosuch: %q", out)
+	}
+	if out := run(bob, "help rules"); !strings.Contains(out, "Be nice.\nReally.") {
+		t.Errorf("help rules: %q", out)
+	}
+	if out := run(bob, "help staff"); strings.Contains(out, "Ask a wizard.") {
+		t.Errorf("mortal saw wizard-only entry: %q", out)
+	}
+	out := run(env.player, "@helptext/list")
+	for _, want := range []string{"help:rules  [Wizard(#1)", "help:staff (wizards only)", "help:@force (removed)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("@helptext/list lacks %q:\n%s", want, out)
+		}
+	}
+
+	// A restart: the changes are laid over the reloaded file.
+	env2 := newTestEnv(t)
+	settings, err := store.LoadRuntimeSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2 := env2.game
+	g2.LoadRuntimeSettings(settings)
+	g2.LoadHelpFiles(dir)
+	if g2.HelpMain.Lookup("rules") != "Be nice.\nReally." || g2.HelpMain.LookupFor("staff", false) != "" ||
+		g2.HelpMain.Lookup("@force") != "" || g2.HelpMain.Lookup("staff") != "Ask a wizard." {
+		t.Errorf("after restart: %v", g2.HelpMain.Entries)
 	}
 }
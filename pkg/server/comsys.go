@@ -3,9 +3,11 @@ package server
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 
+	"github.com/crystal-mush/gotinymush/pkg/eval"
 	"github.com/crystal-mush/gotinymush/pkg/events"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 )
@@ -15,6 +17,7 @@ type Comsys struct {
 	mu       sync.RWMutex
 	Channels map[string]*gamedb.Channel          // lowercase name -> channel
 	Aliases  map[gamedb.DBRef][]*gamedb.ChanAlias // player -> their aliases
+	logs     map[string]*chanLog                  // lowercase name -> recent messages
 }
 
 // NewComsys creates an empty comsys manager.
@@ -22,6 +25,7 @@ func NewComsys() *Comsys {
 	return &Comsys{
 		Channels: make(map[string]*gamedb.Channel),
 		Aliases:  make(map[gamedb.DBRef][]*gamedb.ChanAlias),
+		logs:     make(map[string]*chanLog),
 	}
 }
 
@@ -200,21 +204,88 @@ func (cs *Comsys) RemoveChannel(name string) ([]*gamedb.ChanAlias, error) {
 	return removed, nil
 }
 
+// IsSubscribed returns true if player has at least one alias for the channel.
+func (cs *Comsys) IsSubscribed(player gamedb.DBRef, channelName string) bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	for _, ca := range cs.Aliases[player] {
+		if strings.EqualFold(ca.Channel, channelName) {
+			return true
+		}
+	}
+	return false
+}
+
+// canSeeChannel returns true if player may see a channel's existence and members:
+// the channel is public, or player is subscribed, owns it, or has Comm_All.
+func canSeeChannel(g *Game, player gamedb.DBRef, ch *gamedb.Channel) bool {
+	if ch.Flags&gamedb.ChanPublic != 0 || ch.Owner == player || CommAll(g, player) {
+		return true
+	}
+	return g.Comsys.IsSubscribed(player, ch.Name)
+}
+
+// canEmitChannel returns true if player may @cemit to a channel
+// (channel owner, Comm_All power, or Wizard).
+func canEmitChannel(g *Game, player gamedb.DBRef, ch *gamedb.Channel) bool {
+	return ch.Owner == player || CommAll(g, player)
+}
+
+// channelFlagString returns the human-readable flag list for a channel.
+func channelFlagString(ch *gamedb.Channel) string {
+	var flags []string
+	if ch.Flags&gamedb.ChanPublic != 0 {
+		flags = append(flags, "Public")
+	} else {
+		flags = append(flags, "Private")
+	}
+	if ch.Flags&gamedb.ChanLoud != 0 {
+		flags = append(flags, "Loud")
+	}
+	if ch.Flags&gamedb.ChanObject != 0 {
+		flags = append(flags, "Objects")
+	}
+	if ch.Flags&gamedb.ChanNoTitles != 0 {
+		flags = append(flags, "NoTitles")
+	}
+	return strings.Join(flags, " ")
+}
+
 // SendToChannel broadcasts a message to all listening, connected players on a channel.
 // It emits structured EvChannel events via the event bus.
 func (g *Game) SendToChannel(channelName string, sender gamedb.DBRef, msg string) {
 	if g.Comsys == nil {
 		return
 	}
+	g.logChannelMessage(channelName, sender, msg)
+	g.sendToChannel(channelName, sender, msg, nil)
+	if ch := g.Comsys.GetChannel(channelName); ch != nil {
+		if g.IRC != nil {
+			g.IRC.relay(ch, sender, msg)
+		}
+		if g.Discord != nil {
+			g.Discord.relay(ch, sender, msg)
+		}
+	}
+}
+
+// sendToChannel delivers msg to a channel's listeners, other than those
+// that are gagged, fail its receive lock, or are picked out by skip.
+func (g *Game) sendToChannel(channelName string, sender gamedb.DBRef, msg string, skip func(*gamedb.ChanAlias) bool) {
+	ch := g.Comsys.GetChannel(channelName)
 	listeners := g.Comsys.ChannelListeners(channelName)
 	// Deduplicate by player — a player may have multiple aliases for the
 	// same channel but should only receive each message once.
 	seen := make(map[gamedb.DBRef]bool)
 	for _, ca := range listeners {
-		if seen[ca.Player] {
+		if seen[ca.Player] || ca.Gagged || (skip != nil && skip(ca)) {
 			continue
 		}
 		seen[ca.Player] = true
+		if ch != nil && !g.passesChannelLock(ca.Player, ch, ch.RecvLock) {
+			continue
+		}
 		if g.Conns.IsConnected(ca.Player) {
 			g.EmitEvent(ca.Player, channelName, events.Event{
 				Type:    events.EvChannel,
@@ -230,6 +301,24 @@ func (g *Game) SendToChannel(channelName string, sender gamedb.DBRef, msg string
 	}
 }
 
+// channelHeader returns the channel's message header, defaulting to "[Name]".
+func channelHeader(ch *gamedb.Channel) string {
+	if ch.Header != "" {
+		return ch.Header
+	}
+	return fmt.Sprintf("[%s]", ch.Name)
+}
+
+// ChannelEmitMessage sends a raw message to a channel, prefixed with the
+// channel header unless noHeader is set. Callers are responsible for permission checks.
+func (g *Game) ChannelEmitMessage(ch *gamedb.Channel, sender gamedb.DBRef, message string, noHeader bool) {
+	msg := message
+	if !noHeader {
+		msg = channelHeader(ch) + " " + message
+	}
+	g.SendToChannel(ch.Name, sender, msg)
+}
+
 // ComsysProcessAlias handles a player using a channel alias to send a message.
 func (g *Game) ComsysProcessAlias(d *Descriptor, ca *gamedb.ChanAlias, args string) {
 	args = strings.TrimSpace(args)
@@ -239,18 +328,21 @@ func (g *Game) ComsysProcessAlias(d *Descriptor, ca *gamedb.ChanAlias, args stri
 		return
 	}
 
-	header := ch.Header
-	if header == "" {
-		header = fmt.Sprintf("[%s]", ch.Name)
-	}
+	header := channelHeader(ch)
 
 	playerName := g.PlayerName(d.Player)
-	if ca.Title != "" {
+	if ca.Title != "" && ch.Flags&gamedb.ChanNoTitles == 0 {
 		playerName = ca.Title + " " + playerName
 	}
 
-	// Meta-commands: on, off, who, last
+	// Meta-commands: on, off, who, recall
 	lower := strings.ToLower(args)
+	if word, rest, _ := strings.Cut(lower, " "); word == "recall" || word == "-recall" || word == "last" {
+		if rest = strings.TrimSpace(rest); rest == "" || isNumeric(rest) {
+			g.showChannelRecall(d, ch, rest)
+			return
+		}
+	}
 	switch lower {
 	case "on":
 		ca.IsListening = true
@@ -267,7 +359,19 @@ func (g *Game) ComsysProcessAlias(d *Descriptor, ca *gamedb.ChanAlias, args stri
 		d.Send(fmt.Sprintf("Channel %s is now off.", ch.Name))
 		return
 	case "who":
-		g.showChannelWho(d, ch)
+		g.showChannelWho(d, ch, false)
+		return
+	case "gag", "ungag", "mute", "unmute":
+		switch lower {
+		case "gag", "ungag":
+			ca.Gagged = lower == "gag"
+		default:
+			ca.Muted = lower == "mute"
+		}
+		if g.Store != nil {
+			g.Store.PutChanAlias(ca)
+		}
+		d.Send(fmt.Sprintf("Channel %s is now %sd.", ch.Name, lower))
 		return
 	}
 
@@ -280,6 +384,10 @@ func (g *Game) ComsysProcessAlias(d *Descriptor, ca *gamedb.ChanAlias, args stri
 		d.Send(fmt.Sprintf("You must turn on channel %s first.", ch.Name))
 		return
 	}
+	if !g.passesChannelLock(d.Player, ch, ch.TransLock) {
+		d.Send(fmt.Sprintf("You can't speak on channel %s.", ch.Name))
+		return
+	}
 
 	ch.NumSent++
 
@@ -301,24 +409,108 @@ func (g *Game) ComsysProcessAlias(d *Descriptor, ca *gamedb.ChanAlias, args stri
 	g.SendToChannel(ca.Channel, d.Player, msg)
 }
 
-// showChannelWho shows who's on a channel.
-func (g *Game) showChannelWho(d *Descriptor, ch *gamedb.Channel) {
-	subs := g.Comsys.ChannelSubscribers(ch.Name)
-	d.Send(fmt.Sprintf("-- %s --", ch.Name))
-	d.Send(fmt.Sprintf("%-25s %-10s %-6s", "Name", "Status", "Title"))
-	count := 0
+// showChannelWho shows who's on a channel, connected members first unless
+// alpha is set.
+func (g *Game) showChannelWho(d *Descriptor, ch *gamedb.Channel, alpha bool) {
+	var subs []*gamedb.ChanAlias
+	for _, ca := range g.Comsys.ChannelSubscribers(ch.Name) {
+		if !g.channelHidden(d.Player, ca.Player) {
+			subs = append(subs, ca)
+		}
+	}
+	names := make(map[gamedb.DBRef]string, len(subs))
+	for _, ca := range subs {
+		names[ca.Player] = g.PlayerName(ca.Player)
+	}
+	sort.SliceStable(subs, func(i, j int) bool {
+		if !alpha {
+			ci, cj := g.Conns.IsConnected(subs[i].Player), g.Conns.IsConnected(subs[j].Player)
+			if ci != cj {
+				return ci
+			}
+		}
+		return strings.ToLower(names[subs[i].Player]) < strings.ToLower(names[subs[j].Player])
+	})
+	d.Send(fmt.Sprintf("-- %s %s --", channelHeader(ch), ch.Name))
+	d.Send(comsysHeading(comsysCol("Name", 22) + comsysCol("Status", 6) + comsysCol("Conn", 4) + "Title"))
+	d.Send(strings.Repeat("-", 60))
+	listening, connected := 0, 0
 	for _, ca := range subs {
-		name := g.PlayerName(ca.Player)
 		status := "Off"
 		if ca.IsListening {
 			status = "On"
+			listening++
+		}
+		if ca.Gagged {
+			status = "Gag"
 		}
-		online := ""
+		conn := ""
 		if g.Conns.IsConnected(ca.Player) {
-			online = " *"
+			conn = "*"
+			connected++
+		}
+		d.Send(comsysCol(names[ca.Player], 22) + comsysCol(status, 6) + comsysCol(conn, 4) + ca.Title)
+	}
+	d.Send(fmt.Sprintf("-- %d subscriber(s), %d listening, %d connected --", len(subs), listening, connected))
+}
+
+// channelMemberCount returns the number of distinct objects subscribed to a channel.
+func (g *Game) channelMemberCount(name string) int {
+	seen := make(map[gamedb.DBRef]bool)
+	for _, ca := range g.Comsys.ChannelSubscribers(name) {
+		seen[ca.Player] = true
+	}
+	return len(seen)
+}
+
+// channelFlagLetters abbreviates a channel's flags for column display:
+// P(ublic), L(oud), O(bjects), T (no titles).
+func channelFlagLetters(ch *gamedb.Channel) string {
+	var b strings.Builder
+	for _, f := range []struct {
+		bit    int
+		letter byte
+	}{{gamedb.ChanPublic, 'P'}, {gamedb.ChanLoud, 'L'}, {gamedb.ChanObject, 'O'}, {gamedb.ChanNoTitles, 'T'}} {
+		if ch.Flags&f.bit != 0 {
+			b.WriteByte(f.letter)
 		}
-		d.Send(ansiFmtLeft(name, 25) + ansiFmtLeft(status, 10) + ansiFmtLeft(ca.Title, 6) + online)
-		count++
 	}
-	d.Send(fmt.Sprintf("-- %d subscriber(s) --", count))
+	return b.String()
+}
+
+// comsysCol fits s to a column of the given width plus a separating space,
+// truncating without breaking ANSI sequences.
+func comsysCol(s string, width int) string {
+	if ansiVisualLen(s) > width {
+		var b strings.Builder
+		vis := 0
+		for i := 0; i < len(s) && vis < width; i++ {
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '[' {
+				start := i
+				for i += 2; i < len(s) && !((s[i] >= 'A' && s[i] <= 'Z') || (s[i] >= 'a' && s[i] <= 'z')); i++ {
+				}
+				if i < len(s) {
+					b.WriteString(s[start : i+1])
+				}
+				continue
+			}
+			b.WriteByte(s[i])
+			vis++
+		}
+		s = b.String()
+	}
+	if strings.Contains(s, "\x1b[") {
+		s += eval.AnsiCode('n')
+	}
+	return ansiFmtLeft(s, width) + " "
+}
+
+// comsysNum right-aligns a count in a column.
+func comsysNum(n, width int) string {
+	return fmt.Sprintf("%*d ", width, n)
+}
+
+// comsysHeading highlights a column heading line.
+func comsysHeading(s string) string {
+	return eval.AnsiCode('h') + s + eval.AnsiCode('n')
 }
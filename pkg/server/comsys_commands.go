@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
@@ -37,6 +38,15 @@ func cmdAddcom(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send(fmt.Sprintf("You already have an alias %q for channel %s.", alias, existing.Channel))
 		return
 	}
+	if !g.Comsys.IsSubscribed(d.Player, ch.Name) {
+		if !g.passesChannelLock(d.Player, ch, ch.JoinLock) {
+			d.Send(fmt.Sprintf("You can't join channel %s.", ch.Name))
+			return
+		}
+		if !g.chargeToJoin(d, ch) {
+			return
+		}
+	}
 
 	ca := &gamedb.ChanAlias{
 		Player:      d.Player,
@@ -94,7 +104,8 @@ func cmdClearcom(g *Game, d *Descriptor, _ string, _ []string) {
 }
 
 // cmdComlist handles "comlist" — list your channel aliases.
-func cmdComlist(g *Game, d *Descriptor, _ string, _ []string) {
+// /alpha sorts by alias, /members by channel size.
+func cmdComlist(g *Game, d *Descriptor, _ string, switches []string) {
 	if g.Comsys == nil {
 		d.Send("The channel system is not enabled.")
 		return
@@ -104,15 +115,42 @@ func cmdComlist(g *Game, d *Descriptor, _ string, _ []string) {
 		d.Send("You have no channel aliases. Use addcom <alias>=<channel> to subscribe.")
 		return
 	}
-	d.Send(fmt.Sprintf("%-12s %-20s %-6s %-20s", "Alias", "Channel", "Status", "Title"))
-	d.Send(strings.Repeat("-", 60))
+	members := make(map[string]int)
+	for _, ca := range aliases {
+		if _, ok := members[strings.ToLower(ca.Channel)]; !ok {
+			members[strings.ToLower(ca.Channel)] = g.channelMemberCount(ca.Channel)
+		}
+	}
+	sort.SliceStable(aliases, func(i, j int) bool {
+		if HasSwitch(switches, "members") {
+			mi, mj := members[strings.ToLower(aliases[i].Channel)], members[strings.ToLower(aliases[j].Channel)]
+			if mi != mj {
+				return mi > mj
+			}
+		}
+		if HasSwitch(switches, "alpha") {
+			return strings.ToLower(aliases[i].Alias) < strings.ToLower(aliases[j].Alias)
+		}
+		return strings.ToLower(aliases[i].Channel) < strings.ToLower(aliases[j].Channel)
+	})
+	d.Send(comsysHeading(comsysCol("Alias", 10) + comsysCol("Channel", 18) + comsysCol("Header", 14) +
+		comsysCol("Status", 6) + comsysCol("Members", 7) + "Title"))
+	d.Send(strings.Repeat("-", 78))
+	on := 0
 	for _, ca := range aliases {
 		status := "Off"
 		if ca.IsListening {
 			status = "On"
+			on++
+		}
+		header := ""
+		if ch := g.Comsys.GetChannel(ca.Channel); ch != nil {
+			header = channelHeader(ch)
 		}
-		d.Send(ansiFmtLeft(ca.Alias, 12) + ansiFmtLeft(ca.Channel, 20) + ansiFmtLeft(status, 6) + ansiFmtLeft(ca.Title, 20))
+		d.Send(comsysCol(ca.Alias, 10) + comsysCol(ca.Channel, 18) + comsysCol(header, 14) +
+			comsysCol(status, 6) + comsysNum(members[strings.ToLower(ca.Channel)], 7) + ca.Title)
 	}
+	d.Send(fmt.Sprintf("-- %d alias(es), %d on --", len(aliases), on))
 }
 
 // cmdComtitle handles "comtitle alias=title" — set channel title.
@@ -178,7 +216,7 @@ func cmdAllcom(g *Game, d *Descriptor, args string, _ []string) {
 		for _, ca := range aliases {
 			ch := g.Comsys.GetChannel(ca.Channel)
 			if ch != nil {
-				g.showChannelWho(d, ch)
+				g.showChannelWho(d, ch, false)
 			}
 		}
 	default:
@@ -202,9 +240,10 @@ func cmdCcreate(g *Game, d *Descriptor, args string, _ []string) {
 		return
 	}
 	ch := &gamedb.Channel{
-		Name:  name,
-		Owner: d.Player,
-		Flags: gamedb.ChanPublic,
+		Name:      name,
+		Owner:     d.Player,
+		Flags:     gamedb.ChanPublic,
+		LogLength: chanLogDefault,
 	}
 	if err := g.Comsys.AddChannel(ch); err != nil {
 		d.Send(err.Error())
@@ -236,8 +275,10 @@ func cmdCdestroy(g *Game, d *Descriptor, args string, _ []string) {
 		d.Send(err.Error())
 		return
 	}
+	g.Comsys.DropLog(name)
 	if g.Store != nil {
 		g.Store.DeleteChannel(name)
+		g.Store.DeleteChanLog(name)
 		for _, ca := range removed {
 			g.Store.DeleteChanAlias(ca.Player, ca.Alias)
 		}
@@ -245,31 +286,54 @@ func cmdCdestroy(g *Game, d *Descriptor, args string, _ []string) {
 	d.Send(fmt.Sprintf("Channel %s destroyed. %d subscription(s) removed.", name, len(removed)))
 }
 
-// cmdClist handles "@clist" — list all channels.
-func cmdClist(g *Game, d *Descriptor, _ string, _ []string) {
+// cmdClist handles "@clist" — list all channels you can see.
+// Channels are listed alphabetically; /members sorts the busiest first.
+// /recall shows a channel's history instead.
+func cmdClist(g *Game, d *Descriptor, args string, switches []string) {
 	if g.Comsys == nil {
 		d.Send("The channel system is not enabled.")
 		return
 	}
-	channels := g.Comsys.AllChannels()
+	if HasSwitch(switches, "recall") {
+		channelLogDump(g, d, args)
+		return
+	}
+	var channels []*gamedb.Channel
+	for _, ch := range g.Comsys.AllChannels() {
+		if canSeeChannel(g, d.Player, ch) {
+			channels = append(channels, ch)
+		}
+	}
 	if len(channels) == 0 {
 		d.Send("No channels defined.")
 		return
 	}
+	members := make(map[string]int, len(channels))
+	for _, ch := range channels {
+		members[ch.Name] = g.channelMemberCount(ch.Name)
+	}
 	sort.Slice(channels, func(i, j int) bool {
+		if HasSwitch(switches, "members") && members[channels[i].Name] != members[channels[j].Name] {
+			return members[channels[i].Name] > members[channels[j].Name]
+		}
 		return strings.ToLower(channels[i].Name) < strings.ToLower(channels[j].Name)
 	})
-	d.Send(fmt.Sprintf("%-20s %-6s %-8s %s", "Name", "Msgs", "Owner", "Description"))
-	d.Send(strings.Repeat("-", 70))
+	d.Send(comsysHeading(comsysCol("Name", 18) + comsysCol("Header", 14) + comsysCol("Flags", 5) +
+		comsysCol("Members", 7) + comsysCol("Msgs", 6) + comsysCol("Owner", 12) + "Description"))
+	d.Send(strings.Repeat("-", 78))
+	totalMembers, totalMsgs := 0, 0
 	for _, ch := range channels {
-		owner := g.PlayerName(ch.Owner)
-		d.Send(ansiFmtLeft(ch.Name, 20) + ansiFmtLeft(fmt.Sprintf("%d", ch.NumSent), 6) + ansiFmtLeft(owner, 8) + ch.Description)
+		totalMembers += members[ch.Name]
+		totalMsgs += ch.NumSent
+		d.Send(comsysCol(ch.Name, 18) + comsysCol(channelHeader(ch), 14) + comsysCol(channelFlagLetters(ch), 5) +
+			comsysNum(members[ch.Name], 7) + comsysNum(ch.NumSent, 6) + comsysCol(g.PlayerName(ch.Owner), 12) +
+			ch.Description)
 	}
-	d.Send(fmt.Sprintf("-- %d channel(s) --", len(channels)))
+	d.Send(fmt.Sprintf("-- %d channel(s), %d member(s), %d message(s) --", len(channels), totalMembers, totalMsgs))
 }
 
 // cmdCwho handles "@cwho channel" — show who's on a channel.
-func cmdCwho(g *Game, d *Descriptor, args string, _ []string) {
+func cmdCwho(g *Game, d *Descriptor, args string, switches []string) {
 	if g.Comsys == nil {
 		d.Send("The channel system is not enabled.")
 		return
@@ -280,11 +344,11 @@ func cmdCwho(g *Game, d *Descriptor, args string, _ []string) {
 		return
 	}
 	ch := g.Comsys.GetChannel(name)
-	if ch == nil {
+	if ch == nil || !canSeeChannel(g, d.Player, ch) {
 		d.Send(fmt.Sprintf("Channel %q not found.", name))
 		return
 	}
-	g.showChannelWho(d, ch)
+	g.showChannelWho(d, ch, HasSwitch(switches, "alpha"))
 }
 
 // cmdCboot handles "@cboot channel=player" — boot a player from a channel.
@@ -334,7 +398,8 @@ func cmdCboot(g *Game, d *Descriptor, args string, _ []string) {
 }
 
 // cmdCemit handles "@cemit channel=message" — emit to a channel.
-func cmdCemit(g *Game, d *Descriptor, args string, _ []string) {
+// With /noheader the channel header is not prepended.
+func cmdCemit(g *Game, d *Descriptor, args string, switches []string) {
 	if g.Comsys == nil {
 		d.Send("The channel system is not enabled.")
 		return
@@ -353,16 +418,17 @@ func cmdCemit(g *Game, d *Descriptor, args string, _ []string) {
 		return
 	}
 
-	header := ch.Header
-	if header == "" {
-		header = fmt.Sprintf("[%s]", ch.Name)
+	if !canEmitChannel(g, d.Player, ch) {
+		d.Send("Permission denied.")
+		return
 	}
-	msg := fmt.Sprintf("%s %s", header, message)
-	g.SendToChannel(ch.Name, d.Player, msg)
+
+	g.ChannelEmitMessage(ch, d.Player, message, HasSwitch(switches, "noheader"))
 }
 
 // cmdCset handles "@cset channel=option" — set channel properties.
-func cmdCset(g *Game, d *Descriptor, args string, _ []string) {
+// "@cset/<option> channel=value" is the same as "@cset channel=<option> value".
+func cmdCset(g *Game, d *Descriptor, args string, switches []string) {
 	if g.Comsys == nil {
 		d.Send("The channel system is not enabled.")
 		return
@@ -374,11 +440,14 @@ func cmdCset(g *Game, d *Descriptor, args string, _ []string) {
 	eqIdx := strings.IndexByte(args, '=')
 	if eqIdx < 0 {
 		d.Send("Usage: @cset <channel>=<option>")
-		d.Send("Options: description <text>, header <text>, public, private, loud, quiet")
+		d.Send("Options: description <text>, header <text>, public, private, loud, quiet, log <lines>, persist, nopersist, charge <pennies>, titles, notitles")
 		return
 	}
 	chanName := strings.TrimSpace(args[:eqIdx])
 	option := strings.TrimSpace(args[eqIdx+1:])
+	if len(switches) > 0 {
+		option = strings.TrimSpace(switches[0] + " " + option)
+	}
 
 	ch := g.Comsys.GetChannel(chanName)
 	if ch == nil {
@@ -392,8 +461,23 @@ func cmdCset(g *Game, d *Descriptor, args string, _ []string) {
 		ch.Description = strings.TrimSpace(option[12:])
 		d.Send(fmt.Sprintf("Channel %s description set.", ch.Name))
 	case strings.HasPrefix(lower, "header "):
-		ch.Header = strings.TrimSpace(option[7:])
+		// Evaluated, so %x color codes and ansi() work.
+		ch.Header = evalExpr(g, d.Player, strings.TrimSpace(option[7:]))
 		d.Send(fmt.Sprintf("Channel %s header set.", ch.Name))
+	case strings.HasPrefix(lower, "charge "):
+		n, err := strconv.Atoi(strings.TrimSpace(option[7:]))
+		if err != nil || n < 0 {
+			d.Send("The charge must be a number of pennies, 0 for none.")
+			return
+		}
+		ch.Charge = n
+		d.Send(fmt.Sprintf("Channel %s now costs %d pennies to join.", ch.Name, n))
+	case lower == "titles":
+		ch.Flags &^= gamedb.ChanNoTitles
+		d.Send(fmt.Sprintf("Channel %s shows titles.", ch.Name))
+	case lower == "notitles":
+		ch.Flags |= gamedb.ChanNoTitles
+		d.Send(fmt.Sprintf("Channel %s hides titles.", ch.Name))
 	case lower == "public":
 		ch.Flags |= gamedb.ChanPublic
 		d.Send(fmt.Sprintf("Channel %s set public.", ch.Name))
@@ -406,8 +490,36 @@ func cmdCset(g *Game, d *Descriptor, args string, _ []string) {
 	case lower == "quiet":
 		ch.Flags &^= gamedb.ChanLoud
 		d.Send(fmt.Sprintf("Channel %s set quiet.", ch.Name))
+	case strings.HasPrefix(lower, "log "):
+		n, err := strconv.Atoi(strings.TrimSpace(option[4:]))
+		if err != nil || n < 0 {
+			d.Send("The history length must be a number of lines, 0 for none.")
+			return
+		}
+		ch.LogLength = n
+		dropped := g.Comsys.ResizeLog(ch)
+		if g.Store != nil && ch.LogPersist {
+			for _, msg := range dropped {
+				g.Store.DeleteChanMessage(msg)
+			}
+		}
+		d.Send(fmt.Sprintf("Channel %s now keeps %d line(s) of history.", ch.Name, n))
+	case lower == "persist":
+		ch.LogPersist = true
+		if g.Store != nil {
+			for _, msg := range g.Comsys.Recall(ch.Name, 0) {
+				g.Store.PutChanMessage(&msg)
+			}
+		}
+		d.Send(fmt.Sprintf("Channel %s history will be saved.", ch.Name))
+	case lower == "nopersist":
+		ch.LogPersist = false
+		if g.Store != nil {
+			g.Store.DeleteChanLog(ch.Name)
+		}
+		d.Send(fmt.Sprintf("Channel %s history will not be saved.", ch.Name))
 	default:
-		d.Send("Unknown option. Options: description <text>, header <text>, public, private, loud, quiet")
+		d.Send("Unknown option. Options: description <text>, header <text>, public, private, loud, quiet, log <lines>, persist, nopersist, charge <pennies>, titles, notitles")
 		return
 	}
 	if g.Store != nil {
@@ -441,23 +553,15 @@ func cmdCinfo(g *Game, d *Descriptor, args string, _ []string) {
 	d.Send(fmt.Sprintf("  Description: %s", ch.Description))
 	d.Send(fmt.Sprintf("  Header:      %s", ch.Header))
 	d.Send(fmt.Sprintf("  Messages:    %d", ch.NumSent))
-	// Flags
-	var flags []string
-	if ch.Flags&gamedb.ChanPublic != 0 {
-		flags = append(flags, "Public")
-	} else {
-		flags = append(flags, "Private")
-	}
-	if ch.Flags&gamedb.ChanLoud != 0 {
-		flags = append(flags, "Loud")
-	}
-	if ch.Flags&gamedb.ChanObject != 0 {
-		flags = append(flags, "Objects")
-	}
-	if ch.Flags&gamedb.ChanNoTitles != 0 {
-		flags = append(flags, "NoTitles")
+	d.Send(fmt.Sprintf("  Flags:       %s", channelFlagString(ch)))
+	switch {
+	case ch.LogLength <= 0:
+		d.Send("  History:     (none)")
+	case ch.LogPersist:
+		d.Send(fmt.Sprintf("  History:     %d lines, saved", ch.LogLength))
+	default:
+		d.Send(fmt.Sprintf("  History:     %d lines", ch.LogLength))
 	}
-	d.Send(fmt.Sprintf("  Flags:       %s", strings.Join(flags, " ")))
 	// Locks
 	joinLock := ch.JoinLock
 	if joinLock == "" {
@@ -478,6 +582,24 @@ func cmdCinfo(g *Game, d *Descriptor, args string, _ []string) {
 	if ch.Charge > 0 || ch.ChargeCollected > 0 {
 		d.Send(fmt.Sprintf("  Charge:      %d (collected: %d)", ch.Charge, ch.ChargeCollected))
 	}
+	if g.IRC != nil {
+		if remote, ok := g.IRC.toIRC[strings.ToLower(ch.Name)]; ok {
+			state := "down"
+			if g.IRC.Connected() {
+				state = "up"
+			}
+			d.Send(fmt.Sprintf("  IRC bridge:  %s (link %s)", remote, state))
+		}
+	}
+	if g.Discord != nil {
+		if remote, ok := g.Discord.toDiscord[strings.ToLower(ch.Name)]; ok {
+			how := "outbound"
+			if _, in := g.Discord.inbound[remote]; in {
+				how = "two-way"
+			}
+			d.Send(fmt.Sprintf("  Discord:     %s (%s)", discordTargetName(remote), how))
+		}
+	}
 	// Subscriber count
 	subs := g.Comsys.ChannelSubscribers(ch.Name)
 	d.Send(fmt.Sprintf("  Subscribers: %d", len(subs)))
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// passesChannelLock reports whether player passes one of ch's locks. The
+// owner and those with Comm_All pass every lock, as does anyone when the
+// lock is unset. Locks are evaluated as the channel's owner.
+func (g *Game) passesChannelLock(player gamedb.DBRef, ch *gamedb.Channel, lock string) bool {
+	if lock == "" || ch.Owner == player || CommAll(g, player) {
+		return true
+	}
+	return g.EvalLockStr(ch.Owner, ch.Owner, player, lock)
+}
+
+// channelHidden reports whether member is hidden from viewer on channel
+// listings: hidden players are, except from themselves and those who can
+// see all.
+func (g *Game) channelHidden(viewer, member gamedb.DBRef) bool {
+	return !SeeAll(g, viewer) && HiddenFrom(g, viewer, member)
+}
+
+// chargeToJoin collects ch's joining charge from player, paying it to the
+// channel's owner. The owner and Comm_All members join free.
+func (g *Game) chargeToJoin(d *Descriptor, ch *gamedb.Channel) bool {
+	if ch.Charge <= 0 || ch.Owner == d.Player || CommAll(g, d.Player) {
+		return true
+	}
+	if !g.payFor(d.Player, ch.Charge) {
+		d.Send(fmt.Sprintf("It costs %d pennies to join channel %s.", ch.Charge, ch.Name))
+		return false
+	}
+	ch.ChargeCollected += ch.Charge
+	if owner, ok := g.DB.Objects[ResolveOwner(g, ch.Owner)]; ok {
+		owner.Pennies += ch.Charge
+		g.PersistObject(owner)
+	}
+	if g.Store != nil {
+		g.Store.PutChannel(ch)
+	}
+	d.Send(fmt.Sprintf("You pay %d pennies to join channel %s.", ch.Charge, ch.Name))
+	return true
+}
+
+// announceChannels tells player's LOUD channels that player has connected
+// or disconnected. Hidden wizards aren't announced, and members who have
+// muted the channel don't hear it.
+func (g *Game) announceChannels(player gamedb.DBRef, what string) {
+	if g.Comsys == nil {
+		return
+	}
+	if Hidden(g, player) {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, ca := range g.Comsys.PlayerAliases(player) {
+		key := strings.ToLower(ca.Channel)
+		ch := g.Comsys.GetChannel(ca.Channel)
+		if seen[key] || ch == nil || ch.Flags&gamedb.ChanLoud == 0 {
+			continue
+		}
+		seen[key] = true
+		g.sendToChannel(ch.Name, player, fmt.Sprintf("%s %s has %s.", channelHeader(ch), g.PlayerName(player), what),
+			func(ca *gamedb.ChanAlias) bool { return ca.Muted })
+	}
+}
+
+// cmdClock implements @clock[/join|/transmit|/receive] <channel>[=<lock>]:
+// who may join, speak on, or hear a channel. Without a lock, the lock is
+// cleared.
+func cmdClock(g *Game, d *Descriptor, args string, switches []string) {
+	if g.Comsys == nil {
+		d.Send("The channel system is not enabled.")
+		return
+	}
+	name, lock, _ := strings.Cut(args, "=")
+	name, lock = strings.TrimSpace(name), strings.TrimSpace(lock)
+	ch := g.Comsys.GetChannel(name)
+	if ch == nil {
+		d.Send(fmt.Sprintf("Channel %q not found.", name))
+		return
+	}
+	if ch.Owner != d.Player && !CommAll(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	if lock != "" && ParseBoolExp(g, d.Player, lock) == nil {
+		d.Send("I don't understand that key.")
+		return
+	}
+	kind, field := "join", &ch.JoinLock
+	switch {
+	case HasSwitch(switches, "transmit"):
+		kind, field = "transmit", &ch.TransLock
+	case HasSwitch(switches, "receive"):
+		kind, field = "receive", &ch.RecvLock
+	}
+	*field = lock
+	if g.Store != nil {
+		g.Store.PutChannel(ch)
+	}
+	if lock == "" {
+		d.Send(fmt.Sprintf("Channel %s %s lock cleared.", ch.Name, kind))
+	} else {
+		d.Send(fmt.Sprintf("Channel %s %s lock set.", ch.Name, kind))
+	}
+}
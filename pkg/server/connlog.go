@@ -0,0 +1,249 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	aLast      = 30  // A_LAST — time of last login
+	aLastSite  = 88  // A_LASTSITE — site of last login
+	aLastIP    = 218 // A_LASTIP — address of last login
+	aLoginData = 84  // A_LOGINDATA — "#<good>;<bad>;<new bad>;" login totals
+
+	// maxConnHistory is how many sessions are kept per player.
+	maxConnHistory = 10
+
+	// lastTimeFormat is the ctime() layout C TinyMUSH stores in A_LAST.
+	lastTimeFormat = "Mon Jan _2 15:04:05 2006"
+)
+
+// ColdObject returns the policy lazy loading (lazy_load) uses to decide
+// which objects' attributes can stay on disk until wanted: GOING objects
+// and garbage, and players who haven't connected for idle (0 = never
+// count players as cold). It runs while the database loads, before the
+// game exists, so it reads A_LAST directly.
+func ColdObject(idle time.Duration) func(*gamedb.Object) bool {
+	cutoff := time.Now().Add(-idle)
+	return func(obj *gamedb.Object) bool {
+		if obj.IsGoing() || obj.ObjType() == gamedb.TypeGarbage {
+			return true
+		}
+		if idle <= 0 || obj.ObjType() != gamedb.TypePlayer {
+			return false
+		}
+		for _, attr := range obj.Attrs {
+			if attr.Number == aLast {
+				last, err := time.ParseInLocation(lastTimeFormat, eval.StripAttrPrefix(attr.Value), time.Local)
+				return err == nil && last.Before(cutoff)
+			}
+		}
+		return false
+	}
+}
+
+// loginTotals parses the C TinyMUSH A_LOGINDATA counters.
+func (g *Game) loginTotals(player gamedb.DBRef) []int {
+	totals := []int{0, 0, 0}
+	fields := strings.Split(strings.TrimPrefix(g.GetAttrText(player, aLoginData), "#"), ";")
+	for i := 0; i < len(totals) && i < len(fields); i++ {
+		totals[i], _ = strconv.Atoi(fields[i])
+	}
+	return totals
+}
+
+// loginCount returns how many times player has connected.
+func (g *Game) loginCount(player gamedb.DBRef) int {
+	return g.loginTotals(player)[0]
+}
+
+// countLogin adds a successful login to player's A_LOGINDATA totals.
+func (g *Game) countLogin(player gamedb.DBRef) {
+	t := g.loginTotals(player)
+	g.SetAttr(player, aLoginData, fmt.Sprintf("#%d;%d;%d;", t[0]+1, t[1], t[2]))
+}
+
+// ConnLog holds each player's recent connection history.
+type ConnLog struct {
+	mu   sync.Mutex
+	recs map[gamedb.DBRef][]*gamedb.ConnRecord
+}
+
+// NewConnLog creates an empty connection history.
+func NewConnLog() *ConnLog {
+	return &ConnLog{recs: make(map[gamedb.DBRef][]*gamedb.ConnRecord)}
+}
+
+// Load replaces the history with records read from storage.
+func (cl *ConnLog) Load(recs []gamedb.ConnRecord) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.recs = make(map[gamedb.DBRef][]*gamedb.ConnRecord)
+	for i := range recs {
+		rec := recs[i]
+		cl.recs[rec.Player] = append(cl.recs[rec.Player], &rec)
+	}
+	for player, list := range cl.recs {
+		if len(list) > maxConnHistory {
+			cl.recs[player] = list[len(list)-maxConnHistory:]
+		}
+	}
+}
+
+// add appends a session and returns the records trimmed to make room.
+func (cl *ConnLog) add(rec *gamedb.ConnRecord) []*gamedb.ConnRecord {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	list := append(cl.recs[rec.Player], rec)
+	var dropped []*gamedb.ConnRecord
+	if len(list) > maxConnHistory {
+		dropped = list[:len(list)-maxConnHistory]
+		list = list[len(list)-maxConnHistory:]
+	}
+	cl.recs[rec.Player] = list
+	return dropped
+}
+
+// find returns the player's session that started at connect, or nil.
+func (cl *ConnLog) find(player gamedb.DBRef, connect time.Time) *gamedb.ConnRecord {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	for _, rec := range cl.recs[player] {
+		if rec.Connect.Equal(connect) {
+			return rec
+		}
+	}
+	return nil
+}
+
+// History returns copies of a player's sessions, newest first.
+func (cl *ConnLog) History(player gamedb.DBRef) []gamedb.ConnRecord {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	list := cl.recs[player]
+	out := make([]gamedb.ConnRecord, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		out = append(out, *list[i])
+	}
+	return out
+}
+
+// noteLogin records the start of d's session: it opens a history record and
+// updates LAST, LASTSITE and LASTIP. It returns the "Last connect was
+// from..." line for the previous login, or "" if there wasn't one.
+func (g *Game) noteLogin(d *Descriptor) string {
+	now := time.Now()
+	host := hostAddr(d.Addr)
+	var lastLine string
+	if site := g.GetAttrText(d.Player, aLastSite); site != "" {
+		lastLine = fmt.Sprintf("Last connect was from %s on %s.", site, g.GetAttrText(d.Player, aLast))
+	}
+	if last, err := time.ParseInLocation(lastTimeFormat, g.GetAttrText(d.Player, aLast), time.Local); err == nil &&
+		last.Format("2006-01-02") == now.Format("2006-01-02") {
+		g.markPaid(d.Player, now)
+	}
+	g.payday(d.Player, now)
+	g.SetAttr(d.Player, aLast, now.Format(lastTimeFormat))
+	g.SetAttr(d.Player, aLastSite, host)
+	g.SetAttr(d.Player, aLastIP, host)
+	g.countLogin(d.Player)
+
+	d.LoginTime = now
+	g.restoreDoing(d)
+	if g.isSuspect(d.Player) {
+		g.reportSuspect(d.Player, "connect", fmt.Sprintf("has connected from %s.", host))
+	}
+	if g.ConnLog == nil {
+		return lastLine
+	}
+	rec := &gamedb.ConnRecord{Player: d.Player, Connect: now, Addr: host}
+	dropped := g.ConnLog.add(rec)
+	if g.Store != nil {
+		if err := g.Store.PutConnRecord(rec); err != nil {
+			Logf(LogBugs, LevelError, "persist connection record for #%d: %v", d.Player, err)
+		}
+		for _, old := range dropped {
+			g.Store.DeleteConnRecord(old)
+		}
+	}
+	return lastLine
+}
+
+// noteLogout closes d's history record with the disconnect time and reason.
+func (g *Game) noteLogout(d *Descriptor) {
+	if d.LoginTime.IsZero() {
+		return
+	}
+	connect := d.LoginTime
+	d.LoginTime = time.Time{}
+	reason := d.DisconnectReason
+	if reason == "" {
+		reason = "disconnected"
+	}
+	if g.isSuspect(d.Player) {
+		g.reportSuspect(d.Player, "disconnect", fmt.Sprintf("has disconnected (%s).", reason))
+	}
+	if g.ConnLog == nil {
+		return
+	}
+	rec := g.ConnLog.find(d.Player, connect)
+	if rec == nil {
+		return
+	}
+	rec.Disconnect = time.Now()
+	rec.Reason = reason
+	if g.Store != nil {
+		if err := g.Store.PutConnRecord(rec); err != nil {
+			Logf(LogBugs, LevelError, "persist connection record for #%d: %v", d.Player, err)
+		}
+	}
+}
+
+// cmdLast implements @last [<player>]: a player's recent connections.
+// Wizards may look at anyone; players only at themselves.
+func cmdLast(g *Game, d *Descriptor, args string, _ []string) {
+	target := d.Player
+	if name := strings.TrimSpace(args); name != "" {
+		target = LookupPlayer(g.DB, name)
+		if target == gamedb.Nothing {
+			d.Send("No such player.")
+			return
+		}
+	}
+	if target != d.Player && !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	var history []gamedb.ConnRecord
+	if g.ConnLog != nil {
+		history = g.ConnLog.History(target)
+	}
+	if len(history) == 0 {
+		if site := g.GetAttrText(target, aLastSite); site != "" {
+			d.Send(fmt.Sprintf("%s last connected from %s on %s.", g.PlayerName(target), site, g.GetAttrText(target, aLast)))
+		} else {
+			d.Send(fmt.Sprintf("%s has no connection history.", g.PlayerName(target)))
+		}
+		return
+	}
+
+	d.Send(fmt.Sprintf("Connection history for %s(#%d):", g.PlayerName(target), target))
+	d.Send(fmt.Sprintf("%-24s  %-24s  %9s  %-20s  %s", "Connected", "Disconnected", "Duration", "From", "Reason"))
+	for _, rec := range history {
+		off, dur, reason := "still connected", FormatConnTime(rec.Duration()), rec.Reason
+		if !rec.Disconnect.IsZero() {
+			off = rec.Disconnect.Format(lastTimeFormat)
+		} else if len(g.Conns.GetByPlayer(target)) == 0 {
+			// The server stopped without closing the session.
+			off, dur, reason = "unknown", "?", "crash"
+		}
+		d.Send(fmt.Sprintf("%-24s  %-24s  %9s  %-20s  %s", rec.Connect.Format(lastTimeFormat), off,
+			dur, rec.Addr, reason))
+	}
+}
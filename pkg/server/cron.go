@@ -0,0 +1,409 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const aDaily = 204 // A_DAILY — run once a day at events_daily_hour
+
+// cronAliases are shorthand timestrings accepted in place of five fields.
+var cronAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// cronSpec is a parsed timestring: one bit per allowed value in each field.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// cronFields are the five timestring fields and their ranges. Day of week
+// allows 7 as another name for Sunday.
+var cronFields = []struct {
+	name   string
+	lo, hi int
+}{
+	{"minute", 0, 59}, {"hour", 0, 23}, {"day of month", 1, 31}, {"month", 1, 12}, {"day of week", 0, 7},
+}
+
+// parseCronSpec parses a five-field timestring or an alias.
+func parseCronSpec(s string) (*cronSpec, error) {
+	s = strings.TrimSpace(s)
+	if alias, ok := cronAliases[strings.ToLower(s)]; ok {
+		s = alias
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, errors.New("a timestring has five fields: minute hour day-of-month month day-of-week")
+	}
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFields[i].lo, cronFields[i].hi)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s field %q: %v", cronFields[i].name, f, err)
+		}
+		bits[i] = b
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &cronSpec{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domStar: fields[2] == "*", dowStar: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a comma-separated list of *, n or n-m, each
+// optionally followed by /step.
+func parseCronField(f string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(f, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step %q", stepStr)
+			}
+			step = n
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("%q is not a number", a)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("%q is not a number", b)
+				}
+			} else if hasStep {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return 0, fmt.Errorf("out of range %d-%d", lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// matches reports whether t falls in the spec. As in Unix cron, when both
+// day of month and day of week are restricted, either one matching will do.
+func (c *cronSpec) matches(t time.Time) bool {
+	has := func(bits uint64, v int) bool { return bits&(1<<uint(v)) != 0 }
+	if !has(c.minute, t.Minute()) || !has(c.hour, t.Hour()) || !has(c.month, int(t.Month())) {
+		return false
+	}
+	domOK, dowOK := has(c.dom, t.Day()), has(c.dow, int(t.Weekday()))
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dowOK
+	case c.dowStar:
+		return domOK
+	}
+	return domOK || dowOK
+}
+
+type cronEntry struct {
+	job  gamedb.CronJob
+	spec *cronSpec
+}
+
+// CronTab holds the @cron schedule.
+type CronTab struct {
+	mu     sync.Mutex
+	jobs   []*cronEntry
+	nextID int
+}
+
+// NewCronTab creates an empty schedule.
+func NewCronTab() *CronTab {
+	return &CronTab{nextID: 1}
+}
+
+// Load installs jobs read from the database, skipping any whose spec no
+// longer parses.
+func (ct *CronTab) Load(jobs []gamedb.CronJob) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	for _, job := range jobs {
+		if job.ID >= ct.nextID {
+			ct.nextID = job.ID + 1
+		}
+		spec, err := parseCronSpec(job.Spec)
+		if err != nil {
+			Logf(LogBugs, LevelWarn, "cron job %d (#%d): %v", job.ID, job.Obj, err)
+			continue
+		}
+		ct.jobs = append(ct.jobs, &cronEntry{job: job, spec: spec})
+	}
+}
+
+// add schedules a job, assigning its ID.
+func (ct *CronTab) add(obj gamedb.DBRef, attr int, specStr string, spec *cronSpec) gamedb.CronJob {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	job := gamedb.CronJob{ID: ct.nextID, Obj: obj, Attr: attr, Spec: specStr}
+	ct.nextID++
+	ct.jobs = append(ct.jobs, &cronEntry{job: job, spec: spec})
+	return job
+}
+
+// remove unschedules obj's jobs, only those for attr unless it is -1, and
+// returns them.
+func (ct *CronTab) remove(obj gamedb.DBRef, attr int) []gamedb.CronJob {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	var removed []gamedb.CronJob
+	kept := ct.jobs[:0]
+	for _, e := range ct.jobs {
+		if e.job.Obj == obj && (attr < 0 || e.job.Attr == attr) {
+			removed = append(removed, e.job)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	ct.jobs = kept
+	return removed
+}
+
+// due returns the jobs that run at t.
+func (ct *CronTab) due(t time.Time) []gamedb.CronJob {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	var jobs []gamedb.CronJob
+	for _, e := range ct.jobs {
+		if e.spec.matches(t) {
+			jobs = append(jobs, e.job)
+		}
+	}
+	return jobs
+}
+
+// List returns the jobs for which match returns true, by object then ID.
+func (ct *CronTab) List(match func(gamedb.CronJob) bool) []gamedb.CronJob {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	var jobs []gamedb.CronJob
+	for _, e := range ct.jobs {
+		if match(e.job) {
+			jobs = append(jobs, e.job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Obj != jobs[j].Obj {
+			return jobs[i].Obj < jobs[j].Obj
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// dailyHour returns events_daily_hour.
+func (g *Game) dailyHour() int {
+	if g.Conf == nil {
+		return 7
+	}
+	return g.Conf.EventsDailyHour
+}
+
+// runCron queues everything scheduled for the minute t: @cron jobs, and
+// once a day during events_daily_hour, the @daily of every HAS_DAILY
+// object.
+func (g *Game) runCron(t time.Time) {
+	queued := 0
+	for _, job := range g.Cron.due(t) {
+		if o, ok := g.DB.Objects[job.Obj]; !ok || o.IsGoing() {
+			continue
+		}
+		g.QueueAttrAction(job.Obj, job.Obj, job.Attr, nil)
+		queued++
+	}
+	if day := t.Format("2006-01-02"); t.Hour() == g.dailyHour() && g.lastDaily != day {
+		g.lastDaily = day
+		for ref, obj := range g.DB.Objects {
+			if obj.HasFlag2(gamedb.Flag2HasDaily) && !obj.IsGoing() {
+				g.QueueAttrAction(ref, ref, aDaily, nil)
+				queued++
+			}
+		}
+	}
+	if queued > 0 {
+		g.WakeQueue()
+	}
+}
+
+// StartCron sets HAS_DAILY on objects with a @daily (imported databases
+// may lack it) and starts running the schedule at the top of each minute.
+func (g *Game) StartCron() {
+	for ref, obj := range g.DB.Objects {
+		if !obj.HasFlag2(gamedb.Flag2HasDaily) && g.GetAttrTextDirect(ref, aDaily) != "" {
+			obj.Flags[1] |= gamedb.Flag2HasDaily
+		}
+	}
+	// Don't run today's @daily at startup if the hour has already begun.
+	if now := g.Now(); now.Hour() == g.dailyHour() {
+		g.lastDaily = now.Format("2006-01-02")
+	}
+	go func() {
+		for {
+			next := time.Now().Truncate(time.Minute).Add(time.Minute)
+			time.Sleep(time.Until(next))
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						Logf(LogBugs, LevelError, "PANIC in cron: %v\n%s", r, debug.Stack())
+						g.wizardAlert("Server error: panic in cron: %v", r)
+					}
+				}()
+				// Run for the game clock's minute, which @timewarp may
+				// have moved away from next.
+				g.Do(func() { g.runCron(g.Now().Truncate(time.Minute)) })
+			}()
+		}
+	}()
+}
+
+// parseCronTarget splits <object>[/<attribute>], matching the object and
+// checking that player controls it. attr is -1 if none was given.
+func (g *Game) parseCronTarget(d *Descriptor, s string) (obj gamedb.DBRef, attr int, ok bool) {
+	objStr, attrStr, hasAttr := strings.Cut(strings.TrimSpace(s), "/")
+	obj, attr = g.MatchObject(d.Player, strings.TrimSpace(objStr)), -1
+	if obj == gamedb.Nothing {
+		d.Send("I don't see that here.")
+		return obj, attr, false
+	}
+	if !Controls(g, d.Player, obj) {
+		d.Send("Permission denied.")
+		return obj, attr, false
+	}
+	if hasAttr {
+		if attr = g.ResolveAttrNum(attrStr); attr < 0 {
+			d.Send("No such attribute.")
+			return obj, attr, false
+		}
+	}
+	return obj, attr, true
+}
+
+// cmdCron implements @cron <object>/<attribute> = <timestring>.
+func cmdCron(g *Game, d *Descriptor, args string, _ []string) {
+	target, specStr, hasEq := strings.Cut(args, "=")
+	specStr = strings.TrimSpace(specStr)
+	if !hasEq || specStr == "" {
+		d.Send("Usage: @cron <object>/<attribute> = <timestring>")
+		return
+	}
+	obj, attr, ok := g.parseCronTarget(d, target)
+	if !ok {
+		return
+	}
+	if attr < 0 {
+		d.Send("You must specify an attribute.")
+		return
+	}
+	raw := ""
+	for _, a := range g.DB.Objects[obj].Attributes() {
+		if a.Number == attr {
+			raw = a.Value
+			break
+		}
+	}
+	if !g.CanReadAttrGS(d.Player, obj, attr, raw) {
+		d.Send("Permission denied.")
+		return
+	}
+	spec, err := parseCronSpec(specStr)
+	if err != nil {
+		d.Send("Invalid timestring: " + err.Error() + ".")
+		return
+	}
+	job := g.Cron.add(obj, attr, specStr, spec)
+	if g.Store != nil {
+		if err := g.Store.PutCronJob(&job); err != nil {
+			Logf(LogBugs, LevelError, "persist cron job %d: %v", job.ID, err)
+		}
+	}
+	d.Send("Cron entry added.")
+}
+
+// cmdCrondel implements @crondel <object>[/<attribute>].
+func cmdCrondel(g *Game, d *Descriptor, args string, _ []string) {
+	if strings.TrimSpace(args) == "" {
+		d.Send("Usage: @crondel <object>[/<attribute>]")
+		return
+	}
+	obj, attr, ok := g.parseCronTarget(d, args)
+	if !ok {
+		return
+	}
+	removed := g.Cron.remove(obj, attr)
+	if g.Store != nil {
+		for _, job := range removed {
+			g.Store.DeleteCronJob(job.ID)
+		}
+	}
+	d.Send(fmt.Sprintf("Cron entries removed: %d.", len(removed)))
+}
+
+// cmdCrontab implements @crontab [<object>]. @daily attributes are listed
+// alongside @cron entries.
+func cmdCrontab(g *Game, d *Descriptor, args string, _ []string) {
+	var match func(gamedb.DBRef) bool
+	if strings.TrimSpace(args) != "" {
+		obj, _, ok := g.parseCronTarget(d, args)
+		if !ok {
+			return
+		}
+		match = func(ref gamedb.DBRef) bool { return ref == obj }
+	} else if WizRoy(g, d.Player) || SeeQueue(g, d.Player) {
+		match = func(gamedb.DBRef) bool { return true }
+	} else {
+		owner := ResolveOwner(g, d.Player)
+		match = func(ref gamedb.DBRef) bool { return ResolveOwner(g, ref) == owner }
+	}
+
+	type line struct {
+		obj  gamedb.DBRef
+		text string
+	}
+	var lines []line
+	for _, job := range g.Cron.List(func(job gamedb.CronJob) bool { return match(job.Obj) }) {
+		lines = append(lines, line{job.Obj, fmt.Sprintf("%s(#%d)/%s: %s",
+			g.ObjName(job.Obj), job.Obj, g.queueAttrName(job.Attr), job.Spec)})
+	}
+	daily := fmt.Sprintf("0 %d * * *", g.dailyHour())
+	for ref, obj := range g.DB.Objects {
+		if obj.HasFlag2(gamedb.Flag2HasDaily) && !obj.IsGoing() && match(ref) {
+			lines = append(lines, line{ref, fmt.Sprintf("%s(#%d)/DAILY: %s", g.ObjName(ref), ref, daily)})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].obj < lines[j].obj })
+	for _, l := range lines {
+		d.Send(l.text)
+	}
+	d.Send(fmt.Sprintf("Cron entries: %d.", len(lines)))
+}
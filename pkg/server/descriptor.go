@@ -2,6 +2,7 @@ package server
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"net"
 	"strings"
@@ -45,14 +46,23 @@ type Descriptor struct {
 	IdleTime  time.Duration
 	DoingStr  string // @doing text
 	ProgData  *ProgramData // Active @program state (nil = not programmed)
-	LastRData *eval.RegisterData // Snapshot of q-registers during queue execution (for @program)
 	CmdCount  int    // Total commands entered this session
-	BytesSent int    // Total bytes sent to this connection
-	BytesRecv int    // Total bytes received from this connection
+	BytesSent int    // Total bytes sent to this connection (guarded by mu; see Traffic)
+	BytesRecv int    // Total bytes received from this connection (guarded by mu; see Traffic)
 	Transport TransportType // Transport type (TCP, WebSocket)
 	AutoDark  bool         // Wizard connected dark; cleared on first command input
 	Pueblo    bool         // Client identified as Pueblo-enhanced
 	OOB       *oob.Capabilities // Negotiated OOB protocols (nil = none)
+	Color     eval.ColorDepth   // Client color depth from TTYPE/MTTS negotiation
+	Ansi      bool              // Connected player has the ANSI flag; otherwise color is stripped (guarded by mu)
+	NoBleed   bool              // Connected player has the NO_BLEED flag (guarded by mu)
+	LoginTime time.Time         // When this session logged in (zero = not logged in or already recorded)
+	IdleDark  bool              // Set DARK by idle_wiz_dark; cleared on next input
+	idleWarned time.Time        // When the idle_timeout warning was last sent
+	findMore   *findCursor      // Where @find/more resumes (nil = nothing pending)
+	DisconnectReason string     // Why the session is ending, for @last (quit, booted, ...)
+	OutputPrefix string         // OUTPUTPREFIX: line sent before each command's output
+	OutputSuffix string         // OUTPUTSUFFIX: line sent after each command's output
 
 	// SendFunc overrides the default Send behavior (used by WebSocket transport).
 	// If nil, the default TCP Send is used.
@@ -63,8 +73,22 @@ type Descriptor struct {
 
 	mu        sync.Mutex
 	closed    bool
+
+	// Buffered output (see StartWriter). When outCond is nil, writes go
+	// straight to Conn on the calling goroutine.
+	outCond    *sync.Cond
+	outBuf     []byte
+	outLimit   func() int // Current output_limit in bytes (<= 0 = unbounded)
+	OutFlushed int // Total bytes of pending output discarded by output_limit (guarded by mu)
 }
 
+// outputStallTimeout is how long the writer waits on a client that accepts
+// no output before giving up and dropping the connection.
+const outputStallTimeout = 30 * time.Second
+
+// outputFlushedMsg is inserted where pending output was discarded.
+const outputFlushedMsg = "*** Output Flushed ***\r\n"
+
 // NewDescriptor wraps a net.Conn into a Descriptor.
 func NewDescriptor(id int, conn net.Conn) *Descriptor {
 	now := time.Now()
@@ -78,6 +102,7 @@ func NewDescriptor(id int, conn net.Conn) *Descriptor {
 		ConnTime: now,
 		LastCmd:  now,
 		Retries:  3,
+		Color:    eval.Color16,
 	}
 }
 
@@ -92,13 +117,12 @@ func (d *Descriptor) Send(msg string) {
 	if d.closed {
 		return
 	}
+	msg = d.renderAnsi(msg)
 	// Ensure lines end with \r\n for telnet
 	if !strings.HasSuffix(msg, "\n") {
 		msg += "\r\n"
 	}
-	d.Conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	n, _ := d.Conn.Write([]byte(msg))
-	d.BytesSent += n
+	d.write([]byte(msg))
 }
 
 // SendRaw writes raw bytes to the connection (no newline, no encoding).
@@ -109,9 +133,7 @@ func (d *Descriptor) SendRaw(data []byte) {
 	if d.closed {
 		return
 	}
-	d.Conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	n, _ := d.Conn.Write(data)
-	d.BytesSent += n
+	d.write(data)
 }
 
 // SendNoNewline writes a string without appending a newline.
@@ -121,9 +143,146 @@ func (d *Descriptor) SendNoNewline(msg string) {
 	if d.closed {
 		return
 	}
-	d.Conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	n, _ := d.Conn.Write([]byte(msg))
-	d.BytesSent += n
+	msg = d.renderAnsi(msg)
+	d.write([]byte(msg))
+}
+
+// write queues data for the writer goroutine, or writes it directly if
+// StartWriter was never called. Caller must hold d.mu.
+func (d *Descriptor) write(data []byte) {
+	if d.outCond == nil {
+		d.Conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		n, _ := d.Conn.Write(data)
+		d.BytesSent += n
+		return
+	}
+	d.outBuf = append(d.outBuf, data...)
+	if limit := d.outLimit(); limit > 0 && len(d.outBuf) > limit {
+		d.flushOldest(limit)
+	}
+	d.outCond.Signal()
+}
+
+// flushOldest discards the oldest pending output so that what remains fits
+// within limit, cutting on a line boundary where possible, and marks the
+// gap with a notice. Caller must hold d.mu.
+func (d *Descriptor) flushOldest(limit int) {
+	cut := len(d.outBuf) - limit + len(outputFlushedMsg)
+	if cut > len(d.outBuf) {
+		cut = len(d.outBuf)
+	}
+	if nl := bytes.IndexByte(d.outBuf[cut:], '\n'); nl >= 0 {
+		cut += nl + 1
+	}
+	d.OutFlushed += cut
+	if !bytes.HasPrefix(d.outBuf, []byte(outputFlushedMsg)) {
+		// Log once per overflow episode, not on every trimmed line.
+		Logf(LogBugs, LevelWarn, "[%d] Output flushed: output_limit %d exceeded", d.ID, limit)
+	}
+	rest := make([]byte, 0, len(outputFlushedMsg)+len(d.outBuf)-cut)
+	rest = append(rest, outputFlushedMsg...)
+	rest = append(rest, d.outBuf[cut:]...)
+	d.outBuf = rest
+}
+
+// StartWriter switches the descriptor to buffered, non-blocking output.
+// Sends append to a pending buffer drained by a dedicated goroutine, so a
+// slow client never stalls the caller. Pending output beyond limit() bytes
+// is discarded oldest-first (a limit <= 0 means unbounded); a client that
+// accepts nothing for outputStallTimeout is disconnected.
+func (d *Descriptor) StartWriter(limit func() int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.outCond != nil || d.closed {
+		return
+	}
+	d.outCond = sync.NewCond(&d.mu)
+	d.outLimit = limit
+	go d.writeLoop()
+}
+
+// writeLoop drains the pending output buffer until the descriptor closes.
+// Output queued before Close is still delivered before the socket shuts.
+func (d *Descriptor) writeLoop() {
+	d.mu.Lock()
+	for {
+		for len(d.outBuf) == 0 && !d.closed {
+			d.outCond.Wait()
+		}
+		if len(d.outBuf) == 0 {
+			break
+		}
+		data := d.outBuf
+		d.outBuf = nil
+		timeout := outputStallTimeout
+		if d.closed {
+			timeout = 5 * time.Second
+		}
+		d.mu.Unlock()
+
+		d.Conn.SetWriteDeadline(time.Now().Add(timeout))
+		n, err := d.Conn.Write(data)
+
+		d.mu.Lock()
+		d.BytesSent += n
+		if err != nil {
+			Logf(LogConnections, LevelInfo, "[%d] Write failed, dropping connection: %v", d.ID, err)
+			d.closed = true
+			d.outBuf = nil
+			break
+		}
+	}
+	d.mu.Unlock()
+	d.Conn.Close()
+}
+
+// PendingOutput returns the number of bytes queued but not yet written.
+func (d *Descriptor) PendingOutput() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.outBuf)
+}
+
+// Traffic is a snapshot of a descriptor's byte counts.
+type Traffic struct {
+	Recv    int // Received from the client
+	Sent    int // Written to the client
+	Pending int // Output queued but not yet written
+	Flushed int // Output discarded by output_limit
+}
+
+// Traffic returns the descriptor's byte counts. The writer goroutine
+// updates them, so they are only read this way.
+func (d *Descriptor) Traffic() Traffic {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return Traffic{Recv: d.BytesRecv, Sent: d.BytesSent, Pending: len(d.outBuf), Flushed: d.OutFlushed}
+}
+
+// noteReceived counts n bytes of input from the client.
+func (d *Descriptor) noteReceived(n int) {
+	d.mu.Lock()
+	d.BytesRecv += n
+	d.mu.Unlock()
+}
+
+// renderAnsi adapts the ANSI escapes in msg to this connection. Output to a
+// connected player without the ANSI flag is stripped of color; otherwise
+// extended colors are downgraded to what the client negotiated. NO_BLEED
+// follows every ANSI normal with an explicit white for clients that let
+// the previous color bleed past a reset.
+func (d *Descriptor) renderAnsi(msg string) string {
+	if strings.IndexByte(msg, '\033') < 0 {
+		return msg
+	}
+	if d.State == ConnConnected && !d.Ansi {
+		return eval.StripAnsi(msg)
+	}
+	msg = eval.DowngradeAnsi(msg, d.Color)
+	if d.NoBleed {
+		msg = strings.ReplaceAll(msg, "\033[0m", "\033[0m\033[37m")
+	}
+	return msg
 }
 
 // Close shuts down the connection.
@@ -132,6 +291,12 @@ func (d *Descriptor) Close() {
 	defer d.mu.Unlock()
 	if !d.closed {
 		d.closed = true
+		if d.outCond != nil {
+			// The writer flushes what is pending, then closes Conn.
+			d.Conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			d.outCond.Signal()
+			return
+		}
 		d.Conn.Close()
 	}
 }
@@ -192,9 +357,10 @@ func (nullConn) SetReadDeadline(time.Time) error   { return nil }
 func (nullConn) SetWriteDeadline(time.Time) error  { return nil }
 
 // MakeObjDescriptor creates a synthetic Descriptor for a non-connected object.
-// Output is discarded (STARTUP commands don't need visible output).
+// Output is discarded (STARTUP commands don't need visible output), except
+// that a PUPPET's is relayed to its owner.
 func (g *Game) MakeObjDescriptor(player gamedb.DBRef) *Descriptor {
-	return &Descriptor{
+	d := &Descriptor{
 		ID:       -1,
 		Conn:     nullConn{},
 		State:    ConnConnected,
@@ -203,6 +369,10 @@ func (g *Game) MakeObjDescriptor(player gamedb.DBRef) *Descriptor {
 		ConnTime: time.Now(),
 		LastCmd:  time.Now(),
 	}
+	if obj, ok := g.DB.Objects[player]; ok && obj.HasFlag(gamedb.FlagPuppet) {
+		d.SendFunc = func(msg string) { g.puppetHeard(player, msg, true) }
+	}
+	return d
 }
 
 // ConnManager tracks all active connections.
@@ -213,6 +383,10 @@ type ConnManager struct {
 	byPlayer    map[gamedb.DBRef][]*Descriptor // player -> connections (multi-login)
 	EventBus    *events.Bus                    // Event bus for pub/sub (nil = disabled)
 	PeakPlayers int                            // Historical peak connected player count
+
+	// AnsiFlags reports a player's ANSI and NO_BLEED flags; consulted on
+	// login to set the descriptor's color handling (nil = no color).
+	AnsiFlags func(player gamedb.DBRef) (ansi, noBleed bool)
 }
 
 // NewConnManager creates a new connection manager.
@@ -260,6 +434,12 @@ func (cm *ConnManager) Login(d *Descriptor, player gamedb.DBRef) {
 	defer cm.mu.Unlock()
 	d.State = ConnConnected
 	d.Player = player
+	if cm.AnsiFlags != nil {
+		ansi, noBleed := cm.AnsiFlags(player)
+		d.mu.Lock()
+		d.Ansi, d.NoBleed = ansi, noBleed
+		d.mu.Unlock()
+	}
 	cm.byPlayer[player] = append(cm.byPlayer[player], d)
 
 	// Track peak connected players (unique players, not connections)
@@ -390,10 +570,20 @@ func MakeEvalContextWithGame(g *Game, player gamedb.DBRef, registerFn func(*eval
 	if g.Conf != nil {
 		ctx.MudName = g.Conf.MudName
 		ctx.FuncInvkLim = g.Conf.FunctionInvocationLimit
+		ctx.FuncNestLim = g.Conf.FunctionRecursionLimit
+		ctx.CPULim = time.Duration(g.Conf.FunctionCPULimit) * time.Second
+		ctx.FloatPrecision = g.Conf.FloatPrecision
+		ctx.StructLim = g.Conf.StructLimit
+		ctx.InstanceLim = g.Conf.InstanceLimit
+		ctx.RegisterLim = g.Conf.RegisterLimit
+		ctx.TraceTopDown = g.Conf.TraceTopdown
+		ctx.TraceLim = g.Conf.TraceOutputLimit
+		ctx.CIsCommand = g.Conf.CIsCommand
 	}
 	if registerFn != nil {
 		registerFn(ctx)
 	}
+	g.ApplyFuncAliases(ctx)
 	applyGameFuncs(g, ctx)
 	return ctx
 }
@@ -415,10 +605,20 @@ func MakeEvalContextForObj(g *Game, executor gamedb.DBRef, enactor gamedb.DBRef,
 	if g.Conf != nil {
 		ctx.MudName = g.Conf.MudName
 		ctx.FuncInvkLim = g.Conf.FunctionInvocationLimit
+		ctx.FuncNestLim = g.Conf.FunctionRecursionLimit
+		ctx.CPULim = time.Duration(g.Conf.FunctionCPULimit) * time.Second
+		ctx.FloatPrecision = g.Conf.FloatPrecision
+		ctx.StructLim = g.Conf.StructLimit
+		ctx.InstanceLim = g.Conf.InstanceLimit
+		ctx.RegisterLim = g.Conf.RegisterLimit
+		ctx.TraceTopDown = g.Conf.TraceTopdown
+		ctx.TraceLim = g.Conf.TraceOutputLimit
+		ctx.CIsCommand = g.Conf.CIsCommand
 	}
 	if registerFn != nil {
 		registerFn(ctx)
 	}
+	g.ApplyFuncAliases(ctx)
 	applyGameFuncs(g, ctx)
 	return ctx
 }
@@ -428,9 +628,7 @@ func applyGameFuncs(g *Game, ctx *eval.EvalContext) {
 	if g == nil || g.GameFuncs == nil {
 		return
 	}
-	for name, uf := range g.GameFuncs {
-		ctx.UFunctions[name] = uf
-	}
+	ctx.UFunctions = g.GameFuncs
 }
 
 // FormatIdleTime formats a duration as a human-readable idle time.
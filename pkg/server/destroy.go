@@ -0,0 +1,180 @@
+package server
+
+import (
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// canDestroy reports whether player may destroy target: they control it, or
+// it is DESTROY_OK and they are carrying it.
+func (g *Game) canDestroy(player, target gamedb.DBRef) bool {
+	if g.Controls(player, target) {
+		return true
+	}
+	obj, ok := g.DB.Objects[target]
+	return ok && obj.HasFlag(gamedb.FlagDestroyOK) && obj.Location == player
+}
+
+// safeHome picks somewhere to send ref that isn't avoid: its own home, then
+// the starting home, the starting room, and finally #0.
+func (g *Game) safeHome(ref, avoid gamedb.DBRef) gamedb.DBRef {
+	link := gamedb.Nothing
+	if obj, ok := g.DB.Objects[ref]; ok {
+		link = obj.Link
+	}
+	for _, h := range []gamedb.DBRef{link, g.StartingHome(), g.StartingRoom(), 0} {
+		if h == avoid || h == ref {
+			continue
+		}
+		if hObj, ok := g.DB.Objects[h]; ok && !hObj.IsGoing() && hObj.ObjType() != gamedb.TypeExit {
+			return h
+		}
+	}
+	return gamedb.Nothing
+}
+
+// evict moves ref out of from to a safe home, telling any connected player
+// where they ended up.
+func (g *Game) evict(ref, from gamedb.DBRef, msg string) {
+	obj, ok := g.DB.Objects[ref]
+	if !ok {
+		return
+	}
+	dest := g.safeHome(ref, from)
+	g.RemoveFromContents(from, ref)
+	obj.Location = dest
+	changed := []*gamedb.Object{obj}
+	if dest != gamedb.Nothing {
+		g.AddToContents(dest, ref)
+		changed = append(changed, g.DB.Objects[dest])
+	}
+	g.PersistObjects(changed...)
+	for _, dd := range g.Conns.GetByPlayer(ref) {
+		dd.Send(msg)
+		if dest != gamedb.Nothing {
+			g.ShowArrival(dd, dest)
+		}
+	}
+}
+
+// unlinkExit removes an exit from its source's exit chain. Exits keep their
+// source in the Exits field.
+func (g *Game) unlinkExit(exit gamedb.DBRef) {
+	exitObj, ok := g.DB.Objects[exit]
+	if !ok {
+		return
+	}
+	src, ok := g.DB.Objects[exitObj.Exits]
+	if !ok {
+		return
+	}
+	if src.Exits == exit {
+		src.Exits = exitObj.Next
+	} else {
+		seen := make(map[gamedb.DBRef]bool)
+		for cur := src.Exits; cur != gamedb.Nothing && !seen[cur]; {
+			seen[cur] = true
+			curObj, ok := g.DB.Objects[cur]
+			if !ok {
+				break
+			}
+			if curObj.Next == exit {
+				curObj.Next = exitObj.Next
+				g.PersistObject(curObj)
+				break
+			}
+			cur = curObj.Next
+		}
+	}
+	exitObj.Next = gamedb.Nothing
+	g.PersistObject(src)
+}
+
+// markDestroyed refunds what an object cost to make, flags it GOING and
+// detaches it from the world.
+func (g *Game) markDestroyed(obj *gamedb.Object) {
+	g.destroyRefund(obj)
+	obj.Flags[0] |= gamedb.FlagGoing
+	obj.Location = gamedb.Nothing
+	obj.Next = gamedb.Nothing
+	g.PersistObject(obj)
+	g.purgeStructs(obj.DBRef)
+}
+
+// destroyExit unlinks an exit from its source room and marks it GOING.
+func (g *Game) destroyExit(exit gamedb.DBRef) {
+	obj, ok := g.DB.Objects[exit]
+	if !ok {
+		return
+	}
+	g.unlinkExit(exit)
+	g.markDestroyed(obj)
+}
+
+// destroyThing removes a thing from its location and sends its contents home.
+func (g *Game) destroyThing(thing gamedb.DBRef) {
+	obj, ok := g.DB.Objects[thing]
+	if !ok {
+		return
+	}
+	for _, c := range g.DB.SafeContents(thing) {
+		g.evict(c, thing, "The object you were in has been destroyed.")
+	}
+	for _, e := range g.DB.SafeExits(thing) {
+		g.destroyExit(e)
+	}
+	if obj.Location != gamedb.Nothing {
+		g.RemoveFromContents(obj.Location, thing)
+		if locObj, ok := g.DB.Objects[obj.Location]; ok {
+			g.PersistObject(locObj)
+		}
+	}
+	obj.Contents = gamedb.Nothing
+	g.markDestroyed(obj)
+}
+
+// unlinkFrom clears everything elsewhere that leads to target: exits to it
+// go nowhere, drop-tos to it are removed, and homes move to a safe home.
+func (g *Game) unlinkFrom(target gamedb.DBRef) {
+	for ref, o := range g.DB.Objects {
+		if ref == target || o.IsGoing() {
+			continue
+		}
+		switch {
+		case o.ObjType() == gamedb.TypeExit && o.Location == target:
+			o.Location = gamedb.Nothing
+		case o.ObjType() == gamedb.TypeRoom && o.Link == target:
+			o.Link = gamedb.Nothing
+		case o.Link == target:
+			o.Link = g.safeHome(ref, target)
+		default:
+			continue
+		}
+		g.PersistObject(o)
+	}
+}
+
+// destroyRoom tears down a room that has already been marked GOING: its
+// contents go home, its exits are destroyed, and exits, drop-tos and homes
+// elsewhere that point at it are cleared. The room is left GOING_TWICE.
+func (g *Game) destroyRoom(room gamedb.DBRef) {
+	obj, ok := g.DB.Objects[room]
+	if !ok {
+		return
+	}
+	for _, c := range g.DB.SafeContents(room) {
+		g.evict(c, room, "The floor disappears under your feet, you fall through NOTHINGNESS and then:")
+	}
+	for _, e := range g.DB.SafeExits(room) {
+		if exitObj, ok := g.DB.Objects[e]; ok {
+			exitObj.Next = gamedb.Nothing
+			g.markDestroyed(exitObj)
+		}
+	}
+	obj.Exits = gamedb.Nothing
+	obj.Contents = gamedb.Nothing
+	g.unlinkFrom(room)
+
+	obj.Link = gamedb.Nothing
+	obj.Flags[1] |= gamedb.Flag2GoingTwice
+	g.markDestroyed(obj)
+}
@@ -0,0 +1,377 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	// discordAPI is the base URL of Discord's bot REST API.
+	discordAPI = "https://discord.com/api/v10"
+
+	// discordMessageMax is Discord's limit on message content length.
+	discordMessageMax = 2000
+
+	// discordQueueSize is how many outbound posts may wait for delivery
+	// before new ones are dropped.
+	discordQueueSize = 256
+)
+
+// discordPost is one message waiting to go to Discord.
+type discordPost struct {
+	target  string // webhook URL or channel ID
+	content string
+}
+
+// DiscordRelay relays Comsys channels to Discord, through a webhook or a
+// bot, and posts wizard alerts to an admin channel. With a bot token,
+// messages in Discord channels mapped to discord_inbound channels are
+// polled and appear on the channel, spoken by "<name>@Discord".
+type DiscordRelay struct {
+	g     *Game
+	token string
+	poll  time.Duration
+
+	toDiscord map[string]string // lowercase comsys channel -> webhook URL or channel ID
+	inbound   map[string]string // Discord channel ID -> comsys channel
+	alerts    string            // webhook URL or channel ID for wizard alerts
+
+	// api is the bot REST API base URL; tests replace it.
+	api    string
+	client *http.Client
+	queue  chan discordPost
+
+	mu     sync.Mutex
+	last   map[string]string // Discord channel ID -> newest message ID seen
+	closed bool
+	stop   chan struct{}
+}
+
+// NewDiscordRelay creates a relay from discord_* in the game
+// configuration, or returns nil if nothing is configured.
+func NewDiscordRelay(g *Game) *DiscordRelay {
+	conf := g.Conf
+	if conf == nil || (len(conf.DiscordChannels) == 0 && conf.DiscordAlerts == "") {
+		return nil
+	}
+	r := &DiscordRelay{
+		g:         g,
+		token:     conf.DiscordToken,
+		poll:      time.Duration(conf.DiscordPoll) * time.Second,
+		toDiscord: make(map[string]string),
+		inbound:   make(map[string]string),
+		alerts:    conf.DiscordAlerts,
+		api:       discordAPI,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		queue:     make(chan discordPost, discordQueueSize),
+		last:      make(map[string]string),
+		stop:      make(chan struct{}),
+	}
+	if r.poll <= 0 {
+		r.poll = 5 * time.Second
+	}
+	if r.alerts != "" && !isWebhook(r.alerts) && r.token == "" {
+		Logf(LogBugs, LevelWarn, "discord_alerts: channel IDs need discord_token; alerts disabled")
+		r.alerts = ""
+	}
+	for _, m := range conf.DiscordChannels {
+		local, remote, ok := strings.Cut(m, "=")
+		local, remote = strings.TrimSpace(local), strings.TrimSpace(remote)
+		if !ok || local == "" || remote == "" {
+			Logf(LogBugs, LevelWarn, "discord_channels: ignoring %q, want <channel>=<webhook URL or channel ID>", m)
+			continue
+		}
+		if !isWebhook(remote) && r.token == "" {
+			Logf(LogBugs, LevelWarn, "discord_channels: %q needs discord_token", m)
+			continue
+		}
+		r.toDiscord[strings.ToLower(local)] = remote
+	}
+	for _, local := range conf.DiscordInbound {
+		remote, ok := r.toDiscord[strings.ToLower(local)]
+		if !ok || isWebhook(remote) {
+			Logf(LogBugs, LevelWarn, "discord_inbound: %q isn't mapped to a Discord channel ID", local)
+			continue
+		}
+		r.inbound[remote] = local
+	}
+	if len(r.toDiscord) == 0 && r.alerts == "" {
+		return nil
+	}
+	return r
+}
+
+// isWebhook reports whether target is a webhook URL rather than a
+// channel ID.
+func isWebhook(target string) bool {
+	return strings.HasPrefix(target, "https://") || strings.HasPrefix(target, "http://")
+}
+
+// Start runs the delivery worker and, if any channels are inbound, the
+// poller in the background.
+func (r *DiscordRelay) Start() {
+	go r.work()
+	if len(r.inbound) > 0 {
+		go r.pollLoop()
+	}
+}
+
+// Stop shuts the relay down. Posts still queued are dropped.
+func (r *DiscordRelay) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		r.closed = true
+		close(r.stop)
+	}
+}
+
+// enqueue queues content for target, splitting it to fit Discord's
+// message limit. If the queue is full the post is dropped, so a slow or
+// unreachable Discord never holds up the game.
+func (r *DiscordRelay) enqueue(target, content string) {
+	for content != "" {
+		part := content
+		if len(part) > discordMessageMax {
+			part = part[:discordMessageMax]
+		}
+		content = content[len(part):]
+		select {
+		case r.queue <- discordPost{target: target, content: part}:
+		default:
+			Logf(LogBugs, LevelWarn, "Discord relay queue full; dropping message")
+			return
+		}
+	}
+}
+
+// work delivers queued posts one at a time.
+func (r *DiscordRelay) work() {
+	for {
+		select {
+		case <-r.stop:
+			return
+		case p := <-r.queue:
+			if err := r.deliver(p); err != nil {
+				Logf(LogBugs, LevelWarn, "Discord relay: %v", err)
+			}
+		}
+	}
+}
+
+// deliver sends one post, waiting out a rate limit once if Discord asks.
+func (r *DiscordRelay) deliver(p discordPost) error {
+	body := map[string]any{
+		"content":          p.content,
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+	url := r.api + "/channels/" + p.target + "/messages"
+	if isWebhook(p.target) {
+		url = p.target
+		if r.g.Conf != nil && r.g.Conf.MudName != "" {
+			body["username"] = r.g.Conf.MudName
+		}
+	}
+	data, _ := json.Marshal(body)
+	for attempt := 0; ; attempt++ {
+		resp, err := r.request(http.MethodPost, url, bytes.NewReader(data), !isWebhook(p.target))
+		if err != nil {
+			// The *url.Error would print the webhook URL, token and all.
+			return fmt.Errorf("post to %s: %v", discordTargetName(p.target), errors.Unwrap(err))
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			wait := discordRetryAfter(resp)
+			select {
+			case <-time.After(wait):
+				continue
+			case <-r.stop:
+				return nil
+			}
+		}
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("post to %s: %s", discordTargetName(p.target), resp.Status)
+		}
+		return nil
+	}
+}
+
+// request makes an API request, with the bot token if auth is set.
+func (r *DiscordRelay) request(method, url string, body io.Reader, auth bool) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth {
+		req.Header.Set("Authorization", "Bot "+r.token)
+	}
+	req.Header.Set("User-Agent", "DiscordBot (https://github.com/crystal-mush/gotinymush, 1.0)")
+	return r.client.Do(req)
+}
+
+// discordRetryAfter reads how long a rate-limited request should wait.
+func discordRetryAfter(resp *http.Response) time.Duration {
+	var secs float64
+	fmt.Sscan(resp.Header.Get("Retry-After"), &secs)
+	if secs <= 0 || secs > 60 {
+		secs = 1
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// discordTargetName describes a target for logs without leaking the
+// webhook token.
+func discordTargetName(target string) string {
+	if isWebhook(target) {
+		return "webhook"
+	}
+	return "channel " + target
+}
+
+// pollLoop fetches new messages from the inbound channels every poll
+// interval, backing off while Discord is failing.
+func (r *DiscordRelay) pollLoop() {
+	wait := r.poll
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(wait):
+		}
+		if err := r.pollOnce(); err != nil {
+			Logf(LogConnections, LevelWarn, "Discord relay poll: %v", err)
+			if wait *= 2; wait > ircBackoffMax {
+				wait = ircBackoffMax
+			}
+		} else {
+			wait = r.poll
+		}
+	}
+}
+
+// discordMessage is the part of a Discord message object the relay uses.
+type discordMessage struct {
+	ID        string `json:"id"`
+	Content   string `json:"content"`
+	WebhookID string `json:"webhook_id"`
+	Author    struct {
+		Username   string `json:"username"`
+		GlobalName string `json:"global_name"`
+		Bot        bool   `json:"bot"`
+	} `json:"author"`
+}
+
+// pollOnce fetches and injects messages posted since the last poll. The
+// first poll of a channel only notes where it stands, so old history
+// isn't replayed.
+func (r *DiscordRelay) pollOnce() (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			Logf(LogBugs, LevelError, "PANIC in Discord relay: %v\n%s", p, debug.Stack())
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	for remote, local := range r.inbound {
+		r.mu.Lock()
+		after := r.last[remote]
+		r.mu.Unlock()
+		url := r.api + "/channels/" + remote + "/messages?limit=50"
+		if after == "" {
+			url = r.api + "/channels/" + remote + "/messages?limit=1"
+		} else {
+			url += "&after=" + after
+		}
+		resp, err := r.request(http.MethodGet, url, nil, true)
+		if err != nil {
+			return err
+		}
+		var msgs []discordMessage
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("read channel %s: %s", remote, resp.Status)
+		}
+		err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&msgs)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("read channel %s: %v", remote, err)
+		}
+		if len(msgs) == 0 {
+			continue
+		}
+		// Messages come newest first.
+		r.mu.Lock()
+		r.last[remote] = msgs[0].ID
+		r.mu.Unlock()
+		if after == "" {
+			continue
+		}
+		for i := len(msgs) - 1; i >= 0; i-- {
+			m := msgs[i]
+			if m.Author.Bot || m.WebhookID != "" || m.Content == "" {
+				continue
+			}
+			name := m.Author.GlobalName
+			if name == "" {
+				name = m.Author.Username
+			}
+			r.g.Do(func() { r.inject(local, name, m.Content) })
+		}
+	}
+	return nil
+}
+
+// inject puts a Discord message on a comsys channel.
+func (r *DiscordRelay) inject(local, name, text string) {
+	if r.g.Comsys == nil {
+		return
+	}
+	ch := r.g.Comsys.GetChannel(local)
+	if ch == nil {
+		return
+	}
+	text = strings.NewReplacer("\r", "", "\n", " ").Replace(text)
+	ch.NumSent++
+	r.g.SendToChannel(ch.Name, gamedb.Nothing, fmt.Sprintf("%s %s@Discord says, \"%s\"", channelHeader(ch), name, text))
+}
+
+// relay sends a channel message to its Discord channel, without the
+// channel header and ANSI color. Messages from outside the game (sender
+// Nothing) aren't sent.
+func (r *DiscordRelay) relay(ch *gamedb.Channel, sender gamedb.DBRef, msg string) {
+	remote, ok := r.toDiscord[strings.ToLower(ch.Name)]
+	if !ok || sender == gamedb.Nothing {
+		return
+	}
+	text := eval.StripAnsi(strings.TrimPrefix(msg, channelHeader(ch)+" "))
+	r.enqueue(remote, fmt.Sprintf("**[%s]** %s", ch.Name, text))
+}
+
+// alert posts a wizard alert to the admin channel.
+func (r *DiscordRelay) alert(text string) {
+	if r.alerts == "" {
+		return
+	}
+	r.enqueue(r.alerts, ":warning: "+eval.StripAnsi(text))
+}
+
+// wizardAlert sends text to the Discord admin channel, if one is set.
+func (g *Game) wizardAlert(format string, args ...any) {
+	if g.Discord != nil {
+		g.Discord.alert(fmt.Sprintf(format, args...))
+	}
+}
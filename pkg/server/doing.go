@@ -0,0 +1,231 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// defaultWhoData is the C TinyMUSH mortal WHO layout.
+var defaultWhoData = []string{"name", "onfor", "idle", "doing"}
+
+// whoColumns maps each who_data column to its row format and header.
+// The doing column's header is the current poll.
+var whoColumns = map[string]struct{ format, header string }{
+	"name":  {"%-16s", "Player Name"},
+	"onfor": {"%9s", "On For"},
+	"idle":  {" %4s", "Idle"},
+	"cmds":  {" %5s", "Cmds"},
+	"doing": {"  %s", ""},
+}
+
+// cmdSetDoing implements @doing: sets the player's WHO message on every
+// connection and remembers it across logins. @doing/header sets the poll
+// shown above the Doing column (Wizard or Poll power); @doing/poll shows it.
+func cmdSetDoing(g *Game, d *Descriptor, args string, switches []string) {
+	switch {
+	case HasSwitch(switches, "poll"):
+		d.Send(fmt.Sprintf("The current poll is: %s", g.Poll()))
+		return
+	case HasSwitch(switches, "header"):
+		if pObj, ok := g.DB.Objects[d.Player]; !Wizard(g, d.Player) && (!ok || !pObj.HasPower(0, gamedb.PowPoll)) {
+			d.Send("Permission denied.")
+			return
+		}
+		g.WhoPoll = args
+		if g.Store != nil {
+			if err := g.Store.PutPoll(args); err != nil {
+				Logf(LogBugs, LevelError, "persist poll: %v", err)
+			}
+		}
+		Logf(LogWizard, LevelInfo, "doing: %s(#%d) set the poll to %q", g.PlayerName(d.Player), d.Player, args)
+		if !HasSwitch(switches, "quiet") {
+			d.Send("Poll set.")
+		}
+		return
+	}
+	for _, dd := range g.Conns.GetByPlayer(d.Player) {
+		dd.DoingStr = args
+	}
+	d.DoingStr = args
+	if g.Doings == nil {
+		g.Doings = make(map[gamedb.DBRef]string)
+	}
+	if args == "" {
+		delete(g.Doings, d.Player)
+	} else {
+		g.Doings[d.Player] = args
+	}
+	if g.Store != nil {
+		if err := g.Store.PutDoing(d.Player, args); err != nil {
+			Logf(LogBugs, LevelError, "persist doing for #%d: %v", d.Player, err)
+		}
+	}
+	if !HasSwitch(switches, "quiet") {
+		d.Send("Set.")
+	}
+}
+
+// LoadDoings restores the persisted @doing messages and poll at startup.
+func (g *Game) LoadDoings(doings map[gamedb.DBRef]string, poll string) {
+	g.Doings = doings
+	g.WhoPoll = poll
+}
+
+// restoreDoing puts a player's remembered @doing on a new connection.
+func (g *Game) restoreDoing(d *Descriptor) {
+	if doing, ok := g.Doings[d.Player]; ok {
+		d.DoingStr = doing
+	}
+}
+
+// Poll returns the WHO poll, "Doing" unless a wizard has set one.
+func (g *Game) Poll() string {
+	if g.WhoPoll == "" {
+		return "Doing"
+	}
+	return g.WhoPoll
+}
+
+// whoData returns the configured mortal WHO columns.
+func (g *Game) whoData() []string {
+	if g.Conf == nil || len(g.Conf.WhoData) == 0 {
+		return defaultWhoData
+	}
+	return g.Conf.WhoData
+}
+
+// whoLine formats one mortal WHO row from per-column values.
+func whoLine(cols []string, vals map[string]string) string {
+	var sb strings.Builder
+	for _, col := range cols {
+		c, ok := whoColumns[col]
+		if !ok {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf(c.format, vals[col]))
+	}
+	return sb.String()
+}
+
+// whoPlayerFlags returns the wizard WHO player flags: D for DARK,
+// U for UNFINDABLE and + for SUSPECT.
+func (g *Game) whoPlayerFlags(player gamedb.DBRef) string {
+	obj, ok := g.DB.Objects[player]
+	if !ok {
+		return ""
+	}
+	var flags string
+	if obj.HasFlag(gamedb.FlagDark) {
+		flags += "D"
+	}
+	if obj.HasFlag2(gamedb.Flag2Unfindable) {
+		flags += "U"
+	}
+	if obj.HasFlag2(gamedb.Flag2Suspect) {
+		flags += "+"
+	}
+	return flags
+}
+
+// whoSiteFlags returns the wizard WHO site flags for a connection: F for a
+// forbidden site, R for a registration site and G for a guest-barred site.
+func (g *Game) whoSiteFlags(addr string) string {
+	var flags string
+	if g.SiteRuleFor(addr, gamedb.SiteForbid) != nil {
+		flags += "F"
+	}
+	if g.SiteRuleFor(addr, gamedb.SiteRegister) != nil {
+		flags += "R"
+	}
+	if g.SiteRuleFor(addr, gamedb.SiteNoGuest) != nil {
+		flags += "G"
+	}
+	return flags
+}
+
+// ShowWho sends the WHO list. Wizards get the C TinyMUSH expanded layout
+// with player and site flags; everyone else gets the who_data columns.
+func (g *Game) ShowWho(d *Descriptor) {
+	isWiz := Wizard(g, d.Player)
+	cols := g.whoData()
+
+	now := g.Now()
+
+	// Header — matches C TinyMUSH dump_users() format
+	if isWiz {
+		d.Send("Player Name        On For Idle   Room    Cmds   Host")
+	} else {
+		hdr := make(map[string]string, len(whoColumns))
+		for col, c := range whoColumns {
+			hdr[col] = c.header
+		}
+		hdr["doing"] = g.Poll()
+		d.Send(whoLine(cols, hdr))
+	}
+
+	type whoEntry struct {
+		name      string
+		onFor     string
+		idle      string
+		doing     string
+		flags     string
+		siteFlags string
+		loc       gamedb.DBRef
+		cmds      int
+		host      string
+	}
+	var entries []whoEntry
+
+	descs := g.Conns.AllDescriptors()
+	for _, dd := range descs {
+		if dd.State != ConnConnected {
+			continue
+		}
+		// Hidden players only show to those who can see them
+		if !isWiz && HiddenFrom(g, d.Player, dd.Player) {
+			continue
+		}
+		e := whoEntry{
+			name:  g.PlayerName(dd.Player),
+			onFor: FormatConnTime(now.Sub(dd.ConnTime)),
+			idle:  FormatIdleTime(now.Sub(dd.LastCmd)),
+			doing: dd.DoingStr,
+			cmds:  dd.CmdCount,
+		}
+		if isWiz {
+			e.flags = g.whoPlayerFlags(dd.Player)
+			e.siteFlags = g.whoSiteFlags(dd.Addr)
+			e.host = hostAddr(dd.Addr)
+			e.loc = g.PlayerLocation(dd.Player)
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+
+	for _, e := range entries {
+		if isWiz {
+			// C format: "%-16s%9s %4s%-3s#%-6d%5d%3s%-25s"
+			d.Send(fmt.Sprintf("%-16s%9s %4s%-3s#%-6d%5d%3s%-25s",
+				e.name, e.onFor, e.idle, e.flags, e.loc, e.cmds, e.siteFlags, e.host))
+		} else {
+			d.Send(whoLine(cols, map[string]string{
+				"name": e.name, "onfor": e.onFor, "idle": e.idle,
+				"cmds": strconv.Itoa(e.cmds), "doing": e.doing,
+			}))
+		}
+	}
+
+	count := len(entries)
+	peak := g.Conns.PeakPlayers
+	if count > peak {
+		peak = count
+	}
+	d.Send(fmt.Sprintf("%d Players logged in, %d record, no maximum.", count, peak))
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// dollarCmd is one $-command attribute, already split into its pattern
+// and the command it runs.
+type dollarCmd struct {
+	pos     int              // position in the object's attributes
+	attr    gamedb.Attribute // as stored, for its flags
+	pattern string
+	command string
+}
+
+// dollarList is an object's $-commands, parsed from the attribute slice
+// it describes. PersistObject drops an object's list when it changes, and
+// a list whose slice has been replaced or edited some other way is
+// rebuilt the next time it's used. Attribute flags aren't cached: they're
+// worked out from attr when matching, so @attribute/access applies at
+// once.
+type dollarList struct {
+	base *gamedb.Attribute // &attrs[0] when parsed
+	n    int               // len(attrs) when parsed
+	cmds []dollarCmd
+}
+
+// dollarCmds returns obj's $-commands, parsing its attributes the first
+// time and after they change.
+func (g *Game) dollarCmds(obj *gamedb.Object) []dollarCmd {
+	attrs := obj.Attributes()
+	var base *gamedb.Attribute
+	if len(attrs) > 0 {
+		base = &attrs[0]
+	}
+	if l := g.dollars[obj.DBRef]; l != nil && l.base == base && l.n == len(attrs) && l.current(attrs) {
+		return l.cmds
+	}
+	l := &dollarList{base: base, n: len(attrs)}
+	for i, attr := range attrs {
+		text := eval.StripAttrPrefix(attr.Value)
+		if !strings.HasPrefix(text, "$") {
+			continue
+		}
+		// Split "$pattern:command"
+		rest := text[1:]
+		colonIdx := findUnescapedColon(rest)
+		if colonIdx < 0 {
+			continue
+		}
+		l.cmds = append(l.cmds, dollarCmd{pos: i, attr: attr, pattern: rest[:colonIdx], command: rest[colonIdx+1:]})
+	}
+	if g.dollars == nil {
+		g.dollars = make(map[gamedb.DBRef]*dollarList)
+	}
+	g.dollars[obj.DBRef] = l
+	return l.cmds
+}
+
+// current reports whether every $-command in l still reads as it did when
+// parsed.
+func (l *dollarList) current(attrs []gamedb.Attribute) bool {
+	for _, c := range l.cmds {
+		if attrs[c.pos] != c.attr {
+			return false
+		}
+	}
+	return true
+}
+
+// forgetDollars drops obj's parsed $-commands after it changes.
+func (g *Game) forgetDollars(obj *gamedb.Object) {
+	delete(g.dollars, obj.DBRef)
+}
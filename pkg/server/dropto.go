@@ -0,0 +1,78 @@
+package server
+
+import (
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// dropToDest returns where thing goes when it's left in room: the room's
+// drop-to (its Link), with "home" meaning thing's own home. It returns
+// Nothing if the room has no usable drop-to.
+func (g *Game) dropToDest(room, thing gamedb.DBRef) gamedb.DBRef {
+	roomObj, ok := g.DB.Objects[room]
+	if !ok || roomObj.ObjType() != gamedb.TypeRoom || roomObj.Link == gamedb.Nothing {
+		return gamedb.Nothing
+	}
+	dest := roomObj.Link
+	if dest == gamedb.Home {
+		dest = g.safeHome(thing, room)
+	}
+	destObj, ok := g.DB.Objects[dest]
+	if !ok || destObj.IsGoing() || dest == room || dest == thing {
+		return gamedb.Nothing
+	}
+	return dest
+}
+
+// immediateDropTo returns where a thing dropped in room goes at once: its
+// drop-to, unless the room is STICKY and holds things until it empties.
+func (g *Game) immediateDropTo(room, thing gamedb.DBRef) gamedb.DBRef {
+	if obj, ok := g.DB.Objects[room]; !ok || obj.HasFlag(gamedb.FlagSticky) {
+		return gamedb.Nothing
+	}
+	return g.dropToDest(room, thing)
+}
+
+// stickyDropTo runs a STICKY room's delayed drop-to once nobody is left
+// to see it: everything still lying there is sent to the drop-to, except
+// STICKY things, which go home. leaving is the player on the way out.
+func (g *Game) stickyDropTo(room, leaving gamedb.DBRef) {
+	roomObj, ok := g.DB.Objects[room]
+	if !ok || roomObj.ObjType() != gamedb.TypeRoom || !roomObj.HasFlag(gamedb.FlagSticky) || roomObj.Link == gamedb.Nothing {
+		return
+	}
+	contents := g.DB.SafeContents(room)
+	for _, c := range contents {
+		if c != leaving && g.occupiesRoom(c) {
+			return
+		}
+	}
+	for _, c := range contents {
+		obj, ok := g.DB.Objects[c]
+		if !ok || c == leaving || obj.ObjType() == gamedb.TypePlayer {
+			continue
+		}
+		dest := g.dropToDest(room, c)
+		if obj.HasFlag(gamedb.FlagSticky) {
+			dest = g.safeHome(c, room)
+		}
+		if dest != gamedb.Nothing {
+			g.Teleport(c, dest)
+		}
+	}
+}
+
+// occupiesRoom reports whether obj keeps a STICKY room's drop-to from
+// running: a connected player, or a puppet whose owner is connected.
+func (g *Game) occupiesRoom(obj gamedb.DBRef) bool {
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	switch {
+	case o.ObjType() == gamedb.TypePlayer:
+		return g.Conns.IsConnected(obj)
+	case o.HasFlag(gamedb.FlagPuppet):
+		return g.Conns.IsConnected(o.Owner)
+	}
+	return false
+}
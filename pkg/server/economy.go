@@ -0,0 +1,215 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// freeMoney reports whether player builds, pages and queues for free:
+// wizards, immortals and holders of the free_money power.
+func (g *Game) freeMoney(player gamedb.DBRef) bool {
+	if Wizard(g, player) {
+		return true
+	}
+	o, ok := g.DB.Objects[player]
+	return ok && (o.HasFlag(gamedb.FlagImmortal) || o.HasPower(0, gamedb.PowFreeMoney))
+}
+
+// payFor charges player's owner cost pennies, as C TinyMUSH's payfor().
+// Wizards, immortals and those with free_money don't pay. It reports
+// false, charging nothing, if the owner can't afford it.
+func (g *Game) payFor(player gamedb.DBRef, cost int) bool {
+	if cost <= 0 || g.freeMoney(player) {
+		return true
+	}
+	owner, ok := g.DB.Objects[ResolveOwner(g, player)]
+	if !ok || owner.Pennies < cost {
+		return false
+	}
+	owner.Pennies -= cost
+	g.PersistObject(owner)
+	return true
+}
+
+// refund gives player's owner back amount pennies taken by payFor, when
+// what they paid for fell through or was undone. Those who paid nothing
+// get nothing back.
+func (g *Game) refund(player gamedb.DBRef, amount int) {
+	if amount <= 0 || g.freeMoney(player) {
+		return
+	}
+	if owner, ok := g.DB.Objects[ResolveOwner(g, player)]; ok {
+		owner.Pennies += amount
+		g.PersistObject(owner)
+	}
+}
+
+// giveTo pays who's owner amount pennies they've earned, as C TinyMUSH's
+// giveto(), but never lifts them past earn_limit. It returns how much was
+// actually paid.
+func (g *Game) giveTo(who gamedb.DBRef, amount int) int {
+	owner, ok := g.DB.Objects[ResolveOwner(g, who)]
+	if !ok || amount <= 0 || g.freeMoney(owner.DBRef) {
+		return 0
+	}
+	if limit := g.earnLimit(); limit > 0 && owner.Pennies+amount > limit {
+		amount = limit - owner.Pennies
+	}
+	if amount <= 0 {
+		return 0
+	}
+	owner.Pennies += amount
+	g.PersistObject(owner)
+	return amount
+}
+
+// earnLimit returns earn_limit, the most a player may earn up to, or 0
+// for no limit.
+func (g *Game) earnLimit() int {
+	if g.Conf == nil {
+		return 0
+	}
+	return g.Conf.EarnLimit
+}
+
+// createCost returns what @create charges when asked to spend cost
+// pennies: create_min_cost if none was given, held between
+// create_min_cost and create_max_cost.
+func (g *Game) createCost(cost int) int {
+	if g.Conf == nil {
+		return 0
+	}
+	if cost < g.Conf.CreateMin {
+		cost = g.Conf.CreateMin
+	}
+	if g.Conf.CreateMax > 0 && cost > g.Conf.CreateMax {
+		cost = g.Conf.CreateMax
+	}
+	return cost
+}
+
+// objectEndowment is the value a thing created for cost pennies is worth,
+// and objectDeposit the reverse: what destroying a thing of that value
+// gives back. As in C TinyMUSH, a thing is worth a fifth of its cost.
+func objectEndowment(cost int) int { return cost / 5 }
+func objectDeposit(value int) int  { return value * 5 }
+
+// digCost, openCost, linkCost and pageCost return dig_cost, open_cost,
+// link_cost and page_cost.
+func (g *Game) digCost() int {
+	if g.Conf == nil {
+		return 0
+	}
+	return g.Conf.DigCost
+}
+
+func (g *Game) openCost() int {
+	if g.Conf == nil {
+		return 0
+	}
+	return g.Conf.OpenCost
+}
+
+func (g *Game) linkCost() int {
+	if g.Conf == nil {
+		return 0
+	}
+	return g.Conf.LinkCost
+}
+
+func (g *Game) pageCost() int {
+	if g.Conf == nil {
+		return 0
+	}
+	return g.Conf.PageCost
+}
+
+// queueDeposit charges obj's owner wait_cost to queue a command, plus,
+// one time in machine_command_cost, a penny that isn't given back. It
+// returns what was charged, which is refunded once the command has run
+// or been halted, or -1 if the owner couldn't pay.
+func (g *Game) queueDeposit(obj gamedb.DBRef) int {
+	if g.Conf == nil || g.freeMoney(obj) {
+		return 0
+	}
+	cost := g.Conf.WaitCost
+	if cost > 0 && g.Conf.MachineCommandCost > 0 && rand.Intn(g.Conf.MachineCommandCost) == 0 {
+		cost++
+	}
+	if !g.payFor(obj, cost) {
+		g.Conns.SendToPlayer(ResolveOwner(g, obj), "Not enough money to queue command.")
+		return -1
+	}
+	return g.Conf.WaitCost
+}
+
+// initQueueDeposits hooks wait_cost charging and refunds into the
+// command queue.
+func (g *Game) initQueueDeposits() {
+	g.Queue.Charge = g.queueDeposit
+	g.Queue.Refund = func(obj gamedb.DBRef, amount int) { g.refund(obj, amount) }
+}
+
+// giveMoney implements give <target>=<amount>. Only those with the
+// steal_money power may give a negative amount, taking it from the
+// target, and mortals can't lift a player past earn_limit.
+func giveMoney(g *Game, d *Descriptor, target gamedb.DBRef, amount int) {
+	giver, ok := g.DB.Objects[d.Player]
+	targetObj, tok := g.DB.Objects[target]
+	if !ok || !tok {
+		return
+	}
+	switch {
+	case amount == 0, amount < 0 && !Wizard(g, d.Player) && !giver.HasPower(0, gamedb.PowSteal):
+		d.Send(fmt.Sprintf("You must specify a positive number of %s.", g.MoneyName(2)))
+		return
+	case amount < 0 && targetObj.Pennies < -amount:
+		d.Send(fmt.Sprintf("%s doesn't have that many %s.", DisplayName(targetObj.Name), g.MoneyName(2)))
+		return
+	case amount > 0 && giver.Pennies < amount:
+		d.Send(fmt.Sprintf("You don't have that many %s.", g.MoneyName(2)))
+		return
+	}
+	if limit := g.earnLimit(); amount > 0 && limit > 0 && !Wizard(g, d.Player) &&
+		targetObj.ObjType() == gamedb.TypePlayer && targetObj.Pennies+amount > limit {
+		d.Send(fmt.Sprintf("That player doesn't need that many %s!", g.MoneyName(2)))
+		return
+	}
+	giver.Pennies -= amount
+	targetObj.Pennies += amount
+	g.PersistObjects(giver, targetObj)
+	if amount < 0 {
+		d.Send(fmt.Sprintf("You take %d %s from %s.", -amount, g.MoneyName(-amount), DisplayName(targetObj.Name)))
+		g.Conns.SendToPlayer(target,
+			fmt.Sprintf("%s takes %d %s from you.", g.PlayerName(d.Player), -amount, g.MoneyName(-amount)))
+		return
+	}
+	d.Send(fmt.Sprintf("You give %d %s to %s.", amount, g.MoneyName(amount), DisplayName(targetObj.Name)))
+	g.Conns.SendToPlayer(target,
+		fmt.Sprintf("%s gives you %d %s.", g.PlayerName(d.Player), amount, g.MoneyName(amount)))
+}
+
+// objectCost returns what an object like obj costs to make: for a thing
+// its deposit, as createCost holds it, for a room dig_cost, and for an
+// exit open_cost, plus link_cost if it leads somewhere.
+func (g *Game) objectCost(obj *gamedb.Object) int {
+	switch obj.ObjType() {
+	case gamedb.TypeThing:
+		return g.createCost(objectDeposit(obj.Pennies))
+	case gamedb.TypeRoom:
+		return g.digCost()
+	case gamedb.TypeExit:
+		if obj.Location != gamedb.Nothing {
+			return g.openCost() + g.linkCost()
+		}
+		return g.openCost()
+	}
+	return 0
+}
+
+// destroyRefund gives obj's owner back what it cost to make.
+func (g *Game) destroyRefund(obj *gamedb.Object) {
+	g.refund(obj.Owner, g.objectCost(obj))
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+)
+
+// smtpSendMail delivers a raw message through an SMTP relay.
+// Replaced in tests so nothing leaves the process.
+var smtpSendMail = smtp.SendMail
+
+// EmailEnabled returns true if an outgoing SMTP relay is configured.
+func (g *Game) EmailEnabled() bool {
+	return g.Conf != nil && g.Conf.SMTPHost != "" && g.Conf.SMTPFrom != ""
+}
+
+// sendEmail sends a plain-text email through the configured SMTP relay.
+// ANSI color is stripped from the body. Blocks until the relay answers,
+// so it is run in a goroutine of its own; it takes the world lock to read
+// the config, so it must not be called with it held.
+func (g *Game) sendEmail(to, subject, body string) error {
+	var conf *GameConf
+	g.Do(func() {
+		if g.EmailEnabled() {
+			conf = g.confCopy()
+		}
+	})
+	if conf == nil {
+		return fmt.Errorf("email is not configured")
+	}
+	addr, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("bad address %q: %w", to, err)
+	}
+	from, err := mail.ParseAddress(conf.SMTPFrom)
+	if err != nil {
+		return fmt.Errorf("bad smtp_from %q: %w", conf.SMTPFrom, err)
+	}
+
+	// Header values must stay on one line.
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(eval.StripAnsi(subject))
+	body = strings.ReplaceAll(eval.StripAnsi(body), "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\n", "\r\n")
+
+	var msg strings.Builder
+	msg.WriteString("From: " + from.String() + "\r\n")
+	msg.WriteString("To: " + addr.String() + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+	msg.WriteString("\r\n")
+
+	port := conf.SMTPPort
+	if port <= 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if conf.SMTPUser != "" {
+		auth = smtp.PlainAuth("", conf.SMTPUser, conf.SMTPPassword, conf.SMTPHost)
+	}
+	relay := net.JoinHostPort(conf.SMTPHost, strconv.Itoa(port))
+	return smtpSendMail(relay, auth, from.Address, []string{addr.Address}, []byte(msg.String()))
+}
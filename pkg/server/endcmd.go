@@ -0,0 +1,70 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
+)
+
+// @end, @break and @assert stop the action list they are part of on a
+// condition, as in TinyMUSH 3.3: @break (@end/break) stops when the
+// condition is true, @assert (@end/assert) when it is false. An action
+// list after = runs in place of the rest of the list. They set g.ended,
+// which ExecuteQueueEntry checks after each command.
+
+func cmdEnd(g *Game, d *Descriptor, args string, switches []string) {
+	endActionList(g, d, args, HasSwitch(switches, "assert"))
+}
+
+func cmdBreak(g *Game, d *Descriptor, args string, _ []string) {
+	endActionList(g, d, args, false)
+}
+
+func cmdAssert(g *Game, d *Descriptor, args string, _ []string) {
+	endActionList(g, d, args, true)
+}
+
+// endActionList is @end typed; queued ones go through handleEndDeferred so
+// the condition is evaluated only once. The action list, if any, runs one
+// command at a time as if typed.
+func endActionList(g *Game, d *Descriptor, args string, assert bool) {
+	cond, action, _ := strings.Cut(args, "=")
+	ctx := MakeEvalContextWithGame(g, d.Player, func(c *eval.EvalContext) {
+		functions.RegisterAll(c)
+	})
+	defer ctx.Release()
+	if functions.IsTrue(ctx.Exec(strings.TrimSpace(cond), eval.EvFCheck|eval.EvEval, nil)) == assert {
+		return
+	}
+	for _, cmd := range splitSemicolonRespectingBraces(stripOuterBraces(strings.TrimSpace(action))) {
+		if cmd = strings.TrimSpace(cmd); cmd != "" {
+			DispatchCommand(g, d, cmd)
+		}
+	}
+	g.ended = true
+}
+
+// handleEndDeferred handles @end, @break and @assert in a queued command,
+// with or without an action list. The condition is evaluated; the action
+// list is kept raw and, if the list ends, run at once with the entry's
+// arguments and registers.
+func (g *Game) handleEndDeferred(ctx *eval.EvalContext, entry *QueueEntry, prefix string, switches []string, lhs, body string) {
+	assert := prefix == "@assert" || (prefix == "@end" && HasSwitch(switches, "assert"))
+	cond := ctx.Exec(lhs, eval.EvFCheck|eval.EvEval, entry.Args)
+	if functions.IsTrue(cond) == assert {
+		return
+	}
+	if body = stripOuterBraces(body); strings.TrimSpace(body) != "" {
+		qe := &QueueEntry{
+			Player:  entry.Player,
+			Cause:   entry.Cause,
+			Caller:  entry.Caller,
+			Command: body,
+			Args:    entry.Args,
+			RData:   ctx.RData,
+		}
+		g.ExecuteQueueEntry(qe)
+	}
+	g.ended = true
+}
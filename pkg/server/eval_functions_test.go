@@ -3,6 +3,7 @@ package server
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/crystal-mush/gotinymush/pkg/eval"
 	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
@@ -622,6 +623,90 @@ func TestFnColumns(t *testing.T) {
 	}
 }
 
+func TestFnTableAlign(t *testing.T) {
+	e := newEvalTestEnv(t)
+	if got := e.eval("[table(a bb ccc dddd,4,10)]"); got != "a    bb\r\nccc  dddd" {
+		t.Errorf("table() = %q", got)
+	}
+	if got := e.eval("[table(a|b|c%rd,3,12,|,|,.)]"); got != "a..|b..|c\r\nd" {
+		t.Errorf("table() with separators = %q", got)
+	}
+	// Color doesn't count toward the width, and is reset before the padding.
+	if got := e.eval("[columns([ansi(r,red)] blue,6,,2)]"); got != "  \033[31mred\033[0m   blue" {
+		t.Errorf("columns() = %q", got)
+	}
+	if got := e.eval("[align(>3 5 <1.,x,one two three,|)]"); got != "  x one   |\r\n    two   |\r\n    three |" {
+		t.Errorf("align() = %q", got)
+	}
+	if got := e.eval("[align(-5 3,ab,c,.,-,/)]"); got != ".ab..-c.." {
+		t.Errorf("align() with filler and separators = %q", got)
+	}
+}
+
+func TestFnMathPrecision(t *testing.T) {
+	e := newEvalTestEnv(t)
+	cases := map[string]string{
+		"[fdiv(10,4)]":        "2.500000",
+		"[round(2.5)]":        "3",
+		"[floor(5.678,2)]":    "5.67",
+		"[ceil(5.123,2)]":     "5.13",
+		"[log(8,2)]":          "3",
+		"[bxor(5,3)]":         "6",
+		"[bnot(0)]":           "-1",
+		"[band(12,bor(4,1))]": "4",
+	}
+	for expr, want := range cases {
+		if got := e.eval(expr); got != want {
+			t.Errorf("%s = %q, want %q", expr, got, want)
+		}
+	}
+	e.ctx.FloatPrecision = 2
+	if got := e.eval("[fdiv(10,3)] [pi()]"); got != "3.33 3.14" {
+		t.Errorf("with float_precision 2 = %q", got)
+	}
+}
+
+func TestFnVectorsAndRegex(t *testing.T) {
+	e := newEvalTestEnv(t)
+	cases := map[string]string{
+		"[vadd(0|0|0,1|2|3,|,-)]":             "1-2-3",
+		"[vmul(1 2 3,2 3 4)]":                 "2 6 12",
+		"[vmul(2,1 2 3)]":                     "2 4 6",
+		"[vmag(3|4,|)]":                       "5",
+		"[regraball(ab ac bc,^a,,_)]":         "ab_ac",
+		"[regedit(test best,%(.%)est,$1ash)]": "tash best",
+		"[regeditall(test best,.est,x)]":      "x x",
+		"[reswitch(cab,^a,A,^c,C,none)]":      "C",
+		"[reswitchall(cab,^c,C,b$,B,none)]":   "CB",
+		"[reswitchi(CAB,^x,X,#$)]":            "CAB",
+	}
+	for expr, want := range cases {
+		if got := e.eval(expr); got != want {
+			t.Errorf("%s = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestFnNamedRegisters(t *testing.T) {
+	e := newEvalTestEnv(t)
+	if got := e.eval("[setq(0,a,hp,10,hpmax,20)]%q<hp>/[r(HPMAX)]/[listq()]/[listq(hp*)]"); got != "10/20/0 hp hpmax/hp hpmax" {
+		t.Errorf("named registers = %q", got)
+	}
+	if got := e.eval("[clearregs(hp 0)][listq()]"); got != "hpmax" {
+		t.Errorf("clearregs(list) = %q", got)
+	}
+	if got := e.eval("[setq(1bad,x)]"); got != "#-1 INVALID GLOBAL REGISTER" {
+		t.Errorf("setq() with a bad name = %q", got)
+	}
+	e.ctx.RegisterLim = 2
+	if got := e.eval("[setq(a1,x)][setq(a2,y)]"); got != "#-1 REGISTER LIMIT EXCEEDED" {
+		t.Errorf("setq() past register_limit = %q", got)
+	}
+	if got := e.eval("[clearq()][setq(a2,y)][listq()]"); got != "a2" {
+		t.Errorf("clearq() = %q", got)
+	}
+}
+
 // --- Side-effect Functions ---
 
 func TestFnPemitNotification(t *testing.T) {
@@ -771,6 +856,46 @@ func TestFnStripansi(t *testing.T) {
 	}
 }
 
+func TestAnsiSubstitutions(t *testing.T) {
+	e := newEvalTestEnv(t)
+	tests := map[string]string{
+		"%xrred%xn":                             "\033[31mred\033[0m",
+		"%xRbg%xn":                              "\033[41mbg\033[0m",
+		"%chbold%cn":                            "\033[1mbold\033[0m",
+		"[ansi(hB,x)]":                          "\033[1m\033[44mx\033[0m",
+		"[stripansi(%x<208>a%x/<#FF0000>b%xn)]": "ab",
+		"[ljust(%xrab%xn,4,.)]":                 "\033[31mab\033[0m..",
+		"[rjust(héllo,7,-=)]":                   "-=héllo",
+		"[center(%xgab%xn,6,*)]":                "**\033[32mab\033[0m**",
+	}
+	for expr, want := range tests {
+		got := e.eval(expr)
+		if got != want {
+			t.Errorf("%s = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestDowngradeAnsi(t *testing.T) {
+	tests := []struct {
+		in    string
+		depth eval.ColorDepth
+		want  string
+	}{
+		{"\033[38;2;255;0;0mx", eval.ColorTrue, "\033[38;2;255;0;0mx"},
+		{"\033[38;2;255;0;0mx", eval.Color256, "\033[38;5;196mx"},
+		{"\033[38;2;255;0;0mx", eval.Color16, "\033[91mx"},
+		{"\033[48;5;21mx", eval.Color16, "\033[44mx"},
+		{"\033[1;38;5;2mx", eval.Color16, "\033[1;32mx"},
+		{"\033[31mx\033[0m", eval.ColorNone, "x"},
+	}
+	for _, tt := range tests {
+		if got := eval.DowngradeAnsi(tt.in, tt.depth); got != tt.want {
+			t.Errorf("DowngradeAnsi(%q, %v) = %q, want %q", tt.in, tt.depth, got, tt.want)
+		}
+	}
+}
+
 // --- Ljust / Rjust / Center ---
 
 func TestFnLjust(t *testing.T) {
@@ -991,3 +1116,142 @@ func TestParentChainAttrInheritance(t *testing.T) {
 		t.Errorf("parent chain attr: get(#2/DESC) = %q, want 'Inherited desc'", got)
 	}
 }
+
+// --- Comsys functions ---
+
+func TestFnComsys(t *testing.T) {
+	e := newEvalTestEnv(t)
+	e.game.Comsys = NewComsys()
+	e.game.Comsys.AddChannel(&gamedb.Channel{Name: "Public", Owner: 1, Flags: gamedb.ChanPublic | gamedb.ChanLoud})
+	e.game.Comsys.AddChannel(&gamedb.Channel{Name: "Staff", Owner: 1})
+	e.game.Comsys.AddAlias(&gamedb.ChanAlias{Player: 1, Channel: "Public", Alias: "pub", Title: "Mighty", IsListening: true})
+	e.game.Comsys.AddAlias(&gamedb.ChanAlias{Player: 3, Channel: "Public", Alias: "p", IsListening: true})
+
+	tests := map[string]string{
+		"[comlist()]":            "Public Staff",
+		"[clist(|)]":             "Public|Staff",
+		"[cwho(Public)]":         "",
+		"[cwhoall(Public)]":      "#1 #3",
+		"[cflags(Public)]":       "Public Loud",
+		"[cflags(Nowhere)]":      "#-1 CHANNEL NOT FOUND",
+		"[comalias(#1)]":         "pub",
+		"[comtitle(#1,pub)]":     "Mighty",
+		"[cominfo(#3,p)]":        "Public",
+		"[cominfo(#3,nope)]":     "#-1 NO SUCH ALIAS",
+		"[cemit(Public,hello)]":  "",
+		"[cemit(Nowhere,hello)]": "#-1 CHANNEL NOT FOUND",
+	}
+	for expr, want := range tests {
+		got := e.eval(expr)
+		if got != want {
+			t.Errorf("comsys: %s = %q, want %q", expr, got, want)
+		}
+	}
+
+	// Bob (#3) is not a wizard: the private Staff channel is hidden,
+	// and he can neither read the Wizard's aliases nor emit to Public.
+	e.ctx.Player = 3
+	bobTests := map[string]string{
+		"[comlist()]":           "Public",
+		"[cwhoall(Staff)]":      "#-1 CHANNEL NOT FOUND",
+		"[comtitle(#1,pub)]":    "#-1 PERMISSION DENIED",
+		"[comalias(me)]":        "p",
+		"[cemit(Public,hello)]": "#-1 PERMISSION DENIED",
+	}
+	for expr, want := range bobTests {
+		got := e.eval(expr)
+		if got != want {
+			t.Errorf("comsys (bob): %s = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestFnMailFunctions(t *testing.T) {
+	e := newEvalTestEnv(t)
+	e.game.Mail = NewMail(0)
+	e.game.Mail.SendMessage(3, []gamedb.DBRef{1}, nil, "Hello", "First message")
+	e.game.Mail.SendMessage(3, []gamedb.DBRef{1}, nil, "Again", "Second message")
+
+	tests := map[string]string{
+		"[mail()]":                  "2",
+		"[mail(1)]":                 "First message",
+		"[mail(Wizard)]":            "0 2 0",
+		"[mail(Wizard,2)]":          "Second message",
+		"[maillist()]":              "1 2",
+		"[maillist(me,0)]":          "1 2",
+		"[maillist(me,3)]":          "",
+		"[mailread(2,subject)]":     "Again",
+		"[mailread(2,from)]":        "#3",
+		"[mailread(9)]":             "#-1 NO SUCH MESSAGE",
+		"[mailsend(Bob,Re,Thanks)]": "",
+		"[mailsend(Nobody,Re)]":     "#-1 NO SUCH PLAYER",
+	}
+	for expr, want := range tests {
+		got := e.eval(expr)
+		if got != want {
+			t.Errorf("mail: %s = %q, want %q", expr, got, want)
+		}
+	}
+
+	// Reading the body marks the message read.
+	if got := e.eval("[mailread(1)]"); got != "First message" {
+		t.Errorf("mailread(1) = %q", got)
+	}
+	if got := e.eval("[mail(Wizard)]"); got != "1 1 0" {
+		t.Errorf("after mailread: mail(Wizard) = %q, want %q", got, "1 1 0")
+	}
+	if got := e.game.MailSubject(3, 1); got != "Re" {
+		t.Errorf("mailsend: Bob's message subject = %q, want %q", got, "Re")
+	}
+
+	// Bob can't read the Wizard's mailbox.
+	e.ctx.Player = 3
+	for expr, want := range map[string]string{
+		"[mail(Wizard)]":     "#-1 PERMISSION DENIED",
+		"[mail(Wizard,1)]":   "#-1 PERMISSION DENIED",
+		"[maillist(Wizard)]": "#-1 PERMISSION DENIED",
+		"[mail(1)]":          "Thanks",
+	} {
+		if got := e.eval(expr); got != want {
+			t.Errorf("mail as Bob: %s = %q, want %q", expr, got, want)
+		}
+	}
+}
+
+func TestFnQueueInspection(t *testing.T) {
+	e := newEvalTestEnv(t)
+	q := e.game.Queue
+	q.Add(&QueueEntry{Player: 2, Cause: 1, Command: "think one"})
+	q.AddWait(&QueueEntry{Player: 2, Cause: 1, Command: "think two", WaitUntil: time.Now().Add(time.Hour)})
+	q.AddSemaphore(&QueueEntry{Player: 3, Cause: 3, Command: "think three", SemObj: 3, SemAttr: gamedb.A_SEMAPHORE})
+
+	tests := map[string]string{
+		"[lps(#2)]":            "1 2",
+		"[lps()]":              "1 2 3",
+		"[qentries(#2)]":       "2",
+		"[qentries(#3)]":       "1",
+		"[semcount(#3)]":       "1",
+		"[semcount(#3/VA)]":    "0",
+		"[semcount(#3/BOGUS)]": "#-1 NO SUCH ATTRIBUTE",
+		"[qentries(#99)]":      "#-1 NOT FOUND",
+	}
+	for expr, want := range tests {
+		if got := e.eval(expr); got != want {
+			t.Errorf("queue: %s = %q, want %q", expr, got, want)
+		}
+	}
+
+	// Bob sees only his own entries and can't inspect the Wizard's objects
+	// until he has see_queue.
+	e.ctx.Player = 3
+	if got := e.eval("[lps()]"); got != "3" {
+		t.Errorf("bob lps() = %q", got)
+	}
+	if got := e.eval("[qentries(#2)]"); got != "#-1 PERMISSION DENIED" {
+		t.Errorf("bob qentries(#2) = %q", got)
+	}
+	e.game.DB.Objects[3].Powers[0] |= gamedb.PowSeeQueue
+	if got := e.eval("[qentries(#2)]"); got != "2" {
+		t.Errorf("see_queue qentries(#2) = %q", got)
+	}
+}
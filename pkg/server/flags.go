@@ -57,6 +57,7 @@ var FlagTable = map[string]*FlagDef{
 	"HAS_LISTEN": {Name: "HAS_LISTEN", Word: 1, Bit: gamedb.Flag2HasListen},
 	"CONNECTED":  {Name: "CONNECTED", Word: 1, Bit: gamedb.Flag2Connected},
 	"SLAVE":      {Name: "SLAVE", Word: 1, Bit: gamedb.Flag2Slave},
+	"SUSPECT":    {Name: "SUSPECT", Word: 1, Bit: gamedb.Flag2Suspect},
 	"HTML":       {Name: "HTML", Word: 1, Bit: gamedb.Flag2HTML},
 	"ANSI":       {Name: "ANSI", Word: 1, Bit: gamedb.Flag2Ansi},
 	"BLIND":      {Name: "BLIND", Word: 1, Bit: gamedb.Flag2Blind},
@@ -67,6 +68,7 @@ var FlagTable = map[string]*FlagDef{
 	"BOUNCE":     {Name: "BOUNCE", Word: 1, Bit: gamedb.Flag2Bounce},
 	"ZONE_PARENT": {Name: "ZONE_PARENT", Word: 1, Bit: gamedb.Flag2ZoneParent},
 	"NO_BLEED":   {Name: "NO_BLEED", Word: 1, Bit: gamedb.Flag2NoBLeed},
+	"NOBLEED":    {Name: "NO_BLEED", Word: 1, Bit: gamedb.Flag2NoBLeed}, // alias
 	"HAS_DAILY":  {Name: "HAS_DAILY", Word: 1, Bit: gamedb.Flag2HasDaily},
 	"GAGGED":     {Name: "GAGGED", Word: 1, Bit: gamedb.Flag2Gagged},
 	"STAFF":      {Name: "STAFF", Word: 1, Bit: gamedb.Flag2Staff},
@@ -100,9 +102,35 @@ func (g *Game) SetFlag(target gamedb.DBRef, flagStr string) bool {
 		obj.Flags[def.Word] |= def.Bit
 	}
 	g.PersistObject(obj)
+	if def.Word == 1 && (def.Bit == gamedb.Flag2Ansi || def.Bit == gamedb.Flag2NoBLeed) {
+		g.syncAnsiFlags(target)
+	}
 	return true
 }
 
+// ansiFlags reports whether a player has the ANSI and NO_BLEED flags.
+func (g *Game) ansiFlags(player gamedb.DBRef) (ansi, noBleed bool) {
+	obj, ok := g.DB.Objects[player]
+	if !ok {
+		return false, false
+	}
+	return obj.HasFlag2(gamedb.Flag2Ansi), obj.HasFlag2(gamedb.Flag2NoBLeed)
+}
+
+// syncAnsiFlags pushes a player's current ANSI/NO_BLEED flags to all of
+// their open connections so that color output changes immediately.
+func (g *Game) syncAnsiFlags(player gamedb.DBRef) {
+	if g.Conns == nil {
+		return
+	}
+	ansi, noBleed := g.ansiFlags(player)
+	for _, d := range g.Conns.GetByPlayer(player) {
+		d.mu.Lock()
+		d.Ansi, d.NoBleed = ansi, noBleed
+		d.mu.Unlock()
+	}
+}
+
 // GetAttrTextByName returns the text of an attribute by name.
 func (g *Game) GetAttrTextByName(obj gamedb.DBRef, attrName string) string {
 	o, ok := g.DB.Objects[obj]
@@ -112,22 +140,15 @@ func (g *Game) GetAttrTextByName(obj gamedb.DBRef, attrName string) string {
 
 	// Try user-defined attrs
 	if def, ok := g.DB.AttrByName[attrName]; ok {
-		for _, attr := range o.Attrs {
-			if attr.Number == def.Number {
-				return eval.StripAttrPrefix(attr.Value)
-			}
+		if raw, ok := o.Attr(def.Number); ok {
+			return eval.StripAttrPrefix(raw)
 		}
 	}
 
 	// Try well-known
-	for num, name := range gamedb.WellKnownAttrs {
-		if strings.EqualFold(name, attrName) {
-			for _, attr := range o.Attrs {
-				if attr.Number == num {
-					return eval.StripAttrPrefix(attr.Value)
-				}
-			}
-			break
+	if num, ok := gamedb.WellKnownAttrNum(attrName); ok {
+		if raw, ok := o.Attr(num); ok {
+			return eval.StripAttrPrefix(raw)
 		}
 	}
 	return ""
@@ -139,10 +160,8 @@ func (g *Game) ResolveAttrNum(name string) int {
 	if def, ok := g.DB.AttrByName[name]; ok {
 		return def.Number
 	}
-	for num, n := range gamedb.WellKnownAttrs {
-		if strings.EqualFold(n, name) {
-			return num
-		}
+	if num, ok := gamedb.WellKnownAttrNum(name); ok {
+		return num
 	}
 	return -1
 }
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// cmdFollow implements follow <leader>: whenever the leader goes through
+// an exit, the follower goes after them if they're still in the same
+// room and can pass the exit's lock. With no argument it says who you're
+// following and who's following you.
+func cmdFollow(g *Game, d *Descriptor, args string, _ []string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		g.showFollowing(d)
+		return
+	}
+	leader := g.MatchObject(d.Player, args)
+	if leader == gamedb.Ambiguous {
+		d.Send("I don't know which one you mean!")
+		return
+	}
+	leaderObj, ok := g.DB.Objects[leader]
+	if !ok || !Nearby(g, d.Player, leader) {
+		d.Send("I don't see that here.")
+		return
+	}
+	if t := leaderObj.ObjType(); t != gamedb.TypePlayer && t != gamedb.TypeThing {
+		d.Send("You can't follow that!")
+		return
+	}
+	if leader == d.Player {
+		d.Send("You can't follow yourself.")
+		return
+	}
+	if g.following(d.Player, leader) {
+		d.Send(fmt.Sprintf("You're already following %s.", DisplayName(leaderObj.Name)))
+		return
+	}
+	if g.followers == nil {
+		g.followers = make(map[gamedb.DBRef][]gamedb.DBRef)
+	}
+	g.followers[leader] = append(g.followers[leader], d.Player)
+	d.Send(fmt.Sprintf("You begin following %s.", DisplayName(leaderObj.Name)))
+	g.Conns.SendToPlayer(leader, fmt.Sprintf("%s is now following you.", g.ObjName(d.Player)))
+}
+
+// cmdUnfollow implements unfollow [<leader>]: stop following one leader,
+// or everyone.
+func cmdUnfollow(g *Game, d *Descriptor, args string, _ []string) {
+	args = strings.TrimSpace(args)
+	leaders := g.leadersOf(d.Player)
+	if args != "" {
+		leader := g.MatchObject(d.Player, args)
+		if leader == gamedb.Nothing || leader == gamedb.Ambiguous {
+			leader = g.LookupPlayer(args)
+		}
+		if !g.following(d.Player, leader) {
+			d.Send("You aren't following that.")
+			return
+		}
+		leaders = []gamedb.DBRef{leader}
+	}
+	if len(leaders) == 0 {
+		d.Send("You aren't following anyone.")
+		return
+	}
+	for _, leader := range leaders {
+		g.stopFollowing(d.Player, leader)
+		d.Send(fmt.Sprintf("You stop following %s.", g.ObjName(leader)))
+		g.Conns.SendToPlayer(leader, fmt.Sprintf("%s stops following you.", g.ObjName(d.Player)))
+	}
+}
+
+// cmdDismiss implements dismiss [<follower>]: stop one follower, or all
+// of them, from following you.
+func cmdDismiss(g *Game, d *Descriptor, args string, _ []string) {
+	args = strings.TrimSpace(args)
+	followers := append([]gamedb.DBRef(nil), g.followers[d.Player]...)
+	if args != "" {
+		follower := g.MatchObject(d.Player, args)
+		if follower == gamedb.Nothing || follower == gamedb.Ambiguous {
+			follower = g.LookupPlayer(args)
+		}
+		if !g.following(follower, d.Player) {
+			d.Send("That isn't following you.")
+			return
+		}
+		followers = []gamedb.DBRef{follower}
+	}
+	if len(followers) == 0 {
+		d.Send("Nobody is following you.")
+		return
+	}
+	for _, follower := range followers {
+		g.stopFollowing(follower, d.Player)
+		d.Send(fmt.Sprintf("You dismiss %s.", g.ObjName(follower)))
+		g.Conns.SendToPlayer(follower, fmt.Sprintf("%s dismisses you.", g.ObjName(d.Player)))
+	}
+}
+
+// showFollowing lists who player is following and who follows them.
+func (g *Game) showFollowing(d *Descriptor) {
+	names := func(refs []gamedb.DBRef) string {
+		out := make([]string, len(refs))
+		for i, ref := range refs {
+			out[i] = g.ObjName(ref)
+		}
+		return strings.Join(out, ", ")
+	}
+	leaders, followers := g.leadersOf(d.Player), g.followers[d.Player]
+	if len(leaders) == 0 && len(followers) == 0 {
+		d.Send("You aren't following anyone, and nobody is following you.")
+		return
+	}
+	if len(leaders) > 0 {
+		d.Send("You are following: " + names(leaders))
+	}
+	if len(followers) > 0 {
+		d.Send("You are followed by: " + names(followers))
+	}
+}
+
+// following reports whether follower is following leader.
+func (g *Game) following(follower, leader gamedb.DBRef) bool {
+	for _, f := range g.followers[leader] {
+		if f == follower {
+			return true
+		}
+	}
+	return false
+}
+
+// leadersOf returns everyone follower is following.
+func (g *Game) leadersOf(follower gamedb.DBRef) []gamedb.DBRef {
+	var leaders []gamedb.DBRef
+	for leader := range g.followers {
+		if g.following(follower, leader) {
+			leaders = append(leaders, leader)
+		}
+	}
+	return leaders
+}
+
+// stopFollowing removes follower from leader's followers.
+func (g *Game) stopFollowing(follower, leader gamedb.DBRef) {
+	kept := g.followers[leader][:0]
+	for _, f := range g.followers[leader] {
+		if f != follower {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == 0 {
+		delete(g.followers, leader)
+	} else {
+		g.followers[leader] = kept
+	}
+}
+
+// leadFollowers takes leader's followers who were with them in src
+// through exit to dest after them, each checked against the exit's lock
+// on their own. Followers' followers come along too.
+func (g *Game) leadFollowers(leader, src, exit, dest gamedb.DBRef, moved map[gamedb.DBRef]bool) {
+	if moved == nil {
+		moved = map[gamedb.DBRef]bool{leader: true}
+	}
+	for _, follower := range append([]gamedb.DBRef(nil), g.followers[leader]...) {
+		fObj, ok := g.DB.Objects[follower]
+		if !ok || fObj.IsGoing() {
+			g.stopFollowing(follower, leader)
+			continue
+		}
+		if moved[follower] || fObj.Location != src {
+			continue
+		}
+		moved[follower] = true
+		if !CouldDoIt(g, follower, exit, aLock) {
+			g.Conns.SendToPlayer(follower, fmt.Sprintf("You can't follow %s that way.", g.ObjName(leader)))
+			continue
+		}
+		g.Conns.SendToPlayer(follower, fmt.Sprintf("You follow %s.", g.ObjName(leader)))
+		if g.MoveObject(follower, dest, move{cause: leader, exit: exit}) {
+			g.leadFollowers(follower, src, exit, dest, moved)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	aForwardlist = 95 // A_FORWARDLIST
+	aPrefix      = 90 // A_PREFIX
+
+	// maxForwardTargets caps the size of a @forwardlist, as fwdlist_lim
+	// does in C TinyMUSH.
+	maxForwardTargets = 100
+)
+
+// canForwardTo reports whether objects owned by player may forward to target:
+// player controls it or it is LINK_OK.
+func (g *Game) canForwardTo(player, target gamedb.DBRef) bool {
+	obj, ok := g.DB.Objects[target]
+	if !ok || obj.IsGoing() {
+		return false
+	}
+	return g.Controls(player, target) || obj.HasFlag(gamedb.FlagLinkOK)
+}
+
+// forwardTargets parses obj's FORWARDLIST, dropping entries its owner is
+// no longer allowed to forward to.
+func (g *Game) forwardTargets(obj gamedb.DBRef) []gamedb.DBRef {
+	owner := ResolveOwner(g, obj)
+	var targets []gamedb.DBRef
+	for _, word := range strings.Fields(g.GetAttrText(obj, aForwardlist)) {
+		ref, err := parseDBRef(word)
+		if err != nil || ref == obj || !g.canForwardTo(owner, ref) {
+			continue
+		}
+		targets = append(targets, ref)
+		if len(targets) >= maxForwardTargets {
+			break
+		}
+	}
+	return targets
+}
+
+// relayPrefix returns obj's evaluated PREFIX, or "From <name>," if it has none.
+func (g *Game) relayPrefix(obj gamedb.DBRef) string {
+	if prefix := g.GetAttrText(obj, aPrefix); prefix != "" {
+		return evalExpr(g, obj, prefix)
+	}
+	return fmt.Sprintf("From %s,", DisplayName(g.ObjName(obj)))
+}
+
+// forwardHeard passes a message obj heard on to everything in its
+// FORWARDLIST, prefixed with obj's PREFIX. An object that is already
+// relaying further up the chain is skipped, so forwarding loops end.
+func (g *Game) forwardHeard(obj, cause gamedb.DBRef, message string) {
+	if g.forwarding[obj] {
+		return
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok || o.HasFlag(gamedb.FlagHalt) {
+		return
+	}
+	targets := g.forwardTargets(obj)
+	if len(targets) == 0 {
+		return
+	}
+	if g.forwarding == nil {
+		g.forwarding = make(map[gamedb.DBRef]bool)
+	}
+	g.forwarding[obj] = true
+	defer delete(g.forwarding, obj)
+
+	relayed := message
+	if prefix := g.relayPrefix(obj); prefix != "" {
+		relayed = prefix + " " + message
+	}
+	for _, target := range targets {
+		if g.forwarding[target] {
+			continue
+		}
+		if t := g.DB.Objects[target]; t.ObjType() == gamedb.TypeRoom {
+			g.SendMarkedToRoom(target, "EMIT", relayed)
+			g.MatchListenPatterns(target, obj, relayed)
+			continue
+		}
+		g.SendMarkedToPlayer(target, "EMIT", relayed)
+		g.CheckPemitListen(target, obj, relayed)
+	}
+}
+
+// hasForwardlist reports whether obj has a FORWARDLIST set on it directly.
+// HAS_FORWARDLIST is checked first; imported objects may lack it.
+func hasForwardlist(obj *gamedb.Object) bool {
+	if obj.HasFlag2(gamedb.Flag2HasFwd) {
+		return true
+	}
+	for _, attr := range obj.Attributes() {
+		if attr.Number == aForwardlist {
+			return true
+		}
+	}
+	return false
+}
+
+// cmdForwardlist implements @forwardlist <object>=<dbref list>. Every entry
+// must be an object you control or one that is LINK_OK.
+func cmdForwardlist(g *Game, d *Descriptor, args string, _ []string) {
+	targetStr, list, ok := strings.Cut(args, "=")
+	if !ok {
+		d.Send("I need an object and a value separated by =.")
+		return
+	}
+	target := g.MatchObject(d.Player, strings.TrimSpace(targetStr))
+	if target == gamedb.Nothing {
+		d.Send("I don't see that here.")
+		return
+	}
+	if !g.Controls(d.Player, target) {
+		d.Send("Permission denied.")
+		return
+	}
+
+	words := strings.Fields(list)
+	if len(words) > maxForwardTargets {
+		d.Send(fmt.Sprintf("A forwardlist can hold at most %d objects.", maxForwardTargets))
+		return
+	}
+	refs := make([]string, 0, len(words))
+	for _, word := range words {
+		ref, err := parseDBRef(word)
+		if err != nil || !strings.HasPrefix(word, "#") {
+			d.Send(fmt.Sprintf("%s is not a dbref.", word))
+			return
+		}
+		if ref == target {
+			d.Send("An object can't forward to itself.")
+			return
+		}
+		if !g.canForwardTo(d.Player, ref) {
+			d.Send(fmt.Sprintf("You can't forward to #%d.", ref))
+			return
+		}
+		refs = append(refs, fmt.Sprintf("#%d", ref))
+	}
+
+	if ok, errMsg := g.SetAttrChecked(d.Player, target, aForwardlist, strings.Join(refs, " ")); !ok {
+		d.Send(errMsg)
+		return
+	}
+	obj := g.DB.Objects[target]
+	if len(refs) > 0 {
+		obj.Flags[1] |= gamedb.Flag2HasFwd
+	} else {
+		obj.Flags[1] &^= gamedb.Flag2HasFwd
+	}
+	g.PersistObject(obj)
+	d.Send("Set.")
+}
@@ -33,17 +33,40 @@ type GameConf struct {
 	Paycheck          int    `yaml:"paycheck"`
 	EarnLimit         int    `yaml:"earn_limit"`
 	PageCost          int    `yaml:"page_cost"`
+	PageRateLimit     int    `yaml:"page_rate_limit"`      // Pages per minute per player, 0 = unlimited (wizards exempt)
+	PageRequiresEquals bool  `yaml:"page_requires_equals"` // 'page <message>' without = is refused
 	WaitCost          int    `yaml:"wait_cost"`
 	LinkCost          int    `yaml:"link_cost"`
+	SearchCost        int    `yaml:"search_cost"`    // Cost of @find, @search and other whole-database scans
+	DigCost           int    `yaml:"dig_cost"`       // Cost of @dig
+	OpenCost          int    `yaml:"open_cost"`      // Cost of @open
+	CreateMin         int    `yaml:"create_min_cost"` // Least a @create costs
+	CreateMax         int    `yaml:"create_max_cost"` // Most a @create may cost
+	RobotCost         int    `yaml:"robot_cost"`     // Cost of @robot
+	KillMin           int    `yaml:"kill_min"`       // Least a kill attempt costs
+	KillMax           int    `yaml:"kill_max"`       // Most a kill attempt costs
+	KillGuarantee     int    `yaml:"kill_guarantee"` // A kill costing this much always succeeds
 
 	// --- Idle/timeout ---
 	IdleTimeout int  `yaml:"idle_timeout"`
 	IdleWizDark bool `yaml:"idle_wiz_dark"`
+	IdleMessageTime int `yaml:"idle_message_time"` // Seconds idle before pages get your @idle (0 = always)
 
 	// --- Queue ---
 	QueueIdleChunk          int `yaml:"queue_idle_chunk"`
+	PlayerQueueLimit        int `yaml:"player_queue_limit"`
+	WizardQueueLimit        int `yaml:"wizard_queue_limit"`
+	QueueOwnerRate          int `yaml:"queue_owner_rate"` // Queued commands per second per owner (0 = unlimited)
+	EventsDailyHour         int `yaml:"events_daily_hour"` // Hour (0-23) @daily attributes run
 	FunctionInvocationLimit int `yaml:"function_invocation_limit"`
+	FunctionRecursionLimit  int `yaml:"function_recursion_limit"` // Nesting depth of function calls
+	FunctionCPULimit        int `yaml:"function_cpu_limit"`       // Seconds one action may spend calling functions (0 = unlimited)
+	FloatPrecision          int `yaml:"float_precision"` // Decimal places in floating-point results
+	StructLimit             int `yaml:"struct_limit"`    // Structures per object (0 = unlimited)
+	InstanceLimit           int `yaml:"instance_limit"`  // Structure instances per object (0 = unlimited)
+	RegisterLimit           int `yaml:"register_limit"`  // Named local registers per action (0 = unlimited)
 	MachineCommandCost      int `yaml:"machine_command_cost"`
+	SideEffectFunctions     bool `yaml:"side_effect_functions"` // create(), dig(), tel() and friends may run (default true)
 
 	// --- Output ---
 	OutputLimit int `yaml:"output_limit"`
@@ -61,6 +84,19 @@ type GameConf struct {
 	SweepDark              bool `yaml:"sweep_dark"`
 	TraceTopdown           bool `yaml:"trace_topdown"`
 	TraceOutputLimit       int  `yaml:"trace_output_limit"`
+	PuppetSameRoom         bool `yaml:"puppet_same_room"`   // Puppets relay to an owner in the same room
+	LocalMasterRooms       bool `yaml:"local_master_rooms"` // ZONE parent rooms lend exits and $-commands
+	RobotSpeech            bool `yaml:"robot_speech"`       // Robots may speak where they don't control the room
+	GlobalAconn            bool `yaml:"use_global_aconn"`   // Master room objects run ACONNECT/ADISCONNECT
+	GlobalAconnUselocks    bool `yaml:"global_aconn_uselocks"` // Master room objects check their uselock first
+	RoomAconnect           bool `yaml:"room_aconnect"`      // The player's location runs ACONNECT/ADISCONNECT
+	LoginWho               bool `yaml:"login_who"`          // WHO, DOING and INFO work from the login screen
+	WhoData                []string `yaml:"who_data"`      // Columns of the mortal WHO: name, onfor, idle, cmds, doing
+
+	// --- Messages ---
+	FixedHomeMessage string `yaml:"fixed_home_message"` // Sent when a FIXED player tries to go home
+	FixedTelMessage  string `yaml:"fixed_tel_message"`  // Sent when a FIXED player tries to teleport
+	ProgramPrompt    string `yaml:"program_prompt"`     // Prompt for @program input
 
 	// --- Guest ---
 	GuestCharNum   int    `yaml:"guest_char_num"`
@@ -90,6 +126,18 @@ type GameConf struct {
 	GodDBRef      int `yaml:"god_dbref"`       // The God player dbref (default 1)
 	ZoneNestLimit int `yaml:"zone_nest_limit"` // Max zone recursion depth (default 20)
 
+	// --- Connection security ---
+	ConnRateLimit   int `yaml:"conn_rate_limit"`   // Connections per minute per address, 0 = unlimited (default 20)
+	LoginFailLimit  int `yaml:"login_fail_limit"`  // Failed logins per address before lockout, 0 = unlimited (default 5)
+	LoginFailWindow int `yaml:"login_fail_window"` // Seconds failed logins are remembered (default 600)
+
+	// --- Registration ---
+	RegistrationRequired bool `yaml:"registration_required"` // Disable "create"; new characters are requested with "register" and approved by wizards
+
+	// --- Scenes ---
+	SceneKey       string `yaml:"scene_key"`       // Master key for scene log encryption (empty = generated, kept in the database)
+	SceneRetention int    `yaml:"scene_retention"` // Days finished scenes are kept, 0 = forever (default 90)
+
 	// --- TLS ---
 	Cleartext *bool  `yaml:"cleartext"` // nil = default true; explicitly false disables plaintext
 	TLS       bool   `yaml:"tls"`
@@ -108,11 +156,45 @@ type GameConf struct {
 	SQLTimeout    int    `yaml:"sql_timeout"`     // Query timeout in seconds (default 5)
 	SQLReconnect  bool   `yaml:"sql_reconnect"`   // Auto-reconnect on failure
 
+	// --- Outbound HTTP (@http, httpget(), httppost()) ---
+	HTTPEnabled   bool     `yaml:"http_enabled"`    // Wizards may fetch URLs from softcode
+	HTTPTimeout   int      `yaml:"http_timeout"`    // Request timeout in seconds (default 10)
+	HTTPMaxBody   int      `yaml:"http_max_body"`   // Response bytes kept; the rest is cut off (default 16384)
+	HTTPRateLimit int      `yaml:"http_rate_limit"` // Requests per minute per owner (0 = unlimited)
+	HTTPAllow     []string `yaml:"http_allow"`      // Private or loopback addresses/CIDRs requests may still reach
+
+	// --- Logging ---
+	Log       []string `yaml:"log"`        // Enabled log categories (commands, connections, wizard, suspect, bugs, security, local, all; !name disables)
+	LogLevel  string   `yaml:"log_level"`  // Minimum level written: debug, info, warn, error (default info)
+	LogFormat string   `yaml:"log_format"` // "text" (default) or "json", one object per line
+	LogDir    string   `yaml:"log_dir"`    // Write each category to <log_dir>/<category>.log (empty = main log)
+
 	// --- Archive/Backup ---
 	ArchiveDir      string `yaml:"archive_dir"`       // Archive output directory (default: "backups")
 	ArchiveInterval int    `yaml:"archive_interval"`  // Auto-archive interval in minutes, 0 = disabled
 	ArchiveRetain   int    `yaml:"archive_retain"`    // Keep last N archives, 0 = unlimited
 	ArchiveHook     string `yaml:"archive_hook"`      // Shell command to run after archive, %f = archive path
+	ArchivePassphrase   string   `yaml:"archive_passphrase"`    // Encrypt archives with age using this passphrase
+	ArchiveRecipients   []string `yaml:"archive_recipients"`    // Encrypt archives to these age public keys instead
+	ArchiveRemote       string   `yaml:"archive_remote"`        // Upload archives to s3://bucket/prefix or sftp://user@host[:port]/dir
+	ArchiveRemoteRetain int      `yaml:"archive_remote_retain"` // Keep last N remote archives, 0 = same as archive_retain
+	ArchiveS3Endpoint   string   `yaml:"archive_s3_endpoint"`   // S3-compatible endpoint (default s3.amazonaws.com; http://host:port for plain HTTP)
+	ArchiveS3Region     string   `yaml:"archive_s3_region"`     // S3 region
+	ArchiveS3AccessKey  string   `yaml:"archive_s3_access_key"` // S3 access key (empty = AWS_ACCESS_KEY_ID env)
+	ArchiveS3SecretKey  string   `yaml:"archive_s3_secret_key"` // S3 secret key
+	ArchiveSFTPKey      string   `yaml:"archive_sftp_key"`      // SFTP private key file
+	ArchiveSFTPPassword string   `yaml:"archive_sftp_password"` // SFTP password
+	ArchiveSFTPKnownHosts string `yaml:"archive_sftp_known_hosts"` // known_hosts file (default ~/.ssh/known_hosts)
+	WriteBehind     int    `yaml:"write_behind"`      // Batch object writes every N ms, 0 = write through
+	LazyLoad        bool   `yaml:"lazy_load"`         // Leave cold objects' attributes on disk until used
+	LazyIdleDays    int    `yaml:"lazy_idle_days"`    // Players idle this many days count as cold (0 = only GOING objects)
+	LazyCache       int    `yaml:"lazy_cache"`        // Cold objects' attributes kept in memory at once
+
+	// --- Replication ---
+	ReplicationListen string `yaml:"replication_listen"` // Stream bolt writes to standbys on host:port (empty = off)
+	ReplicationSecret string `yaml:"replication_secret"` // Shared secret standbys must prove they know
+	ReplicationTLS    bool   `yaml:"replication_tls"`    // Replicate over TLS (primary uses tls_cert/tls_key)
+	ReplicationCA     string `yaml:"replication_ca"`     // Standby: PEM file to verify the primary's certificate (empty = system roots)
 
 	// --- Web/Security ---
 	WebEnabled    bool     `yaml:"web_enabled"`     // Enable HTTPS/WSS server
@@ -122,17 +204,41 @@ type GameConf struct {
 	WebStaticDir  string   `yaml:"web_static_dir"`  // Path to built web client (default "web/dist")
 	WebClientURL  string   `yaml:"web_client_url"`  // URL of external web client container (e.g. "http://web-client:80"); if set, / is reverse-proxied to it
 	WebCORSOrigins []string `yaml:"web_cors_origins"` // Allowed CORS origins
+	WebTrustedProxies []string `yaml:"web_trusted_proxies"` // Reverse proxies (addresses/CIDRs) whose X-Forwarded-For is believed
 	WebRateLimit  int      `yaml:"web_rate_limit"`  // Requests per minute per IP (default 60)
+	WebPublicPages bool    `yaml:"web_public_pages"` // Serve the WHO, channel and room pages under /pages/
 	JWTSecret     string   `yaml:"jwt_secret"`      // JWT signing secret (auto-generated if empty)
 	JWTExpiry     int      `yaml:"jwt_expiry"`      // JWT expiry in seconds (default 86400)
 	CertDir       string   `yaml:"cert_dir"`        // Directory for generated certs (default "certs")
 	ScrollbackRetention int `yaml:"scrollback_retention"` // Public scrollback retention in seconds (default 86400)
 
+	// --- Email (SMTP relay for mail forwarding) ---
+	SMTPHost     string `yaml:"smtp_host"`     // Relay host (empty = email disabled)
+	SMTPPort     int    `yaml:"smtp_port"`     // Relay port (default 587)
+	SMTPUser     string `yaml:"smtp_user"`     // AUTH PLAIN username (empty = no auth)
+	SMTPPassword string `yaml:"smtp_password"` // AUTH PLAIN password
+	SMTPFrom     string `yaml:"smtp_from"`     // From address for outgoing email
+
+	// --- IRC channel bridge ---
+	IRCServer   string   `yaml:"irc_server"`   // host:port of the IRC server (empty = bridge disabled)
+	IRCTLS      bool     `yaml:"irc_tls"`      // Connect with TLS
+	IRCNick     string   `yaml:"irc_nick"`     // Bridge nickname (default: mud_name without spaces)
+	IRCPassword string   `yaml:"irc_password"` // Server password (PASS), if needed
+	IRCChannels []string `yaml:"irc_channels"` // Bridged channels, as "<comsys channel>=#<irc channel>"
+
+	// --- Discord relay ---
+	DiscordToken    string   `yaml:"discord_token"`    // Bot token; needed for channel IDs and inbound messages
+	DiscordChannels []string `yaml:"discord_channels"` // Relayed channels, as "<comsys channel>=<webhook URL or channel ID>"
+	DiscordInbound  []string `yaml:"discord_inbound"`  // Comsys channels that also hear their Discord channel (bot only)
+	DiscordAlerts   string   `yaml:"discord_alerts"`   // Webhook URL or channel ID for wizard alerts
+	DiscordPoll     int      `yaml:"discord_poll"`     // Seconds between inbound polls (default 5)
+
 	// --- Alias config includes (YAML: list of paths; legacy: from "include" directives) ---
 	AliasFiles []string `yaml:"alias_files"`
 
 	// --- Compatibility ---
 	FixEscapeEval bool `yaml:"fix_escape_eval"` // Strip double-escaped \\[ \\] \\% \\{ \\} in queued attrs (default true)
+	CIsCommand    bool `yaml:"c_is_command"`    // %c is the current command, not an ANSI color (TinyMUSH style)
 
 	// --- Attribute access config ---
 	UserAttrAccess string   `yaml:"user_attr_access"` // Default flags for user-defined attrs
@@ -158,13 +264,35 @@ func DefaultGameConf() *GameConf {
 		Paycheck:                50,
 		EarnLimit:               10000,
 		PageCost:                0,
+		PageRateLimit:           30,
 		WaitCost:                10,
 		LinkCost:                1,
+		SearchCost:              100,
+		DigCost:                 10,
+		OpenCost:                1,
+		CreateMin:               10,
+		CreateMax:               505,
+		RobotCost:               1000,
+		KillMin:                 10,
+		KillMax:                 100,
+		KillGuarantee:           100,
 		IdleTimeout:             3600,
 		IdleWizDark:             false,
+		IdleMessageTime:         600,
 		QueueIdleChunk:          3,
+		PlayerQueueLimit:        100,
+		WizardQueueLimit:        1000,
+		QueueOwnerRate:          500,
+		EventsDailyHour:         7,
 		FunctionInvocationLimit: 2500,
+		FunctionRecursionLimit:  50,
+		FunctionCPULimit:        10,
+		FloatPrecision:          6,
+		StructLimit:             100,
+		InstanceLimit:           100,
+		RegisterLimit:           50,
 		MachineCommandCost:      64,
+		SideEffectFunctions:     true,
 		OutputLimit:             16384,
 		MatchOwnCommands:        false,
 		PlayerMatchOwnCommands:  false,
@@ -178,6 +306,16 @@ func DefaultGameConf() *GameConf {
 		SweepDark:               false,
 		TraceTopdown:            true,
 		TraceOutputLimit:        200,
+		PuppetSameRoom:          false,
+		LocalMasterRooms:        true,
+		RobotSpeech:             true,
+		GlobalAconn:             true,
+		RoomAconnect:            true,
+		LoginWho:                true,
+		WhoData:                 append([]string(nil), defaultWhoData...),
+		FixedHomeMessage:        "Don't you like it here?",
+		FixedTelMessage:         "Sorry, you are stuck here.",
+		ProgramPrompt:           "> ",
 		GuestCharNum:            -1,
 		GuestBasename:           "Guest",
 		NumberGuests:            30,
@@ -185,6 +323,10 @@ func DefaultGameConf() *GameConf {
 		GuestStartRoom:          -1,
 		GodDBRef:                1,
 		ZoneNestLimit:           20,
+		ConnRateLimit:           20,
+		LoginFailLimit:          5,
+		LoginFailWindow:         600,
+		SceneRetention:          90,
 		MailEnabled:             true,
 		ComsysEnabled:           true,
 		MailExpiration:          14,
@@ -196,7 +338,16 @@ func DefaultGameConf() *GameConf {
 		SQLQueryLimit:           100,
 		SQLTimeout:              5,
 		SQLReconnect:            true,
+		HTTPTimeout:             10,
+		HTTPMaxBody:             16384,
+		HTTPRateLimit:           30,
+		Log:                     append([]string(nil), defaultLogCategories...),
+		LogLevel:                "info",
+		LogFormat:               "text",
 		ArchiveDir:              "backups",
+		LazyIdleDays:            365,
+		LazyCache:               10000,
+		WriteBehind:             100,
 		WebEnabled:              true,
 		WebPort:                 8443,
 		WebStaticDir:            "web/dist",
@@ -204,6 +355,7 @@ func DefaultGameConf() *GameConf {
 		JWTExpiry:               86400,
 		CertDir:                 "",
 		ScrollbackRetention:     86400,
+		SMTPPort:                587,
 		FixEscapeEval:           true,
 	}
 }
@@ -341,24 +493,72 @@ func (gc *GameConf) loadLegacyFile(path string, depth int) error {
 			gc.EarnLimit = atoi(val, gc.EarnLimit)
 		case "page_cost":
 			gc.PageCost = atoi(val, gc.PageCost)
+		case "page_rate_limit":
+			gc.PageRateLimit = atoi(val, gc.PageRateLimit)
+		case "page_requires_equals":
+			gc.PageRequiresEquals = parseBool(val)
 		case "wait_cost":
 			gc.WaitCost = atoi(val, gc.WaitCost)
 		case "link_cost":
 			gc.LinkCost = atoi(val, gc.LinkCost)
+		case "search_cost":
+			gc.SearchCost = atoi(val, gc.SearchCost)
+		case "dig_cost":
+			gc.DigCost = atoi(val, gc.DigCost)
+		case "open_cost":
+			gc.OpenCost = atoi(val, gc.OpenCost)
+		case "create_min_cost":
+			gc.CreateMin = atoi(val, gc.CreateMin)
+		case "create_max_cost":
+			gc.CreateMax = atoi(val, gc.CreateMax)
+		case "robot_cost":
+			gc.RobotCost = atoi(val, gc.RobotCost)
+		case "kill_min":
+			gc.KillMin = atoi(val, gc.KillMin)
+		case "kill_max":
+			gc.KillMax = atoi(val, gc.KillMax)
+		case "kill_guarantee":
+			gc.KillGuarantee = atoi(val, gc.KillGuarantee)
 
 		// --- Idle/timeout ---
 		case "idle_timeout":
 			gc.IdleTimeout = atoi(val, gc.IdleTimeout)
 		case "idle_wiz_dark":
 			gc.IdleWizDark = parseBool(val)
+		case "idle_message_time":
+			gc.IdleMessageTime = atoi(val, gc.IdleMessageTime)
 
 		// --- Queue ---
 		case "queue_idle_chunk":
 			gc.QueueIdleChunk = atoi(val, gc.QueueIdleChunk)
+		case "player_queue_limit":
+			gc.PlayerQueueLimit = atoi(val, gc.PlayerQueueLimit)
+		case "wizard_queue_limit":
+			gc.WizardQueueLimit = atoi(val, gc.WizardQueueLimit)
+		case "queue_owner_rate":
+			gc.QueueOwnerRate = atoi(val, gc.QueueOwnerRate)
+		case "events_daily_hour":
+			gc.EventsDailyHour = atoi(val, gc.EventsDailyHour)
 		case "function_invocation_limit":
 			gc.FunctionInvocationLimit = atoi(val, gc.FunctionInvocationLimit)
+		case "function_recursion_limit":
+			gc.FunctionRecursionLimit = atoi(val, gc.FunctionRecursionLimit)
+		case "function_cpu_limit":
+			gc.FunctionCPULimit = atoi(val, gc.FunctionCPULimit)
+		case "float_precision":
+			gc.FloatPrecision = atoi(val, gc.FloatPrecision)
+		case "struct_limit":
+			gc.StructLimit = atoi(val, gc.StructLimit)
+		case "instance_limit":
+			gc.InstanceLimit = atoi(val, gc.InstanceLimit)
+		case "register_limit":
+			gc.RegisterLimit = atoi(val, gc.RegisterLimit)
 		case "machine_command_cost":
 			gc.MachineCommandCost = atoi(val, gc.MachineCommandCost)
+		case "c_is_command":
+			gc.CIsCommand = parseBool(val)
+		case "side_effect_functions":
+			gc.SideEffectFunctions = parseBool(val)
 
 		// --- Output ---
 		case "output_limit":
@@ -389,6 +589,30 @@ func (gc *GameConf) loadLegacyFile(path string, depth int) error {
 			gc.TraceTopdown = parseBool(val)
 		case "trace_output_limit":
 			gc.TraceOutputLimit = atoi(val, gc.TraceOutputLimit)
+		case "puppet_same_room":
+			gc.PuppetSameRoom = parseBool(val)
+		case "local_master_rooms":
+			gc.LocalMasterRooms = parseBool(val)
+		case "robot_speech":
+			gc.RobotSpeech = parseBool(val)
+		case "login_who":
+			gc.LoginWho = parseBool(val)
+		case "use_global_aconn":
+			gc.GlobalAconn = parseBool(val)
+		case "room_aconnect":
+			gc.RoomAconnect = parseBool(val)
+		case "global_aconn_uselocks":
+			gc.GlobalAconnUselocks = parseBool(val)
+		case "who_data":
+			gc.WhoData = strings.Fields(val)
+
+		// --- Messages ---
+		case "fixed_home_message":
+			gc.FixedHomeMessage = val
+		case "fixed_tel_message":
+			gc.FixedTelMessage = val
+		case "program_prompt":
+			gc.ProgramPrompt = val
 
 		// --- Guest ---
 		case "guest_char_num":
@@ -429,6 +653,20 @@ func (gc *GameConf) loadLegacyFile(path string, depth int) error {
 			gc.GodDBRef = atoi(val, gc.GodDBRef)
 		case "zone_nest_limit":
 			gc.ZoneNestLimit = atoi(val, gc.ZoneNestLimit)
+		case "conn_rate_limit":
+			gc.ConnRateLimit = atoi(val, gc.ConnRateLimit)
+		case "login_fail_limit":
+			gc.LoginFailLimit = atoi(val, gc.LoginFailLimit)
+		case "login_fail_window":
+			gc.LoginFailWindow = atoi(val, gc.LoginFailWindow)
+		case "registration_required":
+			gc.RegistrationRequired = parseBool(val)
+
+		// --- Scenes ---
+		case "scene_key":
+			gc.SceneKey = val
+		case "scene_retention":
+			gc.SceneRetention = atoi(val, gc.SceneRetention)
 
 		// --- SQL ---
 		case "sql_enabled":
@@ -442,6 +680,18 @@ func (gc *GameConf) loadLegacyFile(path string, depth int) error {
 		case "sql_reconnect":
 			gc.SQLReconnect = parseBool(val)
 
+		// --- Outbound HTTP ---
+		case "http_enabled":
+			gc.HTTPEnabled = parseBool(val)
+		case "http_timeout":
+			gc.HTTPTimeout = atoi(val, gc.HTTPTimeout)
+		case "http_max_body":
+			gc.HTTPMaxBody = atoi(val, gc.HTTPMaxBody)
+		case "http_rate_limit":
+			gc.HTTPRateLimit = atoi(val, gc.HTTPRateLimit)
+		case "http_allow":
+			gc.HTTPAllow = append(gc.HTTPAllow, strings.Fields(val)...)
+
 		// --- Archive ---
 		case "archive_dir":
 			gc.ArchiveDir = val
@@ -451,6 +701,55 @@ func (gc *GameConf) loadLegacyFile(path string, depth int) error {
 			gc.ArchiveRetain = atoi(val, gc.ArchiveRetain)
 		case "archive_hook":
 			gc.ArchiveHook = val
+		case "archive_passphrase":
+			gc.ArchivePassphrase = val
+		case "archive_recipients":
+			gc.ArchiveRecipients = append(gc.ArchiveRecipients, strings.Fields(val)...)
+		case "archive_remote":
+			gc.ArchiveRemote = val
+		case "archive_remote_retain":
+			gc.ArchiveRemoteRetain = atoi(val, gc.ArchiveRemoteRetain)
+		case "archive_s3_endpoint":
+			gc.ArchiveS3Endpoint = val
+		case "archive_s3_region":
+			gc.ArchiveS3Region = val
+		case "archive_s3_access_key":
+			gc.ArchiveS3AccessKey = val
+		case "archive_s3_secret_key":
+			gc.ArchiveS3SecretKey = val
+		case "archive_sftp_key":
+			gc.ArchiveSFTPKey = val
+		case "archive_sftp_password":
+			gc.ArchiveSFTPPassword = val
+		case "archive_sftp_known_hosts":
+			gc.ArchiveSFTPKnownHosts = val
+		case "write_behind":
+			gc.WriteBehind = atoi(val, gc.WriteBehind)
+		case "lazy_load":
+			gc.LazyLoad = parseBool(val)
+		case "lazy_idle_days":
+			gc.LazyIdleDays = atoi(val, gc.LazyIdleDays)
+		case "lazy_cache":
+			gc.LazyCache = atoi(val, gc.LazyCache)
+		// --- Replication ---
+		case "replication_listen":
+			gc.ReplicationListen = val
+		case "replication_secret":
+			gc.ReplicationSecret = val
+		case "replication_tls":
+			gc.ReplicationTLS = parseBool(val)
+		case "replication_ca":
+			gc.ReplicationCA = val
+
+		// --- Logging ---
+		case "log":
+			gc.Log = append(gc.Log, strings.Fields(val)...)
+		case "log_level":
+			gc.LogLevel = val
+		case "log_format":
+			gc.LogFormat = val
+		case "log_dir":
+			gc.LogDir = val
 
 		// --- TLS ---
 		case "cleartext":
@@ -465,6 +764,42 @@ func (gc *GameConf) loadLegacyFile(path string, depth int) error {
 		case "tls_key":
 			gc.TLSKey = val
 
+		// --- Email ---
+		case "smtp_host":
+			gc.SMTPHost = val
+		case "smtp_port":
+			gc.SMTPPort = atoi(val, gc.SMTPPort)
+		case "smtp_user":
+			gc.SMTPUser = val
+		case "smtp_password":
+			gc.SMTPPassword = val
+		case "smtp_from":
+			gc.SMTPFrom = val
+
+		// --- IRC ---
+		case "irc_server":
+			gc.IRCServer = val
+		case "irc_tls":
+			gc.IRCTLS = parseBool(val)
+		case "irc_nick":
+			gc.IRCNick = val
+		case "irc_password":
+			gc.IRCPassword = val
+		case "irc_channel", "irc_channels":
+			gc.IRCChannels = append(gc.IRCChannels, strings.Fields(val)...)
+
+		// --- Discord ---
+		case "discord_token":
+			gc.DiscordToken = val
+		case "discord_channel", "discord_channels":
+			gc.DiscordChannels = append(gc.DiscordChannels, strings.Fields(val)...)
+		case "discord_inbound":
+			gc.DiscordInbound = append(gc.DiscordInbound, strings.Fields(val)...)
+		case "discord_alerts":
+			gc.DiscordAlerts = val
+		case "discord_poll":
+			gc.DiscordPoll = atoi(val, gc.DiscordPoll)
+
 		// --- Web/Security ---
 		case "web_enabled":
 			gc.WebEnabled = parseBool(val)
@@ -481,8 +816,12 @@ func (gc *GameConf) loadLegacyFile(path string, depth int) error {
 			for i := range gc.WebCORSOrigins {
 				gc.WebCORSOrigins[i] = strings.TrimSpace(gc.WebCORSOrigins[i])
 			}
+		case "web_trusted_proxies":
+			gc.WebTrustedProxies = append(gc.WebTrustedProxies, strings.Fields(val)...)
 		case "web_rate_limit":
 			gc.WebRateLimit = atoi(val, gc.WebRateLimit)
+		case "web_public_pages":
+			gc.WebPublicPages = parseBool(val)
 		case "jwt_secret":
 			gc.JWTSecret = val
 		case "jwt_expiry":
@@ -530,12 +869,17 @@ func splitKeyVal(line string) (string, string) {
 // ApplyGameConf applies a parsed game config to the Game.
 func (g *Game) ApplyGameConf(gc *GameConf) {
 	g.Conf = gc
+	ConfigureLogging(gc)
 
 	log.Printf("Game config applied: mud_name=%q master_room=#%d start_room=#%d start_home=#%d",
 		gc.MudName, gc.MasterRoom, gc.PlayerStartingRoom, gc.PlayerStartingHome)
 	log.Printf("  economy: %s/%s starting=%d paycheck=%d",
 		gc.MoneyNameSingular, gc.MoneyNamePlural, gc.StartingMoney, gc.Paycheck)
 
+	if g.SQLDB != nil {
+		g.SQLDB.SetLimits(gc.SQLQueryLimit, gc.SQLTimeout)
+	}
+
 	// Apply deferred attribute access directives (requires DB to be loaded)
 	if gc.UserAttrAccess != "" {
 		g.ApplyUserAttrAccess(gc.UserAttrAccess)
@@ -572,6 +916,22 @@ func (g *Game) StartingHome() gamedb.DBRef {
 	return g.StartingRoom()
 }
 
+// fixedHomeMessage returns the message shown when a FIXED player tries to go home.
+func (g *Game) fixedHomeMessage() string {
+	if g.Conf != nil && g.Conf.FixedHomeMessage != "" {
+		return g.Conf.FixedHomeMessage
+	}
+	return "Don't you like it here?"
+}
+
+// fixedTelMessage returns the message shown when a FIXED player tries to teleport.
+func (g *Game) fixedTelMessage() string {
+	if g.Conf != nil && g.Conf.FixedTelMessage != "" {
+		return g.Conf.FixedTelMessage
+	}
+	return "Sorry, you are stuck here."
+}
+
 // MoneyName returns the singular or plural money name.
 func (g *Game) MoneyName(amount int) string {
 	if g.Conf != nil {
@@ -595,6 +955,16 @@ func (gc *GameConf) IsCleartext() bool {
 	return *gc.Cleartext
 }
 
+// confCopy returns a copy of the game config for a goroutine that runs
+// without the world lock, or nil if there is none.
+func (g *Game) confCopy() *GameConf {
+	if g.Conf == nil {
+		return nil
+	}
+	gc := *g.Conf
+	return &gc
+}
+
 // --- Helper functions ---
 
 func atoi(s string, fallback int) int {
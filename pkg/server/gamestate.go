@@ -5,10 +5,12 @@ import (
 	"log"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 )
 
@@ -21,28 +23,32 @@ func (g *Game) ConnectedPlayers() []gamedb.DBRef {
 }
 
 // ConnectedPlayersVisible returns connected players visible to viewer
-// (excludes DARK wizards and UNFINDABLE players unless viewer is wizard).
+// (excludes hidden players unless viewer can see hidden players, and
+// UNFINDABLE players unless viewer can find the unfindable).
 func (g *Game) ConnectedPlayersVisible(viewer gamedb.DBRef) []gamedb.DBRef {
 	all := g.Conns.ConnectedPlayers()
-	isWiz := Wizard(g, viewer)
-	if isWiz {
+	if Wizard(g, viewer) {
 		return all
 	}
+	findAll := FindUnfindable(g, viewer)
 	var visible []gamedb.DBRef
 	for _, p := range all {
-		if obj, ok := g.DB.Objects[p]; ok {
-			if obj.HasFlag(gamedb.FlagDark) && obj.HasFlag(gamedb.FlagWizard) {
-				continue
-			}
-			if obj.HasFlag2(gamedb.Flag2Unfindable) {
-				continue
-			}
+		if HiddenFrom(g, viewer, p) {
+			continue
+		}
+		if obj, ok := g.DB.Objects[p]; ok && p != viewer && !findAll && obj.HasFlag2(gamedb.Flag2Unfindable) {
+			continue
 		}
 		visible = append(visible, p)
 	}
 	return visible
 }
 
+// Locatable reports whether player may learn where target is (eval.GameState).
+func (g *Game) Locatable(player, target gamedb.DBRef) bool {
+	return Locatable(g, player, target)
+}
+
 // ConnTime returns connection time in seconds for a player (-1 if not connected).
 func (g *Game) ConnTime(player gamedb.DBRef) float64 {
 	descs := g.Conns.GetByPlayer(player)
@@ -51,7 +57,7 @@ func (g *Game) ConnTime(player gamedb.DBRef) float64 {
 	}
 	// Return the longest connection (first connected descriptor)
 	var longest time.Duration
-	now := time.Now()
+	now := g.Now()
 	for _, d := range descs {
 		dur := now.Sub(d.ConnTime)
 		if dur > longest {
@@ -69,7 +75,7 @@ func (g *Game) IdleTime(player gamedb.DBRef) float64 {
 	}
 	// Return the least idle descriptor
 	var leastIdle time.Duration = time.Duration(math.MaxInt64)
-	now := time.Now()
+	now := g.Now()
 	for _, d := range descs {
 		dur := now.Sub(d.LastCmd)
 		if dur < leastIdle {
@@ -213,28 +219,16 @@ func (g *Game) LookupPlayer(name string) gamedb.DBRef {
 	if name[0] == '*' {
 		name = name[1:]
 	}
-	// Try exact match first
-	for _, obj := range g.DB.Objects {
-		if obj.ObjType() == gamedb.TypePlayer && !obj.IsGoing() && strings.EqualFold(obj.Name, name) {
-			return obj.DBRef
-		}
+	// Try exact match first (name or alias)
+	if ref := g.DB.LookupPlayer(name); ref != gamedb.Nothing {
+		return ref
 	}
 	// Try prefix match
-	nameLower := strings.ToLower(name)
-	var match gamedb.DBRef = gamedb.Nothing
-	matchCount := 0
-	for _, obj := range g.DB.Objects {
-		if obj.ObjType() == gamedb.TypePlayer && !obj.IsGoing() {
-			if strings.HasPrefix(strings.ToLower(obj.Name), nameLower) {
-				match = obj.DBRef
-				matchCount++
-			}
-		}
-	}
-	if matchCount == 1 {
-		return match
+	matches := g.DB.PlayersByPrefix(name)
+	if len(matches) == 1 {
+		return matches[0]
 	}
-	if matchCount > 1 {
+	if len(matches) > 1 {
 		return gamedb.Ambiguous
 	}
 	return gamedb.Nothing
@@ -295,33 +289,51 @@ func (g *Game) CanReadAttrGS(player, obj gamedb.DBRef, attrNum int, rawValue str
 
 // SpellCheck returns misspelled words in text, considering player's custom dictionary.
 func (g *Game) SpellCheck(player gamedb.DBRef, text string, grammar bool) []string {
-	if g.Spell == nil {
+	sc := g.Spell
+	if sc == nil {
 		return nil
 	}
 	custom := g.gatherCustomWords(player)
-	if grammar {
-		issues := g.Spell.CheckTextWithGrammar(text, custom)
-		var words []string
-		for _, issue := range issues {
+	var words []string
+	g.spellWait(sc, func() {
+		if !grammar {
+			words = sc.CheckText(text, custom)
+			return
+		}
+		for _, issue := range sc.CheckTextWithGrammar(text, custom) {
 			words = append(words, issue.Word)
 		}
-		return words
-	}
-	return g.Spell.CheckText(text, custom)
+	})
+	return words
 }
 
 // SpellHighlight returns text with misspelled words highlighted.
 // Honors the player's ANSI flag for formatting.
 func (g *Game) SpellHighlight(player gamedb.DBRef, text string, grammar bool) string {
-	if g.Spell == nil {
+	sc := g.Spell
+	if sc == nil {
 		return text
 	}
 	custom := g.gatherCustomWords(player)
 	useAnsi := g.playerHasAnsi(player)
-	if grammar {
-		return g.Spell.HighlightTextWithGrammar(text, custom, useAnsi)
+	g.spellWait(sc, func() {
+		if grammar {
+			text = sc.HighlightTextWithGrammar(text, custom, useAnsi)
+		} else {
+			text = sc.HighlightText(text, custom, useAnsi)
+		}
+	})
+	return text
+}
+
+// spellWait runs a spell check, with the world lock released if it may
+// ask the remote checker.
+func (g *Game) spellWait(sc *SpellChecker, check func()) {
+	if sc.enabled && sc.apiURL != "" {
+		g.unlocked(check)
+		return
 	}
-	return g.Spell.HighlightText(text, custom, useAnsi)
+	check()
 }
 
 // ExecuteSQL executes a SQL query with permission checking.
@@ -338,6 +350,15 @@ func (g *Game) ExecuteSQL(player gamedb.DBRef, query, rowDelim, fieldDelim strin
 		return "#-1 PERMISSION DENIED"
 	}
 	result, err := g.SQLDB.Query(query, rowDelim, fieldDelim)
+	if err != nil && isSQLConnErr(err) && g.Conf != nil && g.Conf.SQLReconnect {
+		// sql_reconnect: reopen a dropped connection and try once more.
+		if rerr := g.SQLDB.Reconnect(); rerr != nil {
+			Logf(LogBugs, LevelError, "SQL reconnect: %v", rerr)
+		} else {
+			Logf(LogBugs, LevelWarn, "SQL connection lost (%v); reconnected", err)
+			result, err = g.SQLDB.Query(query, rowDelim, fieldDelim)
+		}
+	}
 	if err != nil {
 		return "#-1 " + strings.ToUpper(err.Error())
 	}
@@ -401,7 +422,7 @@ func (g *Game) EvalLockStr(player, thing, actor gamedb.DBRef, lockStr string) bo
 }
 
 // HelpLookup retrieves help text for a given topic from the named help file.
-func (g *Game) HelpLookup(_ gamedb.DBRef, fileID, topic string) string {
+func (g *Game) HelpLookup(player gamedb.DBRef, fileID, topic string) string {
 	var hf *HelpFile
 	switch strings.ToLower(fileID) {
 	case "help":
@@ -424,7 +445,7 @@ func (g *Game) HelpLookup(_ gamedb.DBRef, fileID, topic string) string {
 		return ""
 	}
 	if hf == nil { return "" }
-	return hf.Lookup(topic)
+	return hf.LookupFor(topic, Wizard(g, player))
 }
 
 // SessionInfo returns session statistics for a connected player.
@@ -432,7 +453,8 @@ func (g *Game) SessionInfo(player gamedb.DBRef) (int, int, int) {
 	descs := g.Conns.GetByPlayer(player)
 	if len(descs) == 0 { return -1, -1, -1 }
 	d := descs[0]
-	return d.CmdCount, d.BytesSent, d.BytesRecv
+	traffic := d.Traffic()
+	return d.CmdCount, traffic.Sent, traffic.Recv
 }
 
 // PersistStructDef saves or deletes a structure definition in bbolt.
@@ -459,6 +481,21 @@ func (g *Game) PersistStructInstance(player gamedb.DBRef, name string, inst *gam
 	}
 }
 
+// purgeStructs drops a destroyed object's structures and instances,
+// from memory and from bbolt.
+func (g *Game) purgeStructs(obj gamedb.DBRef) {
+	defs, insts := functions.PurgeStructs(obj)
+	if g.Store == nil {
+		return
+	}
+	for _, name := range insts {
+		g.Store.DeleteStructInstance(obj, name)
+	}
+	for _, name := range defs {
+		g.Store.DeleteStructDef(obj, name)
+	}
+}
+
 // MailCount returns (total, unread, cleared) for a player.
 func (g *Game) MailCount(player gamedb.DBRef) (int, int, int) {
 	if g.Mail == nil {
@@ -491,6 +528,118 @@ func (g *Game) MailSubject(player gamedb.DBRef, num int) string {
 	return msg.Subject
 }
 
+// MailStats returns "<read> <unread> <cleared>" for target's mailbox.
+// Players may check their own mailbox; Wizards may check anyone's.
+func (g *Game) MailStats(player, target gamedb.DBRef) string {
+	if g.Mail == nil {
+		return "#-1 MAIL SYSTEM DISABLED"
+	}
+	if player != target && !Wizard(g, player) {
+		return "#-1 PERMISSION DENIED"
+	}
+	total, unread, cleared := g.Mail.CountMessages(target)
+	return fmt.Sprintf("%d %d %d", total-unread, unread, cleared)
+}
+
+// MailList returns the space-separated message numbers in target's mailbox,
+// restricted to one folder unless folder is negative.
+func (g *Game) MailList(player, target gamedb.DBRef, folder int) string {
+	if g.Mail == nil {
+		return "#-1 MAIL SYSTEM DISABLED"
+	}
+	if player != target && !Wizard(g, player) {
+		return "#-1 PERMISSION DENIED"
+	}
+	var ids []string
+	for _, msg := range g.Mail.GetInbox(target) {
+		if folder >= 0 && msg.Folder != folder {
+			continue
+		}
+		ids = append(ids, strconv.Itoa(msg.ID))
+	}
+	return strings.Join(ids, " ")
+}
+
+// MailMessageField returns one field of message #num in target's mailbox.
+// Valid fields: body, subject, from, to, cc, time, flags, folder.
+// Players may read their own mail; Wizards may read anyone's.
+func (g *Game) MailMessageField(player, target gamedb.DBRef, num int, field string) string {
+	if g.Mail == nil {
+		return "#-1 MAIL SYSTEM DISABLED"
+	}
+	if player != target && !Wizard(g, player) {
+		return "#-1 PERMISSION DENIED"
+	}
+	msg := g.Mail.GetMessage(target, num)
+	if msg == nil {
+		return "#-1 NO SUCH MESSAGE"
+	}
+	switch strings.ToLower(field) {
+	case "", "body", "text":
+		return msg.Body
+	case "subject", "subj":
+		return msg.Subject
+	case "from":
+		return fmt.Sprintf("#%d", msg.From)
+	case "to":
+		return dbrefList(msg.To)
+	case "cc":
+		return dbrefList(msg.CC)
+	case "time":
+		return msg.Time.Format("Mon Jan 02 15:04:05 2006")
+	case "flags":
+		return FormatMailFlags(msg)
+	case "folder":
+		return strconv.Itoa(msg.Folder)
+	}
+	return "#-1 INVALID FIELD"
+}
+
+// MailMarkRead marks message #num in player's own mailbox as read.
+func (g *Game) MailMarkRead(player gamedb.DBRef, num int) {
+	if g.Mail == nil {
+		return
+	}
+	if g.Mail.MarkRead(player, num) {
+		persistMailMessage(g, player, g.Mail.GetMessage(player, num))
+	}
+}
+
+// MailSend delivers mail from player to a space- or comma-separated list
+// of recipient names. Guests and their objects may not send mail,
+// matching @mail. Returns "" on success or an error string.
+func (g *Game) MailSend(player gamedb.DBRef, recipients, subject, body string) string {
+	if g.Mail == nil {
+		return "#-1 MAIL SYSTEM DISABLED"
+	}
+	obj, ok := g.DB.Objects[player]
+	if !ok || g.IsGuest(player) || g.IsGuest(obj.Owner) {
+		return "#-1 PERMISSION DENIED"
+	}
+	var to []gamedb.DBRef
+	for _, name := range strings.Fields(strings.ReplaceAll(recipients, ",", " ")) {
+		ref := LookupPlayer(g.DB, name)
+		if ref == gamedb.Nothing {
+			return "#-1 NO SUCH PLAYER"
+		}
+		to = append(to, ref)
+	}
+	if len(to) == 0 {
+		return "#-1 NO RECIPIENTS"
+	}
+	g.sendMail(player, to, nil, subject, body)
+	return ""
+}
+
+// dbrefList formats refs as a space-separated list of #dbrefs.
+func dbrefList(refs []gamedb.DBRef) string {
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		parts[i] = fmt.Sprintf("#%d", ref)
+	}
+	return strings.Join(parts, " ")
+}
+
 // ChannelInfo returns a field value for a channel by name.
 // Requires the caller to be the channel owner or a Wizard.
 func (g *Game) ChannelInfo(player gamedb.DBRef, name, field string) string {
@@ -512,22 +661,7 @@ func (g *Game) ChannelInfo(player gamedb.DBRef, name, field string) string {
 	case "header":
 		return ch.Header
 	case "flags":
-		var flags []string
-		if ch.Flags&gamedb.ChanPublic != 0 {
-			flags = append(flags, "Public")
-		} else {
-			flags = append(flags, "Private")
-		}
-		if ch.Flags&gamedb.ChanLoud != 0 {
-			flags = append(flags, "Loud")
-		}
-		if ch.Flags&gamedb.ChanObject != 0 {
-			flags = append(flags, "Objects")
-		}
-		if ch.Flags&gamedb.ChanNoTitles != 0 {
-			flags = append(flags, "NoTitles")
-		}
-		return strings.Join(flags, " ")
+		return channelFlagString(ch)
 	case "numsent", "messages":
 		return fmt.Sprintf("%d", ch.NumSent)
 	case "subscribers", "numusers":
@@ -546,6 +680,124 @@ func (g *Game) ChannelInfo(player gamedb.DBRef, name, field string) string {
 	}
 }
 
+// ChannelList returns the sorted names of all channels visible to player.
+func (g *Game) ChannelList(player gamedb.DBRef) []string {
+	if g.Comsys == nil {
+		return nil
+	}
+	var names []string
+	for _, ch := range g.Comsys.AllChannels() {
+		if canSeeChannel(g, player, ch) {
+			names = append(names, ch.Name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names
+}
+
+// ChannelWho returns a space-separated dbref list of a channel's members.
+// If all is false, only connected players currently listening are included.
+func (g *Game) ChannelWho(player gamedb.DBRef, name string, all bool) string {
+	if g.Comsys == nil {
+		return "#-1 CHANNEL SYSTEM DISABLED"
+	}
+	ch := g.Comsys.GetChannel(name)
+	if ch == nil || !canSeeChannel(g, player, ch) {
+		return "#-1 CHANNEL NOT FOUND"
+	}
+	seen := make(map[gamedb.DBRef]bool)
+	var refs []gamedb.DBRef
+	for _, ca := range g.Comsys.ChannelSubscribers(ch.Name) {
+		if seen[ca.Player] {
+			continue
+		}
+		if !all && (!ca.IsListening || !g.Conns.IsConnected(ca.Player)) {
+			continue
+		}
+		seen[ca.Player] = true
+		refs = append(refs, ca.Player)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+	parts := make([]string, len(refs))
+	for i, r := range refs {
+		parts[i] = fmt.Sprintf("#%d", r)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ChannelFlags returns the flag list of a channel visible to player.
+func (g *Game) ChannelFlags(player gamedb.DBRef, name string) string {
+	if g.Comsys == nil {
+		return "#-1 CHANNEL SYSTEM DISABLED"
+	}
+	ch := g.Comsys.GetChannel(name)
+	if ch == nil || !canSeeChannel(g, player, ch) {
+		return "#-1 CHANNEL NOT FOUND"
+	}
+	return channelFlagString(ch)
+}
+
+// ChannelAliases returns target's channel aliases. Player must control target.
+func (g *Game) ChannelAliases(player, target gamedb.DBRef) string {
+	if g.Comsys == nil {
+		return "#-1 CHANNEL SYSTEM DISABLED"
+	}
+	if !Controls(g, player, target) && !CommAll(g, player) {
+		return "#-1 PERMISSION DENIED"
+	}
+	var names []string
+	for _, ca := range g.Comsys.PlayerAliases(target) {
+		names = append(names, ca.Alias)
+	}
+	return strings.Join(names, " ")
+}
+
+// ChannelAliasField returns a field of target's channel alias.
+// Valid fields: channel, title, status. Player must control target.
+func (g *Game) ChannelAliasField(player, target gamedb.DBRef, alias, field string) string {
+	if g.Comsys == nil {
+		return "#-1 CHANNEL SYSTEM DISABLED"
+	}
+	if !Controls(g, player, target) && !CommAll(g, player) {
+		return "#-1 PERMISSION DENIED"
+	}
+	ca := g.Comsys.LookupAlias(target, alias)
+	if ca == nil {
+		return "#-1 NO SUCH ALIAS"
+	}
+	switch strings.ToLower(field) {
+	case "channel":
+		return ca.Channel
+	case "title":
+		return ca.Title
+	case "status":
+		if ca.IsListening {
+			return "On"
+		}
+		return "Off"
+	}
+	return ""
+}
+
+// ChannelEmit sends a headerless message to a channel on behalf of player.
+// Requires channel ownership, Comm_All, or Wizard.
+func (g *Game) ChannelEmit(player gamedb.DBRef, name, message string) string {
+	if g.Comsys == nil {
+		return "#-1 CHANNEL SYSTEM DISABLED"
+	}
+	ch := g.Comsys.GetChannel(name)
+	if ch == nil {
+		return "#-1 CHANNEL NOT FOUND"
+	}
+	if !canEmitChannel(g, player, ch) {
+		return "#-1 PERMISSION DENIED"
+	}
+	g.ChannelEmitMessage(ch, player, message, true)
+	return ""
+}
+
 // ListAttrDefs returns a space-separated list of user-defined attribute names
 // matching the given pattern. Non-wizards only see VISUAL attr definitions.
 // parseObjTypeFilter converts a type name string to an ObjectType int (-1 if none).
@@ -674,6 +926,122 @@ func (g *Game) IsWizard(player gamedb.DBRef) bool {
 	return Wizard(g, player)
 }
 
+// CanObjeval reports whether player may evaluate from obj's viewpoint
+// with objeval(): obj must have the same owner as player, or player must
+// be a Wizard. Only God may borrow God's viewpoint.
+func (g *Game) CanObjeval(player, obj gamedb.DBRef) bool {
+	if _, ok := g.DB.Objects[obj]; !ok {
+		return false
+	}
+	if IsGod(g, obj) && !IsGod(g, player) {
+		return false
+	}
+	return ResolveOwner(g, obj) == ResolveOwner(g, player) || Wizard(g, player)
+}
+
+// TraceOutput sends TRACE output about obj to its owner.
+func (g *Game) TraceOutput(obj gamedb.DBRef, line string) {
+	g.Conns.SendToPlayer(ResolveOwner(g, obj), line)
+}
+
+// verboseEcho shows a VERBOSE object's owner each command it runs, as
+// "Name] command".
+func (g *Game) verboseEcho(obj gamedb.DBRef, command string) {
+	o, ok := g.DB.Objects[obj]
+	if !ok || !o.HasFlag(gamedb.FlagVerbose) {
+		return
+	}
+	g.Conns.SendToPlayer(ResolveOwner(g, obj), fmt.Sprintf("%s] %s", DisplayName(o.Name), command))
+}
+
+// UFunExecutor returns who u() of thing's attribute runs as. Like
+// TinyMUSH that's thing itself, unless the attribute was set by someone
+// other than thing's owner who is less privileged: code left on a
+// wizard's object by a mortal (kept through @chown, say) runs as its
+// author, so it can't borrow the wizard's powers.
+func (g *Game) UFunExecutor(thing gamedb.DBRef, rawAttr string) gamedb.DBRef {
+	info := ParseAttrInfo(rawAttr)
+	if info.Owner == gamedb.Nothing {
+		return thing
+	}
+	if _, ok := g.DB.Objects[info.Owner]; !ok {
+		return thing
+	}
+	owner := ResolveOwner(g, thing)
+	if info.Owner == owner || ResolveOwner(g, info.Owner) == owner {
+		return thing
+	}
+	if Wizard(g, thing) && !Wizard(g, info.Owner) {
+		return info.Owner
+	}
+	if IsGod(g, owner) && !IsGod(g, info.Owner) {
+		return info.Owner
+	}
+	return thing
+}
+
+// canSeeQueueOf reports whether player may inspect target's queue entries.
+func (g *Game) canSeeQueueOf(player, target gamedb.DBRef) bool {
+	return SeeQueue(g, player) || g.Controls(player, target)
+}
+
+// QueuePIDs returns the PIDs of target's queue entries, or of all entries
+// player may see when target is Nothing.
+func (g *Game) QueuePIDs(player, target gamedb.DBRef) string {
+	var match func(*QueueEntry) bool
+	switch {
+	case target != gamedb.Nothing:
+		if _, ok := g.DB.Objects[target]; !ok {
+			return "#-1 NOT FOUND"
+		}
+		if !g.canSeeQueueOf(player, target) {
+			return "#-1 PERMISSION DENIED"
+		}
+		match = func(e *QueueEntry) bool { return e.Player == target }
+	case SeeQueue(g, player):
+		match = func(*QueueEntry) bool { return true }
+	default:
+		owner := ResolveOwner(g, player)
+		match = func(e *QueueEntry) bool { return ResolveOwner(g, e.Player) == owner }
+	}
+	entries := g.Queue.Matching(match)
+	pids := make([]string, len(entries))
+	for i, e := range entries {
+		pids[i] = strconv.Itoa(e.PID)
+	}
+	return strings.Join(pids, " ")
+}
+
+// QueueEntries returns the number of queue entries run by target.
+func (g *Game) QueueEntries(player, target gamedb.DBRef) string {
+	if _, ok := g.DB.Objects[target]; !ok {
+		return "#-1 NOT FOUND"
+	}
+	if !g.canSeeQueueOf(player, target) {
+		return "#-1 PERMISSION DENIED"
+	}
+	n := len(g.Queue.Matching(func(e *QueueEntry) bool { return e.Player == target }))
+	return strconv.Itoa(n)
+}
+
+// SemaphoreCount returns the number of commands waiting on target's
+// semaphore attribute.
+func (g *Game) SemaphoreCount(player, target gamedb.DBRef, attrName string) string {
+	if _, ok := g.DB.Objects[target]; !ok {
+		return "#-1 NOT FOUND"
+	}
+	if !g.canSeeQueueOf(player, target) {
+		return "#-1 PERMISSION DENIED"
+	}
+	attr := gamedb.A_SEMAPHORE
+	if attrName != "" {
+		if attr = g.ResolveAttrNum(attrName); attr <= 0 {
+			return "#-1 NO SUCH ATTRIBUTE"
+		}
+	}
+	return strconv.Itoa(g.Queue.SemaphoreWaiters(target, attr))
+}
+
 // sortStrings sorts a slice of strings in place.
 func sortStrings(s []string) {
 	for i := 1; i < len(s); i++ {
@@ -194,7 +194,7 @@ func (g *Game) CreateGuest() (gamedb.DBRef, string) {
 	}
 
 	// Copy non-internal attributes from template
-	for _, attr := range template.Attrs {
+	for _, attr := range template.Attributes() {
 		info := ParseAttrInfo(attr.Value)
 		if info.Flags&gamedb.AFInternal != 0 {
 			continue
@@ -204,7 +204,7 @@ func (g *Game) CreateGuest() (gamedb.DBRef, string) {
 			continue
 		}
 		// Copy the raw attribute value (preserves \x01owner:flags:text format)
-		guestObj.Attrs = append(guestObj.Attrs, gamedb.Attribute{
+		guestObj.Attrs = append(guestObj.Attributes(), gamedb.Attribute{
 			Number: attr.Number,
 			Value:  attr.Value,
 		})
@@ -255,9 +255,11 @@ func (g *Game) DestroyGuest(ref gamedb.DBRef) {
 
 	// Untrack
 	g.Guests.Untrack(ref)
+	g.purgeStructs(ref)
 
 	// Delete the object from memory
 	delete(g.DB.Objects, ref)
+	g.DB.UnindexPlayer(ref)
 
 	log.Printf("guest: destroyed %s(#%d)", obj.Name, ref)
 }
@@ -283,6 +285,10 @@ func (s *Server) handleGuest(d *Descriptor) {
 		d.Send("Guest logins are not enabled on this server.")
 		return
 	}
+	if s.Game.SiteRuleFor(d.Addr, gamedb.SiteNoGuest) != nil {
+		d.Send("Guest logins are not allowed from your site.")
+		return
+	}
 
 	// Phase 1: Clean up disconnected guests
 	cleaned := s.Game.CleanupDisconnectedGuests()
@@ -305,10 +311,11 @@ func (s *Server) handleGuest(d *Descriptor) {
 
 	// Phase 4: Log in
 	s.Game.Conns.Login(d, ref)
+	s.Game.noteLogin(d)
 	if guestObj, ok := s.Game.DB.Objects[ref]; ok {
 		guestObj.Flags[1] |= gamedb.Flag2Connected
 	}
-	log.Printf("[%d] Guest %s(#%d) connected from %s", d.ID, name, ref, d.Addr)
+	Logf(LogConnections, LevelInfo, "[%d] Guest %s(#%d) connected from %s", d.ID, name, ref, d.Addr)
 
 	d.Send(fmt.Sprintf("Welcome, %s! You are connected as a guest.", name))
 
@@ -328,6 +335,5 @@ func (s *Server) handleGuest(d *Descriptor) {
 	s.Game.ShowRoom(d, loc)
 
 	// Fire ACONNECT
-	connCount := len(s.Game.Conns.GetByPlayer(ref))
-	s.Game.FireConnectAttr(ref, connCount, 39) // A_ACONNECT = 39
+	s.Game.fireAconnect(d, "guest")
 }
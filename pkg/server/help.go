@@ -7,14 +7,25 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"unicode"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
 )
 
 // HelpFile holds parsed help entries from a TinyMUSH-format help text file.
-// Entries are separated by lines starting with "& topicname".
+// Entries are separated by lines starting with "& topicname"; an entry
+// started with "&& topicname" is shown only to wizards.
 type HelpFile struct {
-	Entries map[string]string // lowercase topic -> text content
+	Entries map[string]string   // lowercase topic -> text content
+	Wizard  map[string]bool     // Topics only wizards see
+	Refs    map[string][]string // Topic -> the topics its "See also" names
+	topics  []string            // Sorted topics, for prefix matching
+	words   map[string][]string // Keyword index: word -> topics whose text has it
 }
 
+// maxHelpSuggestions bounds the topics offered when none matches.
+const maxHelpSuggestions = 10
+
 // LoadHelpFile parses a TinyMUSH help .txt file and returns a HelpFile.
 // Returns nil if the file cannot be opened.
 func LoadHelpFile(path string) *HelpFile {
@@ -24,12 +35,13 @@ func LoadHelpFile(path string) *HelpFile {
 	}
 	defer f.Close()
 
-	hf := &HelpFile{Entries: make(map[string]string)}
+	hf := &HelpFile{Entries: make(map[string]string), Wizard: make(map[string]bool)}
 	scanner := bufio.NewScanner(f)
 
 	// Topics can have multiple "& TOPIC" aliases (e.g. & ESCAPE() / & NESCAPE())
 	// that all share the same content body. Collect all topic names for each entry.
 	var currentTopics []string
+	var currentWizard bool
 	var buf strings.Builder
 
 	saveEntry := func() {
@@ -39,20 +51,26 @@ func LoadHelpFile(path string) *HelpFile {
 		text := strings.TrimRight(buf.String(), "\n ")
 		for _, topic := range currentTopics {
 			hf.Entries[strings.ToLower(topic)] = text
+			if currentWizard {
+				hf.Wizard[strings.ToLower(topic)] = true
+			}
 		}
 	}
 
 	for scanner.Scan() {
 		line := scanner.Text()
-		if strings.HasPrefix(line, "& ") {
-			topic := strings.TrimSpace(line[2:])
+		if strings.HasPrefix(line, "& ") || strings.HasPrefix(line, "&& ") {
+			wizard := strings.HasPrefix(line, "&& ")
+			topic := strings.TrimSpace(strings.TrimLeft(line, "&"))
 			if buf.Len() == 0 && len(currentTopics) > 0 {
 				// Another alias for the same entry (no content yet)
 				currentTopics = append(currentTopics, topic)
+				currentWizard = currentWizard || wizard
 			} else {
 				// New entry — save the previous one
 				saveEntry()
 				currentTopics = []string{topic}
+				currentWizard = wizard
 				buf.Reset()
 			}
 		} else {
@@ -65,13 +83,87 @@ func LoadHelpFile(path string) *HelpFile {
 	// Save last entry
 	saveEntry()
 
+	hf.index()
 	return hf
 }
 
-// Lookup finds a help entry by topic name. Tries exact match first,
-// then prefix match (e.g. "help @swi" matches "@switch").
-// If the topic contains wildcards (* or ?), returns a list of matching topics.
-func (hf *HelpFile) Lookup(topic string) string {
+// index rebuilds the topic list, cross-references and keyword index.
+func (hf *HelpFile) index() {
+	hf.topics = make([]string, 0, len(hf.Entries))
+	hf.Refs = make(map[string][]string)
+	hf.words = make(map[string][]string)
+	for topic, text := range hf.Entries {
+		hf.topics = append(hf.topics, topic)
+		if refs := seeAlso(text); len(refs) > 0 {
+			hf.Refs[topic] = refs
+		}
+		seen := make(map[string]bool)
+		for _, w := range helpWords(text) {
+			if !seen[w] {
+				seen[w] = true
+				hf.words[w] = append(hf.words[w], topic)
+			}
+		}
+	}
+	sort.Strings(hf.topics)
+}
+
+// helpWords splits text into the lowercase words the keyword index holds.
+func helpWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("@+_()", r)
+	})
+}
+
+// seeAlso returns the topics named on an entry's "See also" lines.
+func seeAlso(text string) []string {
+	var refs []string
+	for _, line := range strings.Split(text, "\n") {
+		_, list, ok := cutSeeAlso(line)
+		if !ok {
+			continue
+		}
+		for _, ref := range strings.Split(list, ",") {
+			if ref = strings.ToLower(strings.TrimRight(strings.TrimSpace(ref), ".")); ref != "" {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// cutSeeAlso splits a "See also: a, b" line into its lead and its list.
+func cutSeeAlso(line string) (lead, list string, ok bool) {
+	i := strings.Index(strings.ToLower(line), "see also")
+	if i < 0 {
+		return "", "", false
+	}
+	lead, list = line[:i+len("see also")], line[i+len("see also"):]
+	if len(list) == 0 || (list[0] != ':' && list[0] != ';') {
+		return "", "", false
+	}
+	return lead + list[:1], list[1:], true
+}
+
+// visible reports whether a player, a wizard or not, may see topic.
+func (hf *HelpFile) visible(topic string, wizard bool) bool {
+	return wizard || !hf.Wizard[topic]
+}
+
+// HelpMatch is what a help lookup found: an entry, or failing that the
+// topics it could mean.
+type HelpMatch struct {
+	Topic       string
+	Text        string
+	Ambiguous   []string // Several topics start with what was asked for
+	Suggestions []string // Nothing did; these contain it or mention it
+}
+
+// Find looks up a topic for a player: an exact match, else the one topic
+// starting with it (or the shortest, if the others only extend it, as
+// @function2 does @function). Wildcards list the matching topics. Topics
+// only wizards see are left out for everyone else.
+func (hf *HelpFile) Find(topic string, wizard bool) HelpMatch {
 	topic = strings.ToLower(strings.TrimSpace(topic))
 	if topic == "" {
 		topic = "help"
@@ -80,38 +172,172 @@ func (hf *HelpFile) Lookup(topic string) string {
 	// Wildcard search
 	if strings.ContainsAny(topic, "*?") {
 		var matches []string
-		for key := range hf.Entries {
-			if wildMatchSimple(topic, key) {
+		for _, key := range hf.topics {
+			if hf.visible(key, wizard) && wildMatchSimple(topic, key) {
 				matches = append(matches, key)
 			}
 		}
 		if len(matches) == 0 {
-			return ""
+			return HelpMatch{}
 		}
-		sort.Strings(matches)
-		return fmt.Sprintf("Here are the entries which match '%s':\n  %s",
-			topic, strings.Join(matches, "  "))
+		return HelpMatch{Topic: topic, Text: fmt.Sprintf("Here are the entries which match '%s':\n  %s",
+			topic, strings.Join(matches, "  "))}
 	}
 
 	// Exact match
-	if text, ok := hf.Entries[topic]; ok {
-		return text
+	if text, ok := hf.Entries[topic]; ok && hf.visible(topic, wizard) {
+		return HelpMatch{Topic: topic, Text: text}
+	}
+
+	// Prefix match
+	var matches []string
+	for i := sort.SearchStrings(hf.topics, topic); i < len(hf.topics) && strings.HasPrefix(hf.topics[i], topic); i++ {
+		if hf.visible(hf.topics[i], wizard) {
+			matches = append(matches, hf.topics[i])
+		}
+	}
+	if len(matches) > 0 {
+		best := shortestTopic(matches)
+		for _, key := range matches {
+			if !strings.HasPrefix(key, best) {
+				return HelpMatch{Ambiguous: matches}
+			}
+		}
+		return HelpMatch{Topic: best, Text: hf.Entries[best]}
+	}
+
+	return HelpMatch{Suggestions: hf.suggest(topic, wizard)}
+}
+
+// shortestTopic returns the shortest of topics, the first if tied.
+func shortestTopic(topics []string) string {
+	best := topics[0]
+	for _, t := range topics[1:] {
+		if len(t) < len(best) {
+			best = t
+		}
+	}
+	return best
+}
+
+// suggest offers topics for one that matched nothing: those containing
+// it, then those whose text has all its words.
+func (hf *HelpFile) suggest(topic string, wizard bool) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, key := range hf.topics {
+		if len(out) < maxHelpSuggestions && strings.Contains(key, topic) && hf.visible(key, wizard) {
+			out = append(out, key)
+			seen[key] = true
+		}
+	}
+	for _, key := range hf.Search(topic, wizard) {
+		if len(out) >= maxHelpSuggestions {
+			break
+		}
+		if !seen[key] {
+			out = append(out, key)
+		}
 	}
+	return out
+}
 
-	// Prefix match — find the shortest key that starts with topic
-	var bestKey string
-	for key := range hf.Entries {
-		if strings.HasPrefix(key, topic) {
-			if bestKey == "" || len(key) < len(bestKey) {
-				bestKey = key
+// Search returns the topics whose text has every word of words, sorted.
+func (hf *HelpFile) Search(words string, wizard bool) []string {
+	var found map[string]bool
+	for _, w := range helpWords(words) {
+		next := make(map[string]bool)
+		for _, key := range hf.words[w] {
+			if found == nil || found[key] {
+				next[key] = true
 			}
 		}
+		found = next
 	}
-	if bestKey != "" {
-		return hf.Entries[bestKey]
+	var out []string
+	for key := range found {
+		if hf.visible(key, wizard) {
+			out = append(out, key)
+		}
 	}
+	sort.Strings(out)
+	return out
+}
 
-	return ""
+// Lookup finds a help entry by topic name, as a wizard would. Tries exact
+// match first, then prefix match (e.g. "help @swi" matches "@switch"),
+// taking the shortest topic if several match. If the topic contains
+// wildcards (* or ?), returns a list of matching topics.
+func (hf *HelpFile) Lookup(topic string) string {
+	return hf.LookupFor(topic, true)
+}
+
+// LookupFor is Lookup for a player who may not be a wizard.
+func (hf *HelpFile) LookupFor(topic string, wizard bool) string {
+	m := hf.Find(topic, wizard)
+	if len(m.Ambiguous) > 0 {
+		return hf.Entries[shortestTopic(m.Ambiguous)]
+	}
+	return m.Text
+}
+
+// entry returns topic's text exactly, if hf has it. hf may be nil.
+func (hf *HelpFile) entry(topic string) (string, bool) {
+	if hf == nil {
+		return "", false
+	}
+	text, ok := hf.Entries[topic]
+	return text, ok
+}
+
+// Set adds or replaces an entry.
+func (hf *HelpFile) Set(topic, text string, wizard bool) {
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	hf.Entries[topic] = text
+	if wizard {
+		hf.Wizard[topic] = true
+	} else {
+		delete(hf.Wizard, topic)
+	}
+	hf.index()
+}
+
+// Delete removes an entry, reporting whether there was one.
+func (hf *HelpFile) Delete(topic string) bool {
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	if _, ok := hf.Entries[topic]; !ok {
+		return false
+	}
+	delete(hf.Entries, topic)
+	delete(hf.Wizard, topic)
+	hf.index()
+	return true
+}
+
+// render highlights the topics an entry's "See also" names that the
+// player can look up, for players with ANSI.
+func (hf *HelpFile) render(text string, wizard bool) string {
+	if len(hf.Refs) == 0 || !strings.Contains(strings.ToLower(text), "see also") {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lead, list, ok := cutSeeAlso(line)
+		if !ok {
+			continue
+		}
+		refs := strings.Split(list, ",")
+		for j, ref := range refs {
+			name := strings.TrimRight(strings.TrimSpace(ref), ".")
+			key := strings.ToLower(name)
+			if _, ok := hf.Entries[key]; name == "" || !ok || !hf.visible(key, wizard) {
+				continue
+			}
+			refs[j] = strings.Replace(ref, name, eval.AnsiCode('h')+name+eval.AnsiCode('n'), 1)
+		}
+		lines[i] = lead + strings.Join(refs, ",")
+	}
+	return strings.Join(lines, "\n")
 }
 
 // LoadHelpFiles loads all help files from the text directory into the Game.
@@ -133,143 +359,99 @@ func (g *Game) LoadHelpFiles(textDir string) {
 	g.HelpMan = load("mushman.txt")
 	g.HelpWizNews = load("wiznews.txt")
 	g.HelpJobs = load("jhelp.txt")
+	g.reapplyHelpText()
 }
 
 // --- Help commands ---
 
-func cmdHelp(g *Game, d *Descriptor, args string, _ []string) {
-	if g.HelpMain == nil {
-		d.Send("No help available.")
+// maxHelpList bounds the topics listed for an ambiguous topic or a search.
+const maxHelpList = 40
+
+// sendHelp shows d the entry in hf for args, or what it might have meant.
+// With /search it lists the entries that mention args instead. If hf isn't
+// loaded, d is sent none.
+func sendHelp(g *Game, d *Descriptor, hf *HelpFile, args string, switches []string, none string) {
+	if hf == nil {
+		d.Send(none)
+		return
+	}
+	wizard := Wizard(g, d.Player)
+	args = strings.TrimSpace(args)
+	if HasSwitch(switches, "search") {
+		if args == "" {
+			d.Send("Search for what?")
+			return
+		}
+		found := hf.Search(args, wizard)
+		if len(found) == 0 {
+			d.Send(fmt.Sprintf("No entries mention '%s'.", args))
+			return
+		}
+		d.Send(fmt.Sprintf("Entries mentioning '%s':\n  %s", args, helpList(found)))
 		return
 	}
 	if args == "" {
 		args = "help"
 	}
-	text := g.HelpMain.Lookup(args)
-	if text == "" {
+	m := hf.Find(args, wizard)
+	switch {
+	case m.Text != "":
+		d.Send(hf.render(m.Text, wizard))
+	case len(m.Ambiguous) > 0:
+		d.Send(fmt.Sprintf("'%s' matches several entries:\n  %s", args, helpList(m.Ambiguous)))
+	default:
 		d.Send(fmt.Sprintf("No entry for '%s'.", args))
-		return
+		if len(m.Suggestions) > 0 {
+			d.Send("Perhaps you meant: " + strings.Join(m.Suggestions, ", "))
+		}
 	}
-	d.Send(text)
 }
 
-func cmdQhelp(g *Game, d *Descriptor, args string, _ []string) {
-	if g.HelpQuick == nil {
-		d.Send("No quick help available.")
-		return
-	}
-	if args == "" {
-		args = "help"
+// helpList joins topics for a listing, at most maxHelpList of them.
+func helpList(topics []string) string {
+	if len(topics) <= maxHelpList {
+		return strings.Join(topics, "  ")
 	}
-	text := g.HelpQuick.Lookup(args)
-	if text == "" {
-		d.Send(fmt.Sprintf("No entry for '%s'.", args))
-		return
-	}
-	d.Send(text)
+	return fmt.Sprintf("%s  (and %d more)", strings.Join(topics[:maxHelpList], "  "), len(topics)-maxHelpList)
 }
 
-func cmdWizhelp(g *Game, d *Descriptor, args string, _ []string) {
+func cmdHelp(g *Game, d *Descriptor, args string, switches []string) {
+	sendHelp(g, d, g.HelpMain, args, switches, "No help available.")
+}
+
+func cmdQhelp(g *Game, d *Descriptor, args string, switches []string) {
+	sendHelp(g, d, g.HelpQuick, args, switches, "No quick help available.")
+}
+
+func cmdWizhelp(g *Game, d *Descriptor, args string, switches []string) {
 	// Wizard-only
 	if !Wizard(g, d.Player) {
 		d.Send("Permission denied.")
 		return
 	}
-	if g.HelpWiz == nil {
-		d.Send("No wizard help available.")
-		return
-	}
-	if args == "" {
-		args = "help"
-	}
-	text := g.HelpWiz.Lookup(args)
-	if text == "" {
-		d.Send(fmt.Sprintf("No entry for '%s'.", args))
-		return
-	}
-	d.Send(text)
+	sendHelp(g, d, g.HelpWiz, args, switches, "No wizard help available.")
 }
 
-func cmdNews(g *Game, d *Descriptor, args string, _ []string) {
-	if g.HelpNews == nil {
-		d.Send("No news available.")
-		return
-	}
-	if args == "" {
-		args = "help"
-	}
-	text := g.HelpNews.Lookup(args)
-	if text == "" {
-		d.Send(fmt.Sprintf("No entry for '%s'.", args))
-		return
-	}
-	d.Send(text)
+func cmdNews(g *Game, d *Descriptor, args string, switches []string) {
+	sendHelp(g, d, g.HelpNews, args, switches, "No news available.")
 }
 
-func cmdPlusHelp(g *Game, d *Descriptor, args string, _ []string) {
-	if g.HelpPlus == nil {
-		d.Send("No +help available.")
-		return
-	}
-	if args == "" {
-		args = "help"
-	}
-	text := g.HelpPlus.Lookup(args)
-	if text == "" {
-		d.Send(fmt.Sprintf("No entry for '%s'.", args))
-		return
-	}
-	d.Send(text)
+func cmdPlusHelp(g *Game, d *Descriptor, args string, switches []string) {
+	sendHelp(g, d, g.HelpPlus, args, switches, "No +help available.")
 }
 
-func cmdMan(g *Game, d *Descriptor, args string, _ []string) {
-	if g.HelpMan == nil {
-		d.Send("No manual available.")
-		return
-	}
-	if args == "" {
-		args = "help"
-	}
-	text := g.HelpMan.Lookup(args)
-	if text == "" {
-		d.Send(fmt.Sprintf("No entry for '%s'.", args))
-		return
-	}
-	d.Send(text)
+func cmdMan(g *Game, d *Descriptor, args string, switches []string) {
+	sendHelp(g, d, g.HelpMan, args, switches, "No manual available.")
 }
 
-func cmdWizNews(g *Game, d *Descriptor, args string, _ []string) {
+func cmdWizNews(g *Game, d *Descriptor, args string, switches []string) {
 	if !Wizard(g, d.Player) {
 		d.Send("Permission denied.")
 		return
 	}
-	if g.HelpWizNews == nil {
-		d.Send("No wizard news available.")
-		return
-	}
-	if args == "" {
-		args = "help"
-	}
-	text := g.HelpWizNews.Lookup(args)
-	if text == "" {
-		d.Send(fmt.Sprintf("No entry for '%s'.", args))
-		return
-	}
-	d.Send(text)
+	sendHelp(g, d, g.HelpWizNews, args, switches, "No wizard news available.")
 }
 
-func cmdJhelp(g *Game, d *Descriptor, args string, _ []string) {
-	if g.HelpJobs == nil {
-		d.Send("No +jhelp available.")
-		return
-	}
-	if args == "" {
-		args = "help"
-	}
-	text := g.HelpJobs.Lookup(args)
-	if text == "" {
-		d.Send(fmt.Sprintf("No entry for '%s'.", args))
-		return
-	}
-	d.Send(text)
+func cmdJhelp(g *Game, d *Descriptor, args string, switches []string) {
+	sendHelp(g, d, g.HelpJobs, args, switches, "No +jhelp available.")
 }
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// @helptext adds, replaces and removes help entries in-game. The changes
+// are kept as runtime settings and laid over the help files each time
+// they are loaded, so they survive restarts and @readcache.
+
+// @helptext runtime setting flags.
+const (
+	helpTextWizard  = 0x0001 // Only wizards see the entry
+	helpTextDeleted = 0x0002 // The entry is removed
+)
+
+// helpTextFiles are the help files @helptext edits; the switch names one,
+// help.txt if none does.
+var helpTextFiles = []string{"wizhelp", "news", "plushelp", "wiznews"}
+
+// helpFileField returns the Game field holding a help file, by name.
+func (g *Game) helpFileField(file string) **HelpFile {
+	switch file {
+	case "help":
+		return &g.HelpMain
+	case "wizhelp":
+		return &g.HelpWiz
+	case "news":
+		return &g.HelpNews
+	case "plushelp":
+		return &g.HelpPlus
+	case "wiznews":
+		return &g.HelpWizNews
+	}
+	return nil
+}
+
+// applyHelpText lays an @helptext change over its help file.
+func (g *Game) applyHelpText(rs *gamedb.RuntimeSetting) error {
+	file, topic, _ := strings.Cut(rs.Name, ":")
+	field := g.helpFileField(file)
+	if field == nil {
+		return fmt.Errorf("no help file %q", file)
+	}
+	if *field == nil {
+		*field = &HelpFile{Entries: make(map[string]string), Wizard: make(map[string]bool)}
+	}
+	if rs.Flags&helpTextDeleted != 0 {
+		(*field).Delete(topic)
+	} else {
+		(*field).Set(topic, rs.Value, rs.Flags&helpTextWizard != 0)
+	}
+	return nil
+}
+
+// reapplyHelpText lays the @helptext changes over freshly loaded help
+// files.
+func (g *Game) reapplyHelpText() {
+	for _, rs := range g.Runtime {
+		if rs.Kind == gamedb.RuntimeHelpText {
+			g.applyHelpText(rs)
+		}
+	}
+}
+
+func cmdHelptext(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	if HasSwitch(switches, "list") {
+		helpTextList(g, d)
+		return
+	}
+	file := "help"
+	for _, f := range helpTextFiles {
+		if HasSwitch(switches, f) {
+			file = f
+		}
+	}
+	topic, text, hasText := strings.Cut(args, "=")
+	topic = strings.ToLower(strings.TrimSpace(topic))
+	if topic == "" || strings.ContainsAny(topic, "*?") {
+		d.Send("Usage: @helptext[/<switches>] <topic>=<text>")
+		return
+	}
+	rs := &gamedb.RuntimeSetting{Kind: gamedb.RuntimeHelpText, Name: file + ":" + topic, Setter: d.Player, Changed: g.Now()}
+
+	if HasSwitch(switches, "delete") || (hasText && strings.TrimSpace(text) == "") {
+		hf := *g.helpFileField(file)
+		if _, ok := hf.entry(topic); !ok {
+			d.Send(fmt.Sprintf("No entry for '%s'.", topic))
+			return
+		}
+		rs.Flags = helpTextDeleted
+		g.applyHelpText(rs)
+		g.putRuntime(rs)
+		Logf(LogWizard, LevelInfo, "@helptext: %s(#%d) removed %s '%s'", g.PlayerName(d.Player), d.Player, file, topic)
+		d.Send(fmt.Sprintf("Removed %s entry '%s'.", file, topic))
+		return
+	}
+	if !hasText {
+		d.Send("Usage: @helptext[/<switches>] <topic>=<text>")
+		return
+	}
+
+	ctx := MakeEvalContextWithGame(g, d.Player, func(c *eval.EvalContext) {
+		functions.RegisterAll(c)
+	})
+	defer ctx.Release()
+	rs.Value = strings.ReplaceAll(ctx.Exec(strings.TrimSpace(text), eval.EvFCheck|eval.EvEval, nil), "\r\n", "\n")
+	if HasSwitch(switches, "wizard") {
+		rs.Flags = helpTextWizard
+	}
+	g.applyHelpText(rs)
+	g.putRuntime(rs)
+	Logf(LogWizard, LevelInfo, "@helptext: %s(#%d) set %s '%s'", g.PlayerName(d.Player), d.Player, file, topic)
+	d.Send(fmt.Sprintf("Set %s entry '%s'.", file, topic))
+}
+
+// helpTextList implements @helptext/list: the entries changed in-game,
+// with who changed them.
+func helpTextList(g *Game, d *Descriptor) {
+	var lines []string
+	for _, rs := range g.Runtime {
+		if rs.Kind != gamedb.RuntimeHelpText {
+			continue
+		}
+		note := ""
+		switch {
+		case rs.Flags&helpTextDeleted != 0:
+			note = " (removed)"
+		case rs.Flags&helpTextWizard != 0:
+			note = " (wizards only)"
+		}
+		lines = append(lines, fmt.Sprintf("  %s%s  [%s(#%d) %s]", rs.Name, note,
+			g.PlayerName(rs.Setter), rs.Setter, rs.Changed.Format("2006-01-02 15:04")))
+	}
+	if len(lines) == 0 {
+		d.Send("No help entries have been changed in-game.")
+		return
+	}
+	sort.Strings(lines)
+	d.Send("Help entries changed in-game:")
+	for _, l := range lines {
+		d.Send(l)
+	}
+}
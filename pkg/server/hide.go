@@ -0,0 +1,31 @@
+package server
+
+import "github.com/crystal-mush/gotinymush/pkg/gamedb"
+
+// cmdHide implements @hide: players who may hide (wizards and the hide
+// power) go DARK, dropping off WHO, lwho() and connect messages for
+// those who can't see hidden players.
+func cmdHide(g *Game, d *Descriptor, _ string, _ []string) {
+	setHidden(g, d, true)
+}
+
+// cmdUnhide implements @unhide, reversing @hide.
+func cmdUnhide(g *Game, d *Descriptor, _ string, _ []string) {
+	setHidden(g, d, false)
+}
+
+func setHidden(g *Game, d *Descriptor, hide bool) {
+	obj, ok := g.DB.Objects[d.Player]
+	if !ok || obj.ObjType() != gamedb.TypePlayer || !CanHide(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	if hide {
+		obj.Flags[0] |= gamedb.FlagDark
+		d.Send("You are now hidden.")
+	} else {
+		obj.Flags[0] &^= gamedb.FlagDark
+		d.Send("You are no longer hidden.")
+	}
+	g.PersistObject(obj)
+}
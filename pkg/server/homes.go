@@ -0,0 +1,181 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	aOKill = 10 // A_OKILL
+	aKill  = 11 // A_KILL
+	aAKill = 15 // A_AKILL
+)
+
+// sendHome moves obj to its home, or a safe place if its home is gone,
+// announcing the move to both rooms and showing a player where they
+// ended up. It returns where obj went.
+func (g *Game) sendHome(obj gamedb.DBRef) gamedb.DBRef {
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return gamedb.Nothing
+	}
+	oldLoc := o.Location
+	home := g.safeHome(obj, gamedb.Nothing)
+	if home == gamedb.Nothing || home == oldLoc {
+		return oldLoc
+	}
+	g.MoveObject(obj, home, walk)
+	return home
+}
+
+// killCosts returns kill_min, kill_max and kill_guarantee.
+func (g *Game) killCosts() (min, max, guarantee int) {
+	if g.Conf == nil {
+		return 10, 100, 100
+	}
+	return g.Conf.KillMin, g.Conf.KillMax, g.Conf.KillGuarantee
+}
+
+// unkillable reports why killer can't kill victim, or "" if they can: a
+// mortal can't kill in a HAVEN room or in a room the victim controls and
+// they don't, and nobody kills wizards, immortals or the unkillable.
+func (g *Game) unkillable(killer, victim gamedb.DBRef) string {
+	v, ok := g.DB.Objects[victim]
+	if !ok {
+		return "Sorry."
+	}
+	if Wizard(g, victim) || v.HasPower(0, gamedb.PowUnkillable) || v.HasFlag(gamedb.FlagImmortal) {
+		return "That wouldn't be wise."
+	}
+	if owner, ok := g.DB.Objects[v.Owner]; ok && owner.HasFlag(gamedb.FlagImmortal) &&
+		(v.HasFlag(gamedb.FlagInherit) || owner.HasFlag(gamedb.FlagInherit)) {
+		return "That wouldn't be wise."
+	}
+	if Wizard(g, killer) {
+		return ""
+	}
+	if loc, ok := g.DB.Objects[v.Location]; ok && loc.HasFlag(gamedb.FlagHaven) {
+		return "Sorry."
+	}
+	if g.Controls(victim, v.Location) && !g.Controls(killer, v.Location) {
+		return "Sorry."
+	}
+	return ""
+}
+
+// cmdKill implements kill <victim>[=<cost>]. The cost, held between
+// kill_min and kill_max, buys a cost in kill_guarantee chance of
+// success; wizards always succeed. A victim that's killed is halted,
+// paid half the cost as insurance and sent home.
+func cmdKill(g *Game, d *Descriptor, args string, _ []string) {
+	if args == "" {
+		d.Send("Kill whom?")
+		return
+	}
+	targetStr, costStr, hasCost := strings.Cut(args, "=")
+	target := g.MatchObject(d.Player, strings.TrimSpace(targetStr))
+	if target == gamedb.Nothing {
+		d.Send("I don't see that here.")
+		return
+	}
+	targetObj, ok := g.DB.Objects[target]
+	if !ok {
+		return
+	}
+	if t := targetObj.ObjType(); t != gamedb.TypePlayer && t != gamedb.TypeThing {
+		d.Send("You can't kill that!")
+		return
+	}
+	if why := g.unkillable(d.Player, target); why != "" {
+		d.Send(why)
+		return
+	}
+
+	minCost, maxCost, guarantee := g.killCosts()
+	cost := minCost
+	if hasCost {
+		cost, _ = strconv.Atoi(strings.TrimSpace(costStr))
+	}
+	if cost < minCost {
+		cost = minCost
+	}
+	if cost > maxCost {
+		cost = maxCost
+	}
+	if !g.payFor(d.Player, cost) {
+		d.Send(fmt.Sprintf("You don't have enough %s.", g.MoneyName(2)))
+		return
+	}
+
+	killerName := g.PlayerName(d.Player)
+	if !Wizard(g, d.Player) && guarantee > 0 && rand.Intn(guarantee) >= cost {
+		d.Send("Your murder attempt failed.")
+		g.Conns.SendToPlayer(target, fmt.Sprintf("%s tried to kill you!", killerName))
+		return
+	}
+
+	if g.isSuspect(d.Player) {
+		g.reportSuspect(d.Player, "command", fmt.Sprintf("killed %s(#%d).", targetObj.Name, target))
+	}
+	g.Queue.HaltPlayer(target)
+	if g.GetAttrText(target, aKill) == "" {
+		d.Send(fmt.Sprintf("You killed %s!", DisplayName(targetObj.Name)))
+	}
+	if g.GetAttrText(target, aOKill) == "" {
+		g.Conns.SendToRoomExcept(g.DB, targetObj.Location, d.Player,
+			fmt.Sprintf("%s killed %s!", killerName, DisplayName(targetObj.Name)))
+	}
+	g.DidIt(d.Player, target, aKill, aOKill, aAKill)
+	g.Conns.SendToPlayer(target, fmt.Sprintf("%s killed you!", killerName))
+
+	// Insurance, up to the earn limit.
+	if paid := g.giveTo(target, cost/2); paid > 0 {
+		g.Conns.SendToPlayer(target, fmt.Sprintf("Your insurance policy pays %d %s.", paid, g.MoneyName(paid)))
+	}
+	g.sendHome(target)
+}
+
+// stranded reports whether obj is a thing or player with nowhere valid
+// to be: no location, or one that's gone or is an exit.
+func (g *Game) stranded(obj *gamedb.Object) bool {
+	if t := obj.ObjType(); (t != gamedb.TypeThing && t != gamedb.TypePlayer) || obj.IsGoing() {
+		return false
+	}
+	loc, ok := g.DB.Objects[obj.Location]
+	return !ok || loc.IsGoing() || loc.ObjType() == gamedb.TypeGarbage || loc.ObjType() == gamedb.TypeExit
+}
+
+// sweepStranded sends every stranded thing and player home and returns
+// how many there were.
+func (g *Game) sweepStranded() int {
+	var strays []gamedb.DBRef
+	for ref, obj := range g.DB.Objects {
+		if g.stranded(obj) {
+			strays = append(strays, ref)
+		}
+	}
+	for _, ref := range strays {
+		obj := g.DB.Objects[ref]
+		if _, ok := g.DB.Objects[obj.Location]; !ok {
+			obj.Location = gamedb.Nothing
+		}
+		g.evict(ref, obj.Location, "You have been returned home.")
+	}
+	return len(strays)
+}
+
+// cmdDbck implements @dbck, which sends home anything left somewhere it
+// can't be, such as inside a destroyed object.
+func cmdDbck(g *Game, d *Descriptor, args string, _ []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	n := g.sweepStranded()
+	Logf(LogWizard, LevelInfo, "dbck: %s(#%d) sent %d stranded object(s) home", g.PlayerName(d.Player), d.Player, n)
+	d.Send(fmt.Sprintf("@dbck: %d stranded object(s) sent home.", n))
+}
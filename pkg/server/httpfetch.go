@@ -0,0 +1,239 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// errPrivateAddress is returned when a request would reach a loopback,
+// link-local or private address that http_allow doesn't list.
+var errPrivateAddress = errors.New("private address")
+
+// httpAllowed reports whether a request may connect to addr: any public
+// address, or a private one listed in http_allow as an address or CIDR.
+func httpAllowed(addr netip.Addr, allow []string) bool {
+	addr = addr.Unmap()
+	if !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() && !addr.IsUnspecified() && !addr.IsMulticast() {
+		return true
+	}
+	for _, a := range allow {
+		if p, err := netip.ParsePrefix(a); err == nil && p.Contains(addr) {
+			return true
+		}
+		if ip, err := netip.ParseAddr(a); err == nil && ip.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// httpDialControl refuses connections to addresses httpAllowed rejects.
+// It runs on the resolved address of every connection, redirects
+// included, so a hostname can't be pointed at the game's own network.
+func httpDialControl(allow []string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		ap, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		if !httpAllowed(ap.Addr(), allow) {
+			return errPrivateAddress
+		}
+		return nil
+	}
+}
+
+// httpFetch makes one outbound request for @http or httpget()/httppost(),
+// within http_timeout and keeping at most http_max_body bytes of the
+// response. conf may be nil.
+func httpFetch(conf *GameConf, method, rawURL, body, contentType string) (int, string, error) {
+	timeout, maxBody := 10, 16384
+	var allow []string
+	if conf != nil {
+		allow = conf.HTTPAllow
+		if conf.HTTPTimeout > 0 {
+			timeout = conf.HTTPTimeout
+		}
+		if conf.HTTPMaxBody > 0 {
+			maxBody = conf.HTTPMaxBody
+		}
+	}
+	var rd io.Reader
+	if method == http.MethodPost {
+		rd = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, rawURL, rd)
+	if err != nil {
+		return 0, "", err
+	}
+	if rd != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("User-Agent", "GoTinyMUSH (https://github.com/crystal-mush/gotinymush)")
+	// No proxy: the dial check must see the real destination.
+	dialer := &net.Dialer{Control: httpDialControl(allow)}
+	client := &http.Client{
+		Timeout:   time.Duration(timeout) * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext, DisableKeepAlives: true},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) && uerr.Timeout() {
+			return 0, "", errors.New("timed out")
+		}
+		if errors.Is(err, errPrivateAddress) {
+			return 0, "", errPrivateAddress
+		}
+		return 0, "", errors.Unwrap(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBody)))
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+	return resp.StatusCode, strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// httpCheck returns why player may not make an outbound request right
+// now, or "" if it may. A request that passes counts against the
+// owner's http_rate_limit.
+func (g *Game) httpCheck(player gamedb.DBRef, rawURL string) string {
+	if g.Conf == nil || !g.Conf.HTTPEnabled {
+		return "HTTP DISABLED"
+	}
+	if !Wizard(g, player) {
+		return "PERMISSION DENIED"
+	}
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "BAD URL"
+	}
+	limit := g.Conf.HTTPRateLimit
+	if limit <= 0 {
+		return ""
+	}
+	owner := ResolveOwner(g, player)
+	now := time.Now()
+	g.httpMu.Lock()
+	defer g.httpMu.Unlock()
+	if g.httpSent == nil {
+		g.httpSent = make(map[gamedb.DBRef][]time.Time)
+	}
+	recent := g.httpSent[owner][:0]
+	for _, t := range g.httpSent[owner] {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit {
+		g.httpSent[owner] = recent
+		return "RATE LIMITED"
+	}
+	g.httpSent[owner] = append(recent, now)
+	return ""
+}
+
+// HTTPRequest fetches a URL for httpget() and httppost(), returning the
+// response body or #-1 and what went wrong. The command waits for the
+// response, so @http is better for slow services; the world lock is
+// released meanwhile, so the rest of the game doesn't.
+func (g *Game) HTTPRequest(player gamedb.DBRef, method, rawURL, body, contentType string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if msg := g.httpCheck(player, rawURL); msg != "" {
+		return "#-1 " + msg
+	}
+	var (
+		status int
+		resp   string
+		err    error
+	)
+	conf := g.confCopy()
+	g.unlocked(func() { status, resp, err = httpFetch(conf, method, rawURL, body, contentType) })
+	if err != nil {
+		return "#-1 " + strings.ToUpper(err.Error())
+	}
+	if status >= 400 {
+		return fmt.Sprintf("#-1 HTTP %d", status)
+	}
+	return resp
+}
+
+// cmdHTTP implements @http, which fetches a URL in the background and
+// then triggers an attribute with the response body as %0 and the HTTP
+// status as %1 (0 if the request failed, when %0 is the error):
+//
+//	@http[/get] <object>/<attr>=<url>
+//	@http/post <object>/<attr>=<url>,<body>
+func cmdHTTP(g *Game, d *Descriptor, args string, switches []string) {
+	objAttr, rest, ok := strings.Cut(args, "=")
+	if !ok || strings.TrimSpace(rest) == "" {
+		d.Send("Usage: @http[/post] <object>/<attr>=<url>[,<body>]")
+		return
+	}
+	objStr, attrName, ok := strings.Cut(strings.TrimSpace(objAttr), "/")
+	if !ok {
+		d.Send("@http: Say which attribute gets the response, as <object>/<attr>.")
+		return
+	}
+	target := g.MatchObject(d.Player, strings.TrimSpace(objStr))
+	if target == gamedb.Nothing {
+		d.Send("I don't see that here.")
+		return
+	}
+	if !Controls(g, d.Player, target) {
+		d.Send("Permission denied.")
+		return
+	}
+	attrNum := g.ResolveAttrNum(strings.ToUpper(strings.TrimSpace(attrName)))
+	text := ""
+	if attrNum >= 0 {
+		text = g.GetAttrText(target, attrNum)
+	}
+	if text == "" {
+		d.Send("@http: That attribute is empty.")
+		return
+	}
+
+	method, rawURL, body := http.MethodGet, strings.TrimSpace(rest), ""
+	if HasSwitch(switches, "post") {
+		method = http.MethodPost
+		rawURL, body, _ = strings.Cut(rawURL, ",")
+		rawURL = strings.TrimSpace(rawURL)
+	}
+	if msg := g.httpCheck(d.Player, rawURL); msg != "" {
+		d.Send("@http: " + strings.ToLower(msg[:1]) + strings.ToLower(msg[1:]) + ".")
+		return
+	}
+	player := d.Player
+	conf := g.confCopy()
+	go func() {
+		status, resp, err := httpFetch(conf, method, rawURL, body, "application/x-www-form-urlencoded")
+		if err != nil {
+			Logf(LogBugs, LevelInfo, "@http %s by #%d: %v", rawURL, player, err)
+			resp = "#-1 " + strings.ToUpper(err.Error())
+		}
+		g.Do(func() {
+			g.Queue.Add(&QueueEntry{
+				Player:  target,
+				Cause:   player,
+				Caller:  player,
+				Command: text,
+				Args:    []string{resp, strconv.Itoa(status)},
+			})
+			g.WakeQueue()
+		})
+	}()
+	d.Send("Request sent.")
+}
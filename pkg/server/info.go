@@ -0,0 +1,224 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/archive"
+	"github.com/crystal-mush/gotinymush/pkg/boltstore"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// StoreStats returns the bolt file size and write-behind backlog.
+func (g *Game) StoreStats() map[string]any {
+	stats := map[string]any{"enabled": g.Store != nil}
+	if g.Store == nil {
+		return stats
+	}
+	stats["path"] = g.Store.Path()
+	if fi, err := os.Stat(g.Store.Path()); err == nil {
+		stats["file_bytes"] = fi.Size()
+	}
+	stats["pending_writes"] = g.Store.PendingWrites()
+	if lazy, ok := g.lazyStats(); ok {
+		stats["lazy_paged_out"] = lazy.PagedOut
+		stats["lazy_paged_in"] = lazy.PagedIn
+		stats["lazy_faults"] = lazy.Faults
+	}
+	if g.Replication != nil {
+		var standbys []map[string]any
+		for _, sb := range g.Replication.Standbys() {
+			standbys = append(standbys, map[string]any{
+				"addr":    sb.Addr,
+				"since":   sb.Since.Format(time.RFC3339),
+				"applied": sb.Applied,
+				"behind":  sb.Behind,
+			})
+		}
+		stats["standbys"] = standbys
+	}
+	return stats
+}
+
+// lazyStats reports lazy loading, if the store is doing it.
+func (g *Game) lazyStats() (boltstore.LazyStats, bool) {
+	if g.Store == nil {
+		return boltstore.LazyStats{}, false
+	}
+	return g.Store.LazyStats()
+}
+
+// ArchiveStats returns the newest archive on disk and, if auto-archiving
+// is running, when the next one is due.
+func (g *Game) ArchiveStats() map[string]any {
+	dir := g.ArchiveDir
+	if dir == "" {
+		dir = "backups"
+	}
+	stats := map[string]any{"dir": dir}
+	if archives, err := archive.ListArchives(dir); err == nil {
+		stats["count"] = len(archives)
+		if len(archives) > 0 {
+			stats["last"] = archives[0].Filename
+			stats["last_time"] = archives[0].Timestamp
+			stats["last_bytes"] = archives[0].Size
+		}
+	}
+	if !g.nextArchive.IsZero() {
+		stats["next_time"] = g.nextArchive.Format(time.RFC3339)
+	}
+	return stats
+}
+
+// InfoStats gathers everything @info shows into one map.
+func (g *Game) InfoStats() map[string]any {
+	info := map[string]any{
+		"version":      Version,
+		"connections":  g.ConnectionStats(),
+		"queue":        g.QueueStats(),
+		"memory":       g.MemoryStats(),
+		"game":         g.GameStats(),
+		"store":        g.StoreStats(),
+		"archive":      g.ArchiveStats(),
+		"next_dbref":   int(g.NextRef),
+		"peak_players": g.PeakPlayers,
+	}
+	if !g.StartTime.IsZero() {
+		info["start_time"] = g.StartTime.Format(time.RFC3339)
+		info["uptime_seconds"] = int64(time.Since(g.StartTime).Seconds())
+	}
+	return info
+}
+
+// formatBytes renders a byte count in B, KB or MB.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// cmdInfo implements @info: a one-screen summary of server health.
+func cmdInfo(g *Game, d *Descriptor, _ string, _ []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	line := func(label, value string) {
+		d.Send(fmt.Sprintf("%-14s %s", label+":", value))
+	}
+
+	d.Send(comsysHeading("Server"))
+	line("Version", VersionString())
+	if !g.StartTime.IsZero() {
+		line("Uptime", strings.TrimPrefix(formatUptime(g.StartTime), "Uptime: "))
+	}
+
+	gs := g.GameStats()
+	types := gs["type_counts"].(map[string]int)
+	d.Send(comsysHeading("Database"))
+	line("Objects", fmt.Sprintf("%d (next #%d)", gs["object_count"], g.NextRef))
+	line("Types", fmt.Sprintf("%d rooms, %d exits, %d things, %d players, %d garbage",
+		types["rooms"], types["exits"], types["things"], types["players"], types["garbage"]))
+	line("Attributes", fmt.Sprintf("%d user-defined", gs["attr_def_count"]))
+	ss := g.StoreStats()
+	switch {
+	case g.Store == nil:
+		line("Store", "none (in-memory)")
+	case ss["file_bytes"] != nil:
+		line("Store", fmt.Sprintf("%s, %s, %d pending write(s)", ss["path"],
+			formatBytes(ss["file_bytes"].(int64)), ss["pending_writes"]))
+	default:
+		line("Store", fmt.Sprintf("%s, %d pending write(s)", ss["path"], ss["pending_writes"]))
+	}
+	if lazy, ok := g.lazyStats(); ok {
+		line("Lazy load", fmt.Sprintf("%d object(s) on disk, %d cold object(s) in memory, %d read back",
+			lazy.PagedOut, lazy.PagedIn, lazy.Faults))
+	}
+	if g.Replication != nil {
+		standbys := g.Replication.Standbys()
+		if len(standbys) == 0 {
+			line("Standbys", "none connected")
+		}
+		for _, sb := range standbys {
+			line("Standby", fmt.Sprintf("%s, %d batch(es) behind, since %s",
+				sb.Addr, sb.Behind, sb.Since.Format("2006-01-02 15:04")))
+		}
+	}
+
+	immediate, waiting, semaphore := g.Queue.Stats()
+	cs := g.ConnectionStats()
+	d.Send(comsysHeading("Activity"))
+	line("Queue", fmt.Sprintf("%d immediate, %d waiting, %d semaphore", immediate, waiting, semaphore))
+	line("Connections", fmt.Sprintf("%d total (%d TCP, %d WebSocket), %d connected, %d at login",
+		cs["total"], cs["tcp"], cs["websocket"], cs["connected"], cs["login_screen"]))
+	line("Peak players", fmt.Sprintf("%d", g.PeakPlayers))
+	line("Traffic", fmt.Sprintf("%d commands, %s in, %s out", cs["commands"],
+		formatBytes(int64(cs["bytes_recv"].(int))), formatBytes(int64(cs["bytes_sent"].(int)))))
+
+	as := g.ArchiveStats()
+	d.Send(comsysHeading("Archives"))
+	if last, ok := as["last"]; ok {
+		line("Last", fmt.Sprintf("%s (%s, %s)", last, as["last_time"], formatBytes(as["last_bytes"].(int64))))
+	} else {
+		line("Last", "none in "+as["dir"].(string))
+	}
+	if next, ok := as["next_time"]; ok {
+		line("Next", next.(string))
+	} else {
+		line("Next", "auto-archive disabled")
+	}
+
+	ms := g.MemoryStats()
+	d.Send(comsysHeading("Memory"))
+	line("Heap", fmt.Sprintf("%s allocated, %s in use",
+		formatBytes(int64(ms["heap_alloc_bytes"].(uint64))), formatBytes(int64(ms["heap_inuse_bytes"].(uint64)))))
+	line("Runtime", fmt.Sprintf("%d goroutines, %d GC cycles", ms["goroutines"], ms["gc_cycles"]))
+}
+
+// cmdMudInfo implements INFO, the block MUD listing bots read, as in C
+// TinyMUSH: the game's name, when it started, how many players are
+// visibly connected, the database size and the server version.
+func cmdMudInfo(g *Game, d *Descriptor, _ string, _ []string) {
+	name := "GoTinyMUSH"
+	if g.Conf != nil && g.Conf.MudName != "" {
+		name = g.Conf.MudName
+	}
+	connected := 0
+	for _, p := range g.Conns.ConnectedPlayers() {
+		if o, ok := g.DB.Objects[p]; ok && !o.HasFlag(gamedb.FlagDark) {
+			connected++
+		}
+	}
+	d.Send("### Begin INFO 1")
+	d.Send("Name: " + name)
+	if !g.StartTime.IsZero() {
+		d.Send("Uptime: " + g.StartTime.Format(time.ANSIC))
+	}
+	d.Send(fmt.Sprintf("Connected: %d", connected))
+	d.Send(fmt.Sprintf("Size: %d", g.NextRef))
+	d.Send("Version: " + VersionString())
+	d.Send("### End INFO")
+}
+
+// handleInfo serves @info as JSON to wizards.
+func (ws *WebServer) handleInfo(w http.ResponseWriter, r *http.Request) {
+	claims := ClaimsFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if !Wizard(ws.game, claims.PlayerRef) {
+		http.Error(w, `{"error":"permission denied"}`, http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ws.game.InfoStats())
+}
@@ -0,0 +1,304 @@
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	// ircBackoffMin and ircBackoffMax bound the wait between reconnects.
+	ircBackoffMin = 5 * time.Second
+	ircBackoffMax = 5 * time.Minute
+
+	// ircLineMax is how much message text goes in one PRIVMSG, leaving
+	// room for the command and channel name in IRC's 512-byte line.
+	ircLineMax = 400
+)
+
+// IRCBridge relays Comsys channels to and from channels on an IRC
+// network. Messages said on a bridged channel go to IRC; IRC messages
+// appear on the channel, spoken by "<nick>@IRC".
+type IRCBridge struct {
+	g      *Game
+	server string
+	useTLS bool
+	nick   string
+	pass   string
+
+	toIRC  map[string]string // lowercase comsys channel -> IRC channel
+	toMUSH map[string]string // lowercase IRC channel -> comsys channel
+
+	// dial opens the connection to the IRC server; tests replace it.
+	dial func() (net.Conn, error)
+
+	mu     sync.Mutex
+	conn   net.Conn
+	ready  bool // registered with the server and joined
+	closed bool
+}
+
+// NewIRCBridge creates a bridge from irc_* in the game configuration,
+// or returns nil if no server or channels are configured.
+func NewIRCBridge(g *Game) *IRCBridge {
+	conf := g.Conf
+	if conf == nil || conf.IRCServer == "" || len(conf.IRCChannels) == 0 {
+		return nil
+	}
+	b := &IRCBridge{
+		g:      g,
+		server: conf.IRCServer,
+		useTLS: conf.IRCTLS,
+		nick:   conf.IRCNick,
+		pass:   conf.IRCPassword,
+		toIRC:  make(map[string]string),
+		toMUSH: make(map[string]string),
+	}
+	if b.nick == "" {
+		b.nick = strings.ReplaceAll(conf.MudName, " ", "")
+	}
+	for _, m := range conf.IRCChannels {
+		local, remote, ok := strings.Cut(m, "=")
+		local, remote = strings.TrimSpace(local), strings.TrimSpace(remote)
+		if !ok || local == "" || !strings.HasPrefix(remote, "#") {
+			Logf(LogBugs, LevelWarn, "irc_channels: ignoring %q, want <channel>=#<irc channel>", m)
+			continue
+		}
+		b.toIRC[strings.ToLower(local)] = remote
+		b.toMUSH[strings.ToLower(remote)] = local
+	}
+	if len(b.toIRC) == 0 {
+		return nil
+	}
+	b.dial = func() (net.Conn, error) {
+		d := &net.Dialer{Timeout: 30 * time.Second}
+		if b.useTLS {
+			host, _, _ := net.SplitHostPort(b.server)
+			return tls.DialWithDialer(d, "tcp", b.server, &tls.Config{ServerName: host})
+		}
+		return d.Dial("tcp", b.server)
+	}
+	return b
+}
+
+// Start runs the bridge in the background, reconnecting with
+// exponential backoff whenever the connection fails.
+func (b *IRCBridge) Start() {
+	go func() {
+		backoff := ircBackoffMin
+		for !b.isClosed() {
+			registered, err := b.session()
+			if b.isClosed() {
+				return
+			}
+			if registered {
+				backoff = ircBackoffMin
+			}
+			Logf(LogConnections, LevelWarn, "IRC bridge to %s lost: %v; reconnecting in %s", b.server, err, backoff)
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > ircBackoffMax {
+				backoff = ircBackoffMax
+			}
+		}
+	}()
+}
+
+// Stop disconnects the bridge for good.
+func (b *IRCBridge) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	if b.conn != nil {
+		fmt.Fprintf(b.conn, "QUIT :Shutting down\r\n")
+		b.conn.Close()
+	}
+}
+
+func (b *IRCBridge) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// Connected reports whether the bridge is registered with the server.
+func (b *IRCBridge) Connected() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ready
+}
+
+// send writes one raw IRC line, if connected.
+func (b *IRCBridge) send(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conn == nil {
+		return
+	}
+	b.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	if _, err := fmt.Fprintf(b.conn, "%s\r\n", line); err != nil {
+		b.conn.Close()
+	}
+}
+
+// session connects, registers and relays until the connection drops.
+// It reports whether registration succeeded.
+func (b *IRCBridge) session() (registered bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			Logf(LogBugs, LevelError, "PANIC in IRC bridge: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+		b.mu.Lock()
+		if b.conn != nil {
+			b.conn.Close()
+		}
+		b.conn, b.ready = nil, false
+		b.mu.Unlock()
+	}()
+	conn, err := b.dial()
+	if err != nil {
+		return false, err
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		conn.Close()
+		return false, nil
+	}
+	b.conn = conn
+	b.mu.Unlock()
+
+	nick := b.nick
+	if b.pass != "" {
+		b.send("PASS " + b.pass)
+	}
+	b.send("NICK " + nick)
+	b.send(fmt.Sprintf("USER %s 0 * :%s", nick, b.nick))
+
+	r := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(10 * time.Minute))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return registered, err
+		}
+		prefix, cmd, params := parseIRCLine(strings.TrimRight(line, "\r\n"))
+		switch cmd {
+		case "PING":
+			b.send("PONG :" + strings.Join(params, " "))
+		case "001":
+			registered = true
+			for _, ch := range b.toIRC {
+				b.send("JOIN " + ch)
+			}
+			b.mu.Lock()
+			b.ready = true
+			b.mu.Unlock()
+			Logf(LogConnections, LevelInfo, "IRC bridge connected to %s as %s", b.server, nick)
+		case "433": // nickname in use
+			nick += "_"
+			b.send("NICK " + nick)
+		case "PRIVMSG":
+			if len(params) == 2 {
+				b.g.Do(func() { b.inbound(prefix, params[0], params[1]) })
+			}
+		}
+	}
+}
+
+// parseIRCLine splits a raw IRC line into its prefix, command and
+// parameters; a trailing ":" parameter may contain spaces.
+func parseIRCLine(line string) (prefix, cmd string, params []string) {
+	if strings.HasPrefix(line, ":") {
+		prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+	cmd, line, _ = strings.Cut(line, " ")
+	for line != "" {
+		if strings.HasPrefix(line, ":") {
+			params = append(params, line[1:])
+			break
+		}
+		var p string
+		p, line, _ = strings.Cut(line, " ")
+		if p != "" {
+			params = append(params, p)
+		}
+	}
+	return prefix, strings.ToUpper(cmd), params
+}
+
+// stripIRCFormatting removes mIRC bold, color, italic, underline,
+// reverse and reset codes.
+func stripIRCFormatting(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0x02, 0x0f, 0x16, 0x1d, 0x1f:
+		case 0x03:
+			// Up to two foreground digits, optionally ",bg" with up to two.
+			for n := 0; n < 2 && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9'; n++ {
+				i++
+			}
+			if i+2 < len(s) && s[i+1] == ',' && s[i+2] >= '0' && s[i+2] <= '9' {
+				i += 2
+				if i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9' {
+					i++
+				}
+			}
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// inbound injects a message said on a bridged IRC channel.
+func (b *IRCBridge) inbound(prefix, target, text string) {
+	local, ok := b.toMUSH[strings.ToLower(target)]
+	if !ok || b.g.Comsys == nil {
+		return
+	}
+	ch := b.g.Comsys.GetChannel(local)
+	if ch == nil {
+		return
+	}
+	nick, _, _ := strings.Cut(prefix, "!")
+	speaker := nick + "@IRC"
+	var msg string
+	if action, ok := strings.CutPrefix(text, "\x01ACTION "); ok {
+		msg = fmt.Sprintf("%s %s %s", channelHeader(ch), speaker, stripIRCFormatting(strings.TrimSuffix(action, "\x01")))
+	} else if strings.HasPrefix(text, "\x01") {
+		return // other CTCP
+	} else {
+		msg = fmt.Sprintf("%s %s says, \"%s\"", channelHeader(ch), speaker, stripIRCFormatting(text))
+	}
+	ch.NumSent++
+	b.g.SendToChannel(ch.Name, gamedb.Nothing, msg)
+}
+
+// relay sends a channel message to the bridged IRC channel, without the
+// channel header and ANSI color. Messages that came from IRC (sender
+// Nothing) aren't sent back.
+func (b *IRCBridge) relay(ch *gamedb.Channel, sender gamedb.DBRef, msg string) {
+	remote, ok := b.toIRC[strings.ToLower(ch.Name)]
+	if !ok || sender == gamedb.Nothing || !b.Connected() {
+		return
+	}
+	text := eval.StripAnsi(strings.TrimPrefix(msg, channelHeader(ch)+" "))
+	text = strings.NewReplacer("\r", " ", "\n", " ").Replace(text)
+	for len(text) > ircLineMax {
+		b.send(fmt.Sprintf("PRIVMSG %s :%s", remote, text[:ircLineMax]))
+		text = text[ircLineMax:]
+	}
+	if text != "" {
+		b.send(fmt.Sprintf("PRIVMSG %s :%s", remote, text))
+	}
+}
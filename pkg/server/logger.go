@@ -0,0 +1,297 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogCategory groups log entries the way C TinyMUSH's "log" directive does.
+type LogCategory string
+
+const (
+	LogCommands    LogCategory = "commands"    // every command players type
+	LogConnections LogCategory = "connections" // connects, disconnects, player creation
+	LogWizard      LogCategory = "wizard"      // privileged actions: @toad, @admin, @site...
+	LogSuspect     LogCategory = "suspect"     // commands by SUSPECT players
+	LogBugs        LogCategory = "bugs"        // internal errors and panics
+	LogSecurity    LogCategory = "security"    // refused sites, lockouts, permission problems
+	LogLocal       LogCategory = "local"       // entries written with @log
+)
+
+// LogCategories lists every category, in display order.
+var LogCategories = []LogCategory{LogCommands, LogConnections, LogWizard, LogSuspect, LogBugs, LogSecurity, LogLocal}
+
+// defaultLogCategories are enabled when the config doesn't say otherwise.
+var defaultLogCategories = []string{"connections", "wizard", "suspect", "bugs", "security", "local"}
+
+// logTags are the short tags used in text-format entries.
+var logTags = map[LogCategory]string{
+	LogCommands: "CMD", LogConnections: "NET", LogWizard: "WIZ", LogSuspect: "SUSP",
+	LogBugs: "BUG", LogSecurity: "SEC", LogLocal: "MSG",
+}
+
+// legacyLogOptions maps C TinyMUSH "log" options onto categories.
+var legacyLogOptions = map[string]LogCategory{
+	"all_commands": LogCommands, "commands": LogCommands,
+	"logins": LogConnections, "network": LogConnections, "create": LogConnections, "connections": LogConnections,
+	"wizard": LogWizard, "config_changes": LogWizard,
+	"suspect_commands": LogSuspect, "suspect": LogSuspect,
+	"bugs": LogBugs, "problems": LogBugs,
+	"security": LogSecurity, "local": LogLocal,
+}
+
+// LogLevel is the severity of an entry.
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+func (l LogLevel) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "INFO"
+	}
+	return levelNames[l]
+}
+
+// parseLogLevel accepts debug, info, warn(ing) or error.
+func parseLogLevel(s string) (LogLevel, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, true
+	case "", "info":
+		return LevelInfo, true
+	case "warn", "warning":
+		return LevelWarn, true
+	case "error":
+		return LevelError, true
+	}
+	return LevelInfo, false
+}
+
+// gameLog is the categorized logger. Entries go to the standard log unless
+// a log directory is configured, in which case each category has its own
+// file.
+type gameLog struct {
+	mu      sync.Mutex
+	enabled map[LogCategory]bool
+	level   LogLevel
+	json    bool
+	dir     string
+	files   map[LogCategory]*os.File
+}
+
+var mushLog = &gameLog{enabled: categorySet(defaultLogCategories), level: LevelInfo}
+
+// categorySet turns a list of category or legacy option names into a set.
+// Names are applied in order; "all" enables everything and a leading "!"
+// disables.
+func categorySet(names []string) map[LogCategory]bool {
+	set := make(map[LogCategory]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		on := !strings.HasPrefix(name, "!")
+		name = strings.TrimPrefix(name, "!")
+		if name == "all" {
+			for _, c := range LogCategories {
+				set[c] = on
+			}
+			continue
+		}
+		if c, ok := legacyLogOptions[name]; ok {
+			set[c] = on
+		}
+	}
+	return set
+}
+
+// ConfigureLogging applies the log directives in gc. Category files are
+// reopened if the log directory changed.
+func ConfigureLogging(gc *GameConf) {
+	l := mushLog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.enabled = categorySet(gc.Log)
+	l.level, _ = parseLogLevel(gc.LogLevel)
+	l.json = strings.EqualFold(gc.LogFormat, "json")
+	if gc.LogDir != l.dir {
+		l.closeFiles()
+		l.dir = gc.LogDir
+		if l.dir != "" {
+			if err := os.MkdirAll(l.dir, 0755); err != nil {
+				log.Printf("log: cannot create %s: %v; logging to the main log", l.dir, err)
+				l.dir = ""
+			}
+		}
+	}
+}
+
+func (l *gameLog) closeFiles() {
+	for _, f := range l.files {
+		f.Close()
+	}
+	l.files = nil
+}
+
+// writer returns where entries for cat go, or nil for the standard log.
+func (l *gameLog) writer(cat LogCategory) io.Writer {
+	if l.dir == "" {
+		return nil
+	}
+	if f, ok := l.files[cat]; ok {
+		return f
+	}
+	f, err := os.OpenFile(filepath.Join(l.dir, string(cat)+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Printf("log: cannot open %s log: %v", cat, err)
+		return nil
+	}
+	if l.files == nil {
+		l.files = make(map[LogCategory]*os.File)
+	}
+	l.files[cat] = f
+	return f
+}
+
+// LogEnabled reports whether entries in cat are being written.
+func LogEnabled(cat LogCategory) bool {
+	mushLog.mu.Lock()
+	defer mushLog.mu.Unlock()
+	return mushLog.enabled[cat]
+}
+
+// Logf writes an entry to cat if the category is enabled and level meets
+// the configured minimum. Errors are always written.
+func Logf(cat LogCategory, level LogLevel, format string, args ...any) {
+	l := mushLog
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if level < l.level || (!l.enabled[cat] && level < LevelError) {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	w := l.writer(cat)
+	if w != nil {
+		w = io.MultiWriter(w, recentLog)
+	}
+
+	if l.json {
+		line, _ := json.Marshal(map[string]string{
+			"time":     time.Now().Format(time.RFC3339),
+			"level":    level.String(),
+			"category": string(cat),
+			"msg":      msg,
+		})
+		if w == nil {
+			w = log.Writer()
+		}
+		fmt.Fprintf(w, "%s\n", line)
+		return
+	}
+
+	tag := logTags[cat]
+	if level != LevelInfo {
+		tag += "/" + level.String()
+	}
+	if w == nil {
+		log.Printf("%s: %s", tag, msg)
+		return
+	}
+	fmt.Fprintf(w, "%s %s: %s\n", time.Now().Format("2006/01/02 15:04:05"), tag, msg)
+}
+
+// recentLog keeps the last lines of log output for the admin panel.
+var recentLog = &logRing{max: 1000}
+
+var captureLogOnce sync.Once
+
+// CaptureRecentLog copies everything written to the standard log into
+// recentLog as well. Category files are always captured.
+func CaptureRecentLog() {
+	captureLogOnce.Do(func() {
+		log.SetOutput(io.MultiWriter(log.Writer(), recentLog))
+	})
+}
+
+// logRing is a writer that remembers the last max lines written to it.
+type logRing struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial string
+}
+
+func (r *logRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	text := r.partial + string(p)
+	parts := strings.Split(text, "\n")
+	r.partial = parts[len(parts)-1]
+	r.lines = append(r.lines, parts[:len(parts)-1]...)
+	if over := len(r.lines) - r.max; over > 0 {
+		r.lines = append(r.lines[:0], r.lines[over:]...)
+	}
+	return len(p), nil
+}
+
+// Lines returns up to the last n complete lines written.
+func (r *logRing) Lines(n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n > len(r.lines) {
+		n = len(r.lines)
+	}
+	return append([]string(nil), r.lines[len(r.lines)-n:]...)
+}
+
+// enabledLogCategories returns the enabled category names, sorted.
+func enabledLogCategories() []string {
+	mushLog.mu.Lock()
+	defer mushLog.mu.Unlock()
+	var names []string
+	for c, on := range mushLog.enabled {
+		if on {
+			names = append(names, string(c))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cmdLog implements @log [<type>=]<message>.
+func cmdLog(g *Game, d *Descriptor, args string, _ []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	typ, msg, ok := strings.Cut(args, "=")
+	if !ok {
+		typ, msg = "", args
+	}
+	typ = strings.ToUpper(strings.TrimSpace(typ))
+	if typ == "" {
+		typ = "LOCAL"
+	}
+	if len(typ) > 5 {
+		typ = typ[:5]
+	}
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		d.Send("What do you want to log?")
+		return
+	}
+	Logf(LogLocal, LevelInfo, "%s: %s(#%d): %s", typ, g.PlayerName(d.Player), d.Player, msg)
+}
@@ -50,28 +50,10 @@ func ParseConnect(msg string) (command, user, password string) {
 	return
 }
 
-// LookupPlayer finds a player by name in the database.
+// LookupPlayer finds a player by name or ALIAS in the database. A leading
+// * is allowed, so "@boot *bob" works like "@boot bob".
 func LookupPlayer(db *gamedb.Database, name string) gamedb.DBRef {
-	for _, obj := range db.Objects {
-		if obj.ObjType() != gamedb.TypePlayer {
-			continue
-		}
-		// Match on player name
-		if strings.EqualFold(obj.Name, name) {
-			return obj.DBRef
-		}
-		// Match on ALIAS attribute (A_ALIAS = 58)
-		for _, attr := range obj.Attrs {
-			if attr.Number == 58 {
-				alias := eval.StripAttrPrefix(attr.Value)
-				if alias != "" && strings.EqualFold(alias, name) {
-					return obj.DBRef
-				}
-				break
-			}
-		}
-	}
-	return gamedb.Nothing
+	return db.LookupPlayer(strings.TrimPrefix(strings.TrimSpace(name), "*"))
 }
 
 // CheckPassword verifies a password against the stored password for a player.
@@ -84,7 +66,7 @@ func CheckPassword(db *gamedb.Database, player gamedb.DBRef, password string) bo
 	}
 
 	// Find A_PASS attribute
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		if attr.Number == aPass {
 			stored := eval.StripAttrPrefix(attr.Value)
 			if stored == "" {
@@ -14,26 +14,37 @@ import (
 type MailDraft struct {
 	To      []gamedb.DBRef
 	CC      []gamedb.DBRef
+	BCC     []gamedb.DBRef
 	Subject string
 	Body    strings.Builder
+	Flags   int // MailReply or MailForward, set on every copy sent
 }
 
 // Mail manages the in-memory mail store.
 type Mail struct {
-	mu       sync.RWMutex
-	Messages map[gamedb.DBRef]map[int]*gamedb.MailMessage // recipient -> msgID -> message
-	NextID   map[gamedb.DBRef]int                         // next ID per player
-	Drafts   map[gamedb.DBRef]*MailDraft                  // in-memory only
-	Expire   int                                          // days before auto-expire, 0 = never
+	mu        sync.RWMutex
+	Messages  map[gamedb.DBRef]map[int]*gamedb.MailMessage // recipient -> msgID -> message
+	NextID    map[gamedb.DBRef]int                         // next ID per player
+	Drafts    map[gamedb.DBRef]*MailDraft                  // in-memory only
+	Expire    int                                          // days before auto-expire, 0 = never
+	Pending   map[gamedb.DBRef]*MailtoPending              // @mailto verifications, in-memory only
+	Requested map[gamedb.DBRef]time.Time                   // when each player last asked for an @mailto code
+	Current   map[gamedb.DBRef]int                         // current folder, in-memory only
+	Aliases   map[int]*gamedb.MailAlias                    // @malias lists by ID
+	nextAlias int
 }
 
 // NewMail creates an empty mail manager.
 func NewMail(expireDays int) *Mail {
 	return &Mail{
-		Messages: make(map[gamedb.DBRef]map[int]*gamedb.MailMessage),
-		NextID:   make(map[gamedb.DBRef]int),
-		Drafts:   make(map[gamedb.DBRef]*MailDraft),
-		Expire:   expireDays,
+		Messages:  make(map[gamedb.DBRef]map[int]*gamedb.MailMessage),
+		NextID:    make(map[gamedb.DBRef]int),
+		Drafts:    make(map[gamedb.DBRef]*MailDraft),
+		Expire:    expireDays,
+		Pending:   make(map[gamedb.DBRef]*MailtoPending),
+		Requested: make(map[gamedb.DBRef]time.Time),
+		Current:   make(map[gamedb.DBRef]int),
+		Aliases:   make(map[int]*gamedb.MailAlias),
 	}
 }
 
@@ -57,15 +68,22 @@ func (m *Mail) LoadMessages(all map[gamedb.DBRef]map[int]*gamedb.MailMessage) {
 // SendMessage delivers a message to all recipients (To + CC).
 // Returns the created messages keyed by recipient.
 func (m *Mail) SendMessage(from gamedb.DBRef, to, cc []gamedb.DBRef, subject, body string) map[gamedb.DBRef]*gamedb.MailMessage {
+	return m.Deliver(from, to, cc, nil, subject, body, 0)
+}
+
+// Deliver is SendMessage with blind carbon copies and message flags.
+// BCC recipients get a copy, but don't appear in anyone's To or CC.
+func (m *Mail) Deliver(from gamedb.DBRef, to, cc, bcc []gamedb.DBRef, subject, body string, flags int) map[gamedb.DBRef]*gamedb.MailMessage {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	now := time.Now()
 	result := make(map[gamedb.DBRef]*gamedb.MailMessage)
 
-	allRecipients := make([]gamedb.DBRef, 0, len(to)+len(cc))
+	allRecipients := make([]gamedb.DBRef, 0, len(to)+len(cc)+len(bcc))
 	allRecipients = append(allRecipients, to...)
 	allRecipients = append(allRecipients, cc...)
+	allRecipients = append(allRecipients, bcc...)
 
 	// Deduplicate
 	seen := make(map[gamedb.DBRef]bool)
@@ -92,7 +110,7 @@ func (m *Mail) SendMessage(from gamedb.DBRef, to, cc []gamedb.DBRef, subject, bo
 			Subject: subject,
 			Body:    body,
 			Time:    now,
-			Flags:   0,
+			Flags:   flags,
 			Folder:  0,
 		}
 		m.Messages[r][id] = msg
@@ -199,6 +217,60 @@ func (m *Mail) PurgeCleared(player gamedb.DBRef) []int {
 	return purged
 }
 
+// CurrentFolder returns the folder player's @mail commands work on.
+func (m *Mail) CurrentFolder(player gamedb.DBRef) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.Current[player]
+}
+
+// SetCurrentFolder changes player's current folder.
+func (m *Mail) SetCurrentFolder(player gamedb.DBRef, folder int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Current[player] = folder
+}
+
+// FileMessage moves one of player's messages to folder.
+func (m *Mail) FileMessage(player gamedb.DBRef, msgID, folder int) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msg := m.getMessage(player, msgID)
+	if msg == nil {
+		return false
+	}
+	msg.Folder = folder
+	return true
+}
+
+// SentMail is one recipient's copy of a message, for @mail/review.
+type SentMail struct {
+	To  gamedb.DBRef
+	Msg *gamedb.MailMessage
+}
+
+// SentBy returns the copies of from's messages still in recipients'
+// mailboxes, oldest first.
+func (m *Mail) SentBy(from gamedb.DBRef) []SentMail {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var sent []SentMail
+	for player, msgs := range m.Messages {
+		for _, msg := range msgs {
+			if msg.From == from {
+				sent = append(sent, SentMail{player, msg})
+			}
+		}
+	}
+	sort.Slice(sent, func(i, j int) bool {
+		if !sent[i].Msg.Time.Equal(sent[j].Msg.Time) {
+			return sent[i].Msg.Time.Before(sent[j].Msg.Time)
+		}
+		return sent[i].To < sent[j].To
+	})
+	return sent
+}
+
 // CountMessages returns (total, unread, cleared) for a player.
 func (m *Mail) CountMessages(player gamedb.DBRef) (total, unread, cleared int) {
 	m.mu.RLock()
@@ -246,6 +318,44 @@ func (m *Mail) ClearDraft(player gamedb.DBRef) {
 	delete(m.Drafts, player)
 }
 
+// RequestMailto records an unconfirmed @mailto address for a player, unless
+// they asked for one less than mailtoCooldown ago or mailtoPendingLimit
+// other players are already waiting on a code. Returns why it was refused,
+// or "" if the verification email may be sent.
+func (m *Mail) RequestMailto(player gamedb.DBRef, p *MailtoPending) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if last, ok := m.Requested[player]; ok && now.Sub(last) < mailtoCooldown {
+		return "MAIL: You asked for a verification code recently. Please wait a few minutes."
+	}
+	for ref, pending := range m.Pending {
+		if now.After(pending.Expires) {
+			delete(m.Pending, ref)
+		}
+	}
+	if _, ok := m.Pending[player]; !ok && len(m.Pending) >= mailtoPendingLimit {
+		return "MAIL: Too many verifications are pending. Please try again later."
+	}
+	m.Requested[player] = now
+	m.Pending[player] = p
+	return ""
+}
+
+// MailtoPending returns the player's unconfirmed @mailto address, or nil.
+func (m *Mail) MailtoPending(player gamedb.DBRef) *MailtoPending {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.Pending[player]
+}
+
+// ClearMailtoPending discards the player's unconfirmed @mailto address.
+func (m *Mail) ClearMailtoPending(player gamedb.DBRef) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Pending, player)
+}
+
 // ExpireOld removes messages older than the configured expiration.
 // Returns a map of player -> purged message IDs.
 func (m *Mail) ExpireOld() map[gamedb.DBRef][]int {
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// LoadAliases replaces the @malias lists with those read from storage.
+func (m *Mail) LoadAliases(aliases []gamedb.MailAlias) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Aliases = make(map[int]*gamedb.MailAlias)
+	m.nextAlias = 0
+	for i := range aliases {
+		ma := aliases[i]
+		m.Aliases[ma.ID] = &ma
+		if ma.ID >= m.nextAlias {
+			m.nextAlias = ma.ID + 1
+		}
+	}
+}
+
+// AddAlias registers a new alias, assigning its ID.
+func (m *Mail) AddAlias(ma *gamedb.MailAlias) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.nextAlias == 0 {
+		m.nextAlias = 1
+	}
+	ma.ID = m.nextAlias
+	m.nextAlias++
+	m.Aliases[ma.ID] = ma
+}
+
+// RemoveAlias forgets an alias.
+func (m *Mail) RemoveAlias(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Aliases, id)
+}
+
+// ListAliases returns every alias, in ID order.
+func (m *Mail) ListAliases() []*gamedb.MailAlias {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]*gamedb.MailAlias, 0, len(m.Aliases))
+	for _, ma := range m.Aliases {
+		list = append(list, ma)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// findMailAlias resolves name for player: one of player's own aliases,
+// else a global one (owned by God). Wizards may also give #<number>.
+func (g *Game) findMailAlias(player gamedb.DBRef, name string) *gamedb.MailAlias {
+	if strings.HasPrefix(name, "#") && Wizard(g, player) {
+		if id, err := strconv.Atoi(name[1:]); err == nil {
+			g.Mail.mu.RLock()
+			defer g.Mail.mu.RUnlock()
+			return g.Mail.Aliases[id]
+		}
+		return nil
+	}
+	var global *gamedb.MailAlias
+	for _, ma := range g.Mail.ListAliases() {
+		if ma.Name != name {
+			continue
+		}
+		if ma.Owner == player {
+			return ma
+		}
+		if IsGod(g, ma.Owner) && global == nil {
+			global = ma
+		}
+	}
+	return global
+}
+
+// persistMailAlias saves ma to bbolt.
+func (g *Game) persistMailAlias(ma *gamedb.MailAlias) {
+	if g.Store == nil {
+		return
+	}
+	if err := g.Store.PutMailAlias(ma); err != nil {
+		Logf(LogBugs, LevelError, "persist mail alias %s: %v", ma.Name, err)
+	}
+}
+
+// validMailAliasName reports whether name can name an alias: '*' and
+// then letters, digits, '_' or '-'.
+func validMailAliasName(name string) bool {
+	if len(name) < 2 || name[0] != '*' {
+		return false
+	}
+	for _, c := range name[1:] {
+		if !(c == '_' || c == '-' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+			return false
+		}
+	}
+	return true
+}
+
+// cmdMalias implements @malias[/<switch>] [*<alias>[=<arg>]].
+func cmdMalias(g *Game, d *Descriptor, args string, switches []string) {
+	if g.Mail == nil {
+		d.Send("The mail system is not enabled.")
+		return
+	}
+	name, arg, hasArg := strings.Cut(args, "=")
+	name, arg = strings.TrimSpace(name), strings.TrimSpace(arg)
+	sw := ""
+	if len(switches) > 0 {
+		sw = strings.ToLower(switches[0])
+	}
+
+	switch {
+	case sw == "list" || (sw == "" && name == ""):
+		mailAliasList(g, d, sw == "list")
+		return
+	case sw == "" && !hasArg:
+		ma := g.findMailAlias(d.Player, name)
+		if ma == nil {
+			d.Send("MAIL: I cannot find that alias!")
+			return
+		}
+		d.Send(fmt.Sprintf("MAIL: Alias %s: %s", ma.Name, FormatRecipients(g.DB, ma.Members)))
+		return
+	case sw == "":
+		if !validMailAliasName(name) {
+			d.Send("MAIL: Aliases must start with '*' and contain only letters, digits, '_' and '-'.")
+			return
+		}
+		for _, ma := range g.Mail.ListAliases() {
+			if ma.Name == name && (ma.Owner == d.Player || IsGod(g, ma.Owner)) {
+				d.Send(fmt.Sprintf("MAIL: Mail alias %s already exists.", name))
+				return
+			}
+		}
+		members := parseMailRecipients(g, d, arg)
+		if len(members) == 0 {
+			return
+		}
+		ma := &gamedb.MailAlias{Name: name, Owner: d.Player, Members: members}
+		g.Mail.AddAlias(ma)
+		g.persistMailAlias(ma)
+		d.Send(fmt.Sprintf("MAIL: Alias set '%s'.", name))
+		return
+	}
+
+	ma := g.findMailAlias(d.Player, name)
+	if ma == nil {
+		d.Send("MAIL: I cannot find that alias!")
+		return
+	}
+	if ma.Owner != d.Player && !Wizard(g, d.Player) {
+		d.Send("MAIL: Permission denied.")
+		return
+	}
+	switch sw {
+	case "delete":
+		g.Mail.RemoveAlias(ma.ID)
+		if g.Store != nil {
+			g.Store.DeleteMailAlias(ma.ID)
+		}
+		d.Send("MAIL: Alias deleted.")
+		return
+	case "desc":
+		ma.Desc = arg
+		d.Send("MAIL: Description changed.")
+	case "rename":
+		if !validMailAliasName(arg) {
+			d.Send("MAIL: Aliases must start with '*' and contain only letters, digits, '_' and '-'.")
+			return
+		}
+		ma.Name = arg
+		d.Send("MAIL: Mail alias renamed.")
+	case "chown":
+		if !Wizard(g, d.Player) {
+			d.Send("MAIL: Permission denied.")
+			return
+		}
+		owner := LookupPlayer(g.DB, arg)
+		if owner == gamedb.Nothing {
+			d.Send("MAIL: I cannot find that player.")
+			return
+		}
+		ma.Owner = owner
+		d.Send("MAIL: Owner changed.")
+	case "add", "remove":
+		refs := parseMailRecipients(g, d, arg)
+		if len(refs) == 0 {
+			return
+		}
+		for _, ref := range refs {
+			idx := -1
+			for i, m := range ma.Members {
+				if m == ref {
+					idx = i
+				}
+			}
+			switch {
+			case sw == "add" && idx < 0:
+				ma.Members = append(ma.Members, ref)
+				d.Send(fmt.Sprintf("MAIL: %s added to alias %s.", g.PlayerName(ref), ma.Name))
+			case sw == "add":
+				d.Send(fmt.Sprintf("MAIL: %s is already on alias %s.", g.PlayerName(ref), ma.Name))
+			case idx >= 0:
+				ma.Members = append(ma.Members[:idx], ma.Members[idx+1:]...)
+				d.Send(fmt.Sprintf("MAIL: %s removed from alias %s.", g.PlayerName(ref), ma.Name))
+			default:
+				d.Send(fmt.Sprintf("MAIL: %s is not on alias %s.", g.PlayerName(ref), ma.Name))
+			}
+		}
+	default:
+		d.Send(fmt.Sprintf("@malias: Unknown switch /%s.", sw))
+		return
+	}
+	g.persistMailAlias(ma)
+}
+
+// mailAliasList lists the aliases player can use. With all, wizards see
+// every alias, by number.
+func mailAliasList(g *Game, d *Descriptor, all bool) {
+	if all && !Wizard(g, d.Player) {
+		d.Send("MAIL: Permission denied.")
+		return
+	}
+	var shown []*gamedb.MailAlias
+	for _, ma := range g.Mail.ListAliases() {
+		if all || ma.Owner == d.Player || IsGod(g, ma.Owner) {
+			shown = append(shown, ma)
+		}
+	}
+	if len(shown) == 0 {
+		d.Send("MAIL: There are no mail aliases.")
+		return
+	}
+	d.Send(fmt.Sprintf("%-5s %-16s %-40s %s", "Num", "Name", "Description", "Owner"))
+	for _, ma := range shown {
+		d.Send(fmt.Sprintf("#%-4d %-16s %-40.40s %s", ma.ID, ma.Name, ma.Desc, g.PlayerName(ma.Owner)))
+	}
+	d.Send("*****  End of Mail Aliases *****")
+}
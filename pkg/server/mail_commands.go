@@ -4,10 +4,21 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/crystal-mush/gotinymush/pkg/events"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 )
 
+const (
+	aMailFolders = 96  // A_MAILFOLDERS — folder names, as "<n>:<NAME>" words
+	aAMail       = 202 // A_AMAIL — run on a player when they receive mail
+)
+
+// mailModifiers are @mail switches that qualify another switch rather
+// than selecting an action.
+var mailModifiers = map[string]bool{"urgent": true, "silent": true, "quote": true}
+
 // cmdMail handles the @mail command with switch routing.
 func cmdMail(g *Game, d *Descriptor, args string, switches []string) {
 	if g.Mail == nil {
@@ -15,15 +26,35 @@ func cmdMail(g *Game, d *Descriptor, args string, switches []string) {
 		return
 	}
 
-	if len(switches) > 0 {
-		sw := strings.ToLower(switches[0])
+	sw := ""
+	for _, s := range switches {
+		if s = strings.ToLower(s); !mailModifiers[s] {
+			sw = s
+			break
+		}
+	}
+	flags := 0
+	if HasSwitch(switches, "urgent") {
+		flags |= gamedb.MailUrgent
+	}
+	silent := HasSwitch(switches, "silent")
+
+	if sw == "" && (flags != 0 || silent) && !strings.Contains(args, "=") {
+		// @mail/urgent or @mail/silent alone sends the draft.
+		mailSendDraft(g, d, flags, silent)
+		return
+	}
+
+	if sw != "" {
 		switch sw {
 		case "send":
-			mailSend(g, d, args)
+			mailSendDraft(g, d, flags, silent)
 		case "to":
 			mailTo(g, d, args)
 		case "cc":
 			mailCC(g, d, args)
+		case "bcc":
+			mailBCC(g, d, args)
 		case "subject", "sub":
 			mailSubject(g, d, args)
 		case "proof":
@@ -33,7 +64,7 @@ func cmdMail(g *Game, d *Descriptor, args string, switches []string) {
 		case "read":
 			mailRead(g, d, args)
 		case "list":
-			mailList(g, d)
+			mailList(g, d, args)
 		case "clear":
 			mailClear(g, d, args)
 		case "unclear":
@@ -41,13 +72,25 @@ func cmdMail(g *Game, d *Descriptor, args string, switches []string) {
 		case "purge":
 			mailPurge(g, d)
 		case "reply":
-			mailReply(g, d, args)
+			mailReply(g, d, args, false, HasSwitch(switches, "quote"))
+		case "replyall":
+			mailReply(g, d, args, true, HasSwitch(switches, "quote"))
 		case "forward", "fwd":
-			mailForward(g, d, args)
+			mailForward(g, d, args, flags, silent)
+		case "quick":
+			mailQuick(g, d, args, flags, silent)
+		case "folder":
+			mailFolder(g, d, args)
+		case "file":
+			mailFile(g, d, args)
+		case "review":
+			mailReview(g, d, args)
 		case "stats":
 			mailStats(g, d, args)
 		case "safe":
 			mailSafe(g, d, args)
+		case "export":
+			mailExport(g, d, args)
 		default:
 			d.Send(fmt.Sprintf("@mail: Unknown switch /%s.", sw))
 		}
@@ -56,14 +99,8 @@ func cmdMail(g *Game, d *Descriptor, args string, switches []string) {
 
 	args = strings.TrimSpace(args)
 	if args == "" {
-		// Bare @mail = list inbox
-		mailList(g, d)
-		return
-	}
-
-	// @mail <num> = read message
-	if num, err := strconv.Atoi(args); err == nil {
-		mailRead(g, d, strconv.Itoa(num))
+		// Bare @mail = list current folder
+		mailList(g, d, "")
 		return
 	}
 
@@ -86,11 +123,21 @@ func cmdMail(g *Game, d *Descriptor, args string, switches []string) {
 			return
 		}
 
-		deliverMail(g, d, recipients, nil, subject, body)
+		deliverMail(g, d, recipients, nil, nil, subject, body, flags, silent)
 		return
 	}
 
-	d.Send("Usage: @mail [<num>|<player>=<subj>/<body>]")
+	// @mail <num> reads a message; any other <msg-list> lists the matches.
+	if _, err := strconv.Atoi(args); err == nil {
+		mailRead(g, d, args)
+		return
+	}
+	if _, err := g.mailSelect(d.Player, args); err == nil {
+		mailList(g, d, args)
+		return
+	}
+
+	d.Send("Usage: @mail [<msg-list>|<player>=<subj>/<body>]")
 }
 
 // cmdMailDash handles the "- <text>" prefix command for draft body appending.
@@ -113,6 +160,15 @@ func cmdMailDash(g *Game, d *Descriptor, args string, switches []string) {
 	d.Send("Text added to mail draft.")
 }
 
+// cmdMailSendDash handles "--", which sends the draft.
+func cmdMailSendDash(g *Game, d *Descriptor, _ string, _ []string) {
+	if g.Mail == nil {
+		d.Send("The mail system is not enabled.")
+		return
+	}
+	mailSendDraft(g, d, 0, false)
+}
+
 // mailTo sets draft recipients.
 func mailTo(g *Game, d *Descriptor, args string) {
 	if args == "" {
@@ -130,22 +186,47 @@ func mailTo(g *Game, d *Descriptor, args string) {
 	d.Send("Use @mail/subject <text>, then - <text> to compose body, then @mail/send.")
 }
 
-// mailCC sets draft CC recipients.
+// mailCC sets draft CC recipients. An empty list clears them.
 func mailCC(g *Game, d *Descriptor, args string) {
-	if args == "" {
-		d.Send("Usage: @mail/cc <player list>")
+	if !g.Mail.HasDraft(d.Player) {
+		d.Send("You have no mail draft in progress.")
+		return
+	}
+	draft := g.Mail.GetDraft(d.Player)
+	if strings.TrimSpace(args) == "" {
+		draft.CC = nil
+		d.Send("Mail CC cleared.")
 		return
 	}
 	recipients := parseMailRecipients(g, d, args)
 	if len(recipients) == 0 {
 		return
 	}
-	draft := g.Mail.GetDraft(d.Player)
 	draft.CC = recipients
 	names := FormatRecipients(g.DB, recipients)
 	d.Send(fmt.Sprintf("Mail CC set to: %s", names))
 }
 
+// mailBCC sets draft blind carbon copy recipients. An empty list clears them.
+func mailBCC(g *Game, d *Descriptor, args string) {
+	if !g.Mail.HasDraft(d.Player) {
+		d.Send("You have no mail draft in progress.")
+		return
+	}
+	draft := g.Mail.GetDraft(d.Player)
+	if strings.TrimSpace(args) == "" {
+		draft.BCC = nil
+		d.Send("Mail BCC cleared.")
+		return
+	}
+	recipients := parseMailRecipients(g, d, args)
+	if len(recipients) == 0 {
+		return
+	}
+	draft.BCC = recipients
+	d.Send(fmt.Sprintf("Mail BCC set to: %s", FormatRecipients(g.DB, recipients)))
+}
+
 // mailSubject sets draft subject.
 func mailSubject(g *Game, d *Descriptor, args string) {
 	if args == "" {
@@ -169,6 +250,9 @@ func mailProof(g *Game, d *Descriptor) {
 	if len(draft.CC) > 0 {
 		d.Send(fmt.Sprintf("CC: %s", FormatRecipients(g.DB, draft.CC)))
 	}
+	if len(draft.BCC) > 0 {
+		d.Send(fmt.Sprintf("BCC: %s", FormatRecipients(g.DB, draft.BCC)))
+	}
 	d.Send(fmt.Sprintf("Subject: %s", draft.Subject))
 	d.Send("---")
 	body := draft.Body.String()
@@ -190,8 +274,9 @@ func mailAbort(g *Game, d *Descriptor) {
 	d.Send("Mail draft discarded.")
 }
 
-// mailSend sends the current draft.
-func mailSend(g *Game, d *Descriptor, args string) {
+// mailSendDraft sends the current draft. flags adds MailUrgent; silent
+// suppresses the "Mail sent" confirmation.
+func mailSendDraft(g *Game, d *Descriptor, flags int, silent bool) {
 	if !g.Mail.HasDraft(d.Player) {
 		d.Send("You have no mail draft to send. Use @mail/to <players> first.")
 		return
@@ -206,52 +291,170 @@ func mailSend(g *Game, d *Descriptor, args string) {
 		return
 	}
 
-	deliverMail(g, d, draft.To, draft.CC, draft.Subject, draft.Body.String())
+	deliverMail(g, d, draft.To, draft.CC, draft.BCC, draft.Subject, draft.Body.String(), draft.Flags|flags, silent)
 	g.Mail.ClearDraft(d.Player)
 }
 
-// mailRead reads a message by number.
-func mailRead(g *Game, d *Descriptor, args string) {
-	num, err := strconv.Atoi(strings.TrimSpace(args))
-	if err != nil {
-		d.Send("Usage: @mail/read <number>")
+// mailQuick implements @mail/quick <player list>/<subject>=<message>.
+func mailQuick(g *Game, d *Descriptor, args string, flags int, silent bool) {
+	head, body, ok := strings.Cut(args, "=")
+	to, subject, hasSubj := strings.Cut(head, "/")
+	if !ok || !hasSubj {
+		d.Send("Usage: @mail/quick <player list>/<subject>=<message>")
 		return
 	}
-
-	msg := g.Mail.GetMessage(d.Player, num)
-	if msg == nil {
-		d.Send(fmt.Sprintf("You don't have a message #%d.", num))
+	recipients := parseMailRecipients(g, d, to)
+	if len(recipients) == 0 {
 		return
 	}
+	deliverMail(g, d, recipients, nil, nil, strings.TrimSpace(subject), strings.TrimSpace(body), flags, silent)
+}
 
-	g.Mail.MarkRead(d.Player, num)
-	persistMailMessage(g, d.Player, msg)
+// mailSelect returns the messages in player's current folder matched by a
+// message list: a number, a range (3-5, -5, 3-), *<sender>, ~<days>
+// (exactly), <<days> or ><days> (newer or older than), read, unread,
+// cleared, urgent, or "all" for every message in every folder. An empty
+// list selects the whole folder.
+func (g *Game) mailSelect(player gamedb.DBRef, spec string) ([]*gamedb.MailMessage, error) {
+	spec = strings.TrimSpace(spec)
+	folder := g.Mail.CurrentFolder(player)
+	var keep func(*gamedb.MailMessage) bool
+	lower := strings.ToLower(spec)
+	now := time.Now()
+	days := func(msg *gamedb.MailMessage) int { return int(now.Sub(msg.Time).Hours() / 24) }
+	switch {
+	case spec == "":
+		keep = func(*gamedb.MailMessage) bool { return true }
+	case lower == "all":
+		return g.Mail.GetInbox(player), nil
+	case lower == "read":
+		keep = func(m *gamedb.MailMessage) bool { return m.Flags&gamedb.MailIsRead != 0 }
+	case lower == "unread":
+		keep = func(m *gamedb.MailMessage) bool { return m.Flags&gamedb.MailIsRead == 0 }
+	case lower == "cleared":
+		keep = func(m *gamedb.MailMessage) bool { return m.Flags&gamedb.MailCleared != 0 }
+	case lower == "urgent":
+		keep = func(m *gamedb.MailMessage) bool { return m.Flags&gamedb.MailUrgent != 0 }
+	case spec[0] == '*':
+		from := LookupPlayer(g.DB, strings.TrimSpace(spec[1:]))
+		if from == gamedb.Nothing {
+			return nil, fmt.Errorf("MAIL: No such player.")
+		}
+		keep = func(m *gamedb.MailMessage) bool { return m.From == from }
+	case spec[0] == '~' || spec[0] == '<' || spec[0] == '>':
+		n, err := strconv.Atoi(strings.TrimSpace(spec[1:]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("MAIL: Invalid message specification.")
+		}
+		op := spec[0]
+		keep = func(m *gamedb.MailMessage) bool {
+			switch op {
+			case '~':
+				return days(m) == n
+			case '<':
+				return days(m) < n
+			}
+			return days(m) > n
+		}
+	default:
+		lo, hi, isRange := strings.Cut(spec, "-")
+		low, high := 1, int(^uint(0)>>1)
+		var err error
+		if !isRange {
+			hi = lo
+		}
+		if lo = strings.TrimSpace(lo); lo != "" {
+			if low, err = strconv.Atoi(lo); err != nil {
+				return nil, fmt.Errorf("MAIL: Invalid message specification.")
+			}
+		}
+		if hi = strings.TrimSpace(hi); hi != "" {
+			if high, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("MAIL: Invalid message specification.")
+			}
+		}
+		if lo == "" && hi == "" {
+			return nil, fmt.Errorf("MAIL: Invalid message specification.")
+		}
+		keep = func(m *gamedb.MailMessage) bool { return m.ID >= low && m.ID <= high }
+	}
+	var out []*gamedb.MailMessage
+	for _, msg := range g.Mail.GetInbox(player) {
+		if msg.Folder == folder && keep(msg) {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
 
-	d.Send(fmt.Sprintf("--- Message %d ---", msg.ID))
-	d.Send(fmt.Sprintf("From: %s  Date: %s", playerName(g.DB, msg.From), msg.Time.Format("Mon Jan 02 15:04 2006")))
-	d.Send(fmt.Sprintf("To: %s", FormatRecipients(g.DB, msg.To)))
-	if len(msg.CC) > 0 {
-		d.Send(fmt.Sprintf("CC: %s", FormatRecipients(g.DB, msg.CC)))
+// mailSelectOrSay is mailSelect, reporting errors and empty matches to d.
+func mailSelectOrSay(g *Game, d *Descriptor, spec string) []*gamedb.MailMessage {
+	msgs, err := g.mailSelect(d.Player, spec)
+	if err != nil {
+		d.Send(err.Error())
+		return nil
 	}
-	d.Send(fmt.Sprintf("Subject: %s", msg.Subject))
-	d.Send("---")
-	if msg.Body != "" {
-		d.Send(msg.Body)
+	if len(msgs) == 0 {
+		if num, err := strconv.Atoi(strings.TrimSpace(spec)); err == nil {
+			d.Send(fmt.Sprintf("You don't have a message #%d.", num))
+		} else {
+			d.Send("MAIL: No matching messages.")
+		}
+	}
+	return msgs
+}
+
+// mailRead reads the messages in a message list.
+func mailRead(g *Game, d *Descriptor, args string) {
+	if strings.TrimSpace(args) == "" {
+		d.Send("Usage: @mail/read <msg-list>")
+		return
+	}
+	for _, msg := range mailSelectOrSay(g, d, args) {
+		g.Mail.MarkRead(d.Player, msg.ID)
+		persistMailMessage(g, d.Player, msg)
+
+		d.Send(fmt.Sprintf("--- Message %d ---", msg.ID))
+		d.Send(fmt.Sprintf("From: %s  Date: %s", playerName(g.DB, msg.From), msg.Time.Format("Mon Jan 02 15:04 2006")))
+		d.Send(fmt.Sprintf("To: %s", FormatRecipients(g.DB, msg.To)))
+		if len(msg.CC) > 0 {
+			d.Send(fmt.Sprintf("CC: %s", FormatRecipients(g.DB, msg.CC)))
+		}
+		d.Send(fmt.Sprintf("Subject: %s", msg.Subject))
+		d.Send("---")
+		if msg.Body != "" {
+			d.Send(msg.Body)
+		}
+		d.Send("--- End Message ---")
 	}
-	d.Send("--- End Message ---")
 }
 
-// mailList lists inbox messages.
-func mailList(g *Game, d *Descriptor) {
-	inbox := g.Mail.GetInbox(d.Player)
-	if len(inbox) == 0 {
-		d.Send("You have no mail.")
+// mailList lists the messages in a message list, by default the whole
+// current folder.
+func mailList(g *Game, d *Descriptor, args string) {
+	msgs, err := g.mailSelect(d.Player, args)
+	if err != nil {
+		d.Send(err.Error())
+		return
+	}
+	folder := g.Mail.CurrentFolder(d.Player)
+	if len(msgs) == 0 {
+		if strings.TrimSpace(args) == "" && folder == 0 {
+			d.Send("You have no mail.")
+		} else {
+			d.Send("MAIL: No matching messages.")
+		}
 		return
 	}
 
-	d.Send(fmt.Sprintf("--- Mailbox for %s (%d messages) ---", playerName(g.DB, d.Player), len(inbox)))
+	if folder == 0 {
+		d.Send(fmt.Sprintf("--- Mailbox for %s (%d messages) ---", playerName(g.DB, d.Player), len(msgs)))
+	} else {
+		d.Send(fmt.Sprintf("--- Mailbox for %s, folder %d: %s (%d messages) ---", playerName(g.DB, d.Player),
+			folder, g.mailFolderName(d.Player, folder), len(msgs)))
+	}
 	d.Send(fmt.Sprintf("%-4s %-5s %-16s %-20s %s", "#", "Flags", "From", "Date", "Subject"))
-	for _, msg := range inbox {
+	for _, msg := range msgs {
 		from := playerName(g.DB, msg.From)
 		if len(from) > 16 {
 			from = from[:16]
@@ -270,40 +473,44 @@ func mailList(g *Game, d *Descriptor) {
 	d.Send("---")
 }
 
-// mailClear marks a message for deletion.
+// mailClear marks messages for deletion, by default the whole current
+// folder. Safe messages are skipped.
 func mailClear(g *Game, d *Descriptor, args string) {
-	num, err := strconv.Atoi(strings.TrimSpace(args))
-	if err != nil {
-		d.Send("Usage: @mail/clear <number>")
+	msgs := mailSelectOrSay(g, d, args)
+	if len(msgs) == 1 {
+		msg := msgs[0]
+		if g.Mail.MarkCleared(d.Player, msg.ID) {
+			persistMailMessage(g, d.Player, msg)
+			d.Send(fmt.Sprintf("Message %d marked for clearing.", msg.ID))
+		} else {
+			d.Send(fmt.Sprintf("Message %d is marked safe and cannot be cleared.", msg.ID))
+		}
 		return
 	}
-	if g.Mail.MarkCleared(d.Player, num) {
-		msg := g.Mail.GetMessage(d.Player, num)
-		persistMailMessage(g, d.Player, msg)
-		d.Send(fmt.Sprintf("Message %d marked for clearing.", num))
-	} else {
-		msg := g.Mail.GetMessage(d.Player, num)
-		if msg != nil && msg.Flags&gamedb.MailSafe != 0 {
-			d.Send(fmt.Sprintf("Message %d is marked safe and cannot be cleared.", num))
-		} else {
-			d.Send(fmt.Sprintf("You don't have a message #%d.", num))
+	n := 0
+	for _, msg := range msgs {
+		if g.Mail.MarkCleared(d.Player, msg.ID) {
+			persistMailMessage(g, d.Player, msg)
+			n++
 		}
 	}
+	if len(msgs) > 0 {
+		d.Send(fmt.Sprintf("%d message(s) marked for clearing.", n))
+	}
 }
 
-// mailUnclear removes the cleared flag.
+// mailUnclear removes the cleared flag from messages.
 func mailUnclear(g *Game, d *Descriptor, args string) {
-	num, err := strconv.Atoi(strings.TrimSpace(args))
-	if err != nil {
-		d.Send("Usage: @mail/unclear <number>")
-		return
-	}
-	if g.Mail.MarkUncleared(d.Player, num) {
-		msg := g.Mail.GetMessage(d.Player, num)
+	msgs := mailSelectOrSay(g, d, args)
+	for _, msg := range msgs {
+		g.Mail.MarkUncleared(d.Player, msg.ID)
 		persistMailMessage(g, d.Player, msg)
-		d.Send(fmt.Sprintf("Message %d uncleared.", num))
-	} else {
-		d.Send(fmt.Sprintf("You don't have a message #%d.", num))
+	}
+	switch {
+	case len(msgs) == 1:
+		d.Send(fmt.Sprintf("Message %d uncleared.", msgs[0].ID))
+	case len(msgs) > 1:
+		d.Send(fmt.Sprintf("%d message(s) uncleared.", len(msgs)))
 	}
 }
 
@@ -320,8 +527,10 @@ func mailPurge(g *Game, d *Descriptor) {
 	d.Send(fmt.Sprintf("%d message(s) purged.", len(purged)))
 }
 
-// mailReply starts a reply to a message.
-func mailReply(g *Game, d *Descriptor, args string) {
+// mailReply starts a reply to a message: to its sender, or with all to
+// its sender and every other recipient. With quote, the original is
+// quoted into the draft.
+func mailReply(g *Game, d *Descriptor, args string, all, quote bool) {
 	num, err := strconv.Atoi(strings.TrimSpace(args))
 	if err != nil {
 		d.Send("Usage: @mail/reply <number>")
@@ -333,51 +542,232 @@ func mailReply(g *Game, d *Descriptor, args string) {
 		return
 	}
 
+	g.Mail.ClearDraft(d.Player)
 	draft := g.Mail.GetDraft(d.Player)
 	draft.To = []gamedb.DBRef{msg.From}
+	if all {
+		seen := map[gamedb.DBRef]bool{msg.From: true, d.Player: true}
+		for _, ref := range append(append([]gamedb.DBRef{}, msg.To...), msg.CC...) {
+			if !seen[ref] {
+				seen[ref] = true
+				draft.To = append(draft.To, ref)
+			}
+		}
+	}
+	draft.Flags = gamedb.MailReply
 	subj := msg.Subject
 	if !strings.HasPrefix(strings.ToLower(subj), "re:") {
 		subj = "Re: " + subj
 	}
 	draft.Subject = subj
-	d.Send(fmt.Sprintf("Replying to %s. Subject: %s", playerName(g.DB, msg.From), subj))
+	if quote {
+		draft.Body.WriteString(fmt.Sprintf("On %s, %s wrote:", msg.Time.Format("Mon Jan 02 15:04 2006"), playerName(g.DB, msg.From)))
+		for _, line := range strings.Split(msg.Body, "\n") {
+			draft.Body.WriteString("\n> " + line)
+		}
+	}
+	d.Send(fmt.Sprintf("Replying to %s. Subject: %s", FormatRecipients(g.DB, draft.To), subj))
 	d.Send("Use - <text> to compose body, then @mail/send.")
 }
 
-// mailForward forwards a message to other players.
-func mailForward(g *Game, d *Descriptor, args string) {
+// mailForward forwards messages to other players.
+func mailForward(g *Game, d *Descriptor, args string, flags int, silent bool) {
 	idx := strings.Index(args, "=")
 	if idx < 0 {
-		d.Send("Usage: @mail/forward <number>=<player list>")
+		d.Send("Usage: @mail/forward <msg-list>=<player list>")
+		return
+	}
+	msgs := mailSelectOrSay(g, d, args[:idx])
+	if len(msgs) == 0 {
 		return
 	}
-	numStr := strings.TrimSpace(args[:idx])
-	targetStr := strings.TrimSpace(args[idx+1:])
 
-	num, err := strconv.Atoi(numStr)
-	if err != nil {
-		d.Send("Usage: @mail/forward <number>=<player list>")
+	recipients := parseMailRecipients(g, d, strings.TrimSpace(args[idx+1:]))
+	if len(recipients) == 0 {
 		return
 	}
-	msg := g.Mail.GetMessage(d.Player, num)
-	if msg == nil {
-		d.Send(fmt.Sprintf("You don't have a message #%d.", num))
+
+	for _, msg := range msgs {
+		subj := msg.Subject
+		if !strings.HasPrefix(strings.ToLower(subj), "fwd:") {
+			subj = "Fwd: " + subj
+		}
+		body := fmt.Sprintf("--- Forwarded message from %s ---\n%s\n--- End forwarded message ---",
+			playerName(g.DB, msg.From), msg.Body)
+
+		deliverMail(g, d, recipients, nil, nil, subj, body, flags|gamedb.MailForward, silent)
+	}
+}
+
+// mailFolderName returns the name player gave folder, INCOMING for an
+// unnamed folder 0, or "".
+func (g *Game) mailFolderName(player gamedb.DBRef, folder int) string {
+	prefix := strconv.Itoa(folder) + ":"
+	for _, tok := range strings.Fields(g.GetAttrText(player, aMailFolders)) {
+		if strings.HasPrefix(tok, prefix) {
+			return tok[len(prefix):]
+		}
+	}
+	if folder == 0 {
+		return "INCOMING"
+	}
+	return ""
+}
+
+// mailFolderNumber parses a folder given by number or name, or returns -1.
+func (g *Game) mailFolderNumber(player gamedb.DBRef, s string) int {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if n >= 0 && n < gamedb.MailFolders {
+			return n
+		}
+		return -1
+	}
+	for i := 0; i < gamedb.MailFolders; i++ {
+		if name := g.mailFolderName(player, i); name != "" && strings.EqualFold(name, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// mailFolder implements @mail/folder: with no argument it lists the
+// folders in use, with <folder> it makes that the current folder, and
+// with <number>=<name> it names a folder.
+func mailFolder(g *Game, d *Descriptor, args string) {
+	args = strings.TrimSpace(args)
+	if numStr, name, ok := strings.Cut(args, "="); ok {
+		n := g.mailFolderNumber(d.Player, numStr)
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if n < 0 || name == "" || strings.ContainsAny(name, " :") {
+			d.Send("MAIL: Invalid folder name or number.")
+			return
+		}
+		var toks []string
+		prefix := strconv.Itoa(n) + ":"
+		for _, tok := range strings.Fields(g.GetAttrText(d.Player, aMailFolders)) {
+			if !strings.HasPrefix(tok, prefix) {
+				toks = append(toks, tok)
+			}
+		}
+		g.SetAttr(d.Player, aMailFolders, strings.Join(append(toks, prefix+name), " "))
+		d.Send(fmt.Sprintf("MAIL: Folder %d now named '%s'", n, name))
+		return
+	}
+	if args != "" {
+		n := g.mailFolderNumber(d.Player, args)
+		if n < 0 {
+			d.Send("MAIL: Invalid folder name or number.")
+			return
+		}
+		g.Mail.SetCurrentFolder(d.Player, n)
+		d.Send(fmt.Sprintf("MAIL: Current folder set to %d [%s].", n, g.mailFolderName(d.Player, n)))
 		return
 	}
 
-	recipients := parseMailRecipients(g, d, targetStr)
-	if len(recipients) == 0 {
+	counts := make([][3]int, gamedb.MailFolders) // total, unread, cleared
+	for _, msg := range g.Mail.GetInbox(d.Player) {
+		if msg.Folder < 0 || msg.Folder >= gamedb.MailFolders {
+			continue
+		}
+		counts[msg.Folder][0]++
+		if msg.Flags&gamedb.MailIsRead == 0 {
+			counts[msg.Folder][1]++
+		}
+		if msg.Flags&gamedb.MailCleared != 0 {
+			counts[msg.Folder][2]++
+		}
+	}
+	for i, c := range counts {
+		name := g.mailFolderName(d.Player, i)
+		if c[0] == 0 && name == "" {
+			continue
+		}
+		d.Send(fmt.Sprintf("MAIL: %d messages in folder %d [%s] (%d unread, %d cleared).", c[0], i, name, c[1], c[2]))
+	}
+	cur := g.Mail.CurrentFolder(d.Player)
+	d.Send(fmt.Sprintf("MAIL: Current folder is %d [%s].", cur, g.mailFolderName(d.Player, cur)))
+}
+
+// mailFile implements @mail/file <msg-list>=<folder>.
+func mailFile(g *Game, d *Descriptor, args string) {
+	spec, dest, ok := strings.Cut(args, "=")
+	if !ok {
+		d.Send("Usage: @mail/file <msg-list>=<folder>")
 		return
 	}
+	folder := g.mailFolderNumber(d.Player, dest)
+	if folder < 0 {
+		d.Send("MAIL: Invalid folder name or number.")
+		return
+	}
+	for _, msg := range mailSelectOrSay(g, d, spec) {
+		g.Mail.FileMessage(d.Player, msg.ID, folder)
+		persistMailMessage(g, d.Player, msg)
+		d.Send(fmt.Sprintf("MAIL: Message %d filed in folder %d.", msg.ID, folder))
+	}
+}
 
-	subj := msg.Subject
-	if !strings.HasPrefix(strings.ToLower(subj), "fwd:") {
-		subj = "Fwd: " + subj
+// mailReview implements @mail/review [<player>|all][=<msg-list>]: the
+// copies of your messages still in recipients' mailboxes, numbered in the
+// order sent. With a list, those copies are shown in full.
+func mailReview(g *Game, d *Descriptor, args string) {
+	who, spec, read := strings.Cut(args, "=")
+	who = strings.TrimSpace(who)
+	to := gamedb.Nothing
+	if who != "" && !strings.EqualFold(who, "all") {
+		if to = LookupPlayer(g.DB, who); to == gamedb.Nothing {
+			d.Send("MAIL: No such player.")
+			return
+		}
+	}
+	var sent []SentMail
+	for _, s := range g.Mail.SentBy(d.Player) {
+		if to == gamedb.Nothing || s.To == to {
+			sent = append(sent, s)
+		}
+	}
+	if len(sent) == 0 {
+		d.Send("MAIL: You have no unpurged mail to review.")
+		return
+	}
+
+	if read {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+		if !isRange {
+			hi = lo
+		}
+		low, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		high, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || low < 1 || high < low {
+			d.Send("MAIL: Invalid message specification.")
+			return
+		}
+		for i := low; i <= high && i <= len(sent); i++ {
+			msg := sent[i-1].Msg
+			d.Send(fmt.Sprintf("--- Sent message %d, to %s ---", i, playerName(g.DB, sent[i-1].To)))
+			d.Send(fmt.Sprintf("Date: %s  Flags: %s", msg.Time.Format("Mon Jan 02 15:04 2006"), FormatMailFlags(msg)))
+			d.Send(fmt.Sprintf("Subject: %s", msg.Subject))
+			d.Send("---")
+			if msg.Body != "" {
+				d.Send(msg.Body)
+			}
+			d.Send("--- End Message ---")
+		}
+		return
 	}
-	body := fmt.Sprintf("--- Forwarded message from %s ---\n%s\n--- End forwarded message ---",
-		playerName(g.DB, msg.From), msg.Body)
 
-	deliverMail(g, d, recipients, nil, subj, body)
+	d.Send(fmt.Sprintf("--- Mail sent by %s (%d messages) ---", playerName(g.DB, d.Player), len(sent)))
+	d.Send(fmt.Sprintf("%-4s %-16s %-5s %-20s %s", "#", "To", "Flags", "Date", "Subject"))
+	for i, s := range sent {
+		name := playerName(g.DB, s.To)
+		if len(name) > 16 {
+			name = name[:16]
+		}
+		d.Send(fmt.Sprintf("%-4d %-16s %-5s %-20s %s", i+1, name, FormatMailFlags(s.Msg),
+			s.Msg.Time.Format("Jan 02 15:04"), s.Msg.Subject))
+	}
+	d.Send("---")
 }
 
 // mailStats shows mail statistics.
@@ -402,25 +792,29 @@ func mailStats(g *Game, d *Descriptor, args string) {
 		playerName(g.DB, player), total, unread, cleared))
 }
 
-// mailSafe marks a message as safe (protected from purge).
+// mailSafe marks messages as safe (protected from purge).
 func mailSafe(g *Game, d *Descriptor, args string) {
-	num, err := strconv.Atoi(strings.TrimSpace(args))
-	if err != nil {
-		d.Send("Usage: @mail/safe <number>")
+	if strings.TrimSpace(args) == "" {
+		d.Send("Usage: @mail/safe <msg-list>")
 		return
 	}
-	if g.Mail.MarkSafe(d.Player, num) {
-		msg := g.Mail.GetMessage(d.Player, num)
+	msgs := mailSelectOrSay(g, d, args)
+	for _, msg := range msgs {
+		g.Mail.MarkSafe(d.Player, msg.ID)
 		persistMailMessage(g, d.Player, msg)
-		d.Send(fmt.Sprintf("Message %d marked safe.", num))
-	} else {
-		d.Send(fmt.Sprintf("You don't have a message #%d.", num))
+	}
+	switch {
+	case len(msgs) == 1:
+		d.Send(fmt.Sprintf("Message %d marked safe.", msgs[0].ID))
+	case len(msgs) > 1:
+		d.Send(fmt.Sprintf("%d message(s) marked safe.", len(msgs)))
 	}
 }
 
 // --- Helpers ---
 
-// parseMailRecipients parses a comma/space separated list of player names.
+// parseMailRecipients parses a comma/space separated list of player names
+// and *mail aliases.
 func parseMailRecipients(g *Game, d *Descriptor, input string) []gamedb.DBRef {
 	// Split on commas and spaces
 	input = strings.ReplaceAll(input, ",", " ")
@@ -431,6 +825,15 @@ func parseMailRecipients(g *Game, d *Descriptor, input string) []gamedb.DBRef {
 		if name == "" {
 			continue
 		}
+		if name[0] == '*' {
+			ma := g.findMailAlias(d.Player, name)
+			if ma == nil {
+				d.Send(fmt.Sprintf("No such mail alias: %s", name))
+				return nil
+			}
+			result = append(result, ma.Members...)
+			continue
+		}
 		ref := LookupPlayer(g.DB, name)
 		if ref == gamedb.Nothing {
 			d.Send(fmt.Sprintf("No such player: %s", name))
@@ -445,8 +848,25 @@ func parseMailRecipients(g *Game, d *Descriptor, input string) []gamedb.DBRef {
 }
 
 // deliverMail sends a message and handles persistence + notifications.
-func deliverMail(g *Game, d *Descriptor, to, cc []gamedb.DBRef, subject, body string) {
-	delivered := g.Mail.SendMessage(d.Player, to, cc, subject, body)
+// Unless silent, the sender is told who it went to.
+func deliverMail(g *Game, d *Descriptor, to, cc, bcc []gamedb.DBRef, subject, body string, flags int, silent bool) {
+	g.sendMailFlags(d.Player, to, cc, bcc, subject, body, flags)
+
+	if !silent {
+		names := FormatRecipients(g.DB, to)
+		d.Send(fmt.Sprintf("Mail sent to %s.", names))
+	}
+}
+
+// sendMail delivers a message from sender, persists each recipient's copy,
+// and notifies recipients who are online. Shared by @mail and mailsend().
+func (g *Game) sendMail(from gamedb.DBRef, to, cc []gamedb.DBRef, subject, body string) {
+	g.sendMailFlags(from, to, cc, nil, subject, body, 0)
+}
+
+// sendMailFlags is sendMail with blind carbon copies and message flags.
+func (g *Game) sendMailFlags(from gamedb.DBRef, to, cc, bcc []gamedb.DBRef, subject, body string, flags int) {
+	delivered := g.Mail.Deliver(from, to, cc, bcc, subject, body, flags)
 
 	// Persist all delivered messages
 	if g.Store != nil {
@@ -455,18 +875,59 @@ func deliverMail(g *Game, d *Descriptor, to, cc []gamedb.DBRef, subject, body st
 		}
 	}
 
-	// Notify online recipients
-	for player := range delivered {
-		if player == d.Player {
+	// Notify recipients, run their AMAIL, and forward to verified email
+	for player, msg := range delivered {
+		if player == from {
 			continue
 		}
-		for _, desc := range g.Conns.GetByPlayer(player) {
-			desc.Send(fmt.Sprintf("You have new mail from %s.", playerName(g.DB, d.Player)))
-		}
+		g.notifyMail(player, msg)
+		g.QueueAttrAction(player, from, aAMail, nil)
+		g.forwardMail(player, msg)
+	}
+}
+
+// notifyMail tells player, if connected, that msg has arrived. Web and
+// OOB clients get it as a structured mail event.
+func (g *Game) notifyMail(player gamedb.DBRef, msg *gamedb.MailMessage) {
+	urgent := msg.Flags&gamedb.MailUrgent != 0
+	text := fmt.Sprintf("You have new mail from %s.", playerName(g.DB, msg.From))
+	if urgent {
+		text = fmt.Sprintf("You have new URGENT mail from %s.", playerName(g.DB, msg.From))
+	}
+	if g.EventBus == nil {
+		g.Conns.SendToPlayer(player, text)
+		return
 	}
+	g.EmitEvent(player, "MAIL", events.Event{
+		Type: events.EvMail, Source: msg.From, Text: text,
+		Data: map[string]any{
+			"kind":    "new",
+			"id":      msg.ID,
+			"from":    playerName(g.DB, msg.From),
+			"dbref":   int(msg.From),
+			"subject": msg.Subject,
+			"urgent":  urgent,
+		},
+	})
+}
 
-	names := FormatRecipients(g.DB, to)
-	d.Send(fmt.Sprintf("Mail sent to %s.", names))
+// mailLoginSummary shows d's player how much mail is waiting, in the
+// form C TinyMUSH uses at login, and sends it as a mail event.
+func (g *Game) mailLoginSummary(d *Descriptor) {
+	total, unread, cleared := g.Mail.CountMessages(d.Player)
+	if total == 0 {
+		return
+	}
+	text := fmt.Sprintf("MAIL: You have %d message(s) (%d unread, %d cleared).", total, unread, cleared)
+	if unread > 0 {
+		text += " Type @mail to read."
+	}
+	ev := events.Event{
+		Type: events.EvMail, Player: d.Player, Source: d.Player,
+		Text: g.WrapMarker(d.Player, "MAIL", text),
+		Data: map[string]any{"kind": "summary", "total": total, "unread": unread, "cleared": cleared},
+	}
+	d.Receive(ev)
 }
 
 // persistMailMessage writes a single message update to bbolt.
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// exportMail renders a player's own mail in the given format ("mbox" or
+// "json"). folder < 0 exports every folder. Returns the export and its
+// MIME type.
+func exportMail(g *Game, player gamedb.DBRef, folder int, format string) ([]byte, string, error) {
+	var msgs []*gamedb.MailMessage
+	for _, msg := range g.Mail.GetInbox(player) {
+		if folder < 0 || msg.Folder == folder {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	switch format {
+	case "mbox":
+		return exportMbox(g, msgs), "application/mbox", nil
+	case "json":
+		type jsonMessage struct {
+			ID       int      `json:"id"`
+			Folder   int      `json:"folder"`
+			From     int      `json:"from"`
+			FromName string   `json:"from_name"`
+			To       []string `json:"to"`
+			CC       []string `json:"cc,omitempty"`
+			Subject  string   `json:"subject"`
+			Body     string   `json:"body"`
+			Time     string   `json:"time"`
+			Flags    string   `json:"flags"`
+		}
+		out := make([]jsonMessage, 0, len(msgs))
+		for _, msg := range msgs {
+			out = append(out, jsonMessage{
+				ID:       msg.ID,
+				Folder:   msg.Folder,
+				From:     int(msg.From),
+				FromName: playerName(g.DB, msg.From),
+				To:       recipientNames(g.DB, msg.To),
+				CC:       recipientNames(g.DB, msg.CC),
+				Subject:  msg.Subject,
+				Body:     msg.Body,
+				Time:     msg.Time.UTC().Format(time.RFC3339),
+				Flags:    FormatMailFlags(msg),
+			})
+		}
+		data, err := json.MarshalIndent(map[string]any{
+			"player":   int(player),
+			"name":     playerName(g.DB, player),
+			"messages": out,
+		}, "", "  ")
+		return data, "application/json", err
+	}
+	return nil, "", fmt.Errorf("unknown format %q", format)
+}
+
+// exportMbox writes messages in mboxrd format: lines in the body that
+// start with (any number of '>' followed by) "From " get one more '>'.
+func exportMbox(g *Game, msgs []*gamedb.MailMessage) []byte {
+	host := "localhost"
+	if g.Conf != nil && g.Conf.MudName != "" {
+		host = strings.ToLower(strings.ReplaceAll(g.Conf.MudName, " ", ""))
+	}
+	var b strings.Builder
+	for _, msg := range msgs {
+		sender := fmt.Sprintf("%d@%s", msg.From, host)
+		fmt.Fprintf(&b, "From %s %s\n", sender, msg.Time.UTC().Format(time.ANSIC))
+		fmt.Fprintf(&b, "From: %s <%s>\n", playerName(g.DB, msg.From), sender)
+		fmt.Fprintf(&b, "To: %s\n", strings.Join(recipientNames(g.DB, msg.To), ", "))
+		if len(msg.CC) > 0 {
+			fmt.Fprintf(&b, "Cc: %s\n", strings.Join(recipientNames(g.DB, msg.CC), ", "))
+		}
+		fmt.Fprintf(&b, "Subject: %s\n", msg.Subject)
+		fmt.Fprintf(&b, "Date: %s\n", msg.Time.Format(time.RFC1123Z))
+		fmt.Fprintf(&b, "X-Mail-Id: %d\n", msg.ID)
+		fmt.Fprintf(&b, "X-Mail-Folder: %d\n", msg.Folder)
+		fmt.Fprintf(&b, "X-Mail-Flags: %s\n\n", FormatMailFlags(msg))
+		for _, line := range strings.Split(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n") {
+			if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+				line = ">" + line
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// recipientNames returns player names for a recipient list.
+func recipientNames(db *gamedb.Database, refs []gamedb.DBRef) []string {
+	names := make([]string, 0, len(refs))
+	for _, r := range refs {
+		names = append(names, playerName(db, r))
+	}
+	return names
+}
+
+// parseMailFolder parses an export folder argument: a folder number, or
+// "all" / empty for every folder (-1).
+func parseMailFolder(s string) (int, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "all" {
+		return -1, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n >= gamedb.MailFolders {
+		return 0, false
+	}
+	return n, true
+}
+
+// mailExport handles @mail/export <folder>=<format>. The export itself is
+// served by the web API so it can be downloaded as a file.
+func mailExport(g *Game, d *Descriptor, args string) {
+	folderArg, format := args, "mbox"
+	if idx := strings.Index(args, "="); idx >= 0 {
+		folderArg, format = args[:idx], args[idx+1:]
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != "mbox" && format != "json" {
+		d.Send("MAIL: Export format must be mbox or json.")
+		return
+	}
+	folder, ok := parseMailFolder(folderArg)
+	if !ok {
+		d.Send("MAIL: Invalid folder number.")
+		return
+	}
+	if g.Conf == nil || !g.Conf.WebEnabled {
+		d.Send("MAIL: Mail export requires the web API, which is not enabled.")
+		return
+	}
+
+	count := 0
+	for _, msg := range g.Mail.GetInbox(d.Player) {
+		if folder < 0 || msg.Folder == folder {
+			count++
+		}
+	}
+	folderStr := "all"
+	if folder >= 0 {
+		folderStr = strconv.Itoa(folder)
+	}
+	path := fmt.Sprintf("/api/v1/mail/export?folder=%s&format=%s", folderStr, format)
+	if g.Conf.WebDomain != "" {
+		path = fmt.Sprintf("https://%s:%d%s", g.Conf.WebDomain, g.Conf.WebPort, path)
+	}
+	d.Send(fmt.Sprintf("MAIL: %d message(s) ready for export as %s.", count, format))
+	d.Send("MAIL: Download with your web API token: GET " + path)
+}
+
+// handleMailExport serves GET /api/v1/mail/export?folder=<n|all>&format=<mbox|json>
+// for the authenticated player's own mail.
+func (ws *WebServer) handleMailExport(w http.ResponseWriter, r *http.Request) {
+	claims := ClaimsFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if ws.game.Mail == nil {
+		http.Error(w, `{"error":"mail is not enabled"}`, http.StatusNotFound)
+		return
+	}
+	folder, ok := parseMailFolder(r.URL.Query().Get("folder"))
+	if !ok {
+		http.Error(w, `{"error":"invalid folder"}`, http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "mbox"
+	}
+	data, ctype, err := exportMail(ws.game, claims.PlayerRef, folder, format)
+	if err != nil {
+		http.Error(w, `{"error":"format must be mbox or json"}`, http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"mail-%d.%s\"", claims.PlayerRef, format))
+	w.Write(data)
+}
+
+// --- @mailto: forward received mail to a verified email address ---
+
+// mailtoVerifyExpiry is how long an @mailto verification code stays valid.
+const mailtoVerifyExpiry = time.Hour
+
+// mailtoCooldown is how long a player must wait between verification
+// emails, and mailtoPendingLimit how many may be outstanding at once, so
+// @mailto can't be used to flood someone else's inbox.
+const (
+	mailtoCooldown     = 5 * time.Minute
+	mailtoPendingLimit = 50
+)
+
+// MailtoPending is an address awaiting confirmation via @mailto/verify.
+type MailtoPending struct {
+	Address string
+	Code    string
+	Expires time.Time
+}
+
+// mailtoAttrNum returns the attribute number of MAILTO, defining it on first
+// use. It is wizard-only so players can't skip verification with &MAILTO.
+func (g *Game) mailtoAttrNum() int {
+	const flags = gamedb.AFWizard | gamedb.AFMDark | gamedb.AFNoProg | gamedb.AFNoClone
+	def, ok := g.DB.AttrByName["MAILTO"]
+	if !ok {
+		num := g.DB.NextAttr
+		g.DB.NextAttr++
+		g.DB.AddAttrDef(num, "MAILTO", flags)
+		def = g.DB.AttrNames[num]
+	} else if def.Flags&flags == flags {
+		return def.Number
+	}
+	def.Flags |= flags
+	if g.Store != nil {
+		g.Store.PutAttrDef(def)
+		g.Store.PutMeta()
+	}
+	return def.Number
+}
+
+// forwardMail emails a copy of a delivered message to the recipient's
+// verified MAILTO address, if they have one.
+func (g *Game) forwardMail(recipient gamedb.DBRef, msg *gamedb.MailMessage) {
+	if !g.EmailEnabled() {
+		return
+	}
+	addr := g.GetAttrTextDirect(recipient, g.mailtoAttrNum())
+	if addr == "" {
+		return
+	}
+	mudName := "MUSH"
+	if g.Conf.MudName != "" {
+		mudName = g.Conf.MudName
+	}
+	subject := fmt.Sprintf("[%s] %s", mudName, msg.Subject)
+	body := fmt.Sprintf("From: %s\nTo: %s\nDate: %s\n\n%s",
+		playerName(g.DB, msg.From), strings.Join(recipientNames(g.DB, msg.To), ", "),
+		msg.Time.Format(time.RFC1123), msg.Body)
+	go func() {
+		if err := g.sendEmail(addr, subject, body); err != nil {
+			log.Printf("MAIL: forwarding to #%d failed: %v", recipient, err)
+		}
+	}()
+}
+
+// cmdMailto handles @mailto:
+//
+//	@mailto               show the forwarding address
+//	@mailto <address>     send a verification code to <address>
+//	@mailto/verify <code> confirm the address and start forwarding
+//	@mailto/off           stop forwarding
+func cmdMailto(g *Game, d *Descriptor, args string, switches []string) {
+	if g.Mail == nil {
+		d.Send("The mail system is not enabled.")
+		return
+	}
+	if obj, ok := g.DB.Objects[d.Player]; !ok || obj.ObjType() != gamedb.TypePlayer {
+		d.Send("MAIL: Only players can forward their mail.")
+		return
+	}
+	args = strings.TrimSpace(args)
+	attrNum := g.mailtoAttrNum()
+
+	switch {
+	case HasSwitch(switches, "off"):
+		g.SetAttr(d.Player, attrNum, "")
+		g.Mail.ClearMailtoPending(d.Player)
+		d.Send("MAIL: Email forwarding disabled.")
+
+	case HasSwitch(switches, "verify"):
+		p := g.Mail.MailtoPending(d.Player)
+		if p == nil || time.Now().After(p.Expires) {
+			g.Mail.ClearMailtoPending(d.Player)
+			d.Send("MAIL: No verification is pending. Use @mailto <address> first.")
+			return
+		}
+		if args != p.Code {
+			d.Send("MAIL: That verification code is not correct.")
+			return
+		}
+		g.SetAttr(d.Player, attrNum, p.Address)
+		g.Mail.ClearMailtoPending(d.Player)
+		d.Send(fmt.Sprintf("MAIL: New mail will be forwarded to %s.", p.Address))
+
+	case args == "":
+		if addr := g.GetAttrTextDirect(d.Player, attrNum); addr != "" {
+			d.Send(fmt.Sprintf("MAIL: Mail is forwarded to %s.", addr))
+		} else {
+			d.Send("MAIL: Mail is not forwarded.")
+		}
+
+	default:
+		if !g.EmailEnabled() {
+			d.Send("MAIL: Email is not configured on this game.")
+			return
+		}
+		addr, err := mail.ParseAddress(args)
+		if err != nil {
+			d.Send("MAIL: That is not a valid email address.")
+			return
+		}
+		code := mailtoCode()
+		if msg := g.Mail.RequestMailto(d.Player, &MailtoPending{
+			Address: addr.Address,
+			Code:    code,
+			Expires: time.Now().Add(mailtoVerifyExpiry),
+		}); msg != "" {
+			d.Send(msg)
+			return
+		}
+		name := g.PlayerName(d.Player)
+		body := fmt.Sprintf("%s asked to have their in-game mail forwarded to this address.\n\n"+
+			"To confirm, type this in the game within an hour:\n\n    @mailto/verify %s\n\n"+
+			"If you didn't ask for this, ignore this message.", name, code)
+		go func() {
+			if err := g.sendEmail(addr.Address, "Confirm mail forwarding", body); err != nil {
+				log.Printf("MAIL: verification email for %s(#%d) failed: %v", name, d.Player, err)
+			}
+		}()
+		d.Send(fmt.Sprintf("MAIL: A verification code has been sent to %s.", addr.Address))
+		d.Send("MAIL: Type @mailto/verify <code> to confirm.")
+	}
+}
+
+// mailtoCode returns a random 8-character verification code.
+func mailtoCode() string {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 8)
+	rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b)
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	aAway = 73 // A_AWAY — sent to players who page you while you're disconnected
+	aIdle = 74 // A_IDLE — sent to players who page you while you're idle
+
+	aAllowance = 41 // A_ALLOWANCE — a player's own daily paycheck
+
+	// idleWarning is how long before an idle timeout the player is warned.
+	// Short timeouts are warned at the halfway mark instead.
+	idleWarning = 5 * time.Minute
+)
+
+// canIdle reports whether player is exempt from idle_timeout: wizards and
+// anyone with the idle power, as C TinyMUSH's Can_Idle.
+func (g *Game) canIdle(player gamedb.DBRef) bool {
+	if Wizard(g, player) {
+		return true
+	}
+	o, ok := g.DB.Objects[player]
+	return ok && o.HasPower(0, gamedb.PowIdle)
+}
+
+// checkIdle warns, then disconnects, players idle past idle_timeout.
+// With idle_wiz_dark, idle wizards go DARK instead.
+func (g *Game) checkIdle(now time.Time) {
+	if g.Conf == nil || g.Conf.IdleTimeout <= 0 {
+		return
+	}
+	timeout := time.Duration(g.Conf.IdleTimeout) * time.Second
+	warnAt := timeout - idleWarning
+	if warnAt < timeout/2 {
+		warnAt = timeout / 2
+	}
+	for _, d := range g.Conns.AllDescriptors() {
+		if d.State != ConnConnected {
+			continue
+		}
+		idle := now.Sub(d.LastCmd)
+		if g.canIdle(d.Player) {
+			if g.Conf.IdleWizDark && idle >= timeout && Wizard(g, d.Player) {
+				if o, ok := g.DB.Objects[d.Player]; ok && !o.HasFlag(gamedb.FlagDark) {
+					o.Flags[0] |= gamedb.FlagDark
+					g.PersistObject(o)
+					d.IdleDark = true
+					d.Send("*** Inactivity Timeout: you are now DARK. ***")
+				}
+			}
+			continue
+		}
+		switch {
+		case idle >= timeout:
+			d.Send("*** Inactivity Timeout ***")
+			Logf(LogConnections, LevelInfo, "[%d] %s(#%d) idle timeout after %s", d.ID,
+				g.PlayerName(d.Player), d.Player, FormatConnTime(idle))
+			d.DisconnectReason = "timeout"
+			g.DisconnectPlayer(d)
+		case idle >= warnAt && d.idleWarned.Before(d.LastCmd):
+			d.idleWarned = now
+			left := int((timeout - idle + time.Minute - 1) / time.Minute)
+			d.Send(fmt.Sprintf("*** You have been idle for %d minute(s). You will be disconnected in %d minute(s) unless you do something. ***",
+				int(idle/time.Minute), left))
+		}
+	}
+}
+
+// idleUndark clears the DARK that idle_wiz_dark set, now that d typed
+// something. Wizards who set DARK themselves stay dark.
+func (g *Game) idleUndark(d *Descriptor) {
+	if !d.IdleDark {
+		return
+	}
+	d.IdleDark = false
+	if o, ok := g.DB.Objects[d.Player]; ok && o.HasFlag(gamedb.FlagDark) {
+		o.Flags[0] &^= gamedb.FlagDark
+		g.PersistObject(o)
+	}
+}
+
+// payday credits player's daily paycheck, or their ALLOWANCE if set, if
+// they haven't had one today. Players already holding earn_limit or more
+// get nothing, as do guests.
+func (g *Game) payday(player gamedb.DBRef, now time.Time) {
+	if g.Conf == nil || g.IsGuest(player) {
+		return
+	}
+	day := now.Format("2006-01-02")
+	g.payMu.Lock()
+	if g.paidOn == nil {
+		g.paidOn = make(map[gamedb.DBRef]string)
+	}
+	paid := g.paidOn[player] == day
+	g.paidOn[player] = day
+	g.payMu.Unlock()
+	if paid {
+		return
+	}
+	o, ok := g.DB.Objects[player]
+	if !ok || (g.Conf.EarnLimit > 0 && o.Pennies >= g.Conf.EarnLimit) {
+		return
+	}
+	pay := g.Conf.Paycheck
+	if allowance := g.GetAttrText(player, aAllowance); isNumeric(allowance) {
+		pay = toIntSimple(allowance)
+	}
+	if pay <= 0 {
+		return
+	}
+	o.Pennies += pay
+	g.PersistObject(o)
+}
+
+// markPaid records that player was already paid on day, so a reconnect
+// the same day (even across a restart) doesn't pay again.
+func (g *Game) markPaid(player gamedb.DBRef, day time.Time) {
+	g.payMu.Lock()
+	defer g.payMu.Unlock()
+	if g.paidOn == nil {
+		g.paidOn = make(map[gamedb.DBRef]string)
+	}
+	g.paidOn[player] = day.Format("2006-01-02")
+}
+
+// playerMaintenance runs the periodic checks: idle timeouts, and the
+// paycheck for players who stay connected past midnight.
+func (g *Game) playerMaintenance(now time.Time) {
+	g.checkIdle(now)
+	for _, d := range g.Conns.AllDescriptors() {
+		if d.State == ConnConnected {
+			g.payday(d.Player, now)
+		}
+	}
+}
+
+// maintenanceInterval is how often idle timeouts and paychecks are checked.
+// It is a variable so that tests can run the loop faster.
+var maintenanceInterval = 30 * time.Second
+
+// StartMaintenance starts the player maintenance loop.
+func (g *Game) StartMaintenance() {
+	ticker := time.NewTicker(maintenanceInterval)
+	go func() {
+		defer ticker.Stop()
+		for range ticker.C {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						Logf(LogBugs, LevelError, "PANIC in player maintenance: %v\n%s", r, debug.Stack())
+						g.wizardAlert("Server error: panic in player maintenance: %v", r)
+					}
+				}()
+				g.Do(func() { g.playerMaintenance(g.Now()) })
+			}()
+		}
+	}()
+}
+
+// pageReturn sends pager target's evaluated AWAY or IDLE message, as
+// "Away message from <target>: <text>".
+func (g *Game) pageReturn(pager, target gamedb.DBRef, tag string, attr int) {
+	text := g.GetAttrText(target, attr)
+	if text == "" {
+		return
+	}
+	ctx := MakeEvalContextForObj(g, target, pager, func(c *eval.EvalContext) {
+		functions.RegisterAll(c)
+	})
+	if msg := ctx.Exec(text, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil); msg != "" {
+		g.Conns.SendToPlayer(pager, fmt.Sprintf("%s message from %s: %s", tag, g.PlayerName(target), msg))
+	}
+}
+
+// pageIdleReturn sends pager target's IDLE message if every one of
+// target's connections has been idle at least idle_message_time.
+func (g *Game) pageIdleReturn(pager, target gamedb.DBRef) {
+	threshold := time.Duration(0)
+	if g.Conf != nil {
+		threshold = time.Duration(g.Conf.IdleMessageTime) * time.Second
+	}
+	now := g.Now()
+	for _, d := range g.Conns.GetByPlayer(target) {
+		if now.Sub(d.LastCmd) < threshold {
+			return
+		}
+	}
+	g.pageReturn(pager, target, "Idle", aIdle)
+}
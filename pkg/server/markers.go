@@ -50,9 +50,13 @@ func (g *Game) SendMarkedToRoomExcept(room gamedb.DBRef, except gamedb.DBRef, ma
 }
 
 // EmitEvent sends a structured event to a player via the event bus.
-// The event's Text is marker-wrapped for the recipient.
+// The event's Text is marker-wrapped for the recipient, and emits carry
+// the NOSPOOF attribution of their source.
 func (g *Game) EmitEvent(player gamedb.DBRef, markerType string, ev events.Event) {
 	ev.Player = player
+	if markerType == "EMIT" {
+		ev.Text = g.spoofTag(player, ev.Source, ev.Source) + ev.Text
+	}
 	ev.Text = g.WrapMarker(player, markerType, ev.Text)
 	g.EventBus.Emit(ev)
 }
@@ -65,6 +69,7 @@ func (g *Game) EmitEventToRoom(room gamedb.DBRef, markerType string, ev events.E
 			g.EmitEvent(next, markerType, ev)
 		}
 	}
+	g.EventBus.EmitToWatchers(room, ev)
 }
 
 // EmitEventToRoomExcept sends a structured event to all connected players in a
@@ -75,4 +80,5 @@ func (g *Game) EmitEventToRoomExcept(room gamedb.DBRef, except gamedb.DBRef, mar
 			g.EmitEvent(next, markerType, ev)
 		}
 	}
+	g.EventBus.EmitToWatchers(room, ev)
 }
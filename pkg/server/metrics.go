@@ -118,7 +118,7 @@ func (m *Metrics) Update() {
 // Handler returns an http.Handler that updates metrics before serving them.
 func (m *Metrics) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		m.Update()
+		m.game.Do(m.Update)
 		promhttp.Handler().ServeHTTP(w, r)
 	})
 }
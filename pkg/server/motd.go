@@ -0,0 +1,309 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// The MOTDs shown at login are motd.txt and wizmotd.txt in TextDir, which
+// @motd and @motd/wizard write; the messages @motd/keep holds in the game
+// instead, as @motd/down and @motd/full always do; and the rotating
+// messages added with @motd/add, one of which is shown at each login,
+// each between its own start and end times if it has them. All are kept
+// as runtime settings, with who set them, for wizards to see.
+
+// motdFiles are the text files @motd writes, by switch.
+var motdFiles = map[string]string{"": "motd.txt", "wizard": "wizmotd.txt"}
+
+// motdTitles name the MOTDs, by switch.
+var motdTitles = map[string]string{"": "MOTD", "wizard": "Wizard MOTD", "down": "Down MOTD", "full": "Full MOTD"}
+
+func cmdMotd(g *Game, d *Descriptor, args string, switches []string) {
+	which := ""
+	for _, sw := range []string{"wizard", "down", "full"} {
+		if HasSwitch(switches, sw) {
+			which = sw
+		}
+	}
+	if HasSwitch(switches, "list") {
+		motdList(g, d)
+		return
+	}
+	if (which != "" || args != "" || HasSwitch(switches, "add") || HasSwitch(switches, "remove")) && !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	switch {
+	case HasSwitch(switches, "add"):
+		motdAdd(g, d, args)
+		return
+	case HasSwitch(switches, "remove"):
+		motdRemove(g, d, args)
+		return
+	case args == "":
+		if !showMotd(g, d, which) {
+			if which == "" {
+				d.Send("No message of the day.")
+			} else {
+				d.Send(fmt.Sprintf("No %s set.", strings.ToLower(motdTitles[which][:1])+motdTitles[which][1:]))
+			}
+		}
+		return
+	}
+	if file := motdFiles[which]; file != "" && !HasSwitch(switches, "keep") && g.TextDir != "" && g.Texts != nil {
+		if err := g.writeMotdFile(d.Player, which, args); err != nil {
+			Logf(LogBugs, LevelError, "@motd: %v", err)
+			d.Send(fmt.Sprintf("Couldn't write %s.", file))
+			return
+		}
+		Logf(LogWizard, LevelInfo, "@motd: %s(#%d) wrote %s", g.PlayerName(d.Player), d.Player, file)
+		d.Send(fmt.Sprintf("%s written to %s.", motdTitles[which], file))
+		return
+	}
+	g.setMotd(d.Player, which, args)
+	d.Send(motdTitles[which] + " set.")
+}
+
+// cmdListMotd is @listmotd, the same as @motd/list.
+func cmdListMotd(g *Game, d *Descriptor, _ string, _ []string) {
+	motdList(g, d)
+}
+
+// motdField returns the message @motd/<which>/keep sets, or nil.
+func (g *Game) motdField(which string) *string {
+	switch which {
+	case "":
+		return &g.MOTD
+	case "wizard":
+		return &g.WizMOTD
+	case "down":
+		return &g.DownMOTD
+	case "full":
+		return &g.FullMOTD
+	}
+	return nil
+}
+
+// setMotd sets and keeps the message @motd/<which>/keep sets.
+func (g *Game) setMotd(setter gamedb.DBRef, which, text string) {
+	*g.motdField(which) = text
+	g.putRuntime(&gamedb.RuntimeSetting{Kind: gamedb.RuntimeMOTD, Name: which, Value: text, Setter: setter, Changed: g.Now()})
+}
+
+// writeMotdFile writes the text file for @motd/<which> and loads it at
+// once, rather than waiting for the file watcher.
+func (g *Game) writeMotdFile(setter gamedb.DBRef, which, text string) error {
+	file := motdFiles[which]
+	if err := os.WriteFile(filepath.Join(g.TextDir, file), []byte(text+"\n"), 0644); err != nil {
+		return err
+	}
+	g.Texts.mu.Lock()
+	if which == "wizard" {
+		g.Texts.WizMotd = text + "\n"
+	} else {
+		g.Texts.Motd = text + "\n"
+	}
+	g.Texts.mu.Unlock()
+	g.putRuntime(&gamedb.RuntimeSetting{Kind: gamedb.RuntimeMOTD, Name: file, Setter: setter, Changed: g.Now()})
+	return nil
+}
+
+// motdFileText returns the text file for @motd/<which>, if it has one.
+func (g *Game) motdFileText(which string) string {
+	if g.Texts == nil {
+		return ""
+	}
+	switch which {
+	case "":
+		return g.Texts.GetMotd()
+	case "wizard":
+		return g.Texts.GetWizMotd()
+	}
+	return ""
+}
+
+// motdSetBy says who last set a runtime setting, for wizards.
+func (g *Game) motdSetBy(kind, name string) string {
+	rs := g.Runtime[kind+":"+name]
+	if rs == nil {
+		return ""
+	}
+	return fmt.Sprintf("[Set by %s(#%d) on %s]", g.PlayerName(rs.Setter), rs.Setter, rs.Changed.Format("2006-01-02 15:04"))
+}
+
+// showMotd sends d the file and kept message for @motd/<which>, with who
+// set them if d is a wizard. It reports whether there was anything.
+func showMotd(g *Game, d *Descriptor, which string) bool {
+	wiz := Wizard(g, d.Player)
+	shown := false
+	if txt := g.motdFileText(which); txt != "" {
+		d.Send(strings.TrimRight(txt, "\r\n"))
+		if by := g.motdSetBy(gamedb.RuntimeMOTD, motdFiles[which]); wiz && by != "" {
+			d.Send(by)
+		}
+		shown = true
+	}
+	if txt := *g.motdField(which); txt != "" {
+		d.Send(txt)
+		if by := g.motdSetBy(gamedb.RuntimeMOTD, which); wiz && by != "" {
+			d.Send(by)
+		}
+		shown = true
+	}
+	return shown
+}
+
+// motdList implements @motd/list and @listmotd: the MOTD, and for
+// wizards the others and the rotating messages too.
+func motdList(g *Game, d *Descriptor) {
+	if !Wizard(g, d.Player) {
+		if !showMotd(g, d, "") {
+			d.Send("No message of the day.")
+		}
+		return
+	}
+	for _, which := range []string{"", "wizard", "down", "full"} {
+		d.Send(fmt.Sprintf("--- %s ---", motdTitles[which]))
+		if !showMotd(g, d, which) {
+			d.Send("(none)")
+		}
+	}
+	d.Send("--- Rotating ---")
+	rotation := g.motdRotation()
+	if len(rotation) == 0 {
+		d.Send("(none)")
+	}
+	now := g.Now()
+	for _, rs := range rotation {
+		when := ""
+		switch {
+		case !rs.Until.IsZero() && !now.Before(rs.Until):
+			when = " (over)"
+		case !rs.From.IsZero() && now.Before(rs.From):
+			when = " (from " + rs.From.Format("2006-01-02 15:04") + ")"
+		}
+		if !rs.Until.IsZero() {
+			when += " until " + rs.Until.Format("2006-01-02 15:04")
+		}
+		d.Send(fmt.Sprintf("%s: %s", rs.Name, rs.Value))
+		d.Send(fmt.Sprintf("   %s%s", g.motdSetBy(gamedb.RuntimeRotation, rs.Name), when))
+	}
+}
+
+// motdAdd implements @motd/add [<from>][ to <until>]=<message>.
+func motdAdd(g *Game, d *Descriptor, args string) {
+	text := args
+	var from, until time.Time
+	if when, rest, ok := strings.Cut(args, "="); ok {
+		if from, until, ok = parseMotdSchedule(when); !ok {
+			d.Send("That's not a valid time.")
+			return
+		}
+		text = rest
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		d.Send("Usage: @motd/add [<from>][ to <until>]=<message>")
+		return
+	}
+	id := 1
+	for _, rs := range g.motdRotation() {
+		if n, _ := strconv.Atoi(rs.Name); n >= id {
+			id = n + 1
+		}
+	}
+	g.putRuntime(&gamedb.RuntimeSetting{Kind: gamedb.RuntimeRotation, Name: strconv.Itoa(id), Value: text,
+		From: from, Until: until, Setter: d.Player, Changed: g.Now()})
+	Logf(LogWizard, LevelInfo, "@motd: %s(#%d) added rotating MOTD %d", g.PlayerName(d.Player), d.Player, id)
+	d.Send(fmt.Sprintf("Rotating MOTD %d added.", id))
+}
+
+// parseMotdSchedule parses "<from>", "<from> to <until>" or "to <until>",
+// in the formats @wait/until takes.
+func parseMotdSchedule(s string) (from, until time.Time, ok bool) {
+	s = " " + strings.TrimSpace(s)
+	fromStr, untilStr, _ := strings.Cut(s, " to ")
+	if fromStr = strings.TrimSpace(fromStr); fromStr != "" {
+		if from, ok = parseWaitTime(fromStr); !ok {
+			return
+		}
+	}
+	if untilStr = strings.TrimSpace(untilStr); untilStr != "" {
+		if until, ok = parseWaitTime(untilStr); !ok {
+			return
+		}
+	}
+	return from, until, true
+}
+
+// motdRemove implements @motd/remove <number>.
+func motdRemove(g *Game, d *Descriptor, args string) {
+	n, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil || !g.deleteRuntime(gamedb.RuntimeRotation, strconv.Itoa(n)) {
+		d.Send("No such rotating MOTD.")
+		return
+	}
+	Logf(LogWizard, LevelInfo, "@motd: %s(#%d) removed rotating MOTD %d", g.PlayerName(d.Player), d.Player, n)
+	d.Send(fmt.Sprintf("Rotating MOTD %d removed.", n))
+}
+
+// motdRotation returns the rotating MOTDs, in number order.
+func (g *Game) motdRotation() []*gamedb.RuntimeSetting {
+	var rotation []*gamedb.RuntimeSetting
+	for _, rs := range g.Runtime {
+		if rs.Kind == gamedb.RuntimeRotation {
+			rotation = append(rotation, rs)
+		}
+	}
+	sort.Slice(rotation, func(i, j int) bool {
+		a, _ := strconv.Atoi(rotation[i].Name)
+		b, _ := strconv.Atoi(rotation[j].Name)
+		return a < b
+	})
+	return rotation
+}
+
+// nextRotatingMotd returns the rotating MOTD to show at this login: the
+// next in turn of those due now.
+func (g *Game) nextRotatingMotd() string {
+	now := g.Now()
+	var due []*gamedb.RuntimeSetting
+	for _, rs := range g.motdRotation() {
+		if (rs.From.IsZero() || !now.Before(rs.From)) && (rs.Until.IsZero() || now.Before(rs.Until)) {
+			due = append(due, rs)
+		}
+	}
+	if len(due) == 0 {
+		return ""
+	}
+	rs := due[g.motdTurn%len(due)]
+	g.motdTurn++
+	return rs.Value
+}
+
+// sendLoginMotd sends a player the MOTDs at login.
+func (g *Game) sendLoginMotd(d *Descriptor) {
+	if txt := g.motdFileText(""); txt != "" {
+		d.SendNoNewline(txt)
+	}
+	if g.MOTD != "" {
+		d.Send(g.MOTD)
+	}
+	if txt := g.nextRotatingMotd(); txt != "" {
+		d.Send(txt)
+	}
+	if Wizard(g, d.Player) {
+		if txt := g.motdFileText("wizard"); txt != "" {
+			d.SendNoNewline(txt)
+		}
+		if g.WizMOTD != "" {
+			d.Send(g.WizMOTD)
+		}
+	}
+}
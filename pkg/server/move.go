@@ -0,0 +1,272 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
+	"github.com/crystal-mush/gotinymush/pkg/events"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	aEnter   = 33  // A_ENTER
+	aOXEnter = 34  // A_OXENTER
+	aAEnter  = 35  // A_AENTER
+	aLeave   = 50  // A_LEAVE
+	aOLeave  = 51  // A_OLEAVE
+	aALeave  = 52  // A_ALEAVE
+	aOEnter  = 53  // A_OENTER
+	aOXLeave = 54  // A_OXLEAVE
+	aMove    = 55  // A_MOVE
+	aOMove   = 56  // A_OMOVE
+	aAMove   = 57  // A_AMOVE
+	aTport   = 79  // A_TPORT
+	aOTport  = 80  // A_OTPORT
+	aOXTport = 81  // A_OXTPORT
+	aATport  = 82  // A_ATPORT
+	aLTport  = 85  // A_LTPORT — TportLock
+	aLTelOut = 94  // A_LTELOUT — TeloutLock
+	aTFail   = 138 // A_TFAIL
+	aOTFail  = 139 // A_OTFAIL
+	aATFail  = 140 // A_ATFAIL
+	aTOFail  = 141 // A_TOFAIL
+	aOTOFail = 142 // A_OTOFAIL
+	aATOFail = 143 // A_ATOFAIL
+)
+
+// Hush bits leave out parts of a move's announcements (C TinyMUSH HUSH_*).
+const (
+	hushEnter = 1 << iota // no arrival messages
+	hushLeave             // no departure messages
+	hushExit              // no exit SUCC/DROP messages to others
+)
+
+// A move says how something is getting where it's going. The zero move
+// is a plain walk (enter, leave, home).
+type move struct {
+	cause gamedb.DBRef // who moved it, if not itself
+	exit  gamedb.DBRef // the exit it's going through
+	tport bool         // teleporting: TeloutLock applies and TPORT fires
+	hush  int
+}
+
+// walk is a plain move made by the mover itself.
+var walk = move{cause: gamedb.Nothing, exit: gamedb.Nothing}
+
+// MoveObject is the movement engine behind go, enter, leave, home,
+// @teleport and kill (C TinyMUSH move_via_generic, move_via_exit and
+// move_via_teleport). It fires, in order: the exit's SUCC/OSUCC/ASUCC;
+// OXTPORT; the old location's LEAVE/OLEAVE/ALEAVE and the new one's
+// OXENTER; the exit's DROP/ODROP/ADROP; TPORT/OTPORT/ATPORT; the
+// mover's MOVE/OMOVE/AMOVE; the new location's ENTER/OENTER/AENTER and
+// the old one's OXLEAVE. Anyone connected as thing is shown the new
+// room, and anyone riding inside it is told where it went. Moves that
+// would put thing inside itself, and teleports out of a TeloutLocked
+// location, fail and MoveObject reports false.
+func (g *Game) MoveObject(thing, dest gamedb.DBRef, m move) bool {
+	obj, ok := g.DB.Objects[thing]
+	if !ok {
+		return false
+	}
+	if dest == gamedb.Home {
+		dest = g.safeHome(thing, gamedb.Nothing)
+	}
+	if _, ok := g.DB.Objects[dest]; !ok {
+		return false
+	}
+	src := obj.Location
+	if g.locatedIn(dest, thing) {
+		return false
+	}
+	if m.tport && !g.canTelOut(thing, m.cause) {
+		return false
+	}
+	quietExit := m.hush&hushExit != 0 || obj.HasFlag(gamedb.FlagDark)
+
+	if m.exit != gamedb.Nothing {
+		g.moveDidIt(thing, m.exit, aSucc, aOSucc, aASucc, quietExit)
+	}
+	if m.tport && m.hush&hushLeave == 0 {
+		g.moveDidIt(thing, thing, 0, aOXTport, 0, false)
+	}
+	g.leaveLoc(thing, dest, m.hush)
+	g.Teleport(thing, dest)
+	if obj.ObjType() == gamedb.TypePlayer {
+		g.stickyDropTo(src, thing)
+	}
+	if m.exit != gamedb.Nothing {
+		g.moveDidIt(thing, m.exit, aDrop, aODrop, aADrop, quietExit)
+	}
+	if m.tport && m.hush&hushEnter == 0 {
+		g.moveDidIt(thing, thing, aTport, aOTport, aATport, false)
+	}
+	g.moveDidIt(thing, thing, aMove, aOMove, aAMove, false)
+	g.enterLoc(thing, src, m.hush)
+	if obj.ObjType() == gamedb.TypeThing {
+		g.carryPassengers(thing, src)
+	}
+	return true
+}
+
+// locatedIn reports whether obj is container or somewhere inside it, so
+// that moving container to obj would put it inside itself.
+func (g *Game) locatedIn(obj, container gamedb.DBRef) bool {
+	for i := 0; i < 100 && obj != gamedb.Nothing; i++ {
+		if obj == container {
+			return true
+		}
+		o, ok := g.DB.Objects[obj]
+		if !ok || o.ObjType() == gamedb.TypeRoom {
+			return false
+		}
+		obj = o.Location
+	}
+	return false
+}
+
+// carryPassengers tells everyone inside a thing that has just moved from
+// src where it went. Passengers of a TRANSPARENT thing see the new
+// surroundings.
+func (g *Game) carryPassengers(thing, src gamedb.DBRef) {
+	obj := g.DB.Objects[thing]
+	name := DisplayName(obj.Name)
+	for _, p := range g.DB.SafeContents(thing) {
+		for _, dd := range g.Conns.GetByPlayer(p) {
+			if src != gamedb.Nothing {
+				dd.Send(fmt.Sprintf("%s leaves %s.", name, g.ObjName(src)))
+			}
+			dd.Send(fmt.Sprintf("%s arrives at %s.", name, g.ObjName(obj.Location)))
+			if obj.HasFlag(gamedb.FlagSeeThru) {
+				g.ShowArrival(dd, obj.Location)
+			}
+		}
+	}
+}
+
+// movesQuietly reports whether thing comes and goes from loc unannounced:
+// a DARK thing, or any thing in a DARK place, unless the place is a
+// wizard's.
+func (g *Game) movesQuietly(thing, loc gamedb.DBRef) bool {
+	obj, ok := g.DB.Objects[thing]
+	if !ok {
+		return true
+	}
+	locObj, ok := g.DB.Objects[loc]
+	if !ok {
+		return true
+	}
+	return (obj.HasFlag(gamedb.FlagDark) || locObj.HasFlag(gamedb.FlagDark)) && !Wizard(g, loc)
+}
+
+// leaveLoc announces thing leaving its location for dest (C TinyMUSH
+// process_leave_loc). The old room sees OLEAVE, or "has left." without
+// one; dest's OXENTER is shown there too.
+func (g *Game) leaveLoc(thing, dest gamedb.DBRef, hush int) {
+	obj := g.DB.Objects[thing]
+	loc := obj.Location
+	if loc == gamedb.Nothing || loc == dest {
+		return
+	}
+	quiet := hush&hushLeave != 0 || g.movesQuietly(thing, loc)
+	g.moveDidIt(thing, loc, aLeave, aOLeave, aALeave, quiet)
+	if quiet {
+		return
+	}
+	g.moveDidIt(thing, dest, 0, aOXEnter, 0, false)
+	if g.GetAttrText(loc, aOLeave) == "" {
+		msg := fmt.Sprintf("%s has left.", DisplayName(obj.Name))
+		g.Conns.SendToRoomExcept(g.DB, loc, thing, msg)
+		g.EventBus.EmitToWatchers(loc, events.Event{Type: events.EvMove, Source: thing, Text: msg})
+	}
+}
+
+// enterLoc announces thing arriving from src (C TinyMUSH
+// process_enter_loc): "has arrived." to the new room, then the room is
+// shown to thing, then the new room's ENTER/OENTER/AENTER and src's
+// OXLEAVE.
+func (g *Game) enterLoc(thing, src gamedb.DBRef, hush int) {
+	obj := g.DB.Objects[thing]
+	loc := obj.Location
+	quiet := hush&hushEnter != 0 || g.movesQuietly(thing, loc)
+	arrived := fmt.Sprintf("%s has arrived.", DisplayName(obj.Name))
+	if !quiet {
+		g.Conns.SendToRoomExcept(g.DB, loc, thing, arrived)
+		g.EventBus.EmitToWatchers(loc, events.Event{Type: events.EvMove, Source: thing, Text: arrived})
+	}
+	for _, dd := range g.Conns.GetByPlayer(thing) {
+		g.ShowArrival(dd, loc)
+	}
+	g.moveDidIt(thing, loc, aEnter, aOEnter, aAEnter, quiet)
+	if quiet {
+		return
+	}
+	if src != gamedb.Nothing {
+		g.moveDidIt(thing, src, 0, aOXLeave, 0, false)
+	}
+	g.MatchListenPatterns(loc, thing, arrived)
+}
+
+// moveDidIt shows a movement message from attrs on obj: the first to
+// thing, the second to everyone else where thing now is, after thing's
+// name, and queues the action. A quiet move only shows thing its own
+// message.
+func (g *Game) moveDidIt(thing, obj gamedb.DBRef, msgAttr, oMsgAttr, aMsgAttr int, quiet bool) {
+	eval1 := func(attr int) string {
+		text := g.GetAttrText(obj, attr)
+		if text == "" {
+			return ""
+		}
+		ctx := MakeEvalContextForObj(g, obj, thing, func(c *eval.EvalContext) {
+			functions.RegisterAll(c)
+		})
+		return ctx.Exec(text, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
+	}
+	if msgAttr > 0 {
+		if msg := eval1(msgAttr); msg != "" {
+			g.Conns.SendToPlayer(thing, msg)
+		}
+	}
+	if quiet {
+		return
+	}
+	if oMsgAttr > 0 {
+		if msg := eval1(oMsgAttr); msg != "" {
+			g.Conns.SendToRoomExcept(g.DB, g.PlayerLocation(thing), thing,
+				g.ObjName(thing)+" "+msg)
+		}
+	}
+	if aMsgAttr > 0 {
+		g.QueueAttrAction(obj, thing, aMsgAttr, nil)
+	}
+}
+
+// canTelOut checks the TeloutLock of everything between thing and its
+// room, running the TOFAIL messages of the first it fails.
+func (g *Game) canTelOut(thing, cause gamedb.DBRef) bool {
+	src := g.PlayerLocation(thing)
+	cur := src
+	for i := 0; i < 100; i++ {
+		curObj, ok := g.DB.Objects[cur]
+		if !ok {
+			return true
+		}
+		if !CouldDoIt(g, thing, cur, aLTelOut) {
+			msg := "You can't teleport out!"
+			if cause != thing && cause != gamedb.Nothing {
+				msg = "You can't be teleported out!"
+				g.Conns.SendToPlayer(cause, "You can't teleport that out!")
+			}
+			if g.GetAttrText(src, aTOFail) == "" {
+				g.Conns.SendToPlayer(thing, msg)
+			}
+			g.moveDidIt(thing, src, aTOFail, aOTOFail, aATOFail, false)
+			return false
+		}
+		if curObj.ObjType() == gamedb.TypeRoom {
+			return true
+		}
+		cur = curObj.Location
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/events"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+const (
+	aReject    = 72  // A_REJECT — sent to players your PageLock refuses
+	aLastPage  = 200 // A_LASTPAGE — the players you last paged
+	aPageGroup = 230 // A_PAGEGROUP — who else was in the last page you got
+)
+
+// cmdPage implements page <players>=<message>. With no players it pages
+// the group you last paged; without an equals sign (unless
+// page_requires_equals) the whole argument is a message to that group.
+func cmdPage(g *Game, d *Descriptor, args string, switches []string) {
+	if strings.TrimSpace(args) == "" {
+		d.Send("Page whom?")
+		return
+	}
+	var names, message string
+	if eqIdx := strings.IndexByte(args, '='); eqIdx >= 0 {
+		names = strings.TrimSpace(args[:eqIdx])
+		message = strings.TrimSpace(args[eqIdx+1:])
+	} else if g.Conf != nil && g.Conf.PageRequiresEquals {
+		d.Send("Page whom? Use page <players>=<message>.")
+		return
+	} else {
+		message = strings.TrimSpace(args)
+	}
+
+	var targets []gamedb.DBRef
+	if names == "" {
+		targets = g.pageRefs(d.Player, aLastPage)
+		if len(targets) == 0 {
+			d.Send("You haven't paged anyone yet.")
+			return
+		}
+	} else {
+		for _, name := range strings.Fields(names) {
+			target := g.matchPagee(d.Player, name)
+			if target == gamedb.Nothing {
+				d.Send(fmt.Sprintf("I don't recognize \"%s\".", name))
+				continue
+			}
+			targets = append(targets, target)
+		}
+	}
+	g.sendPage(d, targets, message, !HasSwitch(switches, "noeval"))
+}
+
+// cmdReply implements reply <message>: a page to whoever last paged you,
+// plus everyone else who got that page.
+func cmdReply(g *Game, d *Descriptor, args string, switches []string) {
+	targets := g.pageRefs(d.Player, aPageGroup)
+	if len(targets) == 0 {
+		d.Send("You haven't been paged yet.")
+		return
+	}
+	g.sendPage(d, targets, strings.TrimSpace(args), !HasSwitch(switches, "noeval"))
+}
+
+// matchPagee resolves a page recipient: a player name or alias, or the
+// start of the name of exactly one connected player the pager can see.
+func (g *Game) matchPagee(pager gamedb.DBRef, name string) gamedb.DBRef {
+	if target := LookupPlayer(g.DB, name); target != gamedb.Nothing {
+		return target
+	}
+	match := gamedb.Nothing
+	prefix := strings.ToLower(name)
+	for _, p := range g.ConnectedPlayersVisible(pager) {
+		if p == match || !strings.HasPrefix(strings.ToLower(g.PlayerName(p)), prefix) {
+			continue
+		}
+		if match != gamedb.Nothing {
+			return gamedb.Nothing
+		}
+		match = p
+	}
+	return match
+}
+
+// pageRefs reads a dbref list stored in A_LASTPAGE or A_PAGEGROUP,
+// dropping anything that is no longer a player.
+func (g *Game) pageRefs(player gamedb.DBRef, attr int) []gamedb.DBRef {
+	var refs []gamedb.DBRef
+	for _, f := range strings.Fields(g.GetAttrText(player, attr)) {
+		ref, err := parseDBRef(f)
+		if err != nil || ref == player {
+			continue
+		}
+		if obj, ok := g.DB.Objects[ref]; ok && obj.ObjType() == gamedb.TypePlayer {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// pageRateLimited records a page by player and reports whether it is over
+// page_rate_limit pages a minute. Wizards are never limited.
+func (g *Game) pageRateLimited(player gamedb.DBRef) bool {
+	if g.Conf == nil || g.Conf.PageRateLimit <= 0 || Wizard(g, player) {
+		return false
+	}
+	now := time.Now()
+	if g.pageSent == nil {
+		g.pageSent = make(map[gamedb.DBRef][]time.Time)
+	}
+	recent := g.pageSent[player][:0]
+	for _, t := range g.pageSent[player] {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= g.Conf.PageRateLimit {
+		g.pageSent[player] = recent
+		return true
+	}
+	g.pageSent[player] = append(recent, now)
+	return false
+}
+
+// pageCheck reports whether pager may page target, telling the pager why
+// not (C TinyMUSH page_check). Players who aren't connected, or whose
+// connection is hidden from the pager, send their Away message; HAVEN
+// players and those whose PageLock the pager fails send their Reject
+// message. Mortals can't page anyone who couldn't page them back.
+func (g *Game) pageCheck(d *Descriptor, target gamedb.DBRef) bool {
+	tObj := g.DB.Objects[target]
+	name := DisplayName(tObj.Name)
+	if !g.Conns.IsConnected(target) || HiddenFrom(g, d.Player, target) {
+		d.Send(fmt.Sprintf("%s is not connected.", name))
+		g.pageReturn(d.Player, target, "Away", aAway)
+		return false
+	}
+	if tObj.HasFlag(gamedb.FlagHaven) || !CouldDoIt(g, d.Player, target, aLPage) {
+		d.Send(fmt.Sprintf("%s is not accepting pages.", name))
+		g.pageReturn(d.Player, target, "Reject", aReject)
+		return false
+	}
+	if !CouldDoIt(g, target, d.Player, aLPage) {
+		if !Wizard(g, d.Player) {
+			d.Send(fmt.Sprintf("%s can't return your page.", name))
+			return false
+		}
+		d.Send(fmt.Sprintf("Warning: %s can't return your page.", name))
+	}
+	return true
+}
+
+// sendPage delivers a page to every target that will take it, charging
+// page_cost for each, and remembers the group for repeat pages and
+// replies.
+func (g *Game) sendPage(d *Descriptor, targets []gamedb.DBRef, message string, doEval bool) {
+	if g.pageRateLimited(d.Player) {
+		d.Send("You're paging too quickly. Wait a moment and try again.")
+		return
+	}
+	var group []gamedb.DBRef
+	seen := make(map[gamedb.DBRef]bool)
+	for _, target := range targets {
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		if g.pageCheck(d, target) {
+			group = append(group, target)
+		}
+	}
+	if len(group) == 0 {
+		return
+	}
+	if !g.IsGuest(d.Player) && !g.payFor(d.Player, g.pageCost()*len(group)) {
+		g.notEnoughMoney(d)
+		return
+	}
+	for _, target := range group {
+		defer g.pageIdleReturn(d.Player, target)
+	}
+
+	// Remember the group: the pager's next 'page =msg' goes to it again,
+	// and each recipient's reply goes to the pager and the others.
+	refs := make([]string, 0, len(group)+1)
+	names := make([]string, 0, len(group))
+	for _, target := range group {
+		refs = append(refs, fmt.Sprintf("#%d", target))
+		names = append(names, DisplayName(g.DB.Objects[target].Name))
+	}
+	g.SetAttr(d.Player, aLastPage, strings.Join(refs, " "))
+	refs = append(refs, fmt.Sprintf("#%d", d.Player))
+	for _, target := range group {
+		g.SetAttr(target, aPageGroup, strings.Join(refs, " "))
+	}
+
+	if message != "" && doEval {
+		message = evalExpr(g, d.Player, message)
+	}
+	senderName := g.PlayerName(d.Player)
+	nameList := strings.Join(names, ", ")
+	pageData := map[string]any{
+		"sender":  senderName,
+		"target":  nameList,
+		"message": message,
+	}
+
+	var toPager, toTarget string
+	switch {
+	case message == "":
+		toPager = fmt.Sprintf("You page %s.", nameList)
+		toTarget = fmt.Sprintf("%s pages you.", senderName)
+	case strings.HasPrefix(message, ":"):
+		pose := strings.TrimPrefix(message, ":")
+		toPager = fmt.Sprintf("Long distance to %s: %s %s", nameList, senderName, pose)
+		toTarget = fmt.Sprintf("From afar, %s %s", senderName, pose)
+	case strings.HasPrefix(message, ";"):
+		pose := strings.TrimPrefix(message, ";")
+		toPager = fmt.Sprintf("Long distance to %s: %s%s", nameList, senderName, pose)
+		toTarget = fmt.Sprintf("From afar, %s%s", senderName, pose)
+	default:
+		message = strings.TrimPrefix(message, "\"")
+		pageData["message"] = message
+		toPager = fmt.Sprintf("You page %s with \"%s\"", nameList, message)
+		toTarget = fmt.Sprintf("%s pages: %s", senderName, message)
+	}
+	if len(group) > 1 {
+		toTarget = fmt.Sprintf("To (%s), %s", nameList, toTarget)
+	}
+
+	g.EmitEvent(d.Player, "PAGE", events.Event{
+		Type: events.EvPage, Source: d.Player, Text: toPager, Data: pageData,
+	})
+	for _, target := range group {
+		g.EmitEvent(target, "PAGE", events.Event{
+			Type: events.EvPage, Source: d.Player, Text: toTarget, Data: pageData,
+		})
+	}
+}
@@ -118,6 +118,194 @@ func SeeAll(g *Game, obj gamedb.DBRef) bool {
 	return o.HasPower(0, gamedb.PowExamAll)
 }
 
+// CommAll returns true if obj has POW_COMM_ALL or is an effective wizard.
+func CommAll(g *Game, obj gamedb.DBRef) bool {
+	if Wizard(g, obj) {
+		return true
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	return o.HasPower(0, gamedb.PowCommAll)
+}
+
+// CanBoot returns true if obj has POW_BOOT or is an effective wizard.
+func CanBoot(g *Game, obj gamedb.DBRef) bool {
+	if Wizard(g, obj) {
+		return true
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	return o.HasPower(0, gamedb.PowBoot)
+}
+
+// SeeQueue returns true if obj has POW_SEE_QUEUE or is effective WizRoy.
+func SeeQueue(g *Game, obj gamedb.DBRef) bool {
+	if WizRoy(g, obj) {
+		return true
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	return o.HasPower(0, gamedb.PowSeeQueue)
+}
+
+// TelAnything returns true if obj has POW_TEL_UNRST or is an effective wizard.
+func TelAnything(g *Game, obj gamedb.DBRef) bool {
+	if Wizard(g, obj) {
+		return true
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	return o.HasPower(0, gamedb.PowTelUnrst)
+}
+
+// CanHide returns true if obj has POW_HIDE or is an effective wizard.
+func CanHide(g *Game, obj gamedb.DBRef) bool {
+	if Wizard(g, obj) {
+		return true
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	return o.HasPower(0, gamedb.PowHide)
+}
+
+// SeeHidden returns true if obj has POW_SEE_HIDDEN or is an effective wizard.
+func SeeHidden(g *Game, obj gamedb.DBRef) bool {
+	if Wizard(g, obj) {
+		return true
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	return o.HasPower(0, gamedb.PowSeeHidden)
+}
+
+// FindUnfindable returns true if obj has POW_FIND_UNFIND or is an
+// effective wizard.
+func FindUnfindable(g *Game, obj gamedb.DBRef) bool {
+	if Wizard(g, obj) {
+		return true
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	return o.HasPower(0, gamedb.PowFindUnfind)
+}
+
+// Hidden returns true if player is DARK and allowed to hide: such players
+// are left off WHO, lwho() and connect messages for those who can't
+// see hidden players.
+func Hidden(g *Game, player gamedb.DBRef) bool {
+	o, ok := g.DB.Objects[player]
+	return ok && o.HasFlag(gamedb.FlagDark) && CanHide(g, player)
+}
+
+// HiddenFrom returns true if player is hidden and viewer can't see them.
+func HiddenFrom(g *Game, viewer, player gamedb.DBRef) bool {
+	return viewer != player && Hidden(g, player) && !SeeHidden(g, viewer)
+}
+
+// Locatable returns true if player may learn where target is (C
+// TinyMUSH locatable()): always for things they can examine, their own
+// contents and things in their room, otherwise only if target is not
+// UNFINDABLE and its room is not UNFINDABLE, unless player can find the
+// unfindable.
+func Locatable(g *Game, player, target gamedb.DBRef) bool {
+	tObj, ok := g.DB.Objects[target]
+	if !ok {
+		return false
+	}
+	if player == target || FindUnfindable(g, player) || Examinable(g, player, target) {
+		return true
+	}
+	loc := tObj.Location
+	if loc == player {
+		return true
+	}
+	if pObj, ok := g.DB.Objects[player]; ok && loc != gamedb.Nothing && (loc == pObj.Location || Examinable(g, player, loc)) {
+		return true
+	}
+	if tObj.HasFlag2(gamedb.Flag2Unfindable) {
+		return false
+	}
+	room := loc
+	for i := 0; i < 100; i++ {
+		rObj, ok := g.DB.Objects[room]
+		if !ok {
+			return true
+		}
+		if rObj.ObjType() == gamedb.TypeRoom {
+			return !rObj.HasFlag2(gamedb.Flag2Unfindable)
+		}
+		room = rObj.Location
+	}
+	return true
+}
+
+// Gagged returns true if obj has the GAGGED flag and so may not speak.
+func Gagged(g *Game, obj gamedb.DBRef) bool {
+	o, ok := g.DB.Objects[obj]
+	return ok && o.HasFlag2(gamedb.Flag2Gagged)
+}
+
+// LongFingers returns true if obj has POW_LONG_FINGERS or is an effective
+// wizard, and so may reach things that aren't nearby.
+func LongFingers(g *Game, obj gamedb.DBRef) bool {
+	if Wizard(g, obj) {
+		return true
+	}
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	return o.HasPower(0, gamedb.PowLongfingers)
+}
+
+// Nearby returns true if a and b share a location or one contains the
+// other. Exits count as being in their source room (C TinyMUSH nearby).
+func Nearby(g *Game, a, b gamedb.DBRef) bool {
+	aObj, ok1 := g.DB.Objects[a]
+	bObj, ok2 := g.DB.Objects[b]
+	if !ok1 || !ok2 {
+		return false
+	}
+	aLoc, bLoc := aObj.Location, bObj.Location
+	if aObj.ObjType() == gamedb.TypeExit {
+		aLoc = aObj.Exits
+	}
+	if bObj.ObjType() == gamedb.TypeExit {
+		bLoc = bObj.Exits
+	}
+	return a == b || aLoc == bLoc || aLoc == b || bLoc == a
+}
+
+// Fixed returns true if obj may not teleport or go home: it or its owner
+// has the FIXED flag, and it can't override that with tel_anything.
+func Fixed(g *Game, obj gamedb.DBRef) bool {
+	o, ok := g.DB.Objects[obj]
+	if !ok {
+		return false
+	}
+	if !o.HasFlag2(gamedb.Flag2Fixed) {
+		owner, ok := g.DB.Objects[o.Owner]
+		if !ok || !owner.HasFlag2(gamedb.Flag2Fixed) {
+			return false
+		}
+	}
+	return !TelAnything(g, obj)
+}
+
 // CheckZone checks if player passes the zone control lock chain for thing.
 // This implements TinyMUSH's recursive zone-based control:
 // 1. thing must not be a player
@@ -1,16 +1,48 @@
 package server
 
 import (
-	"log"
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/crystal-mush/gotinymush/pkg/eval"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	"github.com/crystal-mush/gotinymush/pkg/oob"
 )
 
-// progPrompt is the standard prompt sent to players in @program mode.
-// Includes telnet Go-Ahead (IAC GA = \xff\xf9) matching TinyMUSH 3.3 behavior.
-const progPrompt = "> \xff\xf9"
+// sendProgPrompt sends the @program prompt (program_prompt, "> " by
+// default). Like TinyMUSH 3.3 it ends with telnet Go-Ahead, or End of
+// Record for clients that agreed to EOR, so clients know it's a prompt.
+func (g *Game) sendProgPrompt(d *Descriptor) {
+	prompt := "> "
+	if g.Conf != nil && g.Conf.ProgramPrompt != "" {
+		prompt = g.Conf.ProgramPrompt
+	}
+	end := oob.GA
+	if d.OOB != nil && d.OOB.EOR {
+		end = oob.EOR
+	}
+	d.SendNoNewline(prompt + string([]byte{oob.IAC, end}))
+}
+
+// inProgram reports whether any of player's connections is waiting for
+// @program input.
+func (g *Game) inProgram(player gamedb.DBRef) bool {
+	for _, d := range g.Conns.GetByPlayer(player) {
+		if d.ProgData != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// clearProgram ends player's @program, if any.
+func (g *Game) clearProgram(player gamedb.DBRef) {
+	for _, d := range g.Conns.GetByPlayer(player) {
+		d.ProgData = nil
+	}
+	g.removeAttr(player, gamedb.A_PROGCMD)
+}
 
 // ProgramData holds the state for an active @program session on a descriptor.
 type ProgramData struct {
@@ -48,6 +80,10 @@ func cmdProgram(g *Game, d *Descriptor, args string, switches []string) {
 		d.Send("Permission denied.")
 		return
 	}
+	if g.inProgram(target) {
+		d.Send("Input already pending.")
+		return
+	}
 
 	// Parse obj/attr — split on first '/' to get obj and attr[:prompt]
 	slashIdx := strings.IndexByte(objAttr, '/')
@@ -93,10 +129,11 @@ func cmdProgram(g *Game, d *Descriptor, args string, switches []string) {
 		return
 	}
 
-	// Clone q-registers from the calling descriptor's last queue context (if any)
+	// Keep the q-registers of the code running @program, as TinyMUSH
+	// does, so the program's command sees them.
 	var waitData *eval.RegisterData
-	if d.LastRData != nil {
-		waitData = d.LastRData.Clone()
+	if g.runningRData != nil {
+		waitData = g.runningRData.Clone()
 	}
 
 	// Program ALL of the target player's descriptors
@@ -114,10 +151,10 @@ func cmdProgram(g *Game, d *Descriptor, args string, switches []string) {
 		}
 	}
 	for _, td := range targetDescs {
-		td.SendNoNewline(progPrompt)
+		g.sendProgPrompt(td)
 	}
 
-	log.Printf("@program: player #%d programmed by #%d, attr %s on #%d",
+	Logf(LogWizard, LevelInfo, "@program: player #%d programmed by #%d, attr %s on #%d",
 		target, d.Player, attrName, obj)
 }
 
@@ -149,26 +186,16 @@ func cmdQuitProgram(g *Game, d *Descriptor, args string, switches []string) {
 		}
 	}
 
-	// Check if any descriptor is actually programmed
-	targetDescs := g.Conns.GetByPlayer(target)
-	inProg := false
-	for _, td := range targetDescs {
-		if td.ProgData != nil {
-			inProg = true
-			break
-		}
-	}
-	if !inProg {
+	if !g.inProgram(target) {
 		d.Send("That player is not in a program.")
 		return
 	}
 
-	// Clear ALL descriptors for the target player
-	for _, td := range targetDescs {
-		td.ProgData = nil
-	}
-	g.removeAttr(target, gamedb.A_PROGCMD)
+	g.clearProgram(target)
 	g.Conns.SendToPlayer(target, "Program terminated.")
+	if target != d.Player {
+		d.Send(fmt.Sprintf("Program for %s terminated.", g.PlayerName(target)))
+	}
 }
 
 // HandleProgInput handles input from a player who is in @program mode.
@@ -178,18 +205,14 @@ func (g *Game) HandleProgInput(d *Descriptor, input string) {
 	cmdText := g.GetAttrTextDirect(d.Player, gamedb.A_PROGCMD)
 	if cmdText == "" {
 		// No command stored — clear program state on all descriptors
-		for _, td := range g.Conns.GetByPlayer(d.Player) {
-			td.ProgData = nil
-		}
+		g.clearProgram(d.Player)
 		return
 	}
 
-	// Save and clear program state on ALL of the player's descriptors
+	// Save and clear program state on ALL of the player's descriptors,
+	// so the command may start another @program.
 	progData := d.ProgData
-	for _, td := range g.Conns.GetByPlayer(d.Player) {
-		td.ProgData = nil
-	}
-	g.removeAttr(d.Player, gamedb.A_PROGCMD)
+	g.clearProgram(d.Player)
 
 	// Create a queue entry with input as %0
 	entry := &QueueEntry{
@@ -205,15 +228,22 @@ func (g *Game) HandleProgInput(d *Descriptor, input string) {
 	g.ExecuteQueueEntry(entry)
 }
 
+// isQuitProgram reports whether a programmed player's input line is
+// @quitprogram, which escapes the program rather than feeding it.
+func isQuitProgram(line string) bool {
+	cmd, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+	return strings.EqualFold(cmd, "@quitprogram")
+}
+
 // removeAttr removes an attribute from an object's attribute list.
 func (g *Game) removeAttr(obj gamedb.DBRef, attrNum int) {
 	o, ok := g.DB.Objects[obj]
 	if !ok {
 		return
 	}
-	for i, attr := range o.Attrs {
+	for i, attr := range o.Attributes() {
 		if attr.Number == attrNum {
-			o.Attrs = append(o.Attrs[:i], o.Attrs[i+1:]...)
+			o.SetAttributes(slices.Delete(o.Attributes(), i, i+1))
 			g.PersistObject(o)
 			return
 		}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// Public pages are a small read-only website served under /pages/ when
+// web_public_pages is on: the WHO list, recent activity on public
+// channels, and a page for each VISUAL room, grouped into areas by zone.
+// Each page is an html/template; a file of the same name in the text
+// directory's pages/ subdirectory replaces the built-in one.
+//
+// Room descriptions are shown as written, not evaluated: anyone on the
+// web can load a page, and softcode in a DESC may do anything its room
+// can. Only the layout substitutions (%r, %t, %b) are made.
+
+// publicChannelLines is how much history a channel page shows.
+const publicChannelLines = 50
+
+// publicTemplates are the built-in page templates. "layout" is parsed
+// with every page, so overriding it restyles the whole site.
+var publicTemplates = map[string]string{
+	"layout.html": `{{define "header"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}} - {{.Game}}</title>
+<style>body{font-family:sans-serif;max-width:50em;margin:2em auto;padding:0 1em}
+.desc{white-space:pre-wrap}table{border-collapse:collapse}td,th{padding:.2em 1em;text-align:left}</style>
+</head><body>
+<nav><a href="/pages/">{{.Game}}</a> | <a href="/pages/who">WHO</a></nav>
+<h1>{{.Title}}</h1>
+{{end}}{{define "footer"}}<footer><small>Generated {{.Now.Format "2006-01-02 15:04 MST"}}</small></footer>
+</body></html>
+{{end}}`,
+	"index.html": `{{template "header" .}}
+<p>{{len .Players}} connected.</p>
+{{if .Channels}}<h2>Channels</h2><ul>
+{{range .Channels}}<li><a href="/pages/channels/{{.Name}}">{{.Name}}</a>{{if .Description}} - {{.Description}}{{end}}</li>
+{{end}}</ul>{{end}}
+{{range .Areas}}<h2>{{.Name}}</h2><ul>
+{{range .Rooms}}<li><a href="/pages/rooms/{{.Ref}}">{{.Name}}</a></li>
+{{end}}</ul>{{end}}
+{{template "footer" .}}`,
+	"who.html": `{{template "header" .}}
+{{if .Players}}<table><tr><th>Player</th><th>On For</th><th>Idle</th><th>Doing</th></tr>
+{{range .Players}}<tr><td>{{.Name}}</td><td>{{.OnFor}}</td><td>{{.Idle}}</td><td>{{.Doing}}</td></tr>
+{{end}}</table>{{else}}<p>Nobody is connected.</p>{{end}}
+{{template "footer" .}}`,
+	"channel.html": `{{template "header" .}}
+{{with .Channel}}{{if .Description}}<p>{{.Description}}</p>{{end}}{{end}}
+{{if .Messages}}<table>
+{{range .Messages}}<tr><td><small>{{.Time.Format "15:04"}}</small></td><td>{{.Text}}</td></tr>
+{{end}}</table>{{else}}<p>Nothing has been said here lately.</p>{{end}}
+{{template "footer" .}}`,
+	"room.html": `{{template "header" .}}
+{{with .Room}}{{if .Area}}<p>In {{.Area}}</p>{{end}}
+<div class="desc">{{.Desc}}</div>
+{{if .Exits}}<h2>Exits</h2><ul>
+{{range .Exits}}<li><a href="/pages/rooms/{{.Ref}}">{{.Name}}</a></li>
+{{end}}</ul>{{end}}{{end}}
+{{template "footer" .}}`,
+}
+
+// publicPage is the data every public page template gets.
+type publicPage struct {
+	Game     string
+	Title    string
+	Now      time.Time
+	Players  []publicPlayer
+	Channels []publicChannel
+	Areas    []publicArea
+	Channel  *publicChannel
+	Messages []publicMessage
+	Room     *publicRoom
+}
+
+type publicPlayer struct {
+	Name  string
+	OnFor string
+	Idle  string
+	Doing string
+}
+
+type publicChannel struct {
+	Name        string
+	Description string
+}
+
+type publicMessage struct {
+	Time time.Time
+	Text string
+}
+
+type publicArea struct {
+	Name  string
+	Rooms []publicLink
+}
+
+type publicLink struct {
+	Ref  int
+	Name string
+}
+
+type publicRoom struct {
+	Ref   int
+	Name  string
+	Area  string
+	Desc  string
+	Exits []publicLink
+}
+
+// registerPublicPages adds the /pages/ routes.
+func (ws *WebServer) registerPublicPages() {
+	ws.mux.Handle("GET /pages/{$}", ws.locked(ws.handlePublicIndex))
+	ws.mux.Handle("GET /pages/who", ws.locked(ws.handlePublicWho))
+	ws.mux.Handle("GET /pages/channels/{name}", ws.locked(ws.handlePublicChannel))
+	ws.mux.Handle("GET /pages/rooms/{dbref}", ws.locked(ws.handlePublicRoom))
+}
+
+func (ws *WebServer) handlePublicIndex(w http.ResponseWriter, r *http.Request) {
+	p := ws.publicPage("")
+	p.Title = p.Game
+	p.Players = ws.publicWho()
+	p.Channels = ws.publicChannels()
+	p.Areas = ws.publicAreas()
+	ws.renderPublic(w, "index.html", p)
+}
+
+func (ws *WebServer) handlePublicWho(w http.ResponseWriter, r *http.Request) {
+	p := ws.publicPage("Who's Online")
+	p.Players = ws.publicWho()
+	ws.renderPublic(w, "who.html", p)
+}
+
+func (ws *WebServer) handlePublicChannel(w http.ResponseWriter, r *http.Request) {
+	ch := ws.publicChannel(r.PathValue("name"))
+	if ch == nil {
+		http.NotFound(w, r)
+		return
+	}
+	p := ws.publicPage(ch.Name)
+	p.Channel = &publicChannel{Name: ch.Name, Description: eval.StripAnsi(ch.Description)}
+	for _, msg := range ws.game.Comsys.Recall(ch.Name, publicChannelLines) {
+		p.Messages = append(p.Messages, publicMessage{Time: msg.Time, Text: eval.StripAnsi(msg.Text)})
+	}
+	ws.renderPublic(w, "channel.html", p)
+}
+
+func (ws *WebServer) handlePublicRoom(w http.ResponseWriter, r *http.Request) {
+	ref, err := strconv.Atoi(strings.TrimPrefix(r.PathValue("dbref"), "#"))
+	if err != nil || !ws.publicRoom(gamedb.DBRef(ref)) {
+		http.NotFound(w, r)
+		return
+	}
+	g := ws.game
+	room := gamedb.DBRef(ref)
+	obj := g.DB.Objects[room]
+	info := &publicRoom{Ref: ref, Name: DisplayName(obj.Name), Area: ws.areaName(obj.Zone)}
+	info.Desc = ws.publicDesc(room)
+	for _, exit := range g.DB.SafeExits(room) {
+		exitObj, ok := g.DB.Objects[exit]
+		if !ok || exitObj.HasFlag(gamedb.FlagDark) || !ws.publicRoom(exitObj.Location) {
+			continue
+		}
+		info.Exits = append(info.Exits, publicLink{Ref: int(exitObj.Location), Name: DisplayName(exitObj.Name)})
+	}
+	p := ws.publicPage(info.Name)
+	p.Room = info
+	ws.renderPublic(w, "room.html", p)
+}
+
+// publicDescEntry is a room's rendered DESC and the text it came from.
+type publicDescEntry struct {
+	raw  string
+	text string
+}
+
+// publicDesc returns room's DESC for its public page, rendering it again
+// only when the attribute has changed.
+func (ws *WebServer) publicDesc(room gamedb.DBRef) string {
+	raw := ws.game.GetAttrText(room, 6) // A_DESC
+	if e, ok := ws.roomDescs[room]; ok && e.raw == raw {
+		return e.text
+	}
+	if ws.roomDescs == nil {
+		ws.roomDescs = make(map[gamedb.DBRef]publicDescEntry)
+	}
+	text := eval.StripAnsi(layoutSubst(raw))
+	ws.roomDescs[room] = publicDescEntry{raw: raw, text: text}
+	return text
+}
+
+// layoutSubst makes the %-substitutions that only lay text out (%r, %t,
+// %b and %%) and drops colour codes (%x and %c), leaving everything else,
+// function calls included, as written.
+func layoutSubst(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'r', 'R':
+			b.WriteByte('\n')
+		case 't', 'T':
+			b.WriteByte('\t')
+		case 'b', 'B':
+			b.WriteByte(' ')
+		case '%':
+			b.WriteByte('%')
+		case 'x', 'X', 'c', 'C':
+			if i+1 < len(s) && s[i+1] == '<' {
+				if end := strings.IndexByte(s[i+1:], '>'); end >= 0 {
+					i += end + 1
+					continue
+				}
+			}
+			i++
+		default:
+			b.WriteByte('%')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// publicPage starts a page's data with what every page shows.
+func (ws *WebServer) publicPage(title string) *publicPage {
+	name := "GoTinyMUSH"
+	if ws.game.Conf != nil && ws.game.Conf.MudName != "" {
+		name = ws.game.Conf.MudName
+	}
+	return &publicPage{Game: name, Title: title, Now: time.Now()}
+}
+
+// publicWho lists connected players as WHO shows them to a guest: DARK
+// and UNFINDABLE players are left out.
+func (ws *WebServer) publicWho() []publicPlayer {
+	g := ws.game
+	now := time.Now()
+	var players []publicPlayer
+	seen := make(map[gamedb.DBRef]bool)
+	for _, dd := range g.Conns.AllDescriptors() {
+		if dd.State != ConnConnected || seen[dd.Player] {
+			continue
+		}
+		seen[dd.Player] = true
+		obj, ok := g.DB.Objects[dd.Player]
+		if !ok || obj.HasFlag(gamedb.FlagDark) || obj.HasFlag2(gamedb.Flag2Unfindable) {
+			continue
+		}
+		players = append(players, publicPlayer{
+			Name:  g.PlayerName(dd.Player),
+			OnFor: FormatConnTime(now.Sub(dd.ConnTime)),
+			Idle:  FormatIdleTime(now.Sub(dd.LastCmd)),
+			Doing: eval.StripAnsi(dd.DoingStr),
+		})
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].Name < players[j].Name })
+	return players
+}
+
+// publicChannels lists the channels anyone may join.
+func (ws *WebServer) publicChannels() []publicChannel {
+	if ws.game.Comsys == nil {
+		return nil
+	}
+	var out []publicChannel
+	for _, ch := range ws.game.Comsys.AllChannels() {
+		if ws.publicChannel(ch.Name) != nil {
+			out = append(out, publicChannel{Name: ch.Name, Description: eval.StripAnsi(ch.Description)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// publicChannel returns the named channel if its activity may be shown:
+// it is Public and has no join or receive lock.
+func (ws *WebServer) publicChannel(name string) *gamedb.Channel {
+	if ws.game.Comsys == nil {
+		return nil
+	}
+	ch := ws.game.Comsys.GetChannel(name)
+	if ch == nil || ch.Flags&gamedb.ChanPublic == 0 || ch.JoinLock != "" || ch.RecvLock != "" {
+		return nil
+	}
+	return ch
+}
+
+// publicRoom reports whether ref is a room with a public page: a VISUAL
+// room that isn't being destroyed.
+func (ws *WebServer) publicRoom(ref gamedb.DBRef) bool {
+	obj, ok := ws.game.DB.Objects[ref]
+	return ok && obj.ObjType() == gamedb.TypeRoom && obj.HasFlag(gamedb.FlagVisual) && !obj.IsGoing()
+}
+
+// publicAreas groups the public rooms by zone, named after the zone
+// object. Unzoned rooms come last, under "Elsewhere".
+func (ws *WebServer) publicAreas() []publicArea {
+	byZone := make(map[gamedb.DBRef][]publicLink)
+	for ref, obj := range ws.game.DB.Objects {
+		if ws.publicRoom(ref) {
+			byZone[obj.Zone] = append(byZone[obj.Zone], publicLink{Ref: int(ref), Name: DisplayName(obj.Name)})
+		}
+	}
+	var areas []publicArea
+	var elsewhere []publicLink
+	for zone, rooms := range byZone {
+		sort.Slice(rooms, func(i, j int) bool { return rooms[i].Name < rooms[j].Name })
+		name := ws.areaName(zone)
+		if name == "" {
+			elsewhere = rooms
+			continue
+		}
+		areas = append(areas, publicArea{Name: name, Rooms: rooms})
+	}
+	sort.Slice(areas, func(i, j int) bool { return areas[i].Name < areas[j].Name })
+	if len(elsewhere) > 0 {
+		areas = append(areas, publicArea{Name: "Elsewhere", Rooms: elsewhere})
+	}
+	return areas
+}
+
+// areaName is the name of the area a zone makes, or "" for no zone.
+func (ws *WebServer) areaName(zone gamedb.DBRef) string {
+	if obj, ok := ws.game.DB.Objects[zone]; ok && zone != gamedb.Nothing {
+		return DisplayName(obj.Name)
+	}
+	return ""
+}
+
+// renderPublic executes a public page template, preferring the text
+// directory's copy of the page and layout over the built-in ones.
+func (ws *WebServer) renderPublic(w http.ResponseWriter, page string, p *publicPage) {
+	tmpl := template.New("public")
+	for _, name := range []string{"layout.html", page} {
+		text := publicTemplates[name]
+		if ws.game.TextDir != "" {
+			if data, err := os.ReadFile(filepath.Join(ws.game.TextDir, "pages", name)); err == nil {
+				text = string(data)
+			}
+		}
+		if _, err := tmpl.New(name).Parse(text); err != nil {
+			log.Printf("web: public page template %s: %v", name, err)
+			http.Error(w, "page template error", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.ExecuteTemplate(w, page, p); err != nil {
+		log.Printf("web: public page %s: %v", page, err)
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// puppetHeard relays what a PUPPET object hears or sees to its owner as
+// "<name>> <message>". As in TinyMUSH, an owner in the same room as the
+// puppet, or carrying it, already hears the original and gets no copy
+// unless puppet_same_room is set; always is for the puppet's own command
+// output, which the owner hears wherever they are. A puppet already
+// relaying is skipped, so a relay that makes it hear something can't loop.
+func (g *Game) puppetHeard(obj gamedb.DBRef, message string, always bool) {
+	o, ok := g.DB.Objects[obj]
+	if !ok || !o.HasFlag(gamedb.FlagPuppet) || o.ObjType() == gamedb.TypePlayer || o.IsGoing() {
+		return
+	}
+	owner := o.Owner
+	if owner == obj || g.puppeting[obj] {
+		return
+	}
+	sameRoom := g.Conf != nil && g.Conf.PuppetSameRoom
+	if !always && !sameRoom && (o.Location == owner || o.Location == g.PlayerLocation(owner)) {
+		return
+	}
+	if g.puppeting == nil {
+		g.puppeting = make(map[gamedb.DBRef]bool)
+	}
+	g.puppeting[obj] = true
+	defer delete(g.puppeting, obj)
+	g.Conns.SendToPlayer(owner, fmt.Sprintf("%s> %s", DisplayName(o.Name), message))
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -20,6 +21,8 @@ type QueueEntry struct {
 	WaitUntil time.Time    // When to execute (zero = immediate)
 	SemObj  gamedb.DBRef   // Semaphore object (Nothing = none)
 	SemAttr int            // Semaphore attribute number
+	PID     int            // Queue process ID, assigned when first queued
+	Deposit int            // wait_cost paid to queue it, refunded when it runs or is halted
 }
 
 // CommandQueue manages queued commands for execution.
@@ -29,17 +32,91 @@ type CommandQueue struct {
 	waitQueue []*QueueEntry // Delayed execution
 	semQueue  []*QueueEntry // Waiting on semaphores
 	maxPerObj int           // Max queued commands per owner
+	nextPID   int           // Next PID to hand out
+
+	// Per-owner quotas, set up by the game. OwnerOf maps a queued object to
+	// the player charged for it, Quota gives that player's limit (0 = none),
+	// and Runaway is called, without the queue locked, for an object that
+	// tried to queue past its owner's quota. The entry is dropped.
+	OwnerOf func(obj gamedb.DBRef) gamedb.DBRef
+	Quota   func(owner gamedb.DBRef) int
+	Runaway func(obj gamedb.DBRef)
+
+	// Queue costs, set up by the game. Charge takes the deposit for a
+	// newly queued object's command, returning how much was paid or -1 to
+	// refuse it, and is called without the queue locked. Refund gives a
+	// deposit back once its entry has run or been removed; it mustn't
+	// touch the queue.
+	Charge func(obj gamedb.DBRef) int
+	Refund func(obj gamedb.DBRef, amount int)
 }
 
 // NewCommandQueue creates a new command queue.
 func NewCommandQueue() *CommandQueue {
 	return &CommandQueue{
 		maxPerObj: 1000,
+		nextPID:   1,
+	}
+}
+
+// stamp assigns a PID to an entry that doesn't have one yet. Caller holds q.mu.
+func (q *CommandQueue) stamp(entry *QueueEntry) {
+	if entry.PID == 0 {
+		entry.PID = q.nextPID
+		q.nextPID++
+	}
+}
+
+// admit checks entry against its owner's quota, reporting a runaway if
+// the owner is already at the limit, and takes its deposit.
+func (q *CommandQueue) admit(entry *QueueEntry) bool {
+	if !q.admitQuota(entry) {
+		return false
+	}
+	if q.Charge != nil && entry.PID == 0 && entry.Deposit == 0 {
+		paid := q.Charge(entry.Player)
+		if paid < 0 {
+			return false
+		}
+		entry.Deposit = paid
+	}
+	return true
+}
+
+// admitQuota checks entry against its owner's quota.
+func (q *CommandQueue) admitQuota(entry *QueueEntry) bool {
+	if q.OwnerOf == nil || q.Quota == nil {
+		return true
+	}
+	owner := q.OwnerOf(entry.Player)
+	limit := q.Quota(owner)
+	if limit <= 0 {
+		return true
+	}
+	q.mu.Lock()
+	count := 0
+	for _, list := range [][]*QueueEntry{q.immediate, q.waitQueue, q.semQueue} {
+		for _, e := range list {
+			if q.OwnerOf(e.Player) == owner {
+				count++
+			}
+		}
+	}
+	q.mu.Unlock()
+	if count < limit {
+		return true
+	}
+	if q.Runaway != nil {
+		q.Runaway(entry.Player)
 	}
+	return false
 }
 
 // Add queues a command for immediate execution.
 func (q *CommandQueue) Add(entry *QueueEntry) {
+	if !q.admit(entry) {
+		return
+	}
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	// Enforce per-object queue limit to prevent runaway objects
@@ -52,17 +129,28 @@ func (q *CommandQueue) Add(entry *QueueEntry) {
 		}
 		if count >= q.maxPerObj {
 			log.Printf("QUEUE: dropping entry for #%d — per-object limit (%d) reached", entry.Player, q.maxPerObj)
+			q.refund(entry)
 			return
 		}
 	}
+	q.stamp(entry)
 	q.immediate = append(q.immediate, entry)
 }
 
 // AddWait queues a command for delayed execution.
 func (q *CommandQueue) AddWait(entry *QueueEntry) {
+	if !q.admit(entry) {
+		return
+	}
 	q.mu.Lock()
 	defer q.mu.Unlock()
-	// Insert sorted by WaitUntil
+	q.stamp(entry)
+	q.insertWait(entry)
+}
+
+// insertWait adds entry to the wait queue, sorted by WaitUntil. The caller
+// holds q.mu.
+func (q *CommandQueue) insertWait(entry *QueueEntry) {
 	inserted := false
 	for i, e := range q.waitQueue {
 		if entry.WaitUntil.Before(e.WaitUntil) {
@@ -79,11 +167,52 @@ func (q *CommandQueue) AddWait(entry *QueueEntry) {
 
 // AddSemaphore queues a command waiting on a semaphore.
 func (q *CommandQueue) AddSemaphore(entry *QueueEntry) {
+	if !q.admit(entry) {
+		return
+	}
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	q.stamp(entry)
 	q.semQueue = append(q.semQueue, entry)
 }
 
+// Restore puts back a wait or semaphore entry saved before a restart: a
+// semaphore wait if SemObj is set, otherwise a plain @wait. It is neither
+// charged again nor counted against the owner's quota; both were settled
+// when it was first queued.
+func (q *CommandQueue) Restore(entry *QueueEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stamp(entry)
+	if entry.SemObj != gamedb.Nothing {
+		q.semQueue = append(q.semQueue, entry)
+	} else {
+		q.insertWait(entry)
+	}
+}
+
+// Waiting returns copies of the wait and semaphore queues, the entries
+// worth saving across a restart.
+func (q *CommandQueue) Waiting() (waits, sems []*QueueEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*QueueEntry(nil), q.waitQueue...), append([]*QueueEntry(nil), q.semQueue...)
+}
+
+// Settle gives back an entry's deposit once it has run, or been dropped
+// outside the queue.
+func (q *CommandQueue) Settle(entry *QueueEntry) {
+	q.refund(entry)
+}
+
+// refund returns entry's deposit, at most once.
+func (q *CommandQueue) refund(entry *QueueEntry) {
+	if entry.Deposit > 0 && q.Refund != nil {
+		q.Refund(entry.Player, entry.Deposit)
+	}
+	entry.Deposit = 0
+}
+
 // NotifySemaphore wakes up commands waiting on a semaphore.
 // Returns the number of commands woken.
 func (q *CommandQueue) NotifySemaphore(obj gamedb.DBRef, attr int, count int) int {
@@ -94,6 +223,7 @@ func (q *CommandQueue) NotifySemaphore(obj gamedb.DBRef, attr int, count int) in
 	var remaining []*QueueEntry
 	for _, e := range q.semQueue {
 		if e.SemObj == obj && e.SemAttr == attr && woken < count {
+			e.SemObj, e.SemAttr, e.WaitUntil = gamedb.Nothing, 0, time.Time{}
 			q.immediate = append(q.immediate, e)
 			woken++
 		} else {
@@ -104,6 +234,36 @@ func (q *CommandQueue) NotifySemaphore(obj gamedb.DBRef, attr int, count int) in
 	return woken
 }
 
+// ExpireSemaphores removes and returns the semaphore waits whose timeout
+// has passed. The caller gives back their semaphore counts and queues them.
+func (q *CommandQueue) ExpireSemaphores(now time.Time) []*QueueEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var expired, remaining []*QueueEntry
+	for _, e := range q.semQueue {
+		if !e.WaitUntil.IsZero() && !e.WaitUntil.After(now) {
+			expired = append(expired, e)
+		} else {
+			remaining = append(remaining, e)
+		}
+	}
+	q.semQueue = remaining
+	return expired
+}
+
+// SemaphoreWaiters returns the number of commands waiting on a semaphore.
+func (q *CommandQueue) SemaphoreWaiters(obj gamedb.DBRef, attr int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, e := range q.semQueue {
+		if e.SemObj == obj && e.SemAttr == attr {
+			n++
+		}
+	}
+	return n
+}
+
 // DrainSemaphore removes all commands waiting on a semaphore.
 func (q *CommandQueue) DrainSemaphore(obj gamedb.DBRef, attr int) int {
 	q.mu.Lock()
@@ -113,6 +273,7 @@ func (q *CommandQueue) DrainSemaphore(obj gamedb.DBRef, attr int) int {
 	var remaining []*QueueEntry
 	for _, e := range q.semQueue {
 		if e.SemObj == obj && e.SemAttr == attr {
+			q.refund(e)
 			removed++
 		} else {
 			remaining = append(remaining, e)
@@ -133,6 +294,7 @@ func (q *CommandQueue) DrainObject(obj gamedb.DBRef, semAttr int) int {
 	var remSem []*QueueEntry
 	for _, e := range q.semQueue {
 		if e.SemObj == obj && (semAttr <= 0 || e.SemAttr == semAttr) {
+			q.refund(e)
 			removed++
 		} else {
 			remSem = append(remSem, e)
@@ -144,6 +306,7 @@ func (q *CommandQueue) DrainObject(obj gamedb.DBRef, semAttr int) int {
 	var remWait []*QueueEntry
 	for _, e := range q.waitQueue {
 		if e.Player == obj {
+			q.refund(e)
 			removed++
 		} else {
 			remWait = append(remWait, e)
@@ -154,13 +317,12 @@ func (q *CommandQueue) DrainObject(obj gamedb.DBRef, semAttr int) int {
 	return removed
 }
 
-// PromoteReady moves entries from the wait queue whose time has come.
-// Returns the number of entries promoted.
-func (q *CommandQueue) PromoteReady() int {
+// PromoteReady moves entries from the wait queue whose time has come by
+// now. Returns the number of entries promoted.
+func (q *CommandQueue) PromoteReady(now time.Time) int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	now := time.Now()
 	cutoff := 0
 	for i, e := range q.waitQueue {
 		if e.WaitUntil.After(now) {
@@ -169,6 +331,9 @@ func (q *CommandQueue) PromoteReady() int {
 		cutoff = i + 1
 	}
 	if cutoff > 0 {
+		for _, e := range q.waitQueue[:cutoff] {
+			e.WaitUntil = time.Time{}
+		}
 		q.immediate = append(q.immediate, q.waitQueue[:cutoff]...)
 		q.waitQueue = q.waitQueue[cutoff:]
 	}
@@ -187,6 +352,61 @@ func (q *CommandQueue) PopImmediate() *QueueEntry {
 	return entry
 }
 
+// PopFair removes up to max immediate entries and returns them in the order
+// they should run. Owners take turns: each pass takes up to chunk entries
+// from every owner with work waiting, so one owner's flood of @triggers
+// can't hold up everyone else. Within an owner, entries keep their order.
+// A chunk of 0 or less takes entries strictly first-come, first-served.
+func (q *CommandQueue) PopFair(max, chunk int, ownerOf func(gamedb.DBRef) gamedb.DBRef) []*QueueEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if max > len(q.immediate) {
+		max = len(q.immediate)
+	}
+	if chunk <= 0 || ownerOf == nil {
+		batch := append([]*QueueEntry(nil), q.immediate[:max]...)
+		q.immediate = q.immediate[max:]
+		return batch
+	}
+
+	var owners []gamedb.DBRef
+	byOwner := make(map[gamedb.DBRef][]int)
+	for i, e := range q.immediate {
+		owner := ownerOf(e.Player)
+		if _, ok := byOwner[owner]; !ok {
+			owners = append(owners, owner)
+		}
+		byOwner[owner] = append(byOwner[owner], i)
+	}
+	taken := make(map[int]bool, max)
+	var batch []*QueueEntry
+	for len(batch) < max {
+		for _, owner := range owners {
+			idx := byOwner[owner]
+			n := chunk
+			if n > len(idx) {
+				n = len(idx)
+			}
+			if n > max-len(batch) {
+				n = max - len(batch)
+			}
+			for _, i := range idx[:n] {
+				taken[i] = true
+				batch = append(batch, q.immediate[i])
+			}
+			byOwner[owner] = idx[n:]
+		}
+	}
+	var rest []*QueueEntry
+	for i, e := range q.immediate {
+		if !taken[i] {
+			rest = append(rest, e)
+		}
+	}
+	q.immediate = rest
+	return batch
+}
+
 // HaltPlayer removes all queued commands for a player/object.
 func (q *CommandQueue) HaltPlayer(player gamedb.DBRef) int {
 	q.mu.Lock()
@@ -197,6 +417,7 @@ func (q *CommandQueue) HaltPlayer(player gamedb.DBRef) int {
 		var result []*QueueEntry
 		for _, e := range entries {
 			if e.Player == player {
+				q.refund(e)
 				removed++
 			} else {
 				result = append(result, e)
@@ -215,6 +436,11 @@ func (q *CommandQueue) HaltAll() int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	removed := len(q.immediate) + len(q.waitQueue) + len(q.semQueue)
+	for _, list := range [][]*QueueEntry{q.immediate, q.waitQueue, q.semQueue} {
+		for _, e := range list {
+			q.refund(e)
+		}
+	}
 	q.immediate = nil
 	q.waitQueue = nil
 	q.semQueue = nil
@@ -281,3 +507,20 @@ func (q *CommandQueue) Peek(n int) []*QueueEntry {
 	}
 	return result
 }
+
+// Matching returns the entries in all queues for which match returns true,
+// in PID order.
+func (q *CommandQueue) Matching(match func(*QueueEntry) bool) []*QueueEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var result []*QueueEntry
+	for _, list := range [][]*QueueEntry{q.immediate, q.waitQueue, q.semQueue} {
+		for _, e := range list {
+			if match(e) {
+				result = append(result, e)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PID < result[j].PID })
+	return result
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// The wait and semaphore queues are saved to bolt at shutdown and @dump
+// and put back at boot, so long-running @wait timers and semaphore waits
+// survive a restart. The immediate queue is not saved: it is run dry
+// every tick.
+
+// SaveQueue writes the wait and semaphore queues to bolt, replacing what
+// was saved before. It does nothing without a bolt store.
+func (g *Game) SaveQueue() {
+	if g.Store == nil || g.Queue == nil {
+		return
+	}
+	waits, sems := g.Queue.Waiting()
+	saved := make([]gamedb.QueuedCommand, 0, len(waits)+len(sems))
+	for _, e := range waits {
+		qc := savedEntry(e)
+		qc.SemObj = gamedb.Nothing
+		saved = append(saved, qc)
+	}
+	for _, e := range sems {
+		saved = append(saved, savedEntry(e))
+	}
+	if err := g.Store.SaveQueue(saved); err != nil {
+		Logf(LogBugs, LevelError, "saving the command queue: %v", err)
+	}
+}
+
+func savedEntry(e *QueueEntry) gamedb.QueuedCommand {
+	qc := gamedb.QueuedCommand{
+		Player:    e.Player,
+		Cause:     e.Cause,
+		Caller:    e.Caller,
+		Command:   e.Command,
+		Args:      e.Args,
+		WaitUntil: e.WaitUntil,
+		SemObj:    e.SemObj,
+		SemAttr:   e.SemAttr,
+		Deposit:   e.Deposit,
+	}
+	if e.RData != nil {
+		qc.QRegs = append([]string(nil), e.RData.QRegs[:]...)
+		qc.XRegs = e.RData.XRegs
+	}
+	return qc
+}
+
+// RestoreQueue puts back queue entries saved by SaveQueue. Entries for
+// objects, or semaphores on objects, destroyed since are dropped. It
+// returns how many were restored.
+func (g *Game) RestoreQueue(saved []gamedb.QueuedCommand) int {
+	restored := 0
+	for _, qc := range saved {
+		if !g.queueLive(qc.Player) || (qc.SemObj != gamedb.Nothing && !g.queueLive(qc.SemObj)) {
+			Logf(LogBugs, LevelInfo, "dropped saved queue entry for destroyed #%d: %s", qc.Player, truncDebug(qc.Command, 80))
+			continue
+		}
+		rdata := eval.NewRegisterData()
+		copy(rdata.QRegs[:], qc.QRegs)
+		for k, v := range qc.XRegs {
+			rdata.XRegs[k] = v
+		}
+		g.Queue.Restore(&QueueEntry{
+			Player:    qc.Player,
+			Cause:     qc.Cause,
+			Caller:    qc.Caller,
+			Command:   qc.Command,
+			Args:      qc.Args,
+			RData:     rdata,
+			WaitUntil: qc.WaitUntil,
+			SemObj:    qc.SemObj,
+			SemAttr:   qc.SemAttr,
+			Deposit:   qc.Deposit,
+		})
+		restored++
+	}
+	return restored
+}
+
+// queueLive reports whether ref is an object a restored entry may run on.
+func (g *Game) queueLive(ref gamedb.DBRef) bool {
+	obj, ok := g.DB.Objects[ref]
+	return ok && !obj.IsGoing()
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+	"gopkg.in/yaml.v3"
+)
+
+// bootOnlyConf lists settings that are only read at startup. A reload
+// reports changes to them as skipped and keeps the running value.
+var bootOnlyConf = map[string]bool{
+	"port": true, "cleartext": true, "tls": true, "tls_port": true, "tls_cert": true, "tls_key": true,
+	"idle_timeout": true, "god_dbref": true,
+	"web_enabled": true, "web_port": true, "web_host": true, "web_domain": true, "web_static_dir": true,
+	"web_client_url": true, "web_cors_origins": true, "web_rate_limit": true, "web_public_pages": true,
+	"jwt_secret": true, "jwt_expiry": true, "cert_dir": true, "scrollback_retention": true,
+	"irc_server": true, "irc_tls": true, "irc_nick": true, "irc_password": true, "irc_channels": true,
+	"discord_token": true, "discord_channels": true, "discord_inbound": true, "discord_alerts": true, "discord_poll": true,
+	"mail_enabled": true, "comsys_enabled": true, "mail_expiration": true,
+	"spellcheck_enabled": true, "spellcheck_url": true,
+	"sql_enabled": true, "sql_database": true,
+	"archive_dir": true, "archive_interval": true, "write_behind": true, "lazy_load": true, "lazy_idle_days": true, "lazy_cache": true,
+	"scene_key": true, "alias_files": true,
+	"replication_listen": true, "replication_secret": true, "replication_tls": true, "replication_ca": true,
+}
+
+// secretConf lists settings whose values are never echoed back.
+var secretConf = map[string]bool{
+	"jwt_secret": true, "scene_key": true, "smtp_password": true, "guest_password": true, "irc_password": true,
+	"discord_token": true, "discord_channels": true, "discord_alerts": true,
+	"archive_passphrase": true, "archive_s3_secret_key": true, "archive_sftp_password": true,
+	"replication_secret": true,
+}
+
+// roomConf lists settings that must name an existing room.
+var roomConf = map[string]bool{
+	"master_room": true, "player_starting_room": true, "player_starting_home": true,
+	"default_home": true, "guest_start_room": true,
+}
+
+// ConfChange describes one directive seen by @readconf.
+type ConfChange struct {
+	Key    string `json:"key"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ConfReport is the outcome of re-reading the game config.
+type ConfReport struct {
+	Path    string       `json:"path"`
+	Applied []ConfChange `json:"applied"`
+	Skipped []ConfChange `json:"skipped"`
+	Invalid []ConfChange `json:"invalid"`
+}
+
+// confValue renders a config field for a report.
+func confValue(key string, v reflect.Value) string {
+	if secretConf[key] {
+		return "(hidden)"
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "(default)"
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// loadConfForReload parses the config file like LoadGameConf, but for YAML
+// it decodes each key on its own, so unknown keys and badly typed values are
+// returned as invalid directives (keeping old's value) instead of failing
+// the whole file.
+func loadConfForReload(path string, old *GameConf) (*GameConf, []ConfChange, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".yaml" && ext != ".yml" {
+		gc, err := LoadGameConf(path)
+		return gc, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("parsing YAML %s: %w", path, err)
+	}
+
+	var invalid []ConfChange
+	gc := DefaultGameConf()
+	gv, ov := reflect.ValueOf(gc).Elem(), reflect.ValueOf(old).Elem()
+	t := gv.Type()
+	seen := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		key := confKey(t.Field(i))
+		node, ok := raw[key]
+		if !ok {
+			continue
+		}
+		seen[key] = true
+		val := reflect.New(t.Field(i).Type)
+		if err := node.Decode(val.Interface()); err != nil {
+			invalid = append(invalid, ConfChange{Key: key, Reason: strings.TrimPrefix(err.Error(), "yaml: unmarshal errors:\n  ")})
+			gv.Field(i).Set(ov.Field(i))
+			continue
+		}
+		gv.Field(i).Set(val.Elem())
+	}
+	for key := range raw {
+		if !seen[key] {
+			invalid = append(invalid, ConfChange{Key: key, Reason: "unknown directive"})
+		}
+	}
+
+	baseDir := filepath.Dir(path)
+	for i, af := range gc.AliasFiles {
+		if !filepath.IsAbs(af) {
+			gc.AliasFiles[i] = filepath.Join(baseDir, af)
+		}
+	}
+	gc.IncludedAliasConfs = gc.AliasFiles
+	return gc, invalid, nil
+}
+
+// confKey returns the config directive name for a GameConf field, or "" for
+// internal fields.
+func confKey(f reflect.StructField) string {
+	key := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	if key == "-" {
+		return ""
+	}
+	return key
+}
+
+// checkConfValue returns why a changed setting can't be applied, or "".
+func (g *Game) checkConfValue(key string, v reflect.Value) string {
+	if roomConf[key] && v.Kind() == reflect.Int {
+		ref := gamedb.DBRef(v.Int())
+		obj, ok := g.DB.Objects[ref]
+		if !ok || obj.IsGoing() || obj.ObjType() != gamedb.TypeRoom {
+			return fmt.Sprintf("#%d is not a room", ref)
+		}
+	}
+	return ""
+}
+
+// ReadConf re-parses the game config file and applies every changed setting
+// that can take effect while running. Boot-only settings and invalid values
+// keep their current values; the report says which was which.
+func (g *Game) ReadConf() (*ConfReport, error) {
+	if g.ConfPath == "" {
+		return nil, errors.New("no config file is configured")
+	}
+	old := g.Conf
+	if old == nil {
+		old = DefaultGameConf()
+	}
+	gc, invalid, err := loadConfForReload(g.ConfPath, old)
+	if err != nil {
+		return nil, err
+	}
+	report := &ConfReport{Path: g.ConfPath, Invalid: invalid}
+
+	merged := *old
+	ov, nv, mv := reflect.ValueOf(old).Elem(), reflect.ValueOf(gc).Elem(), reflect.ValueOf(&merged).Elem()
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := confKey(t.Field(i))
+		if key == "" || reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		change := ConfChange{Key: key, Old: confValue(key, ov.Field(i)), New: confValue(key, nv.Field(i))}
+		switch {
+		case bootOnlyConf[key]:
+			change.Reason = "takes effect at restart"
+			report.Skipped = append(report.Skipped, change)
+		case g.checkConfValue(key, nv.Field(i)) != "":
+			change.Reason = g.checkConfValue(key, nv.Field(i))
+			report.Invalid = append(report.Invalid, change)
+		default:
+			mv.Field(i).Set(nv.Field(i))
+			report.Applied = append(report.Applied, change)
+		}
+	}
+	merged.IncludedAliasConfs = gc.IncludedAliasConfs
+	g.applyAdminSettings(&merged)
+	g.ApplyGameConf(&merged)
+	g.adminUnsaved = nil
+
+	for _, list := range [][]ConfChange{report.Applied, report.Skipped, report.Invalid} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	}
+	log.Printf("readconf: %s: %d applied, %d skipped, %d invalid",
+		g.ConfPath, len(report.Applied), len(report.Skipped), len(report.Invalid))
+	return report, nil
+}
+
+// cmdReadconf implements @readconf: re-read the game config file.
+func cmdReadconf(g *Game, d *Descriptor, _ string, _ []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	report, err := g.ReadConf()
+	if err != nil {
+		d.Send(fmt.Sprintf("Config not reloaded: %v", err))
+		return
+	}
+	d.Send(fmt.Sprintf("Reading %s...", report.Path))
+	for _, c := range report.Applied {
+		d.Send(fmt.Sprintf("  Applied: %s = %s (was %s)", c.Key, c.New, c.Old))
+	}
+	for _, c := range report.Skipped {
+		d.Send(fmt.Sprintf("  Skipped: %s = %s (%s)", c.Key, c.New, c.Reason))
+	}
+	for _, c := range report.Invalid {
+		d.Send(fmt.Sprintf("  Invalid: %s: %s", c.Key, c.Reason))
+	}
+	d.Send(fmt.Sprintf("%d applied, %d skipped, %d invalid.",
+		len(report.Applied), len(report.Skipped), len(report.Invalid)))
+}
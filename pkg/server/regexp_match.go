@@ -0,0 +1,88 @@
+package server
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// regexpCacheMax bounds the compiled-pattern cache. When it fills, it
+// is emptied and refilled from the patterns still in use.
+const regexpCacheMax = 1024
+
+// regexpCache holds compiled $-command and ^-listen patterns, keyed on
+// the pattern text and case sensitivity. Patterns that fail to compile
+// are cached as nil so they aren't retried on every command.
+var regexpCache = struct {
+	sync.Mutex
+	m map[regexpKey]*regexp.Regexp
+}{m: make(map[regexpKey]*regexp.Regexp)}
+
+type regexpKey struct {
+	pattern string
+	exact   bool // AF_CASE: match case-sensitively
+}
+
+// compileAttrRegexp returns the compiled form of pattern, or nil if it
+// isn't a valid regular expression.
+func compileAttrRegexp(pattern string, exact bool) *regexp.Regexp {
+	key := regexpKey{pattern, exact}
+	regexpCache.Lock()
+	defer regexpCache.Unlock()
+	if re, ok := regexpCache.m[key]; ok {
+		return re
+	}
+	expr := pattern
+	if !exact {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		DebugLog("REGEXP bad pattern %q: %v", pattern, err)
+		re = nil
+	}
+	if len(regexpCache.m) >= regexpCacheMax {
+		regexpCache.m = make(map[regexpKey]*regexp.Regexp)
+	}
+	regexpCache.m[key] = re
+	return re
+}
+
+// matchRegexp matches str against a regular expression pattern, as an
+// AF_REGEXP $-command or ^-listen does. Like TinyMUSH, the pattern isn't
+// anchored, %0 is the whole match and %1-%9 are its groups.
+func matchRegexp(pattern, str string, exact bool) (bool, []string) {
+	re := compileAttrRegexp(pattern, exact)
+	if re == nil {
+		return false, nil
+	}
+	m := re.FindStringSubmatch(str)
+	if m == nil {
+		return false, nil
+	}
+	if len(m) > 10 {
+		m = m[:10]
+	}
+	return true, m
+}
+
+// attrMatchFlags returns the flags governing how attr's pattern
+// matches: those set on the attribute itself plus those of its
+// definition.
+func (g *Game) attrMatchFlags(attr gamedb.Attribute) int {
+	flags := parseAttrFlags(attr.Value)
+	if def, ok := g.DB.AttrNames[attr.Number]; ok {
+		flags |= def.Flags
+	}
+	return flags
+}
+
+// matchAttrPattern matches str against a $-command or ^-listen pattern,
+// as a regexp if the attribute is AF_REGEXP and as a wildcard otherwise.
+func matchAttrPattern(pattern, str string, flags int) (bool, []string) {
+	if flags&AFRegexp != 0 {
+		return matchRegexp(pattern, str, flags&gamedb.AFCase != 0)
+	}
+	return matchWild(pattern, str)
+}
@@ -0,0 +1,358 @@
+package server
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mushcrypt "github.com/crystal-mush/gotinymush/pkg/crypt"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// maxPendingRegistrations bounds the queue so the login screen can't be
+// used to fill the database.
+const maxPendingRegistrations = 200
+
+// maxPendingPerSite is how many requests one address may have waiting,
+// so a single site can't fill the queue for everyone else.
+const maxPendingPerSite = 3
+
+// RegistrationQueue holds character requests awaiting wizard approval.
+type RegistrationQueue struct {
+	mu      sync.Mutex
+	pending map[int]*gamedb.Registration
+	nextID  int
+}
+
+// NewRegistrationQueue creates an empty queue.
+func NewRegistrationQueue() *RegistrationQueue {
+	return &RegistrationQueue{
+		pending: make(map[int]*gamedb.Registration),
+		nextID:  1,
+	}
+}
+
+// LoadRegistrations installs registrations read from the database.
+func (q *RegistrationQueue) LoadRegistrations(regs []gamedb.Registration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range regs {
+		reg := regs[i]
+		q.pending[reg.ID] = &reg
+		if reg.ID >= q.nextID {
+			q.nextID = reg.ID + 1
+		}
+	}
+}
+
+// Add queues a request, assigning its ID.
+func (q *RegistrationQueue) Add(reg gamedb.Registration) (*gamedb.Registration, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= maxPendingRegistrations {
+		return nil, errors.New("The registration queue is full. Please try again later.")
+	}
+	site, fromSite := hostAddr(reg.Addr), 0
+	for _, r := range q.pending {
+		if strings.EqualFold(r.Name, reg.Name) {
+			return nil, errors.New("That name already has a registration pending.")
+		}
+		if hostAddr(r.Addr) == site {
+			fromSite++
+		}
+	}
+	if fromSite >= maxPendingPerSite {
+		return nil, errors.New("Your site already has registrations waiting. Please wait until they are reviewed.")
+	}
+	reg.ID = q.nextID
+	q.nextID++
+	q.pending[reg.ID] = &reg
+	return &reg, nil
+}
+
+// Get returns a pending request by ID, or nil.
+func (q *RegistrationQueue) Get(id int) *gamedb.Registration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending[id]
+}
+
+// Remove drops a request from the queue.
+func (q *RegistrationQueue) Remove(id int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.pending[id]; !ok {
+		return false
+	}
+	delete(q.pending, id)
+	return true
+}
+
+// List returns pending requests, oldest first.
+func (q *RegistrationQueue) List() []gamedb.Registration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]gamedb.Registration, 0, len(q.pending))
+	for _, r := range q.pending {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// RegistrationRequired returns true if "create" is disabled at the login
+// screen in favor of "register".
+func (g *Game) RegistrationRequired() bool {
+	return g.Conf != nil && g.Conf.RegistrationRequired
+}
+
+// checkNewPlayerName returns an error describing why name can't be used for
+// a new character, or nil if it is acceptable.
+func (g *Game) checkNewPlayerName(name string) error {
+	if LookupPlayer(g.DB, name) != gamedb.Nothing {
+		return errors.New("That name is already taken.")
+	}
+	if len(name) < 2 {
+		return errors.New("That name is too short.")
+	}
+	if strings.ContainsAny(name, "\";") {
+		return errors.New("That name contains illegal characters.")
+	}
+	if g.IsBadName(name) {
+		return errors.New("That name is not allowed.")
+	}
+	return nil
+}
+
+// CreatePlayer creates a new player in the starting room with the given
+// password. The error text is suitable to show the requester.
+func (g *Game) CreatePlayer(name, password string) (gamedb.DBRef, error) {
+	if err := g.checkNewPlayerName(name); err != nil {
+		return gamedb.Nothing, err
+	}
+
+	ref := g.CreateObject(name, gamedb.TypePlayer, gamedb.Nothing)
+	playerObj := g.DB.Objects[ref]
+	playerObj.Owner = ref
+
+	g.SetAttr(ref, aPass, mushcrypt.Crypt(password, "XX"))
+
+	// Set start room and home from config
+	startRoom := g.StartingRoom()
+	playerObj.Location = startRoom
+	playerObj.Link = g.StartingHome()
+
+	g.AddToContents(startRoom, ref)
+	if roomObj, ok := g.DB.Objects[startRoom]; ok {
+		g.PersistObjects(playerObj, roomObj)
+	}
+	if g.Store != nil {
+		g.Store.PutMeta()
+		g.Store.UpdatePlayerIndex(playerObj, "")
+	}
+	return ref, nil
+}
+
+// generatePassword returns a random password for approved registrations.
+// The alphabet leaves out characters that are easy to misread.
+func generatePassword() string {
+	const alphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	size := big.NewInt(int64(len(alphabet)))
+	b := make([]byte, 10)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			panic("registration: no randomness: " + err.Error())
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b)
+}
+
+// RequestRegistration queues a character request from the login screen.
+func (g *Game) RequestRegistration(name, email, addr string) (*gamedb.Registration, error) {
+	if err := g.checkNewPlayerName(name); err != nil {
+		return nil, err
+	}
+	parsed, err := mail.ParseAddress(email)
+	if err != nil {
+		return nil, errors.New("That doesn't look like an email address.")
+	}
+	reg, err := g.Registrations.Add(gamedb.Registration{
+		Name:      name,
+		Email:     parsed.Address,
+		Addr:      addr,
+		Requested: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if g.Store != nil {
+		if err := g.Store.PutRegistration(reg); err != nil {
+			Logf(LogBugs, LevelError, "persist registration %d: %v", reg.ID, err)
+		}
+	}
+	log.Printf("registration: request %d for %q <%s> from %s", reg.ID, name, reg.Email, addr)
+	g.NotifyWizards(fmt.Sprintf("[Registration] Request %d: %s <%s> from %s. See @pcreate/list.",
+		reg.ID, name, reg.Email, addr))
+	return reg, nil
+}
+
+// ApproveRegistration creates the requested character with a generated
+// password. If email is configured the password is mailed to the requester
+// and mailed is true; otherwise the caller must pass it on.
+func (g *Game) ApproveRegistration(id int) (ref gamedb.DBRef, password string, mailed bool, err error) {
+	reg := g.Registrations.Get(id)
+	if reg == nil {
+		return gamedb.Nothing, "", false, errors.New("No such registration.")
+	}
+	password = generatePassword()
+	ref, err = g.CreatePlayer(reg.Name, password)
+	if err != nil {
+		return gamedb.Nothing, "", false, err
+	}
+	g.dropRegistration(id)
+	Logf(LogWizard, LevelInfo, "registration: request %d approved, created %s(#%d)", id, reg.Name, ref)
+
+	if g.EmailEnabled() {
+		mudName := "GoTinyMUSH"
+		if g.Conf.MudName != "" {
+			mudName = g.Conf.MudName
+		}
+		body := fmt.Sprintf("Your character on %s has been created.\n\n"+
+			"  connect %s %s\n\n"+
+			"Please change your password after you log in with @password.\n",
+			mudName, quoteLoginName(reg.Name), password)
+		to, name := reg.Email, reg.Name
+		go func() {
+			if err := g.sendEmail(to, mudName+": your new character", body); err != nil {
+				log.Printf("registration: mailing password for %s: %v", name, err)
+			}
+		}()
+		mailed = true
+	}
+	return ref, password, mailed, nil
+}
+
+// RejectRegistration drops a pending request.
+func (g *Game) RejectRegistration(id int) bool {
+	if g.Registrations.Get(id) == nil {
+		return false
+	}
+	g.dropRegistration(id)
+	Logf(LogWizard, LevelInfo, "registration: request %d rejected", id)
+	return true
+}
+
+func (g *Game) dropRegistration(id int) {
+	g.Registrations.Remove(id)
+	if g.Store != nil {
+		if err := g.Store.DeleteRegistration(id); err != nil {
+			Logf(LogBugs, LevelError, "delete registration %d: %v", id, err)
+		}
+	}
+}
+
+// quoteLoginName quotes a name with spaces the way the login screen expects.
+func quoteLoginName(name string) string {
+	if strings.Contains(name, " ") {
+		return `"` + name + `"`
+	}
+	return name
+}
+
+// handleRegister queues a character request: register <name> <email>.
+func (s *Server) handleRegister(d *Descriptor, name, email string) {
+	g := s.Game
+	if !g.RegistrationRequired() && g.SiteRuleFor(d.Addr, gamedb.SiteRegister) == nil {
+		d.Send("Registration isn't needed here. Use: create <name> <password>")
+		return
+	}
+	if name == "" || email == "" {
+		d.Send("Usage: register <name> <email address>")
+		return
+	}
+	if _, err := g.RequestRegistration(name, email, d.Addr); err != nil {
+		d.Send(err.Error())
+		return
+	}
+	if g.EmailEnabled() {
+		d.Send(fmt.Sprintf("Your request for %s has been queued. Your password will be emailed to %s once it is approved.", name, email))
+	} else {
+		d.Send(fmt.Sprintf("Your request for %s has been queued for approval.", name))
+	}
+}
+
+// cmdPcreate implements @pcreate: create a player directly, or manage the
+// registration queue.
+func cmdPcreate(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	args = strings.TrimSpace(args)
+
+	switch {
+	case HasSwitch(switches, "list"):
+		regs := g.Registrations.List()
+		if len(regs) == 0 {
+			d.Send("No registrations are pending.")
+			return
+		}
+		d.Send(fmt.Sprintf("%-4s %-20s %-30s %-16s %s", "ID", "Name", "Email", "Requested", "From"))
+		for _, r := range regs {
+			d.Send(fmt.Sprintf("%-4d %-20s %-30s %-16s %s", r.ID, r.Name, r.Email,
+				r.Requested.Format("2006-01-02 15:04"), r.Addr))
+		}
+		d.Send(fmt.Sprintf("%d pending.", len(regs)))
+	case HasSwitch(switches, "approve"):
+		id, err := strconv.Atoi(args)
+		if err != nil {
+			d.Send("That's not a registration number.")
+			return
+		}
+		ref, password, mailed, err := g.ApproveRegistration(id)
+		if err != nil {
+			d.Send(err.Error())
+			return
+		}
+		if mailed {
+			d.Send(fmt.Sprintf("New player '%s' (#%d) created; password mailed.", g.PlayerName(ref), ref))
+		} else {
+			d.Send(fmt.Sprintf("New player '%s' (#%d) created with password '%s'.", g.PlayerName(ref), ref, password))
+		}
+	case HasSwitch(switches, "reject"):
+		id, err := strconv.Atoi(args)
+		if err != nil {
+			d.Send("That's not a registration number.")
+			return
+		}
+		if !g.RejectRegistration(id) {
+			d.Send("No such registration.")
+			return
+		}
+		d.Send(fmt.Sprintf("Registration %d rejected.", id))
+	default:
+		name, password, ok := strings.Cut(args, "=")
+		name, password = strings.TrimSpace(name), strings.TrimSpace(password)
+		if !ok || name == "" || password == "" {
+			d.Send("Usage: @pcreate <name>=<password>")
+			return
+		}
+		ref, err := g.CreatePlayer(name, password)
+		if err != nil {
+			d.Send(err.Error())
+			return
+		}
+		Logf(LogWizard, LevelInfo, "%s(#%d) created player %s(#%d)", g.PlayerName(d.Player), d.Player, name, ref)
+		d.Send(fmt.Sprintf("New player '%s' (#%d) created with password '%s'.", name, ref, password))
+	}
+}
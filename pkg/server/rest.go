@@ -20,7 +20,7 @@ import (
 func (ws *WebServer) RegisterRESTRoutes() {
 	// WHO list (optional auth)
 	ws.mux.Handle("GET /api/v1/who",
-		authMiddleware(ws.auth, false, http.HandlerFunc(ws.handleWho)))
+		authMiddleware(ws.auth, false, ws.locked(ws.handleWho)))
 
 	// Command execution (required auth)
 	ws.mux.Handle("POST /api/v1/command",
@@ -28,25 +28,47 @@ func (ws *WebServer) RegisterRESTRoutes() {
 
 	// Object info (required auth)
 	ws.mux.Handle("GET /api/v1/objects/{dbref}",
-		authMiddleware(ws.auth, true, http.HandlerFunc(ws.handleGetObject)))
+		authMiddleware(ws.auth, true, ws.locked(ws.handleGetObject)))
 
 	// Attribute value (required auth)
 	ws.mux.Handle("GET /api/v1/objects/{dbref}/attrs/{name}",
-		authMiddleware(ws.auth, true, http.HandlerFunc(ws.handleGetAttr)))
+		authMiddleware(ws.auth, true, ws.locked(ws.handleGetAttr)))
+
+	// Attribute writes and object search, as the token's player (required auth)
+	ws.mux.Handle("PUT /api/v1/objects/{dbref}/attrs/{name}",
+		authMiddleware(ws.auth, true, ws.locked(ws.handlePutAttr)))
+	ws.mux.Handle("DELETE /api/v1/objects/{dbref}/attrs/{name}",
+		authMiddleware(ws.auth, true, ws.locked(ws.handleDeleteAttr)))
+	ws.mux.Handle("GET /api/v1/search",
+		authMiddleware(ws.auth, true, ws.locked(ws.handleSearch)))
 
 	// Channel list (required auth)
 	ws.mux.Handle("GET /api/v1/channels",
-		authMiddleware(ws.auth, true, http.HandlerFunc(ws.handleChannels)))
+		authMiddleware(ws.auth, true, ws.locked(ws.handleChannels)))
 
 	// Channel history (required auth)
 	ws.mux.Handle("GET /api/v1/channels/{name}/history",
-		authMiddleware(ws.auth, true, http.HandlerFunc(ws.handleChannelHistory)))
+		authMiddleware(ws.auth, true, ws.locked(ws.handleChannelHistory)))
 
 	// Personal scrollback (required auth)
 	ws.mux.Handle("GET /api/v1/scrollback",
-		authMiddleware(ws.auth, true, http.HandlerFunc(ws.handleGetScrollback)))
+		authMiddleware(ws.auth, true, ws.locked(ws.handleGetScrollback)))
 	ws.mux.Handle("POST /api/v1/scrollback",
-		authMiddleware(ws.auth, true, http.HandlerFunc(ws.handlePostScrollback)))
+		authMiddleware(ws.auth, true, ws.locked(ws.handlePostScrollback)))
+
+	// Mail export, the caller's own mail (required auth)
+	ws.mux.Handle("GET /api/v1/mail/export",
+		authMiddleware(ws.auth, true, ws.locked(ws.handleMailExport)))
+
+	// Recorded scenes the caller may read, and their logs (required auth)
+	ws.mux.Handle("GET /api/v1/scenes",
+		authMiddleware(ws.auth, true, ws.locked(ws.handleScenes)))
+	ws.mux.Handle("GET /api/v1/scenes/{id}/log",
+		authMiddleware(ws.auth, true, ws.locked(ws.handleSceneLog)))
+
+	// Server health summary, as @info (required auth, wizard only)
+	ws.mux.Handle("GET /api/v1/info",
+		authMiddleware(ws.auth, true, ws.locked(ws.handleInfo)))
 }
 
 // --- WHO ---
@@ -140,17 +162,23 @@ func (ws *WebServer) handleCommand(w http.ResponseWriter, r *http.Request) {
 	// player as "connected". This is critical for queued commands ($-commands,
 	// @trigger, @force) that send output via @pemit %# — without a registered
 	// descriptor, the output has nowhere to go.
-	ws.game.Conns.Add(d)
-	ws.game.Conns.Login(d, claims.PlayerRef)
-	defer ws.game.Conns.Remove(d)
-
-	DispatchCommand(ws.game, d, req.Command)
+	// Queued output arrives while we wait, so only the command itself runs
+	// under the world lock.
+	ws.game.Do(func() {
+		ws.game.Conns.Add(d)
+		ws.game.Conns.Login(d, claims.PlayerRef)
+		ws.game.logInput(d, req.Command)
+		DispatchCommand(ws.game, d, req.Command)
+	})
+	defer ws.game.Do(func() { ws.game.Conns.Remove(d) })
 
 	// Wait for async queue entries to process. Queued commands ($-commands,
 	// @trigger, etc.) fire on the game loop's 10ms tick, so we poll briefly
 	// to capture their output.
 	deadline := time.Now().Add(time.Duration(req.Wait) * time.Millisecond)
+	output.mu.Lock()
 	lastLen := len(output.lines)
+	output.mu.Unlock()
 	settled := 0
 	for time.Now().Before(deadline) {
 		time.Sleep(50 * time.Millisecond)
@@ -233,7 +261,7 @@ func (ws *WebServer) handleGetObject(w http.ResponseWriter, r *http.Request) {
 
 	// Include readable attributes
 	attrs := make(map[string]string)
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		info := ParseAttrInfo(attr.Value)
 		def := ws.game.LookupAttrDef(attr.Number)
 		if !CanReadAttr(ws.game, claims.PlayerRef, ref, def, info.Flags, info.Owner) {
@@ -281,7 +309,7 @@ func (ws *WebServer) handleGetAttr(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		if attr.Number == attrNum {
 			info := ParseAttrInfo(attr.Value)
 			def := ws.game.LookupAttrDef(attrNum)
@@ -302,6 +330,94 @@ func (ws *WebServer) handleGetAttr(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, `{"error":"attribute not found"}`, http.StatusNotFound)
 }
 
+// handlePutAttr sets an attribute from {"value": "..."}, with the same
+// permission checks as &ATTR typed by the token's player.
+func (ws *WebServer) handlePutAttr(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	ws.writeAttr(w, r, req.Value)
+}
+
+// handleDeleteAttr clears an attribute, as &ATTR obj= would.
+func (ws *WebServer) handleDeleteAttr(w http.ResponseWriter, r *http.Request) {
+	ws.writeAttr(w, r, "")
+}
+
+func (ws *WebServer) writeAttr(w http.ResponseWriter, r *http.Request, value string) {
+	claims := ClaimsFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	ref, err := parseDBRef(r.PathValue("dbref"))
+	if err != nil {
+		http.Error(w, `{"error":"invalid dbref"}`, http.StatusBadRequest)
+		return
+	}
+	if _, ok := ws.game.DB.Objects[ref]; !ok {
+		http.Error(w, `{"error":"object not found"}`, http.StatusNotFound)
+		return
+	}
+	attrName := strings.ToUpper(r.PathValue("name"))
+	if !Controls(ws.game, claims.PlayerRef, ref) {
+		http.Error(w, `{"error":"permission denied"}`, http.StatusForbidden)
+		return
+	}
+	if ok, msg := ws.game.SetAttrByNameChecked(claims.PlayerRef, ref, attrName, value); !ok {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, msg), http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"name":  attrName,
+		"value": value,
+	})
+}
+
+// --- Search ---
+
+// handleSearch runs an @search specification (?q=...) as the token's
+// player, so mortals see only their own objects unless they have the
+// search power.
+func (ws *WebServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	claims := ClaimsFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	refs, err := ws.game.Search(claims.PlayerRef, r.URL.Query().Get("q"), nil)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	type match struct {
+		Ref   int    `json:"ref"`
+		Name  string `json:"name"`
+		Type  string `json:"type"`
+		Owner int    `json:"owner"`
+	}
+	matches := make([]match, 0, len(refs))
+	for _, ref := range refs {
+		obj := ws.game.DB.Objects[ref]
+		matches = append(matches, match{
+			Ref:   int(ref),
+			Name:  obj.Name,
+			Type:  obj.ObjType().String(),
+			Owner: int(obj.Owner),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"objects": matches,
+		"count":   len(matches),
+	})
+}
+
 // --- Channels ---
 
 func (ws *WebServer) handleChannels(w http.ResponseWriter, r *http.Request) {
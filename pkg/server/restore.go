@@ -0,0 +1,362 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/archive"
+	"github.com/crystal-mush/gotinymush/pkg/boltstore"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// ObjectRestore reports what RestoreObjects did.
+type ObjectRestore struct {
+	Archive  string         // archive file the objects came from
+	Restored []gamedb.DBRef // in dbref order
+	Skipped  []string       // "#ref Name: reason"
+}
+
+// RestoreObjects copies the objects numbered from..to out of an archive's
+// database snapshot into the live database. Destroyed objects are recreated
+// and put back where they were: things and players into their location
+// (or a safe home if that is gone too), exits into their source's exit
+// list. Objects that still exist are left alone unless overwrite is set,
+// in which case their archived name, flags, powers, attributes, parent and
+// zone replace the current ones in place. owner takes over restored objects
+// whose owner no longer exists.
+//
+// name is an archive filename in the archive directory, or "latest".
+func (g *Game) RestoreObjects(name string, from, to gamedb.DBRef, overwrite bool, owner gamedb.DBRef) (*ObjectRestore, error) {
+	if from < 0 || to < from {
+		return nil, fmt.Errorf("invalid dbref range #%d-#%d", from, to)
+	}
+	path, err := g.findArchive(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "mush-restore-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	boltPath := filepath.Join(tmpDir, "game.bolt")
+	passphrase := ""
+	if g.Conf != nil {
+		passphrase = g.Conf.ArchivePassphrase
+	}
+	if err := archive.ExtractBolt(path, boltPath, passphrase, ""); err != nil {
+		return nil, err
+	}
+	store, err := boltstore.OpenReadOnly(boltPath)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+	if err := store.LoadAll(); err != nil {
+		return nil, err
+	}
+	snap := store.DB()
+
+	var refs []gamedb.DBRef
+	for ref := range snap.Objects {
+		if ref >= from && ref <= to {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+
+	res := &ObjectRestore{Archive: filepath.Base(path)}
+	skip := func(obj *gamedb.Object, format string, args ...any) {
+		res.Skipped = append(res.Skipped, fmt.Sprintf("#%d %s: %s", obj.DBRef, obj.Name, fmt.Sprintf(format, args...)))
+	}
+
+	// Decide what comes back before touching anything, so exits and
+	// contents can be matched up with sources restored alongside them.
+	incoming := make(map[gamedb.DBRef]*gamedb.Object)
+	var replaced []*gamedb.Object
+	for _, ref := range refs {
+		src := snap.Objects[ref]
+		if !present(src) {
+			continue
+		}
+		cur, exists := g.DB.Objects[ref]
+		if exists && present(cur) {
+			switch {
+			case !overwrite:
+				skip(src, "still exists")
+			case cur.ObjType() != src.ObjType():
+				skip(src, "is now a %s", cur.ObjType())
+			case src.ObjType() == gamedb.TypePlayer && g.playerNameTaken(src.Name, ref):
+				skip(src, "another player is named %s", src.Name)
+			default:
+				replaced = append(replaced, src)
+			}
+			continue
+		}
+		if src.ObjType() == gamedb.TypePlayer && g.playerNameTaken(src.Name, ref) {
+			skip(src, "another player is named %s", src.Name)
+			continue
+		}
+		incoming[ref] = src
+	}
+	for _, ref := range refs {
+		if src, ok := incoming[ref]; ok && src.ObjType() == gamedb.TypeExit && incoming[src.Exits] == nil && !g.liveRef(src.Exits) {
+			skip(src, "its source #%d is gone", src.Exits)
+			delete(incoming, ref)
+		}
+	}
+
+	var changed []*gamedb.Object
+	for _, src := range replaced {
+		changed = append(changed, g.overwriteObject(snap, src, owner))
+		res.Restored = append(res.Restored, src.DBRef)
+	}
+
+	// Place the recreated objects, detached, then link them in once all
+	// of them exist.
+	var placed []*gamedb.Object
+	for _, ref := range refs {
+		src, ok := incoming[ref]
+		if !ok {
+			continue
+		}
+		obj := *src
+		obj.SetAttributes(g.remapAttrs(snap, src.Attributes()))
+		obj.Flags[1] &^= gamedb.Flag2Connected
+		obj.Contents, obj.Next = gamedb.Nothing, gamedb.Nothing
+		if obj.ObjType() != gamedb.TypeExit {
+			obj.Exits = gamedb.Nothing
+		}
+		g.DB.Objects[ref] = &obj
+		if ref >= g.NextRef {
+			g.NextRef = ref + 1
+		}
+		placed = append(placed, &obj)
+		res.Restored = append(res.Restored, ref)
+	}
+	for _, obj := range placed {
+		changed = append(changed, g.relinkRestored(obj, owner)...)
+	}
+	sort.Slice(res.Restored, func(i, j int) bool { return res.Restored[i] < res.Restored[j] })
+
+	g.PersistObjects(changed...)
+	return res, nil
+}
+
+// findArchive resolves an archive filename in the archive directory.
+func (g *Game) findArchive(name string) (string, error) {
+	archiveDir := g.ArchiveDir
+	if archiveDir == "" {
+		archiveDir = "backups"
+	}
+	if name == "" || strings.EqualFold(name, "latest") {
+		archives, err := archive.ListArchives(archiveDir)
+		if err != nil {
+			return "", err
+		}
+		if len(archives) == 0 {
+			return "", fmt.Errorf("no archives found in %s", archiveDir)
+		}
+		return archives[0].Path, nil
+	}
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("give just the archive's filename, as @archive/list shows it")
+	}
+	path := filepath.Join(archiveDir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no archive %s in %s", name, archiveDir)
+	}
+	return path, nil
+}
+
+// present reports whether obj is still in the world: neither GARBAGE nor
+// destroyed. A room only marked GOING is intact until its second @destroy.
+func present(obj *gamedb.Object) bool {
+	if obj == nil || obj.ObjType() == gamedb.TypeGarbage {
+		return false
+	}
+	if obj.IsGoing() {
+		return obj.ObjType() == gamedb.TypeRoom && !obj.HasFlag2(gamedb.Flag2GoingTwice)
+	}
+	return true
+}
+
+// liveRef reports whether ref names an object that is present.
+func (g *Game) liveRef(ref gamedb.DBRef) bool {
+	return present(g.DB.Objects[ref])
+}
+
+// playerNameTaken reports whether a live player other than self is named name.
+func (g *Game) playerNameTaken(name string, self gamedb.DBRef) bool {
+	for ref, obj := range g.DB.Objects {
+		if ref != self && obj.ObjType() == gamedb.TypePlayer && present(obj) && strings.EqualFold(obj.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// remapAttrs renumbers an archived object's user attributes to this game's
+// numbers for the same names, defining any that no longer exist.
+func (g *Game) remapAttrs(snap *gamedb.Database, attrs []gamedb.Attribute) []gamedb.Attribute {
+	out := make([]gamedb.Attribute, 0, len(attrs))
+	for _, a := range attrs {
+		if a.Number >= gamedb.A_USER_START {
+			def, ok := snap.AttrNames[a.Number]
+			if !ok {
+				continue
+			}
+			a.Number = g.userAttrNum(def)
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// userAttrNum returns the number of the user attribute named like def,
+// creating it with def's flags if this game has no such attribute.
+func (g *Game) userAttrNum(def *gamedb.AttrDef) int {
+	if cur, ok := g.DB.AttrByName[def.Name]; ok {
+		return cur.Number
+	}
+	if g.DB.NextAttr < gamedb.A_USER_START {
+		g.DB.NextAttr = gamedb.A_USER_START
+	}
+	num := g.DB.NextAttr
+	g.DB.NextAttr++
+	g.DB.AddAttrDef(num, def.Name, def.Flags)
+	if g.Store != nil {
+		g.Store.PutAttrDef(g.DB.AttrNames[num])
+		g.Store.PutMeta()
+	}
+	return num
+}
+
+// overwriteObject copies an archived object's own state onto the live
+// object with the same dbref, leaving where it is and what it holds alone.
+func (g *Game) overwriteObject(snap *gamedb.Database, src *gamedb.Object, owner gamedb.DBRef) *gamedb.Object {
+	obj := g.DB.Objects[src.DBRef]
+	connected := obj.Flags[1] & gamedb.Flag2Connected
+	obj.Name = src.Name
+	obj.Flags = src.Flags
+	obj.Flags[1] = obj.Flags[1]&^gamedb.Flag2Connected | connected
+	obj.Powers = src.Powers
+	obj.Lock = src.Lock
+	obj.SetAttributes(g.remapAttrs(snap, src.Attributes()))
+	obj.Owner = g.liveOr(src.Owner, owner)
+	obj.Parent = g.liveOr(src.Parent, gamedb.Nothing)
+	obj.Zone = g.liveOr(src.Zone, gamedb.Nothing)
+	if src.Link < 0 || g.liveRef(src.Link) {
+		obj.Link = src.Link
+	}
+	return obj
+}
+
+// liveOr returns ref if it names a live object, else fallback.
+func (g *Game) liveOr(ref, fallback gamedb.DBRef) gamedb.DBRef {
+	if ref != gamedb.Nothing && g.liveRef(ref) {
+		return ref
+	}
+	return fallback
+}
+
+// relinkRestored fixes up a recreated object's references and puts it back
+// in its location's contents or its source's exit list. It returns the
+// objects it changed.
+func (g *Game) relinkRestored(obj *gamedb.Object, owner gamedb.DBRef) []*gamedb.Object {
+	changed := []*gamedb.Object{obj}
+	obj.Owner = g.liveOr(obj.Owner, owner)
+	obj.Parent = g.liveOr(obj.Parent, gamedb.Nothing)
+	obj.Zone = g.liveOr(obj.Zone, gamedb.Nothing)
+
+	switch obj.ObjType() {
+	case gamedb.TypeRoom:
+		// Link is the drop-to, which may be HOME.
+		if obj.Link >= 0 && !g.liveRef(obj.Link) {
+			obj.Link = gamedb.Nothing
+		}
+	case gamedb.TypeExit:
+		// Location is the destination; Exits holds the source.
+		if obj.Location >= 0 && !g.liveRef(obj.Location) {
+			obj.Location = gamedb.Nothing
+		}
+		src := g.DB.Objects[obj.Exits]
+		obj.Next = src.Exits
+		src.Exits = obj.DBRef
+		changed = append(changed, src)
+	default:
+		obj.Link = g.liveOr(obj.Link, gamedb.Nothing)
+		if obj.Link == gamedb.Nothing {
+			obj.Link = g.safeHome(obj.DBRef, obj.DBRef)
+		}
+		if obj.Location == obj.DBRef || !g.liveRef(obj.Location) || g.DB.Objects[obj.Location].ObjType() == gamedb.TypeExit {
+			obj.Location = g.safeHome(obj.DBRef, obj.DBRef)
+		}
+		if obj.Location != gamedb.Nothing {
+			g.AddToContents(obj.Location, obj.DBRef)
+			changed = append(changed, g.DB.Objects[obj.Location])
+		}
+	}
+	return changed
+}
+
+// cmdRestore implements @restore/object <dbref>[-<dbref>]=<archive>.
+func cmdRestore(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	if !HasSwitch(switches, "object") {
+		d.Send("Usage: @restore/object <dbref>[-<dbref>]=<archive>")
+		return
+	}
+	refs, name, ok := strings.Cut(args, "=")
+	if !ok || strings.TrimSpace(refs) == "" {
+		d.Send("Usage: @restore/object <dbref>[-<dbref>]=<archive>")
+		return
+	}
+	from, to, ok := parseRefRange(strings.TrimSpace(refs))
+	if !ok {
+		d.Send("Bad dbref range.")
+		return
+	}
+
+	res, err := g.RestoreObjects(strings.TrimSpace(name), from, to, HasSwitch(switches, "overwrite"), d.Player)
+	if err != nil {
+		d.Send(fmt.Sprintf("Restore failed: %v", err))
+		return
+	}
+	for _, line := range res.Skipped {
+		d.Send("Skipped " + line)
+	}
+	for _, ref := range res.Restored {
+		d.Send(fmt.Sprintf("Restored #%d %s.", ref, g.DB.Objects[ref].Name))
+	}
+	if len(res.Restored) == 0 && len(res.Skipped) == 0 {
+		d.Send(fmt.Sprintf("No objects in that range in %s.", res.Archive))
+		return
+	}
+	d.Send(fmt.Sprintf("%d object(s) restored from %s.", len(res.Restored), res.Archive))
+	Logf(LogWizard, LevelInfo, "%s(#%d) restored %d object(s) #%d-#%d from %s",
+		g.PlayerName(d.Player), d.Player, len(res.Restored), from, to, res.Archive)
+}
+
+// parseRefRange parses "#5" or "#5-#9".
+func parseRefRange(s string) (from, to gamedb.DBRef, ok bool) {
+	lo, hi, isRange := strings.Cut(s, "-")
+	from, err := parseDBRef(strings.TrimSpace(lo))
+	if err != nil {
+		return 0, 0, false
+	}
+	to = from
+	if isRange {
+		if to, err = parseDBRef(strings.TrimSpace(hi)); err != nil {
+			return 0, 0, false
+		}
+	}
+	return from, to, from >= 0 && to >= from
+}
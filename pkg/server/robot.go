@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// cmdRobot implements @robot <name>=<password>: a player set ROBOT and
+// owned by its creator, for running a client program. It costs
+// robot_cost, given back if the robot can't be made.
+func cmdRobot(g *Game, d *Descriptor, args string, _ []string) {
+	name, password, ok := strings.Cut(args, "=")
+	name, password = strings.TrimSpace(name), strings.TrimSpace(password)
+	if !ok || name == "" || password == "" {
+		d.Send("Usage: @robot <name>=<password>")
+		return
+	}
+	cost := g.robotCost()
+	if !g.payFor(d.Player, cost) {
+		g.notEnoughMoney(d)
+		return
+	}
+	ref, err := g.CreatePlayer(name, password)
+	if err != nil {
+		g.refund(d.Player, cost)
+		d.Send(err.Error())
+		return
+	}
+	robot := g.DB.Objects[ref]
+	robot.Owner = ResolveOwner(g, d.Player)
+	robot.Flags[0] |= gamedb.FlagRobot
+	g.PersistObject(robot)
+	Logf(LogConnections, LevelInfo, "%s(#%d) created robot %s(#%d)", g.PlayerName(d.Player), d.Player, name, ref)
+	d.Send(fmt.Sprintf("New robot '%s' (#%d) created with password '%s'.", name, ref, password))
+}
+
+// robotCost returns robot_cost.
+func (g *Game) robotCost() int {
+	if g.Conf == nil {
+		return 0
+	}
+	return g.Conf.RobotCost
+}
+
+// isRobot reports whether player is set ROBOT.
+func (g *Game) isRobot(player gamedb.DBRef) bool {
+	o, ok := g.DB.Objects[player]
+	return ok && o.ObjType() == gamedb.TypePlayer && o.HasFlag(gamedb.FlagRobot)
+}
+
+// robotBarred reports whether player is a robot and any of the exits,
+// rooms and things it would pass through or into is set ROBOT, keeping
+// robots out. It tells the robot so.
+func (g *Game) robotBarred(d *Descriptor, through ...gamedb.DBRef) bool {
+	if !g.isRobot(d.Player) {
+		return false
+	}
+	for _, ref := range through {
+		if o, ok := g.DB.Objects[ref]; ok && o.ObjType() != gamedb.TypePlayer && o.HasFlag(gamedb.FlagRobot) {
+			d.Send("Sorry, robots aren't allowed there.")
+			return true
+		}
+	}
+	return false
+}
+
+// robotMuted reports whether player is a robot that may not speak where
+// it is: with robot_speech off, robots only speak in rooms they control.
+// It tells the robot so.
+func (g *Game) robotMuted(d *Descriptor) bool {
+	if g.Conf == nil || g.Conf.RobotSpeech || !g.isRobot(d.Player) {
+		return false
+	}
+	if Controls(g, d.Player, g.PlayerLocation(d.Player)) {
+		return false
+	}
+	d.Send("Sorry, robots may not speak in public.")
+	return true
+}
+
+// outputFix handles OUTPUTPREFIX and OUTPUTSUFFIX, which set a line sent
+// before and after the output of each command, so that client programs
+// can pick it out. As in C TinyMUSH, they must be typed in capitals. It
+// reports whether line was one of them.
+func outputFix(d *Descriptor, line string) bool {
+	cmd, text, _ := strings.Cut(line, " ")
+	switch cmd {
+	case "OUTPUTPREFIX":
+		d.OutputPrefix = text
+	case "OUTPUTSUFFIX":
+		d.OutputSuffix = text
+	default:
+		return false
+	}
+	return true
+}
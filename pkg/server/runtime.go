@@ -0,0 +1,197 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// Runtime settings are the ones changed in-game that would otherwise be
+// lost at restart: the MOTDs, @function definitions, @helptext entries
+// and @admin values saved with @admin/save. Each is kept in bolt with who
+// set it and when. See motd.go for the MOTDs and helptext.go for the help
+// entries.
+
+// LoadRuntimeSettings applies runtime settings read from storage. Saved
+// @admin values override the config file.
+func (g *Game) LoadRuntimeSettings(settings []gamedb.RuntimeSetting) {
+	g.Runtime = make(map[string]*gamedb.RuntimeSetting)
+	for i := range settings {
+		rs := settings[i]
+		if err := g.applyRuntime(&rs); err != nil {
+			log.Printf("runtime setting %s %q: %v", rs.Kind, rs.Name, err)
+			continue
+		}
+		g.Runtime[rs.Kind+":"+rs.Name] = &rs
+	}
+}
+
+// applyRuntime makes a runtime setting take effect.
+func (g *Game) applyRuntime(rs *gamedb.RuntimeSetting) error {
+	switch rs.Kind {
+	case gamedb.RuntimeMOTD:
+		if strings.HasSuffix(rs.Name, ".txt") {
+			return nil // Who last wrote the file; the file has the text
+		}
+		motd := g.motdField(rs.Name)
+		if motd == nil {
+			return fmt.Errorf("no such MOTD")
+		}
+		*motd = rs.Value
+	case gamedb.RuntimeRotation:
+		// Shown from g.Runtime at login.
+	case gamedb.RuntimeHelpText:
+		return g.applyHelpText(rs)
+	case gamedb.RuntimeFunction:
+		if obj, ok := g.DB.Objects[rs.Obj]; !ok || obj.IsGoing() {
+			return fmt.Errorf("#%d no longer exists", rs.Obj)
+		}
+		if g.GameFuncs == nil {
+			g.GameFuncs = make(map[string]*eval.UFunction)
+		}
+		g.GameFuncs[rs.Name] = &eval.UFunction{Name: rs.Name, Obj: rs.Obj, Attr: rs.Attr, Flags: rs.Flags}
+	case gamedb.RuntimeAdmin:
+		if g.Conf == nil || !setAdminParam(g.Conf, rs.Name, rs.Value) {
+			return fmt.Errorf("unknown parameter")
+		}
+	default:
+		return fmt.Errorf("unknown kind")
+	}
+	return nil
+}
+
+// applyAdminSettings puts the saved @admin values into c, so that they
+// still override the config file after @readconf.
+func (g *Game) applyAdminSettings(c *GameConf) {
+	for _, rs := range g.Runtime {
+		if rs.Kind == gamedb.RuntimeAdmin {
+			setAdminParam(c, rs.Name, rs.Value)
+		}
+	}
+}
+
+// putRuntime records a runtime setting and writes it to bolt.
+func (g *Game) putRuntime(rs *gamedb.RuntimeSetting) {
+	if g.Runtime == nil {
+		g.Runtime = make(map[string]*gamedb.RuntimeSetting)
+	}
+	g.Runtime[rs.Kind+":"+rs.Name] = rs
+	if g.Store != nil {
+		if err := g.Store.PutRuntimeSetting(rs); err != nil {
+			Logf(LogBugs, LevelError, "persist %s %q: %v", rs.Kind, rs.Name, err)
+		}
+	}
+}
+
+// deleteRuntime forgets a runtime setting, reporting whether there was
+// one.
+func (g *Game) deleteRuntime(kind, name string) bool {
+	key := kind + ":" + name
+	if _, ok := g.Runtime[key]; !ok {
+		return false
+	}
+	delete(g.Runtime, key)
+	if g.Store != nil {
+		if err := g.Store.DeleteRuntimeSetting(kind, name); err != nil {
+			Logf(LogBugs, LevelError, "remove %s %q: %v", kind, name, err)
+		}
+	}
+	return true
+}
+
+// adminSave implements @admin/save. With a parameter it saves that one,
+// as last set with @admin or else its current value; without, every
+// @admin change not yet saved.
+func adminSave(g *Game, d *Descriptor, param string) {
+	if param == "" {
+		if len(g.adminUnsaved) == 0 {
+			d.Send("No unsaved @admin changes.")
+			return
+		}
+		n := len(g.adminUnsaved)
+		for name, rs := range g.adminUnsaved {
+			g.putRuntime(rs)
+			delete(g.adminUnsaved, name)
+		}
+		Logf(LogWizard, LevelInfo, "@admin: %s(#%d) saved %d change(s)", g.PlayerName(d.Player), d.Player, n)
+		d.Send(fmt.Sprintf("Saved %d @admin change(s).", n))
+		return
+	}
+	rs := g.adminUnsaved[param]
+	if rs == nil {
+		val, ok := getAdminParam(g.Conf, param)
+		if !ok {
+			d.Send(fmt.Sprintf("Unknown parameter: %s", param))
+			return
+		}
+		rs = &gamedb.RuntimeSetting{Kind: gamedb.RuntimeAdmin, Name: param, Value: val, Setter: d.Player, Changed: g.Now()}
+	}
+	g.putRuntime(rs)
+	delete(g.adminUnsaved, param)
+	Logf(LogWizard, LevelInfo, "@admin: %s(#%d) saved %s = %s", g.PlayerName(d.Player), d.Player, param, rs.Value)
+	d.Send(fmt.Sprintf("Saved: %s = %s", param, rs.Value))
+}
+
+// adminList implements @admin/list: the runtime settings and unsaved
+// @admin changes, with who made them.
+func adminList(g *Game, d *Descriptor) {
+	var lines []string
+	line := func(rs *gamedb.RuntimeSetting, note string) {
+		var what string
+		switch rs.Kind {
+		case gamedb.RuntimeMOTD:
+			if strings.HasSuffix(rs.Name, ".txt") {
+				what = "@motd wrote " + rs.Name
+				break
+			}
+			what = "@motd"
+			if rs.Name != "" {
+				what += "/" + rs.Name
+			}
+			what += " = " + truncDebug(rs.Value, 40)
+		case gamedb.RuntimeRotation:
+			what = fmt.Sprintf("@motd/add %s = %s", rs.Name, truncDebug(rs.Value, 40))
+		case gamedb.RuntimeHelpText:
+			what = "@helptext " + rs.Name
+		case gamedb.RuntimeFunction:
+			what = fmt.Sprintf("@function %s = #%d/%d", rs.Name, rs.Obj, rs.Attr)
+		default:
+			what = fmt.Sprintf("@admin %s = %s", rs.Name, rs.Value)
+		}
+		lines = append(lines, fmt.Sprintf("  %s  [%s(#%d) %s]%s", what,
+			g.PlayerName(rs.Setter), rs.Setter, rs.Changed.Format("2006-01-02 15:04"), note))
+	}
+	for _, rs := range g.Runtime {
+		line(rs, "")
+	}
+	for _, rs := range g.adminUnsaved {
+		line(rs, " (not saved)")
+	}
+	if len(lines) == 0 {
+		d.Send("No runtime settings.")
+		return
+	}
+	sort.Strings(lines)
+	d.Send("Runtime settings:")
+	for _, l := range lines {
+		d.Send(l)
+	}
+	d.Send(fmt.Sprintf("%d setting(s).", len(lines)))
+}
+
+// adminClear implements @admin/clear: forget a saved @admin value. The
+// current value stands until restart or @readconf.
+func adminClear(g *Game, d *Descriptor, param string) {
+	unsaved := g.adminUnsaved[param] != nil
+	delete(g.adminUnsaved, param)
+	if !g.deleteRuntime(gamedb.RuntimeAdmin, param) && !unsaved {
+		d.Send(fmt.Sprintf("No saved value for %s.", param))
+		return
+	}
+	Logf(LogWizard, LevelInfo, "@admin: %s(#%d) cleared the saved %s", g.PlayerName(d.Player), d.Player, param)
+	d.Send(fmt.Sprintf("Saved value for %s cleared.", param))
+}
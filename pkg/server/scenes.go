@@ -0,0 +1,677 @@
+package server
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/events"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// sceneLogLimit caps the number of lines in one scene. Recording stops
+// automatically when it is reached.
+const sceneLogLimit = 5000
+
+// SceneRecorder captures room events into encrypted scene logs. It watches
+// only rooms with a scene in progress, via events.Bus.WatchRoom.
+type SceneRecorder struct {
+	mu     sync.Mutex
+	game   *Game
+	master []byte                         // Master key; per-room keys are derived from it
+	scenes map[int]*gamedb.Scene          // All scenes by ID
+	active map[gamedb.DBRef]*gamedb.Scene // Room -> scene being recorded
+	nextID int
+}
+
+// NewSceneRecorder creates a recorder. master must be non-empty; it is
+// hashed, so any length works.
+func NewSceneRecorder(g *Game, master []byte) *SceneRecorder {
+	sum := sha256.Sum256(master)
+	return &SceneRecorder{
+		game:   g,
+		master: sum[:],
+		scenes: make(map[int]*gamedb.Scene),
+		active: make(map[gamedb.DBRef]*gamedb.Scene),
+		nextID: 1,
+	}
+}
+
+// SceneMasterKey returns the key scene logs are encrypted under: the
+// scene_key config value if set, otherwise a random key generated once
+// and kept in the database meta bucket.
+func SceneMasterKey(g *Game) ([]byte, error) {
+	if g.Conf != nil && g.Conf.SceneKey != "" {
+		return []byte(g.Conf.SceneKey), nil
+	}
+	if g.Store != nil {
+		key, err := g.Store.SceneKey()
+		if err != nil {
+			return nil, err
+		}
+		if len(key) > 0 {
+			return key, nil
+		}
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if g.Store != nil {
+		if err := g.Store.PutSceneKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// LoadScenes installs scenes read from the database and resumes watching
+// rooms whose recording was still running at shutdown.
+func (sr *SceneRecorder) LoadScenes(scenes []gamedb.Scene) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	for i := range scenes {
+		sc := &scenes[i]
+		sr.scenes[sc.ID] = sc
+		if sc.ID >= sr.nextID {
+			sr.nextID = sc.ID + 1
+		}
+		if sc.Ended.IsZero() {
+			sr.active[sc.Room] = sc
+			sr.game.EventBus.WatchRoom(sc.Room, sr)
+		}
+	}
+}
+
+// roomKey derives the AES-256 key for a room's scene logs.
+func (sr *SceneRecorder) roomKey(room gamedb.DBRef) []byte {
+	mac := hmac.New(sha256.New, sr.master)
+	fmt.Fprintf(mac, "scene-room:%d", room)
+	return mac.Sum(nil)
+}
+
+func (sr *SceneRecorder) aead(room gamedb.DBRef) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(sr.roomKey(room))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts a log entry with the room key. Output is nonce||ciphertext.
+func (sr *SceneRecorder) seal(room gamedb.DBRef, entry gamedb.SceneEntry) ([]byte, error) {
+	plain, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := sr.aead(room)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// unseal decrypts a log entry sealed by seal.
+func (sr *SceneRecorder) unseal(room gamedb.DBRef, blob []byte) (gamedb.SceneEntry, error) {
+	var entry gamedb.SceneEntry
+	gcm, err := sr.aead(room)
+	if err != nil {
+		return entry, err
+	}
+	if len(blob) < gcm.NonceSize() {
+		return entry, errors.New("short scene record")
+	}
+	plain, err := gcm.Open(nil, blob[:gcm.NonceSize()], blob[gcm.NonceSize():], nil)
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(plain, &entry)
+	return entry, err
+}
+
+// persist writes a scene through to the bolt store (no-op without one).
+func (sr *SceneRecorder) persist(sc *gamedb.Scene) {
+	if sr.game.Store == nil {
+		return
+	}
+	if err := sr.game.Store.PutScene(sc); err != nil {
+		Logf(LogBugs, LevelError, "persist scene %d: %v", sc.ID, err)
+	}
+}
+
+// persistLine writes the newest line of a scene's log through to the bolt
+// store. Lines are stored on their own so a busy scene costs one small
+// write per line, not a rewrite of the whole log.
+func (sr *SceneRecorder) persistLine(sc *gamedb.Scene) {
+	if sr.game.Store == nil {
+		return
+	}
+	seq := len(sc.Log) - 1
+	if err := sr.game.Store.PutSceneLine(sc.ID, seq, sc.Log[seq]); err != nil {
+		Logf(LogBugs, LevelError, "persist scene %d line %d: %v", sc.ID, seq, err)
+	}
+}
+
+// Start begins recording a scene in room. Everyone present becomes a
+// participant. Returns an error if the room is already recording.
+func (sr *SceneRecorder) Start(room, owner gamedb.DBRef, title string) (*gamedb.Scene, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if _, ok := sr.active[room]; ok {
+		return nil, errors.New("a scene is already being recorded here")
+	}
+	sc := &gamedb.Scene{
+		ID:      sr.nextID,
+		Room:    room,
+		Title:   title,
+		Owner:   owner,
+		Started: time.Now(),
+	}
+	sr.nextID++
+	addSceneRef(&sc.Participants, owner)
+	for _, ref := range sr.game.DB.SafeContents(room) {
+		if obj, ok := sr.game.DB.Objects[ref]; ok && obj.ObjType() == gamedb.TypePlayer {
+			addSceneRef(&sc.Participants, ref)
+		}
+	}
+	sr.scenes[sc.ID] = sc
+	sr.active[room] = sc
+	sr.persist(sc)
+	sr.game.EventBus.WatchRoom(room, sr)
+	return sc, nil
+}
+
+// Stop ends the recording in room, returning the finished scene or nil.
+func (sr *SceneRecorder) Stop(room gamedb.DBRef) *gamedb.Scene {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.stopLocked(room)
+}
+
+func (sr *SceneRecorder) stopLocked(room gamedb.DBRef) *gamedb.Scene {
+	sc, ok := sr.active[room]
+	if !ok {
+		return nil
+	}
+	delete(sr.active, room)
+	sc.Ended = time.Now()
+	sr.persist(sc)
+	sr.game.EventBus.UnwatchRoom(room, sr)
+	return sc
+}
+
+// Active returns the scene being recorded in room, or nil.
+func (sr *SceneRecorder) Active(room gamedb.DBRef) *gamedb.Scene {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.active[room]
+}
+
+// Get returns a scene by ID, or nil.
+func (sr *SceneRecorder) Get(id int) *gamedb.Scene {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.scenes[id]
+}
+
+// Delete removes a scene and its log. A scene still recording is stopped first.
+func (sr *SceneRecorder) Delete(id int) bool {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sc, ok := sr.scenes[id]
+	if !ok {
+		return false
+	}
+	if sr.active[sc.Room] == sc {
+		sr.stopLocked(sc.Room)
+	}
+	delete(sr.scenes, id)
+	if sr.game.Store != nil {
+		if err := sr.game.Store.DeleteScene(id); err != nil {
+			Logf(LogBugs, LevelError, "delete scene %d: %v", id, err)
+		}
+	}
+	return true
+}
+
+// Update applies fn to a scene under the recorder lock and persists it.
+func (sr *SceneRecorder) Update(sc *gamedb.Scene, fn func(sc *gamedb.Scene)) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	fn(sc)
+	sr.persist(sc)
+}
+
+// Readable returns the scenes player may read, oldest first.
+func (sr *SceneRecorder) Readable(player gamedb.DBRef) []*gamedb.Scene {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	var out []*gamedb.Scene
+	for _, sc := range sr.scenes {
+		if sceneCanRead(sc, player) {
+			out = append(out, sc)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// CanRead reports whether player may read a scene's log.
+func (sr *SceneRecorder) CanRead(sc *gamedb.Scene, player gamedb.DBRef) bool {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sceneCanRead(sc, player)
+}
+
+// sceneCanRead: participants, granted viewers, and anyone for a public scene.
+// Wizards get no special access; scene logs are private to those present.
+func sceneCanRead(sc *gamedb.Scene, player gamedb.DBRef) bool {
+	if sc.Public || sc.Owner == player {
+		return true
+	}
+	for _, ref := range sc.Participants {
+		if ref == player {
+			return true
+		}
+	}
+	for _, ref := range sc.Viewers {
+		if ref == player {
+			return true
+		}
+	}
+	return false
+}
+
+// Entries decrypts a scene's log.
+func (sr *SceneRecorder) Entries(sc *gamedb.Scene) ([]gamedb.SceneEntry, error) {
+	sr.mu.Lock()
+	blobs := append([][]byte(nil), sc.Log...)
+	sr.mu.Unlock()
+	entries := make([]gamedb.SceneEntry, 0, len(blobs))
+	for i, blob := range blobs {
+		entry, err := sr.unseal(sc.Room, blob)
+		if err != nil {
+			return nil, fmt.Errorf("scene %d line %d: %w", sc.ID, i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// PurgeExpired deletes finished scenes that ended more than retention ago.
+func (sr *SceneRecorder) PurgeExpired(retention time.Duration) int {
+	if retention <= 0 {
+		return 0
+	}
+	cutoff := time.Now().Add(-retention)
+	sr.mu.Lock()
+	var expired []int
+	for id, sc := range sr.scenes {
+		if !sc.Ended.IsZero() && sc.Ended.Before(cutoff) {
+			expired = append(expired, id)
+		}
+	}
+	sr.mu.Unlock()
+	for _, id := range expired {
+		sr.Delete(id)
+	}
+	return len(expired)
+}
+
+// Receive implements events.Subscriber. Called for events in watched rooms.
+func (sr *SceneRecorder) Receive(ev events.Event) {
+	if ev.Text == "" {
+		return
+	}
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sc, ok := sr.active[ev.Room]
+	if !ok {
+		return
+	}
+	joined := false
+	if obj, ok := sr.game.DB.Objects[ev.Source]; ok && obj.ObjType() == gamedb.TypePlayer {
+		joined = addSceneRef(&sc.Participants, ev.Source)
+	}
+	blob, err := sr.seal(sc.Room, gamedb.SceneEntry{
+		Time:   time.Now(),
+		Source: ev.Source,
+		Type:   ev.Type.String(),
+		Text:   ev.Text,
+	})
+	if err != nil {
+		Logf(LogBugs, LevelError, "scene %d: %v", sc.ID, err)
+		return
+	}
+	sc.Log = append(sc.Log, blob)
+	sr.persistLine(sc)
+	if len(sc.Log) >= sceneLogLimit {
+		sr.stopLocked(sc.Room)
+		sr.game.Conns.SendToRoom(sr.game.DB, sc.Room,
+			fmt.Sprintf("Scene %d has reached %d lines and is no longer recording.", sc.ID, sceneLogLimit))
+		return
+	}
+	if joined {
+		sr.persist(sc)
+	}
+}
+
+// Closed implements events.Subscriber.
+func (sr *SceneRecorder) Closed() bool {
+	return false
+}
+
+// addSceneRef adds ref to list, reporting whether it was not already there.
+func addSceneRef(list *[]gamedb.DBRef, ref gamedb.DBRef) bool {
+	for _, r := range *list {
+		if r == ref {
+			return false
+		}
+	}
+	*list = append(*list, ref)
+	return true
+}
+
+func removeSceneRef(list *[]gamedb.DBRef, ref gamedb.DBRef) bool {
+	for i, r := range *list {
+		if r == ref {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// sceneRetention returns how long finished scenes are kept (0 = forever).
+func (g *Game) sceneRetention() time.Duration {
+	if g.Conf == nil {
+		return 0
+	}
+	return time.Duration(g.Conf.SceneRetention) * 24 * time.Hour
+}
+
+// StartSceneRetention starts an hourly goroutine that purges expired scenes.
+func StartSceneRetention(g *Game) {
+	if g.Scenes == nil || g.sceneRetention() <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			var n int
+			g.Do(func() { n = g.Scenes.PurgeExpired(g.sceneRetention()) })
+			if n > 0 {
+				log.Printf("scenes: purged %d expired scenes", n)
+			}
+		}
+	}()
+}
+
+// --- +scene command ---
+
+// cmdScene implements +scene: start, stop and read back room scene logs.
+func cmdScene(g *Game, d *Descriptor, args string, switches []string) {
+	if g.Scenes == nil {
+		d.Send("Scene logging is not enabled.")
+		return
+	}
+	args = strings.TrimSpace(args)
+	room := g.PlayerLocation(d.Player)
+
+	switch {
+	case HasSwitch(switches, "start"):
+		if roomObj, ok := g.DB.Objects[room]; !ok || roomObj.ObjType() != gamedb.TypeRoom {
+			d.Send("Scenes can only be recorded in rooms.")
+			return
+		}
+		sc, err := g.Scenes.Start(room, d.Player, args)
+		if err != nil {
+			d.Send("A scene is already being recorded here.")
+			return
+		}
+		g.Conns.SendToRoom(g.DB, room, fmt.Sprintf("%s starts recording scene %d%s.",
+			g.PlayerName(d.Player), sc.ID, sceneTitleSuffix(sc)))
+	case HasSwitch(switches, "stop"):
+		sc := g.Scenes.Active(room)
+		if sc == nil {
+			d.Send("No scene is being recorded here.")
+			return
+		}
+		if sc.Owner != d.Player && !g.Controls(d.Player, room) {
+			d.Send("Permission denied.")
+			return
+		}
+		g.Scenes.Stop(room)
+		g.Conns.SendToRoom(g.DB, room, fmt.Sprintf("%s stops recording scene %d (%d lines).",
+			g.PlayerName(d.Player), sc.ID, len(sc.Log)))
+	case HasSwitch(switches, "list"):
+		sceneList(g, d)
+	case HasSwitch(switches, "view"):
+		sc := sceneLookup(g, d, args, false)
+		if sc == nil {
+			return
+		}
+		sceneView(g, d, sc)
+	case HasSwitch(switches, "allow"), HasSwitch(switches, "deny"):
+		idStr, name, _ := strings.Cut(args, "=")
+		sc := sceneLookup(g, d, idStr, true)
+		if sc == nil {
+			return
+		}
+		who := g.LookupPlayer(strings.TrimSpace(name))
+		if who == gamedb.Nothing {
+			d.Send("No such player.")
+			return
+		}
+		if HasSwitch(switches, "allow") {
+			g.Scenes.Update(sc, func(sc *gamedb.Scene) { addSceneRef(&sc.Viewers, who) })
+			d.Send(fmt.Sprintf("%s may now read scene %d.", g.PlayerName(who), sc.ID))
+			return
+		}
+		removed := false
+		g.Scenes.Update(sc, func(sc *gamedb.Scene) {
+			removed = removeSceneRef(&sc.Viewers, who) || removeSceneRef(&sc.Participants, who)
+		})
+		if !removed {
+			d.Send(fmt.Sprintf("%s has no access to scene %d.", g.PlayerName(who), sc.ID))
+			return
+		}
+		d.Send(fmt.Sprintf("%s may no longer read scene %d.", g.PlayerName(who), sc.ID))
+	case HasSwitch(switches, "public"), HasSwitch(switches, "private"):
+		sc := sceneLookup(g, d, args, true)
+		if sc == nil {
+			return
+		}
+		public := HasSwitch(switches, "public")
+		g.Scenes.Update(sc, func(sc *gamedb.Scene) { sc.Public = public })
+		if public {
+			d.Send(fmt.Sprintf("Scene %d is now public.", sc.ID))
+		} else {
+			d.Send(fmt.Sprintf("Scene %d is now private.", sc.ID))
+		}
+	case HasSwitch(switches, "delete"):
+		sc := sceneLookup(g, d, args, true)
+		if sc == nil {
+			return
+		}
+		g.Scenes.Delete(sc.ID)
+		d.Send(fmt.Sprintf("Scene %d deleted.", sc.ID))
+	default:
+		sc := g.Scenes.Active(room)
+		if sc == nil {
+			d.Send("No scene is being recorded here.")
+			return
+		}
+		d.Send(fmt.Sprintf("Scene %d%s is being recorded here by %s (%d lines).",
+			sc.ID, sceneTitleSuffix(sc), g.PlayerName(sc.Owner), len(sc.Log)))
+	}
+}
+
+func sceneTitleSuffix(sc *gamedb.Scene) string {
+	if sc.Title == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", sc.Title)
+}
+
+// sceneLookup parses a scene ID and checks access. manage requires the
+// scene's owner or a wizard; otherwise read access is enough.
+func sceneLookup(g *Game, d *Descriptor, idStr string, manage bool) *gamedb.Scene {
+	id, err := strconv.Atoi(strings.TrimSpace(idStr))
+	if err != nil {
+		d.Send("That's not a scene number.")
+		return nil
+	}
+	sc := g.Scenes.Get(id)
+	if sc == nil {
+		d.Send("No such scene.")
+		return nil
+	}
+	if manage {
+		if sc.Owner != d.Player && !Wizard(g, d.Player) {
+			d.Send("Permission denied.")
+			return nil
+		}
+	} else if !g.Scenes.CanRead(sc, d.Player) {
+		d.Send("Permission denied.")
+		return nil
+	}
+	return sc
+}
+
+func sceneList(g *Game, d *Descriptor) {
+	scenes := g.Scenes.Readable(d.Player)
+	if len(scenes) == 0 {
+		d.Send("You have no recorded scenes.")
+		return
+	}
+	d.Send(fmt.Sprintf("%-5s %-16s %-20s %6s  %s", "ID", "Started", "Room", "Lines", "Title"))
+	for _, sc := range scenes {
+		status := sc.Title
+		if sc.Ended.IsZero() {
+			status = "(recording) " + status
+		}
+		d.Send(fmt.Sprintf("%-5d %-16s %-20s %6d  %s", sc.ID, sc.Started.Format("2006-01-02 15:04"),
+			sceneRoomName(g, sc.Room), len(sc.Log), status))
+	}
+}
+
+func sceneRoomName(g *Game, room gamedb.DBRef) string {
+	name := g.ObjName(room)
+	if len(name) > 20 {
+		name = name[:20]
+	}
+	return name
+}
+
+func sceneView(g *Game, d *Descriptor, sc *gamedb.Scene) {
+	entries, err := g.Scenes.Entries(sc)
+	if err != nil {
+		Logf(LogBugs, LevelError, "%v", err)
+		d.Send("That scene's log could not be read.")
+		return
+	}
+	d.Send(fmt.Sprintf("--- Scene %d%s in %s, %s ---", sc.ID, sceneTitleSuffix(sc),
+		g.ObjName(sc.Room), sc.Started.Format("2006-01-02 15:04")))
+	for _, e := range entries {
+		d.Send(fmt.Sprintf("[%s] %s", e.Time.Format("15:04"), e.Text))
+	}
+	d.Send(fmt.Sprintf("--- End of scene %d (%d lines) ---", sc.ID, len(entries)))
+}
+
+// --- REST: scene download ---
+
+// handleScenes lists the scenes the caller may read.
+func (ws *WebServer) handleScenes(w http.ResponseWriter, r *http.Request) {
+	claims := ClaimsFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if ws.game.Scenes == nil {
+		http.Error(w, `{"error":"scene logging is not enabled"}`, http.StatusNotFound)
+		return
+	}
+	type sceneInfo struct {
+		ID      int       `json:"id"`
+		Room    string    `json:"room"`
+		Title   string    `json:"title"`
+		Owner   string    `json:"owner"`
+		Started time.Time `json:"started"`
+		Ended   time.Time `json:"ended,omitzero"`
+		Lines   int       `json:"lines"`
+		Public  bool      `json:"public"`
+	}
+	list := []sceneInfo{}
+	for _, sc := range ws.game.Scenes.Readable(claims.PlayerRef) {
+		list = append(list, sceneInfo{
+			ID:      sc.ID,
+			Room:    fmt.Sprintf("#%d", sc.Room),
+			Title:   sc.Title,
+			Owner:   ws.game.PlayerName(sc.Owner),
+			Started: sc.Started,
+			Ended:   sc.Ended,
+			Lines:   len(sc.Log),
+			Public:  sc.Public,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleSceneLog returns a decrypted scene log as text (default) or JSON.
+func (ws *WebServer) handleSceneLog(w http.ResponseWriter, r *http.Request) {
+	claims := ClaimsFromContext(r.Context())
+	if claims == nil {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	if ws.game.Scenes == nil {
+		http.Error(w, `{"error":"scene logging is not enabled"}`, http.StatusNotFound)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, `{"error":"invalid scene id"}`, http.StatusBadRequest)
+		return
+	}
+	sc := ws.game.Scenes.Get(id)
+	if sc == nil || !ws.game.Scenes.CanRead(sc, claims.PlayerRef) {
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		return
+	}
+	entries, err := ws.game.Scenes.Entries(sc)
+	if err != nil {
+		Logf(LogBugs, LevelError, "%v", err)
+		http.Error(w, `{"error":"scene log could not be read"}`, http.StatusInternalServerError)
+		return
+	}
+	if strings.EqualFold(r.URL.Query().Get("format"), "json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"scene-%d.txt\"", sc.ID))
+	for _, e := range entries {
+		fmt.Fprintf(w, "[%s] %s\n", e.Time.Format("2006-01-02 15:04:05"), eval.StripAnsi(e.Text))
+	}
+}
@@ -0,0 +1,487 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/eval/functions"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// searchSpec is a parsed @search / search() specification:
+// [<player>] [<class>=<restriction>[,<low>[,<high>]]]
+type searchSpec struct {
+	owner     gamedb.DBRef      // Nothing = any owner
+	objType   gamedb.ObjectType // -1 = any type
+	name      string            // name prefix (lowercased, may hold wildcards)
+	ref       gamedb.DBRef      // zone= / parent= target
+	class     string
+	flagsOn   [2]int
+	flagsOff  [2]int
+	powWord   int
+	powBit    int
+	eval      string // eval= predicate, ## replaced by each dbref
+	ueval     string // ueval= obj/attr, called with each dbref as %0
+	low, high gamedb.DBRef
+}
+
+// canSearchAll reports whether player may search objects owned by anyone.
+func (g *Game) canSearchAll(player gamedb.DBRef) bool {
+	if Wizard(g, player) {
+		return true
+	}
+	o, ok := g.DB.Objects[player]
+	return ok && o.HasPower(0, gamedb.PowSearch)
+}
+
+// splitSearchArgs splits s at commas outside (), [] and {}, so eval=
+// predicates can contain function calls. At most n pieces are returned.
+func splitSearchArgs(s string, n int) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s) && len(parts) < n-1; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseSearchType maps a type word to an object type.
+func parseSearchType(s string) (gamedb.ObjectType, bool) {
+	switch strings.ToLower(s) {
+	case "room", "rooms":
+		return gamedb.TypeRoom, true
+	case "exit", "exits":
+		return gamedb.TypeExit, true
+	case "thing", "things", "object", "objects":
+		return gamedb.TypeThing, true
+	case "player", "players":
+		return gamedb.TypePlayer, true
+	case "garbage":
+		return gamedb.TypeGarbage, true
+	}
+	return -1, false
+}
+
+// parseSearch parses a search specification as player, the way C
+// TinyMUSH's search_setup does. Errors are messages for @search.
+func (g *Game) parseSearch(player gamedb.DBRef, args string) (*searchSpec, error) {
+	sp := &searchSpec{owner: player, objType: -1, ref: gamedb.Nothing, high: gamedb.DBRef(len(g.DB.Objects) - 1)}
+	args = strings.TrimSpace(args)
+
+	var who, restrict string
+	if eq := strings.IndexByte(args, '='); eq >= 0 {
+		left := strings.Fields(args[:eq])
+		if len(left) == 0 {
+			return nil, errors.New("Missing search class.")
+		}
+		sp.class = strings.ToLower(left[len(left)-1])
+		who = strings.Join(left[:len(left)-1], " ")
+		parts := splitSearchArgs(args[eq+1:], 3)
+		restrict = strings.TrimSpace(parts[0])
+		for i, p := range parts[1:] {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimPrefix(p, "#"))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("Invalid dbref range: %s.", p)
+			}
+			if i == 0 {
+				sp.low = gamedb.DBRef(n)
+			} else if gamedb.DBRef(n) < sp.high {
+				sp.high = gamedb.DBRef(n)
+			}
+		}
+	} else {
+		who = args
+	}
+
+	// Player restriction. Without one, those allowed to search everyone do.
+	all := g.canSearchAll(player)
+	named := who != "" && !strings.EqualFold(who, "all")
+	switch {
+	case who == "" && all, strings.EqualFold(who, "all") && all:
+		sp.owner = gamedb.Nothing
+	case who == "", strings.EqualFold(who, "me"), strings.EqualFold(who, "all"):
+	default:
+		target := LookupPlayer(g.DB, who)
+		if target == gamedb.Nothing {
+			return nil, errors.New("No such player.")
+		}
+		if target != player && !all {
+			return nil, errors.New("You need a search warrant to do that!")
+		}
+		sp.owner = target
+	}
+
+	switch sp.class {
+	case "":
+	case "type":
+		t, ok := parseSearchType(restrict)
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown type.", restrict)
+		}
+		sp.objType = t
+	case "name":
+		sp.name = strings.ToLower(restrict)
+	case "rooms", "exits", "things", "objects", "players":
+		sp.objType, _ = parseSearchType(sp.class)
+		sp.name = strings.ToLower(restrict)
+	case "zone", "parent":
+		sp.ref = g.ResolveRef(player, restrict)
+		if _, ok := g.DB.Objects[sp.ref]; !ok {
+			return nil, errors.New("I don't see that here.")
+		}
+	case "power":
+		found := false
+		for _, pe := range powerNames {
+			if strings.EqualFold(pe.Name, restrict) {
+				sp.powWord, sp.powBit, found = pe.Word, pe.Bit, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s: no such power.", restrict)
+		}
+	case "flags":
+		if err := sp.parseFlags(restrict); err != nil {
+			return nil, err
+		}
+	case "eval", "evaluate", "eplayer", "eroom", "eexit", "ething", "eobject":
+		if sp.class != "eval" && sp.class != "evaluate" {
+			sp.objType, _ = parseSearchType(sp.class[1:])
+		}
+		sp.eval = restrict
+	case "ueval", "uplayer", "uroom", "uexit", "uthing", "uobject":
+		if sp.class != "ueval" {
+			sp.objType, _ = parseSearchType(sp.class[1:])
+		}
+		slash := strings.IndexByte(restrict, '/')
+		if slash < 0 || g.ResolveRef(player, restrict[:slash]) == gamedb.Nothing {
+			return nil, errors.New("No match.")
+		}
+		sp.ueval = restrict
+	default:
+		return nil, fmt.Errorf("%s: unknown search class.", sp.class)
+	}
+
+	// Lists of players aren't restricted to your own objects.
+	if sp.objType == gamedb.TypePlayer && !named {
+		sp.owner = gamedb.Nothing
+	}
+	return sp, nil
+}
+
+// parseFlags reads a flags= restriction: flag letters, each optionally
+// preceded by ! to require it be clear. P, R and E select a type.
+func (sp *searchSpec) parseFlags(s string) error {
+	for i := 0; i < len(s); i++ {
+		negate := false
+		if s[i] == '!' && i+1 < len(s) {
+			negate = true
+			i++
+		}
+		if t, ok := map[byte]gamedb.ObjectType{'P': gamedb.TypePlayer, 'R': gamedb.TypeRoom, 'E': gamedb.TypeExit}[s[i]]; ok && !negate {
+			sp.objType = t
+			continue
+		}
+		found := false
+		for _, fl := range flagLetters {
+			if fl.Letter != s[i] {
+				continue
+			}
+			if negate {
+				sp.flagsOff[fl.Word] |= fl.Bit
+			} else {
+				sp.flagsOn[fl.Word] |= fl.Bit
+			}
+			found = true
+			break
+		}
+		if !found {
+			return fmt.Errorf("%c: unknown flag.", s[i])
+		}
+	}
+	return nil
+}
+
+// matches reports whether obj meets every restriction except eval=.
+func (sp *searchSpec) matches(obj *gamedb.Object) bool {
+	if sp.objType == gamedb.TypeGarbage {
+		return obj.ObjType() == gamedb.TypeGarbage || obj.IsGoing()
+	}
+	if obj.IsGoing() || obj.ObjType() == gamedb.TypeGarbage {
+		return false
+	}
+	if sp.owner != gamedb.Nothing && obj.Owner != sp.owner {
+		return false
+	}
+	if sp.objType >= 0 && obj.ObjType() != sp.objType {
+		return false
+	}
+	if sp.name != "" && !wildMatchSimple(sp.name+"*", strings.ToLower(obj.Name)) {
+		return false
+	}
+	switch sp.class {
+	case "zone":
+		return obj.Zone == sp.ref
+	case "parent":
+		return obj.Parent == sp.ref
+	case "power":
+		return obj.HasPower(sp.powWord, sp.powBit)
+	}
+	for w := 0; w < 2; w++ {
+		if obj.Flags[w]&sp.flagsOn[w] != sp.flagsOn[w] || obj.Flags[w]&sp.flagsOff[w] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Search runs a search for player and returns the matching dbrefs in
+// order. exec evaluates eval= predicates; nil evaluates them as player.
+func (g *Game) Search(player gamedb.DBRef, args string, exec func(string) string) ([]gamedb.DBRef, error) {
+	sp, err := g.parseSearch(player, args)
+	if err != nil {
+		return nil, err
+	}
+	return g.runSearch(player, sp, exec), nil
+}
+
+// runSearch returns the objects sp selects, evaluating eval= and ueval=
+// predicates with exec (as player when exec is nil).
+func (g *Game) runSearch(player gamedb.DBRef, sp *searchSpec, exec func(string) string) []gamedb.DBRef {
+	if (sp.eval != "" || sp.ueval != "") && exec == nil {
+		ctx := MakeEvalContextForObj(g, player, player, func(c *eval.EvalContext) {
+			functions.RegisterAll(c)
+		})
+		exec = func(s string) string {
+			return ctx.Exec(s, eval.EvFCheck|eval.EvEval|eval.EvStrip, nil)
+		}
+	}
+	var refs []gamedb.DBRef
+	for ref := sp.low; ref <= sp.high; ref++ {
+		obj, ok := g.DB.Objects[ref]
+		if !ok || !sp.matches(obj) {
+			continue
+		}
+		var expr string
+		switch {
+		case sp.eval != "":
+			expr = strings.ReplaceAll(sp.eval, "##", fmt.Sprintf("#%d", ref))
+		case sp.ueval != "":
+			expr = fmt.Sprintf("u(%s,#%d)", sp.ueval, ref)
+		}
+		if expr != "" {
+			result := strings.TrimSpace(exec(expr))
+			if result == "" || result == "0" || strings.HasPrefix(result, "#-") {
+				continue
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// SearchObjects implements search() and lsearch(): a space-separated list
+// of dbrefs, or "#-1 <ERROR>".
+func (g *Game) SearchObjects(player gamedb.DBRef, args string, exec func(string) string) string {
+	refs, err := g.Search(player, args, exec)
+	if err != nil {
+		return "#-1 " + strings.ToUpper(strings.TrimSuffix(err.Error(), "."))
+	}
+	out := make([]string, len(refs))
+	for i, ref := range refs {
+		out[i] = fmt.Sprintf("#%d", ref)
+	}
+	return strings.Join(out, " ")
+}
+
+// findPageSize is how many matches @find shows before pausing for @find/more.
+const findPageSize = 100
+
+// findCursor is a paused @find, resumed by @find/more.
+type findCursor struct {
+	name  string
+	next  gamedb.DBRef
+	high  gamedb.DBRef
+	owner gamedb.DBRef // Nothing = any owner
+}
+
+// searchCost returns search_cost, what whole-database scans cost.
+func (g *Game) searchCost() int {
+	if g.Conf == nil {
+		return 0
+	}
+	return g.Conf.SearchCost
+}
+
+// notEnoughMoney tells d they can't afford a command.
+func (g *Game) notEnoughMoney(d *Descriptor) {
+	coins := "pennies"
+	if g.Conf != nil {
+		coins = g.Conf.MoneyNamePlural
+	}
+	d.Send(fmt.Sprintf("You don't have enough %s.", coins))
+}
+
+// cmdFind implements @find <name>[,<low>[,<high>]]: the rooms, things and
+// players you own whose names match. Those who may search anyone see
+// everything. Matches are shown findPageSize at a time; @find/more
+// continues where the last page stopped, at no further cost.
+func cmdFind(g *Game, d *Descriptor, args string, switches []string) {
+	if HasSwitch(switches, "more") {
+		cur := d.findMore
+		if cur == nil {
+			d.Send("There is no @find to continue.")
+			return
+		}
+		findPage(g, d, cur)
+		return
+	}
+
+	parts := strings.SplitN(args, ",", 3)
+	cur := &findCursor{
+		name:  strings.ToLower(strings.TrimSpace(parts[0])),
+		high:  gamedb.DBRef(len(g.DB.Objects) - 1),
+		owner: ResolveOwner(g, d.Player),
+	}
+	for i, p := range parts[1:] {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(p, "#"))
+		if err != nil || n < 0 {
+			d.Send(fmt.Sprintf("Invalid dbref range: %s.", p))
+			return
+		}
+		if i == 0 {
+			cur.next = gamedb.DBRef(n)
+		} else if gamedb.DBRef(n) < cur.high {
+			cur.high = gamedb.DBRef(n)
+		}
+	}
+	if g.canSearchAll(d.Player) {
+		cur.owner = gamedb.Nothing
+	}
+	if !g.payFor(d.Player, g.searchCost()) {
+		g.notEnoughMoney(d)
+		return
+	}
+	findPage(g, d, cur)
+}
+
+// findPage lists the next page of cur's matches, and saves cur on d if
+// there are more.
+func findPage(g *Game, d *Descriptor, cur *findCursor) {
+	d.findMore = nil
+	wild := strings.ContainsAny(cur.name, "*?")
+	shown := 0
+	for ; cur.next <= cur.high; cur.next++ {
+		obj, ok := g.DB.Objects[cur.next]
+		if !ok || obj.IsGoing() || obj.ObjType() == gamedb.TypeExit || obj.ObjType() == gamedb.TypeGarbage {
+			continue
+		}
+		if cur.owner != gamedb.Nothing && obj.Owner != cur.owner {
+			continue
+		}
+		name := strings.ToLower(obj.Name)
+		if cur.name != "" && ((wild && !wildMatchSimple(cur.name, name)) || (!wild && !stringMatchWord(name, cur.name))) {
+			continue
+		}
+		if shown == findPageSize {
+			d.findMore = cur
+			d.Send("*** More matches: type '@find/more' to continue. ***")
+			return
+		}
+		d.Send(fmt.Sprintf("%s(#%d%s)", obj.Name, obj.DBRef, typeChar(obj.ObjType())))
+		shown++
+	}
+	d.Send("***End of List***")
+}
+
+// cmdSearch implements @search [<player>] [<class>=<restriction>[,<low>[,<high>]]].
+// Results are listed by type, as in C TinyMUSH. It costs search_cost.
+func cmdSearch(g *Game, d *Descriptor, args string, _ []string) {
+	sp, err := g.parseSearch(d.Player, args)
+	if err != nil {
+		d.Send(err.Error())
+		return
+	}
+	if !g.payFor(d.Player, g.searchCost()) {
+		g.notEnoughMoney(d)
+		return
+	}
+	refs := g.runSearch(d.Player, sp, nil)
+	groups := []struct {
+		t     gamedb.ObjectType
+		title string
+	}{
+		{gamedb.TypeRoom, "ROOMS:"},
+		{gamedb.TypeExit, "EXITS:"},
+		{gamedb.TypeThing, "THINGS:"},
+		{gamedb.TypePlayer, "PLAYERS:"},
+		{gamedb.TypeGarbage, "GARBAGE:"},
+	}
+	counts := make(map[gamedb.ObjectType]int)
+	for _, grp := range groups {
+		for _, ref := range refs {
+			obj := g.DB.Objects[ref]
+			t := obj.ObjType()
+			if obj.IsGoing() {
+				t = gamedb.TypeGarbage
+			}
+			if t != grp.t {
+				continue
+			}
+			if counts[t] == 0 {
+				d.Send("")
+				d.Send(grp.title)
+			}
+			counts[t]++
+			line := fmt.Sprintf("%s(#%d%s)", obj.Name, ref, typeChar(obj.ObjType()))
+			switch t {
+			case gamedb.TypeExit:
+				line += fmt.Sprintf(" [from %s to %s]", searchRefName(g, obj.Exits), searchRefName(g, obj.Location))
+			case gamedb.TypeThing:
+				line += fmt.Sprintf(" [owner: %s]", searchRefName(g, obj.Owner))
+			}
+			d.Send(line)
+		}
+	}
+	if len(refs) == 0 {
+		d.Send("Nothing found.")
+		return
+	}
+	d.Send("")
+	d.Send(fmt.Sprintf("Found:  Rooms...%d  Exits...%d  Objects...%d  Players...%d  Garbage...%d",
+		counts[gamedb.TypeRoom], counts[gamedb.TypeExit], counts[gamedb.TypeThing],
+		counts[gamedb.TypePlayer], counts[gamedb.TypeGarbage]))
+}
+
+// searchRefName formats ref as Name(#ref) for @search output.
+func searchRefName(g *Game, ref gamedb.DBRef) string {
+	if ref == gamedb.Nothing {
+		return "NOTHING"
+	}
+	return fmt.Sprintf("%s(#%d)", g.ObjName(ref), ref)
+}
@@ -10,8 +10,10 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/crystal-mush/gotinymush/pkg/eval"
 	"github.com/crystal-mush/gotinymush/pkg/gamedb"
 	"github.com/crystal-mush/gotinymush/pkg/oob"
 )
@@ -48,6 +50,8 @@ type Server struct {
 	listener    net.Listener
 	tlsListener net.Listener
 	webServer   *WebServer
+	cleartextUp atomic.Bool // cleartext listener is accepting connections
+	tlsUp       atomic.Bool // TLS listener is accepting connections
 }
 
 // NewServer creates a new server instance.
@@ -114,7 +118,9 @@ func (s *Server) Start() error {
 			}
 			s.listener = ln
 			log.Printf("Listening (cleartext) on port %d", s.Config.Port)
+			s.cleartextUp.Store(true)
 			s.acceptLoop(ln)
+			s.cleartextUp.Store(false)
 		}()
 	}
 
@@ -135,7 +141,9 @@ func (s *Server) Start() error {
 			}
 			s.tlsListener = ln
 			log.Printf("Listening (TLS) on port %d", s.Config.TLSPort)
+			s.tlsUp.Store(true)
 			s.acceptLoop(ln)
+			s.tlsUp.Store(false)
 		}()
 	}
 
@@ -192,6 +200,29 @@ func (s *Server) acceptLoop(ln net.Listener) {
 	}
 }
 
+// ListenerStatus reports, for the cleartext and TLS game ports, whether
+// each is configured and whether it is accepting connections.
+func (s *Server) ListenerStatus() map[string]any {
+	return map[string]any{
+		"cleartext": map[string]any{
+			"enabled":   s.Config.Cleartext,
+			"port":      s.Config.Port,
+			"listening": s.cleartextUp.Load(),
+		},
+		"tls": map[string]any{
+			"enabled":   s.Config.TLS,
+			"port":      s.Config.TLSPort,
+			"listening": s.tlsUp.Load(),
+		},
+	}
+}
+
+// ListenersUp reports whether every configured game port is accepting
+// connections.
+func (s *Server) ListenersUp() bool {
+	return (!s.Config.Cleartext || s.cleartextUp.Load()) && (!s.Config.TLS || s.tlsUp.Load())
+}
+
 // Stop closes all active listeners.
 func (s *Server) Stop() {
 	if s.listener != nil {
@@ -209,47 +240,57 @@ func (s *Server) Stop() {
 
 // handleConnection manages a single client connection lifecycle.
 func (s *Server) handleConnection(conn net.Conn) {
+	if !s.screenConnection(conn) {
+		return
+	}
 	id := s.Game.Conns.NextID()
 	d := NewDescriptor(id, conn)
 	s.Game.Conns.Add(d)
 
-	log.Printf("[%d] New connection from %s", d.ID, d.Addr)
+	Logf(LogConnections, LevelInfo, "[%d] New connection from %s", d.ID, d.Addr)
 
 	// OOB protocol negotiation (GMCP/MSDP/MSSP) with 1-second timeout.
 	// Non-OOB clients simply don't respond and we move on.
 	caps := oob.Negotiate(conn, 1*time.Second)
+	d.Color = clientColorDepth(caps)
 	if caps.HasAny() {
 		d.OOB = caps
 		log.Printf("[%d] OOB negotiated: GMCP=%v MSDP=%v MSSP=%v", d.ID, caps.GMCP, caps.MSDP, caps.MSSP)
 	}
 
-	// Send MSSP response immediately after negotiation
-	if caps.MSSP {
-		d.SendRaw(oob.EncodeMSSP(s.buildMSSPData()))
-	}
+	// Negotiation is done; from here on output is buffered so a slow
+	// client can't block broadcasts to everyone else.
+	d.StartWriter(s.Game.outputLimit)
 
 	defer func() {
-		s.Game.DisconnectPlayer(d)
+		s.Game.Do(func() { s.Game.DisconnectPlayer(d) })
 		s.Game.Conns.Remove(d)
 		d.Close()
-		log.Printf("[%d] Connection closed from %s", d.ID, d.Addr)
+		Logf(LogConnections, LevelInfo, "[%d] Connection closed from %s", d.ID, d.Addr)
 	}()
 
-	// Send Pueblo version string if enabled (before welcome screen)
-	if s.Game.Conf != nil && s.Game.Conf.PuebloEnabled && s.Game.Conf.PuebloVersion != "" {
-		d.Send(s.Game.Conf.PuebloVersion)
-	}
+	s.Game.Do(func() {
+		// Send MSSP response immediately after negotiation
+		if caps.MSSP {
+			d.SendRaw(oob.EncodeMSSP(s.buildMSSPData()))
+		}
 
-	// Send welcome screen
-	if s.Game.Texts != nil {
-		if txt := s.Game.Texts.GetConnect(); txt != "" {
-			d.SendNoNewline(txt)
+		// Send Pueblo version string if enabled (before welcome screen)
+		if s.Game.Conf != nil && s.Game.Conf.PuebloEnabled && s.Game.Conf.PuebloVersion != "" {
+			d.Send(s.Game.Conf.PuebloVersion)
+		}
+
+		// Send welcome screen
+		if s.Game.Texts != nil {
+			if txt := s.Game.Texts.GetConnect(); txt != "" {
+				d.SendNoNewline(txt)
+			} else {
+				d.SendNoNewline(s.Config.WelcomeText)
+			}
 		} else {
 			d.SendNoNewline(s.Config.WelcomeText)
 		}
-	} else {
-		d.SendNoNewline(s.Config.WelcomeText)
-	}
+	})
 
 	// Main read loop
 	scanner := bufio.NewScanner(d.Conn)
@@ -260,51 +301,85 @@ func (s *Server) handleConnection(conn net.Conn) {
 			return
 		}
 
-		line := scanner.Text()
-		d.BytesRecv += len(line) + 1 // +1 for newline
+		raw := scanner.Text()
 		// Strip telnet control sequences (IAC sequences)
-		line = stripTelnet(line)
-		line = strings.TrimRight(line, "\r\n")
-		d.LastCmd = time.Now()
-		if d.State == ConnConnected {
-			d.CmdCount++
+		line := strings.TrimRight(stripTelnet(raw), "\r\n")
+		s.Game.Do(func() { s.handleInput(d, line, len(raw)+1) }) // +1 for newline
+
+		if d.IsClosed() {
+			return
 		}
+	}
+}
 
-		if d.State == ConnLogin {
-			s.handleLoginCommand(d, line)
-		} else {
-			// Clear AutoDark tracking flag but keep DARK set —
-			// player must manually @set me=!DARK to become visible.
-			if d.AutoDark {
-				d.AutoDark = false
-			}
-			log.Printf("[%d] CMD state=%d player=#%d input=%q", d.ID, d.State, d.Player, line)
-			if d.ProgData != nil {
-				if strings.HasPrefix(line, "|") {
-					// Pipe escape: execute remainder as normal command
-					DispatchCommand(s.Game, d, line[1:])
-					// Re-send prompt if still in program mode
-					if d.ProgData != nil {
-						d.SendNoNewline(progPrompt)
-					}
-				} else if strings.EqualFold(strings.TrimSpace(line), "@quitprogram") {
-					// Allow @quitprogram to work normally
-					DispatchCommand(s.Game, d, line)
-				} else {
-					// Feed input to program handler
-					s.Game.HandleProgInput(d, line)
+// handleInput runs one line of input from a telnet connection. The world
+// lock must be held.
+func (s *Server) handleInput(d *Descriptor, line string, size int) {
+	d.noteReceived(size)
+	d.LastCmd = s.Game.Now()
+	if d.State == ConnConnected {
+		d.CmdCount++
+	}
+
+	if d.State == ConnLogin {
+		s.handleLoginCommand(d, line)
+	} else if !outputFix(d, line) {
+		if d.OutputPrefix != "" {
+			d.Send(d.OutputPrefix)
+		}
+		// Clear AutoDark tracking flag but keep DARK set —
+		// player must manually @set me=!DARK to become visible.
+		if d.AutoDark {
+			d.AutoDark = false
+		}
+		s.Game.idleUndark(d)
+		s.Game.logInput(d, line)
+		if d.ProgData != nil {
+			if strings.HasPrefix(line, "|") {
+				// Pipe escape: execute remainder as normal command
+				DispatchCommand(s.Game, d, line[1:])
+				// Re-send prompt if still in program mode
+				if d.ProgData != nil {
+					s.Game.sendProgPrompt(d)
 				}
-			} else {
+			} else if isQuitProgram(line) {
+				// Allow @quitprogram to work normally
 				DispatchCommand(s.Game, d, line)
+			} else {
+				// Feed input to program handler
+				s.Game.HandleProgInput(d, line)
 			}
+		} else {
+			DispatchCommand(s.Game, d, line)
 		}
-
-		if d.IsClosed() {
-			return
+		if d.OutputSuffix != "" {
+			d.Send(d.OutputSuffix)
 		}
 	}
 }
 
+// clientColorDepth picks the color depth to render for a telnet client from
+// its TTYPE/MTTS replies. Clients that say nothing get basic 16-color ANSI.
+func clientColorDepth(caps *oob.Capabilities) eval.ColorDepth {
+	switch {
+	case caps.TrueColor():
+		return eval.ColorTrue
+	case caps.Xterm256():
+		return eval.Color256
+	case caps.MTTS != 0 && !caps.Ansi():
+		return eval.ColorNone
+	}
+	return eval.Color16
+}
+
+// outputLimit returns the configured per-connection output_limit in bytes.
+func (g *Game) outputLimit() int {
+	if g.Conf == nil {
+		return 0
+	}
+	return g.Conf.OutputLimit
+}
+
 // buildMSSPData returns the MSSP key-value pairs for this server.
 func (s *Server) buildMSSPData() map[string]string {
 	mudName := "GoTinyMUSH"
@@ -338,6 +413,21 @@ func (s *Server) buildMSSPData() map[string]string {
 	return data
 }
 
+// loginInfoCommands are the commands that work from the login screen, if
+// login_who is on, for listing bots and people deciding whether to
+// connect.
+var loginInfoCommands = map[string]CommandHandler{
+	"WHO":   cmdWho,
+	"DOING": cmdDoing,
+	"INFO":  cmdMudInfo,
+}
+
+// loginWho reports whether login_who lets WHO, DOING and INFO run before
+// connecting.
+func (g *Game) loginWho() bool {
+	return g.Conf == nil || g.Conf.LoginWho
+}
+
 // handleLoginCommand processes pre-login commands.
 func (s *Server) handleLoginCommand(d *Descriptor, input string) {
 	input = strings.TrimSpace(input)
@@ -360,8 +450,17 @@ func (s *Server) handleLoginCommand(d *Descriptor, input string) {
 		d.Close()
 		return
 	}
-	if upper == "WHO" {
-		s.Game.ShowWho(d)
+	if outputFix(d, input) {
+		return
+	}
+	if cmd, ok := loginInfoCommands[upper]; ok && s.Game.loginWho() {
+		if d.OutputPrefix != "" {
+			d.Send(d.OutputPrefix)
+		}
+		cmd(s.Game, d, "", nil)
+		if d.OutputSuffix != "" {
+			d.Send(d.OutputSuffix)
+		}
 		return
 	}
 	if strings.HasPrefix(upper, "PUEBLOCLIENT") {
@@ -396,6 +495,9 @@ func (s *Server) handleLoginCommand(d *Descriptor, input string) {
 	case strings.HasPrefix(command, "cr"): // create
 		s.handleCreate(d, user, password)
 
+	case strings.HasPrefix(command, "reg"): // register <name> <email>
+		s.handleRegister(d, user, password)
+
 	default:
 		d.Send("Welcome to GoTinyMUSH. Commands: connect, create, WHO, QUIT")
 	}
@@ -409,21 +511,20 @@ func (s *Server) handleConnect(d *Descriptor, user, password string, dark bool)
 		return
 	}
 
-	player := LookupPlayer(s.Game.DB, user)
-	if player == gamedb.Nothing {
-		d.Send("Either that player does not exist, or has a different password.")
-		d.Retries--
-		if d.Retries <= 0 {
-			d.Send("Too many failed attempts. Disconnecting.")
-			d.Close()
-		}
+	if s.Game.LoginLocked(d.Addr) {
+		d.Send("Too many failed logins from your site. Please try again later.")
+		d.Close()
 		return
 	}
 
-	if !CheckPassword(s.Game.DB, player, password) {
+	player := LookupPlayer(s.Game.DB, user)
+	if player == gamedb.Nothing || !CheckPassword(s.Game.DB, player, password) {
 		d.Send("Either that player does not exist, or has a different password.")
 		d.Retries--
-		if d.Retries <= 0 {
+		if s.Game.NoteLoginFailure(d.Addr) {
+			d.Send("Too many failed logins from your site. Disconnecting.")
+			d.Close()
+		} else if d.Retries <= 0 {
 			d.Send("Too many failed attempts. Disconnecting.")
 			d.Close()
 		}
@@ -441,48 +542,45 @@ func (s *Server) handleConnect(d *Descriptor, user, password string, dark bool)
 	// Normal connect: clear DARK flag
 	if dark && (Wizard(s.Game, player) || player == gamedb.DBRef(1)) {
 		playerObj.Flags[0] |= gamedb.FlagDark
-		log.Printf("[%d] Player %s(#%d) DARK-connected from %s", d.ID, playerObj.Name, player, d.Addr)
+		Logf(LogConnections, LevelInfo, "[%d] Player %s(#%d) DARK-connected from %s", d.ID, playerObj.Name, player, d.Addr)
 	} else {
 		playerObj.Flags[0] &^= gamedb.FlagDark
-		log.Printf("[%d] Player %s(#%d) connected from %s", d.ID, playerObj.Name, player, d.Addr)
+		Logf(LogConnections, LevelInfo, "[%d] Player %s(#%d) connected from %s", d.ID, playerObj.Name, player, d.Addr)
 	}
 
 	d.Send(fmt.Sprintf("Welcome back, %s!", playerObj.Name))
-
-	// Show MOTD if available
-	if s.Game.Texts != nil {
-		if txt := s.Game.Texts.GetMotd(); txt != "" {
-			d.SendNoNewline(txt)
-		}
-		// Show wizard MOTD if player is a wizard
-		if Wizard(s.Game, d.Player) {
-			if txt := s.Game.Texts.GetWizMotd(); txt != "" {
-				d.SendNoNewline(txt)
-			}
-		}
+	if last := s.Game.noteLogin(d); last != "" {
+		d.Send(last)
 	}
 
-	// Announce to room (suppress if dark-connected)
+	// Show the MOTDs (see motd.go)
+	s.Game.sendLoginMotd(d)
+
+	// Announce to room (suppress if dark-connected or hidden)
 	loc := playerObj.Location
-	if !d.AutoDark {
+	if !d.AutoDark && !Hidden(s.Game, player) {
 		s.Game.Conns.SendToRoomExcept(s.Game.DB, loc, player,
 			fmt.Sprintf("%s has connected.", playerObj.Name))
 	}
 
+	if len(s.Game.Conns.GetByPlayer(player)) == 1 {
+		s.Game.announceChannels(player, "connected")
+	}
+
 	// Show current room
 	s.Game.ShowRoom(d, loc)
 
-	// Announce unread mail
+	// Announce waiting mail
 	if s.Game.Mail != nil {
-		total, unread, _ := s.Game.Mail.CountMessages(player)
-		if total > 0 && unread > 0 {
-			d.Send(fmt.Sprintf("You have %d unread mail message(s). Type @mail to read.", unread))
-		}
+		s.Game.mailLoginSummary(d)
 	}
 
 	// Fire ACONNECT triggers
-	connCount := len(s.Game.Conns.GetByPlayer(player))
-	s.Game.FireConnectAttr(player, connCount, 39) // A_ACONNECT = 39
+	reason := "connect"
+	if dark {
+		reason = "cd"
+	}
+	s.Game.fireAconnect(d, reason)
 }
 
 // handleCreate creates a new player and logs them in.
@@ -492,56 +590,29 @@ func (s *Server) handleCreate(d *Descriptor, user, password string) {
 		return
 	}
 
-	// Check if name already exists
-	if LookupPlayer(s.Game.DB, user) != gamedb.Nothing {
-		d.Send("That name is already taken.")
-		return
-	}
-
-	// Validate name
-	if len(user) < 2 {
-		d.Send("That name is too short.")
-		return
-	}
-	for _, ch := range user {
-		if ch == '"' || ch == ';' {
-			d.Send("That name contains illegal characters.")
-			return
+	if s.Game.RegistrationRequired() || s.Game.SiteRuleFor(d.Addr, gamedb.SiteRegister) != nil {
+		if s.Game.Texts != nil {
+			if txt := s.Game.Texts.GetRegister(); txt != "" {
+				d.SendNoNewline(txt)
+				return
+			}
 		}
-	}
-	if s.Game.IsBadName(user) {
-		d.Send("That name is not allowed.")
+		d.Send("Character creation is by registration only. Use: register <name> <email address>")
 		return
 	}
 
-	// Create the player object
-	ref := s.Game.CreateObject(user, gamedb.TypePlayer, gamedb.Nothing)
-	playerObj := s.Game.DB.Objects[ref]
-	playerObj.Owner = ref
-
-	// Set password (plaintext for now, TODO: add encryption)
-	s.Game.SetAttr(ref, aPass, password)
-
-	// Set start room and home from config
-	startRoom := s.Game.StartingRoom()
-	startHome := s.Game.StartingHome()
-	playerObj.Location = startRoom
-	playerObj.Link = startHome // home
-
-	// Add to start room contents
-	s.Game.AddToContents(startRoom, ref)
-	if roomObj, ok := s.Game.DB.Objects[startRoom]; ok {
-		s.Game.PersistObjects(playerObj, roomObj)
-	}
-	if s.Game.Store != nil {
-		s.Game.Store.PutMeta()
-		s.Game.Store.UpdatePlayerIndex(playerObj, "")
+	ref, err := s.Game.CreatePlayer(user, password)
+	if err != nil {
+		d.Send(err.Error())
+		return
 	}
+	startRoom := s.Game.DB.Objects[ref].Location
 
-	log.Printf("[%d] New player %s(#%d) created from %s", d.ID, user, ref, d.Addr)
+	Logf(LogConnections, LevelInfo, "[%d] New player %s(#%d) created from %s", d.ID, user, ref, d.Addr)
 
 	// Log them in
 	s.Game.Conns.Login(d, ref)
+	s.Game.noteLogin(d)
 
 	d.Send(fmt.Sprintf("Welcome to GoTinyMUSH, %s! Your character has been created as #%d.", user, ref))
 
@@ -554,6 +625,9 @@ func (s *Server) handleCreate(d *Descriptor, user, password string) {
 
 	// Show room
 	s.Game.ShowRoom(d, startRoom)
+
+	// Fire ACONNECT triggers
+	s.Game.fireAconnect(d, "create")
 }
 
 // stripTelnet removes telnet IAC command sequences from input.
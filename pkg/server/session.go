@@ -0,0 +1,93 @@
+package server
+
+import (
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// maxDrainTicks bounds Session.Run's queue draining, so softcode that
+// keeps re-queueing itself can't hang the caller.
+const maxDrainTicks = 1000
+
+// Session is a connection with no network behind it, for driving a game
+// from a tool such as evaltest rather than from the server loop. Commands
+// run as its player, the queue is run to completion after each one, and
+// everything the player is sent along the way is collected. Sessions take
+// the world lock themselves; they must not be used while it is held.
+type Session struct {
+	g   *Game
+	d   *Descriptor
+	out []string
+}
+
+// OpenSession connects a Session as player.
+func (g *Game) OpenSession(player gamedb.DBRef) *Session {
+	s := &Session{g: g}
+	g.Do(func() {
+		now := g.Now()
+		s.d = &Descriptor{
+			ID:        g.Conns.NextID(),
+			Conn:      nullConn{},
+			State:     ConnConnected,
+			Player:    player,
+			Addr:      "session",
+			ConnTime:  now,
+			LastCmd:   now,
+			Transport: TransportWebSocket,
+			SendFunc:  func(msg string) { s.out = append(s.out, msg) },
+		}
+		g.Conns.Add(s.d)
+		g.Conns.Login(s.d, player)
+	})
+	return s
+}
+
+// Player returns the session's player.
+func (s *Session) Player() gamedb.DBRef { return s.d.Player }
+
+// Run runs command as the session's player, then the queue until it is
+// empty, and returns the output sent to the player meanwhile. Waits that
+// aren't due yet stay queued; see Game.Clock to move time along. It
+// reports false if the queue was still busy after maxDrainTicks passes.
+func (s *Session) Run(command string) (out []string, settled bool) {
+	s.g.Do(func() {
+		s.d.LastCmd = s.g.Now()
+		DispatchCommand(s.g, s.d, command)
+	})
+	settled = s.g.DrainQueue()
+	return s.Output(), settled
+}
+
+// Eval evaluates expr as the session's player, as think would, and
+// returns the result.
+func (s *Session) Eval(expr string) string {
+	var result string
+	s.g.Do(func() { result = evalExpr(s.g, s.d.Player, expr) })
+	return result
+}
+
+// Output returns and clears what the player has been sent since the last
+// call.
+func (s *Session) Output() []string {
+	var out []string
+	s.g.Do(func() { out, s.out = s.out, nil })
+	return out
+}
+
+// Close disconnects the session.
+func (s *Session) Close() {
+	s.g.Do(func() { s.g.Conns.Remove(s.d) })
+}
+
+// DrainQueue runs the queue until nothing is left that is due, taking the
+// world lock for each pass as the queue processor does. It reports false
+// if work remained after maxDrainTicks passes.
+func (g *Game) DrainQueue() bool {
+	for i := 0; i < maxDrainTicks; i++ {
+		var busy bool
+		g.Do(func() { busy = g.ProcessQueue() })
+		if !busy {
+			return true
+		}
+	}
+	return false
+}
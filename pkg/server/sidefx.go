@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// SideEffect runs the command behind a side-effect function as player,
+// so create(), tel() and the rest are held to the same permission
+// checks as @create, @tel and so on. The command's output is swallowed.
+// create(), dig() and open() return the new object's dbref, or #-1 and
+// the reason nothing was made; the others return nothing.
+func (g *Game) SideEffect(player gamedb.DBRef, fn string, args []string) string {
+	if g.Conf != nil && !g.Conf.SideEffectFunctions {
+		return "#-1 FUNCTION DISABLED"
+	}
+	arg := func(i int) string {
+		if i < len(args) {
+			return strings.TrimSpace(args[i])
+		}
+		return ""
+	}
+	switch fn {
+	case "create":
+		// create(<name>, <cost>[, <type>]). create(<name>, <type>) is
+		// also accepted for older softcode.
+		cost, typ := arg(1), strings.ToLower(arg(2))
+		if cost == "r" || cost == "e" || cost == "t" {
+			cost, typ = "", cost
+		}
+		switch typ {
+		case "r":
+			return g.sideEffectCreate(player, "@dig", arg(0))
+		case "e":
+			return g.sideEffectCreate(player, "@open", arg(0))
+		}
+		if cost != "" {
+			return g.sideEffectCreate(player, "@create", arg(0)+"="+cost)
+		}
+		return g.sideEffectCreate(player, "@create", arg(0))
+	case "dig":
+		line := arg(0)
+		if arg(1) != "" || arg(2) != "" {
+			line += "=" + arg(1) + "," + arg(2)
+		}
+		return g.sideEffectCreate(player, "@dig", line)
+	case "open":
+		line := arg(0)
+		if arg(1) != "" {
+			line += "=" + arg(1)
+		}
+		return g.sideEffectCreate(player, "@open", line)
+	case "tel":
+		// tel()'s arguments are already evaluated, so it skips @tel's
+		// own evaluation.
+		if g.IsGuest(player) {
+			return ""
+		}
+		d, _ := g.sideEffectDescriptor(player)
+		g.teleport(d, arg(0), arg(1))
+	case "link":
+		g.sideEffectRun(player, "@link", arg(0)+"="+arg(1))
+	case "set":
+		g.sideEffectRun(player, "@set", arg(0)+"="+arg(1))
+	case "wipe":
+		g.sideEffectRun(player, "@wipe", arg(0))
+	}
+	return ""
+}
+
+// sideEffectCreate runs a building command and returns the dbref of the
+// first object it made: the thing, room or exit being created.
+func (g *Game) sideEffectCreate(player gamedb.DBRef, cmd, line string) string {
+	if strings.TrimSpace(line) == "" {
+		return "#-1 NO NAME GIVEN"
+	}
+	before := g.NextRef
+	out := g.sideEffectRun(player, cmd, line)
+	if g.NextRef > before {
+		return fmt.Sprintf("#%d", before)
+	}
+	if len(*out) == 0 {
+		return "#-1"
+	}
+	return "#-1 " + strings.ToUpper(strings.TrimSuffix((*out)[0], "."))
+}
+
+// sideEffectRun calls a command's handler as player, with guest
+// restrictions applied as DispatchCommand would, and returns what it
+// would have said.
+func (g *Game) sideEffectRun(player gamedb.DBRef, cmdName, line string) *[]string {
+	d, out := g.sideEffectDescriptor(player)
+	cmd, ok := g.Commands[cmdName]
+	if !ok {
+		return out
+	}
+	if cmd.NoGuest && g.IsGuest(player) {
+		d.Send("Permission denied.")
+		return out
+	}
+	cmd.Handler(g, d, line, nil)
+	return out
+}
+
+// sideEffectDescriptor makes a descriptor for player that collects its
+// output instead of sending it.
+func (g *Game) sideEffectDescriptor(player gamedb.DBRef) (*Descriptor, *[]string) {
+	out := &[]string{}
+	d := g.MakeObjDescriptor(player)
+	d.SendFunc = func(msg string) { *out = append(*out, msg) }
+	return d, out
+}
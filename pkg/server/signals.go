@@ -0,0 +1,113 @@
+package server
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Checkpoint commits queued object writes and checkpoints the SQL store, so
+// nothing in flight is lost if the process exits next.
+func (g *Game) Checkpoint() {
+	if g.Store != nil {
+		if err := g.Store.StopWriteBehind(); err != nil {
+			Logf(LogBugs, LevelError, "final database commit: %v", err)
+		}
+	}
+	if g.SQLDB != nil {
+		if err := g.SQLDB.Checkpoint(); err != nil {
+			Logf(LogBugs, LevelError, "SQL checkpoint: %v", err)
+		}
+	}
+}
+
+// Shutdown sends msg (if any) to connected players, disconnects everyone,
+// checkpoints the databases and closes the listeners. srv may be nil. It is
+// the graceful-shutdown path for both the admin panel and SIGTERM. It takes
+// the world lock, and keeps it, so nothing changes after the final commit.
+func (g *Game) Shutdown(srv *Server, msg string) {
+	g.shuttingDown.Store(true)
+	g.Lock()
+	if g.Conns != nil {
+		for _, d := range g.Conns.AllDescriptors() {
+			if d.State == ConnConnected {
+				if msg != "" {
+					d.Send(msg)
+				}
+				d.DisconnectReason = "shutdown"
+				g.DisconnectPlayer(d)
+			}
+			d.Close()
+		}
+	}
+	if g.IRC != nil {
+		g.IRC.Stop()
+	}
+	if g.Discord != nil {
+		g.Discord.Stop()
+	}
+	// Commit before closing listeners: once they close, Server.Start
+	// returns and main exits.
+	g.SaveQueue()
+	g.Checkpoint()
+	if g.Replication != nil {
+		// Give standbys a moment to apply the final commit.
+		if !g.Replication.Drain(5 * time.Second) {
+			log.Printf("Replication: standby still behind at shutdown")
+		}
+		g.Replication.Close()
+	}
+	if srv != nil {
+		srv.Stop()
+	}
+}
+
+// ReloadConfig re-reads the game config (as @readconf), alias configs and
+// text files from disk.
+func (g *Game) ReloadConfig() {
+	if g.ConfPath != "" {
+		if _, err := g.ReadConf(); err != nil {
+			log.Printf("WARNING: reload %s: %v (keeping current config)", g.ConfPath, err)
+		}
+	}
+	if len(g.AliasConfs) > 0 {
+		ac, err := LoadAliasConfig(g.AliasConfs...)
+		if err != nil {
+			log.Printf("WARNING: reload alias config: %v", err)
+		} else {
+			g.BadNames = nil
+			g.ApplyAliasConfig(ac)
+			g.reapplyConfAliases()
+		}
+	}
+	if g.TextDir != "" {
+		n := g.ReloadTextFiles()
+		log.Printf("Reloaded %d text file(s) from %s", n, g.TextDir)
+	}
+}
+
+// HandleSignals shuts the server down cleanly on SIGTERM or SIGINT and
+// reloads its configuration on SIGHUP.
+func (s *Server) HandleSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	go func() {
+		for sig := range ch {
+			if sig == syscall.SIGHUP {
+				log.Printf("SIGHUP: reloading configuration")
+				s.Game.Do(func() {
+					s.Game.ReloadConfig()
+					s.Game.NotifyWizards("GAME: Configuration reloaded (SIGHUP).")
+				})
+				continue
+			}
+			log.Printf("%v: shutting down", sig)
+			signal.Stop(ch)
+			s.Game.Shutdown(s, "GAME: Shutdown by system signal. Goodbye!")
+			log.Printf("Shutdown complete")
+			return
+		}
+	}()
+}
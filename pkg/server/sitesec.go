@@ -0,0 +1,397 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// SiteSecurity holds the site access rules (@site) and the per-address
+// connection and failed-login history used for throttling.
+type SiteSecurity struct {
+	mu       sync.Mutex
+	rules    map[string]*gamedb.SiteRule // pattern -> rule
+	nets     map[string]*net.IPNet       // pattern -> parsed range
+	conns    map[string][]time.Time      // address -> recent connection times
+	failures map[string][]time.Time      // address -> recent failed logins
+}
+
+// NewSiteSecurity creates an empty site security table.
+func NewSiteSecurity() *SiteSecurity {
+	return &SiteSecurity{
+		rules:    make(map[string]*gamedb.SiteRule),
+		nets:     make(map[string]*net.IPNet),
+		conns:    make(map[string][]time.Time),
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// parseSitePattern parses a CIDR range or single address into a network.
+// Returns the canonical pattern string alongside it.
+func parseSitePattern(pattern string) (string, *net.IPNet, error) {
+	pattern = strings.TrimSpace(pattern)
+	if !strings.Contains(pattern, "/") {
+		ip := net.ParseIP(pattern)
+		if ip == nil {
+			return "", nil, fmt.Errorf("not an address or CIDR range: %q", pattern)
+		}
+		if ip.To4() != nil {
+			pattern += "/32"
+		} else {
+			pattern += "/128"
+		}
+	}
+	_, ipnet, err := net.ParseCIDR(pattern)
+	if err != nil {
+		return "", nil, fmt.Errorf("not an address or CIDR range: %q", pattern)
+	}
+	return ipnet.String(), ipnet, nil
+}
+
+// LoadRules replaces the rule table (used at startup from bbolt).
+func (ss *SiteSecurity) LoadRules(rules []gamedb.SiteRule) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for i := range rules {
+		pattern, ipnet, err := parseSitePattern(rules[i].Pattern)
+		if err != nil {
+			log.Printf("site: skipping bad rule %q: %v", rules[i].Pattern, err)
+			continue
+		}
+		rules[i].Pattern = pattern
+		ss.rules[pattern] = &rules[i]
+		ss.nets[pattern] = ipnet
+	}
+}
+
+// AddRule adds or replaces the rule for rule.Pattern, normalizing the
+// pattern to CIDR form. Returns the stored rule.
+func (ss *SiteSecurity) AddRule(rule gamedb.SiteRule) (*gamedb.SiteRule, error) {
+	pattern, ipnet, err := parseSitePattern(rule.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	rule.Pattern = pattern
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.rules[pattern] = &rule
+	ss.nets[pattern] = ipnet
+	return &rule, nil
+}
+
+// RemoveRule deletes the rule for a pattern. Returns the normalized
+// pattern and whether a rule was removed.
+func (ss *SiteSecurity) RemoveRule(pattern string) (string, bool) {
+	pattern, _, err := parseSitePattern(pattern)
+	if err != nil {
+		return "", false
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if _, ok := ss.rules[pattern]; !ok {
+		return pattern, false
+	}
+	delete(ss.rules, pattern)
+	delete(ss.nets, pattern)
+	return pattern, true
+}
+
+// Rules returns a snapshot of all rules, sorted by kind then pattern.
+func (ss *SiteSecurity) Rules() []gamedb.SiteRule {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	out := make([]gamedb.SiteRule, 0, len(ss.rules))
+	for _, r := range ss.rules {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Pattern < out[j].Pattern
+	})
+	return out
+}
+
+// Match returns the narrowest rule of the given kind covering addr, or nil.
+func (ss *SiteSecurity) Match(addr string, kind string) *gamedb.SiteRule {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	var best *gamedb.SiteRule
+	bestBits := -1
+	for pattern, r := range ss.rules {
+		if r.Kind != kind || !ss.nets[pattern].Contains(ip) {
+			continue
+		}
+		if bits, _ := ss.nets[pattern].Mask.Size(); bits > bestBits {
+			best, bestBits = r, bits
+		}
+	}
+	return best
+}
+
+// NoteConnection records a connection attempt from addr and returns false
+// if it makes more than limit attempts within window (limit <= 0 = no limit).
+func (ss *SiteSecurity) NoteConnection(addr string, limit int, window time.Duration) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if len(ss.conns) > siteHistorySweep {
+		sweepTimes(ss.conns, window)
+	}
+	recent := append(pruneTimes(ss.conns[addr], window), time.Now())
+	ss.conns[addr] = recent
+	return limit <= 0 || len(recent) <= limit
+}
+
+// NoteFailure records a failed login from addr and returns the number of
+// failures still inside window.
+func (ss *SiteSecurity) NoteFailure(addr string, window time.Duration) int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if len(ss.failures) > siteHistorySweep {
+		sweepTimes(ss.failures, window)
+	}
+	recent := append(pruneTimes(ss.failures[addr], window), time.Now())
+	ss.failures[addr] = recent
+	return len(recent)
+}
+
+// Failures returns the number of failed logins from addr inside window.
+func (ss *SiteSecurity) Failures(addr string, window time.Duration) int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	recent := pruneTimes(ss.failures[addr], window)
+	if len(recent) == 0 {
+		delete(ss.failures, addr)
+	} else {
+		ss.failures[addr] = recent
+	}
+	return len(recent)
+}
+
+// Unthrottle forgets the connection and failed-login history for addr.
+func (ss *SiteSecurity) Unthrottle(addr string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	delete(ss.conns, addr)
+	delete(ss.failures, addr)
+}
+
+// siteHistorySweep is the number of tracked addresses past which stale
+// history is swept from the whole map rather than only the current address.
+const siteHistorySweep = 1024
+
+// sweepTimes prunes every address in m and drops those with no recent entries.
+func sweepTimes(m map[string][]time.Time, window time.Duration) {
+	for addr, times := range m {
+		if kept := pruneTimes(times, window); len(kept) == 0 {
+			delete(m, addr)
+		} else {
+			m[addr] = kept
+		}
+	}
+}
+
+// pruneTimes drops entries older than window, reusing the slice.
+func pruneTimes(times []time.Time, window time.Duration) []time.Time {
+	cutoff := time.Now().Add(-window)
+	kept := times[:0]
+	for _, t := range times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// hostAddr strips the port and IPv6 brackets from a "host:port" address.
+func hostAddr(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
+
+// SiteRuleFor returns the site rule of the given kind that applies to a
+// connection address, or nil.
+func (g *Game) SiteRuleFor(addr string, kind string) *gamedb.SiteRule {
+	if g.Sites == nil {
+		return nil
+	}
+	return g.Sites.Match(hostAddr(addr), kind)
+}
+
+// loginFailWindow returns how long failed logins count against an address.
+func (g *Game) loginFailWindow() time.Duration {
+	if g.Conf == nil || g.Conf.LoginFailWindow <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(g.Conf.LoginFailWindow) * time.Second
+}
+
+// LoginLocked returns true if addr has used up its failed-login allowance.
+func (g *Game) LoginLocked(addr string) bool {
+	if g.Sites == nil || g.Conf == nil || g.Conf.LoginFailLimit <= 0 {
+		return false
+	}
+	return g.Sites.Failures(hostAddr(addr), g.loginFailWindow()) >= g.Conf.LoginFailLimit
+}
+
+// NoteLoginFailure records a failed login from addr. Returns true if the
+// address is now locked out.
+func (g *Game) NoteLoginFailure(addr string) bool {
+	if g.Sites == nil || g.Conf == nil || g.Conf.LoginFailLimit <= 0 {
+		return false
+	}
+	host := hostAddr(addr)
+	n := g.Sites.NoteFailure(host, g.loginFailWindow())
+	if n == g.Conf.LoginFailLimit {
+		Logf(LogSecurity, LevelWarn, "site: %s locked out after %d failed logins", host, n)
+	}
+	return n >= g.Conf.LoginFailLimit
+}
+
+// siteRefusal applies site bans and throttling to a new connection from
+// addr, counting it against conn_rate_limit. Returns why it is refused, or
+// "" if it may go ahead.
+func (g *Game) siteRefusal(addr string) string {
+	if g.Sites == nil {
+		return ""
+	}
+	host := hostAddr(addr)
+	switch {
+	case g.SiteRuleFor(addr, gamedb.SiteForbid) != nil:
+		Logf(LogSecurity, LevelInfo, "site: refused connection from forbidden site %s", host)
+		if g.Texts != nil {
+			if txt := g.Texts.GetBadSite(); txt != "" {
+				return txt
+			}
+		}
+		return "Connections from your site are not allowed."
+	case g.Conf != nil && !g.Sites.NoteConnection(host, g.Conf.ConnRateLimit, time.Minute):
+		Logf(LogSecurity, LevelInfo, "site: throttled connection from %s", host)
+		return "Too many connections from your site. Please try again later."
+	case g.LoginLocked(addr):
+		return "Too many failed logins from your site. Please try again later."
+	}
+	return ""
+}
+
+// screenConnection applies site bans and throttling to a new connection
+// before anything is sent. Returns false (after telling the client why and
+// closing it) if the connection is refused.
+func (s *Server) screenConnection(conn net.Conn) bool {
+	g := s.Game
+	if g.Sites == nil {
+		return true
+	}
+	var msg string
+	g.Do(func() { msg = g.siteRefusal(conn.RemoteAddr().String()) })
+	if msg == "" {
+		return true
+	}
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\r\n"
+	}
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte(msg))
+	conn.Close()
+	return false
+}
+
+// cmdSite manages site access rules. Wizard-only.
+//
+//	@site[/list]                          list rules
+//	@site/forbid <address|cidr>[=<note>]  refuse connections
+//	@site/register <address|cidr>[=<note>] no character creation
+//	@site/noguest <address|cidr>[=<note>] no guest logins
+//	@site/remove <address|cidr>           delete the rule
+//	@site/unthrottle <address>            clear connection/login throttling
+func cmdSite(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	if g.Sites == nil {
+		g.Sites = NewSiteSecurity()
+	}
+	args = strings.TrimSpace(args)
+
+	kind := ""
+	switch {
+	case HasSwitch(switches, "forbid"):
+		kind = gamedb.SiteForbid
+	case HasSwitch(switches, "register"):
+		kind = gamedb.SiteRegister
+	case HasSwitch(switches, "noguest"):
+		kind = gamedb.SiteNoGuest
+	case HasSwitch(switches, "remove"):
+		pattern, ok := g.Sites.RemoveRule(args)
+		if !ok {
+			d.Send("No such site rule.")
+			return
+		}
+		if g.Store != nil {
+			g.Store.DeleteSiteRule(pattern)
+		}
+		Logf(LogWizard, LevelInfo, "site: %s(#%d) removed rule for %s", g.PlayerName(d.Player), d.Player, pattern)
+		d.Send(fmt.Sprintf("Site rule for %s removed.", pattern))
+		return
+	case HasSwitch(switches, "unthrottle"):
+		if net.ParseIP(args) == nil {
+			d.Send("That's not an address.")
+			return
+		}
+		g.Sites.Unthrottle(args)
+		d.Send(fmt.Sprintf("Throttling cleared for %s.", args))
+		return
+	default:
+		siteList(g, d)
+		return
+	}
+
+	pattern, note := args, ""
+	if idx := strings.Index(args, "="); idx >= 0 {
+		pattern, note = strings.TrimSpace(args[:idx]), strings.TrimSpace(args[idx+1:])
+	}
+	rule, err := g.Sites.AddRule(gamedb.SiteRule{
+		Pattern: pattern,
+		Kind:    kind,
+		Reason:  note,
+		Setter:  d.Player,
+		Created: time.Now(),
+	})
+	if err != nil {
+		d.Send("That's not an address or CIDR range.")
+		return
+	}
+	if g.Store != nil {
+		g.Store.PutSiteRule(rule)
+	}
+	Logf(LogWizard, LevelInfo, "site: %s(#%d) set %s on %s", g.PlayerName(d.Player), d.Player, kind, rule.Pattern)
+	d.Send(fmt.Sprintf("Site %s is now %s.", rule.Pattern, kind))
+}
+
+// siteList shows all site rules.
+func siteList(g *Game, d *Descriptor) {
+	rules := g.Sites.Rules()
+	if len(rules) == 0 {
+		d.Send("No site rules.")
+		return
+	}
+	d.Send(fmt.Sprintf("%-9s %-40s %-16s %s", "Kind", "Site", "Set By", "Note"))
+	for _, r := range rules {
+		d.Send(fmt.Sprintf("%-9s %-40s %-16s %s", r.Kind, r.Pattern, g.PlayerName(r.Setter), r.Reason))
+	}
+	d.Send(fmt.Sprintf("%d site rule(s).", len(rules)))
+}
@@ -565,6 +565,68 @@ func TestHelpFileMultiAlias(t *testing.T) {
 	}
 }
 
+func TestHelpFileIndex(t *testing.T) {
+	content := `& @function
+  Defines a global function.
+See also: @function2, @secret, nowhere
+& @function2
+  More about @function.
+& @force
+  Makes an object do something.
+&& @secret
+  A wizard-only topic about functions.
+`
+	path := t.TempDir() + "/test_help.txt"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hf := LoadHelpFile(path)
+	if hf == nil {
+		t.Fatal("LoadHelpFile returned nil")
+	}
+
+	// A prefix that only the extensions of one topic share finds it
+	if m := hf.Find("@fun", false); m.Topic != "@function" {
+		t.Errorf("@fun found %q, want @function", m.Topic)
+	}
+	// One that starts several different topics is ambiguous
+	if m := hf.Find("@f", false); m.Text != "" || len(m.Ambiguous) != 3 {
+		t.Errorf("@f: got %+v, want 3 ambiguous topics", m)
+	}
+	// Nothing matching offers topics containing it or mentioning it
+	if m := hf.Find("object", false); len(m.Suggestions) != 1 || m.Suggestions[0] != "@force" {
+		t.Errorf("object suggestions = %v, want [@force]", m.Suggestions)
+	}
+
+	// Wizard-only topics are hidden from mortals everywhere
+	if m := hf.Find("@secret", false); m.Text != "" {
+		t.Errorf("mortal found @secret: %q", m.Text)
+	}
+	if m := hf.Find("@secret", true); m.Text == "" {
+		t.Error("wizard didn't find @secret")
+	}
+	if got := hf.Search("functions", false); len(got) != 0 {
+		t.Errorf("mortal search found %v", got)
+	}
+	if got := hf.Search("functions", true); len(got) != 1 || got[0] != "@secret" {
+		t.Errorf("wizard search found %v, want [@secret]", got)
+	}
+
+	// See also references
+	if refs := hf.Refs["@function"]; len(refs) != 3 || refs[0] != "@function2" {
+		t.Errorf("@function refs = %v", refs)
+	}
+
+	// Entries set and deleted are indexed at once
+	hf.Set("@fnord", "Nothing to see.", false)
+	if m := hf.Find("@fn", false); m.Topic != "@fnord" {
+		t.Errorf("@fn found %q after Set, want @fnord", m.Topic)
+	}
+	if !hf.Delete("@fnord") || hf.Find("@fn", false).Text != "" {
+		t.Error("@fnord still found after Delete")
+	}
+}
+
 // ============================================================================
 // Help coverage: every registered () function should have a help entry
 // ============================================================================
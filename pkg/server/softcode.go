@@ -46,6 +46,11 @@ func (g *Game) MatchDollarCommands(player, cause gamedb.DBRef, input string) boo
 	// Player's inventory
 	searchObjs = append(searchObjs, g.DB.SafeContents(player)...)
 
+	// Parent rooms are local master rooms: their contents are checked
+	for _, parent := range g.parentRooms(loc) {
+		searchObjs = append(searchObjs, g.DB.SafeContents(parent)...)
+	}
+
 	// Master room contents — global commands live here in heavy softcode games
 	masterRoom := g.MasterRoomRef()
 	if loc != masterRoom {
@@ -65,6 +70,18 @@ func (g *Game) MatchDollarCommands(player, cause gamedb.DBRef, input string) boo
 		}
 	}
 
+	// An object reached more than one way (a shared zone, a parent room
+	// that is also the zone) only gets checked once.
+	seen := make(map[gamedb.DBRef]bool, len(searchObjs))
+	unique := searchObjs[:0]
+	for _, ref := range searchObjs {
+		if !seen[ref] {
+			seen[ref] = true
+			unique = append(unique, ref)
+		}
+	}
+	searchObjs = unique
+
 	if IsDebug() {
 		names := make([]string, len(searchObjs))
 		for i, ref := range searchObjs {
@@ -84,6 +101,7 @@ func (g *Game) MatchDollarCommands(player, cause gamedb.DBRef, input string) boo
 	for _, objRef := range searchObjs {
 		if g.matchDollarOnObject(objRef, player, cause, input) {
 			DebugLog("DOLLAR MATCHED on #%d", objRef)
+			g.traceWatch(objRef, "$-command matched by #%d: %s", player, input)
 			found = true
 		}
 	}
@@ -93,6 +111,41 @@ func (g *Game) MatchDollarCommands(player, cause gamedb.DBRef, input string) boo
 	return found
 }
 
+// parentRooms returns loc's parent rooms, nearest first: the objects set
+// ZONE up its parent chain, then its zone if that is set ZONE. Exits in a
+// parent room can be used from loc, and the $-commands on its contents
+// are checked as if it were a local master room. Off unless
+// local_master_rooms is set.
+func (g *Game) parentRooms(loc gamedb.DBRef) []gamedb.DBRef {
+	if g.Conf == nil || !g.Conf.LocalMasterRooms {
+		return nil
+	}
+	locObj, ok := g.DB.Objects[loc]
+	if !ok {
+		return nil
+	}
+	var rooms []gamedb.DBRef
+	seen := map[gamedb.DBRef]bool{loc: true}
+	add := func(ref gamedb.DBRef) {
+		if obj, ok := g.DB.Objects[ref]; ok && !seen[ref] && obj.HasFlag2(gamedb.Flag2ZoneParent) && !obj.IsGoing() {
+			rooms = append(rooms, ref)
+		}
+		seen[ref] = true
+	}
+	for parent, depth := locObj.Parent, 0; parent != gamedb.Nothing && depth < 10 && !seen[parent]; depth++ {
+		add(parent)
+		obj, ok := g.DB.Objects[parent]
+		if !ok {
+			break
+		}
+		parent = obj.Parent
+	}
+	if locObj.Zone != gamedb.Nothing {
+		add(locObj.Zone)
+	}
+	return rooms
+}
+
 // addZoneObjects appends a zone object and its contents to the search list.
 func (g *Game) addZoneObjects(searchObjs []gamedb.DBRef, zone gamedb.DBRef) []gamedb.DBRef {
 	searchObjs = append(searchObjs, zone)
@@ -116,34 +169,18 @@ func (g *Game) matchDollarOnObject(objRef, player, cause gamedb.DBRef, input str
 	}
 
 	found := false
-	dollarCount := 0
-	for _, attr := range obj.Attrs {
-		text := eval.StripAttrPrefix(attr.Value)
-		if !strings.HasPrefix(text, "$") {
-			continue
-		}
-		dollarCount++
-
-		// Parse attribute flags from the raw value
-		// Format: "owner:flags:$pattern:command"
-		attrFlags := parseAttrFlags(attr.Value)
+	for i, dc := range g.dollarCmds(obj) {
+		// Attribute flags come from the stored "owner:flags:" prefix
+		// and the attribute's definition.
+		attrFlags := g.attrMatchFlags(dc.attr)
 		if attrFlags&AFNoProg != 0 {
 			continue
 		}
 
-		// Split "$pattern:command"
-		rest := text[1:] // skip $
-		colonIdx := findUnescapedColon(rest)
-		if colonIdx < 0 {
-			continue
-		}
-		pattern := rest[:colonIdx]
-		command := rest[colonIdx+1:]
-
 		// Match the pattern against input
-		matched, args := matchWild(pattern, input)
-		if IsDebug() && dollarCount <= 10 {
-			DebugLog("DOLLAR #%d(%s) attr %d: pattern=%q input=%q matched=%v", objRef, obj.Name, attr.Number, pattern, input, matched)
+		matched, args := matchAttrPattern(dc.pattern, input, attrFlags)
+		if IsDebug() && i < 10 {
+			DebugLog("DOLLAR #%d(%s) attr %d: pattern=%q input=%q matched=%v", objRef, obj.Name, dc.attr.Number, dc.pattern, input, matched)
 		}
 		if !matched {
 			continue
@@ -154,7 +191,7 @@ func (g *Game) matchDollarOnObject(objRef, player, cause gamedb.DBRef, input str
 			Player:  objRef,
 			Cause:   cause,
 			Caller:  player,
-			Command: command,
+			Command: dc.command,
 			Args:    args,
 		}
 
@@ -172,7 +209,7 @@ func (g *Game) matchDollarOnObject(objRef, player, cause gamedb.DBRef, input str
 	parentRef := obj.Parent
 	if IsDebug() && parentRef != gamedb.Nothing {
 		if pObj, ok := g.DB.Objects[parentRef]; ok {
-			DebugLog("DOLLAR #%d(%s) checking parent #%d(%s) attrs=%d", objRef, obj.Name, parentRef, pObj.Name, len(pObj.Attrs))
+			DebugLog("DOLLAR #%d(%s) checking parent #%d(%s) attrs=%d", objRef, obj.Name, parentRef, pObj.Name, len(pObj.Attributes()))
 		} else {
 			DebugLog("DOLLAR #%d(%s) parent #%d NOT FOUND in DB", objRef, obj.Name, parentRef)
 		}
@@ -202,30 +239,16 @@ func (g *Game) matchDollarOnParent(parentRef, childRef, player, cause gamedb.DBR
 	}
 
 	found := false
-	dollarCount := 0
-	for _, attr := range parent.Attrs {
-		text := eval.StripAttrPrefix(attr.Value)
-		if !strings.HasPrefix(text, "$") {
-			continue
-		}
-		dollarCount++
-		attrFlags := parseAttrFlags(attr.Value)
+	for i, dc := range g.dollarCmds(parent) {
+		attrFlags := g.attrMatchFlags(dc.attr)
 		if attrFlags&AFNoProg != 0 || attrFlags&AFPrivate != 0 {
-			DebugLog("DOLLAR parent #%d attr %d SKIPPED flags=0x%x (noprog=%v private=%v)", parentRef, attr.Number, attrFlags, attrFlags&AFNoProg != 0, attrFlags&AFPrivate != 0)
+			DebugLog("DOLLAR parent #%d attr %d SKIPPED flags=0x%x (noprog=%v private=%v)", parentRef, dc.attr.Number, attrFlags, attrFlags&AFNoProg != 0, attrFlags&AFPrivate != 0)
 			continue
 		}
 
-		rest := text[1:]
-		colonIdx := findUnescapedColon(rest)
-		if colonIdx < 0 {
-			continue
-		}
-		pattern := rest[:colonIdx]
-		command := rest[colonIdx+1:]
-
-		matched, args := matchWild(pattern, input)
-		if IsDebug() && dollarCount <= 10 {
-			DebugLog("DOLLAR parent #%d attr %d: pattern=%q input=%q matched=%v", parentRef, attr.Number, pattern, input, matched)
+		matched, args := matchAttrPattern(dc.pattern, input, attrFlags)
+		if IsDebug() && i < 10 {
+			DebugLog("DOLLAR parent #%d attr %d: pattern=%q input=%q matched=%v", parentRef, dc.attr.Number, dc.pattern, input, matched)
 		}
 		if !matched {
 			continue
@@ -236,7 +259,7 @@ func (g *Game) matchDollarOnParent(parentRef, childRef, player, cause gamedb.DBR
 			Player:  childRef, // Execute as child, not parent
 			Cause:   cause,
 			Caller:  player,
-			Command: command,
+			Command: dc.command,
 			Args:    args,
 		}
 		g.Queue.Add(entry)
@@ -248,14 +271,23 @@ func (g *Game) matchDollarOnParent(parentRef, childRef, player, cause gamedb.DBR
 
 // ExecuteQueueEntry executes a queued command.
 // Like TinyMUSH's process_cmdline, it splits on semicolons to handle
-// multi-command strings (e.g. "@drain me;@notify me").
+// multi-command strings (e.g. "@drain me;@notify me"). A panic abandons
+// the rest of the entry and halts its object; see commandPanic.
 func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.commandPanic(entry.Player, entry.Cause, entry.Command, r)
+			g.Conns.SendToPlayer(entry.Cause, panicMsg)
+		}
+	}()
+
 	// Check HALT flag — halted objects should not execute queue entries
 	if obj, ok := g.DB.Objects[entry.Player]; ok {
 		if obj.HasFlag(gamedb.FlagHalt) {
 			return
 		}
 	}
+	g.traceWatch(entry.Player, "queue: %s", entry.Command)
 
 	// When fix_escape_eval is enabled, strip double-escaped specials (\\[ → \[, etc.)
 	// so that data written for C TinyMUSH's extra eval pass displays correctly.
@@ -268,6 +300,7 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 	ctx := MakeEvalContextWithGame(g, entry.Player, func(c *eval.EvalContext) {
 		functions.RegisterAll(c)
 	})
+	defer ctx.Release()
 	ctx.Cause = entry.Cause
 	ctx.Caller = entry.Caller
 
@@ -281,7 +314,15 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 	// to split BEFORE evaluation, preserving brace-protected content for @wait etc.
 	cmds := splitSemicolonRespectingBraces(entry.Command)
 
-	// Snapshot q-registers onto descriptors so @program can capture them.
+	// @program run from here keeps these q-registers for its command.
+	prevRData := g.runningRData
+	g.runningRData = ctx.RData
+	defer func() { g.runningRData = prevRData }()
+
+	// An @end in this list stops only this list.
+	g.ended = false
+	defer func() { g.ended = false }()
+
 	descs := g.Conns.GetByPlayer(entry.Player)
 
 	for _, cmd := range cmds {
@@ -297,6 +338,9 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 		// evaluated later in the appropriate context (e.g. per-iteration
 		// for @dolist, when wait fires for @wait).
 		if handled := g.handleDeferredBodyCmd(cmd, ctx, entry, descs); handled {
+			if g.ended {
+				break
+			}
 			continue
 		}
 
@@ -312,6 +356,9 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 		evaluated := ctx.Exec(cmd, eval.EvFCheck|eval.EvEval|eval.EvFCheckPersist, entry.Args)
 		evaluated = strings.TrimSpace(evaluated)
 		DebugLog("EVAL player=#%d cmd=%q evaluated=%q args=%v", entry.Player, truncDebug(cmd, 200), truncDebug(evaluated, 200), entry.Args)
+		if ctx.CPUExceeded() {
+			break
+		}
 		if evaluated == "" {
 			continue
 		}
@@ -336,6 +383,9 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 				// outer braces now get evaluated.
 				ic = ctx.Exec(ic, eval.EvFCheck|eval.EvEval|eval.EvFCheckPersist, entry.Args)
 				ic = strings.TrimSpace(ic)
+				if ctx.CPUExceeded() {
+					break
+				}
 				if ic == "" {
 					continue
 				}
@@ -344,6 +394,12 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 				} else {
 					g.ExecuteAsObject(entry.Player, entry.Cause, ic)
 				}
+				if g.ended {
+					break
+				}
+			}
+			if g.ended {
+				break
 			}
 			continue
 		}
@@ -355,17 +411,13 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 			// Object executing without a connected player - execute internally
 			g.ExecuteAsObject(entry.Player, entry.Cause, evaluated)
 		}
+		if g.ended {
+			break
+		}
 	}
 
-	// Snapshot final q-registers onto descriptors so @program can capture them.
-	rDataSnapshot := ctx.RData.Clone()
-	for _, dd := range descs {
-		dd.LastRData = rDataSnapshot
-	}
-
-	// Clear q-register snapshot from descriptors
-	for _, dd := range descs {
-		dd.LastRData = nil
+	if ctx.CPUExceeded() {
+		g.cpuOverrun(entry)
 	}
 
 	// Handle any notifications from the eval context
@@ -385,6 +437,17 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 	}
 }
 
+// cpuOverrun logs a queued action abandoned at function_cpu_limit and
+// tells the owner of the object that ran it.
+func (g *Game) cpuOverrun(entry *QueueEntry) {
+	owner := gamedb.Nothing
+	if obj, ok := g.DB.Objects[entry.Player]; ok {
+		owner = obj.Owner
+	}
+	Logf(LogBugs, LevelWarn, "CPU limit exceeded by #%d (owner #%d), enactor #%d: %s", entry.Player, owner, entry.Cause, truncDebug(entry.Command, 200))
+	g.Conns.SendToPlayer(owner, fmt.Sprintf("GAME: CPU limit exceeded by %s(#%d); the rest of its action was abandoned.", g.PlayerName(entry.Player), entry.Player))
+}
+
 // splitDeferredBody detects commands like "@wait <spec>={body}" where the body
 // should be stored raw (not evaluated). It matches the given prefix (case-insensitive),
 // finds the first '=' at brace-depth 0, and returns:
@@ -394,29 +457,10 @@ func (g *Game) ExecuteQueueEntry(entry *QueueEntry) {
 //
 // This implements C TinyMUSH's split-before-eval behavior for deferred commands.
 func splitDeferredBody(cmd, prefix string) (lhs, body string, ok bool) {
-	lower := strings.ToLower(strings.TrimSpace(cmd))
-	pfx := strings.ToLower(prefix)
-	if !strings.HasPrefix(lower, pfx) {
-		return "", "", false
-	}
-	// Must be followed by space or /
-	rest := cmd[len(pfx):]
-	if len(rest) == 0 || (rest[0] != ' ' && rest[0] != '/') {
-		return "", "", false
-	}
-	// Skip /switches and find the first space (start of args)
-	argsStart := 0
-	for argsStart < len(rest) && rest[argsStart] != ' ' {
-		argsStart++
-	}
-	// Skip spaces to get to actual args
-	for argsStart < len(rest) && rest[argsStart] == ' ' {
-		argsStart++
-	}
-	if argsStart >= len(rest) {
+	argsStr, ok := deferredArgs(cmd, prefix)
+	if !ok || argsStr == "" {
 		return "", "", false
 	}
-	argsStr := rest[argsStart:]
 
 	// Find the '=' at brace depth 0 within the args portion
 	depth := 0
@@ -443,24 +487,66 @@ func splitDeferredBody(cmd, prefix string) (lhs, body string, ok bool) {
 	return "", "", false
 }
 
+// deferredArgs returns what follows prefix and any /switches in cmd, or
+// false if cmd is not that command.
+func deferredArgs(cmd, prefix string) (string, bool) {
+	lower := strings.ToLower(strings.TrimSpace(cmd))
+	pfx := strings.ToLower(prefix)
+	if !strings.HasPrefix(lower, pfx) {
+		return "", false
+	}
+	// Must be followed by space or /, or nothing
+	rest := cmd[len(pfx):]
+	if len(rest) > 0 && rest[0] != ' ' && rest[0] != '/' {
+		return "", false
+	}
+	// Skip /switches and find the first space (start of args)
+	argsStart := 0
+	for argsStart < len(rest) && rest[argsStart] != ' ' {
+		argsStart++
+	}
+	// Skip spaces to get to actual args
+	for argsStart < len(rest) && rest[argsStart] == ' ' {
+		argsStart++
+	}
+	return rest[argsStart:], true
+}
+
 // handleDeferredBodyCmd checks if cmd is a deferred-body command (@wait,
 // @dolist, @switch, @swi) and handles it with split-before-eval semantics.
 // The LHS (before '=') is evaluated; the RHS body is preserved raw.
 // Returns true if the command was handled.
 func (g *Game) handleDeferredBodyCmd(cmd string, ctx *eval.EvalContext, entry *QueueEntry, descs []*Descriptor) bool {
-	for _, prefix := range []string{"@wait", "@dolist", "@switch", "@swi", "@trigger", "@tr"} {
-		if lhs, body, ok := splitDeferredBody(cmd, prefix); ok {
+	for _, prefix := range []string{"@wait", "@dolist", "@switch", "@swi", "@trigger", "@tr", "@end", "@break", "@assert"} {
+		lhs, body, ok := splitDeferredBody(cmd, prefix)
+		if !ok && (prefix == "@end" || prefix == "@break" || prefix == "@assert") {
+			// The condition alone must not be evaluated here and then
+			// again by the command.
+			lhs, ok = deferredArgs(cmd, prefix)
+			lhs = strings.TrimSpace(lhs)
+		}
+		if ok {
 			// Extract /switches from the command prefix
 			switches := extractDeferredSwitches(cmd, prefix)
+			if c := g.Commands[deferredCommandName(prefix)]; c != nil {
+				resolved, errMsg := resolveSwitches(c, switches)
+				if errMsg != "" {
+					g.Conns.SendToPlayer(entry.Player, errMsg)
+					return true
+				}
+				switches = resolved
+			}
 			switch prefix {
 			case "@wait":
-				g.handleWaitDeferred(ctx, entry, descs, lhs, body)
+				g.handleWaitDeferred(ctx, entry, descs, switches, lhs, body)
 			case "@dolist":
 				g.handleDolistDeferred(ctx, entry, descs, switches, lhs, body)
 			case "@switch", "@swi":
 				g.handleSwitchDeferred(ctx, entry, descs, switches, lhs, body)
 			case "@trigger", "@tr":
 				g.handleTriggerDeferred(ctx, entry, descs, switches, lhs, body)
+			case "@end", "@break", "@assert":
+				g.handleEndDeferred(ctx, entry, prefix, switches, lhs, body)
 			}
 			return true
 		}
@@ -468,6 +554,14 @@ func (g *Game) handleDeferredBodyCmd(cmd string, ctx *eval.EvalContext, entry *Q
 	return false
 }
 
+// deferredCommandName maps a deferred-body prefix to its registered command.
+func deferredCommandName(prefix string) string {
+	if prefix == "@tr" {
+		return "@trigger"
+	}
+	return prefix
+}
+
 // extractDeferredSwitches pulls /switch names from a command like "@dolist/now".
 func extractDeferredSwitches(cmd, prefix string) []string {
 	rest := cmd[len(prefix):]
@@ -485,7 +579,7 @@ func extractDeferredSwitches(cmd, prefix string) []string {
 
 // handleWaitDeferred handles @wait with split-before-eval.
 // Evaluates LHS (time/semaphore spec), preserves body raw for deferred execution.
-func (g *Game) handleWaitDeferred(ctx *eval.EvalContext, entry *QueueEntry, descs []*Descriptor, lhs, body string) {
+func (g *Game) handleWaitDeferred(ctx *eval.EvalContext, entry *QueueEntry, descs []*Descriptor, switches []string, lhs, body string) {
 	evalLHS := ctx.Exec(lhs, eval.EvFCheck|eval.EvEval, entry.Args)
 	evalLHS = strings.TrimSpace(evalLHS)
 
@@ -504,39 +598,92 @@ func (g *Game) handleWaitDeferred(ctx *eval.EvalContext, entry *QueueEntry, desc
 	if ctx.RData != nil {
 		qe.RData = ctx.RData.Clone()
 	}
+	g.scheduleWait(entry.Player, evalLHS, HasSwitch(switches, "until"), qe)
+}
+
+// waitTimeLayouts are the date formats @wait/until accepts besides epoch
+// seconds, read in the server's time zone.
+var waitTimeLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", time.ANSIC,
+}
+
+// parseWaitTime parses an @wait/until time: epoch seconds or a date.
+func parseWaitTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if isNumeric(s) {
+		return time.Unix(int64(toIntSimple(s)), 0), true
+	}
+	for _, layout := range waitTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
 
-	if isNumeric(evalLHS) {
-		secs := toIntSimple(evalLHS)
+// scheduleWait queues qe for @wait. spec is <seconds>, <object>,
+// <object>/<seconds>, <object>/<attr> or <object>/<attr>/<seconds>; a
+// semaphore wait with <seconds> runs anyway once they pass. With until,
+// spec is the time to run at instead. It reports false, after telling
+// player, if the spec is bad.
+func (g *Game) scheduleWait(player gamedb.DBRef, spec string, until bool, qe *QueueEntry) bool {
+	if until {
+		at, ok := parseWaitTime(spec)
+		if !ok {
+			g.Conns.SendToPlayer(player, "That's not a valid time.")
+			return false
+		}
+		qe.WaitUntil = at
+		g.Queue.AddWait(qe)
+		return true
+	}
+	if isNumeric(spec) {
+		secs := toIntSimple(spec)
 		if secs < 0 {
 			secs = 0
 		}
-		qe.WaitUntil = time.Now().Add(time.Duration(secs) * time.Second)
+		qe.WaitUntil = g.Now().Add(time.Duration(secs) * time.Second)
 		g.Queue.AddWait(qe)
-	} else if slashIdx := strings.IndexByte(evalLHS, '/'); slashIdx >= 0 {
-		objStr := evalLHS[:slashIdx]
-		attrStr := evalLHS[slashIdx+1:]
-		target := g.ResolveRef(entry.Player, objStr)
+		return true
+	}
+
+	parts := strings.SplitN(spec, "/", 3)
+	attrStr, timeout := "", 0
+	switch {
+	case len(parts) == 3:
+		attrStr = parts[1]
+		timeout = toIntSimple(parts[2])
+	case len(parts) == 2 && isNumeric(parts[1]):
+		timeout = toIntSimple(parts[1])
+	case len(parts) == 2:
+		attrStr = parts[1]
+	}
+	var target gamedb.DBRef
+	if len(parts) > 1 {
+		target = g.ResolveRef(player, parts[0])
 		if target == gamedb.Nothing {
-			return
-		}
-		attr := g.ResolveAttrNum(attrStr)
-		if attr <= 0 {
-			attr = gamedb.A_SEMAPHORE
+			return true
 		}
-		g.semaphoreWait(target, attr, qe)
 	} else {
 		// C TinyMUSH: @wait <obj>={cmd} without /attr defaults to A_SEMAPHORE (47).
-		target := g.ResolveRef(entry.Player, evalLHS)
+		target = g.ResolveRef(player, spec)
 		if target == gamedb.Nothing {
-			target = g.MatchObject(entry.Player, evalLHS)
+			target = g.MatchObject(player, spec)
 		}
-		if target != gamedb.Nothing {
-			g.semaphoreWait(target, gamedb.A_SEMAPHORE, qe)
-		} else {
+		if target == gamedb.Nothing {
 			// Unrecognized — queue immediate
 			g.Queue.Add(qe)
+			return true
 		}
 	}
+	attr := gamedb.A_SEMAPHORE
+	if attrStr != "" {
+		if n := g.ResolveAttrNum(attrStr); n > 0 {
+			attr = n
+		}
+	}
+	g.semaphoreWait(target, attr, qe, time.Duration(timeout)*time.Second)
+	return true
 }
 
 // handleDolistDeferred handles @dolist with split-before-eval.
@@ -793,6 +940,8 @@ func (g *Game) ExecuteAsObject(player, cause gamedb.DBRef, input string) {
 	}
 
 	DebugLog("OBJEXEC ExecuteAsObject player=#%d cause=#%d input=%q", player, cause, truncDebug(input, 200))
+	g.traceWatch(player, "command: %s", input)
+	g.verboseEcho(player, input)
 
 	// Handle say/pose/setvattr prefixes
 	switch input[0] {
@@ -848,7 +997,7 @@ func (g *Game) ExecuteAsObject(player, cause gamedb.DBRef, input string) {
 			if strings.HasPrefix(switches, "content") {
 				// @pemit/contents: send to all contents of target
 				for _, cur := range g.DB.SafeContents(target) {
-					g.SendMarkedToPlayer(cur, "EMIT", message)
+					g.SendEmitToPlayer(cur, player, cause, message)
 					g.CheckPemitListen(cur, player, message)
 				}
 				// C TinyMUSH also delivers to the room itself (notify_all_from_inside
@@ -862,12 +1011,12 @@ func (g *Game) ExecuteAsObject(player, cause gamedb.DBRef, input string) {
 				for _, t := range strings.Fields(targetStr) {
 					ref := g.ResolveRef(player, t)
 					if ref != gamedb.Nothing {
-						g.SendMarkedToPlayer(ref, "EMIT", message)
+						g.SendEmitToPlayer(ref, player, cause, message)
 						g.CheckPemitListen(ref, player, message)
 					}
 				}
 			} else {
-				g.SendMarkedToPlayer(target, "EMIT", message)
+				g.SendEmitToPlayer(target, player, cause, message)
 				// C TinyMUSH: @pemit to an object triggers its LISTEN/^ patterns
 				g.CheckPemitListen(target, player, message)
 			}
@@ -875,7 +1024,7 @@ func (g *Game) ExecuteAsObject(player, cause gamedb.DBRef, input string) {
 	case "@emit":
 		loc := g.PlayerLocation(player)
 		if loc != gamedb.Nothing {
-			g.SendMarkedToRoom(loc, "EMIT", stripAllBraces(args))
+			g.SendEmitToRoom(loc, player, cause, stripAllBraces(args))
 		}
 	case "@oemit":
 		if eqIdx := strings.IndexByte(args, '='); eqIdx >= 0 {
@@ -884,7 +1033,7 @@ func (g *Game) ExecuteAsObject(player, cause gamedb.DBRef, input string) {
 			target := g.ResolveRef(player, targetStr)
 			if target != gamedb.Nothing {
 				if tObj, ok := g.DB.Objects[target]; ok {
-					g.SendMarkedToRoomExcept(tObj.Location, target, "EMIT", message)
+					g.SendEmitToRoomExcept(tObj.Location, target, player, cause, message)
 				}
 			}
 		}
@@ -894,7 +1043,7 @@ func (g *Game) ExecuteAsObject(player, cause gamedb.DBRef, input string) {
 			message := strings.TrimSpace(stripAllBraces(args[eqIdx+1:]))
 			room := g.ResolveRef(player, roomStr)
 			if room != gamedb.Nothing {
-				g.SendMarkedToRoom(room, "EMIT", message)
+				g.SendEmitToRoom(room, player, cause, message)
 			}
 		}
 	case "@trigger":
@@ -902,7 +1051,7 @@ func (g *Game) ExecuteAsObject(player, cause gamedb.DBRef, input string) {
 	case "@set":
 		g.DoSet(player, args)
 	case "@wait":
-		g.DoWait(player, cause, args)
+		g.DoWait(player, cause, args, nil)
 	case "@switch":
 		g.doSwitchObj(player, cause, args)
 	default:
@@ -910,7 +1059,7 @@ func (g *Game) ExecuteAsObject(player, cause gamedb.DBRef, input string) {
 		// This allows STARTUP and other non-connected object commands (@function, @drain,
 		// @notify, @dolist, etc.) to work without being individually hardcoded here.
 		synth := g.MakeObjDescriptor(player)
-		DispatchCommand(g, synth, input)
+		dispatchCommand(g, synth, input)
 	}
 }
 
@@ -1141,6 +1290,7 @@ func (g *Game) DoTrigger(player, cause gamedb.DBRef, args string) {
 		return
 	}
 	DebugLog("TRIGGER player=#%d target=#%d attr=%q (#%d) text=%q", player, target, attrName, attrNum, truncDebug(text, 200))
+	g.traceWatch(target, "triggered %s by #%d", attrName, player)
 
 	// Parse comma-separated args and evaluate each one (CS_ARGV behavior).
 	// C TinyMUSH's @trigger evaluates each arg via parse_arglist before
@@ -1225,15 +1375,16 @@ func (g *Game) DoTriggerNow(player, cause gamedb.DBRef, args string) {
 
 // DoWait queues a delayed command.
 // Format: @wait seconds = command  OR  @wait obj/attr = command
-func (g *Game) DoWait(player, cause gamedb.DBRef, args string) {
+// It reports whether the command was queued.
+func (g *Game) DoWait(player, cause gamedb.DBRef, args string, switches []string) bool {
 	eqIdx := strings.IndexByte(args, '=')
 	if eqIdx < 0 {
-		return
+		return false
 	}
 	waitSpec := strings.TrimSpace(args[:eqIdx])
 	command := strings.TrimSpace(args[eqIdx+1:])
 	if command == "" {
-		return
+		return false
 	}
 	// Strip outer braces so the body is treated as multiple semicolon-separated
 	// commands (each evaluated independently), not as a single brace-grouped
@@ -1248,40 +1399,7 @@ func (g *Game) DoWait(player, cause gamedb.DBRef, args string) {
 		Command: command,
 	}
 
-	// Check if it's a number (timed wait) or obj/attr (semaphore)
-	if isNumeric(waitSpec) {
-		secs := toIntSimple(waitSpec)
-		if secs < 0 {
-			secs = 0
-		}
-		entry.WaitUntil = time.Now().Add(time.Duration(secs) * time.Second)
-		g.Queue.AddWait(entry)
-	} else if slashIdx := strings.IndexByte(waitSpec, '/'); slashIdx >= 0 {
-		// Semaphore wait: obj/attr
-		objStr := waitSpec[:slashIdx]
-		attrStr := waitSpec[slashIdx+1:]
-		target := g.ResolveRef(player, objStr)
-		if target == gamedb.Nothing {
-			return
-		}
-		attr := g.ResolveAttrNum(attrStr)
-		if attr <= 0 {
-			attr = gamedb.A_SEMAPHORE
-		}
-		g.semaphoreWait(target, attr, entry)
-	} else {
-		// C TinyMUSH: @wait <obj>={cmd} without /attr defaults to A_SEMAPHORE (47).
-		target := g.ResolveRef(player, waitSpec)
-		if target == gamedb.Nothing {
-			target = g.MatchObject(player, waitSpec)
-		}
-		if target != gamedb.Nothing {
-			g.semaphoreWait(target, gamedb.A_SEMAPHORE, entry)
-		} else {
-			// Unrecognized — queue immediate
-			g.Queue.Add(entry)
-		}
-	}
+	return g.scheduleWait(player, waitSpec, HasSwitch(switches, "until"), entry)
 }
 
 // DoForce forces an object to execute a command.
@@ -1320,16 +1438,89 @@ func (g *Game) DoSet(player gamedb.DBRef, args string) {
 	g.SetFlag(target, value)
 }
 
+// initQueueLimits hooks the owner quotas (player_queue_limit and
+// wizard_queue_limit) into the command queue.
+func (g *Game) initQueueLimits() {
+	g.Queue.OwnerOf = func(obj gamedb.DBRef) gamedb.DBRef { return ResolveOwner(g, obj) }
+	g.Queue.Quota = g.queueQuota
+	g.Queue.Runaway = func(obj gamedb.DBRef) {
+		g.haltRunaway(obj, "too many commands queued")
+	}
+}
+
+// queueQuota returns how many commands owner may have queued, or 0 for no
+// limit.
+func (g *Game) queueQuota(owner gamedb.DBRef) int {
+	if g.Conf == nil {
+		return 0
+	}
+	if Wizard(g, owner) {
+		return g.Conf.WizardQueueLimit
+	}
+	return g.Conf.PlayerQueueLimit
+}
+
+// haltRunaway stops an object that went over its owner's queue quota or
+// command rate: it sets HALT, clears the object's queued commands and tells
+// the owner, as C TinyMUSH does for run away objects.
+func (g *Game) haltRunaway(obj gamedb.DBRef, why string) {
+	o, ok := g.DB.Objects[obj]
+	if !ok || o.HasFlag(gamedb.FlagHalt) {
+		return
+	}
+	o.Flags[0] |= gamedb.FlagHalt
+	g.PersistObject(o)
+	removed := g.Queue.HaltPlayer(obj)
+	owner := ResolveOwner(g, obj)
+	Logf(LogBugs, LevelWarn, "run away object %s(#%d) owned by #%d: %s; halted, %d command(s) removed",
+		o.Name, obj, owner, why, removed)
+	g.Conns.SendToPlayer(owner, fmt.Sprintf("Run away object %s(#%d): %s. Halted, %d command(s) removed.",
+		o.Name, obj, why, removed))
+}
+
+// panicMsg is what the enactor of a command that panicked is told.
+const panicMsg = "GAME: That command ran into a server error and was stopped. The wizards have been told."
+
+// commandPanic cleans up after a panic recovered while running cmd for obj
+// on behalf of enactor: it logs the stack, tells the connected wizards and
+// halts obj, clearing its queue and, unless it is a player, setting HALT so
+// the same code can't run again until someone looks at it. The caller
+// tells the enactor.
+func (g *Game) commandPanic(obj, enactor gamedb.DBRef, cmd string, r any) {
+	Logf(LogBugs, LevelError, "PANIC running command for #%d (enactor #%d) %q: %v\n%s",
+		obj, enactor, truncDebug(cmd, 200), r, debug.Stack())
+	g.wizardAlert("Server error: panic running a command for #%d: %v", obj, r)
+	removed := g.Queue.HaltPlayer(obj)
+	if o, ok := g.DB.Objects[obj]; ok && o.ObjType() != gamedb.TypePlayer && !o.HasFlag(gamedb.FlagHalt) {
+		o.Flags[0] |= gamedb.FlagHalt
+		g.PersistObject(o)
+	}
+	g.NotifyWizards(fmt.Sprintf("GAME: Server error running a command for %s(#%d), enactor #%d: %v. Halted, %d command(s) removed.",
+		g.PlayerName(obj), obj, enactor, r, removed))
+}
+
 // ProcessQueue processes queued commands (called periodically).
 func (g *Game) ProcessQueue() bool {
-	// Move ready entries from wait queue
-	promoted := g.Queue.PromoteReady()
+	// Move ready entries from wait queue, and semaphore waits that timed
+	// out; those give back their count as if notified.
+	now := g.Now()
+	promoted := g.Queue.PromoteReady(now)
+	for _, e := range g.Queue.ExpireSemaphores(now) {
+		g.semaphoreAddTo(e.SemObj, e.SemAttr, -1)
+		e.SemObj, e.SemAttr, e.WaitUntil = gamedb.Nothing, 0, time.Time{}
+		g.Queue.Add(e)
+		promoted++
+	}
 
-	// Reset per-object execution counters every second
-	now := time.Now()
-	if g.objExecCountReset.IsZero() || now.Sub(g.objExecCountReset) > time.Second {
-		g.objExecCount = make(map[gamedb.DBRef]int)
-		g.objExecCountReset = now
+	// Reset per-owner execution counters every second
+	now = time.Now()
+	if g.ownerExecCountReset.IsZero() || now.Sub(g.ownerExecCountReset) > time.Second {
+		g.ownerExecCount = make(map[gamedb.DBRef]int)
+		g.ownerExecCountReset = now
+	}
+	chunk, rate := 0, 0
+	if g.Conf != nil {
+		chunk, rate = g.Conf.QueueIdleChunk, g.Conf.QueueOwnerRate
 	}
 
 	// Only process entries that existed BEFORE this tick started.
@@ -1337,41 +1528,29 @@ func (g *Game) ProcessQueue() bool {
 	// those go to the back of the immediate queue and won't be processed until
 	// the next tick. This matches C TinyMUSH behavior where each tick processes
 	// only pre-existing queue entries (new entries wait for the next timeslice).
-	available := g.Queue.ImmediateCount()
-	maxPerTick := 100
-	if available < maxPerTick {
-		maxPerTick = available
-	}
-	const maxPerObjPerSec = 200 // Per-object rate limit
+	// Owners take turns, queue_idle_chunk entries at a time.
+	const maxPerTick = 100
+	ownerOf := func(obj gamedb.DBRef) gamedb.DBRef { return ResolveOwner(g, obj) }
 	processed := 0
-	for i := 0; i < maxPerTick; i++ {
-		entry := g.Queue.PopImmediate()
-		if entry == nil {
-			break
-		}
-		g.objExecCount[entry.Player]++
-		if g.objExecCount[entry.Player] > maxPerObjPerSec {
-			if g.objExecCount[entry.Player] == maxPerObjPerSec+1 {
-				log.Printf("QUEUE: throttling #%d — exceeded %d executions/sec", entry.Player, maxPerObjPerSec)
-			}
+	for _, entry := range g.Queue.PopFair(maxPerTick, chunk, ownerOf) {
+		owner := ownerOf(entry.Player)
+		g.ownerExecCount[owner]++
+		if rate > 0 && g.ownerExecCount[owner] > rate {
+			g.Queue.Settle(entry)
+			g.haltRunaway(entry.Player, fmt.Sprintf("owner ran over %d commands per second", rate))
 			continue // Drop entry
 		}
 		g.safeExecuteQueueEntry(entry)
+		g.Queue.Settle(entry)
 		processed++
 	}
 	return processed > 0 || promoted > 0
 }
 
-// safeExecuteQueueEntry wraps ExecuteQueueEntry with panic recovery and a
-// watchdog that logs slow entries (but still blocks until completion).
+// safeExecuteQueueEntry wraps ExecuteQueueEntry, which recovers from its
+// own panics, with a watchdog that logs slow entries (but still blocks
+// until completion).
 func (g *Game) safeExecuteQueueEntry(entry *QueueEntry) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("PANIC in queue entry (player=#%d cmd=%q): %v\n%s",
-				entry.Player, entry.Command, r, debug.Stack())
-		}
-	}()
-
 	// Watchdog: log if entry takes longer than 5 seconds
 	timer := time.AfterFunc(5*time.Second, func() {
 		cmdSnippet := entry.Command
@@ -1413,10 +1592,11 @@ func (g *Game) StartQueueProcessor() {
 				func() {
 					defer func() {
 						if r := recover(); r != nil {
-							log.Printf("PANIC in queue processor: %v", r)
+							Logf(LogBugs, LevelError, "PANIC in queue processor: %v", r)
 						}
 					}()
-					hadWork := g.ProcessQueue()
+					var hadWork bool
+					g.Do(func() { hadWork = g.ProcessQueue() })
 					if hadWork && idle {
 						idle = false
 						ticker.Reset(queueTick)
@@ -1430,10 +1610,10 @@ func (g *Game) StartQueueProcessor() {
 				func() {
 					defer func() {
 						if r := recover(); r != nil {
-							log.Printf("PANIC in queue processor (wake): %v", r)
+							Logf(LogBugs, LevelError, "PANIC in queue processor (wake): %v", r)
 						}
 					}()
-					g.ProcessQueue()
+					g.Do(func() { g.ProcessQueue() })
 					if idle {
 						idle = false
 						ticker.Reset(queueTick)
@@ -1505,37 +1685,105 @@ func stripDoubleEscapeSpecials(text string) string {
 	return b.String()
 }
 
-// FireConnectAttr fires ACONNECT (or ADISCONNECT) on a player, matching C's
-// announce_connattr: fires on the player, the master room, and all objects
-// in the master room's contents chain.
-func (g *Game) FireConnectAttr(player gamedb.DBRef, connCount int, attrNum int) {
-	args := []string{"connect", fmt.Sprintf("%d", connCount)}
+// FireConnectAttr fires ACONNECT (or ADISCONNECT) with args, matching C's
+// announce_connattr: on the player; with use_global_aconn, on the master
+// room and every object in it (that the player passes the uselock of,
+// with global_aconn_uselocks); on the zone of the player's location (a
+// thing zone itself, or the contents of a room zone); and with
+// room_aconnect, on the location itself. Each object fires at most once.
+func (g *Game) FireConnectAttr(player gamedb.DBRef, attrNum int, args []string) {
+	seen := map[gamedb.DBRef]bool{player: true}
+	fire := func(obj gamedb.DBRef) {
+		if obj == gamedb.Nothing || seen[obj] {
+			return
+		}
+		seen[obj] = true
+		g.QueueAttrAction(obj, player, attrNum, args)
+	}
+	fireContents := func(container gamedb.DBRef, useLocks bool) {
+		cObj, ok := g.DB.Objects[container]
+		if !ok {
+			return
+		}
+		walked := make(map[gamedb.DBRef]bool)
+		for obj := cObj.Contents; obj != gamedb.Nothing && !walked[obj]; {
+			walked[obj] = true
+			o, exists := g.DB.Objects[obj]
+			if !exists {
+				break
+			}
+			if !useLocks || CouldDoIt(g, player, obj, aLUse) {
+				fire(obj)
+			}
+			obj = o.Next
+		}
+	}
 
 	// 1. Fire on the player itself
 	g.QueueAttrAction(player, player, attrNum, args)
 
-	// 2. Fire on the master room
-	masterRoom := g.MasterRoomRef()
-	if masterRoom == gamedb.Nothing {
-		return
+	// 2. Fire on the master room and everything in it, honoring uselocks
+	// with global_aconn_uselocks
+	if g.Conf == nil || g.Conf.GlobalAconn {
+		if masterRoom := g.MasterRoomRef(); masterRoom != gamedb.Nothing {
+			fire(masterRoom)
+			fireContents(masterRoom, g.Conf != nil && g.Conf.GlobalAconnUselocks)
+		}
 	}
-	g.QueueAttrAction(masterRoom, player, attrNum, args)
 
-	// 3. Fire on every object in the master room's contents
-	mrObj, ok := g.DB.Objects[masterRoom]
+	loc := g.PlayerLocation(player)
+	locObj, ok := g.DB.Objects[loc]
 	if !ok {
 		return
 	}
-	seen := make(map[gamedb.DBRef]bool)
-	for obj := mrObj.Contents; obj != gamedb.Nothing && !seen[obj]; {
-		seen[obj] = true
-		o, exists := g.DB.Objects[obj]
-		if !exists {
-			break
+
+	// 3. Fire on the location's zone: a thing fires itself, a room its contents
+	if zObj, ok := g.DB.Objects[locObj.Zone]; ok {
+		switch zObj.ObjType() {
+		case gamedb.TypeThing:
+			fire(locObj.Zone)
+		case gamedb.TypeRoom:
+			fireContents(locObj.Zone, false)
 		}
-		g.QueueAttrAction(obj, player, attrNum, args)
-		obj = o.Next
 	}
+
+	// 4. Fire on the location itself
+	if g.Conf == nil || g.Conf.RoomAconnect {
+		fire(loc)
+	}
+}
+
+// fireAconnect fires ACONNECT for a new session: %0 is the connect reason
+// (connect, cd, create or guest), %1 the player's open connections and %2
+// their total number of connects.
+func (g *Game) fireAconnect(d *Descriptor, reason string) {
+	conns := len(g.Conns.GetByPlayer(d.Player))
+	g.FireConnectAttr(d.Player, 39, []string{ // A_ACONNECT = 39
+		reason, strconv.Itoa(conns), strconv.Itoa(g.loginCount(d.Player))})
+}
+
+// fireAdisconnect fires ADISCONNECT for a session that is ending: %0 is
+// the disconnect reason, %1 the connections left open, %2 and %3 when the
+// session connected and was last active, %4 its command count, and %5
+// and %6 its bytes in and out.
+func (g *Game) fireAdisconnect(d *Descriptor, remaining int) {
+	traffic := d.Traffic()
+	g.FireConnectAttr(d.Player, 40, []string{ // A_ADISCONNECT = 40
+		disconnReason(d), strconv.Itoa(remaining),
+		strconv.FormatInt(d.ConnTime.Unix(), 10), strconv.FormatInt(d.LastCmd.Unix(), 10),
+		strconv.Itoa(d.CmdCount), strconv.Itoa(traffic.Recv), strconv.Itoa(traffic.Sent)})
+}
+
+// disconnReason maps a descriptor's DisconnectReason to the C TinyMUSH
+// ADISCONNECT reason names.
+func disconnReason(d *Descriptor) string {
+	switch d.DisconnectReason {
+	case "":
+		return "netdeath"
+	case "booted":
+		return "boot"
+	}
+	return d.DisconnectReason
 }
 
 // RunStartup walks all objects and queues STARTUP (attr #19).
@@ -1592,13 +1840,14 @@ func (g *Game) MatchListenPatterns(loc gamedb.DBRef, speaker gamedb.DBRef, messa
 		excludeSet[e] = true
 	}
 
-	// Walk contents of the room
+	// Walk contents of the room. Objects inside a HAVEN location don't hear.
+	haven := locObj.HasFlag(gamedb.FlagHaven)
 	for _, next := range g.DB.SafeContents(loc) {
 		if next == speaker || excludeSet[next] {
 			continue
 		}
 		obj, ok := g.DB.Objects[next]
-		if !ok {
+		if !ok || (haven && obj.ObjType() != gamedb.TypePlayer) {
 			continue
 		}
 		// Check for MONITOR flag, HAS_LISTEN flag, or presence of LISTEN attr.
@@ -1607,11 +1856,20 @@ func (g *Game) MatchListenPatterns(loc gamedb.DBRef, speaker gamedb.DBRef, messa
 		if obj.HasFlag(gamedb.FlagMonitor) || obj.HasFlag2(gamedb.Flag2HasListen) || g.hasListenAttr(obj) {
 			g.checkListenAttrs(next, speaker, message)
 		}
+		if hasForwardlist(obj) {
+			g.forwardHeard(next, speaker, message)
+		}
+		g.puppetHeard(next, message, false)
 	}
 
 	// Also check the room itself
-	if loc != speaker && !excludeSet[loc] && (locObj.HasFlag(gamedb.FlagMonitor) || locObj.HasFlag2(gamedb.Flag2HasListen) || g.hasListenAttr(locObj)) {
-		g.checkListenAttrs(loc, speaker, message)
+	if loc != speaker && !excludeSet[loc] {
+		if locObj.HasFlag(gamedb.FlagMonitor) || locObj.HasFlag2(gamedb.Flag2HasListen) || g.hasListenAttr(locObj) {
+			g.checkListenAttrs(loc, speaker, message)
+		}
+		if hasForwardlist(locObj) {
+			g.forwardHeard(loc, speaker, message)
+		}
 	}
 }
 
@@ -1627,6 +1885,10 @@ func (g *Game) CheckPemitListen(target, cause gamedb.DBRef, message string) {
 	if obj.HasFlag(gamedb.FlagMonitor) || obj.HasFlag2(gamedb.Flag2HasListen) || g.hasListenAttr(obj) {
 		g.checkListenAttrs(target, cause, message)
 	}
+	if hasForwardlist(obj) {
+		g.forwardHeard(target, cause, message)
+	}
+	g.puppetHeard(target, message, false)
 }
 
 // AudibleRelay implements the AUDIBLE (HEARTHRU) relay system from C TinyMUSH.
@@ -1640,7 +1902,11 @@ func (g *Game) CheckPemitListen(target, cause gamedb.DBRef, message string) {
 // 2. INWARD (outside→inside): For each AUDIBLE object in the room with LISTEN
 //    matching the message, relay to the object's contents with @inprefix.
 //
-// The speaker param is who spoke, loc is where they spoke.
+// 3. EXITS: If the room is AUDIBLE, relay through each of its AUDIBLE exits
+//    to the exit's destination with the exit's @prefix.
+//
+// Messages matching the object's @filter (outward) or @infilter (inward)
+// aren't relayed. The speaker param is who spoke, loc is where they spoke.
 func (g *Game) AudibleRelay(loc, speaker gamedb.DBRef, message string) {
 	locObj, ok := g.DB.Objects[loc]
 	if !ok {
@@ -1649,9 +1915,9 @@ func (g *Game) AudibleRelay(loc, speaker gamedb.DBRef, message string) {
 
 	// --- OUTWARD relay: inside container → container's location ---
 	// If we're inside an AUDIBLE object (e.g., a sled), relay to its location.
-	if locObj.HasFlag(gamedb.FlagHearThru) {
+	if locObj.HasFlag(gamedb.FlagHearThru) && locObj.ObjType() != gamedb.TypeRoom {
 		outerLoc := locObj.Location
-		if outerLoc != gamedb.Nothing {
+		if outerLoc != gamedb.Nothing && !g.relayFiltered(loc, 92, message) { // A_FILTER
 			// Get PREFIX attribute (attr 90) and prepend to message
 			prefix := g.GetAttrText(loc, 90) // A_PREFIX
 			if prefix != "" {
@@ -1680,6 +1946,60 @@ func (g *Game) AudibleRelay(loc, speaker gamedb.DBRef, message string) {
 
 	// --- INWARD relay: outside → inside AUDIBLE objects ---
 	g.audibleInwardRelay(loc, speaker, message)
+
+	// --- EXIT relay: AUDIBLE room → through AUDIBLE exits ---
+	if locObj.HasFlag(gamedb.FlagHearThru) && locObj.ObjType() == gamedb.TypeRoom {
+		g.audibleExitRelay(loc, speaker, message)
+	}
+}
+
+// audibleExitRelay passes a message heard in an AUDIBLE room through the
+// room's AUDIBLE exits to the rooms they lead to, prefixed with the
+// exit's @prefix or "From a distance," as in TinyMUSH. The relayed
+// message reaches listeners and FORWARDLISTs there, but isn't relayed
+// onward through that room's own exits.
+func (g *Game) audibleExitRelay(room, speaker gamedb.DBRef, message string) {
+	for _, exit := range g.DB.SafeExits(room) {
+		exitObj, ok := g.DB.Objects[exit]
+		if !ok || !exitObj.HasFlag(gamedb.FlagHearThru) || g.relayFiltered(exit, 92, message) { // A_FILTER
+			continue
+		}
+		dest := exitObj.Location
+		destObj, ok := g.DB.Objects[dest]
+		if !ok || dest == room || destObj.ObjType() != gamedb.TypeRoom {
+			continue
+		}
+		prefix := "From a distance,"
+		if p := g.GetAttrText(exit, 90); p != "" { // A_PREFIX
+			prefix = evalExpr(g, exit, p)
+		}
+		relayed := message
+		if prefix != "" {
+			relayed = prefix + " " + message
+		}
+		g.SendMarkedToRoom(dest, "EMIT", relayed)
+		g.MatchListenPatterns(dest, speaker, relayed)
+	}
+}
+
+// relayFiltered reports whether obj's @filter or @infilter (attr) stops
+// it relaying message. The attribute is evaluated and split on commas
+// into wildcard patterns; a message matching any of them isn't relayed.
+func (g *Game) relayFiltered(obj gamedb.DBRef, attr int, message string) bool {
+	filter := g.GetAttrText(obj, attr)
+	if filter == "" {
+		return false
+	}
+	for _, pattern := range strings.Split(evalExpr(g, obj, filter), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if matched, _ := matchWild(pattern, message); matched {
+			return true
+		}
+	}
+	return false
 }
 
 // audibleInwardRelay checks each AUDIBLE object in a room for LISTEN match
@@ -1700,7 +2020,7 @@ func (g *Game) audibleInwardRelay(room, speaker gamedb.DBRef, message string) {
 		}
 		listenText = evalExpr(g, next, listenText)
 		matched, _ := matchWild(listenText, message)
-		if !matched {
+		if !matched || g.relayFiltered(next, 91, message) { // A_INFILTER
 			continue
 		}
 
@@ -1715,10 +2035,12 @@ func (g *Game) audibleInwardRelay(room, speaker gamedb.DBRef, message string) {
 		} else {
 			relayed = message
 		}
-		// Send to all contents of this AUDIBLE object
+		// Send to all contents of this AUDIBLE object, and let their
+		// listeners and FORWARDLISTs hear it too.
 		for _, inner := range g.DB.SafeContents(next) {
 			g.SendMarkedToPlayer(inner, "EMIT", relayed)
 		}
+		g.MatchListenPatterns(next, speaker, relayed, next)
 	}
 }
 
@@ -1742,7 +2064,7 @@ func (g *Game) checkListenAttrs(obj, cause gamedb.DBRef, message string) {
 		if !ok {
 			break
 		}
-		for _, attr := range cur.Attrs {
+		for _, attr := range cur.Attributes() {
 			text := eval.StripAttrPrefix(attr.Value)
 			if !strings.HasPrefix(text, "^") {
 				continue
@@ -1758,12 +2080,13 @@ func (g *Game) checkListenAttrs(obj, cause gamedb.DBRef, message string) {
 			action := rest[colonIdx+1:]
 
 			// Match the message against the pattern
-			matched, args := matchWild(pattern, message)
+			matched, args := matchAttrPattern(pattern, message, g.attrMatchFlags(attr))
 			if !matched {
 				continue
 			}
 
 			DebugLog("LISTEN MATCH obj=#%d pattern=%q action=%q args=%v", obj, pattern, action, args)
+			g.traceWatch(obj, "^-listen matched: %s", message)
 			entry := &QueueEntry{
 				Player:  obj, // executor is always the child object, not the parent
 				Cause:   cause,
@@ -1802,7 +2125,7 @@ func (g *Game) checkListenAttrs(obj, cause gamedb.DBRef, message string) {
 // hasListenAttr returns true if an object has a LISTEN attr (26) or any ^-prefix attr.
 // Used as a fallback when HAS_LISTEN flag may not be set (e.g. imported data).
 func (g *Game) hasListenAttr(obj *gamedb.Object) bool {
-	for _, attr := range obj.Attrs {
+	for _, attr := range obj.Attributes() {
 		if attr.Number == 26 { // A_LISTEN
 			return true
 		}
@@ -1838,7 +2161,8 @@ func (g *Game) semaphoreAddTo(obj gamedb.DBRef, attr int, amount int) int {
 // Increments the semaphore count. If count <= 0, the semaphore was pre-notified
 // so the command executes immediately. Otherwise, queue it.
 // This matches C TinyMUSH's behavior in cque_do_wait().
-func (g *Game) semaphoreWait(target gamedb.DBRef, attr int, qe *QueueEntry) {
+// A timeout above zero runs the command anyway once it passes.
+func (g *Game) semaphoreWait(target gamedb.DBRef, attr int, qe *QueueEntry, timeout time.Duration) {
 	count := g.semaphoreAddTo(target, attr, 1)
 	DebugLog("SEMWAIT target=#%d attr=%d count=%d player=#%d cmd=%q", target, attr, count, qe.Player, truncDebug(qe.Command, 200))
 	if count <= 0 {
@@ -1849,6 +2173,9 @@ func (g *Game) semaphoreWait(target gamedb.DBRef, attr int, qe *QueueEntry) {
 		// Wait for notification
 		qe.SemObj = target
 		qe.SemAttr = attr
+		if timeout > 0 {
+			qe.WaitUntil = g.Now().Add(timeout)
+		}
 		g.Queue.AddSemaphore(qe)
 	}
 }
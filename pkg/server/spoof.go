@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// spoofTag returns the NOSPOOF attribution put in front of an emit:
+// "[Name(#n)] " for a speaker acting on its own behalf, with the owner in
+// braces when the speaker isn't self-owned and "<-(#cause)" when someone
+// else caused it (C TinyMUSH notify_check). Recipients without NOSPOOF,
+// and the speaker itself, get nothing.
+func (g *Game) spoofTag(recipient, speaker, cause gamedb.DBRef) string {
+	if recipient == speaker {
+		return ""
+	}
+	rObj, ok := g.DB.Objects[recipient]
+	if !ok || !rObj.HasFlag(gamedb.FlagNoSpoof) {
+		return ""
+	}
+	sObj, ok := g.DB.Objects[speaker]
+	if !ok {
+		return ""
+	}
+	tag := fmt.Sprintf("[%s(#%d)", DisplayName(sObj.Name), speaker)
+	if sObj.Owner != speaker {
+		tag += fmt.Sprintf("{%s}", g.PlayerName(sObj.Owner))
+	}
+	if cause != speaker && cause != gamedb.Nothing {
+		tag += fmt.Sprintf("<-(#%d)", cause)
+	}
+	return tag + "] "
+}
+
+// SendEmitToPlayer sends an emit from speaker to player, attributed if the
+// player is NOSPOOF.
+func (g *Game) SendEmitToPlayer(player, speaker, cause gamedb.DBRef, msg string) {
+	g.SendMarkedToPlayer(player, "EMIT", g.spoofTag(player, speaker, cause)+msg)
+}
+
+// SendEmitToRoom sends an emit from speaker to everyone connected in room.
+func (g *Game) SendEmitToRoom(room, speaker, cause gamedb.DBRef, msg string) {
+	g.SendEmitToRoomExcept(room, gamedb.Nothing, speaker, cause, msg)
+}
+
+// SendEmitToRoomExcept sends an emit from speaker to everyone connected in
+// room except one.
+func (g *Game) SendEmitToRoomExcept(room, except, speaker, cause gamedb.DBRef, msg string) {
+	for _, next := range g.DB.SafeContents(room) {
+		if next != except && g.Conns.IsConnected(next) {
+			g.SendEmitToPlayer(next, speaker, cause, msg)
+		}
+	}
+}
+
+// canPemit reports whether player may @pemit to target (C TinyMUSH
+// do_pemit). Anything nearby or controlled is fine, as is anything at all
+// for Long_Fingers or with pemit_any_object. Far players need
+// pemit_far_players and a PageLock the sender passes. These limits apply
+// to what players type; object code is not held to them.
+func (g *Game) canPemit(player, target gamedb.DBRef) bool {
+	if Nearby(g, player, target) || Controls(g, player, target) || LongFingers(g, player) {
+		return true
+	}
+	tObj, ok := g.DB.Objects[target]
+	if !ok || g.Conf == nil {
+		return false
+	}
+	if g.Conf.PemitAnyObject {
+		return true
+	}
+	return tObj.ObjType() == gamedb.TypePlayer && g.Conf.PemitFarPlayers && CouldDoIt(g, player, target, aLPage)
+}
+
+// canEmitIn reports whether player may emit into room (@remit,
+// @emit/room, @oemit): it must be in or next to it, control it, or have
+// Long_Fingers.
+func (g *Game) canEmitIn(player, room gamedb.DBRef) bool {
+	return Nearby(g, player, room) || Controls(g, player, room) || LongFingers(g, player)
+}
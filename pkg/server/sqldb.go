@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -38,12 +39,47 @@ func OpenSQLStore(path string, queryLimit, timeoutSec int) (*SQLStore, error) {
 		db.Close()
 		return nil, fmt.Errorf("setting busy timeout: %w", err)
 	}
-	return &SQLStore{
-		db:         db,
-		path:       path,
-		queryLimit: queryLimit,
-		timeout:    time.Duration(timeoutSec) * time.Second,
-	}, nil
+	s := &SQLStore{db: db, path: path}
+	s.SetLimits(queryLimit, timeoutSec)
+	return s, nil
+}
+
+// SetLimits changes the row limit and per-query timeout, as from
+// sql_query_limit and sql_timeout. Values below 1 fall back to the
+// defaults of 100 rows and 5 seconds.
+func (s *SQLStore) SetLimits(queryLimit, timeoutSec int) {
+	if queryLimit < 1 {
+		queryLimit = 100
+	}
+	if timeoutSec < 1 {
+		timeoutSec = 5
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queryLimit = queryLimit
+	s.timeout = time.Duration(timeoutSec) * time.Second
+}
+
+// ErrSQLTimeout is returned by Query when a query runs past sql_timeout.
+var ErrSQLTimeout = errors.New("QUERY TIMED OUT")
+
+// sqlReturnsRows reports whether query is a statement that returns rows
+// rather than a count of rows changed.
+func sqlReturnsRows(query string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(query))
+	for _, kw := range []string{"SELECT", "WITH", "PRAGMA", "EXPLAIN", "VALUES"} {
+		if strings.HasPrefix(upper, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSQLConnErr reports whether err means the connection itself is gone,
+// so that reconnecting might help.
+func isSQLConnErr(err error) bool {
+	return errors.Is(err, sql.ErrConnDone) || strings.Contains(err.Error(), "database is closed") ||
+		err.Error() == "SQL NOT CONFIGURED"
 }
 
 // Close closes the SQLite3 database connection.
@@ -71,9 +107,10 @@ func (s *SQLStore) Checkpoint() error {
 }
 
 // Query executes a SQL query and returns results as delimited text.
-// SELECT queries return rows delimited by rowDelim with fields separated by fieldDelim.
-// Non-SELECT queries return the number of affected rows.
-func (s *SQLStore) Query(query, rowDelim, fieldDelim string) (string, error) {
+// SELECT queries return rows delimited by rowDelim with fields separated by fieldDelim,
+// at most sql_query_limit of them. Other statements return the number of affected rows.
+// A query running past sql_timeout is abandoned with ErrSQLTimeout.
+func (s *SQLStore) Query(query, rowDelim, fieldDelim string) (result string, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -83,12 +120,16 @@ func (s *SQLStore) Query(query, rowDelim, fieldDelim string) (string, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 	defer cancel()
+	defer func() {
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			err = ErrSQLTimeout
+		}
+	}()
 
 	trimmed := strings.TrimSpace(query)
-	upper := strings.ToUpper(trimmed)
 
-	// Non-SELECT statements (INSERT, UPDATE, DELETE, CREATE, DROP, ALTER, etc.)
-	if !strings.HasPrefix(upper, "SELECT") {
+	// Statements that don't return rows (INSERT, UPDATE, DELETE, CREATE, DROP, ALTER, etc.)
+	if !sqlReturnsRows(trimmed) {
 		result, err := s.db.ExecContext(ctx, trimmed)
 		if err != nil {
 			return "", err
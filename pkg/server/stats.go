@@ -30,8 +30,9 @@ func (g *Game) ConnectionStats() map[string]any {
 		case ConnConnected:
 			connected++
 		}
-		bytesSent += d.BytesSent
-		bytesRecv += d.BytesRecv
+		traffic := d.Traffic()
+		bytesSent += traffic.Sent
+		bytesRecv += traffic.Recv
 		cmdCount += d.CmdCount
 	}
 
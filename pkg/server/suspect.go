@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/crystal-mush/gotinymush/pkg/events"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// wizardSetFlags lists flags only wizards may set or clear, so players
+// can't take them off themselves.
+var wizardSetFlags = map[string]bool{"SUSPECT": true, "GAGGED": true}
+
+// isSuspect reports whether obj has the SUSPECT flag.
+func (g *Game) isSuspect(obj gamedb.DBRef) bool {
+	o, ok := g.DB.Objects[obj]
+	return ok && o.HasFlag2(gamedb.Flag2Suspect)
+}
+
+// suspectWatcher reports whether player is sent [Suspect] reports: a wizard
+// with the WATCHER flag, or anyone with the watch_logins power.
+func (g *Game) suspectWatcher(player gamedb.DBRef) bool {
+	o, ok := g.DB.Objects[player]
+	if !ok {
+		return false
+	}
+	if o.HasPower(0, gamedb.PowWatch) {
+		return true
+	}
+	return o.HasFlag2(gamedb.Flag2Watcher) && Wizard(g, player)
+}
+
+// reportSuspect logs something a SUSPECT player did and sends it to every
+// connected watcher as an EvSuspect event. kind is "command", "connect",
+// "disconnect" or "name".
+func (g *Game) reportSuspect(player gamedb.DBRef, kind, msg string) {
+	Logf(LogSuspect, LevelInfo, "%s(#%d) %s", g.PlayerName(player), player, msg)
+	if kind == "connect" {
+		g.wizardAlert("[Suspect] %s(#%d) %s", g.PlayerName(player), player, msg)
+	}
+	ev := events.Event{
+		Type:   events.EvSuspect,
+		Source: player,
+		Room:   g.PlayerLocation(player),
+		Text:   fmt.Sprintf("[Suspect] %s %s", g.PlayerName(player), msg),
+		Data:   map[string]any{"player": int(player), "name": g.PlayerName(player), "kind": kind, "msg": msg},
+	}
+	for _, dd := range g.Conns.AllDescriptors() {
+		if dd.State != ConnConnected || dd.Player == player || !g.suspectWatcher(dd.Player) {
+			continue
+		}
+		dev := ev
+		dev.Player = dd.Player
+		dd.Receive(dev)
+	}
+	if g.EventBus != nil {
+		g.EventBus.EmitToTypeSubscribers(ev)
+	}
+}
+
+// logInput records a command typed at d in the commands log and, for
+// SUSPECT players, reports it to watching wizards.
+func (g *Game) logInput(d *Descriptor, line string) {
+	Logf(LogCommands, LevelInfo, "[%d] #%d: %s", d.ID, d.Player, line)
+	if d.State == ConnConnected && g.isSuspect(d.Player) {
+		g.reportSuspect(d.Player, "command", "typed: "+line)
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/eval"
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// @sweep listener types. Each selects what sweepCheck reports.
+const (
+	sweepCommands  = 1 << iota // objects with $-commands
+	sweepListeners             // @listen, ^-patterns, MONITOR, AUDIBLE exits
+	sweepPlayers               // players and puppets
+	sweepConnected             // connected players and their puppets
+	sweepAll       = sweepCommands | sweepListeners | sweepPlayers | sweepConnected
+)
+
+// hasCommandAttr reports whether obj has any $-command attributes.
+func hasCommandAttr(obj *gamedb.Object) bool {
+	if obj.HasFlag2(gamedb.Flag2HasCommands) {
+		return true
+	}
+	for _, attr := range obj.Attributes() {
+		if strings.HasPrefix(eval.StripAttrPrefix(attr.Value), "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// sweepCheck reports to d how ref could be listening, limited to the
+// listener types in lev, as C TinyMUSH's sweep_check.
+func (g *Game) sweepCheck(d *Descriptor, ref gamedb.DBRef, lev int) {
+	obj, ok := g.DB.Objects[ref]
+	if !ok || obj.IsGoing() {
+		return
+	}
+	var kinds []string
+	if obj.ObjType() == gamedb.TypeExit {
+		if lev&sweepListeners != 0 && obj.HasFlag(gamedb.FlagHearThru) {
+			kinds = append(kinds, "audible")
+		}
+	} else {
+		if lev&sweepCommands != 0 && hasCommandAttr(obj) {
+			kinds = append(kinds, "commands")
+		}
+		if lev&sweepListeners != 0 && (obj.HasFlag(gamedb.FlagMonitor) || obj.HasFlag2(gamedb.Flag2HasListen) || g.hasListenAttr(obj)) {
+			kinds = append(kinds, "messages")
+		}
+		isPlayer := obj.ObjType() == gamedb.TypePlayer
+		isPuppet := obj.HasFlag(gamedb.FlagPuppet) && !isPlayer
+		if lev&sweepPlayers != 0 && isPlayer {
+			kinds = append(kinds, "player")
+		}
+		if lev&sweepPlayers != 0 && isPuppet {
+			kinds = append(kinds, fmt.Sprintf("puppet(%s)", g.PlayerName(obj.Owner)))
+		}
+		if lev&sweepConnected != 0 && ((isPlayer && g.Conns.IsConnected(ref)) || (isPuppet && g.Conns.IsConnected(obj.Owner))) {
+			kinds = append(kinds, "connected")
+		}
+	}
+	if len(kinds) == 0 {
+		return
+	}
+	name := obj.Name
+	if obj.ObjType() == gamedb.TypeExit {
+		name = strings.SplitN(name, ";", 2)[0]
+	}
+	d.Send(fmt.Sprintf("  %s is listening. [%s]", name, strings.Join(kinds, " ")))
+}
+
+// cmdSweep implements @sweep[/<location>][/<listener type>]: who might
+// overhear you. Location switches are /here, /inventory and /exits;
+// listener switches are /commands, /connected, /listeners and /players.
+// Without switches of a kind, all of that kind are checked.
+func cmdSweep(g *Game, d *Descriptor, _ string, switches []string) {
+	here, inven, exits := HasSwitch(switches, "here"), HasSwitch(switches, "inventory"), HasSwitch(switches, "exits")
+	if !here && !inven && !exits {
+		here, inven, exits = true, true, true
+	}
+	lev := 0
+	for sw, bit := range map[string]int{"commands": sweepCommands, "listeners": sweepListeners,
+		"players": sweepPlayers, "connected": sweepConnected} {
+		if HasSwitch(switches, sw) {
+			lev |= bit
+		}
+	}
+	if lev == 0 {
+		lev = sweepAll
+	}
+
+	loc := g.PlayerLocation(d.Player)
+	locObj, hasLoc := g.DB.Objects[loc]
+	dark := hasLoc && locObj.HasFlag(gamedb.FlagDark) && (g.Conf == nil || !g.Conf.SweepDark) &&
+		!g.Controls(d.Player, loc) && !SeeAll(g, d.Player)
+	if here {
+		d.Send("Sweeping location...")
+		switch {
+		case !hasLoc || dark:
+			d.Send("Sorry, it is dark here and you can't search for bugs")
+			g.sweepCheck(d, d.Player, lev)
+		default:
+			g.sweepCheck(d, loc, lev)
+			for _, ref := range g.DB.SafeContents(loc) {
+				g.sweepCheck(d, ref, lev)
+			}
+		}
+	}
+	if exits {
+		d.Send("Sweeping exits...")
+		if hasLoc && !dark && locObj.HasFlag(gamedb.FlagHearThru) {
+			for _, ref := range g.DB.SafeExits(loc) {
+				g.sweepCheck(d, ref, lev)
+			}
+		}
+	}
+	if inven {
+		d.Send("Sweeping inventory...")
+		for _, ref := range g.DB.SafeContents(d.Player) {
+			g.sweepCheck(d, ref, lev)
+		}
+	}
+	d.Send("Sweep complete.")
+}
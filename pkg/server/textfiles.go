@@ -192,7 +192,9 @@ func (g *Game) WatchTextFiles() {
 					}
 				}
 				log.Printf("Text file changed: %s", desc)
-				g.NotifyWizards(fmt.Sprintf("GAME: Text file changed on disk: %s — use @readcache to reload.", desc))
+				g.Do(func() {
+					g.NotifyWizards(fmt.Sprintf("GAME: Text file changed on disk: %s — use @readcache to reload.", desc))
+				})
 
 			case err, ok := <-watcher.Errors:
 				if !ok {
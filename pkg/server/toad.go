@@ -0,0 +1,204 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// strippedFlags are removed from objects whose ownership is handed to
+// someone else, so a new owner can't inherit the old owner's privileges.
+const strippedFlags = gamedb.FlagWizard | gamedb.FlagRoyalty | gamedb.FlagInherit
+
+// ChownAll gives every object owned by victim (other than victim itself)
+// to recipient and returns the count. Unless nostrip is set the objects are
+// set HALT and lose privileged flags and powers; a non-God nostrip still
+// strips WIZARD and powers.
+func (g *Game) ChownAll(victim, recipient, by gamedb.DBRef, nostrip bool) int {
+	var changed []*gamedb.Object
+	for ref, obj := range g.DB.Objects {
+		if ref == victim || obj.Owner != victim {
+			continue
+		}
+		obj.Owner = recipient
+		switch {
+		case !nostrip:
+			obj.Flags[0] = obj.Flags[0]&^strippedFlags | gamedb.FlagHalt
+			obj.Powers = [2]int{}
+		case !IsGod(g, by):
+			obj.Flags[0] &^= gamedb.FlagWizard
+			obj.Powers = [2]int{}
+		}
+		changed = append(changed, obj)
+	}
+	if len(changed) > 0 {
+		g.PersistObjects(changed...)
+	}
+	return len(changed)
+}
+
+// bootAll disconnects every connection a player has.
+func (g *Game) bootAll(player gamedb.DBRef, msg string) {
+	for _, dd := range g.Conns.GetByPlayer(player) {
+		dd.Send(msg)
+		dd.DisconnectReason = "booted"
+		g.DisconnectPlayer(dd)
+	}
+}
+
+// retirePlayer removes a player from the name index after it stops being
+// a usable character.
+func (g *Game) retirePlayer(obj *gamedb.Object, oldName string) {
+	if g.Store == nil {
+		return
+	}
+	if err := g.Store.UpdatePlayerIndex(obj, oldName); err != nil {
+		Logf(LogBugs, LevelError, "player index for #%d: %v", obj.DBRef, err)
+	}
+}
+
+// Toad turns a player into a thing named after them, boots them, and
+// unless nochown is set hands the toad and everything they own to recipient.
+func (g *Game) Toad(victim, recipient, by gamedb.DBRef, nochown bool) {
+	obj := g.DB.Objects[victim]
+	oldName := obj.Name
+	loc := obj.Location
+
+	g.bootAll(victim, "You have been turned into a toad.")
+
+	// As in TinyMUSH, a toad keeps none of the player's flags or powers.
+	obj.Flags = [3]int{int(gamedb.TypeThing) | gamedb.FlagHalt, 0, 0}
+	obj.Powers = [2]int{}
+	obj.Name = "a slimy toad named " + oldName
+	g.SetAttr(victim, aPass, "")
+	if !nochown {
+		g.ChownAll(victim, recipient, by, false)
+		obj.Owner = recipient
+	}
+	g.PersistObject(obj)
+	g.retirePlayer(obj, oldName)
+
+	if loc != gamedb.Nothing {
+		g.Conns.SendToRoomExcept(g.DB, loc, victim,
+			fmt.Sprintf("%s has been turned into a slimy toad!", oldName))
+	}
+	Logf(LogWizard, LevelInfo, "%s(#%d) toaded %s(#%d)", g.PlayerName(by), by, oldName, victim)
+}
+
+// destroyPlayer destroys a player object, giving their possessions to the
+// destroying wizard so nothing is left with a missing owner. Whatever they
+// carry is sent home, and homes set to the player are moved elsewhere.
+func (g *Game) destroyPlayer(d *Descriptor, target gamedb.DBRef) {
+	obj := g.DB.Objects[target]
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	if target == d.Player {
+		d.Send("Sorry, no suicide allowed.")
+		return
+	}
+	if IsGod(g, target) {
+		d.Send("You can't destroy God!")
+		return
+	}
+	if Wizard(g, target) && !IsGod(g, d.Player) {
+		d.Send("Even you can't do that!")
+		return
+	}
+
+	g.bootAll(target, "You have been destroyed.")
+	n := g.ChownAll(target, d.Player, d.Player, false)
+
+	for _, c := range g.DB.SafeContents(target) {
+		g.evict(c, target, "The player carrying you has been destroyed.")
+	}
+	for _, e := range g.DB.SafeExits(target) {
+		g.destroyExit(e)
+	}
+	g.unlinkFrom(target)
+
+	obj.Flags[0] |= gamedb.FlagGoing
+	obj.Flags[1] &^= gamedb.Flag2Connected
+	if obj.Location != gamedb.Nothing {
+		g.RemoveFromContents(obj.Location, target)
+		if locObj, ok := g.DB.Objects[obj.Location]; ok {
+			g.PersistObject(locObj)
+		}
+	}
+	obj.Location = gamedb.Nothing
+	obj.Contents = gamedb.Nothing
+	obj.Exits = gamedb.Nothing
+	g.PersistObject(obj)
+	g.retirePlayer(obj, obj.Name)
+	g.purgeStructs(target)
+
+	Logf(LogWizard, LevelInfo, "%s(#%d) destroyed player %s(#%d), %d objects chowned", g.PlayerName(d.Player), d.Player, obj.Name, target, n)
+	d.Send(fmt.Sprintf("Destroyed: %s(#%d)", obj.Name, target))
+	if n > 0 {
+		d.Send(fmt.Sprintf("%d objects that belonged to %s are now yours.", n, obj.Name))
+	}
+}
+
+// toadArgs parses "<victim>[=<recipient>]" for @toad and @chownall.
+// recipient defaults to the enactor.
+func toadArgs(g *Game, d *Descriptor, args string) (victim, recipient gamedb.DBRef, ok bool) {
+	victimStr, recipStr, hasRecip := strings.Cut(args, "=")
+	victim = LookupPlayer(g.DB, strings.TrimSpace(victimStr))
+	if victim == gamedb.Nothing {
+		d.Send("No such player.")
+		return
+	}
+	recipient = d.Player
+	if hasRecip && strings.TrimSpace(recipStr) != "" {
+		recipient = LookupPlayer(g.DB, strings.TrimSpace(recipStr))
+		if recipient == gamedb.Nothing {
+			d.Send("No such recipient.")
+			return
+		}
+	}
+	if recipient == victim {
+		d.Send("The recipient can't be the victim.")
+		return
+	}
+	return victim, recipient, true
+}
+
+// cmdToad implements @toad[/no_chown] <victim>[=<recipient>].
+func cmdToad(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	victim, recipient, ok := toadArgs(g, d, strings.TrimSpace(args))
+	if !ok {
+		return
+	}
+	if victim == d.Player || IsGod(g, victim) {
+		d.Send("You can't toad that player.")
+		return
+	}
+	if Wizard(g, victim) && !IsGod(g, d.Player) {
+		d.Send("You can't toad another wizard.")
+		return
+	}
+	name := g.PlayerName(victim)
+	g.Toad(victim, recipient, d.Player, HasSwitch(switches, "no_chown"))
+	d.Send(fmt.Sprintf("You toaded %s! (#%d)", name, victim))
+}
+
+// cmdChownall implements @chownall[/nostrip] <victim>[=<recipient>].
+func cmdChownall(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	victim, recipient, ok := toadArgs(g, d, strings.TrimSpace(args))
+	if !ok {
+		return
+	}
+	n := g.ChownAll(victim, recipient, d.Player, HasSwitch(switches, "nostrip"))
+	d.Send(fmt.Sprintf("Ownership of %d objects changed from %s to %s.", n,
+		g.PlayerName(victim), g.PlayerName(recipient)))
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crystal-mush/gotinymush/pkg/gamedb"
+)
+
+// watchRateLimit is how many trace lines a watcher is sent per second for
+// one object; the rest are counted and summarized.
+const watchRateLimit = 20
+
+// objWatch is one wizard's @watch on one object.
+type objWatch struct {
+	watcher     gamedb.DBRef
+	windowStart time.Time
+	sent        int
+	dropped     int
+}
+
+// watchList tracks @watch traces, keyed by the watched object.
+type watchList struct {
+	mu    sync.Mutex
+	byObj map[gamedb.DBRef][]*objWatch
+}
+
+// add starts watcher watching obj. It reports false if it already was.
+func (wl *watchList) add(obj, watcher gamedb.DBRef) bool {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	for _, w := range wl.byObj[obj] {
+		if w.watcher == watcher {
+			return false
+		}
+	}
+	if wl.byObj == nil {
+		wl.byObj = make(map[gamedb.DBRef][]*objWatch)
+	}
+	wl.byObj[obj] = append(wl.byObj[obj], &objWatch{watcher: watcher})
+	return true
+}
+
+// remove stops watcher watching obj, or everything if obj is Nothing. It
+// returns how many watches were removed.
+func (wl *watchList) remove(obj, watcher gamedb.DBRef) int {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	n := 0
+	for ref, list := range wl.byObj {
+		if obj != gamedb.Nothing && ref != obj {
+			continue
+		}
+		kept := list[:0]
+		for _, w := range list {
+			if w.watcher == watcher {
+				n++
+				continue
+			}
+			kept = append(kept, w)
+		}
+		if len(kept) == 0 {
+			delete(wl.byObj, ref)
+		} else {
+			wl.byObj[ref] = kept
+		}
+	}
+	return n
+}
+
+// watching returns the objects watcher is watching, in dbref order.
+func (wl *watchList) watching(watcher gamedb.DBRef) []gamedb.DBRef {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	var refs []gamedb.DBRef
+	for ref, list := range wl.byObj {
+		for _, w := range list {
+			if w.watcher == watcher {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i] < refs[j] })
+	return refs
+}
+
+// traceWatch sends a line about obj to everyone watching it. It sits next
+// to the DebugLog calls for queue entries, commands, $-command matches,
+// triggers and listens, and costs nothing when no one is watching.
+func (g *Game) traceWatch(obj gamedb.DBRef, format string, args ...any) {
+	wl := &g.watches
+	wl.mu.Lock()
+	list := wl.byObj[obj]
+	if len(list) == 0 {
+		wl.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	type delivery struct {
+		watcher gamedb.DBRef
+		dropped int
+	}
+	var out []delivery
+	for _, w := range list {
+		if now.Sub(w.windowStart) >= time.Second {
+			w.windowStart, w.sent = now, 0
+		}
+		if w.sent >= watchRateLimit {
+			w.dropped++
+			continue
+		}
+		w.sent++
+		out = append(out, delivery{w.watcher, w.dropped})
+		w.dropped = 0
+	}
+	wl.mu.Unlock()
+
+	prefix := fmt.Sprintf("[watch] %s(#%d): ", g.ObjName(obj), obj)
+	msg := prefix + truncDebug(fmt.Sprintf(format, args...), 400)
+	for _, dv := range out {
+		if dv.dropped > 0 {
+			g.Conns.SendToPlayer(dv.watcher, fmt.Sprintf("%s(%d line(s) suppressed)", prefix, dv.dropped))
+		}
+		g.Conns.SendToPlayer(dv.watcher, msg)
+	}
+}
+
+// cmdWatch implements @watch[/off|/list] [<object>].
+func cmdWatch(g *Game, d *Descriptor, args string, switches []string) {
+	if !Wizard(g, d.Player) {
+		d.Send("Permission denied.")
+		return
+	}
+	args = strings.TrimSpace(args)
+
+	if HasSwitch(switches, "list") || (args == "" && !HasSwitch(switches, "off")) {
+		refs := g.watches.watching(d.Player)
+		if len(refs) == 0 {
+			d.Send("You aren't watching anything.")
+			return
+		}
+		names := make([]string, len(refs))
+		for i, ref := range refs {
+			names[i] = fmt.Sprintf("%s(#%d)", g.ObjName(ref), ref)
+		}
+		d.Send("Watching: " + strings.Join(names, ", "))
+		return
+	}
+
+	if HasSwitch(switches, "off") {
+		target := gamedb.Nothing
+		if args != "" && !strings.EqualFold(args, "all") {
+			if target = g.MatchObject(d.Player, args); target == gamedb.Nothing {
+				d.Send("I don't see that here.")
+				return
+			}
+		}
+		if g.watches.remove(target, d.Player) == 0 {
+			d.Send("You weren't watching that.")
+			return
+		}
+		d.Send("Watch stopped.")
+		return
+	}
+
+	target := g.MatchObject(d.Player, args)
+	if target == gamedb.Nothing {
+		d.Send("I don't see that here.")
+		return
+	}
+	if !g.watches.add(target, d.Player) {
+		d.Send(fmt.Sprintf("You are already watching %s(#%d).", g.ObjName(target), target))
+		return
+	}
+	d.Send(fmt.Sprintf("Watching %s(#%d). Use @watch/off to stop.", g.ObjName(target), target))
+}
@@ -1,17 +1,20 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/crystal-mush/gotinymush/pkg/admin"
@@ -46,12 +49,19 @@ type WebServer struct {
 	upgrader  websocket.Upgrader
 	admin     *admin.Admin
 	ctrl      *gameServerController
+	server    *Server // nil when the web server runs without game listeners
 	metrics   *Metrics
 	startTime time.Time
+
+	// roomDescs caches the public pages' room descriptions (guarded by
+	// the world lock, like the handlers that use it).
+	roomDescs map[gamedb.DBRef]publicDescEntry
 }
 
-// SetServer gives the admin controller a reference to the Server for shutdown support.
+// SetServer gives the web server a reference to the Server, for listener
+// status in /health and /ready and for shutdown from the admin panel.
 func (ws *WebServer) SetServer(s *Server) {
+	ws.server = s
 	if ws.ctrl != nil {
 		ws.ctrl.server = s
 	}
@@ -101,28 +111,39 @@ func (ws *WebServer) registerRoutes(cfg WebConfig) {
 	handler = corsMiddleware(cfg.CORSOrigins, handler)
 
 	ws.httpSrv = &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-		Handler: handler,
+		Addr:              fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       2 * time.Minute,
 	}
 
 	// WebSocket endpoint
 	ws.mux.HandleFunc("GET /ws", ws.handleWebSocket)
 
 	// Auth endpoints
-	ws.mux.HandleFunc("POST /api/v1/auth/login", ws.handleAuthLogin)
-	ws.mux.HandleFunc("POST /api/v1/auth/refresh", ws.handleAuthRefresh)
+	ws.mux.Handle("POST /api/v1/auth/login", ws.locked(ws.handleAuthLogin))
+	ws.mux.Handle("POST /api/v1/auth/refresh", ws.locked(ws.handleAuthRefresh))
 
 	// REST API endpoints
 	ws.RegisterRESTRoutes()
 
-	// Health endpoint (no auth, before admin)
+	// Health and readiness endpoints (no auth, before admin). They don't
+	// wait for the world lock; see tryDo.
 	ws.mux.HandleFunc("GET /health", ws.handleHealth)
+	ws.mux.HandleFunc("GET /ready", ws.handleReady)
+
+	// Public website (no auth)
+	if ws.game.Conf != nil && ws.game.Conf.WebPublicPages {
+		ws.registerPublicPages()
+	}
 
 	// Prometheus metrics endpoint
 	ws.metrics = NewMetrics(ws.game, time.Now())
 	ws.mux.Handle("GET /metrics", ws.metrics.Handler())
 
 	// Admin panel
+	CaptureRecentLog()
 	ctrl := &gameServerController{game: ws.game, running: true, startTime: time.Now()}
 	ws.ctrl = ctrl
 	ws.admin = admin.New(ctrl)
@@ -130,7 +151,7 @@ func (ws *WebServer) registerRoutes(cfg WebConfig) {
 		ws.admin.SetDataDir(filepath.Dir(ws.game.ConfPath))
 		ws.admin.SetConfPath(ws.game.ConfPath)
 	}
-	ws.mux.Handle("/admin/", ws.admin.Handler("/admin"))
+	ws.mux.Handle("/admin/", noWriteTimeout(ws.admin.Handler("/admin")))
 
 	// Root "/" handler: reverse proxy to web client container, serve local SPA, or redirect to /admin.
 	// NOTE: Must use method-less pattern "/" (not "GET /") to avoid Go 1.22 mux conflict
@@ -239,6 +260,49 @@ type WSMessage struct {
 	Command string         `json:"command,omitempty"`
 }
 
+// clientAddr returns the address a request came from. Behind a reverse
+// proxy listed in web_trusted_proxies that is the last address in
+// X-Forwarded-For the proxies didn't add themselves, or X-Real-IP; from
+// anyone else the headers are ignored, since a client can send them to
+// dodge a site ban or lock out someone else's address.
+func (ws *WebServer) clientAddr(r *http.Request) string {
+	var trusted []string
+	if ws.game.Conf != nil {
+		trusted = ws.game.Conf.WebTrustedProxies
+	}
+	isTrusted := func(addr string) bool {
+		ip, err := netip.ParseAddr(hostAddr(addr))
+		if err != nil {
+			return false
+		}
+		ip = ip.Unmap()
+		for _, t := range trusted {
+			if p, err := netip.ParsePrefix(t); err == nil && p.Contains(ip) {
+				return true
+			}
+			if a, err := netip.ParseAddr(t); err == nil && a.Unmap() == ip {
+				return true
+			}
+		}
+		return false
+	}
+	if !isTrusted(r.RemoteAddr) {
+		return r.RemoteAddr
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			if hop := strings.TrimSpace(hops[i]); hop != "" && (i == 0 || !isTrusted(hop)) {
+				return hop
+			}
+		}
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+	return r.RemoteAddr
+}
+
 // handleWebSocket upgrades an HTTP connection to a WebSocket and creates
 // a game Descriptor for the client.
 func (ws *WebServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -266,17 +330,14 @@ func (ws *WebServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Use X-Forwarded-For or X-Real-IP if behind a reverse proxy (e.g. Docker)
-	remoteAddr := r.RemoteAddr
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// X-Forwarded-For can be comma-separated; first entry is the real client
-		if idx := strings.Index(xff, ","); idx >= 0 {
-			remoteAddr = strings.TrimSpace(xff[:idx])
-		} else {
-			remoteAddr = strings.TrimSpace(xff)
-		}
-	} else if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		remoteAddr = strings.TrimSpace(xri)
+	ws.game.Lock()
+	defer ws.game.Unlock()
+	remoteAddr := ws.clientAddr(r)
+	if msg := ws.game.siteRefusal(remoteAddr); msg != "" {
+		wsConn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		wsConn.WriteJSON(WSMessage{Type: "error", Text: strings.TrimSpace(msg)})
+		wsConn.Close()
+		return
 	}
 	d, wc := newWSDescriptor(ws.game, wsConn, remoteAddr)
 	ws.game.Conns.Add(d)
@@ -284,9 +345,11 @@ func (ws *WebServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	if claims != nil {
 		// Auto-login
 		ws.game.Conns.Login(d, claims.PlayerRef)
+		ws.game.noteLogin(d)
 		if pObj, ok := ws.game.DB.Objects[claims.PlayerRef]; ok {
 			pObj.Flags[1] |= gamedb.Flag2Connected
 		}
+		ws.game.fireAconnect(d, "connect")
 		wc.sendJSON(WSMessage{
 			Type: "login",
 			Data: map[string]any{
@@ -305,19 +368,63 @@ func (ws *WebServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go wsReadLoop(ws, d, wc)
 }
 
-// wsConn holds the WebSocket connection and its write mutex.
+// wsConn is a WebSocket client's side of its Descriptor. It is the
+// net.Conn the descriptor's writer drains into (see StartWriter), so that
+// output to a slow browser is buffered like a telnet client's and never
+// written under the world lock. Each line queued is one JSON message;
+// anything else, such as the output flushed notice, is sent as text.
 type wsConn struct {
 	conn *websocket.Conn
-	mu   sync.Mutex
+	d    *Descriptor
 }
 
+// sendJSON queues msg for the client.
 func (wc *wsConn) sendJSON(msg WSMessage) {
-	wc.mu.Lock()
-	defer wc.mu.Unlock()
-	wc.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-	wc.conn.WriteJSON(msg)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[ws:%d] encode %s message: %v", wc.d.ID, msg.Type, err)
+		return
+	}
+	// Marshal escapes newlines within strings, so the message is one line.
+	wc.d.SendRaw(append(data, '\n'))
+}
+
+// Write sends the lines the descriptor's writer has drained, one message
+// each.
+func (wc *wsConn) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > n {
+		line := p[n:]
+		if nl := bytes.IndexByte(line, '\n'); nl >= 0 {
+			line = line[:nl+1]
+		}
+		msg := bytes.TrimRight(line, "\r\n")
+		var err error
+		switch {
+		case len(msg) == 0:
+		case msg[0] == '{':
+			err = wc.conn.WriteMessage(websocket.TextMessage, msg)
+		default:
+			err = wc.conn.WriteJSON(WSMessage{Type: "text", Text: string(msg)})
+		}
+		if err != nil {
+			return n, err
+		}
+		n += len(line)
+	}
+	return n, nil
 }
 
+func (wc *wsConn) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("websocket input is read by wsReadLoop")
+}
+func (wc *wsConn) Close() error                       { return wc.conn.Close() }
+func (wc *wsConn) LocalAddr() net.Addr                { return wc.conn.LocalAddr() }
+func (wc *wsConn) RemoteAddr() net.Addr               { return wc.conn.RemoteAddr() }
+func (wc *wsConn) SetDeadline(t time.Time) error      { return wc.conn.SetWriteDeadline(t) }
+func (wc *wsConn) SetReadDeadline(time.Time) error    { return nil }
+func (wc *wsConn) SetWriteDeadline(t time.Time) error { return wc.conn.SetWriteDeadline(t) }
+
 // newWSDescriptor creates a Descriptor configured for WebSocket transport.
 // The Descriptor's SendFunc and ReceiveFunc are wired to write JSON to the WS conn.
 func newWSDescriptor(game *Game, conn *websocket.Conn, addr string) (*Descriptor, *wsConn) {
@@ -325,7 +432,7 @@ func newWSDescriptor(game *Game, conn *websocket.Conn, addr string) (*Descriptor
 	id := game.Conns.NextID()
 	d := &Descriptor{
 		ID:        id,
-		Conn:      nullConn{}, // No raw TCP conn for WS
+		Conn:      wc,
 		State:     ConnLogin,
 		Player:    gamedb.Nothing,
 		Addr:      addr,
@@ -345,15 +452,17 @@ func newWSDescriptor(game *Game, conn *websocket.Conn, addr string) (*Descriptor
 			Channel: ev.Channel,
 		})
 	}
+	wc.d = d
+	d.StartWriter(game.outputLimit)
 	return d, wc
 }
 
 func wsReadLoop(ws *WebServer, d *Descriptor, wc *wsConn) {
 	defer func() {
-		ws.game.DisconnectPlayer(d)
+		ws.game.Do(func() { ws.game.DisconnectPlayer(d) })
 		ws.game.Conns.Remove(d)
-		wc.conn.Close()
-		log.Printf("[ws:%d] WebSocket closed from %s", d.ID, d.Addr)
+		d.Close()
+		Logf(LogConnections, LevelInfo, "[ws:%d] WebSocket closed from %s", d.ID, d.Addr)
 	}()
 
 	for {
@@ -365,39 +474,55 @@ func wsReadLoop(ws *WebServer, d *Descriptor, wc *wsConn) {
 			return
 		}
 
-		d.LastCmd = time.Now()
+		ws.game.Do(func() { handleWSMessage(ws, d, wc, msgBytes) })
+	}
+}
 
-		var msg WSMessage
-		if err := json.Unmarshal(msgBytes, &msg); err != nil {
-			wc.sendJSON(WSMessage{Type: "error", Text: "Invalid JSON message"})
-			continue
-		}
+// handleWSMessage runs one message from a WebSocket client. The world lock
+// must be held.
+func handleWSMessage(ws *WebServer, d *Descriptor, wc *wsConn, msgBytes []byte) {
+	d.LastCmd = ws.game.Now()
+	if d.State == ConnConnected {
+		ws.game.idleUndark(d)
+	}
 
-		switch msg.Type {
-		case "command":
-			if d.State == ConnLogin {
-				handleWSLogin(ws, d, wc, msg.Command)
-			} else {
-				d.CmdCount++
-				DispatchCommand(ws.game, d, msg.Command)
-			}
-		case "login":
+	var msg WSMessage
+	if err := json.Unmarshal(msgBytes, &msg); err != nil {
+		wc.sendJSON(WSMessage{Type: "error", Text: "Invalid JSON message"})
+		return
+	}
+
+	switch msg.Type {
+	case "command":
+		if d.State == ConnLogin {
 			handleWSLogin(ws, d, wc, msg.Command)
-		default:
-			wc.sendJSON(WSMessage{Type: "error", Text: fmt.Sprintf("Unknown message type: %s", msg.Type)})
+		} else {
+			d.CmdCount++
+			ws.game.logInput(d, msg.Command)
+			DispatchCommand(ws.game, d, msg.Command)
 		}
+	case "login":
+		handleWSLogin(ws, d, wc, msg.Command)
+	default:
+		wc.sendJSON(WSMessage{Type: "error", Text: fmt.Sprintf("Unknown message type: %s", msg.Type)})
 	}
 }
 
 func handleWSLogin(ws *WebServer, d *Descriptor, wc *wsConn, input string) {
 	command, user, password := ParseConnect(input)
 	if strings.HasPrefix(command, "co") {
+		if ws.game.LoginLocked(d.Addr) {
+			wc.sendJSON(WSMessage{Type: "error", Text: "Too many failed logins; try again later"})
+			return
+		}
 		player := LookupPlayer(ws.game.DB, user)
 		if player == gamedb.Nothing || !CheckPassword(ws.game.DB, player, password) {
+			ws.game.NoteLoginFailure(d.Addr)
 			wc.sendJSON(WSMessage{Type: "error", Text: "Invalid credentials"})
 			return
 		}
 		ws.game.Conns.Login(d, player)
+		ws.game.noteLogin(d)
 		if pObj, ok := ws.game.DB.Objects[player]; ok {
 			pObj.Flags[1] |= gamedb.Flag2Connected
 		}
@@ -411,11 +536,79 @@ func handleWSLogin(ws *WebServer, d *Descriptor, wc *wsConn, input string) {
 		})
 		loc := ws.game.PlayerLocation(player)
 		ws.game.ShowRoom(d, loc)
+		ws.game.fireAconnect(d, "connect")
 	} else {
 		wc.sendJSON(WSMessage{Type: "error", Text: "Use: connect <name> <password>"})
 	}
 }
 
+// noWriteTimeout lifts the server's WriteTimeout for h, whose responses
+// may stream for as long as the client watches (console events) or wait
+// on a long job (import commit). The admin panel never holds the world
+// lock while writing.
+func noWriteTimeout(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		h.ServeHTTP(w, r)
+	})
+}
+
+// maxLockedBody bounds the request body a locked handler is given.
+const maxLockedBody = 1 << 20
+
+// locked wraps h to run with the world lock held, for handlers that read
+// or change game state. The request body is read before the lock is taken
+// and the response is sent after it is released, so a slow client can't
+// hold up the game.
+func (ws *WebServer) locked(h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil && r.Body != http.NoBody {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLockedBody))
+			if err != nil {
+				http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		buf := &bufferedResponse{header: make(http.Header)}
+		ws.game.Do(func() { h(buf, r) })
+		buf.sendTo(w)
+	})
+}
+
+// bufferedResponse is an http.ResponseWriter that keeps the response for
+// sending later.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// sendTo writes the response kept to w.
+func (b *bufferedResponse) sendTo(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
+
 // --- Auth HTTP Handlers ---
 
 func (ws *WebServer) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
@@ -428,8 +621,14 @@ func (ws *WebServer) handleAuthLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	addr := ws.clientAddr(r)
+	if ws.game.LoginLocked(addr) {
+		http.Error(w, `{"error":"too many failed logins"}`, http.StatusTooManyRequests)
+		return
+	}
 	token, err := ws.auth.Login(req.Name, req.Password)
 	if err != nil {
+		ws.game.NoteLoginFailure(addr)
 		http.Error(w, `{"error":"invalid credentials"}`, http.StatusUnauthorized)
 		return
 	}
@@ -454,16 +653,74 @@ func (ws *WebServer) handleAuthRefresh(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"token": newToken})
 }
 
-// --- Health Handler ---
+// --- Health Handlers ---
 
+// handleHealth is the liveness probe: it answers 200 whenever the process
+// is serving HTTP, with database, queue, archive and listener status for
+// whoever is looking. While a command holds the world lock the game
+// details are left out and "busy" is set.
 func (ws *WebServer) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
+	g := ws.game
+	health := map[string]any{
 		"status":         "ok",
 		"version":        Version,
 		"uptime_seconds": time.Since(ws.startTime).Seconds(),
 		"game_running":   true,
+		"shutting_down":  g.shuttingDown.Load(),
+	}
+	if ws.server != nil {
+		health["listeners"] = ws.server.ListenerStatus()
+	}
+	ran := g.tryDo(func() {
+		db := map[string]any{"loaded": g.DB != nil}
+		if g.DB != nil {
+			db["objects"] = len(g.DB.Objects)
+			db["next_dbref"] = int(g.NextRef)
+		}
+		db["store"] = g.StoreStats()
+		archive := map[string]any{}
+		for k, v := range g.ArchiveStats() {
+			if k == "last" || k == "last_time" || k == "next_time" {
+				archive[k] = v
+			}
+		}
+		health["database"] = db
+		health["queue"] = g.QueueStats()
+		health["archive"] = archive
 	})
+	if !ran {
+		health["busy"] = true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// handleReady is the readiness probe: 200 once the database is loaded and
+// every configured game port is accepting connections, 503 with the
+// reasons otherwise, including while the game is shutting down. If a
+// command holds the world lock, the database is taken to be loaded, since
+// commands only run once it is.
+func (ws *WebServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	var reasons []string
+	if ws.game.shuttingDown.Load() {
+		reasons = append(reasons, "shutting down")
+	} else {
+		ws.game.tryDo(func() {
+			if ws.game.DB == nil || len(ws.game.DB.Objects) == 0 {
+				reasons = append(reasons, "database not loaded")
+			}
+		})
+	}
+	if ws.server != nil && !ws.server.ListenersUp() {
+		reasons = append(reasons, "game listeners not accepting connections")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(reasons) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]any{"ready": false, "reasons": reasons})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ready": true})
 }
 
 // --- SPA Handler ---
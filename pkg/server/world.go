@@ -0,0 +1,59 @@
+package server
+
+// The game runs one thing at a time, as C TinyMUSH's single thread did.
+//
+// Connections, the web server, the queue processor and the timers (cron,
+// maintenance, auto-save, guest cleanup, the IRC and Discord bridges) each
+// run on their own goroutine, but none of them touches the database, the
+// Game's maps or the objects' contents and exits chains except while
+// holding the world lock. Softcode sees the world exactly as it did with
+// a single thread: nothing moves underneath a command while it runs, so
+// a contents chain can't be half-relinked by two commands at once.
+//
+// The lock is taken at the edges, by whatever receives work from outside:
+// a line of input, an HTTP request, a timer firing. Everything reached
+// from there — commands, functions, queue entries, triggers — runs with it
+// already held and must not take it again, since a sync.Mutex isn't
+// reentrant. Slow work that doesn't need the world (network I/O, e-mail,
+// bolt backups, archives) is done outside the lock, copying out what it
+// needs first. The few functions that must wait on the network for their
+// result — httpget(), httppost() and a remote spell checker — release the
+// lock around the request with unlocked.
+//
+// Subsystems with their own locks (ConnManager, CommandQueue, Comsys,
+// Mail, the bolt store) may be used without the world lock; the lock is
+// for the game state they don't cover.
+
+// Lock takes the world lock. Code reached from a command already holds it.
+func (g *Game) Lock() { g.world.Lock() }
+
+// Unlock releases the world lock.
+func (g *Game) Unlock() { g.world.Unlock() }
+
+// unlocked runs fn with the world lock released, for a wait on the network
+// in the middle of a command. The caller must hold the lock, and must not
+// count on the world being as it was when fn returns.
+func (g *Game) unlocked(fn func()) {
+	g.world.Unlock()
+	defer g.world.Lock()
+	fn()
+}
+
+// Do runs fn with the world lock held.
+func (g *Game) Do(fn func()) {
+	g.world.Lock()
+	defer g.world.Unlock()
+	fn()
+}
+
+// tryDo runs fn with the world lock held if it is free, reporting whether
+// it ran. The health probes use it, so a long command, or a shutdown,
+// which keeps the lock for good, can't hang them.
+func (g *Game) tryDo(fn func()) bool {
+	if !g.world.TryLock() {
+		return false
+	}
+	defer g.world.Unlock()
+	fn()
+	return true
+}
@@ -34,6 +34,7 @@ export const api = {
   // Config
   getConfig: () => request<any>('GET', '/config'),
   putConfig: (config: Record<string, unknown>) => request<any>('PUT', '/config', config),
+  reloadConfig: () => request<any>('POST', '/config/reload'),
 
   // Import — existing
   importUpload: (path: string) => request<any>('POST', '/import/upload', { path }),
@@ -75,6 +76,14 @@ export const api = {
   shutdownStatus: () => request<any>('GET', '/server/shutdown'),
   shutdownCancel: () => request<any>('DELETE', '/server/shutdown'),
 
+  // Live console
+  consoleOpen: (wizard: string) => request<any>('POST', '/console', { wizard }),
+  consoleCommand: (id: string, command: string) =>
+    request<any>('POST', `/console/${encodeURIComponent(id)}/command`, { command }),
+  consoleClose: (id: string) => request<any>('DELETE', `/console/${encodeURIComponent(id)}`),
+  consoleEvents: (id: string) => new EventSource(`${BASE}/console/${encodeURIComponent(id)}/events`),
+  recentLog: (lines = 200) => request<any>('GET', `/logs?lines=${lines}`),
+
   // Setup
   setupStatus: () => request<any>('GET', '/setup/status'),
   createNewDB: () => request<any>('POST', '/import/create-new'),
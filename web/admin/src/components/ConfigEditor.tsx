@@ -15,6 +15,8 @@ export function ConfigEditor({ mode = 'live', stagedContent, onStagedSave }: Con
   const [saved, setSaved] = useState(false)
   const [rawMode, setRawMode] = useState(false)
   const [rawContent, setRawContent] = useState('')
+  const [reloading, setReloading] = useState(false)
+  const [reloadReport, setReloadReport] = useState<any>(null)
 
   useEffect(() => {
     if (mode === 'staged' && stagedContent) {
@@ -73,6 +75,18 @@ export function ConfigEditor({ mode = 'live', stagedContent, onStagedSave }: Con
     }
   }
 
+  const handleReload = async () => {
+    setReloading(true)
+    setError('')
+    try {
+      setReloadReport(await api.reloadConfig())
+    } catch (e: any) {
+      setError(e.message)
+    } finally {
+      setReloading(false)
+    }
+  }
+
   const updateField = (key: string, value: any) => {
     setConfig(prev => prev ? { ...prev, [key]: value } : null)
   }
@@ -165,9 +179,19 @@ export function ConfigEditor({ mode = 'live', stagedContent, onStagedSave }: Con
           >
             {saving ? 'Saving...' : 'Save'}
           </button>
+          <button
+            onClick={handleReload}
+            disabled={reloading}
+            title="Re-read the config file and apply what can change without a restart (@readconf)"
+            class="px-4 py-2 bg-slate-700 hover:bg-slate-600 text-white rounded text-sm transition-colors disabled:opacity-50"
+          >
+            {reloading ? 'Applying...' : 'Apply Now'}
+          </button>
         </div>
       </div>
 
+      {reloadReport && <ReloadReport report={reloadReport} onClose={() => setReloadReport(null)} />}
+
       {sections.map(([section, keys]) => (
         <div key={section} class="mb-6">
           <h3 class="text-sm font-semibold text-slate-400 uppercase tracking-wider mb-2">{section}</h3>
@@ -187,6 +211,36 @@ export function ConfigEditor({ mode = 'live', stagedContent, onStagedSave }: Con
   )
 }
 
+function ReloadReport({ report, onClose }: { report: any; onClose: () => void }) {
+  const groups: [string, string, any[]][] = [
+    ['Applied', 'text-green-400', report.applied || []],
+    ['Skipped (restart required)', 'text-yellow-400', report.skipped || []],
+    ['Invalid', 'text-red-400', report.invalid || []],
+  ]
+  return (
+    <div class="bg-slate-800 rounded p-4 mb-6 text-sm">
+      <div class="flex items-center justify-between mb-2">
+        <span class="text-slate-300">
+          {groups.map(([label, , items]) => `${items.length} ${label.split(' ')[0].toLowerCase()}`).join(', ')}
+        </span>
+        <button onClick={onClose} class="text-slate-400 hover:text-slate-200 text-xs">Dismiss</button>
+      </div>
+      {groups.filter(([, , items]) => items.length > 0).map(([label, color, items]) => (
+        <div key={label} class="mt-2">
+          <h4 class={`font-semibold ${color}`}>{label}</h4>
+          <ul class="font-mono text-xs text-slate-300">
+            {items.map((c: any) => (
+              <li key={c.key}>
+                {c.key}{c.new !== undefined && c.new !== '' ? ` = ${c.new}` : ''}{c.reason ? ` — ${c.reason}` : ''}
+              </li>
+            ))}
+          </ul>
+        </div>
+      ))}
+    </div>
+  )
+}
+
 function renderStructured(config: Record<string, any>, updateField: (k: string, v: any) => void) {
   const sections = groupConfigKeys(config)
   return (
@@ -277,7 +331,8 @@ function groupConfigKeys(config: Record<string, any>): [string, string[]][] {
     money_name_singular: 'Economy', money_name_plural: 'Economy', starting_money: 'Economy',
     paycheck: 'Economy', earn_limit: 'Economy', page_cost: 'Economy', wait_cost: 'Economy', link_cost: 'Economy',
     idle_timeout: 'Idle', idle_wiz_dark: 'Idle',
-    queue_idle_chunk: 'Queue', function_invocation_limit: 'Queue', machine_command_cost: 'Queue',
+    queue_idle_chunk: 'Queue', function_invocation_limit: 'Queue', function_recursion_limit: 'Queue',
+    function_cpu_limit: 'Queue', machine_command_cost: 'Queue',
     output_limit: 'Output',
     web_enabled: 'Web', web_port: 'Web', web_host: 'Web', web_domain: 'Web', web_static_dir: 'Web',
     web_cors_origins: 'Web', web_rate_limit: 'Web', jwt_secret: 'Web', jwt_expiry: 'Web',
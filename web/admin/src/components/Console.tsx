@@ -0,0 +1,161 @@
+import { useState, useEffect, useRef } from 'preact/hooks'
+import { api } from '../api/client'
+
+const maxLines = 1000
+
+export function Console() {
+  const [wizard, setWizard] = useState('')
+  const [session, setSession] = useState<{ id: string; player: string } | null>(null)
+  const [lines, setLines] = useState<string[]>([])
+  const [command, setCommand] = useState('')
+  const [history, setHistory] = useState<string[]>([])
+  const [historyPos, setHistoryPos] = useState(-1)
+  const [error, setError] = useState('')
+  const [logLines, setLogLines] = useState<string[]>([])
+  const outputRef = useRef<HTMLDivElement>(null)
+
+  const refreshLog = () => {
+    api.recentLog(200)
+      .then(r => setLogLines(r.lines || []))
+      .catch(() => {})
+  }
+
+  useEffect(() => {
+    refreshLog()
+    const interval = setInterval(refreshLog, 5000)
+    return () => clearInterval(interval)
+  }, [])
+
+  // Stream output for the open console; close it when leaving the page.
+  useEffect(() => {
+    if (!session) return
+    const events = api.consoleEvents(session.id)
+    events.onmessage = (e) => {
+      setLines(prev => [...prev, e.data].slice(-maxLines))
+    }
+    events.addEventListener('closed', () => {
+      events.close()
+      setSession(null)
+    })
+    return () => {
+      events.close()
+      api.consoleClose(session.id).catch(() => {})
+    }
+  }, [session?.id])
+
+  useEffect(() => {
+    if (outputRef.current) {
+      outputRef.current.scrollTop = outputRef.current.scrollHeight
+    }
+  }, [lines])
+
+  const handleOpen = async () => {
+    setError('')
+    try {
+      const s = await api.consoleOpen(wizard)
+      setLines([])
+      setSession(s)
+    } catch (e: any) {
+      setError(e.message)
+    }
+  }
+
+  const handleSend = async () => {
+    if (!session || !command.trim()) return
+    setError('')
+    setLines(prev => [...prev, `> ${command}`].slice(-maxLines))
+    setHistory(prev => [...prev, command])
+    setHistoryPos(-1)
+    const cmd = command
+    setCommand('')
+    try {
+      await api.consoleCommand(session.id, cmd)
+    } catch (e: any) {
+      setError(e.message)
+    }
+  }
+
+  const handleKey = (e: KeyboardEvent) => {
+    if (e.key === 'Enter') {
+      handleSend()
+    } else if (e.key === 'ArrowUp' && history.length > 0) {
+      const pos = historyPos < 0 ? history.length - 1 : Math.max(0, historyPos - 1)
+      setHistoryPos(pos)
+      setCommand(history[pos])
+      e.preventDefault()
+    } else if (e.key === 'ArrowDown' && historyPos >= 0) {
+      const pos = historyPos + 1
+      setHistoryPos(pos < history.length ? pos : -1)
+      setCommand(pos < history.length ? history[pos] : '')
+      e.preventDefault()
+    }
+  }
+
+  return (
+    <div>
+      <div class="flex items-center justify-between mb-6">
+        <h2 class="text-2xl font-bold">Console</h2>
+        {session && (
+          <div class="flex items-center gap-3">
+            <span class="text-sm text-slate-400">Connected as <span class="text-slate-200">{session.player}</span></span>
+            <button
+              onClick={() => setSession(null)}
+              class="px-4 py-2 bg-slate-700 hover:bg-slate-600 text-white rounded text-sm transition-colors"
+            >
+              Disconnect
+            </button>
+          </div>
+        )}
+      </div>
+
+      {error && <div class="bg-red-500/10 border border-red-500/30 rounded p-3 mb-4 text-red-300 text-sm">{error}</div>}
+
+      {!session ? (
+        <div class="bg-slate-800 rounded p-4 mb-6 flex items-center gap-3">
+          <input
+            type="text"
+            value={wizard}
+            onInput={(e) => setWizard((e.target as HTMLInputElement).value)}
+            onKeyDown={(e) => e.key === 'Enter' && handleOpen()}
+            placeholder="Wizard name"
+            class="flex-1 bg-slate-900 border border-slate-700 rounded px-3 py-2 text-sm text-slate-200"
+          />
+          <button
+            onClick={handleOpen}
+            disabled={!wizard.trim()}
+            class="px-4 py-2 bg-indigo-600 hover:bg-indigo-500 text-white rounded text-sm transition-colors disabled:opacity-50"
+          >
+            Connect
+          </button>
+        </div>
+      ) : (
+        <div class="mb-6">
+          <div
+            ref={outputRef}
+            class="bg-slate-950 rounded-t p-3 h-96 overflow-y-auto font-mono text-xs text-slate-200 whitespace-pre-wrap"
+          >
+            {lines.map((line, i) => <div key={i}>{line || ' '}</div>)}
+          </div>
+          <input
+            type="text"
+            value={command}
+            onInput={(e) => setCommand((e.target as HTMLInputElement).value)}
+            onKeyDown={handleKey}
+            placeholder="Type a command"
+            class="w-full bg-slate-900 border border-slate-700 rounded-b px-3 py-2 font-mono text-sm text-slate-200"
+          />
+        </div>
+      )}
+
+      <div class="flex items-center justify-between mb-2">
+        <h3 class="text-sm font-semibold text-slate-400 uppercase tracking-wider">Recent Server Log</h3>
+        <button onClick={refreshLog} class="text-slate-400 hover:text-slate-200 text-xs">Refresh</button>
+      </div>
+      <div class="bg-slate-800 rounded p-3 h-64 overflow-y-auto font-mono text-xs text-slate-300 whitespace-pre-wrap">
+        {logLines.length === 0
+          ? <span class="text-slate-500">No log output yet.</span>
+          : logLines.map((line, i) => <div key={i}>{line}</div>)}
+      </div>
+    </div>
+  )
+}
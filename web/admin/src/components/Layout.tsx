@@ -1,7 +1,7 @@
 import { ComponentChildren } from 'preact'
 import { branding } from '../tokens/branding'
 
-type Page = 'dashboard' | 'import' | 'config' | 'setup'
+type Page = 'dashboard' | 'console' | 'import' | 'config' | 'setup'
 
 interface LayoutProps {
   currentPage: Page
@@ -13,6 +13,7 @@ interface LayoutProps {
 
 const navItems: { page: Page; label: string; icon: string }[] = [
   { page: 'dashboard', label: 'Dashboard', icon: '\u2302' },
+  { page: 'console', label: 'Console', icon: '\u276F' },
   { page: 'import', label: 'Import', icon: '\u21E7' },
   { page: 'config', label: 'Config', icon: '\u2699' },
 ]
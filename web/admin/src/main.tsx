@@ -5,11 +5,12 @@ import { Layout } from './components/Layout'
 import { Dashboard } from './components/Dashboard'
 import { ImportFlow } from './components/ImportFlow'
 import { ConfigEditor } from './components/ConfigEditor'
+import { Console } from './components/Console'
 import { SetupWizard } from './components/SetupWizard'
 import { LoginScreen } from './components/LoginScreen'
 import { api, setAuthLostHandler } from './api/client'
 
-type Page = 'dashboard' | 'import' | 'config' | 'setup'
+type Page = 'dashboard' | 'console' | 'import' | 'config' | 'setup'
 
 function App() {
   const [page, setPage] = useState<Page>('dashboard')
@@ -67,6 +68,7 @@ function App() {
   const content = () => {
     switch (page) {
       case 'dashboard': return <Dashboard />
+      case 'console': return <Console />
       case 'import': return <ImportFlow />
       case 'config': return <ConfigEditor />
       case 'setup': return <SetupWizard onComplete={() => { setSetupMode(false); setPage('dashboard') }} />